@@ -3,11 +3,16 @@ package main
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/dimiro1/lunar/internal/ai"
+	"github.com/dimiro1/lunar/internal/tracing"
 )
 
 type Config struct {
@@ -16,6 +21,17 @@ type Config struct {
 	ExecutionTimeout time.Duration
 	APIKey           string
 	BaseURL          string
+	MetricsToken     string
+	OTLPEndpoint     string
+	OTLPHeaders      map[string]string
+	ServiceName      string
+	AIPricing        ai.Pricing
+	AIBudget         ai.Budget
+	InboundToken     string
+	InboundSMTPAddr  string
+	InboundSMTPHost  string
+	ResendSecret     string
+	BounceFunctionID string
 }
 
 func loadPort(getenv func(string) string) string {
@@ -97,6 +113,38 @@ func loadBaseURL(getenv func(string) string, port string) string {
 	return baseURL
 }
 
+// loadOTLPEndpoint returns the OTLP/HTTP traces URL, preferring the
+// signal-specific variable over the base endpoint as the OpenTelemetry spec does
+func loadOTLPEndpoint(getenv func(string) string) string {
+	if endpoint := getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+func loadServiceName(getenv func(string) string) string {
+	serviceName := getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "lunar"
+	}
+	return serviceName
+}
+
+// loadInboundSMTPHost returns the hostname the SMTP listener greets clients
+// with, the machine's hostname by default
+func loadInboundSMTPHost(getenv func(string) string) string {
+	if host := getenv("INBOUND_SMTP_HOSTNAME"); host != "" {
+		return host
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "localhost"
+}
+
 func initDataDir(getenv func(string) string) (string, error) {
 	dataDir := getenv("DATA_DIR")
 	if dataDir == "" {
@@ -119,11 +167,33 @@ func loadConfig(getenv func(string) string, dataDir string) (Config, error) {
 		return Config{}, err
 	}
 
+	aiPricing, err := ai.ParsePricing(getenv("AI_PRICES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid AI_PRICES: %w", err)
+	}
+
+	// Budgets of every function, which can only lower them
+	aiBudget, err := ai.ParseBudget(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
 		Port:             port,
 		DataDir:          dataDir,
 		ExecutionTimeout: timeout,
 		APIKey:           apiKey,
 		BaseURL:          baseURL,
+		MetricsToken:     getenv("METRICS_TOKEN"),
+		OTLPEndpoint:     loadOTLPEndpoint(getenv),
+		OTLPHeaders:      tracing.ParseHeaders(getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		ServiceName:      loadServiceName(getenv),
+		AIPricing:        aiPricing,
+		AIBudget:         aiBudget,
+		InboundToken:     getenv("INBOUND_EMAIL_TOKEN"),
+		InboundSMTPAddr:  getenv("INBOUND_SMTP_ADDR"),
+		InboundSMTPHost:  loadInboundSMTPHost(getenv),
+		ResendSecret:     getenv("RESEND_WEBHOOK_SECRET"),
+		BounceFunctionID: getenv("EMAIL_BOUNCE_FUNCTION"),
 	}, nil
 }
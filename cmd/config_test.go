@@ -423,3 +423,129 @@ func TestLoadConfig_BaseURLDefaultsToLocalhost(t *testing.T) {
 		t.Errorf("expected base URL %s, got %s", expected, config.BaseURL)
 	}
 }
+
+func TestLoadConfig_MetricsToken(t *testing.T) {
+	tmpDir := t.TempDir()
+	getenv := func(key string) string {
+		if key == "METRICS_TOKEN" {
+			return "scrape-token"
+		}
+		return ""
+	}
+
+	config, err := loadConfig(getenv, tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.MetricsToken != "scrape-token" {
+		t.Errorf("expected metrics token 'scrape-token', got %s", config.MetricsToken)
+	}
+}
+
+func TestLoadConfig_OTLP(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	t.Run("disabled by default", func(t *testing.T) {
+		config, err := loadConfig(func(string) string { return "" }, tmpDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.OTLPEndpoint != "" {
+			t.Errorf("expected empty OTLP endpoint, got %s", config.OTLPEndpoint)
+		}
+		if config.ServiceName != "lunar" {
+			t.Errorf("expected default service name 'lunar', got %s", config.ServiceName)
+		}
+	})
+
+	t.Run("base endpoint", func(t *testing.T) {
+		env := map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/",
+			"OTEL_EXPORTER_OTLP_HEADERS":  "x-api-key=abc",
+			"OTEL_SERVICE_NAME":           "my-lunar",
+		}
+		config, err := loadConfig(func(k string) string { return env[k] }, tmpDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.OTLPEndpoint != "http://collector:4318/v1/traces" {
+			t.Errorf("expected traces path appended, got %s", config.OTLPEndpoint)
+		}
+		if config.OTLPHeaders["x-api-key"] != "abc" {
+			t.Errorf("expected OTLP headers to be parsed, got %v", config.OTLPHeaders)
+		}
+		if config.ServiceName != "my-lunar" {
+			t.Errorf("expected service name 'my-lunar', got %s", config.ServiceName)
+		}
+	})
+
+	t.Run("traces endpoint takes precedence", func(t *testing.T) {
+		env := map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://collector:4318",
+			"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/custom",
+		}
+		config, err := loadConfig(func(k string) string { return env[k] }, tmpDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.OTLPEndpoint != "http://traces:4318/custom" {
+			t.Errorf("expected traces endpoint used as-is, got %s", config.OTLPEndpoint)
+		}
+	})
+}
+
+func TestLoadConfig_AIAccounting(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	env := map[string]string{
+		"AI_PRICES":             "openai/gpt-4o-mini=0.15:0.6",
+		"AI_DAILY_TOKEN_BUDGET": "100000",
+	}
+	config, err := loadConfig(func(k string) string { return env[k] }, tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if price := config.AIPricing["openai/gpt-4o-mini"]; price.Input != 0.15 || price.Output != 0.6 {
+		t.Errorf("expected gpt-4o-mini to be priced, got %v", config.AIPricing)
+	}
+	if config.AIBudget.DailyTokens != 100000 {
+		t.Errorf("expected a daily token budget of 100000, got %d", config.AIBudget.DailyTokens)
+	}
+
+	env["AI_PRICES"] = "gpt-4o-mini=0.15"
+	if _, err := loadConfig(func(k string) string { return env[k] }, tmpDir); err == nil {
+		t.Error("expected an error for invalid AI_PRICES")
+	}
+}
+
+func TestLoadConfig_InboundEmail(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	env := map[string]string{
+		"INBOUND_EMAIL_TOKEN":   "inbound-token",
+		"INBOUND_SMTP_ADDR":     ":2525",
+		"INBOUND_SMTP_HOSTNAME": "mx.example.com",
+		"RESEND_WEBHOOK_SECRET": "whsec_c2VjcmV0",
+		"EMAIL_BOUNCE_FUNCTION": "fn_bounces",
+	}
+	config, err := loadConfig(func(k string) string { return env[k] }, tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InboundToken != "inbound-token" || config.InboundSMTPAddr != ":2525" || config.InboundSMTPHost != "mx.example.com" {
+		t.Errorf("unexpected inbound email config: %+v", config)
+	}
+	if config.ResendSecret != "whsec_c2VjcmV0" || config.BounceFunctionID != "fn_bounces" {
+		t.Errorf("unexpected email delivery config: %+v", config)
+	}
+
+	delete(env, "INBOUND_SMTP_HOSTNAME")
+	config, err = loadConfig(func(k string) string { return env[k] }, tmpDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InboundSMTPHost == "" {
+		t.Error("expected the hostname to default to the machine's")
+	}
+}
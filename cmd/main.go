@@ -21,6 +21,8 @@ import (
 	"github.com/dimiro1/lunar/internal/logger"
 	"github.com/dimiro1/lunar/internal/migrate"
 	store "github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+	"github.com/dimiro1/lunar/internal/vectors"
 	_ "modernc.org/sqlite"
 )
 
@@ -62,12 +64,25 @@ func main() {
 
 	apiDB := store.NewSQLiteDB(db)
 	kvStore := kv.NewSQLiteStore(db)
+	vectorStore := vectors.NewSQLiteStore(db)
 	envStore := env.NewSQLiteStore(db)
 	appLogger := logger.NewSQLiteLogger(db)
+	httpRequestTracker := internalhttp.NewSQLiteTracker(db)
 	aiRequestTracker := ai.NewSQLiteTracker(db)
+	aiUsageStore := ai.NewSQLiteUsageStore(db)
+	aiCache := ai.NewSQLiteCache(db)
+	emailTemplates := email.NewSQLiteTemplateStore(db)
 	emailRequestTracker := email.NewSQLiteTracker(db)
+	emailRoutes := email.NewSQLiteRouteStore(db)
+	traceStore := tracing.NewSQLiteStore(db)
 	httpClient := internalhttp.NewDefaultClient()
 
+	// Export traces to an OpenTelemetry collector when configured
+	var traceExporter tracing.Exporter
+	if config.OTLPEndpoint != "" {
+		traceExporter = tracing.NewOTLPExporter(httpClient, config.OTLPEndpoint, config.OTLPHeaders, config.ServiceName)
+	}
+
 	// Initialize housekeeping scheduler
 	housekeepingScheduler := housekeeping.NewScheduler(apiDB)
 	if err := housekeepingScheduler.Start(); err != nil {
@@ -79,14 +94,28 @@ func main() {
 		DB:               apiDB,
 		Logger:           appLogger,
 		KVStore:          kvStore,
+		VectorStore:      vectorStore,
 		EnvStore:         envStore,
 		HTTPClient:       httpClient,
+		HTTPTracker:      httpRequestTracker,
 		AITracker:        aiRequestTracker,
+		AIPricing:        config.AIPricing,
+		AIBudget:         config.AIBudget,
+		AIUsage:          aiUsageStore,
+		AICache:          aiCache,
+		EmailTemplates:   emailTemplates,
 		EmailTracker:     emailRequestTracker,
+		EmailRoutes:      emailRoutes,
+		InboundToken:     config.InboundToken,
+		ResendSecret:     config.ResendSecret,
+		BounceFunctionID: config.BounceFunctionID,
+		TraceStore:       traceStore,
+		TraceExporter:    traceExporter,
 		ExecutionTimeout: config.ExecutionTimeout,
 		FrontendHandler:  frontend.Handler(),
 		APIKey:           config.APIKey,
 		BaseURL:          config.BaseURL,
+		MetricsToken:     config.MetricsToken,
 	})
 
 	addr := ":" + config.Port
@@ -96,19 +125,44 @@ func main() {
 		"execution_timeout", config.ExecutionTimeout)
 	slog.Info("Frontend available", "url", "http://localhost:"+config.Port)
 	slog.Info("API available", "url", "http://localhost:"+config.Port+"/api")
+	if config.MetricsToken != "" {
+		slog.Info("Metrics available", "url", "http://localhost:"+config.Port+"/metrics")
+	}
+	if config.OTLPEndpoint != "" {
+		slog.Info("Exporting traces", "endpoint", config.OTLPEndpoint)
+	}
+
+	if config.InboundToken != "" {
+		slog.Info("Inbound email webhooks available", "url", "http://localhost:"+config.Port+"/inbound/email/{provider}")
+	}
+	if config.ResendSecret != "" {
+		slog.Info("Email delivery webhook available", "url", "http://localhost:"+config.Port+"/inbound/email-events/resend")
+	}
 
 	// Setup graceful shutdown
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
 	// Start server in a goroutine
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 2)
 	go func() {
 		if err := server.ListenAndServe(addr); err != nil {
 			serverErr <- err
 		}
 	}()
 
+	// Start the inbound SMTP listener when configured
+	var smtpListener *email.SMTPListener
+	if config.InboundSMTPAddr != "" {
+		smtpListener = email.NewSMTPListener(server.InboundEmailHandler(), config.InboundSMTPHost)
+		slog.Info("Starting inbound SMTP listener", "addr", config.InboundSMTPAddr, "hostname", config.InboundSMTPHost)
+		go func() {
+			if err := smtpListener.ListenAndServe(config.InboundSMTPAddr); err != nil {
+				serverErr <- err
+			}
+		}()
+	}
+
 	// Wait for shutdown signal or server error
 	select {
 	case sig := <-shutdown:
@@ -122,6 +176,13 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if smtpListener != nil {
+			slog.Info("Stopping inbound SMTP listener...")
+			if err := smtpListener.Shutdown(ctx); err != nil {
+				slog.Error("Error stopping inbound SMTP listener", "error", err)
+			}
+		}
+
 		slog.Info("Shutting down server gracefully...")
 		if err := server.Shutdown(ctx); err != nil {
 			slog.Error("Error during shutdown", "error", err)
@@ -18,6 +18,7 @@ import (
 	"github.com/dimiro1/lunar/internal/logger"
 	"github.com/dimiro1/lunar/internal/migrate"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 	_ "modernc.org/sqlite"
 )
 
@@ -299,6 +300,8 @@ func startTestServer(t *testing.T) *testEnv {
 		KVStore:          kvStore,
 		EnvStore:         envStore,
 		HTTPClient:       httpClient,
+		HTTPTracker:      internalhttp.NewSQLiteTracker(db),
+		TraceStore:       tracing.NewSQLiteStore(db),
 		ExecutionTimeout: 30 * time.Second,
 		FrontendHandler:  frontend.Handler(),
 		APIKey:           testAPIKey,
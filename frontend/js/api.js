@@ -11,6 +11,7 @@
  * @typedef {import('./types.js').Execution} Execution
  * @typedef {import('./types.js').ExecutionsListResponse} ExecutionsListResponse
  * @typedef {import('./types.js').ExecutionLogsResponse} ExecutionLogsResponse
+ * @typedef {import('./types.js').TraceResponse} TraceResponse
  * @typedef {import('./types.js').DiffResponse} DiffResponse
  * @typedef {import('./types.js').ExecuteRequest} ExecuteRequest
  * @typedef {import('./types.js').ExecuteResponse} ExecuteResponse
@@ -298,6 +299,31 @@ export const API = {
         url:
           `/api/executions/${executionId}/email-requests?limit=${limit}&offset=${offset}`,
       }),
+
+    /**
+     * Gets outbound HTTP requests for an execution.
+     * @param {string} executionId - Execution ID
+     * @param {number} [limit=20] - Maximum number of HTTP requests to return
+     * @param {number} [offset=0] - Number of HTTP requests to skip
+     * @returns {Promise<HTTPRequestsResponse>} Paginated list of HTTP requests
+     */
+    getHTTPRequests: (executionId, limit = 20, offset = 0) =>
+      apiRequest({
+        method: "GET",
+        url:
+          `/api/executions/${executionId}/http-requests?limit=${limit}&offset=${offset}`,
+      }),
+
+    /**
+     * Gets the trace (span tree) recorded for an execution.
+     * @param {string} executionId - Execution ID
+     * @returns {Promise<TraceResponse>} Trace ID and spans
+     */
+    getTrace: (executionId) =>
+      apiRequest({
+        method: "GET",
+        url: `/api/executions/${executionId}/trace`,
+      }),
   },
 
   /**
@@ -229,6 +229,17 @@ export const AIRequestViewer = {
               },
               t(`common.status.${req.status}`),
             ),
+            req.cached
+              ? m(
+                Badge,
+                {
+                  variant: BadgeVariant.OUTLINE,
+                  size: BadgeSize.SM,
+                  style: "margin-left: 0.25rem;",
+                },
+                t("aiRequestViewer.cached"),
+              )
+              : null,
           ]),
 
           // Tokens
@@ -250,6 +261,12 @@ export const AIRequestViewer = {
                   "span.ai-request-viewer__token-label",
                   ` ${t("aiRequestViewer.out")}`,
                 ),
+                req.cost !== null && req.cost !== undefined
+                  ? m(
+                    "span.ai-request-viewer__token-label",
+                    ` · $${Number(req.cost.toPrecision(3))}`,
+                  )
+                  : null,
               ]
               : "-",
           ),
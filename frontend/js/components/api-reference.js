@@ -191,6 +191,36 @@ export function getLuaAPISections() {
               type: "function",
               description: t("luaApi.ai.items.chat"),
             },
+            {
+              name: "ai.run(options)",
+              type: "function",
+              description: t("luaApi.ai.items.run"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.ai.groups.vectors"),
+          items: [
+            {
+              name: "ai.embed(texts, options)",
+              type: "function",
+              description: t("luaApi.ai.items.embed"),
+            },
+            {
+              name: "vectors.upsert(collection, records)",
+              type: "function",
+              description: t("luaApi.ai.items.vectorsUpsert"),
+            },
+            {
+              name: "vectors.query(collection, vector, options?)",
+              type: "function",
+              description: t("luaApi.ai.items.vectorsQuery"),
+            },
+            {
+              name: "vectors.delete(collection, ids)",
+              type: "function",
+              description: t("luaApi.ai.items.vectorsDelete"),
+            },
           ],
         },
       ],
@@ -255,6 +285,11 @@ export function getLuaAPISections() {
               type: "string",
               description: t("luaApi.handler.items.baseUrl"),
             },
+            {
+              name: "ctx.traceId",
+              type: "string",
+              description: t("luaApi.handler.items.traceId"),
+            },
           ],
         },
         {
@@ -285,6 +320,86 @@ export function getLuaAPISections() {
               type: "table",
               description: t("luaApi.handler.items.query"),
             },
+            {
+              name: "event.form",
+              type: "table",
+              description: t("luaApi.handler.items.form"),
+            },
+            {
+              name: "event.files",
+              type: "table",
+              description: t("luaApi.handler.items.files"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.handler.groups.emailEvent"),
+          items: [
+            {
+              name: "event.recipient",
+              type: "string",
+              description: t("luaApi.handler.items.emailRecipient"),
+            },
+            {
+              name: "event.from",
+              type: "string",
+              description: t("luaApi.handler.items.emailFrom"),
+            },
+            {
+              name: "event.to",
+              type: "table",
+              description: t("luaApi.handler.items.emailTo"),
+            },
+            {
+              name: "event.subject",
+              type: "string",
+              description: t("luaApi.handler.items.emailSubject"),
+            },
+            {
+              name: "event.text",
+              type: "string",
+              description: t("luaApi.handler.items.emailText"),
+            },
+            {
+              name: "event.html",
+              type: "string",
+              description: t("luaApi.handler.items.emailHtml"),
+            },
+            {
+              name: "event.attachments",
+              type: "table",
+              description: t("luaApi.handler.items.emailAttachments"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.handler.groups.emailBounceEvent"),
+          items: [
+            {
+              name: "event.emailId",
+              type: "string",
+              description: t("luaApi.handler.items.bounceEmailId"),
+            },
+            {
+              name: "event.executionId",
+              type: "string",
+              description: t("luaApi.handler.items.bounceExecutionId"),
+            },
+            {
+              name: "event.to",
+              type: "table",
+              description: t("luaApi.handler.items.bounceTo"),
+            },
+            {
+              name: "event.bounceType",
+              type: "string",
+              description: t("luaApi.handler.items.bounceType"),
+            },
+            {
+              name: "event.message",
+              type: "string",
+              description: t("luaApi.handler.items.bounceMessage"),
+            },
           ],
         },
       ],
@@ -298,22 +413,22 @@ export function getLuaAPISections() {
           name: t("luaApi.io.groups.logging"),
           items: [
             {
-              name: "log.info(msg)",
+              name: "log.info(msg, fields?)",
               type: "function",
               description: t("luaApi.io.items.logInfo"),
             },
             {
-              name: "log.debug(msg)",
+              name: "log.debug(msg, fields?)",
               type: "function",
               description: t("luaApi.io.items.logDebug"),
             },
             {
-              name: "log.warn(msg)",
+              name: "log.warn(msg, fields?)",
               type: "function",
               description: t("luaApi.io.items.logWarn"),
             },
             {
-              name: "log.error(msg)",
+              name: "log.error(msg, fields?)",
               type: "function",
               description: t("luaApi.io.items.logError"),
             },
@@ -358,20 +473,55 @@ export function getLuaAPISections() {
               description: t("luaApi.io.items.httpGet"),
             },
             {
-              name: "http.post(url, body)",
+              name: "http.post(url, options)",
               type: "function",
               description: t("luaApi.io.items.httpPost"),
             },
             {
-              name: "http.put(url, body)",
+              name: "http.put(url, options)",
               type: "function",
               description: t("luaApi.io.items.httpPut"),
             },
+            {
+              name: "http.patch(url, options)",
+              type: "function",
+              description: t("luaApi.io.items.httpPatch"),
+            },
             {
               name: "http.delete(url)",
               type: "function",
               description: t("luaApi.io.items.httpDelete"),
             },
+            {
+              name: "http.head(url)",
+              type: "function",
+              description: t("luaApi.io.items.httpHead"),
+            },
+            {
+              name: "http.request(options)",
+              type: "function",
+              description: t("luaApi.io.items.httpRequest"),
+            },
+            {
+              name: "http.multipart(spec)",
+              type: "function",
+              description: t("luaApi.io.items.httpMultipart"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.io.groups.stream"),
+          items: [
+            {
+              name: "stream.start(options?)",
+              type: "function",
+              description: t("luaApi.io.items.streamStart"),
+            },
+            {
+              name: "stream.write(chunk)",
+              type: "function",
+              description: t("luaApi.io.items.streamWrite"),
+            },
           ],
         },
       ],
@@ -411,6 +561,46 @@ export function getLuaAPISections() {
             },
           ],
         },
+        {
+          name: t("luaApi.data.groups.csv"),
+          items: [
+            {
+              name: "csv.decode(str, options)",
+              type: "function",
+              description: t("luaApi.data.items.csvDecode"),
+            },
+            {
+              name: "csv.encode(rows, options)",
+              type: "function",
+              description: t("luaApi.data.items.csvEncode"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.data.groups.xml"),
+          items: [
+            {
+              name: "xml.decode(str)",
+              type: "function",
+              description: t("luaApi.data.items.xmlDecode"),
+            },
+            {
+              name: "xml.encode(element, options)",
+              type: "function",
+              description: t("luaApi.data.items.xmlEncode"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.data.groups.yaml"),
+          items: [
+            {
+              name: "yaml.decode(str)",
+              type: "function",
+              description: t("luaApi.data.items.yamlDecode"),
+            },
+          ],
+        },
         {
           name: t("luaApi.data.groups.crypto"),
           items: [
@@ -429,6 +619,36 @@ export function getLuaAPISections() {
               type: "function",
               description: t("luaApi.data.items.hmacSha256"),
             },
+            {
+              name: "crypto.aes_gcm_encrypt(plaintext, key)",
+              type: "function",
+              description: t("luaApi.data.items.aesGcmEncrypt"),
+            },
+            {
+              name: "crypto.aes_gcm_decrypt(ciphertext, key)",
+              type: "function",
+              description: t("luaApi.data.items.aesGcmDecrypt"),
+            },
+            {
+              name: "crypto.sign(alg, key, data)",
+              type: "function",
+              description: t("luaApi.data.items.cryptoSign"),
+            },
+            {
+              name: "crypto.verify(alg, key, data, sig)",
+              type: "function",
+              description: t("luaApi.data.items.cryptoVerify"),
+            },
+            {
+              name: "crypto.password_hash(password)",
+              type: "function",
+              description: t("luaApi.data.items.passwordHash"),
+            },
+            {
+              name: "crypto.password_verify(password, hash)",
+              type: "function",
+              description: t("luaApi.data.items.passwordVerify"),
+            },
             {
               name: "crypto.uuid()",
               type: "function",
@@ -436,6 +656,61 @@ export function getLuaAPISections() {
             },
           ],
         },
+        {
+          name: t("luaApi.data.groups.jwt"),
+          items: [
+            {
+              name: "jwt.sign(claims, key, options)",
+              type: "function",
+              description: t("luaApi.data.items.jwtSign"),
+            },
+            {
+              name: "jwt.verify(token, key, options)",
+              type: "function",
+              description: t("luaApi.data.items.jwtVerify"),
+            },
+            {
+              name: "jwt.decode(token)",
+              type: "function",
+              description: t("luaApi.data.items.jwtDecode"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.data.groups.webhook"),
+          items: [
+            {
+              name: "webhook.verifyStripe(event, secret)",
+              type: "function",
+              description: t("luaApi.data.items.webhookVerifyStripe"),
+            },
+            {
+              name: "webhook.verifyGithub(event, secret)",
+              type: "function",
+              description: t("luaApi.data.items.webhookVerifyGithub"),
+            },
+            {
+              name: "webhook.verifySlack(event, secret)",
+              type: "function",
+              description: t("luaApi.data.items.webhookVerifySlack"),
+            },
+            {
+              name: "webhook.verifyStandard(event, secret)",
+              type: "function",
+              description: t("luaApi.data.items.webhookVerifyStandard"),
+            },
+            {
+              name: "webhook.sign(payload, secret, options)",
+              type: "function",
+              description: t("luaApi.data.items.webhookSign"),
+            },
+            {
+              name: "webhook.compare(a, b)",
+              type: "function",
+              description: t("luaApi.data.items.webhookCompare"),
+            },
+          ],
+        },
       ],
     },
     {
@@ -466,6 +741,31 @@ export function getLuaAPISections() {
               type: "function",
               description: t("luaApi.utils.items.timeSleep"),
             },
+            {
+              name: "time.date(ts, zone)",
+              type: "function",
+              description: t("luaApi.utils.items.timeDate"),
+            },
+            {
+              name: "time.add(ts, duration, zone)",
+              type: "function",
+              description: t("luaApi.utils.items.timeAdd"),
+            },
+            {
+              name: "time.diff(a, b, unit)",
+              type: "function",
+              description: t("luaApi.utils.items.timeDiff"),
+            },
+            {
+              name: "time.truncate(ts, unit, zone)",
+              type: "function",
+              description: t("luaApi.utils.items.timeTruncate"),
+            },
+            {
+              name: "time.nextBusinessDay(ts, zone)",
+              type: "function",
+              description: t("luaApi.utils.items.timeNextBusinessDay"),
+            },
           ],
         },
         {
@@ -498,6 +798,51 @@ export function getLuaAPISections() {
             },
           ],
         },
+        {
+          name: t("luaApi.utils.groups.regex"),
+          items: [
+            {
+              name: "regex.match(pattern, str)",
+              type: "function",
+              description: t("luaApi.utils.items.regexMatch"),
+            },
+            {
+              name: "regex.find(pattern, str)",
+              type: "function",
+              description: t("luaApi.utils.items.regexFind"),
+            },
+            {
+              name: "regex.findAll(pattern, str)",
+              type: "function",
+              description: t("luaApi.utils.items.regexFindAll"),
+            },
+            {
+              name: "regex.replace(pattern, str, repl)",
+              type: "function",
+              description: t("luaApi.utils.items.regexReplace"),
+            },
+            {
+              name: "regex.split(pattern, str)",
+              type: "function",
+              description: t("luaApi.utils.items.regexSplit"),
+            },
+          ],
+        },
+        {
+          name: t("luaApi.utils.groups.template"),
+          items: [
+            {
+              name: "template.render(source, data, options)",
+              type: "function",
+              description: t("luaApi.utils.items.templateRender"),
+            },
+            {
+              name: "template.renderText(source, data, options)",
+              type: "function",
+              description: t("luaApi.utils.items.templateRenderText"),
+            },
+          ],
+        },
         {
           name: t("luaApi.utils.groups.random"),
           items: [
@@ -50,6 +50,12 @@ const API_DOCS = {
     snippet: "ctx.baseUrl",
     description: "Base URL of the server deployment",
   },
+  "ctx.traceId": {
+    signature: "ctx.traceId: string",
+    snippet: "ctx.traceId",
+    description:
+      "W3C trace ID of this execution (continues an incoming traceparent header)",
+  },
   "event.method": {
     signature: "event.method: string",
     snippet: "event.method",
@@ -75,25 +81,61 @@ const API_DOCS = {
     snippet: "event.query",
     description: "Query parameters (table with param name as key)",
   },
+  "event.recipient": {
+    signature: "event.recipient: string",
+    snippet: "event.recipient",
+    description: "Email events: address the inbound email was routed by",
+  },
+  "event.from": {
+    signature: "event.from: string",
+    snippet: "event.from",
+    description: "Email events: sender address",
+  },
+  "event.subject": {
+    signature: "event.subject: string",
+    snippet: "event.subject",
+    description: "Email events: decoded subject",
+  },
+  "event.text": {
+    signature: "event.text: string",
+    snippet: "event.text",
+    description: "Email events: plain text body",
+  },
+  "event.attachments": {
+    signature: "event.attachments: table",
+    snippet: "event.attachments",
+    description:
+      "Email events: files {filename, contentType, contentId, size, content} with base64 content",
+  },
+  "event.emailId": {
+    signature: "event.emailId: string",
+    snippet: "event.emailId",
+    description: "Email bounce events: ID returned by email.send",
+  },
+  "event.bounceType": {
+    signature: "event.bounceType: string",
+    snippet: "event.bounceType",
+    description: "Email bounce events: Permanent, Transient or Undetermined",
+  },
   "log.info": {
-    signature: "log.info(message: string)",
+    signature: "log.info(message: string, fields?: table)",
     snippet: 'log.info("${1:message}")',
-    description: "Log an informational message",
+    description: "Log an informational message, with optional structured fields",
   },
   "log.debug": {
-    signature: "log.debug(message: string)",
+    signature: "log.debug(message: string, fields?: table)",
     snippet: 'log.debug("${1:message}")',
-    description: "Log a debug message",
+    description: "Log a debug message, with optional structured fields",
   },
   "log.warn": {
-    signature: "log.warn(message: string)",
+    signature: "log.warn(message: string, fields?: table)",
     snippet: 'log.warn("${1:message}")',
-    description: "Log a warning message",
+    description: "Log a warning message, with optional structured fields",
   },
   "log.error": {
-    signature: "log.error(message: string)",
+    signature: "log.error(message: string, fields?: table)",
     snippet: 'log.error("${1:message}")',
-    description: "Log an error message",
+    description: "Log an error message, with optional structured fields",
   },
   "kv.get": {
     signature: "kv.get(key: string): string | nil",
@@ -137,6 +179,45 @@ const API_DOCS = {
     snippet: 'http.delete("${1:url}")',
     description: "Make a DELETE request.",
   },
+  "http.patch": {
+    signature:
+      "http.patch(url: string, options?: table): {statusCode, body, headers, json}",
+    snippet: 'http.patch("${1:url}", { json = ${2:{}} })',
+    description: "Make a PATCH request.",
+  },
+  "http.head": {
+    signature: "http.head(url: string, options?: table): {statusCode, headers}",
+    snippet: 'http.head("${1:url}")',
+    description: "Make a HEAD request. The response has no body.",
+  },
+  "http.multipart": {
+    signature:
+      "http.multipart({fields?: table, files?: table[]}): body, contentType",
+    snippet: "http.multipart({ fields = ${1:{}}, files = ${2:{}} })",
+    description:
+      "Builds a multipart/form-data body. Files are tables with field, filename, content and contentType.",
+  },
+  "stream.start": {
+    signature:
+      "stream.start(options?: {statusCode?: number, headers?: table}): boolean, error | nil",
+    snippet:
+      'stream.start({ statusCode = ${1:200}, headers = { ["Content-Type"] = "${2:text/event-stream}" } })',
+    description:
+      "Starts streaming the HTTP response: sends the status code and headers now. Content-Type defaults to text/plain.",
+  },
+  "stream.write": {
+    signature: "stream.write(chunk: string): boolean, error | nil",
+    snippet: 'stream.write("${1:chunk}")',
+    description:
+      "Sends a chunk of the response body to the client right away, starting a 200 response if needed. A body returned by the handler is sent as the last chunk.",
+  },
+  "http.request": {
+    signature:
+      "http.request(options: table): {statusCode, body, headers, json}",
+    snippet: 'http.request({ method = "${1:GET}", url = "${2:url}" })',
+    description:
+      "Make a request with any method. Accepts the same options as http.get plus method and url.",
+  },
   "json.encode": {
     signature: "json.encode(table: table): string",
     snippet: "json.encode(${1:table})",
@@ -157,6 +238,38 @@ const API_DOCS = {
     snippet: 'base64.decode("${1:base64String}")',
     description: "Decode a base64 string",
   },
+  "csv.decode": {
+    signature:
+      "csv.decode(str: string, options?: table): table | nil, error | nil",
+    snippet: "csv.decode(${1:event.body})",
+    description:
+      "Parses CSV into rows keyed by the header record. options: headers = false for lists of fields, delimiter.",
+  },
+  "csv.encode": {
+    signature:
+      "csv.encode(rows: table, options?: table): string | nil, error | nil",
+    snippet: 'csv.encode(${1:rows}, { headers = { "${2:id}", "${3:name}" } })',
+    description:
+      "Writes rows (lists or keyed tables) as CSV. options: headers (column order), delimiter.",
+  },
+  "xml.decode": {
+    signature: "xml.decode(str: string): table | nil, error | nil",
+    snippet: "xml.decode(${1:event.body})",
+    description:
+      "Parses XML into its root element: {name, namespace, attributes, children, text}",
+  },
+  "xml.encode": {
+    signature:
+      "xml.encode(element: table, options?: table): string | nil, error | nil",
+    snippet: 'xml.encode({ name = "${1:root}", children = { ${2} } })',
+    description:
+      "Writes an element table as XML. options: indent, declaration = true.",
+  },
+  "yaml.decode": {
+    signature: "yaml.decode(str: string): any, error | nil",
+    snippet: "yaml.decode(${1:str})",
+    description: "Decodes a YAML document to a Lua value",
+  },
   "crypto.md5": {
     signature: "crypto.md5(str: string): string",
     snippet: 'crypto.md5("${1:string}")',
@@ -192,33 +305,193 @@ const API_DOCS = {
     snippet: 'crypto.hmac_sha512("${1:message}", "${2:key}")',
     description: "Computes HMAC-SHA512 and returns hex-encoded result",
   },
+  "crypto.aes_gcm_encrypt": {
+    signature:
+      "crypto.aes_gcm_encrypt(plaintext: string, key: string, aad?: string): string | nil, error | nil",
+    snippet: 'crypto.aes_gcm_encrypt(${1:plaintext}, env.get("${2:ENCRYPTION_KEY}"))',
+    description:
+      "Encrypts with AES-GCM using a 16, 24 or 32 byte key. Returns base64 with the nonce prepended.",
+  },
+  "crypto.aes_gcm_decrypt": {
+    signature:
+      "crypto.aes_gcm_decrypt(ciphertext: string, key: string, aad?: string): string | nil, error | nil",
+    snippet: 'crypto.aes_gcm_decrypt(${1:ciphertext}, env.get("${2:ENCRYPTION_KEY}"))',
+    description: "Decrypts and authenticates the output of aes_gcm_encrypt",
+  },
+  "crypto.sign": {
+    signature:
+      "crypto.sign(alg: string, privateKey: string, data: string): string | nil, error | nil",
+    snippet: 'crypto.sign("${1:RS256}", env.get("${2:PRIVATE_KEY}"), ${3:data})',
+    description:
+      "Signs data with a PEM or JWK key and returns a base64 signature. Supports RS*, PS*, ES*, EdDSA and HS*.",
+  },
+  "crypto.verify": {
+    signature:
+      "crypto.verify(alg: string, publicKey: string, data: string, signature: string): boolean, error | nil",
+    snippet: 'crypto.verify("${1:RS256}", ${2:publicKey}, ${3:data}, ${4:signature})',
+    description:
+      "Verifies a base64 signature with a PEM key, certificate, JWK or JWKS",
+  },
+  "crypto.password_hash": {
+    signature:
+      "crypto.password_hash(password: string, options?: table): string | nil, error | nil",
+    snippet: "crypto.password_hash(${1:password})",
+    description:
+      'Hashes a password with argon2id, or bcrypt with {algorithm = "bcrypt", cost = 10}',
+  },
+  "crypto.password_verify": {
+    signature:
+      "crypto.password_verify(password: string, hash: string): boolean, error | nil",
+    snippet: "crypto.password_verify(${1:password}, ${2:hash})",
+    description: "Checks a password against an argon2id or bcrypt hash",
+  },
   "crypto.uuid": {
     signature: "crypto.uuid(): string",
     snippet: "crypto.uuid()",
     description: "Generates a new UUID v4 (36 characters)",
   },
+  "jwt.sign": {
+    signature:
+      "jwt.sign(claims: table, key: string, options?: table): string | nil, error | nil",
+    snippet: 'jwt.sign({ sub = ${1:userId} }, env.get("${2:JWT_SECRET}"), { expires_in = ${3:3600} })',
+    description:
+      'Signs claims as a JWT. options: alg (default "HS256"), kid, expires_in (seconds), header.',
+  },
+  "jwt.verify": {
+    signature:
+      "jwt.verify(token: string, key: string, options?: table): table | nil, error | nil",
+    snippet: 'jwt.verify(${1:token}, env.get("${2:JWT_SECRET}"))',
+    description:
+      "Verifies the signature, exp, nbf, aud and iss of a JWT and returns its claims. The key can be a secret, PEM, JWK or JWKS. options: algorithms, audience, issuer, leeway.",
+  },
+  "jwt.decode": {
+    signature: "jwt.decode(token: string): table | nil, error | nil",
+    snippet: "jwt.decode(${1:token})",
+    description:
+      "Decodes a JWT into {header, claims} without verifying the signature",
+  },
+  "webhook.verifyStripe": {
+    signature:
+      "webhook.verifyStripe(event: table, secret: string, options?: table): boolean, error | nil",
+    snippet: 'webhook.verifyStripe(event, env.get("${1:STRIPE_WEBHOOK_SECRET}"))',
+    description:
+      "Verifies the Stripe-Signature header of an event. options.tolerance sets the maximum age in seconds (default: 300).",
+  },
+  "webhook.verifyGithub": {
+    signature:
+      "webhook.verifyGithub(event: table, secret: string): boolean, error | nil",
+    snippet: 'webhook.verifyGithub(event, env.get("${1:GITHUB_WEBHOOK_SECRET}"))',
+    description: "Verifies the X-Hub-Signature-256 header of an event.",
+  },
+  "webhook.verifySlack": {
+    signature:
+      "webhook.verifySlack(event: table, secret: string, options?: table): boolean, error | nil",
+    snippet: 'webhook.verifySlack(event, env.get("${1:SLACK_SIGNING_SECRET}"))',
+    description:
+      "Verifies the X-Slack-Signature and X-Slack-Request-Timestamp headers of an event.",
+  },
+  "webhook.verifyStandard": {
+    signature:
+      "webhook.verifyStandard(event: table, secret: string, options?: table): boolean, error | nil",
+    snippet: 'webhook.verifyStandard(event, env.get("${1:WEBHOOK_SECRET}"))',
+    description:
+      "Verifies Standard Webhooks headers (webhook-id, webhook-timestamp, webhook-signature). The secret is base64, optionally prefixed with whsec_.",
+  },
+  "webhook.sign": {
+    signature:
+      "webhook.sign(payload: string, secret: string, options?: table): table | nil, error | nil",
+    snippet: 'webhook.sign(${1:body}, env.get("${2:WEBHOOK_SECRET}"))',
+    description:
+      'Signs a payload and returns the headers to send. options.scheme is "standard" (default), "stripe", "github" or "slack".',
+  },
+  "webhook.compare": {
+    signature: "webhook.compare(a: string, b: string): boolean",
+    snippet: "webhook.compare(${1:a}, ${2:b})",
+    description: "Compares two strings in constant time",
+  },
   "time.now": {
     signature: "time.now(): number",
     snippet: "time.now()",
     description: "Returns current Unix timestamp in seconds",
   },
   "time.format": {
-    signature: "time.format(timestamp: number, layout: string): string",
+    signature:
+      "time.format(timestamp: number, layout: string, zone?: string): string",
     snippet: 'time.format(${1:timestamp}, "${2:2006-01-02 15:04:05}")',
     description:
-      'Formats Unix timestamp to string using Go time layout (e.g., "2006-01-02 15:04:05")',
+      'Formats Unix timestamp to string using Go time layout (e.g., "2006-01-02 15:04:05"), optionally in an IANA time zone',
   },
   "time.parse": {
     signature:
-      "time.parse(timeStr: string, layout: string): number | nil, error | nil",
+      "time.parse(timeStr: string, layout: string, zone?: string): number | nil, error | nil",
     snippet: 'time.parse("${1:timeStr}", "${2:2006-01-02 15:04:05}")',
-    description: "Parses time string using layout",
+    description:
+      "Parses time string using layout. Times without an offset are read in zone (default UTC).",
   },
   "time.sleep": {
     signature: "time.sleep(milliseconds: number)",
     snippet: "time.sleep(${1:milliseconds})",
     description: "Sleeps for specified milliseconds",
   },
+  "time.date": {
+    signature: "time.date(timestamp: number, zone?: string): table",
+    snippet: 'time.date(${1:timestamp}, "${2:America/New_York}")',
+    description:
+      "Returns {year, month, day, hour, min, sec, wday, yday, isdst, zone, offset} in a time zone",
+  },
+  "time.fromDate": {
+    signature: "time.fromDate(date: table, zone?: string): number",
+    snippet:
+      'time.fromDate({ year = ${1:2024}, month = ${2:1}, day = ${3:1}, hour = ${4:9} }, "${5:America/New_York}")',
+    description: "Converts calendar fields in a time zone to a Unix timestamp",
+  },
+  "time.add": {
+    signature:
+      "time.add(timestamp: number, duration: number | string | table, zone?: string): number",
+    snippet: 'time.add(${1:timestamp}, "${2:P1D}", "${3:UTC}")',
+    description:
+      'Adds seconds, an ISO 8601 ("P1DT2H") or Go ("90m") duration, or {years, months, weeks, days, hours, minutes, seconds}. Calendar parts follow the wall clock in the zone.',
+  },
+  "time.sub": {
+    signature:
+      "time.sub(timestamp: number, duration: number | string | table, zone?: string): number",
+    snippet: 'time.sub(${1:timestamp}, "${2:P1D}", "${3:UTC}")',
+    description: "Subtracts a duration from a timestamp, see time.add",
+  },
+  "time.diff": {
+    signature:
+      "time.diff(a: number, b: number, unit?: string, zone?: string): number",
+    snippet: 'time.diff(${1:a}, ${2:b}, "${3:days}")',
+    description:
+      "Returns a - b in seconds, minutes, hours, or calendar days or weeks in the zone",
+  },
+  "time.truncate": {
+    signature:
+      "time.truncate(timestamp: number, unit: string, zone?: string): number",
+    snippet: 'time.truncate(${1:timestamp}, "${2:day}", "${3:UTC}")',
+    description:
+      "Returns the start of the minute, hour, day, week (Monday), month or year in the zone",
+  },
+  "time.parseDuration": {
+    signature: "time.parseDuration(str: string): table | nil, error | nil",
+    snippet: 'time.parseDuration("${1:PT1H30M}")',
+    description:
+      "Parses an ISO 8601 or Go duration into {years, months, days, hours, minutes, seconds, total}",
+  },
+  "time.isBusinessDay": {
+    signature:
+      "time.isBusinessDay(timestamp: number, zone?: string, options?: table): boolean",
+    snippet: 'time.isBusinessDay(${1:timestamp}, "${2:UTC}")',
+    description:
+      'Reports whether the day is not a weekend or holiday. options: weekend = {1, 7}, holidays = {"2024-12-25"}',
+  },
+  "time.nextBusinessDay": {
+    signature:
+      "time.nextBusinessDay(timestamp: number, zone?: string, options?: table): number",
+    snippet: 'time.nextBusinessDay(${1:timestamp}, "${2:UTC}")',
+    description:
+      "Returns the same local time on the next business day. options: weekend, holidays",
+  },
   "url.parse": {
     signature: "url.parse(urlStr: string): table | nil, error | nil",
     snippet: 'url.parse("${1:url}")',
@@ -235,6 +508,18 @@ const API_DOCS = {
     snippet: 'url.decode("${1:encodedString}")',
     description: "URL-decodes a string",
   },
+  "url.encodeForm": {
+    signature: "url.encodeForm(values: table): string",
+    snippet: "url.encodeForm(${1:values})",
+    description:
+      "Encodes a table as an application/x-www-form-urlencoded string. Arrays become repeated keys.",
+  },
+  "url.decodeForm": {
+    signature: "url.decodeForm(body: string): table | nil, error | nil",
+    snippet: "url.decodeForm(${1:body})",
+    description:
+      "Decodes an application/x-www-form-urlencoded string. Repeated keys become arrays.",
+  },
   "strings.trim": {
     signature: "strings.trim(str: string): string",
     snippet: 'strings.trim("${1:string}")',
@@ -296,6 +581,53 @@ const API_DOCS = {
     snippet: 'strings.repeat("${1:string}", ${2:n})',
     description: "Repeats string n times",
   },
+  "regex.match": {
+    signature: "regex.match(pattern: string, str: string): boolean",
+    snippet: 'regex.match("${1:pattern}", ${2:str})',
+    description: "Reports whether str contains a match of the Go (RE2) pattern",
+  },
+  "regex.find": {
+    signature: "regex.find(pattern: string, str: string): table | nil",
+    snippet: 'regex.find("${1:pattern}", ${2:str})',
+    description:
+      "Returns the first match as {match, start, stop, groups, named}, or nil",
+  },
+  "regex.findAll": {
+    signature: "regex.findAll(pattern: string, str: string, n?: number): table",
+    snippet: 'regex.findAll("${1:pattern}", ${2:str})',
+    description: "Returns all matches (at most n) as match tables",
+  },
+  "regex.replace": {
+    signature:
+      "regex.replace(pattern: string, str: string, replacement: string | function): string",
+    snippet: 'regex.replace("${1:pattern}", ${2:str}, "${3:replacement}")',
+    description:
+      "Replaces all matches. $1 and ${name} expand capture groups; a function receives the match table.",
+  },
+  "regex.split": {
+    signature: "regex.split(pattern: string, str: string, n?: number): table",
+    snippet: 'regex.split("${1:pattern}", ${2:str})',
+    description: "Splits str around matches of the pattern",
+  },
+  "regex.escape": {
+    signature: "regex.escape(str: string): string",
+    snippet: "regex.escape(${1:str})",
+    description: "Escapes regular expression metacharacters",
+  },
+  "template.render": {
+    signature:
+      "template.render(source: string, data?: table, options?: table): string | nil, error | nil",
+    snippet: "template.render(${1:source}, { ${2:name} = ${3:value} })",
+    description:
+      "Renders a Go html/template with data, escaping values automatically. options.partials maps names to templates used with {{template \"name\" .}}.",
+  },
+  "template.renderText": {
+    signature:
+      "template.renderText(source: string, data?: table, options?: table): string | nil, error | nil",
+    snippet: "template.renderText(${1:source}, { ${2:name} = ${3:value} })",
+    description:
+      "Renders a Go text/template without escaping, e.g. for plain text emails. Accepts options.partials.",
+  },
   "random.int": {
     signature: "random.int(min: number, max: number): number",
     snippet: "random.int(${1:min}, ${2:max})",
@@ -336,7 +668,52 @@ const API_DOCS = {
 \t}
 })`,
     description:
-      "Send chat completion request to AI provider (openai or anthropic). Returns {content, model, usage}.",
+      "Send chat completion request to AI provider (openai, anthropic, gemini, ollama or an OpenAI-compatible profile). Message content may be a list of parts: strings and {type = image or document, data (base64) and media_type, or url}. Accepts tools ({name, description, parameters}) and a JSON schema for structured replies (returned decoded as data). stream = true sends the reply text to on_token(token), or to the streamed response, as it is generated. cache = true (or {ttl = seconds}) reuses identical replies. Returns {content, model, stop_reason, tool_calls, message, usage, cached}.",
+  },
+  "ai.run": {
+    signature: "ai.run(options: table): table | nil, error | nil",
+    snippet: `ai.run({
+\tprovider = "\${1:openai}",
+\tmodel = "\${2:gpt-4o-mini}",
+\tmessages = {
+\t\t{role = "user", content = "\${3:Hello}"}
+\t},
+\ttools = {
+\t\t{
+\t\t\tname = "\${4:get_weather}",
+\t\t\tdescription = "\${5:Current weather for a city}",
+\t\t\tparameters = {type = "object", properties = {city = {type = "string"}}},
+\t\t\thandler = function(args)
+\t\t\t\t\${6:return "ok"}
+\t\t\tend
+\t\t}
+\t}
+})`,
+    description:
+      "Chat with tools, calling each tool's handler(args, call) and sending the results back until the model answers. Returns the final response with messages, rounds and total usage.",
+  },
+  "ai.embed": {
+    signature: "ai.embed(texts: string | table, options: table): table | nil, error | nil",
+    snippet: 'ai.embed(${1:texts}, {provider = "${2:openai}", model = "${3:text-embedding-3-small}"})',
+    description:
+      "Compute embeddings for a string or a list of strings (openai, ollama or an OpenAI-compatible profile). Returns {embeddings, model, usage}.",
+  },
+  "vectors.upsert": {
+    signature: "vectors.upsert(collection: string, records: table): boolean, error | nil",
+    snippet: 'vectors.upsert("${1:collection}", {{id = "${2:id}", vector = ${3:vector}, metadata = {}}})',
+    description:
+      "Insert or replace records {id, vector, metadata} in a per-function vector collection",
+  },
+  "vectors.query": {
+    signature: "vectors.query(collection: string, vector: table, options?: table): table | nil, error | nil",
+    snippet: 'vectors.query("${1:collection}", ${2:vector}, {top_k = ${3:5}})',
+    description:
+      "Find the most similar records by cosine similarity. Options: top_k, filter (metadata values), min_score. Returns a list of {id, score, metadata}.",
+  },
+  "vectors.delete": {
+    signature: "vectors.delete(collection: string, ids: string | table): boolean, error | nil",
+    snippet: 'vectors.delete("${1:collection}", {"${2:id}"})',
+    description: "Delete records from a vector collection by ID",
   },
   "email.send": {
     signature: "email.send(options: table): table | nil, error | nil",
@@ -347,7 +724,7 @@ const API_DOCS = {
 \ttext = "\${4:Message body}"
 })`,
     description:
-      "Send email via Resend. Requires RESEND_API_KEY env var. scheduled_at accepts Unix timestamp or ISO 8601 string. Returns {id}.",
+      "Send email via Resend (RESEND_API_KEY env var) or SMTP (EMAIL_PROVIDER=smtp, SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, SMTP_TLS). template = name and data render a stored template. attachments = {{filename, content (base64) or url, content_type, content_id}}; content_id shows a file inline as cid:<content_id>. scheduled_at (Resend only) accepts Unix timestamp or ISO 8601 string. Returns {id}.",
   },
 };
 
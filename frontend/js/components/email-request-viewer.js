@@ -27,6 +27,30 @@ import { t } from "../i18n/index.js";
  */
 const MAX_JSON_DISPLAY_LENGTH = 5000;
 
+/**
+ * Delivery event types with a translated label. Other types reported by the
+ * provider are shown as they are.
+ * @type {Set<string>}
+ */
+const DELIVERY_EVENT_TYPES = new Set([
+  "scheduled",
+  "sent",
+  "delivered",
+  "delivery_delayed",
+  "opened",
+  "clicked",
+  "bounced",
+  "complained",
+  "failed",
+]);
+
+/**
+ * Delivery event types meaning the email did not reach the recipient or was
+ * unwanted.
+ * @type {Set<string>}
+ */
+const FAILED_DELIVERY_TYPES = new Set(["bounced", "complained", "failed"]);
+
 /**
  * Email Request viewer component for displaying email API requests in a table with expandable details.
  * @type {Object}
@@ -50,6 +74,35 @@ export const EmailRequestViewer = {
     }
   },
 
+  /**
+   * Returns the label of a delivery event type.
+   * @param {string} type - Delivery event type
+   * @returns {string} Label
+   */
+  deliveryLabel(type) {
+    return DELIVERY_EVENT_TYPES.has(type)
+      ? t(`emailRequestViewer.delivery.${type}`)
+      : type;
+  },
+
+  /**
+   * Returns the badge variant of a delivery event type.
+   * @param {string} type - Delivery event type
+   * @returns {string} Badge variant
+   */
+  deliveryVariant(type) {
+    if (FAILED_DELIVERY_TYPES.has(type)) {
+      return BadgeVariant.DESTRUCTIVE;
+    }
+    if (type === "delivery_delayed") {
+      return BadgeVariant.WARNING;
+    }
+    if (type === "scheduled" || type === "sent") {
+      return BadgeVariant.SECONDARY;
+    }
+    return BadgeVariant.SUCCESS;
+  },
+
   /**
    * Formats JSON string for display with optional truncation.
    * @param {string} jsonStr - JSON string
@@ -128,12 +181,12 @@ export const EmailRequestViewer = {
               m(TableHead, { style: "width: 2rem;" }, ""),
               m(
                 TableHead,
-                { style: "width: 25%;" },
+                { style: "width: 22%;" },
                 t("emailRequestViewer.to"),
               ),
               m(
                 TableHead,
-                { style: "width: 25%;" },
+                { style: "width: 22%;" },
                 t("emailRequestViewer.subject"),
               ),
               m(
@@ -141,6 +194,11 @@ export const EmailRequestViewer = {
                 { style: "width: 10%;" },
                 t("emailRequestViewer.status"),
               ),
+              m(
+                TableHead,
+                { style: "width: 12%;" },
+                t("emailRequestViewer.deliveryStatus"),
+              ),
               m(
                 TableHead,
                 { style: "width: 10%;" },
@@ -148,12 +206,12 @@ export const EmailRequestViewer = {
               ),
               m(
                 TableHead,
-                { style: "width: 15%;" },
+                { style: "width: 10%;" },
                 t("emailRequestViewer.duration"),
               ),
               m(
                 TableHead,
-                { style: "width: 15%;" },
+                { style: "width: 14%;" },
                 t("emailRequestViewer.time"),
               ),
             ]),
@@ -240,6 +298,20 @@ export const EmailRequestViewer = {
             ),
           ]),
 
+          // Delivery status reported by the provider
+          m(TableCell, [
+            req.delivery_status
+              ? m(
+                Badge,
+                {
+                  variant: this.deliveryVariant(req.delivery_status),
+                  size: BadgeSize.SM,
+                },
+                this.deliveryLabel(req.delivery_status),
+              )
+              : "-",
+          ]),
+
           // Type (HTML/Text)
           m(TableCell, [
             m(
@@ -280,7 +352,7 @@ export const EmailRequestViewer = {
           "tr.email-request-viewer__expanded-row",
           { key: req.id + "-expanded" },
           [
-            m("td", { colspan: 7 }, [
+            m("td", { colspan: 8 }, [
               m(".email-request-viewer__content", [
                 // Error message if present
                 req.error_message
@@ -311,6 +383,34 @@ export const EmailRequestViewer = {
                     : null,
                 ]),
 
+                // Delivery timeline
+                req.delivery_events && req.delivery_events.length > 0
+                  ? m(".email-request-viewer__timeline", [
+                    m("strong", t("emailRequestViewer.deliveryEvents")),
+                    req.delivery_events.map((event) =>
+                      m(
+                        ".email-request-viewer__timeline-event",
+                        { key: event.id },
+                        [
+                          m(
+                            "span.email-request-viewer__timeline-time",
+                            formatUnixTimestamp(event.occurred_at),
+                          ),
+                          m(
+                            Badge,
+                            {
+                              variant: this.deliveryVariant(event.type),
+                              size: BadgeSize.SM,
+                            },
+                            this.deliveryLabel(event.type),
+                          ),
+                          event.detail ? m("span", event.detail) : null,
+                        ],
+                      )
+                    ),
+                  ])
+                  : null,
+
                 // Request/Response panels
                 m(
                   ".email-request-viewer__panels",
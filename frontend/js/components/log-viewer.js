@@ -10,8 +10,20 @@ import { t } from "../i18n/index.js";
  * @property {string} [timestamp] - Formatted timestamp
  * @property {string} [level] - Log level (INFO, WARN, ERROR, DEBUG)
  * @property {string} message - Log message content
+ * @property {Object<string, *>} [fields] - Structured fields
+ * @property {number} [line] - Source line of the log call
  */
 
+/**
+ * Formats a structured field value for display.
+ * Strings are shown as-is, everything else as JSON.
+ * @param {*} value - Field value
+ * @returns {string} Display text
+ */
+export function formatLogField(value) {
+  return typeof value === "string" ? value : JSON.stringify(value);
+}
+
 /**
  * Log viewer component for displaying execution logs with color-coded levels.
  * @type {Object}
@@ -57,7 +69,25 @@ export const LogViewer = {
                   },
                   (log.level || "INFO").toUpperCase(),
                 ),
-                m("span.log-viewer__message", log.message),
+                m("span.log-viewer__message", [
+                  log.message,
+                  log.fields && Object.keys(log.fields).length > 0 &&
+                  m(
+                    "span.log-viewer__fields",
+                    Object.entries(log.fields).map(([key, value]) =>
+                      m("span.log-viewer__field", { key }, [
+                        m("span.log-viewer__field-key", `${key}=`),
+                        formatLogField(value),
+                      ])
+                    ),
+                  ),
+                ]),
+                log.line > 0 &&
+                m(
+                  "span.log-viewer__line",
+                  { title: t("logViewer.line", { line: log.line }) },
+                  `:${log.line}`,
+                ),
               ],
             )
           ),
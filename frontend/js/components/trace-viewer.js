@@ -0,0 +1,249 @@
+/**
+ * @fileoverview Trace viewer component rendering execution spans as a waterfall.
+ */
+
+import { icons } from "../icons.js";
+import { Badge, BadgeSize, BadgeVariant } from "./badge.js";
+import { t } from "../i18n/index.js";
+
+/**
+ * @typedef {import('../types.js').Span} Span
+ */
+
+/**
+ * Minimum bar width as a percentage, so very short spans stay visible.
+ * @type {number}
+ */
+const MIN_BAR_WIDTH = 0.5;
+
+/**
+ * Bar end position (percentage) after which the duration label is drawn before the bar.
+ * @type {number}
+ */
+const LABEL_FLIP_THRESHOLD = 85;
+
+/**
+ * Formats a duration in nanoseconds for display.
+ * @param {number} ns - Duration in nanoseconds
+ * @returns {string} Human readable duration (e.g. "850µs", "12.4ms", "1.20s")
+ */
+export function formatSpanDuration(ns) {
+  if (ns < 1e6) {
+    return `${Math.round(ns / 1e3)}µs`;
+  }
+  if (ns < 1e9) {
+    return `${(ns / 1e6).toFixed(ns < 1e7 ? 2 : 1)}ms`;
+  }
+  return `${(ns / 1e9).toFixed(2)}s`;
+}
+
+/**
+ * Positions a duration label after its bar, or before or inside it when the bar
+ * ends near the right edge of the timeline.
+ * @param {number} left - Bar start as a percentage of the timeline
+ * @param {number} width - Bar width as a percentage of the timeline
+ * @returns {Object} Mithril attributes for the label
+ */
+function durationLabelAttrs(left, width) {
+  const end = left + width;
+  if (end <= LABEL_FLIP_THRESHOLD) {
+    return { style: `left: ${end}%` };
+  }
+  if (left >= 100 - LABEL_FLIP_THRESHOLD) {
+    return {
+      class: "trace-viewer__duration--before",
+      style: `right: ${100 - left}%`,
+    };
+  }
+  return {
+    class: "trace-viewer__duration--inside",
+    style: `right: ${100 - end}%`,
+  };
+}
+
+/**
+ * Orders spans depth-first so children follow their parent.
+ * Spans whose parent is not part of the trace (e.g. a remote caller) are roots.
+ * @param {Span[]} spans - Spans of a single trace
+ * @returns {Array<{span: Span, depth: number}>} Ordered spans with their depth
+ */
+export function buildSpanTree(spans) {
+  const ids = new Set(spans.map((s) => s.id));
+  const children = new Map();
+  const roots = [];
+
+  const byStart = [...spans].sort((a, b) => a.start_time - b.start_time);
+  for (const span of byStart) {
+    if (span.parent_id && ids.has(span.parent_id)) {
+      if (!children.has(span.parent_id)) {
+        children.set(span.parent_id, []);
+      }
+      children.get(span.parent_id).push(span);
+    } else {
+      roots.push(span);
+    }
+  }
+
+  const ordered = [];
+  const visit = (span, depth) => {
+    ordered.push({ span, depth });
+    for (const child of children.get(span.id) || []) {
+      visit(child, depth + 1);
+    }
+  };
+  roots.forEach((root) => visit(root, 0));
+  return ordered;
+}
+
+/**
+ * Trace viewer component displaying spans as a timing waterfall with expandable attributes.
+ * @type {Object}
+ */
+export const TraceViewer = {
+  /**
+   * Track which spans are expanded.
+   * @type {Set<string>}
+   */
+  expandedRows: new Set(),
+
+  /**
+   * Toggles expansion state for a span.
+   * @param {string} id - Span ID
+   */
+  toggleRow(id) {
+    if (this.expandedRows.has(id)) {
+      this.expandedRows.delete(id);
+    } else {
+      this.expandedRows.add(id);
+    }
+  },
+
+  /**
+   * Renders the trace viewer component.
+   * @param {Object} vnode - Mithril vnode
+   * @param {Object} vnode.attrs - Component attributes
+   * @param {Span[]} [vnode.attrs.spans=[]] - Spans of the trace
+   * @param {string} [vnode.attrs.maxHeight='400px'] - Maximum height
+   * @param {boolean} [vnode.attrs.noBorder=false] - Remove border styling
+   * @returns {Object} Mithril vnode
+   */
+  view(vnode) {
+    const { spans = [], maxHeight = "400px", noBorder = false } = vnode.attrs;
+
+    if (spans.length === 0) {
+      return m(".trace-viewer__empty", t("traceViewer.noSpans"));
+    }
+
+    const traceStart = Math.min(...spans.map((s) => s.start_time));
+    const traceEnd = Math.max(...spans.map((s) => s.end_time));
+    const total = Math.max(traceEnd - traceStart, 1);
+
+    return m(
+      ".trace-viewer",
+      {
+        class: noBorder ? "trace-viewer--no-border" : "",
+        style: maxHeight ? `max-height: ${maxHeight}; overflow-y: auto` : "",
+      },
+      [
+        m(".trace-viewer__header", [
+          m(".trace-viewer__name", t("traceViewer.span")),
+          m(".trace-viewer__timeline", [
+            m("span", "0ms"),
+            m("span", formatSpanDuration(total)),
+          ]),
+        ]),
+        buildSpanTree(spans).map(({ span, depth }) =>
+          this.renderSpan(span, depth, traceStart, total)
+        ),
+      ],
+    );
+  },
+
+  /**
+   * Renders a single span row with its waterfall bar and optional details.
+   * @param {Span} span - The span
+   * @param {number} depth - Nesting depth in the tree
+   * @param {number} traceStart - Trace start time in nanoseconds
+   * @param {number} total - Trace duration in nanoseconds
+   * @returns {Object} Mithril vnode
+   */
+  renderSpan(span, depth, traceStart, total) {
+    const isExpanded = this.expandedRows.has(span.id);
+    const duration = span.end_time - span.start_time;
+    const left = ((span.start_time - traceStart) / total) * 100;
+    const width = Math.max((duration / total) * 100, MIN_BAR_WIDTH);
+    const isError = span.status === "error";
+    const attributes = Object.entries(span.attributes || {}).sort(([a], [b]) =>
+      a.localeCompare(b)
+    );
+
+    return m(".trace-viewer__span", { key: span.id }, [
+      m(
+        ".trace-viewer__row",
+        {
+          class: isExpanded ? "trace-viewer__row--expanded" : "",
+          onclick: () => this.toggleRow(span.id),
+        },
+        [
+          m(
+            ".trace-viewer__name",
+            { style: `padding-left: ${depth}rem` },
+            [
+              m(
+                ".trace-viewer__chevron",
+                {
+                  class: isExpanded ? "trace-viewer__chevron--expanded" : "",
+                },
+                m.trust(icons.chevronRight()),
+              ),
+              m("span.trace-viewer__label", span.name),
+            ],
+          ),
+          m(".trace-viewer__timeline", [
+            m(".trace-viewer__bar", {
+              class: `trace-viewer__bar--${span.kind}` +
+                (isError ? " trace-viewer__bar--error" : ""),
+              style: `left: ${left}%; width: ${width}%`,
+              title: formatSpanDuration(duration),
+            }),
+            m(
+              "span.trace-viewer__duration",
+              durationLabelAttrs(left, width),
+              formatSpanDuration(duration),
+            ),
+          ]),
+        ],
+      ),
+      isExpanded &&
+      m(".trace-viewer__details", [
+        span.error_message &&
+        m(".trace-viewer__error", [
+          m("strong", t("traceViewer.error") + ": "),
+          span.error_message,
+        ]),
+        m(".trace-viewer__meta", [
+          m(Badge, {
+            variant: isError
+              ? BadgeVariant.DESTRUCTIVE
+              : BadgeVariant.SUCCESS,
+            size: BadgeSize.SM,
+          }, span.status),
+          m(
+            Badge,
+            { variant: BadgeVariant.SECONDARY, size: BadgeSize.SM },
+            span.kind,
+          ),
+          m("code", `${t("traceViewer.spanId")}: ${span.id}`),
+        ]),
+        attributes.length > 0 &&
+        m(
+          "dl.trace-viewer__attributes",
+          attributes.flatMap(([key, value]) => [
+            m("dt", key),
+            m("dd", value),
+          ]),
+        ),
+      ]),
+    ]);
+  },
+};
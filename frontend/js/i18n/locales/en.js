@@ -120,6 +120,18 @@ export default {
     network: "Network & Triggers",
     invocationUrl: "Invocation URL",
     supportedMethods: "Supported Methods",
+    networkPolicy: "Outbound Network",
+    networkPolicyHelp: "Where http.* calls may connect",
+    allowPrivateNetworks: "Allow private networks",
+    allowPrivateNetworksHelp:
+      "Private, loopback and link-local addresses (e.g. 10.0.0.0/8, 127.0.0.1, 169.254.169.254) are blocked unless enabled.",
+    allowHosts: "Allowed Hosts",
+    allowHostsHelp:
+      "One per line: hostnames, *.wildcards, IPs or CIDRs. When set, only these hosts can be reached. Only IPs and CIDRs allow private addresses.",
+    denyHosts: "Denied Hosts",
+    denyHostsHelp: "Always blocked, even if allowed above.",
+    allowPorts: "Allowed Ports",
+    denyPorts: "Denied Ports",
     functionStatus: "Function Status",
     enableFunction: "Enable Function",
     disableWarning:
@@ -273,6 +285,10 @@ export default {
     aiRequestsCount: "{{count}} API calls",
     emailRequests: "Email Requests",
     emailsSent: "{{count}} emails sent",
+    httpRequests: "HTTP Requests",
+    httpRequestsCount: "{{count}} outbound requests",
+    trace: "Trace",
+    spansCount: "{{count}} spans",
     executionLogs: "Execution Logs",
     logEntries: "{{count}} log entries",
   },
@@ -317,11 +333,25 @@ export default {
     time: "Time",
     in: "in",
     out: "out",
+    cached: "cached",
     error: "Error",
     endpoint: "Endpoint",
     request: "Request",
     response: "Response",
     truncated: "... (truncated)",
+    deliveryStatus: "Delivery",
+    deliveryEvents: "Delivery timeline",
+    delivery: {
+      scheduled: "Scheduled",
+      sent: "Sent",
+      delivered: "Delivered",
+      delivery_delayed: "Delayed",
+      opened: "Opened",
+      clicked: "Clicked",
+      bounced: "Bounced",
+      complained: "Complained",
+      failed: "Failed",
+    },
   },
 
   // API Reference
@@ -360,6 +390,32 @@ export default {
     truncated: "... (truncated)",
   },
 
+  // HTTP request viewer
+  httpRequestViewer: {
+    noRequests: "No HTTP requests recorded for this execution.",
+    method: "Method",
+    url: "URL",
+    status: "Status",
+    size: "Size",
+    duration: "Duration",
+    time: "Time",
+    error: "Error",
+    request: "Request",
+    response: "Response",
+    headers: "Headers",
+    body: "Body",
+    noHeaders: "(no headers)",
+    truncated: "... (truncated)",
+  },
+
+  // Trace viewer
+  traceViewer: {
+    noSpans: "No trace recorded for this execution.",
+    span: "Span",
+    spanId: "Span ID",
+    error: "Error",
+  },
+
   // Form
   form: {
     showPassword: "Show password",
@@ -371,6 +427,7 @@ export default {
   // Log viewer
   logViewer: {
     noLogs: "No logs available",
+    line: "Line {{line}}",
   },
 
   // Table
@@ -390,14 +447,21 @@ export default {
     ai: {
       name: "AI",
       description: "AI provider integrations",
-      groups: { chat: "Chat (ai)" },
-      items: { chat: "Chat completion with OpenAI or Anthropic" },
+      groups: { chat: "Chat (ai)", vectors: "Embeddings & Vectors" },
+      items: {
+        chat: "Chat completion with OpenAI, Anthropic, Gemini, Ollama or OpenAI-compatible APIs, with optional tools, JSON schema output and response caching",
+        run: "Chat with tools, running Lua handlers until the model answers",
+        embed: "Compute embeddings with OpenAI, Ollama or OpenAI-compatible APIs",
+        vectorsUpsert: "Insert or replace records {id, vector, metadata} in a collection",
+        vectorsQuery: "Find the most similar records by cosine similarity, with metadata filters",
+        vectorsDelete: "Delete records from a collection by ID",
+      },
     },
     email: {
       name: "Email",
-      description: "Email sending via Resend",
+      description: "Email sending via Resend or SMTP",
       groups: { send: "Send (email)" },
-      items: { send: "Send email via Resend API" },
+      items: { send: "Send email via Resend or SMTP" },
     },
     handler: {
       name: "Handler",
@@ -405,6 +469,8 @@ export default {
       groups: {
         context: "Context (ctx)",
         event: "Event (event)",
+        emailEvent: "Email Event (event)",
+        emailBounceEvent: "Email Bounce Event (event)",
       },
       items: {
         executionId: "Unique execution identifier",
@@ -414,11 +480,26 @@ export default {
         requestId: "HTTP request identifier",
         startedAt: "Start timestamp (Unix)",
         baseUrl: "Server base URL",
+        traceId: "W3C trace ID of the execution",
         method: "HTTP method (GET, POST, etc.)",
         path: "Request path",
         body: "Request body as string",
         headers: "Request headers table",
         query: "Query parameters table",
+        form: "Form fields (urlencoded or multipart)",
+        files: "Uploaded files (multipart)",
+        emailRecipient: "Address the email was routed by",
+        emailFrom: "Sender address",
+        emailTo: "Recipient addresses (also event.cc)",
+        emailSubject: "Decoded subject",
+        emailText: "Plain text body",
+        emailHtml: "HTML body",
+        emailAttachments: "Files {filename, contentType, contentId, size, content}",
+        bounceEmailId: "ID returned by email.send",
+        bounceExecutionId: "Execution that sent the email",
+        bounceTo: "Recipient addresses (also event.from, event.subject)",
+        bounceType: "Permanent, Transient or Undetermined (also event.bounceSubType)",
+        bounceMessage: "Bounce message",
       },
     },
     io: {
@@ -429,6 +510,7 @@ export default {
         kv: "Key-Value Store (kv)",
         env: "Environment (env)",
         http: "HTTP Client (http)",
+        stream: "Streaming Response (stream)",
       },
       items: {
         logInfo: "Log info message",
@@ -443,6 +525,12 @@ export default {
         httpPost: "POST request",
         httpPut: "PUT request",
         httpDelete: "DELETE request",
+        httpPatch: "PATCH request",
+        httpHead: "HEAD request",
+        httpRequest: "Request with any method",
+        httpMultipart: "Build a multipart/form-data body",
+        streamStart: "Send the status code and headers, starting a streamed response",
+        streamWrite: "Send a chunk of the response body right away",
       },
     },
     data: {
@@ -451,17 +539,42 @@ export default {
       groups: {
         json: "JSON (json)",
         base64: "Base64 (base64)",
+        csv: "CSV (csv)",
+        xml: "XML (xml)",
+        yaml: "YAML (yaml)",
         crypto: "Crypto (crypto)",
+        jwt: "JSON Web Tokens (jwt)",
+        webhook: "Webhooks (webhook)",
       },
       items: {
         jsonEncode: "Encode table to JSON",
         jsonDecode: "Decode JSON to table",
         base64Encode: "Encode to base64",
         base64Decode: "Decode from base64",
+        csvDecode: "Parse CSV into rows",
+        csvEncode: "Write rows as CSV",
+        xmlDecode: "Parse XML into elements",
+        xmlEncode: "Write elements as XML",
+        yamlDecode: "Decode YAML to table",
         md5: "MD5 hash (hex)",
         sha256: "SHA256 hash (hex)",
         hmacSha256: "HMAC-SHA256 (hex)",
+        aesGcmEncrypt: "Encrypt with AES-GCM (base64)",
+        aesGcmDecrypt: "Decrypt AES-GCM ciphertext",
+        cryptoSign: "Sign with a PEM or JWK key",
+        cryptoVerify: "Verify a signature",
+        passwordHash: "Hash a password (argon2id or bcrypt)",
+        passwordVerify: "Check a password against a hash",
         uuid: "Generate UUID v4",
+        jwtSign: "Sign claims as a JWT",
+        jwtVerify: "Verify a JWT and return its claims",
+        jwtDecode: "Decode a JWT without verifying",
+        webhookVerifyStripe: "Verify a Stripe signature",
+        webhookVerifyGithub: "Verify a GitHub signature",
+        webhookVerifySlack: "Verify a Slack signature",
+        webhookVerifyStandard: "Verify a Standard Webhooks signature",
+        webhookSign: "Sign an outgoing payload",
+        webhookCompare: "Constant-time comparison",
       },
     },
     utils: {
@@ -470,6 +583,8 @@ export default {
       groups: {
         time: "Time (time)",
         strings: "Strings (strings)",
+        regex: "Regex (regex)",
+        template: "Templates (template)",
         random: "Random (random)",
       },
       items: {
@@ -477,11 +592,23 @@ export default {
         timeFormat: "Format timestamp",
         timeParse: "Parse time string",
         timeSleep: "Sleep milliseconds",
+        timeDate: "Calendar fields in a time zone",
+        timeAdd: "Add a duration (DST-aware)",
+        timeDiff: "Difference in a unit",
+        timeTruncate: "Start of day, week or month",
+        timeNextBusinessDay: "Next business day",
         trim: "Trim whitespace",
         split: "Split by separator",
         join: "Join with separator",
         contains: "Contains substring",
         replace: "Replace in string",
+        regexMatch: "Test for a match",
+        regexFind: "First match with groups",
+        regexFindAll: "All matches",
+        regexReplace: "Replace with captures",
+        regexSplit: "Split by pattern",
+        templateRender: "Render an HTML template (escaped)",
+        templateRenderText: "Render a plain text template",
         randomInt: "Random integer",
         randomFloat: "Random float 0.0-1.0",
         randomString: "Random alphanumeric",
@@ -122,6 +122,18 @@ export default {
     network: "Endpoint",
     invocationUrl: "URL de Invocação",
     supportedMethods: "Métodos Suportados",
+    networkPolicy: "Rede de Saída",
+    networkPolicyHelp: "Onde as chamadas http.* podem se conectar",
+    allowPrivateNetworks: "Permitir redes privadas",
+    allowPrivateNetworksHelp:
+      "Endereços privados, de loopback e link-local (ex.: 10.0.0.0/8, 127.0.0.1, 169.254.169.254) são bloqueados, a menos que habilitado.",
+    allowHosts: "Hosts Permitidos",
+    allowHostsHelp:
+      "Um por linha: hostnames, *.curingas, IPs ou CIDRs. Quando definido, apenas esses hosts podem ser acessados. Apenas IPs e CIDRs permitem endereços privados.",
+    denyHosts: "Hosts Bloqueados",
+    denyHostsHelp: "Sempre bloqueados, mesmo se permitidos acima.",
+    allowPorts: "Portas Permitidas",
+    denyPorts: "Portas Bloqueadas",
     functionStatus: "Status da Função",
     enableFunction: "Habilitar Função",
     disableWarning:
@@ -275,6 +287,10 @@ export default {
     aiRequestsCount: "{{count}} chamadas de API",
     emailRequests: "Requisições de Email",
     emailsSent: "{{count}} emails enviados",
+    httpRequests: "Requisições HTTP",
+    httpRequestsCount: "{{count}} requisições de saída",
+    trace: "Trace",
+    spansCount: "{{count}} spans",
     executionLogs: "Logs de Execução",
     logEntries: "{{count}} entradas de log",
   },
@@ -320,11 +336,25 @@ export default {
     time: "Hora",
     in: "entrada",
     out: "saída",
+    cached: "cache",
     error: "Erro",
     endpoint: "Endpoint",
     request: "Requisição",
     response: "Resposta",
     truncated: "... (truncado)",
+    deliveryStatus: "Entrega",
+    deliveryEvents: "Histórico de entrega",
+    delivery: {
+      scheduled: "Agendado",
+      sent: "Enviado",
+      delivered: "Entregue",
+      delivery_delayed: "Atrasado",
+      opened: "Aberto",
+      clicked: "Clicado",
+      bounced: "Devolvido",
+      complained: "Denunciado",
+      failed: "Falhou",
+    },
   },
 
   // API Reference
@@ -363,6 +393,32 @@ export default {
     truncated: "... (truncado)",
   },
 
+  // HTTP request viewer
+  httpRequestViewer: {
+    noRequests: "Nenhuma requisição HTTP registrada para esta execução.",
+    method: "Método",
+    url: "URL",
+    status: "Status",
+    size: "Tamanho",
+    duration: "Duração",
+    time: "Hora",
+    error: "Erro",
+    request: "Requisição",
+    response: "Resposta",
+    headers: "Cabeçalhos",
+    body: "Corpo",
+    noHeaders: "(sem cabeçalhos)",
+    truncated: "... (truncado)",
+  },
+
+  // Trace viewer
+  traceViewer: {
+    noSpans: "Nenhum trace registrado para esta execução.",
+    span: "Span",
+    spanId: "ID do Span",
+    error: "Erro",
+  },
+
   // Form
   form: {
     showPassword: "Mostrar senha",
@@ -374,6 +430,7 @@ export default {
   // Log viewer
   logViewer: {
     noLogs: "Nenhum log disponível",
+    line: "Linha {{line}}",
   },
 
   // Table
@@ -393,14 +450,21 @@ export default {
     ai: {
       name: "IA",
       description: "Integrações com provedores de IA",
-      groups: { chat: "Chat (IA)" },
-      items: { chat: "Chat com OpenAI ou Anthropic" },
+      groups: { chat: "Chat (IA)", vectors: "Embeddings e Vetores" },
+      items: {
+        chat: "Chat com OpenAI, Anthropic, Gemini, Ollama ou APIs compatíveis com OpenAI, com ferramentas opcionais, saída em JSON schema e cache de respostas",
+        run: "Chat com ferramentas, executando handlers Lua até o modelo responder",
+        embed: "Gera embeddings com OpenAI, Ollama ou APIs compatíveis com OpenAI",
+        vectorsUpsert: "Insere ou substitui registros {id, vector, metadata} em uma coleção",
+        vectorsQuery: "Busca os registros mais similares por similaridade de cosseno, com filtros de metadados",
+        vectorsDelete: "Remove registros de uma coleção por ID",
+      },
     },
     email: {
       name: "Email",
-      description: "Envio de email via Resend",
+      description: "Envio de email via Resend ou SMTP",
       groups: { send: "Enviar (email)" },
-      items: { send: "Enviar email via API Resend" },
+      items: { send: "Enviar email via Resend ou SMTP" },
     },
     handler: {
       name: "Handler",
@@ -408,6 +472,8 @@ export default {
       groups: {
         context: "Contexto (ctx)",
         event: "Evento (event)",
+        emailEvent: "Evento de Email (event)",
+        emailBounceEvent: "Evento de Devolução de Email (event)",
       },
       items: {
         executionId: "Identificador único da execução",
@@ -417,11 +483,26 @@ export default {
         requestId: "Identificador da requisição HTTP",
         startedAt: "Timestamp de início (Unix)",
         baseUrl: "URL base do servidor",
+        traceId: "ID de trace W3C da execução",
         method: "Método HTTP (GET, POST, etc.)",
         path: "Caminho da requisição",
         body: "Corpo da requisição como string",
         headers: "Tabela de cabeçalhos da requisição",
         query: "Tabela de parâmetros de query",
+        form: "Campos do formulário (urlencoded ou multipart)",
+        files: "Arquivos enviados (multipart)",
+        emailRecipient: "Endereço pelo qual o email foi roteado",
+        emailFrom: "Endereço do remetente",
+        emailTo: "Endereços dos destinatários (também event.cc)",
+        emailSubject: "Assunto decodificado",
+        emailText: "Corpo em texto simples",
+        emailHtml: "Corpo em HTML",
+        emailAttachments: "Arquivos {filename, contentType, contentId, size, content}",
+        bounceEmailId: "ID retornado por email.send",
+        bounceExecutionId: "Execução que enviou o email",
+        bounceTo: "Endereços dos destinatários (também event.from, event.subject)",
+        bounceType: "Permanent, Transient ou Undetermined (também event.bounceSubType)",
+        bounceMessage: "Mensagem de devolução",
       },
     },
     io: {
@@ -432,6 +513,7 @@ export default {
         kv: "Armazenamento Chave-Valor (kv)",
         env: "Ambiente (env)",
         http: "Cliente HTTP (http)",
+        stream: "Resposta em Streaming (stream)",
       },
       items: {
         logInfo: "Registrar mensagem de info",
@@ -446,6 +528,12 @@ export default {
         httpPost: "Requisição POST",
         httpPut: "Requisição PUT",
         httpDelete: "Requisição DELETE",
+        httpPatch: "Requisição PATCH",
+        httpHead: "Requisição HEAD",
+        httpRequest: "Requisição com qualquer método",
+        httpMultipart: "Monta um corpo multipart/form-data",
+        streamStart: "Envia o status e os cabeçalhos, iniciando uma resposta em streaming",
+        streamWrite: "Envia um trecho do corpo da resposta imediatamente",
       },
     },
     data: {
@@ -454,17 +542,42 @@ export default {
       groups: {
         json: "JSON (json)",
         base64: "Base64 (base64)",
+        csv: "CSV (csv)",
+        xml: "XML (xml)",
+        yaml: "YAML (yaml)",
         crypto: "Criptografia (crypto)",
+        jwt: "JSON Web Tokens (jwt)",
+        webhook: "Webhooks (webhook)",
       },
       items: {
         jsonEncode: "Codificar tabela para JSON",
         jsonDecode: "Decodificar JSON para tabela",
         base64Encode: "Codificar para base64",
         base64Decode: "Decodificar de base64",
+        csvDecode: "Converte CSV em linhas",
+        csvEncode: "Escreve linhas como CSV",
+        xmlDecode: "Converte XML em elementos",
+        xmlEncode: "Escreve elementos como XML",
+        yamlDecode: "Decodificar YAML para tabela",
         md5: "Hash MD5 (hex)",
         sha256: "Hash SHA256 (hex)",
         hmacSha256: "HMAC-SHA256 (hex)",
+        aesGcmEncrypt: "Criptografa com AES-GCM (base64)",
+        aesGcmDecrypt: "Descriptografa um texto AES-GCM",
+        cryptoSign: "Assina com uma chave PEM ou JWK",
+        cryptoVerify: "Verifica uma assinatura",
+        passwordHash: "Gera o hash de uma senha (argon2id ou bcrypt)",
+        passwordVerify: "Confere uma senha com um hash",
         uuid: "Gerar UUID v4",
+        jwtSign: "Assina claims como um JWT",
+        jwtVerify: "Verifica um JWT e retorna as claims",
+        jwtDecode: "Decodifica um JWT sem verificar",
+        webhookVerifyStripe: "Verifica uma assinatura do Stripe",
+        webhookVerifyGithub: "Verifica uma assinatura do GitHub",
+        webhookVerifySlack: "Verifica uma assinatura do Slack",
+        webhookVerifyStandard: "Verifica uma assinatura Standard Webhooks",
+        webhookSign: "Assina um payload de saída",
+        webhookCompare: "Comparação em tempo constante",
       },
     },
     utils: {
@@ -473,6 +586,8 @@ export default {
       groups: {
         time: "Tempo (time)",
         strings: "Strings (strings)",
+        regex: "Regex (regex)",
+        template: "Templates (template)",
         random: "Aleatório (random)",
       },
       items: {
@@ -480,11 +595,23 @@ export default {
         timeFormat: "Formatar timestamp",
         timeParse: "Converter texto em data",
         timeSleep: "Pausar milissegundos",
+        timeDate: "Campos de data em um fuso horário",
+        timeAdd: "Soma uma duração (considera horário de verão)",
+        timeDiff: "Diferença em uma unidade",
+        timeTruncate: "Início do dia, semana ou mês",
+        timeNextBusinessDay: "Próximo dia útil",
         trim: "Remover espaços",
         split: "Dividir por separador",
         join: "Juntar com separador",
         contains: "Verificar se contém texto",
         replace: "Substituir na string",
+        regexMatch: "Testa se há correspondência",
+        regexFind: "Primeira correspondência com grupos",
+        regexFindAll: "Todas as correspondências",
+        regexReplace: "Substitui com capturas",
+        regexSplit: "Divide por padrão",
+        templateRender: "Renderiza um template HTML (com escape)",
+        templateRenderText: "Renderiza um template de texto",
         randomInt: "Inteiro aleatório",
         randomFloat: "Float aleatório 0.0-1.0",
         randomString: "String alfanumérica aleatória",
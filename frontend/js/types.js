@@ -31,10 +31,20 @@
  * @property {boolean} disabled - Whether function is disabled
  * @property {FunctionVersion} active_version - Currently active version
  * @property {Object.<string, string>} [env_vars] - Environment variables
+ * @property {NetworkPolicy} [network_policy] - Outbound network policy
  * @property {string} created_at - ISO timestamp
  * @property {string} updated_at - ISO timestamp
  */
 
+/**
+ * @typedef {Object} NetworkPolicy
+ * @property {boolean} allow_private_networks - Allow private, loopback and link-local addresses
+ * @property {string[]} [allow_hosts] - Allowed hostnames, *.wildcards, IPs or CIDRs
+ * @property {string[]} [deny_hosts] - Denied hostnames, *.wildcards, IPs or CIDRs
+ * @property {number[]} [allow_ports] - Allowed destination ports
+ * @property {number[]} [deny_ports] - Denied destination ports
+ */
+
 /**
  * @typedef {Object} FunctionsListResponse
  * @property {LunarFunction[]} functions - List of functions
@@ -71,6 +81,8 @@
  * @property {string} execution_id - Parent execution ID
  * @property {string} level - Log level (INFO, WARN, ERROR, DEBUG)
  * @property {string} message - Log message
+ * @property {Object<string, *>} [fields] - Structured fields passed to log.*
+ * @property {number} [line] - Lua source line of the log call
  * @property {string} timestamp - ISO timestamp
  */
 
@@ -84,7 +96,7 @@
  * @typedef {Object} AIRequest
  * @property {string} id - AI request ID
  * @property {string} execution_id - Parent execution ID
- * @property {string} provider - AI provider (openai, anthropic)
+ * @property {string} provider - AI provider (openai, anthropic, gemini, ollama or profile name)
  * @property {string} model - Model name
  * @property {string} endpoint - API endpoint
  * @property {string} request_json - Request JSON
@@ -93,6 +105,8 @@
  * @property {string} [error_message] - Error message if failed
  * @property {number} [input_tokens] - Input token count
  * @property {number} [output_tokens] - Output token count
+ * @property {number} [cost] - Cost in USD, absent when the model has no price
+ * @property {boolean} cached - Whether the response came from the cache
  * @property {number} duration_ms - Duration in milliseconds
  * @property {number} created_at - Unix timestamp
  */
@@ -119,6 +133,16 @@
  * @property {string} [email_id] - Email ID from provider
  * @property {number} duration_ms - Duration in milliseconds
  * @property {number} created_at - Unix timestamp
+ * @property {string} [delivery_status] - Latest delivery event type reported by the provider
+ * @property {EmailDeliveryEvent[]} [delivery_events] - Delivery timeline
+ */
+
+/**
+ * @typedef {Object} EmailDeliveryEvent
+ * @property {string} id - Webhook message ID
+ * @property {string} type - Event type (delivered, bounced, complained...)
+ * @property {string} [detail] - Bounce message, failure reason or clicked link
+ * @property {number} occurred_at - Unix timestamp
  */
 
 /**
@@ -127,6 +151,51 @@
  * @property {Pagination} pagination - Pagination info
  */
 
+/**
+ * @typedef {Object} HTTPRequest
+ * @property {string} id - HTTP request ID
+ * @property {string} execution_id - Parent execution ID
+ * @property {string} method - HTTP method
+ * @property {string} url - Request URL with sensitive query parameters masked
+ * @property {Object<string, string>} request_headers - Request headers (masked)
+ * @property {string} [request_body] - Request body (masked, truncated)
+ * @property {number} [status_code] - Response status code, absent if no response
+ * @property {Object<string, string>} [response_headers] - Response headers (masked)
+ * @property {string} [response_body] - Response body (masked, truncated)
+ * @property {number} response_size - Response body size in bytes
+ * @property {string} status - Status (success, error)
+ * @property {string} [error_message] - Error message if failed
+ * @property {number} duration_ms - Duration in milliseconds
+ * @property {number} created_at - Unix timestamp
+ */
+
+/**
+ * @typedef {Object} HTTPRequestsResponse
+ * @property {HTTPRequest[]} http_requests - List of HTTP requests
+ * @property {Pagination} pagination - Pagination info
+ */
+
+/**
+ * @typedef {Object} Span
+ * @property {string} id - Span ID (16 hex characters)
+ * @property {string} trace_id - Trace ID (32 hex characters)
+ * @property {string} [parent_id] - Parent span ID
+ * @property {string} execution_id - Parent execution ID
+ * @property {string} name - Span name (e.g. "http.get", "kv.set")
+ * @property {string} kind - Span kind (server, client, internal)
+ * @property {string} status - Status (ok, error)
+ * @property {string} [error_message] - Error message if failed
+ * @property {Object<string, string>} attributes - Span attributes
+ * @property {number} start_time - Start time in Unix nanoseconds
+ * @property {number} end_time - End time in Unix nanoseconds
+ */
+
+/**
+ * @typedef {Object} TraceResponse
+ * @property {string} trace_id - Trace ID
+ * @property {Span[]} spans - Spans of the trace
+ */
+
 /**
  * @typedef {Object} DiffResponse
  * @property {string} diff - Unified diff string
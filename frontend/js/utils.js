@@ -76,3 +76,17 @@ export const formatUnixTimestamp = (timestamp, format = "datetime") => {
       return date.toLocaleString(locale);
   }
 };
+
+/**
+ * Splits text into a list of trimmed, non-empty entries separated by
+ * newlines or commas.
+ * @param {string} text - Text to split
+ * @returns {string[]} List entries
+ * @example
+ * splitList("a.com, b.com\n10.0.0.0/8"); // ["a.com", "b.com", "10.0.0.0/8"]
+ */
+export const splitList = (text) =>
+  (text || "")
+    .split(/[\n,]/)
+    .map((entry) => entry.trim())
+    .filter((entry) => entry !== "");
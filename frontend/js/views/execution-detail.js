@@ -25,6 +25,8 @@ import { LogViewer } from "../components/log-viewer.js";
 import { CodeViewer } from "../components/code-viewer.js";
 import { AIRequestViewer } from "../components/ai-request-viewer.js";
 import { EmailRequestViewer } from "../components/email-request-viewer.js";
+import { HTTPRequestViewer } from "../components/http-request-viewer.js";
+import { TraceViewer } from "../components/trace-viewer.js";
 import { t } from "../i18n/index.js";
 
 /**
@@ -33,6 +35,8 @@ import { t } from "../i18n/index.js";
  * @typedef {import('../types.js').ExecutionLog} ExecutionLog
  * @typedef {import('../types.js').AIRequest} AIRequest
  * @typedef {import('../types.js').EmailRequest} EmailRequest
+ * @typedef {import('../types.js').HTTPRequest} HTTPRequest
+ * @typedef {import('../types.js').Span} Span
  */
 
 /**
@@ -131,6 +135,36 @@ export const ExecutionDetail = {
    */
   emailRequestsTotal: 0,
 
+  /**
+   * Outbound HTTP requests for this execution.
+   * @type {HTTPRequest[]}
+   */
+  httpRequests: [],
+
+  /**
+   * Number of HTTP requests per page.
+   * @type {number}
+   */
+  httpRequestsLimit: 20,
+
+  /**
+   * Current HTTP requests pagination offset.
+   * @type {number}
+   */
+  httpRequestsOffset: 0,
+
+  /**
+   * Total number of HTTP requests.
+   * @type {number}
+   */
+  httpRequestsTotal: 0,
+
+  /**
+   * Trace spans for this execution.
+   * @type {Span[]}
+   */
+  spans: [],
+
   /**
    * Initializes the view and loads execution data.
    * @param {Object} vnode - Mithril vnode
@@ -147,25 +181,37 @@ export const ExecutionDetail = {
   loadExecution: async (id) => {
     ExecutionDetail.loading = true;
     try {
-      const [execution, logsData, aiRequestsData, emailRequestsData] =
-        await Promise.all([
-          API.executions.get(id),
-          API.executions.getLogs(
-            id,
-            ExecutionDetail.logsLimit,
-            ExecutionDetail.logsOffset,
-          ),
-          API.executions.getAIRequests(
-            id,
-            ExecutionDetail.aiRequestsLimit,
-            ExecutionDetail.aiRequestsOffset,
-          ),
-          API.executions.getEmailRequests(
-            id,
-            ExecutionDetail.emailRequestsLimit,
-            ExecutionDetail.emailRequestsOffset,
-          ),
-        ]);
+      const [
+        execution,
+        logsData,
+        aiRequestsData,
+        emailRequestsData,
+        httpRequestsData,
+        traceData,
+      ] = await Promise.all([
+        API.executions.get(id),
+        API.executions.getLogs(
+          id,
+          ExecutionDetail.logsLimit,
+          ExecutionDetail.logsOffset,
+        ),
+        API.executions.getAIRequests(
+          id,
+          ExecutionDetail.aiRequestsLimit,
+          ExecutionDetail.aiRequestsOffset,
+        ),
+        API.executions.getEmailRequests(
+          id,
+          ExecutionDetail.emailRequestsLimit,
+          ExecutionDetail.emailRequestsOffset,
+        ),
+        API.executions.getHTTPRequests(
+          id,
+          ExecutionDetail.httpRequestsLimit,
+          ExecutionDetail.httpRequestsOffset,
+        ),
+        API.executions.getTrace(id).catch(() => ({ spans: [] })),
+      ]);
       ExecutionDetail.execution = execution;
       ExecutionDetail.logs = logsData.logs || [];
       ExecutionDetail.logsTotal = logsData.pagination?.total || 0;
@@ -174,6 +220,10 @@ export const ExecutionDetail = {
       ExecutionDetail.emailRequests = emailRequestsData.email_requests || [];
       ExecutionDetail.emailRequestsTotal =
         emailRequestsData.pagination?.total || 0;
+      ExecutionDetail.httpRequests = httpRequestsData.http_requests || [];
+      ExecutionDetail.httpRequestsTotal =
+        httpRequestsData.pagination?.total || 0;
+      ExecutionDetail.spans = traceData.spans || [];
 
       // Load function details
       ExecutionDetail.func = await API.functions.get(execution.function_id);
@@ -299,6 +349,44 @@ export const ExecutionDetail = {
     ExecutionDetail.loadEmailRequests();
   },
 
+  /**
+   * Reloads HTTP requests with current pagination.
+   * @returns {Promise<void>}
+   */
+  loadHTTPRequests: async () => {
+    try {
+      const data = await API.executions.getHTTPRequests(
+        ExecutionDetail.execution.id,
+        ExecutionDetail.httpRequestsLimit,
+        ExecutionDetail.httpRequestsOffset,
+      );
+      ExecutionDetail.httpRequests = data.http_requests || [];
+      ExecutionDetail.httpRequestsTotal = data.pagination?.total || 0;
+      m.redraw();
+    } catch (e) {
+      console.error("Failed to load HTTP requests:", e);
+    }
+  },
+
+  /**
+   * Handles page change from HTTP requests pagination.
+   * @param {number} newOffset - New pagination offset
+   */
+  handleHTTPRequestsPageChange: (newOffset) => {
+    ExecutionDetail.httpRequestsOffset = newOffset;
+    ExecutionDetail.loadHTTPRequests();
+  },
+
+  /**
+   * Handles limit change from HTTP requests pagination.
+   * @param {number} newLimit - New items per page limit
+   */
+  handleHTTPRequestsLimitChange: (newLimit) => {
+    ExecutionDetail.httpRequestsLimit = newLimit;
+    ExecutionDetail.httpRequestsOffset = 0;
+    ExecutionDetail.loadHTTPRequests();
+  },
+
   /**
    * Renders the execution detail view.
    * @returns {Object} Mithril vnode
@@ -451,6 +539,25 @@ export const ExecutionDetail = {
           ]),
         ]),
 
+        // Trace
+        ExecutionDetail.spans.length > 0 &&
+        m(Card, { style: "margin-bottom: 1.5rem" }, [
+          m(CardHeader, {
+            title: t("execution.trace"),
+            subtitle: t("execution.spansCount", {
+              count: ExecutionDetail.spans.length,
+            }),
+            icon: "clock",
+          }),
+          m(CardContent, { noPadding: true }, [
+            m(TraceViewer, {
+              spans: ExecutionDetail.spans,
+              maxHeight: "400px",
+              noBorder: true,
+            }),
+          ]),
+        ]),
+
         // AI Requests
         ExecutionDetail.aiRequestsTotal > 0 &&
         m(Card, { style: "margin-bottom: 1.5rem" }, [
@@ -506,6 +613,34 @@ export const ExecutionDetail = {
           }),
         ]),
 
+        // HTTP Requests
+        ExecutionDetail.httpRequestsTotal > 0 &&
+        m(Card, { style: "margin-bottom: 1.5rem" }, [
+          m(CardHeader, {
+            title: t("execution.httpRequests"),
+            subtitle: t("execution.httpRequestsCount", {
+              count: ExecutionDetail.httpRequestsTotal,
+            }),
+            icon: "globe",
+          }),
+          m(CardContent, { noPadding: true }, [
+            m(HTTPRequestViewer, {
+              requests: ExecutionDetail.httpRequests,
+              maxHeight: "400px",
+              noBorder: true,
+            }),
+          ]),
+          ExecutionDetail.httpRequestsTotal >
+            ExecutionDetail.httpRequestsLimit &&
+          m(Pagination, {
+            total: ExecutionDetail.httpRequestsTotal,
+            limit: ExecutionDetail.httpRequestsLimit,
+            offset: ExecutionDetail.httpRequestsOffset,
+            onPageChange: ExecutionDetail.handleHTTPRequestsPageChange,
+            onLimitChange: ExecutionDetail.handleHTTPRequestsLimitChange,
+          }),
+        ]),
+
         // Execution Logs
         m(Card, [
           m(CardHeader, {
@@ -22,7 +22,7 @@ import {
   StatusBadge,
 } from "../components/badge.js";
 import { TabContent, Tabs } from "../components/tabs.js";
-import { getFunctionTabs } from "../utils.js";
+import { getFunctionTabs, splitList } from "../utils.js";
 import { paths, routes } from "../routes.js";
 import {
   CopyInput,
@@ -37,6 +37,16 @@ import { EnvEditor } from "../components/env-editor.js";
 
 /**
  * @typedef {import('../types.js').LunarFunction} LunarFunction
+ * @typedef {import('../types.js').NetworkPolicy} NetworkPolicy
+ */
+
+/**
+ * @typedef {Object} NetworkPolicyForm
+ * @property {boolean} allow_private_networks - Allow private network addresses
+ * @property {string} allow_hosts - Allowed hosts, one per line
+ * @property {string} deny_hosts - Denied hosts, one per line
+ * @property {string} allow_ports - Allowed ports, comma-separated
+ * @property {string} deny_ports - Denied ports, comma-separated
  */
 
 /**
@@ -89,6 +99,12 @@ export const FunctionSettings = {
    */
   editedRetentionDays: null,
 
+  /**
+   * Edited network policy form (null if unchanged).
+   * @type {NetworkPolicyForm|null}
+   */
+  editedNetworkPolicy: null,
+
   /**
    * Array of environment variables with edit state.
    * @type {EnvVar[]}
@@ -110,6 +126,7 @@ export const FunctionSettings = {
     FunctionSettings.editedDescription = null;
     FunctionSettings.editedDisabled = null;
     FunctionSettings.editedRetentionDays = null;
+    FunctionSettings.editedNetworkPolicy = null;
     FunctionSettings.envVars = [];
     FunctionSettings.envErrors = {};
     FunctionSettings.loadFunction(vnode.attrs.id);
@@ -128,6 +145,7 @@ export const FunctionSettings = {
       FunctionSettings.editedDescription = null;
       FunctionSettings.editedDisabled = null;
       FunctionSettings.editedRetentionDays = null;
+      FunctionSettings.editedNetworkPolicy = null;
       FunctionSettings.envVars = Object.entries(
         FunctionSettings.func.env_vars || {},
       ).map(([key, value]) => ({
@@ -223,6 +241,62 @@ export const FunctionSettings = {
     }
   },
 
+  /**
+   * Returns the network policy form, starting from the saved policy.
+   * @returns {NetworkPolicyForm} Network policy form values
+   */
+  networkPolicyForm: () => {
+    if (FunctionSettings.editedNetworkPolicy !== null) {
+      return FunctionSettings.editedNetworkPolicy;
+    }
+    const policy = FunctionSettings.func.network_policy || {};
+    return {
+      allow_private_networks: policy.allow_private_networks || false,
+      allow_hosts: (policy.allow_hosts || []).join("\n"),
+      deny_hosts: (policy.deny_hosts || []).join("\n"),
+      allow_ports: (policy.allow_ports || []).join(", "),
+      deny_ports: (policy.deny_ports || []).join(", "),
+    };
+  },
+
+  /**
+   * Updates a network policy form field.
+   * @param {keyof NetworkPolicyForm} field - Field name
+   * @param {string|boolean} value - New value
+   */
+  setNetworkPolicyField: (field, value) => {
+    FunctionSettings.editedNetworkPolicy = {
+      ...FunctionSettings.networkPolicyForm(),
+      [field]: value,
+    };
+  },
+
+  /**
+   * Saves the outbound network policy to the API.
+   * @returns {Promise<void>}
+   */
+  saveNetworkPolicy: async () => {
+    const form = FunctionSettings.editedNetworkPolicy;
+    if (form === null) return;
+
+    /** @type {NetworkPolicy} */
+    const network_policy = {
+      allow_private_networks: form.allow_private_networks,
+      allow_hosts: splitList(form.allow_hosts),
+      deny_hosts: splitList(form.deny_hosts),
+      allow_ports: splitList(form.allow_ports).map(Number),
+      deny_ports: splitList(form.deny_ports).map(Number),
+    };
+
+    try {
+      await API.functions.update(FunctionSettings.func.id, { network_policy });
+      Toast.show(t("toast.settingsSaved"), "success");
+      await FunctionSettings.loadFunction(FunctionSettings.func.id);
+    } catch (e) {
+      Toast.show(t("toast.failedToSave") + ": " + e.message, "error");
+    }
+  },
+
   /**
    * Deletes the function after confirmation.
    * @returns {Promise<void>}
@@ -498,6 +572,96 @@ export const FunctionSettings = {
             ]),
           ]),
 
+          // Outbound Network Policy
+          (() => {
+            const form = FunctionSettings.networkPolicyForm();
+            return m(Card, { style: "margin-bottom: 1.5rem" }, [
+              m(CardHeader, {
+                title: t("settings.networkPolicy"),
+                subtitle: t("settings.networkPolicyHelp"),
+              }),
+              m(CardContent, [
+                m(FormGroup, [
+                  m(FormCheckbox, {
+                    id: "allow-private-networks",
+                    label: t("settings.allowPrivateNetworks"),
+                    description: t("settings.allowPrivateNetworksHelp"),
+                    checked: form.allow_private_networks,
+                    onchange: (e) =>
+                      FunctionSettings.setNetworkPolicyField(
+                        "allow_private_networks",
+                        e.target.checked,
+                      ),
+                  }),
+                ]),
+                m(FormGroup, [
+                  m(FormLabel, { text: t("settings.allowHosts") }),
+                  m(FormTextarea, {
+                    value: form.allow_hosts,
+                    rows: 3,
+                    placeholder: "api.example.com\n*.example.org\n10.0.0.0/8",
+                    oninput: (e) =>
+                      FunctionSettings.setNetworkPolicyField(
+                        "allow_hosts",
+                        e.target.value,
+                      ),
+                  }),
+                  m(FormHelp, { text: t("settings.allowHostsHelp") }),
+                ]),
+                m(FormGroup, [
+                  m(FormLabel, { text: t("settings.denyHosts") }),
+                  m(FormTextarea, {
+                    value: form.deny_hosts,
+                    rows: 3,
+                    oninput: (e) =>
+                      FunctionSettings.setNetworkPolicyField(
+                        "deny_hosts",
+                        e.target.value,
+                      ),
+                  }),
+                  m(FormHelp, { text: t("settings.denyHostsHelp") }),
+                ]),
+                m(FormGroup, [
+                  m(FormLabel, { text: t("settings.allowPorts") }),
+                  m(FormInput, {
+                    value: form.allow_ports,
+                    mono: true,
+                    placeholder: "80, 443",
+                    oninput: (e) =>
+                      FunctionSettings.setNetworkPolicyField(
+                        "allow_ports",
+                        e.target.value,
+                      ),
+                  }),
+                ]),
+                m(FormGroup, [
+                  m(FormLabel, { text: t("settings.denyPorts") }),
+                  m(FormInput, {
+                    value: form.deny_ports,
+                    mono: true,
+                    placeholder: "25",
+                    oninput: (e) =>
+                      FunctionSettings.setNetworkPolicyField(
+                        "deny_ports",
+                        e.target.value,
+                      ),
+                  }),
+                ]),
+              ]),
+              m(CardFooter, [
+                m(
+                  Button,
+                  {
+                    variant: ButtonVariant.PRIMARY,
+                    onclick: FunctionSettings.saveNetworkPolicy,
+                    disabled: FunctionSettings.editedNetworkPolicy === null,
+                  },
+                  t("common.saveChanges"),
+                ),
+              ]),
+            ]);
+          })(),
+
           // Function Status
           m(Card, { variant: "warning", style: "margin-bottom: 1.5rem" }, [
             m(CardHeader, { title: t("settings.functionStatus") }),
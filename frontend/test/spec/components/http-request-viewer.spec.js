@@ -0,0 +1,169 @@
+/**
+ * @fileoverview Tests for HTTPRequestViewer component.
+ */
+
+import { HTTPRequestViewer } from "../../../js/components/http-request-viewer.js";
+import { BadgeVariant } from "../../../js/components/badge.js";
+import {
+  Table,
+  TableBody,
+  TableEmpty,
+  TableRow,
+} from "../../../js/components/table.js";
+import { CodeViewer } from "../../../js/components/code-viewer.js";
+import { t } from "../../../js/i18n/index.js";
+
+describe("HTTPRequestViewer", () => {
+  // Reset expanded rows before each test
+  beforeEach(() => {
+    HTTPRequestViewer.expandedRows.clear();
+  });
+
+  describe("view()", () => {
+    it("renders empty state when no requests", () => {
+      const vnode = { attrs: { requests: [] } };
+      const result = HTTPRequestViewer.view(vnode);
+
+      expect(result.tag).toBe(Table);
+      const tableBody = result.children[0];
+      expect(tableBody.tag).toBe(TableBody);
+      const emptyRow = tableBody.children[0];
+      expect(emptyRow.tag).toBe(TableEmpty);
+      expect(emptyRow.attrs.message).toBe(t("httpRequestViewer.noRequests"));
+    });
+
+    it("renders table with requests", () => {
+      const vnode = { attrs: { requests: [createMockRequest()] } };
+      const result = HTTPRequestViewer.view(vnode);
+
+      expect(result.tag).toBe("div");
+      expect(result).toHaveClass("http-request-viewer");
+    });
+
+    it("applies noBorder class when specified", () => {
+      const vnode = { attrs: { requests: [createMockRequest()], noBorder: true } };
+      const result = HTTPRequestViewer.view(vnode);
+
+      expect(result).toHaveClass("http-request-viewer--no-border");
+    });
+  });
+
+  describe("formatBody()", () => {
+    it("pretty-prints JSON bodies", () => {
+      const { formatted, language } = HTTPRequestViewer.formatBody(
+        '{"name":"test"}',
+      );
+
+      expect(formatted).toContain('"name": "test"');
+      expect(language).toBe("json");
+    });
+
+    it("returns non-JSON bodies as-is", () => {
+      const { formatted, language } = HTTPRequestViewer.formatBody("plain");
+
+      expect(formatted).toBe("plain");
+      expect(language).toBe("");
+    });
+
+    it("returns empty string for missing body", () => {
+      expect(HTTPRequestViewer.formatBody(undefined).formatted).toBe("");
+    });
+
+    it("truncates long bodies", () => {
+      const { formatted } = HTTPRequestViewer.formatBody("x".repeat(6000));
+
+      expect(formatted).toContain(t("httpRequestViewer.truncated"));
+      expect(formatted.length).toBeLessThan(6000);
+    });
+  });
+
+  describe("formatSize()", () => {
+    it("formats bytes, kilobytes and megabytes", () => {
+      expect(HTTPRequestViewer.formatSize(0)).toBe("0 B");
+      expect(HTTPRequestViewer.formatSize(512)).toBe("512 B");
+      expect(HTTPRequestViewer.formatSize(2048)).toBe("2.0 KB");
+      expect(HTTPRequestViewer.formatSize(3 * 1024 * 1024)).toBe("3.0 MB");
+    });
+  });
+
+  describe("statusVariant()", () => {
+    it("maps status codes to badge variants", () => {
+      expect(HTTPRequestViewer.statusVariant({ status_code: 200 })).toBe(
+        BadgeVariant.SUCCESS,
+      );
+      expect(HTTPRequestViewer.statusVariant({ status_code: 302 })).toBe(
+        BadgeVariant.WARNING,
+      );
+      expect(HTTPRequestViewer.statusVariant({ status_code: 500 })).toBe(
+        BadgeVariant.DESTRUCTIVE,
+      );
+    });
+
+    it("uses destructive variant when no response was received", () => {
+      expect(HTTPRequestViewer.statusVariant({ status: "error" })).toBe(
+        BadgeVariant.DESTRUCTIVE,
+      );
+    });
+  });
+
+  describe("renderRequestRows()", () => {
+    it("renders main row with request data", () => {
+      const req = createMockRequest();
+      const result = HTTPRequestViewer.renderRequestRows(req);
+
+      expect(result.tag).toBe("[");
+      expect(result.attrs.key).toBe(req.id);
+      expect(result.children.length).toBe(1);
+      expect(result.children[0].tag).toBe(TableRow);
+    });
+
+    it("renders request and response panels when expanded", () => {
+      const req = createMockRequest();
+      HTTPRequestViewer.expandedRows.add(req.id);
+
+      const result = HTTPRequestViewer.renderRequestRows(req);
+
+      expect(result.children.length).toBe(2);
+      const expandedRow = result.children[1];
+      expect(expandedRow.attrs.key).toBe(req.id + "-expanded");
+    });
+  });
+
+  describe("renderPanel()", () => {
+    it("renders headers and body viewers", () => {
+      const result = HTTPRequestViewer.renderPanel(
+        "Request",
+        { "Content-Type": "application/json" },
+        '{"a":1}',
+      );
+
+      const [headers, body] = result.children;
+      expect(headers.tag).toBe(CodeViewer);
+      expect(headers.attrs.code).toBe("Content-Type: application/json");
+      expect(body.tag).toBe(CodeViewer);
+      expect(body.attrs.language).toBe("json");
+    });
+  });
+});
+
+/**
+ * Creates a mock HTTP request for testing.
+ * @returns {Object} Mock HTTP request
+ */
+function createMockRequest() {
+  return {
+    id: "req-123",
+    execution_id: "exec-1",
+    method: "POST",
+    url: "https://api.example.com/items?api_key=[REDACTED]",
+    request_headers: { "Content-Type": "application/json" },
+    request_body: '{"name":"widget"}',
+    status_code: 201,
+    response_headers: { "Content-Type": "application/json" },
+    response_body: '{"id":"item_1"}',
+    response_size: 15,
+    status: "success",
+    duration_ms: 120,
+    created_at: 1700000000,
+  };
+}
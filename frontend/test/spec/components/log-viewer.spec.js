@@ -2,7 +2,10 @@
  * @fileoverview Tests for LogViewer component - focused on critical functionality.
  */
 
-import { LogViewer } from "../../../js/components/log-viewer.js";
+import {
+  formatLogField,
+  LogViewer,
+} from "../../../js/components/log-viewer.js";
 
 /**
  * Recursively flatten all children arrays
@@ -98,4 +101,60 @@ describe("LogViewer", () => {
     const levelClass = (levelSpan.tag || "") + " " + getVnodeClass(levelSpan);
     expect(levelClass).toContain("log-viewer__level--error");
   });
+
+  it("renders structured fields and line", () => {
+    const vnode = {
+      attrs: {
+        logs: [{
+          message: "charged",
+          level: "INFO",
+          fields: { amount: 10, user: "u_1" },
+          line: 12,
+        }],
+      },
+      children: [],
+    };
+    const result = LogViewer.view(vnode);
+
+    const fields = findNode(result, (node) => {
+      if (!node || typeof node !== "object") return false;
+      return (node.tag || "").includes("log-viewer__fields");
+    });
+    expect(fields).toBeTruthy();
+    expect(JSON.stringify(fields)).toContain("user=");
+    expect(JSON.stringify(fields)).toContain("u_1");
+
+    const line = findNode(result, (node) => {
+      if (!node || typeof node !== "object") return false;
+      return (node.tag || "").includes("log-viewer__line");
+    });
+    expect(line).toBeTruthy();
+    expect(JSON.stringify(line)).toContain(":12");
+  });
+
+  it("omits fields when none are given", () => {
+    const vnode = {
+      attrs: { logs: [{ message: "plain" }] },
+      children: [],
+    };
+    const result = LogViewer.view(vnode);
+
+    const fields = findNode(result, (node) => {
+      if (!node || typeof node !== "object") return false;
+      return (node.tag || "").includes("log-viewer__fields");
+    });
+    expect(fields).toBeFalsy();
+  });
+});
+
+describe("formatLogField", () => {
+  it("shows strings as-is", () => {
+    expect(formatLogField("u_1")).toBe("u_1");
+  });
+
+  it("shows other values as JSON", () => {
+    expect(formatLogField(10)).toBe("10");
+    expect(formatLogField(true)).toBe("true");
+    expect(formatLogField({ retry: 1 })).toBe('{"retry":1}');
+  });
 });
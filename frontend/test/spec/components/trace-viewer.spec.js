@@ -0,0 +1,116 @@
+/**
+ * @fileoverview Tests for TraceViewer component.
+ */
+
+import {
+  buildSpanTree,
+  formatSpanDuration,
+  TraceViewer,
+} from "../../../js/components/trace-viewer.js";
+import { t } from "../../../js/i18n/index.js";
+
+describe("TraceViewer", () => {
+  beforeEach(() => {
+    TraceViewer.expandedRows.clear();
+  });
+
+  describe("formatSpanDuration()", () => {
+    it("formats microseconds", () => {
+      expect(formatSpanDuration(850000)).toBe("850µs");
+    });
+
+    it("formats milliseconds", () => {
+      expect(formatSpanDuration(2500000)).toBe("2.50ms");
+      expect(formatSpanDuration(12400000)).toBe("12.4ms");
+    });
+
+    it("formats seconds", () => {
+      expect(formatSpanDuration(1200000000)).toBe("1.20s");
+    });
+  });
+
+  describe("buildSpanTree()", () => {
+    it("orders children after their parent with depth", () => {
+      const spans = [
+        createMockSpan({ id: "c", parent_id: "b", start_time: 30 }),
+        createMockSpan({ id: "a", parent_id: "remote", start_time: 10 }),
+        createMockSpan({ id: "d", parent_id: "a", start_time: 40 }),
+        createMockSpan({ id: "b", parent_id: "a", start_time: 20 }),
+      ];
+
+      const tree = buildSpanTree(spans);
+
+      expect(tree.map((n) => n.span.id)).toEqual(["a", "b", "c", "d"]);
+      expect(tree.map((n) => n.depth)).toEqual([0, 1, 2, 1]);
+    });
+  });
+
+  describe("view()", () => {
+    it("renders empty state when there are no spans", () => {
+      const result = TraceViewer.view({ attrs: { spans: [] } });
+
+      expect(result).toHaveClass("trace-viewer__empty");
+      expect(JSON.stringify(result)).toContain(t("traceViewer.noSpans"));
+    });
+
+    it("renders a row for each span", () => {
+      const spans = [
+        createMockSpan({ id: "a", start_time: 0, end_time: 100 }),
+        createMockSpan({ id: "b", parent_id: "a", start_time: 50, end_time: 100 }),
+      ];
+
+      const result = TraceViewer.view({ attrs: { spans } });
+
+      expect(result).toHaveClass("trace-viewer");
+      const rows = result.children[1];
+      expect(rows.length).toBe(2);
+    });
+
+    it("positions bars relative to the trace duration", () => {
+      const spans = [
+        createMockSpan({ id: "a", start_time: 0, end_time: 100 }),
+        createMockSpan({ id: "b", parent_id: "a", start_time: 50, end_time: 75 }),
+      ];
+
+      const row = TraceViewer.renderSpan(spans[1], 1, 0, 100);
+      const timeline = row.children[0].children[1];
+      const bar = timeline.children[0];
+
+      expect(bar.attrs.style).toBe("left: 50%; width: 25%");
+    });
+
+    it("marks error spans", () => {
+      const span = createMockSpan({ status: "error", error_message: "boom" });
+
+      const row = TraceViewer.renderSpan(span, 0, span.start_time, 1000);
+      const bar = row.children[0].children[1].children[0];
+
+      expect(bar).toHaveClass("trace-viewer__bar--error");
+    });
+
+    it("shows attributes when expanded", () => {
+      const span = createMockSpan({ attributes: { "kv.key": "counter" } });
+      TraceViewer.toggleRow(span.id);
+
+      const row = TraceViewer.renderSpan(span, 0, span.start_time, 1000);
+      const details = row.children[1];
+
+      expect(details).toHaveClass("trace-viewer__details");
+    });
+  });
+});
+
+function createMockSpan(overrides = {}) {
+  return {
+    id: "span-1",
+    trace_id: "4bf92f3577b34da6a3ce929d0e0e4736",
+    execution_id: "exec-1",
+    name: "kv.get",
+    kind: "internal",
+    status: "ok",
+    attributes: {},
+    start_time: 0,
+    end_time: 1000,
+    ...overrides,
+  };
+}
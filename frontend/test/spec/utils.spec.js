@@ -2,7 +2,11 @@
  * @fileoverview Tests for utility functions - focused on critical functionality.
  */
 
-import { formatUnixTimestamp, getFunctionTabs } from "../../js/utils.js";
+import {
+  formatUnixTimestamp,
+  getFunctionTabs,
+  splitList,
+} from "../../js/utils.js";
 
 describe("getFunctionTabs", () => {
   it("returns all 5 tabs", () => {
@@ -89,3 +93,22 @@ describe("formatUnixTimestamp", () => {
     expect(result).not.toContain("1970");
   });
 });
+
+describe("splitList", () => {
+  it("splits on newlines and commas", () => {
+    expect(splitList("a.com, b.com\n10.0.0.0/8")).toEqual([
+      "a.com",
+      "b.com",
+      "10.0.0.0/8",
+    ]);
+  });
+
+  it("drops empty entries", () => {
+    expect(splitList(" \n,a.com,, \n")).toEqual(["a.com"]);
+  });
+
+  it("returns an empty list for empty input", () => {
+    expect(splitList("")).toEqual([]);
+    expect(splitList(undefined)).toEqual([]);
+  });
+});
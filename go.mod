@@ -11,6 +11,8 @@ require (
 	github.com/rs/xid v1.6.0
 	github.com/sergi/go-diff v1.4.0
 	github.com/yuin/gopher-lua v1.1.1
+	golang.org/x/crypto v0.45.0
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.40.1
 )
 
@@ -1,8 +1,12 @@
 package ai
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/dimiro1/lunar/internal/env"
 	internalhttp "github.com/dimiro1/lunar/internal/http"
@@ -14,8 +18,8 @@ import (
 type provider interface {
 	// defaultEndpoint returns the default API base URL for this provider.
 	defaultEndpoint() string
-	// urlPath returns the API path to append to the endpoint (e.g., "/v1/chat/completions").
-	urlPath() string
+	// urlPath returns the API path to append to the endpoint for a model (e.g., "/v1/chat/completions").
+	urlPath(model string) string
 	// headers returns the HTTP headers required for authentication and API versioning.
 	headers(apiKey string) internalhttp.Headers
 	// buildRequestBody constructs the provider-specific request payload from a ChatRequest.
@@ -24,10 +28,21 @@ type provider interface {
 	parseResponse(body string) (*ChatResponse, error)
 }
 
+// builtinProvider is a provider implementation with the environment
+// variables holding its API key and endpoint
+type builtinProvider struct {
+	provider
+	apiKeyEnv   string
+	endpointEnv string
+	keyOptional bool // Local servers run without an API key
+}
+
 // Provider implementations
-var providers = map[string]provider{
-	"openai":    openAIProvider{},
-	"anthropic": anthropicProvider{},
+var providers = map[string]builtinProvider{
+	"openai":    {openAIProvider{}, openAIAPIKeyEnv, openAIEndpointEnv, false},
+	"anthropic": {anthropicProvider{}, anthropicAPIKeyEnv, anthropicEndpointEnv, false},
+	"gemini":    {geminiProvider{}, geminiAPIKeyEnv, geminiEndpointEnv, false},
+	"ollama":    {ollamaProvider{}, ollamaAPIKeyEnv, ollamaEndpointEnv, true},
 }
 
 const anthropicVersion = "2023-06-01"
@@ -38,29 +53,97 @@ const (
 	openAIEndpointEnv    = "OPENAI_ENDPOINT"
 	anthropicAPIKeyEnv   = "ANTHROPIC_API_KEY"
 	anthropicEndpointEnv = "ANTHROPIC_ENDPOINT"
+	geminiAPIKeyEnv      = "GEMINI_API_KEY"
+	geminiEndpointEnv    = "GEMINI_ENDPOINT"
+	ollamaAPIKeyEnv      = "OLLAMA_API_KEY"
+	ollamaEndpointEnv    = "OLLAMA_ENDPOINT"
+)
+
+// Suffixes of the environment variables configuring a provider profile.
+// The prefix is the upper-cased provider name with dashes replaced by
+// underscores, so provider "my-llm" reads MY_LLM_BASE_URL. The models
+// variable also applies to the built-in providers (e.g. OPENAI_MODELS).
+const (
+	profileBaseURLSuffix    = "_BASE_URL"    // Base URL of an OpenAI-compatible API
+	profileAPIKeySuffix     = "_API_KEY"     // Optional API key
+	profileAuthHeaderSuffix = "_AUTH_HEADER" // Header carrying the key (default: Authorization: Bearer)
+	profileModelsSuffix     = "_MODELS"      // Model aliases: "fast=llama3.2:3b,smart=qwen2.5:14b"
 )
 
-// Message represents a chat message
+// profileNamePattern matches the provider names that may name a profile
+var profileNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Message represents a chat message.
+// Assistant messages may carry the tool calls the model requested, and
+// messages with the "tool" role carry the result of one call in Content.
+// Multimodal messages carry their text, images and documents in Parts,
+// which replace Content.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Parts      []Part     `json:"-"`
+	ToolCalls  []ToolCall `json:"-"`
+	ToolCallID string     `json:"-"`
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema of the arguments object
 }
 
+// ToolCall is a request from the model to call a tool
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // JSON-encoded arguments object
+}
+
+// ResponseSchema asks the model to reply with a JSON object matching a schema
+type ResponseSchema struct {
+	Name   string         // Name of the reply format, e.g. "invoice"
+	Schema map[string]any // JSON schema of the reply object
+}
+
+// Stop reasons reported in ChatResponse.StopReason
+const (
+	StopReasonStop      = "stop"
+	StopReasonToolCalls = "tool_calls"
+	StopReasonLength    = "length"
+)
+
 // ChatRequest represents a unified chat request
 type ChatRequest struct {
 	Provider    string
 	Model       string
 	Messages    []Message
+	Tools       []Tool
+	Schema      *ResponseSchema // Optional structured output format
 	MaxTokens   int
 	Temperature float64
 	Endpoint    string // Optional custom endpoint URL (overrides env)
+	// Policy is the network policy of the calling function, enforced on
+	// endpoints set by the function (optional)
+	Policy *internalhttp.Policy
+	// OnToken receives the reply text as it is generated (optional). Providers
+	// that cannot stream deliver the whole text in a single call. Returning an
+	// error aborts the request.
+	OnToken func(token string) error
+	// CacheTTL caches the response for this long when positive. Cached
+	// responses are returned without calling the provider.
+	CacheTTL time.Duration
 }
 
 // ChatResponse represents the unified response from AI providers
 type ChatResponse struct {
-	Content string
-	Model   string
-	Usage   Usage
+	Content    string
+	ToolCalls  []ToolCall
+	StopReason string // StopReasonStop, StopReasonToolCalls, StopReasonLength or the provider's own value
+	Model      string
+	Usage      Usage
+	Cost       *float64 // USD, nil when the model has no price
+	Cached     bool     // Answered from the cache, without calling the provider
 	// Tracking info for logging/debugging
 	Endpoint     string // Full URL used for the request
 	RequestJSON  string // Raw request body JSON
@@ -73,15 +156,18 @@ type Usage struct {
 	OutputTokens int
 }
 
-// Client is an interface for making AI chat requests
+// Client is an interface for making AI chat and embeddings requests
 type Client interface {
 	Chat(functionID string, req ChatRequest) (*ChatResponse, error)
+	Embed(functionID string, req EmbedRequest) (*EmbedResponse, error)
 }
 
 // DefaultClient is the default implementation of Client
 type DefaultClient struct {
 	httpClient internalhttp.Client
 	envStore   env.Store
+	accounting Accounting
+	cache      Cache
 }
 
 // NewDefaultClient creates a new AI client
@@ -94,13 +180,8 @@ func NewDefaultClient(httpClient internalhttp.Client, envStore env.Store) *Defau
 
 // Chat executes a chat request using the specified provider
 func (c *DefaultClient) Chat(functionID string, req ChatRequest) (*ChatResponse, error) {
-	p, ok := providers[req.Provider]
-	if !ok {
-		return nil, fmt.Errorf("unsupported provider: %s (use openai or anthropic)", req.Provider)
-	}
-
-	// Get API key and endpoint from environment
-	apiKey, endpoint, err := c.getProviderConfig(functionID, req.Provider)
+	// Get the provider, API key and endpoint from environment
+	p, apiKey, endpoint, err := c.getProviderConfig(functionID, req.Provider)
 	if err != nil {
 		return nil, err
 	}
@@ -110,28 +191,102 @@ func (c *DefaultClient) Chat(functionID string, req ChatRequest) (*ChatResponse,
 		endpoint = req.Endpoint
 	}
 
-	return c.callProvider(req, p, apiKey, endpoint)
+	// Resolve model aliases
+	if model, ok := c.modelAliases(functionID, req.Provider)[req.Model]; ok {
+		req.Model = model
+	}
+
+	// Cache hits are free, so they are served even over budget
+	var cacheKeyHash, cacheRequestJSON string
+	if req.CacheTTL > 0 && c.cache != nil {
+		cacheRequestJSON, cacheKeyHash, err = cacheKey(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build cache key: %v", err)
+		}
+		if resp, err := c.cachedChat(functionID, cacheKeyHash, cacheRequestJSON); err != nil {
+			// Log error and call the provider instead
+			fmt.Printf("Failed to read AI cache: %v\n", err)
+		} else if resp != nil {
+			if req.OnToken != nil && resp.Content != "" {
+				if err := req.OnToken(resp.Content); err != nil {
+					return resp, err
+				}
+			}
+			return resp, nil
+		}
+	}
+
+	if err := c.checkBudget(functionID); err != nil {
+		return nil, err
+	}
+	resp, err := c.callProvider(req, p, apiKey, endpoint)
+	if err != nil {
+		// Requests aborted by OnToken used tokens until they stopped
+		if resp != nil && resp.Usage != (Usage{}) {
+			resp.Cost = c.account(functionID, req.Provider, req.Model, resp.Usage)
+		}
+		return resp, err
+	}
+	resp.Cost = c.account(functionID, req.Provider, req.Model, resp.Usage)
+	if cacheKeyHash != "" {
+		if err := c.cacheChat(functionID, cacheKeyHash, cacheRequestJSON, req, resp); err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Failed to write AI cache: %v\n", err)
+		}
+	}
+	return resp, nil
 }
 
-// getProviderConfig returns the API key and endpoint for the given provider
-func (c *DefaultClient) getProviderConfig(functionID, providerName string) (apiKey, endpoint string, err error) {
-	switch providerName {
-	case "openai":
-		apiKey, err = c.envStore.Get(functionID, openAIAPIKeyEnv)
-		if err != nil || apiKey == "" {
-			return "", "", fmt.Errorf("%s not set in function environment", openAIAPIKeyEnv)
+// getProviderConfig returns the implementation, API key and endpoint for
+// the given provider. Names other than the built-in providers refer to an
+// OpenAI-compatible profile, which exists when its base URL is set.
+func (c *DefaultClient) getProviderConfig(functionID, providerName string) (p provider, apiKey, endpoint string, err error) {
+	if builtin, ok := providers[providerName]; ok {
+		apiKey, err = c.envStore.Get(functionID, builtin.apiKeyEnv)
+		if (err != nil || apiKey == "") && !builtin.keyOptional {
+			return nil, "", "", fmt.Errorf("%s not set in function environment", builtin.apiKeyEnv)
 		}
-		endpoint, _ = c.envStore.Get(functionID, openAIEndpointEnv)
-	case "anthropic":
-		apiKey, err = c.envStore.Get(functionID, anthropicAPIKeyEnv)
-		if err != nil || apiKey == "" {
-			return "", "", fmt.Errorf("%s not set in function environment", anthropicAPIKeyEnv)
+		endpoint, _ = c.envStore.Get(functionID, builtin.endpointEnv)
+		return builtin.provider, apiKey, endpoint, nil
+	}
+
+	prefix := profileEnvPrefix(providerName)
+	if prefix != "" {
+		endpoint, _ = c.envStore.Get(functionID, prefix+profileBaseURLSuffix)
+	}
+	if endpoint == "" {
+		return nil, "", "", fmt.Errorf("unsupported provider: %s (use openai, anthropic, gemini, ollama or set %s%s for an OpenAI-compatible provider)",
+			providerName, cmp.Or(prefix, "<NAME>"), profileBaseURLSuffix)
+	}
+	apiKey, _ = c.envStore.Get(functionID, prefix+profileAPIKeySuffix)
+	authHeader, _ := c.envStore.Get(functionID, prefix+profileAuthHeaderSuffix)
+	return openAICompatibleProvider{authHeader: authHeader}, apiKey, endpoint, nil
+}
+
+// modelAliases returns the model aliases configured for a provider
+func (c *DefaultClient) modelAliases(functionID, providerName string) map[string]string {
+	prefix := profileEnvPrefix(providerName)
+	if prefix == "" {
+		return nil
+	}
+	value, _ := c.envStore.Get(functionID, prefix+profileModelsSuffix)
+	aliases := make(map[string]string)
+	for entry := range strings.SplitSeq(value, ",") {
+		alias, model, ok := strings.Cut(entry, "=")
+		if ok && strings.TrimSpace(alias) != "" && strings.TrimSpace(model) != "" {
+			aliases[strings.TrimSpace(alias)] = strings.TrimSpace(model)
 		}
-		endpoint, _ = c.envStore.Get(functionID, anthropicEndpointEnv)
-	default:
-		return "", "", fmt.Errorf("unsupported provider: %s (use openai or anthropic)", providerName)
 	}
-	return apiKey, endpoint, nil
+	return aliases
+}
+
+// profileEnvPrefix returns the environment variable prefix for a provider
+// name, or "" if the name cannot name a profile
+func profileEnvPrefix(providerName string) string {
+	if !profileNamePattern.MatchString(providerName) {
+		return ""
+	}
+	return strings.ToUpper(strings.ReplaceAll(providerName, "-", "_"))
 }
 
 // callProvider executes an AI request using the given provider
@@ -140,7 +295,15 @@ func (c *DefaultClient) callProvider(req ChatRequest, p provider, apiKey, endpoi
 	if endpoint == "" {
 		endpoint = p.defaultEndpoint()
 	}
-	fullURL := endpoint + p.urlPath()
+	fullURL := strings.TrimSuffix(endpoint, "/") + p.urlPath(req.Model)
+
+	// Stream when both the provider and the HTTP client support it,
+	// otherwise the whole reply is delivered to OnToken at once
+	onToken := req.OnToken
+	streamer, canStream := c.httpClient.(internalhttp.Streamer)
+	if _, ok := p.(chatStreamer); !ok || !canStream {
+		req.OnToken = nil
+	}
 
 	// Build request body
 	reqBody, err := p.buildRequestBody(req)
@@ -159,29 +322,82 @@ func (c *DefaultClient) callProvider(req ChatRequest, p provider, apiKey, endpoi
 		RequestJSON: string(jsonBody),
 	}
 
-	// Make HTTP request
-	resp, err := c.httpClient.Post(internalhttp.Request{
+	httpReq := internalhttp.Request{
 		URL:     fullURL,
 		Headers: p.headers(apiKey),
 		Body:    string(jsonBody),
-	})
-	if err != nil {
-		return chatResp, fmt.Errorf("HTTP request failed: %v", err)
+		Policy:  endpointPolicy(p, endpoint, req.Policy),
 	}
 
-	// Store response body for tracking
-	chatResp.ResponseJSON = resp.Body
+	var parsedResp *ChatResponse
+	if req.OnToken != nil {
+		parsedResp, err = c.streamProvider(req, p, streamer, httpReq, chatResp)
+		if err != nil {
+			return chatResp, err
+		}
+	} else {
+		// Make HTTP request
+		resp, err := c.httpClient.Post(httpReq)
+		if err != nil {
+			return chatResp, fmt.Errorf("HTTP request failed: %v", err)
+		}
 
-	// Parse response
-	parsedResp, err := p.parseResponse(resp.Body)
-	if err != nil {
-		return chatResp, err
+		// Store response body for tracking
+		chatResp.ResponseJSON = resp.Body
+
+		// Parse response
+		parsedResp, err = p.parseResponse(resp.Body)
+		if err != nil {
+			return chatResp, err
+		}
+		if onToken != nil && parsedResp.Content != "" {
+			if err := onToken(parsedResp.Content); err != nil {
+				chatResp.Usage = parsedResp.Usage
+				return chatResp, err
+			}
+		}
 	}
 
 	// Copy parsed response fields
 	chatResp.Content = parsedResp.Content
+	chatResp.ToolCalls = parsedResp.ToolCalls
+	chatResp.StopReason = parsedResp.StopReason
 	chatResp.Model = parsedResp.Model
 	chatResp.Usage = parsedResp.Usage
+	if req.Schema != nil {
+		// Providers that force a tool call for structured output return the
+		// reply as the arguments of that call
+		for i, call := range chatResp.ToolCalls {
+			if call.Name == req.Schema.Name {
+				chatResp.Content = call.Arguments
+				chatResp.ToolCalls = append(chatResp.ToolCalls[:i:i], chatResp.ToolCalls[i+1:]...)
+				if len(chatResp.ToolCalls) == 0 && chatResp.StopReason == StopReasonToolCalls {
+					chatResp.StopReason = StopReasonStop
+				}
+				break
+			}
+		}
+	}
 
 	return chatResp, nil
 }
+
+// endpointPolicy returns the network policy for a request to endpoint.
+// Endpoints from the function environment or code get the function's policy,
+// since a function can point them anywhere. The built-in provider endpoints
+// are always allowed, including the Ollama default on localhost.
+func endpointPolicy(p provider, endpoint string, policy *internalhttp.Policy) *internalhttp.Policy {
+	if strings.TrimSuffix(endpoint, "/") == p.defaultEndpoint() {
+		return nil
+	}
+	return policy
+}
+
+// schema returns the tool's parameters schema, defaulting to an object
+// without properties for tools that take no arguments
+func (t Tool) schema() map[string]any {
+	if t.Parameters == nil {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return t.Parameters
+}
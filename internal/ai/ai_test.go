@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/dimiro1/lunar/internal/env"
@@ -41,7 +43,7 @@ func TestChat_UnsupportedProvider(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for unsupported provider")
 	}
-	if err.Error() != "unsupported provider: unsupported (use openai or anthropic)" {
+	if err.Error() != "unsupported provider: unsupported (use openai, anthropic, gemini, ollama or set UNSUPPORTED_BASE_URL for an OpenAI-compatible provider)" {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
@@ -339,3 +341,668 @@ func TestChat_UsesEnvEndpoint(t *testing.T) {
 		t.Error("expected request to be received")
 	}
 }
+
+func TestChat_EnforcesNetworkPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected the request to be blocked")
+	}))
+	defer server.Close()
+
+	policy, err := internalhttp.NewPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OLLAMA_ENDPOINT", server.URL)
+	_ = envStore.Set("func-1", "LOCAL_BASE_URL", server.URL)
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	for _, provider := range []string{"ollama", "local"} {
+		_, err := client.Chat("func-1", ChatRequest{
+			Provider: provider,
+			Model:    "llama3.2",
+			Messages: []Message{{Role: "user", Content: "Hello"}},
+			Policy:   policy,
+		})
+		if err == nil || !strings.Contains(err.Error(), "blocked by network policy") {
+			t.Errorf("%s: expected a network policy error, got %v", provider, err)
+		}
+	}
+
+	// The built-in endpoints are allowed whatever the policy
+	fake := internalhttp.NewFakeClient()
+	fake.SetResponse("POST", "http://localhost:11434/api/chat", internalhttp.Response{
+		StatusCode: 200,
+		Body:       `{"model": "llama3.2", "message": {"role": "assistant", "content": "Hi"}}`,
+	})
+	client = NewDefaultClient(fake, env.NewMemoryStore())
+	if _, err := client.Chat("func-1", ChatRequest{
+		Provider: "ollama",
+		Model:    "llama3.2",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+		Policy:   policy,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.Requests[0].Policy != nil {
+		t.Error("expected no policy for the default Ollama endpoint")
+	}
+}
+
+// toolConversation is a conversation where the assistant called a tool
+var toolConversation = ChatRequest{
+	Model: "test-model",
+	Messages: []Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Weather in Lisbon and Porto?"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Lisbon"}`},
+			{ID: "call_2", Name: "get_weather", Arguments: `{"city":"Porto"}`},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Content: "21C"},
+		{Role: "tool", ToolCallID: "call_2", Content: "18C"},
+	},
+	Tools: []Tool{
+		{
+			Name:        "get_weather",
+			Description: "Current weather for a city",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+			},
+		},
+		{Name: "get_time"},
+	},
+	MaxTokens: 100,
+}
+
+// assertJSON compares the JSON encoding of v with the expected document
+func assertJSON(t *testing.T, v any, expected string) {
+	t.Helper()
+	got, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var gotValue, expectedValue any
+	_ = json.Unmarshal(got, &gotValue)
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, expectedValue) {
+		t.Errorf("expected JSON:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestOpenAI_BuildRequestBody_Tools(t *testing.T) {
+	body, err := openAIProvider{}.buildRequestBody(toolConversation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "test-model",
+		"max_tokens": 100,
+		"messages": [
+			{"role": "system", "content": "Be brief"},
+			{"role": "user", "content": "Weather in Lisbon and Porto?"},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Lisbon\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Porto\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "21C"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "18C"}
+		],
+		"tools": [
+			{"type": "function", "function": {
+				"name": "get_weather",
+				"description": "Current weather for a city",
+				"parameters": {"type": "object", "properties": {"city": {"type": "string"}}}
+			}},
+			{"type": "function", "function": {
+				"name": "get_time",
+				"parameters": {"type": "object", "properties": {}}
+			}}
+		]
+	}`)
+}
+
+func TestAnthropic_BuildRequestBody_Tools(t *testing.T) {
+	body, err := anthropicProvider{}.buildRequestBody(toolConversation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "test-model",
+		"max_tokens": 100,
+		"system": "Be brief",
+		"messages": [
+			{"role": "user", "content": "Weather in Lisbon and Porto?"},
+			{"role": "assistant", "content": [
+				{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Lisbon"}},
+				{"type": "tool_use", "id": "call_2", "name": "get_weather", "input": {"city": "Porto"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": "21C"},
+				{"type": "tool_result", "tool_use_id": "call_2", "content": "18C"}
+			]}
+		],
+		"tools": [
+			{
+				"name": "get_weather",
+				"description": "Current weather for a city",
+				"input_schema": {"type": "object", "properties": {"city": {"type": "string"}}}
+			},
+			{"name": "get_time", "input_schema": {"type": "object", "properties": {}}}
+		]
+	}`)
+}
+
+func TestOpenAI_ParseResponse_ToolCalls(t *testing.T) {
+	resp, err := openAIProvider{}.parseResponse(`{
+		"model": "gpt-4o-mini",
+		"choices": [{
+			"message": {"content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Lisbon\"}"}}
+			]},
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {"prompt_tokens": 20, "completion_tokens": 5}
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city":"Lisbon"}`}}
+	if !reflect.DeepEqual(resp.ToolCalls, expected) {
+		t.Errorf("expected tool calls %+v, got %+v", expected, resp.ToolCalls)
+	}
+	if resp.StopReason != StopReasonToolCalls {
+		t.Errorf("expected stop reason %q, got %q", StopReasonToolCalls, resp.StopReason)
+	}
+}
+
+func TestAnthropic_ParseResponse_ToolCalls(t *testing.T) {
+	resp, err := anthropicProvider{}.parseResponse(`{
+		"model": "claude-3-haiku",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Lisbon"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 20, "output_tokens": 5}
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "Let me check." {
+		t.Errorf("expected text content, got %q", resp.Content)
+	}
+	expected := []ToolCall{{ID: "toolu_1", Name: "get_weather", Arguments: `{"city": "Lisbon"}`}}
+	if !reflect.DeepEqual(resp.ToolCalls, expected) {
+		t.Errorf("expected tool calls %+v, got %+v", expected, resp.ToolCalls)
+	}
+	if resp.StopReason != StopReasonToolCalls {
+		t.Errorf("expected stop reason %q, got %q", StopReasonToolCalls, resp.StopReason)
+	}
+}
+
+func TestAnthropicStopReason(t *testing.T) {
+	tests := map[string]string{
+		"end_turn":      StopReasonStop,
+		"stop_sequence": StopReasonStop,
+		"tool_use":      StopReasonToolCalls,
+		"max_tokens":    StopReasonLength,
+		"refusal":       "refusal",
+	}
+	for reason, expected := range tests {
+		if got := anthropicStopReason(reason); got != expected {
+			t.Errorf("anthropicStopReason(%q) = %q, expected %q", reason, got, expected)
+		}
+	}
+}
+
+func TestChat_Schema(t *testing.T) {
+	schema := &ResponseSchema{
+		Name:   "person",
+		Schema: map[string]any{"type": "object", "properties": map[string]any{"name": map[string]any{"type": "string"}}},
+	}
+
+	t.Run("openai", func(t *testing.T) {
+		body, err := openAIProvider{}.buildRequestBody(ChatRequest{Model: "m", Messages: []Message{{Role: "user", Content: "Hi"}}, Schema: schema})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertJSON(t, body, `{
+			"model": "m",
+			"messages": [{"role": "user", "content": "Hi"}],
+			"response_format": {"type": "json_schema", "json_schema": {
+				"name": "person",
+				"schema": {"type": "object", "properties": {"name": {"type": "string"}}}
+			}}
+		}`)
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var reqBody map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&reqBody)
+			choice, _ := json.Marshal(reqBody["tool_choice"])
+			if string(choice) != `{"name":"person","type":"tool"}` {
+				t.Errorf("expected forced person tool, got %s", choice)
+			}
+			tools, _ := reqBody["tools"].([]any)
+			if len(tools) != 1 || tools[0].(map[string]any)["input_schema"] == nil {
+				t.Errorf("expected schema tool, got %v", reqBody["tools"])
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"model": "claude-3-haiku",
+				"content": [{"type": "tool_use", "id": "toolu_1", "name": "person", "input": {"name": "Ann"}}],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 10, "output_tokens": 5}
+			}`))
+		}))
+		defer server.Close()
+
+		envStore := env.NewMemoryStore()
+		_ = envStore.Set("func-1", "ANTHROPIC_API_KEY", "test-api-key")
+		client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+		resp, err := client.Chat("func-1", ChatRequest{
+			Provider:  "anthropic",
+			Model:     "claude-3-haiku",
+			Messages:  []Message{{Role: "user", Content: "Who?"}},
+			Schema:    schema,
+			MaxTokens: 100,
+			Endpoint:  server.URL,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Content != `{"name": "Ann"}` {
+			t.Errorf("expected tool input as content, got %q", resp.Content)
+		}
+		if len(resp.ToolCalls) != 0 {
+			t.Errorf("expected no tool calls, got %+v", resp.ToolCalls)
+		}
+		if resp.StopReason != StopReasonStop {
+			t.Errorf("expected stop reason %q, got %q", StopReasonStop, resp.StopReason)
+		}
+	})
+}
+
+func TestOllama_BuildRequestBody(t *testing.T) {
+	req := toolConversation
+	req.Schema = &ResponseSchema{Name: "weather", Schema: map[string]any{"type": "object"}}
+	req.Temperature = 0.2
+	body, err := ollamaProvider{}.buildRequestBody(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "test-model",
+		"stream": false,
+		"options": {"num_predict": 100, "temperature": 0.2},
+		"format": {"type": "object"},
+		"messages": [
+			{"role": "system", "content": "Be brief"},
+			{"role": "user", "content": "Weather in Lisbon and Porto?"},
+			{"role": "assistant", "content": "", "tool_calls": [
+				{"function": {"name": "get_weather", "arguments": {"city": "Lisbon"}}},
+				{"function": {"name": "get_weather", "arguments": {"city": "Porto"}}}
+			]},
+			{"role": "tool", "tool_name": "get_weather", "content": "21C"},
+			{"role": "tool", "tool_name": "get_weather", "content": "18C"}
+		],
+		"tools": [
+			{"type": "function", "function": {
+				"name": "get_weather",
+				"description": "Current weather for a city",
+				"parameters": {"type": "object", "properties": {"city": {"type": "string"}}}
+			}},
+			{"type": "function", "function": {
+				"name": "get_time",
+				"parameters": {"type": "object", "properties": {}}
+			}}
+		]
+	}`)
+}
+
+func TestOllama_ParseResponse(t *testing.T) {
+	resp, err := ollamaProvider{}.parseResponse(`{
+		"model": "llama3.2",
+		"message": {"role": "assistant", "content": "", "tool_calls": [
+			{"function": {"name": "get_weather", "arguments": {"city": "Lisbon"}}}
+		]},
+		"done_reason": "stop",
+		"prompt_eval_count": 30,
+		"eval_count": 12
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city": "Lisbon"}`}}
+	if !reflect.DeepEqual(resp.ToolCalls, expected) {
+		t.Errorf("expected tool calls %+v, got %+v", expected, resp.ToolCalls)
+	}
+	if resp.StopReason != StopReasonToolCalls {
+		t.Errorf("expected stop reason %q, got %q", StopReasonToolCalls, resp.StopReason)
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 12 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+
+	if _, err := (ollamaProvider{}).parseResponse(`{"error": "model \"nope\" not found"}`); err == nil || err.Error() != `ollama API error: model "nope" not found` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestChat_Ollama_WithoutAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected /api/chat, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no Authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "llama3.2:3b", "message": {"role": "assistant", "content": "positive"}, "done_reason": "stop"}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OLLAMA_ENDPOINT", server.URL)
+	_ = envStore.Set("func-1", "OLLAMA_MODELS", "classifier=llama3.2:3b")
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	resp, err := client.Chat("func-1", ChatRequest{
+		Provider: "ollama",
+		Model:    "classifier",
+		Messages: []Message{{Role: "user", Content: "Great product!"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "positive" {
+		t.Errorf("expected content 'positive', got %q", resp.Content)
+	}
+	if !strings.Contains(resp.RequestJSON, `"model":"llama3.2:3b"`) {
+		t.Errorf("expected alias to be resolved, got %s", resp.RequestJSON)
+	}
+}
+
+func TestGemini_BuildRequestBody(t *testing.T) {
+	req := toolConversation
+	req.Messages = append(req.Messages, Message{Role: "assistant", Content: "Lisbon is warmer."})
+	req.Schema = &ResponseSchema{Name: "weather", Schema: map[string]any{"type": "object"}}
+	body, err := geminiProvider{}.buildRequestBody(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"systemInstruction": {"parts": [{"text": "Be brief"}]},
+		"contents": [
+			{"role": "user", "parts": [{"text": "Weather in Lisbon and Porto?"}]},
+			{"role": "model", "parts": [
+				{"functionCall": {"name": "get_weather", "args": {"city": "Lisbon"}}},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Porto"}}}
+			]},
+			{"role": "user", "parts": [
+				{"functionResponse": {"name": "get_weather", "response": {"result": "21C"}}},
+				{"functionResponse": {"name": "get_weather", "response": {"result": "18C"}}}
+			]},
+			{"role": "model", "parts": [{"text": "Lisbon is warmer."}]}
+		],
+		"tools": [{"functionDeclarations": [
+			{
+				"name": "get_weather",
+				"description": "Current weather for a city",
+				"parameters": {"type": "object", "properties": {"city": {"type": "string"}}}
+			},
+			{"name": "get_time", "parameters": {"type": "object", "properties": {}}}
+		]}],
+		"generationConfig": {
+			"maxOutputTokens": 100,
+			"responseMimeType": "application/json",
+			"responseJsonSchema": {"type": "object"}
+		}
+	}`)
+}
+
+func TestGemini_ParseResponse(t *testing.T) {
+	resp, err := geminiProvider{}.parseResponse(`{
+		"modelVersion": "gemini-2.0-flash",
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "thinking...", "thought": true},
+				{"text": "Checking."},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Lisbon"}}}
+			]},
+			"finishReason": "STOP"
+		}],
+		"usageMetadata": {"promptTokenCount": 25, "candidatesTokenCount": 9}
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Content != "Checking." {
+		t.Errorf("expected content without thoughts, got %q", resp.Content)
+	}
+	expected := []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: `{"city": "Lisbon"}`}}
+	if !reflect.DeepEqual(resp.ToolCalls, expected) {
+		t.Errorf("expected tool calls %+v, got %+v", expected, resp.ToolCalls)
+	}
+	if resp.StopReason != StopReasonToolCalls || resp.Model != "gemini-2.0-flash" {
+		t.Errorf("unexpected stop reason %q or model %q", resp.StopReason, resp.Model)
+	}
+
+	errorTests := map[string]string{
+		`{"error": {"code": 400, "message": "API key not valid"}}`: "gemini API error: API key not valid",
+		`{"promptFeedback": {"blockReason": "SAFETY"}}`:            "gemini blocked the prompt: SAFETY",
+		`{"candidates": []}`: "no response from Gemini",
+	}
+	for body, expected := range errorTests {
+		if _, err := (geminiProvider{}).parseResponse(body); err == nil || err.Error() != expected {
+			t.Errorf("expected error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestChat_Gemini_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.0-flash:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-api-key" {
+			t.Errorf("expected x-goog-api-key header")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"modelVersion": "gemini-2.0-flash",
+			"candidates": [{"content": {"parts": [{"text": "Hello from Gemini!"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "GEMINI_API_KEY", "test-api-key")
+	_ = envStore.Set("func-1", "GEMINI_ENDPOINT", server.URL+"/")
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	resp, err := client.Chat("func-1", ChatRequest{
+		Provider: "gemini",
+		Model:    "gemini-2.0-flash",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "Hello from Gemini!" || resp.StopReason != StopReasonStop {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	if _, err := client.Chat("func-2", ChatRequest{Provider: "gemini", Model: "m"}); err == nil || err.Error() != "GEMINI_API_KEY not set in function environment" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestChat_OpenAICompatibleProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		authHeader string
+		header     string
+		expected   string
+	}{
+		{name: "bearer", apiKey: "secret", header: "Authorization", expected: "Bearer secret"},
+		{name: "custom header", apiKey: "secret", authHeader: "api-key", header: "api-key", expected: "secret"},
+		{name: "no key", header: "Authorization", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if got := r.Header.Get(tt.header); got != tt.expected {
+					t.Errorf("expected %s header %q, got %q", tt.header, tt.expected, got)
+				}
+				var reqBody map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&reqBody)
+				if reqBody["model"] != "llama-3.3-70b-versatile" {
+					t.Errorf("expected aliased model, got %v", reqBody["model"])
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"model": "llama-3.3-70b-versatile", "choices": [{"message": {"content": "hi"}, "finish_reason": "stop"}]}`))
+			}))
+			defer server.Close()
+
+			envStore := env.NewMemoryStore()
+			_ = envStore.Set("func-1", "MY_GROQ_BASE_URL", server.URL+"/v1")
+			_ = envStore.Set("func-1", "MY_GROQ_MODELS", "fast = llama-3.1-8b-instant, smart = llama-3.3-70b-versatile")
+			if tt.apiKey != "" {
+				_ = envStore.Set("func-1", "MY_GROQ_API_KEY", tt.apiKey)
+			}
+			if tt.authHeader != "" {
+				_ = envStore.Set("func-1", "MY_GROQ_AUTH_HEADER", tt.authHeader)
+			}
+			client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+			resp, err := client.Chat("func-1", ChatRequest{
+				Provider: "my-groq",
+				Model:    "smart",
+				Messages: []Message{{Role: "user", Content: "Hello"}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Content != "hi" {
+				t.Errorf("expected content 'hi', got %q", resp.Content)
+			}
+		})
+	}
+}
+
+func TestChat_InvalidProfileName(t *testing.T) {
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), env.NewMemoryStore())
+	_, err := client.Chat("func-1", ChatRequest{Provider: "Not Valid", Model: "m"})
+	if err == nil || err.Error() != "unsupported provider: Not Valid (use openai, anthropic, gemini, ollama or set <NAME>_BASE_URL for an OpenAI-compatible provider)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEmbed_OpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("expected /embeddings, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"model": "text-embedding-3-small",
+			"data": [
+				{"index": 1, "embedding": [0.3, 0.4]},
+				{"index": 0, "embedding": [0.1, 0.2]}
+			],
+			"usage": {"prompt_tokens": 6}
+		}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OPENAI_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "OPENAI_ENDPOINT", server.URL)
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	resp, err := client.Embed("func-1", EmbedRequest{
+		Provider: "openai",
+		Model:    "text-embedding-3-small",
+		Input:    []string{"first", "second"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Embeddings) != 2 || resp.Embeddings[0][0] != 0.1 || resp.Embeddings[1][0] != 0.3 {
+		t.Errorf("expected embeddings in input order, got %v", resp.Embeddings)
+	}
+	if resp.Usage.InputTokens != 6 {
+		t.Errorf("expected 6 input tokens, got %d", resp.Usage.InputTokens)
+	}
+	if resp.RequestJSON != `{"model":"text-embedding-3-small","input":["first","second"]}` {
+		t.Errorf("unexpected request: %s", resp.RequestJSON)
+	}
+}
+
+func TestEmbed_Ollama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("expected /api/embed, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "nomic-embed-text", "embeddings": [[0.5, 0.5, 0.5]], "prompt_eval_count": 3}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OLLAMA_ENDPOINT", server.URL)
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	resp, err := client.Embed("func-1", EmbedRequest{
+		Provider: "ollama",
+		Model:    "nomic-embed-text",
+		Input:    []string{"hello"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Embeddings) != 1 || len(resp.Embeddings[0]) != 3 {
+		t.Errorf("unexpected embeddings: %v", resp.Embeddings)
+	}
+	if resp.Usage.InputTokens != 3 {
+		t.Errorf("expected 3 input tokens, got %d", resp.Usage.InputTokens)
+	}
+}
+
+func TestEmbed_UnsupportedProvider(t *testing.T) {
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "ANTHROPIC_API_KEY", "test-key")
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+
+	_, err := client.Embed("func-1", EmbedRequest{
+		Provider: "anthropic",
+		Model:    "claude-3-haiku",
+		Input:    []string{"hello"},
+	})
+	if err == nil || err.Error() != "provider anthropic does not support embeddings" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
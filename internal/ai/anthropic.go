@@ -11,7 +11,7 @@ import (
 type anthropicProvider struct{}
 
 func (anthropicProvider) defaultEndpoint() string { return "https://api.anthropic.com" }
-func (anthropicProvider) urlPath() string         { return "/v1/messages" }
+func (anthropicProvider) urlPath(string) string   { return "/v1/messages" }
 
 func (anthropicProvider) headers(apiKey string) internalhttp.Headers {
 	return internalhttp.Headers{
@@ -21,29 +21,138 @@ func (anthropicProvider) headers(apiKey string) internalhttp.Headers {
 	}
 }
 
+// anthropicMessage is a chat message in the Anthropic format. Content is
+// either a string or a list of content blocks.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicBlock is a content block of a message
+type anthropicBlock struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ID        string           `json:"id,omitempty"`
+	Name      string           `json:"name,omitempty"`
+	Input     json.RawMessage  `json:"input,omitempty"`
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	Source    *anthropicSource `json:"source,omitempty"`
+	Title     string           `json:"title,omitempty"`
+}
+
+// anthropicSource is the base64 data or URL of an image or document block
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicContentBlocks converts message parts to content blocks
+func anthropicContentBlocks(parts []Part) []anthropicBlock {
+	var blocks []anthropicBlock
+	for _, part := range parts {
+		block := anthropicBlock{Type: part.Type, Text: part.Text}
+		switch {
+		case part.Type == PartText:
+		case part.URL != "":
+			block.Source = &anthropicSource{Type: "url", URL: part.URL}
+		default:
+			block.Source = &anthropicSource{Type: "base64", MediaType: part.MediaType, Data: part.Data}
+		}
+		if part.Type == PartDocument {
+			block.Title = part.Name
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// anthropicTool is a tool definition in the Anthropic format
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
 func (anthropicProvider) buildRequestBody(req ChatRequest) (any, error) {
 	// Extract system message (Anthropic handles it separately)
 	var systemPrompt string
-	var userMessages []Message
+	var userMessages []anthropicMessage
 	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			systemPrompt = msg.Content
-		} else {
-			userMessages = append(userMessages, msg)
+		switch {
+		case msg.Role == "system":
+			systemPrompt = msg.text()
+		case msg.Role == "tool":
+			// Tool results are sent by the user, and results of calls made in
+			// the same turn share one message
+			block := anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.text()}
+			if n := len(userMessages); n > 0 {
+				if blocks, ok := userMessages[n-1].Content.([]anthropicBlock); ok && userMessages[n-1].Role == "user" && blocks[0].Type == "tool_result" {
+					userMessages[n-1].Content = append(blocks, block)
+					continue
+				}
+			}
+			userMessages = append(userMessages, anthropicMessage{Role: "user", Content: []anthropicBlock{block}})
+		case len(msg.ToolCalls) > 0:
+			var blocks []anthropicBlock
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			userMessages = append(userMessages, anthropicMessage{Role: msg.Role, Content: blocks})
+		case len(msg.Parts) > 0:
+			userMessages = append(userMessages, anthropicMessage{Role: msg.Role, Content: anthropicContentBlocks(msg.Parts)})
+		default:
+			userMessages = append(userMessages, anthropicMessage{Role: msg.Role, Content: msg.Content})
 		}
 	}
 
+	var tools []anthropicTool
+	for _, tool := range req.Tools {
+		tools = append(tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.schema(),
+		})
+	}
+
+	// Structured output is a tool the model is forced to call, whose input
+	// is the reply
+	var toolChoice any
+	if req.Schema != nil {
+		tools = append(tools, anthropicTool{
+			Name:        req.Schema.Name,
+			Description: "Reply with a JSON object matching the input schema",
+			InputSchema: req.Schema.Schema,
+		})
+		toolChoice = map[string]string{"type": "tool", "name": req.Schema.Name}
+	}
+
 	body := struct {
-		Model       string    `json:"model"`
-		MaxTokens   int       `json:"max_tokens"`
-		System      string    `json:"system,omitempty"`
-		Messages    []Message `json:"messages"`
-		Temperature float64   `json:"temperature,omitempty"`
+		Model       string             `json:"model"`
+		MaxTokens   int                `json:"max_tokens"`
+		System      string             `json:"system,omitempty"`
+		Messages    []anthropicMessage `json:"messages"`
+		Tools       []anthropicTool    `json:"tools,omitempty"`
+		ToolChoice  any                `json:"tool_choice,omitempty"`
+		Temperature float64            `json:"temperature,omitempty"`
+		Stream      bool               `json:"stream,omitempty"`
 	}{
-		Model:     req.Model,
-		MaxTokens: req.MaxTokens,
-		System:    systemPrompt,
-		Messages:  userMessages,
+		Model:      req.Model,
+		MaxTokens:  req.MaxTokens,
+		System:     systemPrompt,
+		Messages:   userMessages,
+		Tools:      tools,
+		ToolChoice: toolChoice,
+		Stream:     req.OnToken != nil,
 	}
 	if req.Temperature > 0 {
 		body.Temperature = req.Temperature
@@ -55,10 +164,14 @@ func (anthropicProvider) parseResponse(body string) (*ChatResponse, error) {
 	var resp struct {
 		Model   string `json:"model"`
 		Content []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
-		Usage struct {
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
 			InputTokens  int `json:"input_tokens"`
 			OutputTokens int `json:"output_tokens"`
 		} `json:"usage"`
@@ -79,20 +192,167 @@ func (anthropicProvider) parseResponse(body string) (*ChatResponse, error) {
 		return nil, fmt.Errorf("no response from Anthropic")
 	}
 
-	// Concatenate all text content
+	// Concatenate all text content and collect tool calls
 	var content string
+	var toolCalls []ToolCall
 	for _, c := range resp.Content {
-		if c.Type == "text" {
+		switch c.Type {
+		case "text":
 			content += c.Text
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: c.ID, Name: c.Name, Arguments: string(c.Input)})
 		}
 	}
 
 	return &ChatResponse{
-		Content: content,
-		Model:   resp.Model,
+		Content:    content,
+		ToolCalls:  toolCalls,
+		StopReason: anthropicStopReason(resp.StopReason),
+		Model:      resp.Model,
 		Usage: Usage{
 			InputTokens:  resp.Usage.InputTokens,
 			OutputTokens: resp.Usage.OutputTokens,
 		},
 	}, nil
 }
+
+func (anthropicProvider) newChatStream() chatStream { return &anthropicStream{} }
+
+// anthropicStream assembles a message from its streamed events
+type anthropicStream struct {
+	received   bool
+	model      string
+	blocks     []anthropicStreamBlock
+	stopReason string
+	usage      Usage
+}
+
+// anthropicStreamBlock is a content block being streamed
+type anthropicStreamBlock struct {
+	Type  string
+	Text  string
+	ID    string
+	Name  string
+	Input string // JSON of a tool_use block, streamed in pieces
+}
+
+func (s *anthropicStream) event(_, data string) (string, error) {
+	var ev struct {
+		Type    string `json:"type"`
+		Index   int    `json:"index"`
+		Message struct {
+			Model string `json:"model"`
+			Usage struct {
+				InputTokens  int `json:"input_tokens"`
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+		} `json:"message"`
+		ContentBlock struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"content_block"`
+		Delta struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		Usage struct {
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return "", fmt.Errorf("failed to parse stream event: %v", err)
+	}
+
+	switch ev.Type {
+	case "error":
+		message := "unknown error"
+		if ev.Error != nil {
+			message = ev.Error.Message
+		}
+		return "", fmt.Errorf("anthropic API error: %s", message)
+	case "message_start":
+		s.model = ev.Message.Model
+		s.usage = Usage{InputTokens: ev.Message.Usage.InputTokens, OutputTokens: ev.Message.Usage.OutputTokens}
+	case "content_block_start":
+		s.received = true
+		for len(s.blocks) <= ev.Index {
+			s.blocks = append(s.blocks, anthropicStreamBlock{})
+		}
+		block := &s.blocks[ev.Index]
+		block.Type = ev.ContentBlock.Type
+		block.ID = ev.ContentBlock.ID
+		block.Name = ev.ContentBlock.Name
+		block.Text = ev.ContentBlock.Text
+		if block.Type == "text" {
+			return ev.ContentBlock.Text, nil
+		}
+	case "content_block_delta":
+		if ev.Index >= len(s.blocks) {
+			return "", fmt.Errorf("failed to parse stream event: delta for unknown block %d", ev.Index)
+		}
+		block := &s.blocks[ev.Index]
+		switch ev.Delta.Type {
+		case "text_delta":
+			block.Text += ev.Delta.Text
+			return ev.Delta.Text, nil
+		case "input_json_delta":
+			block.Input += ev.Delta.PartialJSON
+		}
+	case "message_delta":
+		s.stopReason = ev.Delta.StopReason
+		s.usage.OutputTokens = ev.Usage.OutputTokens
+	}
+	return "", nil
+}
+
+func (s *anthropicStream) reportedUsage() Usage { return s.usage }
+
+func (s *anthropicStream) response() (*ChatResponse, error) {
+	if !s.received {
+		return nil, fmt.Errorf("no response from Anthropic")
+	}
+
+	var content string
+	var toolCalls []ToolCall
+	for _, block := range s.blocks {
+		switch block.Type {
+		case "text":
+			content += block.Text
+		case "tool_use":
+			arguments := block.Input
+			if arguments == "" {
+				arguments = "{}"
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: arguments})
+		}
+	}
+
+	return &ChatResponse{
+		Content:    content,
+		ToolCalls:  toolCalls,
+		StopReason: anthropicStopReason(s.stopReason),
+		Model:      s.model,
+		Usage:      s.usage,
+	}, nil
+}
+
+// anthropicStopReason maps Anthropic stop reasons to the unified ones
+func anthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return StopReasonStop
+	case "tool_use":
+		return StopReasonToolCalls
+	case "max_tokens":
+		return StopReasonLength
+	default:
+		return reason
+	}
+}
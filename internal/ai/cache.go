@@ -0,0 +1,348 @@
+package ai
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/masking"
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// DefaultCacheTTL is how long responses are cached when a request does not
+// say
+const DefaultCacheTTL = time.Hour
+
+// Cache stores chat responses per function. Expired entries are never
+// returned.
+type Cache interface {
+	// Get returns the entry for a key, or nil if there is none, counting a hit.
+	Get(functionID, key string) (*store.AICacheEntry, error)
+	// Set stores an entry, replacing the entry with the same key.
+	Set(entry store.AICacheEntry) error
+	// List returns the entries of a function, newest first, and their total.
+	List(functionID string, limit, offset int) ([]store.AICacheEntry, int64, error)
+	// Clear removes every entry of a function and returns how many there were.
+	Clear(functionID string) (int64, error)
+}
+
+// WithCache makes the client answer requests that opt in to caching from
+// the cache
+func (c *DefaultClient) WithCache(cache Cache) *DefaultClient {
+	c.cache = cache
+	return c
+}
+
+// cacheRequest is what identifies a chat request in the cache
+type cacheRequest struct {
+	Provider    string          `json:"provider"`
+	Model       string          `json:"model"`
+	Messages    []cacheMessage  `json:"messages"`
+	Tools       []cacheTool     `json:"tools,omitempty"`
+	Schema      *ResponseSchema `json:"schema,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature float64         `json:"temperature,omitempty"`
+}
+
+type cacheMessage struct {
+	Role       string          `json:"role"`
+	Content    string          `json:"content"`
+	Parts      []cachePart     `json:"parts,omitempty"`
+	ToolCalls  []cacheToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+type cachePart struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+type cacheTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type cacheToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// cacheResponse is the cached part of a chat response
+type cacheResponse struct {
+	Content    string          `json:"content"`
+	ToolCalls  []cacheToolCall `json:"tool_calls,omitempty"`
+	StopReason string          `json:"stop_reason"`
+	Model      string          `json:"model"`
+}
+
+// cacheKey returns the request as stored in the cache and its key. The
+// endpoint is left out so the cache survives moving to another server.
+func cacheKey(req ChatRequest) (string, string, error) {
+	key := cacheRequest{
+		Provider:    req.Provider,
+		Model:       req.Model,
+		Schema:      req.Schema,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	for _, msg := range req.Messages {
+		m := cacheMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCalls:  toCacheToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, part := range msg.Parts {
+			m.Parts = append(m.Parts, cachePart(part))
+		}
+		key.Messages = append(key.Messages, m)
+	}
+	for _, tool := range req.Tools {
+		key.Tools = append(key.Tools, cacheTool(tool))
+	}
+
+	requestJSON, err := json.Marshal(key)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(requestJSON)
+	return string(requestJSON), hex.EncodeToString(sum[:]), nil
+}
+
+func toCacheToolCalls(calls []ToolCall) []cacheToolCall {
+	var cached []cacheToolCall
+	for _, call := range calls {
+		cached = append(cached, cacheToolCall(call))
+	}
+	return cached
+}
+
+// cachedChat returns the cached response to a request, or nil on a miss.
+// Hits cost nothing and use no tokens.
+func (c *DefaultClient) cachedChat(functionID, key, requestJSON string) (*ChatResponse, error) {
+	entry, err := c.cache.Get(functionID, key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	var cached cacheResponse
+	if err := json.Unmarshal([]byte(entry.ResponseJSON), &cached); err != nil {
+		return nil, err
+	}
+	resp := &ChatResponse{
+		Content:      cached.Content,
+		StopReason:   cached.StopReason,
+		Model:        cached.Model,
+		Cost:         new(float64),
+		Cached:       true,
+		RequestJSON:  requestJSON,
+		ResponseJSON: entry.ResponseJSON,
+	}
+	for _, call := range cached.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall(call))
+	}
+	return resp, nil
+}
+
+// cacheChat stores a response in the cache. The request is masked like
+// tracked requests since entries are listed by the API; hits return the
+// request of the call that hit instead.
+func (c *DefaultClient) cacheChat(functionID, key, requestJSON string, req ChatRequest, resp *ChatResponse) error {
+	responseJSON, err := json.Marshal(cacheResponse{
+		Content:    resp.Content,
+		ToolCalls:  toCacheToolCalls(resp.ToolCalls),
+		StopReason: resp.StopReason,
+		Model:      resp.Model,
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return c.cache.Set(store.AICacheEntry{
+		FunctionID:   functionID,
+		Key:          key,
+		Provider:     req.Provider,
+		Model:        req.Model,
+		RequestJSON:  masking.TruncateBinaryJSON(masking.MaskJSONBody(requestJSON)),
+		ResponseJSON: string(responseJSON),
+		CreatedAt:    now.Unix(),
+		ExpiresAt:    now.Add(req.CacheTTL).Unix(),
+	})
+}
+
+// MemoryCache is an in-memory implementation of Cache
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]store.AICacheEntry
+}
+
+// NewMemoryCache creates a new in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]map[string]store.AICacheEntry)}
+}
+
+// Get returns the entry for a key, or nil if there is none, counting a hit
+func (m *MemoryCache) Get(functionID, key string) (*store.AICacheEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[functionID][key]
+	if !ok || entry.ExpiresAt <= time.Now().Unix() {
+		return nil, nil
+	}
+	entry.Hits++
+	m.entries[functionID][key] = entry
+	return &entry, nil
+}
+
+// Set stores an entry, replacing the entry with the same key
+func (m *MemoryCache) Set(entry store.AICacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries[entry.FunctionID] == nil {
+		m.entries[entry.FunctionID] = make(map[string]store.AICacheEntry)
+	}
+	m.entries[entry.FunctionID][entry.Key] = entry
+	return nil
+}
+
+// List returns the entries of a function, newest first, and their total
+func (m *MemoryCache) List(functionID string, limit, offset int) ([]store.AICacheEntry, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().Unix()
+	entries := make([]store.AICacheEntry, 0)
+	for _, entry := range m.entries[functionID] {
+		if entry.ExpiresAt > now {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b store.AICacheEntry) int {
+		return cmp.Or(cmp.Compare(b.CreatedAt, a.CreatedAt), cmp.Compare(a.Key, b.Key))
+	})
+
+	total := int64(len(entries))
+	if offset >= len(entries) {
+		return []store.AICacheEntry{}, total, nil
+	}
+	return entries[offset:min(offset+limit, len(entries))], total, nil
+}
+
+// Clear removes every entry of a function and returns how many there were
+func (m *MemoryCache) Clear(functionID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := int64(len(m.entries[functionID]))
+	delete(m.entries, functionID)
+	return count, nil
+}
+
+// SQLiteCache is a SQLite-backed implementation of Cache
+type SQLiteCache struct {
+	db *sql.DB
+}
+
+// NewSQLiteCache creates a new SQLite-backed cache
+func NewSQLiteCache(db *sql.DB) *SQLiteCache {
+	return &SQLiteCache{db: db}
+}
+
+// Get returns the entry for a key, or nil if there is none, counting a hit
+func (s *SQLiteCache) Get(functionID, key string) (*store.AICacheEntry, error) {
+	var entry store.AICacheEntry
+	err := s.db.QueryRow(
+		`UPDATE ai_cache SET hits = hits + 1
+		 WHERE function_id = ? AND key = ? AND expires_at > ?
+		 RETURNING function_id, key, provider, model, request_json, response_json, hits, created_at, expires_at`,
+		functionID, key, time.Now().Unix(),
+	).Scan(
+		&entry.FunctionID, &entry.Key, &entry.Provider, &entry.Model, &entry.RequestJSON,
+		&entry.ResponseJSON, &entry.Hits, &entry.CreatedAt, &entry.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Set stores an entry, replacing the entry with the same key. Expired
+// entries of the function are removed on the way.
+func (s *SQLiteCache) Set(entry store.AICacheEntry) error {
+	if _, err := s.db.Exec(
+		"DELETE FROM ai_cache WHERE function_id = ? AND expires_at <= ?",
+		entry.FunctionID, time.Now().Unix(),
+	); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(
+		`INSERT OR REPLACE INTO ai_cache
+		(function_id, key, provider, model, request_json, response_json, hits, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.FunctionID, entry.Key, entry.Provider, entry.Model, entry.RequestJSON,
+		entry.ResponseJSON, entry.Hits, entry.CreatedAt, entry.ExpiresAt,
+	)
+	return err
+}
+
+// List returns the entries of a function, newest first, and their total
+func (s *SQLiteCache) List(functionID string, limit, offset int) ([]store.AICacheEntry, int64, error) {
+	now := time.Now().Unix()
+	var total int64
+	if err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM ai_cache WHERE function_id = ? AND expires_at > ?",
+		functionID, now,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT function_id, key, provider, model, request_json, response_json, hits, created_at, expires_at
+		 FROM ai_cache WHERE function_id = ? AND expires_at > ?
+		 ORDER BY created_at DESC, key LIMIT ? OFFSET ?`,
+		functionID, now, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := make([]store.AICacheEntry, 0)
+	for rows.Next() {
+		var entry store.AICacheEntry
+		if err := rows.Scan(
+			&entry.FunctionID, &entry.Key, &entry.Provider, &entry.Model, &entry.RequestJSON,
+			&entry.ResponseJSON, &entry.Hits, &entry.CreatedAt, &entry.ExpiresAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// Clear removes every entry of a function and returns how many there were
+func (s *SQLiteCache) Clear(functionID string) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM ai_cache WHERE function_id = ?", functionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
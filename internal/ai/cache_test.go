@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/env"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// caches returns every implementation, so each test runs against both
+func caches(t *testing.T) map[string]Cache {
+	return map[string]Cache{
+		"memory": NewMemoryCache(),
+		"sqlite": NewSQLiteCache(setupTestDB(t)),
+	}
+}
+
+func TestCache(t *testing.T) {
+	for name, cache := range caches(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now().Unix()
+			for _, entry := range []store.AICacheEntry{
+				{FunctionID: "func-1", Key: "a", Provider: "openai", Model: "gpt-4o-mini", RequestJSON: "{}", ResponseJSON: `{"content":"a"}`, CreatedAt: now - 10, ExpiresAt: now + 60},
+				{FunctionID: "func-1", Key: "b", Provider: "openai", Model: "gpt-4o-mini", RequestJSON: "{}", ResponseJSON: `{"content":"b"}`, CreatedAt: now, ExpiresAt: now + 60},
+				{FunctionID: "func-1", Key: "old", Provider: "openai", Model: "gpt-4o-mini", RequestJSON: "{}", ResponseJSON: "{}", CreatedAt: now - 120, ExpiresAt: now - 60},
+				{FunctionID: "func-2", Key: "a", Provider: "openai", Model: "gpt-4o-mini", RequestJSON: "{}", ResponseJSON: "{}", CreatedAt: now, ExpiresAt: now + 60},
+			} {
+				if err := cache.Set(entry); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			entry, err := cache.Get("func-1", "a")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry == nil || entry.ResponseJSON != `{"content":"a"}` || entry.Hits != 1 {
+				t.Errorf("unexpected entry: %+v", entry)
+			}
+			if entry, _ := cache.Get("func-1", "old"); entry != nil {
+				t.Errorf("expected expired entries to be skipped, got %+v", entry)
+			}
+			if entry, _ := cache.Get("func-1", "missing"); entry != nil {
+				t.Errorf("expected a miss, got %+v", entry)
+			}
+
+			entries, total, err := cache.List("func-1", 10, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if total != 2 || len(entries) != 2 || entries[0].Key != "b" || entries[1].Hits != 1 {
+				t.Errorf("expected live entries newest first, got %+v (total %d)", entries, total)
+			}
+
+			if _, err := cache.Clear("func-1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, total, _ := cache.List("func-1", 10, 0); total != 0 {
+				t.Errorf("expected func-1 to be cleared, got %d entries", total)
+			}
+			if _, total, _ := cache.List("func-2", 10, 0); total != 1 {
+				t.Errorf("expected func-2 to be kept, got %d entries", total)
+			}
+		})
+	}
+}
+
+func TestChat_Cache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "gpt-4o-mini", "choices": [{"message": {"content": "{\"spam\": false}"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 600, "completion_tokens": 400}}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OPENAI_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "OPENAI_ENDPOINT", server.URL)
+	cache := NewMemoryCache()
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore).WithAccounting(Accounting{
+		Usage:  NewMemoryUsageStore(),
+		Budget: Budget{DailyTokens: 1000},
+	}).WithCache(cache)
+	req := ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Is this spam?"}},
+		CacheTTL: time.Minute,
+	}
+
+	first, err := client.Chat("func-1", req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Cached {
+		t.Error("expected the first response not to be cached")
+	}
+
+	// Hits are served over budget, without calling the provider
+	var tokens []string
+	req.OnToken = func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	}
+	second, err := client.Chat("func-1", req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !second.Cached || second.Content != first.Content || second.StopReason != StopReasonStop {
+		t.Errorf("unexpected cached response: %+v", second)
+	}
+	if second.Cost == nil || *second.Cost != 0 || second.Usage != (Usage{}) {
+		t.Errorf("expected a free hit, got cost %v and usage %+v", second.Cost, second.Usage)
+	}
+	if len(tokens) != 1 || tokens[0] != first.Content {
+		t.Errorf("expected the cached reply as one token, got %q", tokens)
+	}
+	if calls != 1 {
+		t.Errorf("expected one provider call, got %d", calls)
+	}
+
+	// Other parameters are another entry, refused over budget
+	req.Temperature = 0.5
+	if _, err := client.Chat("func-1", req); err == nil {
+		t.Error("expected a budget error for a cache miss")
+	}
+
+	entries, _, _ := cache.List("func-1", 10, 0)
+	if len(entries) != 1 || entries[0].Hits != 1 || entries[0].Provider != "openai" {
+		t.Errorf("unexpected cache entries: %+v", entries)
+	}
+}
+
+func TestChat_CacheMasksStoredRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "gpt-4o-mini", "choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 10, "completion_tokens": 2}}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OPENAI_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "OPENAI_ENDPOINT", server.URL)
+	cache := NewMemoryCache()
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore).WithCache(cache)
+	req := ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Log in"}},
+		Tools: []Tool{{
+			Name: "login",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"password": map[string]any{"type": "string"}},
+			},
+		}},
+		CacheTTL: time.Minute,
+	}
+
+	if _, err := client.Chat("func-1", req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, _, _ := cache.List("func-1", 10, 0)
+	if len(entries) != 1 || !strings.Contains(entries[0].RequestJSON, `"password":"[REDACTED]"`) {
+		t.Fatalf("expected the stored request to be masked, got %+v", entries)
+	}
+
+	hit, err := client.Chat("func-1", req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hit.Cached || !strings.Contains(hit.RequestJSON, `"password":{"type":"string"}`) {
+		t.Errorf("expected the hit to return the unmasked request, got %s", hit.RequestJSON)
+	}
+}
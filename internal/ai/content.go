@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Content part types
+const (
+	PartText     = "text"
+	PartImage    = "image"
+	PartDocument = "document"
+)
+
+// Part is a piece of a multimodal message: text, or an image or document
+// given as base64 data or as a URL
+type Part struct {
+	Type      string // PartText, PartImage or PartDocument
+	Text      string
+	Data      string // Base64-encoded content
+	URL       string
+	MediaType string // e.g. "image/png" or "application/pdf", required with Data
+	Name      string // File name of a document (optional)
+}
+
+// Validate checks that the part has what its type needs
+func (p Part) Validate() error {
+	switch p.Type {
+	case PartText:
+		return nil
+	case PartImage, PartDocument:
+		switch {
+		case p.Data == "" && p.URL == "":
+			return fmt.Errorf("%s needs data or url", p.Type)
+		case p.Data != "" && p.URL != "":
+			return fmt.Errorf("%s takes data or url, not both", p.Type)
+		case p.Data != "" && p.MediaType == "":
+			return fmt.Errorf("%s data needs a media_type", p.Type)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown content part type %q (use text, image or document)", p.Type)
+	}
+}
+
+// dataURL returns the part's data as a data URL
+func (p Part) dataURL() string {
+	return "data:" + p.MediaType + ";base64," + p.Data
+}
+
+// text returns the text of a message, joining the text parts of
+// multimodal messages
+func (m Message) text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == PartText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
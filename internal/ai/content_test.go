@@ -0,0 +1,135 @@
+package ai
+
+import "testing"
+
+// receiptConversation asks about a receipt photo and a PDF invoice
+var receiptConversation = ChatRequest{
+	Model:     "test-model",
+	MaxTokens: 100,
+	Messages: []Message{
+		{Role: "system", Content: "Extract totals"},
+		{Role: "user", Parts: []Part{
+			{Type: PartText, Text: "What are the totals?"},
+			{Type: PartImage, Data: "iVBORw0KGgo=", MediaType: "image/png"},
+			{Type: PartImage, URL: "https://example.com/receipt.jpg"},
+			{Type: PartDocument, Data: "JVBERi0xLjc=", MediaType: "application/pdf", Name: "invoice.pdf"},
+		}},
+	},
+}
+
+func TestOpenAI_BuildRequestBody_Parts(t *testing.T) {
+	body, err := openAIProvider{}.buildRequestBody(receiptConversation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "test-model",
+		"max_tokens": 100,
+		"messages": [
+			{"role": "system", "content": "Extract totals"},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What are the totals?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}},
+				{"type": "image_url", "image_url": {"url": "https://example.com/receipt.jpg"}},
+				{"type": "file", "file": {"filename": "invoice.pdf", "file_data": "data:application/pdf;base64,JVBERi0xLjc="}}
+			]}
+		]
+	}`)
+
+	_, err = openAIProvider{}.buildRequestBody(ChatRequest{Messages: []Message{
+		{Role: "user", Parts: []Part{{Type: PartDocument, URL: "https://example.com/invoice.pdf"}}},
+	}})
+	if err == nil || err.Error() != "OpenAI does not accept document URLs, send the document data" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAnthropic_BuildRequestBody_Parts(t *testing.T) {
+	body, err := anthropicProvider{}.buildRequestBody(receiptConversation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "test-model",
+		"max_tokens": 100,
+		"system": "Extract totals",
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "What are the totals?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+				{"type": "image", "source": {"type": "url", "url": "https://example.com/receipt.jpg"}},
+				{"type": "document", "title": "invoice.pdf", "source": {"type": "base64", "media_type": "application/pdf", "data": "JVBERi0xLjc="}}
+			]}
+		]
+	}`)
+}
+
+func TestGemini_BuildRequestBody_Parts(t *testing.T) {
+	body, err := geminiProvider{}.buildRequestBody(receiptConversation)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"systemInstruction": {"parts": [{"text": "Extract totals"}]},
+		"contents": [
+			{"role": "user", "parts": [
+				{"text": "What are the totals?"},
+				{"inlineData": {"mimeType": "image/png", "data": "iVBORw0KGgo="}},
+				{"fileData": {"fileUri": "https://example.com/receipt.jpg"}},
+				{"inlineData": {"mimeType": "application/pdf", "data": "JVBERi0xLjc="}}
+			]}
+		],
+		"generationConfig": {"maxOutputTokens": 100}
+	}`)
+}
+
+func TestOllama_BuildRequestBody_Parts(t *testing.T) {
+	body, err := ollamaProvider{}.buildRequestBody(ChatRequest{
+		Model: "llava",
+		Messages: []Message{{Role: "user", Parts: []Part{
+			{Type: PartText, Text: "Describe"},
+			{Type: PartImage, Data: "iVBORw0KGgo=", MediaType: "image/png"},
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertJSON(t, body, `{
+		"model": "llava",
+		"messages": [{"role": "user", "content": "Describe", "images": ["iVBORw0KGgo="]}],
+		"stream": false,
+		"options": {}
+	}`)
+
+	if _, err := (ollamaProvider{}).buildRequestBody(receiptConversation); err == nil {
+		t.Error("expected an error for image URLs and documents")
+	}
+}
+
+func TestPart_Validate(t *testing.T) {
+	tests := []struct {
+		part     Part
+		expected string
+	}{
+		{Part{Type: PartText, Text: "Hi"}, ""},
+		{Part{Type: PartImage, URL: "https://example.com/a.png"}, ""},
+		{Part{Type: PartImage}, "image needs data or url"},
+		{Part{Type: PartImage, Data: "abc", URL: "https://example.com/a.png", MediaType: "image/png"}, "image takes data or url, not both"},
+		{Part{Type: PartDocument, Data: "abc"}, "document data needs a media_type"},
+		{Part{Type: "audio"}, `unknown content part type "audio" (use text, image or document)`},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if err := tt.part.Validate(); err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("%+v: expected error %q, got %q", tt.part, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// embedder is implemented by providers that can create embeddings
+type embedder interface {
+	// embedPath returns the API path of the embeddings endpoint.
+	embedPath() string
+	// buildEmbedBody constructs the provider-specific request payload from an EmbedRequest.
+	buildEmbedBody(req EmbedRequest) (any, error)
+	// parseEmbedResponse parses the provider's JSON response body into an EmbedResponse.
+	parseEmbedResponse(body string) (*EmbedResponse, error)
+}
+
+// EmbedRequest represents a unified embeddings request
+type EmbedRequest struct {
+	Provider string
+	Model    string
+	Input    []string
+	Endpoint string // Optional custom endpoint URL (overrides env)
+	// Policy is the network policy of the calling function, enforced on
+	// endpoints set by the function (optional)
+	Policy *internalhttp.Policy
+}
+
+// EmbedResponse contains one embedding per input, in input order
+type EmbedResponse struct {
+	Embeddings [][]float64
+	Model      string
+	Usage      Usage
+	Cost       *float64 // USD, nil when the model has no price
+	// Tracking info for logging/debugging
+	Endpoint     string // Full URL used for the request
+	RequestJSON  string // Raw request body JSON
+	ResponseJSON string // Raw response body JSON
+}
+
+// Embed creates embeddings for the input texts using the specified provider
+func (c *DefaultClient) Embed(functionID string, req EmbedRequest) (*EmbedResponse, error) {
+	p, apiKey, endpoint, err := c.getProviderConfig(functionID, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.(embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support embeddings", req.Provider)
+	}
+
+	// Allow endpoint override from request
+	if req.Endpoint != "" {
+		endpoint = req.Endpoint
+	}
+	if endpoint == "" {
+		endpoint = p.defaultEndpoint()
+	}
+
+	// Resolve model aliases
+	if model, ok := c.modelAliases(functionID, req.Provider)[req.Model]; ok {
+		req.Model = model
+	}
+
+	if err := c.checkBudget(functionID); err != nil {
+		return nil, err
+	}
+
+	fullURL := strings.TrimSuffix(endpoint, "/") + e.embedPath()
+
+	reqBody, err := e.buildEmbedBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %v", err)
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	// Create partial response with tracking info (returned even on error)
+	embedResp := &EmbedResponse{
+		Endpoint:    fullURL,
+		RequestJSON: string(jsonBody),
+	}
+
+	resp, err := c.httpClient.Post(internalhttp.Request{
+		URL:     fullURL,
+		Headers: p.headers(apiKey),
+		Body:    string(jsonBody),
+		Policy:  endpointPolicy(p, endpoint, req.Policy),
+	})
+	if err != nil {
+		return embedResp, fmt.Errorf("HTTP request failed: %v", err)
+	}
+	embedResp.ResponseJSON = resp.Body
+
+	parsedResp, err := e.parseEmbedResponse(resp.Body)
+	if err != nil {
+		return embedResp, err
+	}
+	if len(parsedResp.Embeddings) != len(req.Input) {
+		return embedResp, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(parsedResp.Embeddings))
+	}
+
+	embedResp.Embeddings = parsedResp.Embeddings
+	embedResp.Model = parsedResp.Model
+	embedResp.Usage = parsedResp.Usage
+	embedResp.Cost = c.account(functionID, req.Provider, req.Model, embedResp.Usage)
+	return embedResp, nil
+}
+
+func (openAIProvider) embedPath() string { return "/embeddings" }
+
+func (openAIProvider) buildEmbedBody(req EmbedRequest) (any, error) {
+	return struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{
+		Model: req.Model,
+		Input: req.Input,
+	}, nil
+}
+
+// openAIEmbedding is an embedding with the index of its input
+type openAIEmbedding struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+func (openAIProvider) parseEmbedResponse(body string) (*EmbedResponse, error) {
+	var resp struct {
+		Model string            `json:"model"`
+		Data  []openAIEmbedding `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("OpenAI API error: %s", resp.Error.Message)
+	}
+
+	// Embeddings are returned with the index of their input
+	slices.SortFunc(resp.Data, func(a, b openAIEmbedding) int { return a.Index - b.Index })
+	embeddings := make([][]float64, len(resp.Data))
+	for i, d := range resp.Data {
+		embeddings[i] = d.Embedding
+	}
+
+	return &EmbedResponse{
+		Embeddings: embeddings,
+		Model:      resp.Model,
+		Usage:      Usage{InputTokens: resp.Usage.PromptTokens},
+	}, nil
+}
+
+func (ollamaProvider) embedPath() string { return "/api/embed" }
+
+func (ollamaProvider) buildEmbedBody(req EmbedRequest) (any, error) {
+	return struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{
+		Model: req.Model,
+		Input: req.Input,
+	}, nil
+}
+
+func (ollamaProvider) parseEmbedResponse(body string) (*EmbedResponse, error) {
+	var resp struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+		Error           string      `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama API error: %s", resp.Error)
+	}
+
+	return &EmbedResponse{
+		Embeddings: resp.Embeddings,
+		Model:      resp.Model,
+		Usage:      Usage{InputTokens: resp.PromptEvalCount},
+	}, nil
+}
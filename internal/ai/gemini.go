@@ -0,0 +1,270 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// geminiProvider implements provider for Google Gemini
+type geminiProvider struct{}
+
+func (geminiProvider) defaultEndpoint() string { return "https://generativelanguage.googleapis.com" }
+
+func (geminiProvider) urlPath(model string) string {
+	return "/v1beta/models/" + url.PathEscape(model) + ":generateContent"
+}
+
+func (geminiProvider) headers(apiKey string) internalhttp.Headers {
+	return internalhttp.Headers{
+		"Content-Type":   "application/json",
+		"x-goog-api-key": apiKey,
+	}
+}
+
+// geminiContent is a turn of the conversation, made of parts
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is text, inline data, a file, a function call or a function
+// response
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiBlob is base64 data sent with the request
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// geminiFileData is a file Gemini fetches by URI
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+// geminiParts converts message parts to the Gemini format
+func geminiParts(parts []Part) []geminiPart {
+	var converted []geminiPart
+	for _, part := range parts {
+		switch {
+		case part.Type == PartText:
+			converted = append(converted, geminiPart{Text: part.Text})
+		case part.URL != "":
+			converted = append(converted, geminiPart{FileData: &geminiFileData{MimeType: part.MediaType, FileURI: part.URL}})
+		default:
+			converted = append(converted, geminiPart{InlineData: &geminiBlob{MimeType: part.MediaType, Data: part.Data}})
+		}
+	}
+	return converted
+}
+
+// geminiFunctionCall is a function call requested by the model
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// geminiFunctionResponse is the result of a function call. Gemini matches
+// it with the call by name.
+type geminiFunctionResponse struct {
+	Name     string `json:"name"`
+	Response any    `json:"response"`
+}
+
+// geminiFunctionDeclaration is a tool definition in the Gemini format
+type geminiFunctionDeclaration struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+func (geminiProvider) buildRequestBody(req ChatRequest) (any, error) {
+	var system *geminiContent
+	var contents []geminiContent
+	// Call IDs are only unique within a response, so each tool result is
+	// matched with the latest call with its ID
+	names := make(map[string]string)
+	for _, msg := range req.Messages {
+		switch {
+		case msg.Role == "system":
+			system = &geminiContent{Parts: []geminiPart{{Text: msg.text()}}}
+		case msg.Role == "tool":
+			// Function responses of the same turn share one content
+			part := geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     names[msg.ToolCallID],
+				Response: geminiToolResult(msg.text()),
+			}}
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" && contents[n-1].Parts[0].FunctionResponse != nil {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+				continue
+			}
+			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		default:
+			role := msg.Role
+			if role == "assistant" {
+				role = "model"
+			}
+			content := geminiContent{Role: role, Parts: geminiParts(msg.Parts)}
+			if msg.Content != "" && len(msg.Parts) == 0 {
+				content.Parts = append(content.Parts, geminiPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				names[call.ID] = call.Name
+				args := json.RawMessage(call.Arguments)
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				}
+				content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: args}})
+			}
+			contents = append(contents, content)
+		}
+	}
+
+	type toolSet struct {
+		FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+	}
+	var tools []toolSet
+	if len(req.Tools) > 0 {
+		var declarations []geminiFunctionDeclaration
+		for _, tool := range req.Tools {
+			declarations = append(declarations, geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.schema(),
+			})
+		}
+		tools = []toolSet{{FunctionDeclarations: declarations}}
+	}
+
+	type generationConfig struct {
+		MaxOutputTokens    int            `json:"maxOutputTokens,omitempty"`
+		Temperature        float64        `json:"temperature,omitempty"`
+		ResponseMimeType   string         `json:"responseMimeType,omitempty"`
+		ResponseJSONSchema map[string]any `json:"responseJsonSchema,omitempty"`
+	}
+	body := struct {
+		SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
+		Contents          []geminiContent  `json:"contents"`
+		Tools             []toolSet        `json:"tools,omitempty"`
+		GenerationConfig  generationConfig `json:"generationConfig"`
+	}{
+		SystemInstruction: system,
+		Contents:          contents,
+		Tools:             tools,
+		GenerationConfig:  generationConfig{MaxOutputTokens: req.MaxTokens},
+	}
+	if req.Schema != nil {
+		body.GenerationConfig.ResponseMimeType = "application/json"
+		body.GenerationConfig.ResponseJSONSchema = req.Schema.Schema
+	}
+	if req.Temperature > 0 {
+		body.GenerationConfig.Temperature = req.Temperature
+	}
+	return body, nil
+}
+
+// geminiToolResult converts a tool result to the object Gemini expects:
+// JSON objects are sent as they are and anything else as {"result": ...}
+func geminiToolResult(content string) any {
+	if strings.HasPrefix(strings.TrimSpace(content), "{") && json.Valid([]byte(content)) {
+		return json.RawMessage(content)
+	}
+	return map[string]string{"result": content}
+}
+
+func (geminiProvider) parseResponse(body string) (*ChatResponse, error) {
+	var resp struct {
+		ModelVersion string `json:"modelVersion"`
+		Candidates   []struct {
+			Content struct {
+				Parts []struct {
+					Text         string              `json:"text"`
+					Thought      bool                `json:"thought"`
+					FunctionCall *geminiFunctionCall `json:"functionCall"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("gemini API error: %s", resp.Error.Message)
+	}
+
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini blocked the prompt: %s", resp.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	// Concatenate all text content, skipping thoughts, and collect tool calls
+	candidate := resp.Candidates[0]
+	var content string
+	var toolCalls []ToolCall
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = "call_" + strconv.Itoa(len(toolCalls)+1)
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: id, Name: part.FunctionCall.Name, Arguments: string(part.FunctionCall.Args)})
+		case !part.Thought:
+			content += part.Text
+		}
+	}
+
+	return &ChatResponse{
+		Content:    content,
+		ToolCalls:  toolCalls,
+		StopReason: geminiStopReason(candidate.FinishReason, len(toolCalls) > 0),
+		Model:      resp.ModelVersion,
+		Usage: Usage{
+			InputTokens:  resp.UsageMetadata.PromptTokenCount,
+			OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+		},
+	}, nil
+}
+
+// geminiStopReason maps Gemini finish reasons to the unified ones. Gemini
+// reports STOP when the model calls functions.
+func geminiStopReason(reason string, calledTools bool) string {
+	switch {
+	case calledTools:
+		return StopReasonToolCalls
+	case reason == "STOP":
+		return StopReasonStop
+	case reason == "MAX_TOKENS":
+		return StopReasonLength
+	default:
+		return strings.ToLower(reason)
+	}
+}
@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// ollamaProvider implements provider for Ollama using its native chat API
+type ollamaProvider struct{}
+
+func (ollamaProvider) defaultEndpoint() string { return "http://localhost:11434" }
+func (ollamaProvider) urlPath(string) string   { return "/api/chat" }
+
+// headers sends the API key only when set, for Ollama behind an
+// authenticating proxy
+func (ollamaProvider) headers(apiKey string) internalhttp.Headers {
+	headers := internalhttp.Headers{"Content-Type": "application/json"}
+	if apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	return headers
+}
+
+// ollamaMessage is a chat message in the Ollama format. Tool results
+// name the tool instead of referring to a call ID.
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"` // Base64 data
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+// ollamaToolCall is a function call requested by the model; unlike OpenAI
+// the arguments are an object
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func (ollamaProvider) buildRequestBody(req ChatRequest) (any, error) {
+	// Call IDs are only unique within a response, so each tool result is
+	// matched with the latest call with its ID
+	names := make(map[string]string)
+	messages := make([]ollamaMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		m := ollamaMessage{Role: msg.Role, Content: msg.text()}
+		for _, part := range msg.Parts {
+			switch {
+			case part.Type == PartDocument:
+				return nil, fmt.Errorf("ollama does not support documents")
+			case part.Type == PartImage && part.URL != "":
+				return nil, fmt.Errorf("ollama does not accept image URLs, send the image data")
+			case part.Type == PartImage:
+				m.Images = append(m.Images, part.Data)
+			}
+		}
+		if msg.Role == "tool" {
+			m.ToolName = names[msg.ToolCallID]
+		}
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Name
+			var tc ollamaToolCall
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = json.RawMessage(call.Arguments)
+			if len(tc.Function.Arguments) == 0 {
+				tc.Function.Arguments = json.RawMessage("{}")
+			}
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		messages = append(messages, m)
+	}
+
+	// Ollama accepts tools in the OpenAI format
+	var tools []openAITool
+	for _, tool := range req.Tools {
+		t := openAITool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.schema()
+		tools = append(tools, t)
+	}
+
+	type options struct {
+		NumPredict  int     `json:"num_predict,omitempty"`
+		Temperature float64 `json:"temperature,omitempty"`
+	}
+	body := struct {
+		Model    string          `json:"model"`
+		Messages []ollamaMessage `json:"messages"`
+		Tools    []openAITool    `json:"tools,omitempty"`
+		Format   map[string]any  `json:"format,omitempty"`
+		Stream   bool            `json:"stream"`
+		Options  options         `json:"options"`
+	}{
+		Model:    req.Model,
+		Messages: messages,
+		Tools:    tools,
+		Options:  options{NumPredict: req.MaxTokens},
+	}
+	if req.Schema != nil {
+		body.Format = req.Schema.Schema
+	}
+	if req.Temperature > 0 {
+		body.Options.Temperature = req.Temperature
+	}
+	return body, nil
+}
+
+func (ollamaProvider) parseResponse(body string) (*ChatResponse, error) {
+	var resp struct {
+		Model   string `json:"model"`
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []ollamaToolCall `json:"tool_calls"`
+		} `json:"message"`
+		DoneReason      string `json:"done_reason"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		Error           string `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama API error: %s", resp.Error)
+	}
+
+	// Ollama does not identify tool calls, so they are numbered
+	var toolCalls []ToolCall
+	for i, call := range resp.Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        "call_" + strconv.Itoa(i+1),
+			Name:      call.Function.Name,
+			Arguments: string(call.Function.Arguments),
+		})
+	}
+
+	// Ollama reports "stop" when the model calls tools
+	stopReason := resp.DoneReason
+	if len(toolCalls) > 0 {
+		stopReason = StopReasonToolCalls
+	}
+
+	return &ChatResponse{
+		Content:    resp.Message.Content,
+		ToolCalls:  toolCalls,
+		StopReason: stopReason,
+		Model:      resp.Model,
+		Usage: Usage{
+			InputTokens:  resp.PromptEvalCount,
+			OutputTokens: resp.EvalCount,
+		},
+	}, nil
+}
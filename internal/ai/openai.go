@@ -1,8 +1,10 @@
 package ai
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 )
@@ -11,7 +13,7 @@ import (
 type openAIProvider struct{}
 
 func (openAIProvider) defaultEndpoint() string { return "https://api.openai.com/v1" }
-func (openAIProvider) urlPath() string         { return "/chat/completions" }
+func (openAIProvider) urlPath(string) string   { return "/chat/completions" }
 
 func (openAIProvider) headers(apiKey string) internalhttp.Headers {
 	return internalhttp.Headers{
@@ -20,16 +22,152 @@ func (openAIProvider) headers(apiKey string) internalhttp.Headers {
 	}
 }
 
+// openAIMessage is a chat message in the OpenAI format. Content is a
+// string, a list of content parts or null.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is a part of a multimodal message in the OpenAI format
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+	File *struct {
+		Filename string `json:"filename,omitempty"`
+		FileData string `json:"file_data"`
+	} `json:"file,omitempty"`
+}
+
+// openAIContentParts converts message parts to the OpenAI format. Images
+// may be URLs or data URLs, documents are sent as file data.
+func openAIContentParts(parts []Part) ([]openAIContentPart, error) {
+	var converted []openAIContentPart
+	for _, part := range parts {
+		c := openAIContentPart{Type: part.Type}
+		switch part.Type {
+		case PartText:
+			c.Text = part.Text
+		case PartImage:
+			c.Type = "image_url"
+			c.ImageURL = &struct {
+				URL string `json:"url"`
+			}{URL: cmp.Or(part.URL, part.dataURL())}
+		case PartDocument:
+			if part.URL != "" {
+				return nil, fmt.Errorf("OpenAI does not accept document URLs, send the document data")
+			}
+			c.Type = "file"
+			c.File = &struct {
+				Filename string `json:"filename,omitempty"`
+				FileData string `json:"file_data"`
+			}{Filename: cmp.Or(part.Name, "document"), FileData: part.dataURL()}
+		}
+		converted = append(converted, c)
+	}
+	return converted, nil
+}
+
+// openAIToolCall is a function call requested by the model
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAITool is a function definition in the OpenAI tools format
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// openAIResponseFormat requests structured output matching a JSON schema
+type openAIResponseFormat struct {
+	Type       string `json:"type"`
+	JSONSchema struct {
+		Name   string         `json:"name"`
+		Schema map[string]any `json:"schema"`
+	} `json:"json_schema"`
+}
+
 func (openAIProvider) buildRequestBody(req ChatRequest) (any, error) {
+	messages := make([]openAIMessage, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		m := openAIMessage{Role: msg.Role, ToolCallID: msg.ToolCallID}
+		// Assistant messages that only call tools have null content
+		if len(msg.Parts) > 0 {
+			parts, err := openAIContentParts(msg.Parts)
+			if err != nil {
+				return nil, err
+			}
+			m.Content = parts
+		} else if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			content := msg.Content
+			m.Content = &content
+		}
+		for _, call := range msg.ToolCalls {
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.Arguments
+			m.ToolCalls = append(m.ToolCalls, tc)
+		}
+		messages = append(messages, m)
+	}
+
+	var tools []openAITool
+	for _, tool := range req.Tools {
+		t := openAITool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.schema()
+		tools = append(tools, t)
+	}
+
+	var responseFormat *openAIResponseFormat
+	if req.Schema != nil {
+		responseFormat = &openAIResponseFormat{Type: "json_schema"}
+		responseFormat.JSONSchema.Name = req.Schema.Name
+		responseFormat.JSONSchema.Schema = req.Schema.Schema
+	}
+
+	// Streams end with a chunk carrying the usage
+	type streamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	}
+	var streamOpts *streamOptions
+	if req.OnToken != nil {
+		streamOpts = &streamOptions{IncludeUsage: true}
+	}
+
 	body := struct {
-		Model       string    `json:"model"`
-		Messages    []Message `json:"messages"`
-		MaxTokens   int       `json:"max_tokens,omitempty"`
-		Temperature float64   `json:"temperature,omitempty"`
+		Model          string                `json:"model"`
+		Messages       []openAIMessage       `json:"messages"`
+		Tools          []openAITool          `json:"tools,omitempty"`
+		ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+		MaxTokens      int                   `json:"max_tokens,omitempty"`
+		Temperature    float64               `json:"temperature,omitempty"`
+		Stream         bool                  `json:"stream,omitempty"`
+		StreamOptions  *streamOptions        `json:"stream_options,omitempty"`
 	}{
-		Model:     req.Model,
-		Messages:  req.Messages,
-		MaxTokens: req.MaxTokens,
+		Model:          req.Model,
+		Messages:       messages,
+		Tools:          tools,
+		ResponseFormat: responseFormat,
+		MaxTokens:      req.MaxTokens,
+		Stream:         req.OnToken != nil,
+		StreamOptions:  streamOpts,
 	}
 	if req.Temperature > 0 {
 		body.Temperature = req.Temperature
@@ -42,8 +180,10 @@ func (openAIProvider) parseResponse(body string) (*ChatResponse, error) {
 		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string           `json:"content"`
+				ToolCalls []openAIToolCall `json:"tool_calls"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Usage struct {
 			PromptTokens     int `json:"prompt_tokens"`
@@ -66,12 +206,142 @@ func (openAIProvider) parseResponse(body string) (*ChatResponse, error) {
 		return nil, fmt.Errorf("no response from OpenAI")
 	}
 
+	choice := resp.Choices[0]
+	var toolCalls []ToolCall
+	for _, call := range choice.Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
 	return &ChatResponse{
-		Content: resp.Choices[0].Message.Content,
-		Model:   resp.Model,
+		Content:    choice.Message.Content,
+		ToolCalls:  toolCalls,
+		StopReason: choice.FinishReason,
+		Model:      resp.Model,
 		Usage: Usage{
 			InputTokens:  resp.Usage.PromptTokens,
 			OutputTokens: resp.Usage.CompletionTokens,
 		},
 	}, nil
 }
+
+func (openAIProvider) newChatStream() chatStream { return &openAIStream{} }
+
+// openAIStream assembles a chat completion from its streamed chunks
+type openAIStream struct {
+	received     bool
+	model        string
+	content      strings.Builder
+	toolCalls    []ToolCall
+	finishReason string
+	usage        Usage
+}
+
+func (s *openAIStream) event(_, data string) (string, error) {
+	if data == "[DONE]" {
+		return "", nil
+	}
+
+	var chunk struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Delta struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					Index    int    `json:"index"`
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return "", fmt.Errorf("failed to parse stream event: %v", err)
+	}
+	if chunk.Error != nil {
+		return "", fmt.Errorf("OpenAI API error: %s", chunk.Error.Message)
+	}
+
+	if chunk.Model != "" {
+		s.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+	}
+	if len(chunk.Choices) == 0 {
+		return "", nil
+	}
+
+	// Tool calls arrive in pieces, identified by their index
+	s.received = true
+	choice := chunk.Choices[0]
+	for _, delta := range choice.Delta.ToolCalls {
+		for len(s.toolCalls) <= delta.Index {
+			s.toolCalls = append(s.toolCalls, ToolCall{})
+		}
+		call := &s.toolCalls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		call.Name += delta.Function.Name
+		call.Arguments += delta.Function.Arguments
+	}
+	if choice.FinishReason != "" {
+		s.finishReason = choice.FinishReason
+	}
+	s.content.WriteString(choice.Delta.Content)
+	return choice.Delta.Content, nil
+}
+
+func (s *openAIStream) reportedUsage() Usage { return s.usage }
+
+func (s *openAIStream) response() (*ChatResponse, error) {
+	if !s.received {
+		return nil, fmt.Errorf("no response from OpenAI")
+	}
+	return &ChatResponse{
+		Content:    s.content.String(),
+		ToolCalls:  s.toolCalls,
+		StopReason: s.finishReason,
+		Model:      s.model,
+		Usage:      s.usage,
+	}, nil
+}
+
+// openAICompatibleProvider implements provider for servers exposing the
+// OpenAI chat completions API (vLLM, LM Studio, Groq, ...), configured by a
+// profile in the function environment
+type openAICompatibleProvider struct {
+	openAIProvider
+	authHeader string
+}
+
+func (openAICompatibleProvider) defaultEndpoint() string { return "" }
+
+// headers sends the key as a bearer token in the Authorization header, or
+// as is in a custom header. Servers without authentication get no key.
+func (p openAICompatibleProvider) headers(apiKey string) internalhttp.Headers {
+	headers := internalhttp.Headers{"Content-Type": "application/json"}
+	switch {
+	case apiKey == "":
+	case p.authHeader == "" || strings.EqualFold(p.authHeader, "Authorization"):
+		headers["Authorization"] = "Bearer " + apiKey
+	default:
+		headers[p.authHeader] = apiKey
+	}
+	return headers
+}
@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// chatStreamer is implemented by providers that can stream chat completions
+// as Server-Sent Events. Their buildRequestBody asks for a stream when
+// ChatRequest.OnToken is set.
+type chatStreamer interface {
+	// newChatStream returns a decoder for the events of one response.
+	newChatStream() chatStream
+}
+
+// chatStream assembles a streamed chat completion from its events
+type chatStream interface {
+	// event handles the data of one event and returns the text it adds, if any.
+	event(name, data string) (string, error)
+	// response returns the assembled response once the stream has ended.
+	response() (*ChatResponse, error)
+	// reportedUsage returns the usage the provider reported so far.
+	reportedUsage() Usage
+}
+
+// estimateTokens estimates the number of tokens of a text of the given
+// size, at about four bytes per token
+func estimateTokens(bytes int) int {
+	return (bytes + 3) / 4
+}
+
+// partialUsage returns the usage of a stream that ended early. Providers
+// may report usage only once they finish, so tokens they did not report
+// are estimated from the request body and the text streamed so far.
+func partialUsage(reported Usage, requestBody string, streamed int) Usage {
+	usage := reported
+	if usage.InputTokens == 0 {
+		usage.InputTokens = estimateTokens(len(requestBody))
+	}
+	usage.OutputTokens = max(usage.OutputTokens, estimateTokens(streamed))
+	return usage
+}
+
+// streamProvider performs a streaming chat request, calling req.OnToken with
+// each piece of text as it arrives. The raw event stream is kept in
+// ResponseJSON for tracking, and the usage of a stream that was aborted in
+// chatResp.Usage.
+func (c *DefaultClient) streamProvider(req ChatRequest, p provider, s internalhttp.Streamer, httpReq internalhttp.Request, chatResp *ChatResponse) (*ChatResponse, error) {
+	decoder := p.(chatStreamer).newChatStream()
+	var eventName string
+	var data []string
+	var streamed int
+	dispatch := func() error {
+		defer func() { eventName, data = "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		token, err := decoder.event(eventName, strings.Join(data, "\n"))
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return nil
+		}
+		streamed += len(token)
+		return req.OnToken(token)
+	}
+
+	httpReq.Method = "POST"
+	resp, err := s.Stream(httpReq, func(line string) error {
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// A blank line ends the event; lines starting with ':' are comments
+			if line == "" {
+				return dispatch()
+			}
+		case "event":
+			eventName = value
+		case "data":
+			data = append(data, value)
+		}
+		return nil
+	})
+	chatResp.ResponseJSON = resp.Body
+	if err != nil {
+		// Errors of the token callback and the decoder come with a response
+		if resp.StatusCode == 0 {
+			return nil, fmt.Errorf("HTTP request failed: %v", err)
+		}
+		chatResp.Usage = partialUsage(decoder.reportedUsage(), httpReq.Body, streamed)
+		return nil, err
+	}
+
+	// Errors are reported as a regular JSON body
+	if !resp.IsSuccess() {
+		return p.parseResponse(resp.Body)
+	}
+	if err := dispatch(); err != nil {
+		chatResp.Usage = partialUsage(decoder.reportedUsage(), httpReq.Body, streamed)
+		return nil, err
+	}
+	return decoder.response()
+}
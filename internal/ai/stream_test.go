@@ -0,0 +1,248 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/env"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// sseServer replies to every request with the given Server-Sent Events,
+// checking that the request asked for a stream
+func sseServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("expected stream=true, got %v", body["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = w.Write([]byte(event + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func streamClient(server *httptest.Server) *DefaultClient {
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OPENAI_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "OPENAI_ENDPOINT", server.URL)
+	_ = envStore.Set("func-1", "ANTHROPIC_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "ANTHROPIC_ENDPOINT", server.URL)
+	_ = envStore.Set("func-1", "OLLAMA_ENDPOINT", server.URL)
+	return NewDefaultClient(internalhttp.NewDefaultClient(), envStore)
+}
+
+func TestChat_Stream_OpenAI(t *testing.T) {
+	server := sseServer(t,
+		`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"role": "assistant", "content": ""}}]}`,
+		`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"content": "Hel"}}]}`,
+		`data: {"model": "gpt-4o-mini", "choices": [{"delta": {"content": "lo"}, "finish_reason": "stop"}]}`,
+		`data: {"model": "gpt-4o-mini", "choices": [], "usage": {"prompt_tokens": 9, "completion_tokens": 2}}`,
+		`data: [DONE]`,
+	)
+	defer server.Close()
+
+	var tokens []string
+	resp, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		OnToken: func(token string) error {
+			tokens = append(tokens, token)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(tokens, "|") != "Hel|lo" {
+		t.Errorf("unexpected tokens: %q", tokens)
+	}
+	if resp.Content != "Hello" || resp.StopReason != StopReasonStop || resp.Model != "gpt-4o-mini" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Usage.InputTokens != 9 || resp.Usage.OutputTokens != 2 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+	if !strings.Contains(resp.RequestJSON, `"stream_options":{"include_usage":true}`) {
+		t.Errorf("expected usage to be requested, got %s", resp.RequestJSON)
+	}
+	if !strings.Contains(resp.ResponseJSON, "data: [DONE]") {
+		t.Errorf("expected the event stream to be kept, got %s", resp.ResponseJSON)
+	}
+}
+
+func TestChat_Stream_OpenAI_ToolCalls(t *testing.T) {
+	server := sseServer(t,
+		`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "id": "call_1", "function": {"name": "get_weather", "arguments": ""}}]}}]}`,
+		`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "{\"city\":"}}]}}]}`,
+		`data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "\"Lisbon\"}"}}]}, "finish_reason": "tool_calls"}]}`,
+		`data: [DONE]`,
+	)
+	defer server.Close()
+
+	resp, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Weather?"}},
+		OnToken:  func(string) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments != `{"city":"Lisbon"}` {
+		t.Errorf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.StopReason != StopReasonToolCalls {
+		t.Errorf("expected tool_calls stop reason, got %q", resp.StopReason)
+	}
+}
+
+func TestChat_Stream_Anthropic(t *testing.T) {
+	server := sseServer(t,
+		"event: message_start\ndata: {\"type\": \"message_start\", \"message\": {\"model\": \"claude-3-5-haiku\", \"usage\": {\"input_tokens\": 12, \"output_tokens\": 1}}}",
+		"event: content_block_start\ndata: {\"type\": \"content_block_start\", \"index\": 0, \"content_block\": {\"type\": \"text\", \"text\": \"\"}}",
+		"event: ping\ndata: {\"type\": \"ping\"}",
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"index\": 0, \"delta\": {\"type\": \"text_delta\", \"text\": \"Let me check\"}}",
+		"event: content_block_start\ndata: {\"type\": \"content_block_start\", \"index\": 1, \"content_block\": {\"type\": \"tool_use\", \"id\": \"toolu_1\", \"name\": \"get_order\", \"input\": {}}}",
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"index\": 1, \"delta\": {\"type\": \"input_json_delta\", \"partial_json\": \"{\\\"id\\\": 42}\"}}",
+		"event: message_delta\ndata: {\"type\": \"message_delta\", \"delta\": {\"stop_reason\": \"tool_use\"}, \"usage\": {\"output_tokens\": 20}}",
+		"event: message_stop\ndata: {\"type\": \"message_stop\"}",
+	)
+	defer server.Close()
+
+	var tokens []string
+	resp, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "anthropic",
+		Model:    "claude-3-5-haiku",
+		Messages: []Message{{Role: "user", Content: "Is order 42 shipped?"}},
+		OnToken: func(token string) error {
+			tokens = append(tokens, token)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(tokens, "|") != "Let me check" {
+		t.Errorf("unexpected tokens: %q", tokens)
+	}
+	if resp.Content != "Let me check" || resp.StopReason != StopReasonToolCalls {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "get_order" || resp.ToolCalls[0].Arguments != `{"id": 42}` {
+		t.Errorf("unexpected tool calls: %+v", resp.ToolCalls)
+	}
+	if resp.Usage.InputTokens != 12 || resp.Usage.OutputTokens != 20 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestChat_Stream_Anthropic_Error(t *testing.T) {
+	server := sseServer(t,
+		"event: error\ndata: {\"type\": \"error\", \"error\": {\"type\": \"overloaded_error\", \"message\": \"Overloaded\"}}",
+	)
+	defer server.Close()
+
+	_, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "anthropic",
+		Model:    "claude-3-5-haiku",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		OnToken:  func(string) error { return nil },
+	})
+	if err == nil || err.Error() != "anthropic API error: Overloaded" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestChat_Stream_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"message": "Invalid API key"}}`))
+	}))
+	defer server.Close()
+
+	resp, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		OnToken:  func(string) error { return nil },
+	})
+	if err == nil || err.Error() != "OpenAI API error: Invalid API key" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if resp == nil || !strings.Contains(resp.ResponseJSON, "Invalid API key") {
+		t.Errorf("expected the error body to be kept for tracking, got %+v", resp)
+	}
+}
+
+func TestChat_Stream_TokenError(t *testing.T) {
+	server := sseServer(t,
+		`data: {"choices": [{"delta": {"content": "one"}}]}`,
+		`data: {"choices": [{"delta": {"content": "two"}}]}`,
+	)
+	defer server.Close()
+
+	usageStore := NewMemoryUsageStore()
+	client := streamClient(server).WithAccounting(Accounting{Usage: usageStore})
+
+	calls := 0
+	resp, err := client.Chat("func-1", ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		OnToken: func(string) error {
+			calls++
+			return errors.New("client went away")
+		},
+	})
+	if err == nil || err.Error() != "client went away" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the stream to stop after the first token, got %d calls", calls)
+	}
+
+	// The provider reports usage at the end, so the tokens used until the
+	// abort are estimated and count towards the budgets
+	if resp == nil || resp.Usage.InputTokens == 0 || resp.Usage.OutputTokens != 1 {
+		t.Fatalf("expected the partial usage, got %+v", resp)
+	}
+	totals, _ := usageStore.Totals("func-1", "")
+	if totals.Requests != 1 || totals.InputTokens != resp.Usage.InputTokens || totals.OutputTokens != 1 {
+		t.Errorf("expected the partial usage to be recorded, got %+v", totals)
+	}
+}
+
+func TestChat_Stream_FallbackSingleToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "llama3.2", "message": {"role": "assistant", "content": "Hello there"}, "done_reason": "stop"}`))
+	}))
+	defer server.Close()
+
+	var tokens []string
+	resp, err := streamClient(server).Chat("func-1", ChatRequest{
+		Provider: "ollama",
+		Model:    "llama3.2",
+		Messages: []Message{{Role: "user", Content: "Hi"}},
+		OnToken: func(token string) error {
+			tokens = append(tokens, token)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0] != "Hello there" || resp.Content != "Hello there" {
+		t.Errorf("expected the whole reply as one token, got %q", tokens)
+	}
+}
@@ -22,6 +22,8 @@ type TrackRequest struct {
 	ErrorMessage *string
 	InputTokens  *int
 	OutputTokens *int
+	Cost         *float64
+	Cached       bool
 	DurationMs   int64
 }
 
@@ -51,8 +53,9 @@ func (m *MemoryTracker) Track(executionID string, req TrackRequest) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Mask sensitive data in request/response JSON
-	maskedRequestJSON := masking.MaskJSONBody(req.RequestJSON)
+	// Mask sensitive data in request/response JSON, leaving out the data
+	// of images and documents
+	maskedRequestJSON := masking.TruncateBinaryJSON(masking.MaskJSONBody(req.RequestJSON))
 	var maskedResponseJSON *string
 	if req.ResponseJSON != nil {
 		masked := masking.MaskJSONBody(*req.ResponseJSON)
@@ -71,6 +74,8 @@ func (m *MemoryTracker) Track(executionID string, req TrackRequest) {
 		ErrorMessage: req.ErrorMessage,
 		InputTokens:  req.InputTokens,
 		OutputTokens: req.OutputTokens,
+		Cost:         req.Cost,
+		Cached:       req.Cached,
 		DurationMs:   req.DurationMs,
 		CreatedAt:    time.Now().Unix(),
 	}
@@ -137,8 +142,9 @@ func NewSQLiteTracker(db *sql.DB) *SQLiteTracker {
 
 // Track records an AI request
 func (s *SQLiteTracker) Track(executionID string, req TrackRequest) {
-	// Mask sensitive data in request/response JSON
-	maskedRequestJSON := masking.MaskJSONBody(req.RequestJSON)
+	// Mask sensitive data in request/response JSON, leaving out the data
+	// of images and documents
+	maskedRequestJSON := masking.TruncateBinaryJSON(masking.MaskJSONBody(req.RequestJSON))
 	var maskedResponseJSON *string
 	if req.ResponseJSON != nil {
 		masked := masking.MaskJSONBody(*req.ResponseJSON)
@@ -149,11 +155,11 @@ func (s *SQLiteTracker) Track(executionID string, req TrackRequest) {
 	_, err := s.db.Exec(
 		`INSERT INTO ai_requests
 		(id, execution_id, provider, model, endpoint, request_json, response_json,
-		 status, error_message, input_tokens, output_tokens, duration_ms, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		 status, error_message, input_tokens, output_tokens, cost, cached, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		id, executionID, req.Provider, req.Model, req.Endpoint, maskedRequestJSON,
 		maskedResponseJSON, req.Status, req.ErrorMessage, req.InputTokens,
-		req.OutputTokens, req.DurationMs, time.Now().Unix(),
+		req.OutputTokens, req.Cost, req.Cached, req.DurationMs, time.Now().Unix(),
 	)
 	if err != nil {
 		// Log error but don't fail the execution
@@ -165,7 +171,7 @@ func (s *SQLiteTracker) Track(executionID string, req TrackRequest) {
 func (s *SQLiteTracker) Requests(executionID string) []store.AIRequest {
 	rows, err := s.db.Query(
 		`SELECT id, execution_id, provider, model, endpoint, request_json, response_json,
-		        status, error_message, input_tokens, output_tokens, duration_ms, created_at
+		        status, error_message, input_tokens, output_tokens, cost, cached, duration_ms, created_at
 		 FROM ai_requests WHERE execution_id = ? ORDER BY created_at`,
 		executionID,
 	)
@@ -189,7 +195,7 @@ func (s *SQLiteTracker) RequestsPaginated(executionID string, limit, offset int)
 	// Get paginated requests
 	rows, err := s.db.Query(
 		`SELECT id, execution_id, provider, model, endpoint, request_json, response_json,
-		        status, error_message, input_tokens, output_tokens, duration_ms, created_at
+		        status, error_message, input_tokens, output_tokens, cost, cached, duration_ms, created_at
 		 FROM ai_requests WHERE execution_id = ? ORDER BY created_at LIMIT ? OFFSET ?`,
 		executionID, limit, offset,
 	)
@@ -208,11 +214,12 @@ func (s *SQLiteTracker) scanRequests(rows *sql.Rows) []store.AIRequest {
 		var req store.AIRequest
 		var responseJSON, errorMessage sql.NullString
 		var inputTokens, outputTokens sql.NullInt64
+		var cost sql.NullFloat64
 
 		if err := rows.Scan(
 			&req.ID, &req.ExecutionID, &req.Provider, &req.Model, &req.Endpoint,
 			&req.RequestJSON, &responseJSON, &req.Status, &errorMessage,
-			&inputTokens, &outputTokens, &req.DurationMs, &req.CreatedAt,
+			&inputTokens, &outputTokens, &cost, &req.Cached, &req.DurationMs, &req.CreatedAt,
 		); err != nil {
 			continue
 		}
@@ -231,6 +238,9 @@ func (s *SQLiteTracker) scanRequests(rows *sql.Rows) []store.AIRequest {
 			tokens := int(outputTokens.Int64)
 			req.OutputTokens = &tokens
 		}
+		if cost.Valid {
+			req.Cost = &cost.Float64
+		}
 
 		requests = append(requests, req)
 	}
@@ -0,0 +1,401 @@
+package ai
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// dayLayout formats the UTC day usage is accounted to
+const dayLayout = "2006-01-02"
+
+// Budget environment variable names. The server environment sets the
+// budgets of every function, and a function environment can only lower them.
+const (
+	DailyTokenBudgetEnv   = "AI_DAILY_TOKEN_BUDGET"
+	MonthlyTokenBudgetEnv = "AI_MONTHLY_TOKEN_BUDGET"
+	DailyCostBudgetEnv    = "AI_DAILY_COST_BUDGET"
+	MonthlyCostBudgetEnv  = "AI_MONTHLY_COST_BUDGET"
+)
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Pricing maps "provider/model" to the price of the model. An entry for
+// "provider/*" prices the models of the provider without their own entry.
+type Pricing map[string]Price
+
+// ParsePricing parses a price table such as
+// "openai/gpt-4o-mini=0.15:0.6,ollama/*=0:0", where the prices are USD per
+// million input and output tokens
+func ParsePricing(s string) (Pricing, error) {
+	pricing := make(Pricing)
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		provider, model, hasModel := strings.Cut(strings.TrimSpace(key), "/")
+		input, output, hasOutput := strings.Cut(value, ":")
+		if !ok || !hasModel || provider == "" || model == "" || !hasOutput {
+			return nil, fmt.Errorf("invalid price %q, expected provider/model=input:output", entry)
+		}
+		inputPrice, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil || inputPrice < 0 {
+			return nil, fmt.Errorf("invalid input price in %q", entry)
+		}
+		outputPrice, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil || outputPrice < 0 {
+			return nil, fmt.Errorf("invalid output price in %q", entry)
+		}
+		pricing[provider+"/"+model] = Price{Input: inputPrice, Output: outputPrice}
+	}
+	return pricing, nil
+}
+
+// Cost returns the cost of the usage in USD, and false when the model has
+// no price
+func (p Pricing) Cost(provider, model string, usage Usage) (float64, bool) {
+	price, ok := p[provider+"/"+model]
+	if !ok {
+		price, ok = p[provider+"/*"]
+	}
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.InputTokens)*price.Input + float64(usage.OutputTokens)*price.Output) / 1e6, true
+}
+
+// Budget limits the AI usage of a function per UTC day and month. Tokens
+// count input and output tokens, cost is in USD. Zero means no limit.
+type Budget struct {
+	DailyTokens   int
+	MonthlyTokens int
+	DailyCost     float64
+	MonthlyCost   float64
+}
+
+// ParseBudget reads a budget from the budget environment variables
+func ParseBudget(getenv func(string) string) (Budget, error) {
+	var budget Budget
+	for _, limit := range []struct {
+		name   string
+		tokens *int
+		cost   *float64
+	}{
+		{DailyTokenBudgetEnv, &budget.DailyTokens, nil},
+		{MonthlyTokenBudgetEnv, &budget.MonthlyTokens, nil},
+		{DailyCostBudgetEnv, nil, &budget.DailyCost},
+		{MonthlyCostBudgetEnv, nil, &budget.MonthlyCost},
+	} {
+		value := strings.TrimSpace(getenv(limit.name))
+		if value == "" {
+			continue
+		}
+		if limit.tokens != nil {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return Budget{}, fmt.Errorf("invalid %s: %q is not a number of tokens", limit.name, value)
+			}
+			*limit.tokens = n
+			continue
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n < 0 {
+			return Budget{}, fmt.Errorf("invalid %s: %q is not an amount in USD", limit.name, value)
+		}
+		*limit.cost = n
+	}
+	return budget, nil
+}
+
+// lowerTo returns the budget with each limit of lower that is stricter than
+// its own. Zero limits of lower are not set and keep the budget's limit.
+func (b Budget) lowerTo(lower Budget) Budget {
+	return Budget{
+		DailyTokens:   stricter(b.DailyTokens, lower.DailyTokens),
+		MonthlyTokens: stricter(b.MonthlyTokens, lower.MonthlyTokens),
+		DailyCost:     stricter(b.DailyCost, lower.DailyCost),
+		MonthlyCost:   stricter(b.MonthlyCost, lower.MonthlyCost),
+	}
+}
+
+// stricter returns the lower of two limits where zero means no limit
+func stricter[T int | float64](limit, lower T) T {
+	if lower > 0 && (limit == 0 || lower < limit) {
+		return lower
+	}
+	return limit
+}
+
+// BudgetError is returned when a function has used up one of its budgets
+type BudgetError struct {
+	Period string // "daily" or "monthly"
+	Limit  string // e.g. "100000 tokens" or "$5.00"
+	Used   string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s AI budget of %s exceeded (%s used)", e.Period, e.Limit, e.Used)
+}
+
+// check returns a *BudgetError if the usage of a period reached a limit
+func (b Budget) check(period string, tokens int, cost float64, used UsageTotals) error {
+	if spent := used.InputTokens + used.OutputTokens; tokens > 0 && spent >= tokens {
+		return &BudgetError{Period: period, Limit: fmt.Sprintf("%d tokens", tokens), Used: fmt.Sprintf("%d tokens", spent)}
+	}
+	if cost > 0 && used.Cost >= cost {
+		return &BudgetError{Period: period, Limit: formatUSD(cost), Used: formatUSD(used.Cost)}
+	}
+	return nil
+}
+
+// formatUSD formats an amount in USD, keeping cents of a cent
+func formatUSD(amount float64) string {
+	if amount < 0.01 && amount > 0 {
+		return "$" + strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+// Accounting prices AI requests and enforces the budgets of functions
+type Accounting struct {
+	Pricing Pricing    // Requests to models without a price cost nothing
+	Usage   UsageStore // Budgets are not enforced without it
+	Budget  Budget     // Applies to every function, which can only set lower limits
+}
+
+// WithAccounting makes the client price its requests, record their usage
+// and refuse requests of functions over budget
+func (c *DefaultClient) WithAccounting(accounting Accounting) *DefaultClient {
+	c.accounting = accounting
+	return c
+}
+
+// checkBudget returns a *BudgetError when the function has used up its
+// daily or monthly budget. A request may go over a budget, the next one
+// is refused. Functions can change their environment, so their own limits
+// only apply when they are lower than the server's.
+func (c *DefaultClient) checkBudget(functionID string) error {
+	if c.accounting.Usage == nil {
+		return nil
+	}
+	functionBudget, err := ParseBudget(func(name string) string {
+		value, _ := c.envStore.Get(functionID, name)
+		return value
+	})
+	if err != nil {
+		return err
+	}
+	budget := c.accounting.Budget.lowerTo(functionBudget)
+
+	now := time.Now().UTC()
+	if budget.DailyTokens > 0 || budget.DailyCost > 0 {
+		used, err := c.accounting.Usage.Totals(functionID, now.Format(dayLayout))
+		if err != nil {
+			return fmt.Errorf("failed to check AI budget: %v", err)
+		}
+		if err := budget.check("daily", budget.DailyTokens, budget.DailyCost, used); err != nil {
+			return err
+		}
+	}
+	if budget.MonthlyTokens > 0 || budget.MonthlyCost > 0 {
+		firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		used, err := c.accounting.Usage.Totals(functionID, firstDay.Format(dayLayout))
+		if err != nil {
+			return fmt.Errorf("failed to check AI budget: %v", err)
+		}
+		if err := budget.check("monthly", budget.MonthlyTokens, budget.MonthlyCost, used); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// account prices a request and adds it to the function's usage.
+// It returns the cost, or nil when the model has no price.
+func (c *DefaultClient) account(functionID, provider, model string, usage Usage) *float64 {
+	cost, priced := c.accounting.Pricing.Cost(provider, model, usage)
+	if c.accounting.Usage != nil {
+		if err := c.accounting.Usage.Record(functionID, provider, model, time.Now(), usage, cost); err != nil {
+			// Log error but don't fail the request
+			fmt.Printf("Failed to record AI usage: %v\n", err)
+		}
+	}
+	if !priced {
+		return nil
+	}
+	return &cost
+}
+
+// UsageTotals is the AI usage of a function over a period
+type UsageTotals struct {
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+// UsageStore keeps the daily AI usage of each function. Days are UTC dates
+// formatted as YYYY-MM-DD.
+type UsageStore interface {
+	// Record adds one request to the usage of the day of at.
+	Record(functionID, provider, model string, at time.Time, usage Usage, cost float64) error
+	// Totals returns the usage of a function from the day since onwards.
+	Totals(functionID, since string) (UsageTotals, error)
+	// Report returns the usage between the days from and to, inclusive,
+	// ordered by day, function, provider and model. An empty functionID
+	// reports every function.
+	Report(from, to, functionID string) ([]store.AIUsage, error)
+}
+
+// MemoryUsageStore is an in-memory implementation of UsageStore
+type MemoryUsageStore struct {
+	mu    sync.RWMutex
+	usage map[memoryUsageKey]store.AIUsage
+}
+
+type memoryUsageKey struct {
+	functionID, day, provider, model string
+}
+
+// NewMemoryUsageStore creates a new in-memory usage store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{usage: make(map[memoryUsageKey]store.AIUsage)}
+}
+
+// Record adds one request to the usage of the day of at
+func (m *MemoryUsageStore) Record(functionID, provider, model string, at time.Time, usage Usage, cost float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	day := at.UTC().Format(dayLayout)
+	key := memoryUsageKey{functionID, day, provider, model}
+	row, ok := m.usage[key]
+	if !ok {
+		row = store.AIUsage{FunctionID: functionID, Day: day, Provider: provider, Model: model}
+	}
+	row.Requests++
+	row.InputTokens += usage.InputTokens
+	row.OutputTokens += usage.OutputTokens
+	row.Cost += cost
+	m.usage[key] = row
+	return nil
+}
+
+// Totals returns the usage of a function from the day since onwards
+func (m *MemoryUsageStore) Totals(functionID, since string) (UsageTotals, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var totals UsageTotals
+	for key, row := range m.usage {
+		if key.functionID == functionID && key.day >= since {
+			totals.Requests += row.Requests
+			totals.InputTokens += row.InputTokens
+			totals.OutputTokens += row.OutputTokens
+			totals.Cost += row.Cost
+		}
+	}
+	return totals, nil
+}
+
+// Report returns the usage between the days from and to, inclusive
+func (m *MemoryUsageStore) Report(from, to, functionID string) ([]store.AIUsage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report := make([]store.AIUsage, 0)
+	for key, row := range m.usage {
+		if key.day >= from && key.day <= to && (functionID == "" || key.functionID == functionID) {
+			report = append(report, row)
+		}
+	}
+	slices.SortFunc(report, func(a, b store.AIUsage) int {
+		return cmp.Or(
+			strings.Compare(a.Day, b.Day),
+			strings.Compare(a.FunctionID, b.FunctionID),
+			strings.Compare(a.Provider, b.Provider),
+			strings.Compare(a.Model, b.Model),
+		)
+	})
+	return report, nil
+}
+
+// SQLiteUsageStore is a SQLite-backed implementation of UsageStore
+type SQLiteUsageStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteUsageStore creates a new SQLite-backed usage store
+func NewSQLiteUsageStore(db *sql.DB) *SQLiteUsageStore {
+	return &SQLiteUsageStore{db: db}
+}
+
+// Record adds one request to the usage of the day of at
+func (s *SQLiteUsageStore) Record(functionID, provider, model string, at time.Time, usage Usage, cost float64) error {
+	_, err := s.db.Exec(
+		`INSERT INTO ai_usage
+		(function_id, day, provider, model, requests, input_tokens, output_tokens, cost)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?)
+		ON CONFLICT(function_id, day, provider, model) DO UPDATE SET
+			requests = requests + 1,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cost = cost + excluded.cost`,
+		functionID, at.UTC().Format(dayLayout), provider, model,
+		usage.InputTokens, usage.OutputTokens, cost,
+	)
+	return err
+}
+
+// Totals returns the usage of a function from the day since onwards
+func (s *SQLiteUsageStore) Totals(functionID, since string) (UsageTotals, error) {
+	var totals UsageTotals
+	err := s.db.QueryRow(
+		`SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(input_tokens), 0),
+		        COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost), 0)
+		 FROM ai_usage WHERE function_id = ? AND day >= ?`,
+		functionID, since,
+	).Scan(&totals.Requests, &totals.InputTokens, &totals.OutputTokens, &totals.Cost)
+	return totals, err
+}
+
+// Report returns the usage between the days from and to, inclusive
+func (s *SQLiteUsageStore) Report(from, to, functionID string) ([]store.AIUsage, error) {
+	rows, err := s.db.Query(
+		`SELECT function_id, day, provider, model, requests, input_tokens, output_tokens, cost
+		 FROM ai_usage
+		 WHERE day >= ? AND day <= ? AND (? = '' OR function_id = ?)
+		 ORDER BY day, function_id, provider, model`,
+		from, to, functionID, functionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	report := make([]store.AIUsage, 0)
+	for rows.Next() {
+		var row store.AIUsage
+		if err := rows.Scan(
+			&row.FunctionID, &row.Day, &row.Provider, &row.Model,
+			&row.Requests, &row.InputTokens, &row.OutputTokens, &row.Cost,
+		); err != nil {
+			return nil, err
+		}
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
@@ -0,0 +1,220 @@
+package ai
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/env"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/migrate"
+	_ "modernc.org/sqlite"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	// Create a temporary database file
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	_ = tmpfile.Close()
+
+	db, err := sql.Open("sqlite", tmpfile.Name())
+	if err != nil {
+		_ = os.Remove(tmpfile.Name())
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// Run migrations
+	migrate.RunTest(t, db)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		_ = os.Remove(tmpfile.Name())
+	})
+
+	return db
+}
+
+// usageStores returns every implementation, so each test runs against both
+func usageStores(t *testing.T) map[string]UsageStore {
+	return map[string]UsageStore{
+		"memory": NewMemoryUsageStore(),
+		"sqlite": NewSQLiteUsageStore(setupTestDB(t)),
+	}
+}
+
+func TestParsePricing(t *testing.T) {
+	pricing, err := ParsePricing("openai/gpt-4o-mini=0.15:0.6, ollama/*=0:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cost, ok := pricing.Cost("openai", "gpt-4o-mini", Usage{InputTokens: 1_000_000, OutputTokens: 500_000})
+	if !ok || cost != 0.45 {
+		t.Errorf("expected cost 0.45, got %v (priced: %v)", cost, ok)
+	}
+	if cost, ok := pricing.Cost("ollama", "llama3.2", Usage{InputTokens: 100}); !ok || cost != 0 {
+		t.Errorf("expected the provider wildcard to price llama3.2 at 0, got %v (priced: %v)", cost, ok)
+	}
+	if _, ok := pricing.Cost("openai", "gpt-4o", Usage{InputTokens: 100}); ok {
+		t.Error("expected gpt-4o to have no price")
+	}
+
+	for _, invalid := range []string{"gpt-4o=1:2", "openai/gpt-4o=1", "openai/gpt-4o=a:2", "openai/gpt-4o=1:-2"} {
+		if _, err := ParsePricing(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestParseBudget(t *testing.T) {
+	env := map[string]string{
+		DailyTokenBudgetEnv:  "1000",
+		MonthlyCostBudgetEnv: "2.5",
+	}
+	budget, err := ParseBudget(func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Budget{DailyTokens: 1000, MonthlyCost: 2.5}
+	if budget != expected {
+		t.Errorf("expected %+v, got %+v", expected, budget)
+	}
+
+	// Lower limits replace those of the budget, zero ones keep them
+	lowered := Budget{DailyTokens: 10, MonthlyTokens: 500, DailyCost: 1}.lowerTo(budget)
+	expected = Budget{DailyTokens: 10, MonthlyTokens: 500, DailyCost: 1, MonthlyCost: 2.5}
+	if lowered != expected {
+		t.Errorf("expected %+v, got %+v", expected, lowered)
+	}
+
+	env[MonthlyTokenBudgetEnv] = "lots"
+	if _, err := ParseBudget(func(k string) string { return env[k] }); err == nil {
+		t.Error("expected an error for an invalid token budget")
+	}
+}
+
+func TestUsageStore(t *testing.T) {
+	for name, usageStore := range usageStores(t) {
+		t.Run(name, func(t *testing.T) {
+			day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+			day2 := time.Date(2025, 3, 2, 23, 0, 0, 0, time.UTC)
+			records := []struct {
+				functionID, model string
+				at                time.Time
+				cost              float64
+			}{
+				{"func-1", "gpt-4o-mini", day1, 0.5},
+				{"func-1", "gpt-4o-mini", day1, 0.25},
+				{"func-1", "gpt-4o", day2, 1},
+				{"func-2", "gpt-4o-mini", day2, 2},
+			}
+			for _, r := range records {
+				if err := usageStore.Record(r.functionID, "openai", r.model, r.at, Usage{InputTokens: 10, OutputTokens: 5}, r.cost); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			totals, err := usageStore.Totals("func-1", "2025-03-01")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if totals != (UsageTotals{Requests: 3, InputTokens: 30, OutputTokens: 15, Cost: 1.75}) {
+				t.Errorf("unexpected totals: %+v", totals)
+			}
+			if totals, _ := usageStore.Totals("func-1", "2025-03-02"); totals.Requests != 1 {
+				t.Errorf("expected one request since 2025-03-02, got %+v", totals)
+			}
+
+			report, err := usageStore.Report("2025-03-01", "2025-03-02", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(report) != 3 {
+				t.Fatalf("expected 3 rows, got %+v", report)
+			}
+			if report[0].Day != "2025-03-01" || report[0].Requests != 2 || report[0].Cost != 0.75 {
+				t.Errorf("unexpected first row: %+v", report[0])
+			}
+			if report[1].FunctionID != "func-1" || report[1].Model != "gpt-4o" || report[2].FunctionID != "func-2" {
+				t.Errorf("unexpected order: %+v", report)
+			}
+
+			report, err = usageStore.Report("2025-03-02", "2025-03-02", "func-2")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(report) != 1 || report[0].FunctionID != "func-2" {
+				t.Errorf("expected only func-2, got %+v", report)
+			}
+		})
+	}
+}
+
+func TestChat_Accounting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "gpt-4o-mini", "choices": [{"message": {"content": "Hi"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 600, "completion_tokens": 400}}`))
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("func-1", "OPENAI_API_KEY", "test-key")
+	_ = envStore.Set("func-1", "OPENAI_ENDPOINT", server.URL)
+	usageStore := NewMemoryUsageStore()
+	client := NewDefaultClient(internalhttp.NewDefaultClient(), envStore).WithAccounting(Accounting{
+		Pricing: Pricing{"openai/gpt-4o-mini": {Input: 1, Output: 2}},
+		Usage:   usageStore,
+		Budget:  Budget{DailyTokens: 1500},
+	})
+	req := ChatRequest{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	}
+
+	resp, err := client.Chat("func-1", req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Cost == nil || *resp.Cost != 0.0014 {
+		t.Errorf("expected cost 0.0014, got %v", resp.Cost)
+	}
+
+	// The second request goes over the budget, the third is refused
+	if _, err := client.Chat("func-1", req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = client.Chat("func-1", req)
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("expected a budget error, got %v", err)
+	}
+	if err.Error() != "daily AI budget of 1500 tokens exceeded (2000 tokens used)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The function environment can lower the server budget, not lift it
+	for _, limit := range []string{"0", "5000"} {
+		_ = envStore.Set("func-1", DailyTokenBudgetEnv, limit)
+		_, err = client.Chat("func-1", req)
+		if err == nil || err.Error() != "daily AI budget of 1500 tokens exceeded (2000 tokens used)" {
+			t.Errorf("%s: unexpected error: %v", limit, err)
+		}
+	}
+	_ = envStore.Set("func-1", DailyTokenBudgetEnv, "1000")
+	_, err = client.Chat("func-1", req)
+	if err == nil || err.Error() != "daily AI budget of 1000 tokens exceeded (2000 tokens used)" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	totals, _ := usageStore.Totals("func-1", time.Now().UTC().Format(dayLayout))
+	if totals.Requests != 2 {
+		t.Errorf("expected refused requests not to be recorded, got %+v", totals)
+	}
+}
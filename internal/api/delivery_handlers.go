@@ -0,0 +1,79 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/dimiro1/lunar/internal/email"
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+// maxDeliveryWebhookSize limits the body of email delivery webhooks, which
+// describe an email without its content
+const maxDeliveryWebhookSize = 1 << 20
+
+// EmailDeliveryWebhookHandler returns a handler for the Resend delivery
+// webhooks. It adds the events to the delivery timeline of the emails
+// functions sent, and runs the bounce function, when set, for new bounces.
+func EmailDeliveryWebhookHandler(deps ExecuteFunctionDeps, secret, bounceFunctionID string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDeliveryWebhookSize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		if err := email.VerifyResendWebhook(r.Header, body, secret); err != nil {
+			slog.Debug("Rejected email delivery webhook", "error", err)
+			writeError(w, http.StatusUnauthorized, "Invalid signature")
+			return
+		}
+
+		report, err := email.ParseResendDelivery(r.Header.Get("svix-id"), body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if report == nil {
+			// Acknowledged so the provider does not retry it
+			writeJSON(w, http.StatusOK, EmailDeliveryResponse{})
+			return
+		}
+
+		req, recorded, err := deps.EmailTracker.RecordDelivery(report.EmailID, report.Event)
+		if err != nil {
+			slog.Error("Failed to record email delivery event", "email_id", report.EmailID, "error", err)
+			writeError(w, http.StatusInternalServerError, "Failed to record delivery event")
+			return
+		}
+		response := EmailDeliveryResponse{Recorded: recorded}
+
+		if recorded && report.Bounce != nil && bounceFunctionID != "" {
+			to := report.To
+			if len(to) == 0 {
+				to = req.To
+			}
+			event := events.EmailBounceEvent{
+				EmailID:     report.EmailID,
+				ExecutionID: req.ExecutionID,
+				From:        req.From,
+				To:          to,
+				Subject:     req.Subject,
+				BounceType:  report.Bounce.Type,
+				SubType:     report.Bounce.SubType,
+				Message:     report.Bounce.Message,
+			}
+			executionID, status, err := executeEvent(r.Context(), deps, bounceFunctionID, event, event, map[string]string{
+				"email.id": report.EmailID,
+			})
+			response.Bounce = &EmailBounceExecution{FunctionID: bounceFunctionID, ExecutionID: executionID, Status: status}
+			if err != nil && executionID == "" {
+				// The event is recorded, so a retry would not run the function again
+				slog.Error("Failed to run the email bounce function", "function_id", bounceFunctionID, "email_id", report.EmailID, "error", err)
+				response.Bounce.Error = err.Error()
+			}
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -17,8 +18,11 @@ import (
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
 	"github.com/dimiro1/lunar/internal/masking"
+	"github.com/dimiro1/lunar/internal/metrics"
 	"github.com/dimiro1/lunar/internal/runner"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+	"github.com/dimiro1/lunar/internal/vectors"
 	"github.com/rs/xid"
 )
 
@@ -27,12 +31,17 @@ type ExecuteFunctionDeps struct {
 	DB               store.DB
 	Logger           logger.Logger
 	KVStore          kv.Store
+	VectorStore      vectors.Store
 	EnvStore         env.Store
 	HTTPClient       internalhttp.Client
+	HTTPTracker      internalhttp.Tracker
 	AIClient         ai.Client
 	AITracker        ai.Tracker
 	EmailClient      email.Client
+	EmailTemplates   email.TemplateStore
 	EmailTracker     email.Tracker
+	TraceStore       tracing.Store
+	TraceExporter    tracing.Exporter
 	ExecutionTimeout time.Duration
 	BaseURL          string
 }
@@ -236,7 +245,8 @@ func UpdateFunctionHandler(database store.DB) http.HandlerFunc {
 		}
 
 		// If metadata is provided, update the function
-		if req.Name != nil || req.Description != nil || req.Disabled != nil || req.RetentionDays != nil {
+		if req.Name != nil || req.Description != nil || req.Disabled != nil || req.RetentionDays != nil ||
+			req.NetworkPolicy != nil {
 			err := database.UpdateFunction(r.Context(), id, req)
 			if err != nil {
 				writeError(w, http.StatusNotFound, "Function not found")
@@ -500,33 +510,20 @@ func GetExecutionLogsHandler(database store.DB, appLogger logger.Logger) http.Ha
 			return
 		}
 
+		filter, err := parseLogFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Get the logs for this execution from the logger
 		params = params.Normalize()
-		logEntries, total := appLogger.EntriesPaginated(id, params.Limit, params.Offset)
+		logEntries, total := appLogger.EntriesFiltered(id, filter, params.Limit, params.Offset)
 
 		// Convert logger.LogEntry to API LogEntry format
 		apiLogs := make([]LogEntry, len(logEntries))
 		for i, entry := range logEntries {
-			// Map logger.LogLevel (int) to API LogLevel (string)
-			var level LogLevel
-			switch entry.Level {
-			case logger.Debug:
-				level = LogLevelDebug
-			case logger.Info:
-				level = LogLevelInfo
-			case logger.Warn:
-				level = LogLevelWarn
-			case logger.Error:
-				level = LogLevelError
-			default:
-				level = LogLevelInfo
-			}
-
-			apiLogs[i] = LogEntry{
-				Level:     level,
-				Message:   entry.Message,
-				CreatedAt: entry.Timestamp,
-			}
+			apiLogs[i] = toAPILogEntry(entry)
 		}
 
 		resp := PaginatedExecutionWithLogs{
@@ -573,6 +570,178 @@ func GetExecutionAIRequestsHandler(database store.DB, aiTracker ai.Tracker) http
 	}
 }
 
+// ListAICacheHandler returns a handler for listing the cached AI responses of a function
+func ListAICacheHandler(database store.DB, cache ai.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		params := parsePaginationParams(r)
+
+		// Verify function exists
+		if _, err := database.GetFunction(r.Context(), id); err != nil {
+			writeError(w, http.StatusNotFound, "Function not found")
+			return
+		}
+
+		params = params.Normalize()
+		entries, total, err := cache.List(id, params.Limit, params.Offset)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list AI cache")
+			return
+		}
+
+		resp := PaginatedAICacheResponse{
+			Entries: entries,
+			Pagination: store.PaginationInfo{
+				Total:  total,
+				Limit:  params.Limit,
+				Offset: params.Offset,
+			},
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// ClearAICacheHandler returns a handler for clearing the cached AI responses of a function
+func ClearAICacheHandler(database store.DB, cache ai.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		// Verify function exists
+		if _, err := database.GetFunction(r.Context(), id); err != nil {
+			writeError(w, http.StatusNotFound, "Function not found")
+			return
+		}
+
+		deleted, err := cache.Clear(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to clear AI cache")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ClearAICacheResponse{Deleted: deleted})
+	}
+}
+
+// ListEmailTemplatesHandler returns a handler for listing the email templates
+func ListEmailTemplatesHandler(templates email.TemplateStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := templates.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list email templates")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ListEmailTemplatesResponse{Templates: list})
+	}
+}
+
+// GetEmailTemplateHandler returns a handler for getting an email template
+func GetEmailTemplateHandler(templates email.TemplateStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl, err := templates.Get(r.PathValue("name"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to get email template")
+			return
+		}
+		if tmpl == nil {
+			writeError(w, http.StatusNotFound, "Email template not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tmpl)
+	}
+}
+
+// SaveEmailTemplateHandler returns a handler for creating or replacing an
+// email template. Templates that do not parse are rejected.
+func SaveEmailTemplateHandler(templates email.TemplateStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SaveEmailTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		tmpl := store.EmailTemplate{
+			Name:    r.PathValue("name"),
+			Subject: req.Subject,
+			Text:    req.Text,
+			HTML:    req.HTML,
+		}
+		if err := email.ValidateTemplate(tmpl); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		saved, err := templates.Save(tmpl)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to save email template")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, saved)
+	}
+}
+
+// DeleteEmailTemplateHandler returns a handler for deleting an email template
+func DeleteEmailTemplateHandler(templates email.TemplateStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deleted, err := templates.Delete(r.PathValue("name"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to delete email template")
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, "Email template not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetAIUsageHandler returns a handler for the AI usage report. The report
+// covers the last 30 days unless from and to (YYYY-MM-DD, UTC) are given,
+// and can be narrowed to one function with function_id.
+func GetAIUsageHandler(usageStore ai.UsageStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		today := time.Now().UTC()
+		from := today.AddDate(0, 0, -29).Format(time.DateOnly)
+		to := today.Format(time.DateOnly)
+		for name, value := range map[string]*string{"from": &from, "to": &to} {
+			if day := query.Get(name); day != "" {
+				if _, err := time.Parse(time.DateOnly, day); err != nil {
+					writeError(w, http.StatusBadRequest, "Invalid "+name+" date, expected YYYY-MM-DD")
+					return
+				}
+				*value = day
+			}
+		}
+		if from > to {
+			writeError(w, http.StatusBadRequest, "from must not be after to")
+			return
+		}
+
+		usage, err := usageStore.Report(from, to, query.Get("function_id"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to get AI usage")
+			return
+		}
+
+		resp := AIUsageResponse{From: from, To: to, Usage: usage}
+		for _, row := range usage {
+			resp.Total.Requests += row.Requests
+			resp.Total.InputTokens += row.InputTokens
+			resp.Total.OutputTokens += row.OutputTokens
+			resp.Total.Cost += row.Cost
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
 // GetExecutionEmailRequestsHandler returns a handler for getting email requests for an execution
 func GetExecutionEmailRequestsHandler(database store.DB, emailTracker email.Tracker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -603,6 +772,61 @@ func GetExecutionEmailRequestsHandler(database store.DB, emailTracker email.Trac
 	}
 }
 
+// GetExecutionHTTPRequestsHandler returns a handler for getting outbound HTTP requests for an execution
+func GetExecutionHTTPRequestsHandler(database store.DB, httpTracker internalhttp.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		params := parsePaginationParams(r)
+
+		// Verify execution exists
+		_, err := database.GetExecution(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Execution not found")
+			return
+		}
+
+		// Get HTTP requests for this execution
+		params = params.Normalize()
+		httpRequests, total := httpTracker.RequestsPaginated(id, params.Limit, params.Offset)
+
+		resp := PaginatedHTTPRequestsResponse{
+			HTTPRequests: httpRequests,
+			Pagination: store.PaginationInfo{
+				Total:  total,
+				Limit:  params.Limit,
+				Offset: params.Offset,
+			},
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// GetExecutionTraceHandler returns a handler for getting the trace of an execution
+func GetExecutionTraceHandler(database store.DB, traceStore tracing.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		// Verify execution exists
+		_, err := database.GetExecution(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Execution not found")
+			return
+		}
+
+		spans := traceStore.Spans(id)
+
+		resp := ExecutionTraceResponse{
+			Spans: spans,
+		}
+		if len(spans) > 0 {
+			resp.TraceID = spans[0].TraceID
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
 // ExecuteFunctionHandler returns a handler for executing functions
 func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -660,11 +884,22 @@ func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 			}
 		}
 
+		// Start the execution trace, continuing the caller's trace if present
+		trace := tracing.NewTrace(executionID, r.Header.Get(tracing.TraceparentHeader))
+		rootSpan := trace.Start("execution", store.SpanKindServer, map[string]string{
+			"faas.trigger":        "http",
+			"faas.invocation_id":  executionID,
+			"lunar.function_id":   functionID,
+			"lunar.version":       strconv.Itoa(version.Version),
+			"http.request.method": r.Method,
+		})
+
 		// Create execution context
 		execContext := &events.ExecutionContext{
 			ExecutionID: executionID,
 			FunctionID:  functionID,
 			StartedAt:   time.Now().Unix(),
+			TraceID:     trace.TraceID(),
 			Version:     strconv.Itoa(version.Version),
 			BaseURL:     deps.BaseURL,
 		}
@@ -695,27 +930,26 @@ func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 			return
 		}
 
-		// Prepare runner dependencies
-		runnerDeps := runner.Dependencies{
-			Logger:       deps.Logger,
-			KV:           deps.KVStore,
-			Env:          deps.EnvStore,
-			HTTP:         deps.HTTPClient,
-			AI:           deps.AIClient,
-			AITracker:    deps.AITracker,
-			Email:        deps.EmailClient,
-			EmailTracker: deps.EmailTracker,
-			Timeout:      deps.ExecutionTimeout,
-		}
+		// Set custom headers, sent right away if the function streams its response
+		w.Header().Set("X-Function-Id", functionID)
+		w.Header().Set("X-Function-Version-Id", version.ID)
+		w.Header().Set("X-Execution-Id", executionID)
+		w.Header().Set("X-Trace-Id", trace.TraceID())
 
 		// Execute the function
+		stream := &executionStream{w: w, rc: http.NewResponseController(w)}
 		req := runner.Request{
 			Context: execContext,
 			Event:   httpEvent,
 			Code:    version.Code,
+			Stream:  stream,
 		}
 
-		resp, runErr := runner.Run(r.Context(), runnerDeps, req)
+		var resp runner.Response
+		runnerDeps, runErr := newRunnerDependencies(deps, fn, trace)
+		if runErr == nil {
+			resp, runErr = runner.Run(r.Context(), runnerDeps, req)
+		}
 
 		// Calculate duration
 		duration := time.Since(startTime).Milliseconds()
@@ -737,11 +971,20 @@ func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 			slog.Error("Failed to update execution status", "execution_id", executionID, "error", err)
 		}
 
-		// Set custom headers
-		w.Header().Set("X-Function-Id", functionID)
-		w.Header().Set("X-Function-Version-Id", version.ID)
-		w.Header().Set("X-Execution-Id", executionID)
-		w.Header().Set("X-Execution-Duration-Ms", strconv.FormatInt(duration, 10))
+		metrics.ExecutionsTotal.Inc(functionID, string(status))
+		metrics.ExecutionDuration.Observe(time.Since(startTime).Seconds(), functionID, string(status))
+
+		// Finish and record the execution trace
+		if runErr != nil {
+			rootSpan.SetError(runErr.Error())
+		} else if resp.HTTP != nil {
+			rootSpan.SetAttribute("http.response.status_code", strconv.Itoa(resp.HTTP.StatusCode))
+			if status == store.ExecutionStatusError {
+				rootSpan.SetError("HTTP " + strconv.Itoa(resp.HTTP.StatusCode))
+			}
+		}
+		rootSpan.End()
+		recordTrace(deps, trace)
 
 		// If execution failed, log details and return generic error
 		if runErr != nil {
@@ -750,9 +993,17 @@ func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 				"execution_id", executionID,
 				"function_id", functionID,
 				"error", runErr)
-			writeError(w, http.StatusInternalServerError, "Function execution failed")
+			// A streamed response cannot be replaced, the client sees it end early
+			if !stream.started {
+				w.Header().Set("X-Execution-Duration-Ms", strconv.FormatInt(duration, 10))
+				writeError(w, http.StatusInternalServerError, "Function execution failed")
+			}
+			return
+		}
+		if resp.Streamed {
 			return
 		}
+		w.Header().Set("X-Execution-Duration-Ms", strconv.FormatInt(duration, 10))
 
 		// Return HTTP response
 		if resp.HTTP != nil {
@@ -777,3 +1028,78 @@ func ExecuteFunctionHandler(deps ExecuteFunctionDeps) http.HandlerFunc {
 		}
 	}
 }
+
+// newRunnerDependencies prepares the dependencies of a function execution
+func newRunnerDependencies(deps ExecuteFunctionDeps, fn store.Function, trace *tracing.Trace) (runner.Dependencies, error) {
+	// Restrict outbound requests to the function's network policy. The
+	// policy was validated when saved; if it no longer compiles the function
+	// does not run, since any fallback would allow hosts the policy denies.
+	httpPolicy, err := internalhttp.NewPolicy(fn.NetworkPolicy)
+	if err != nil {
+		return runner.Dependencies{}, fmt.Errorf("invalid network policy: %w", err)
+	}
+
+	return runner.Dependencies{
+		Logger:         deps.Logger,
+		KV:             deps.KVStore,
+		Vectors:        deps.VectorStore,
+		Env:            deps.EnvStore,
+		HTTP:           deps.HTTPClient,
+		HTTPTracker:    deps.HTTPTracker,
+		HTTPPolicy:     httpPolicy,
+		AI:             deps.AIClient,
+		AITracker:      deps.AITracker,
+		Email:          deps.EmailClient,
+		EmailTemplates: deps.EmailTemplates,
+		EmailTracker:   deps.EmailTracker,
+		Trace:          trace,
+		Timeout:        deps.ExecutionTimeout,
+	}, nil
+}
+
+// executionStream streams a function's HTTP response to the client, flushing
+// every chunk as it is written
+type executionStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+// Start sends the status code and headers, defaulting to plain text
+func (s *executionStream) Start(statusCode int, headers map[string]string) error {
+	for key, value := range headers {
+		s.w.Header().Set(key, value)
+	}
+	if s.w.Header().Get("Content-Type") == "" {
+		s.w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.WriteHeader(statusCode)
+	s.started = true
+	return s.rc.Flush()
+}
+
+// Write sends a chunk of the body
+func (s *executionStream) Write(chunk string) error {
+	if _, err := io.WriteString(s.w, chunk); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// recordTrace stores the spans of a finished execution and exports them in the background
+func recordTrace(deps ExecuteFunctionDeps, trace *tracing.Trace) {
+	spans := trace.Spans()
+
+	if deps.TraceStore != nil {
+		deps.TraceStore.Save(spans)
+	}
+
+	if deps.TraceExporter != nil {
+		go func() {
+			if err := deps.TraceExporter.Export(spans); err != nil {
+				slog.Error("Failed to export trace", "trace_id", trace.TraceID(), "error", err)
+			}
+		}()
+	}
+}
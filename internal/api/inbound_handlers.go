@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/email"
+	"github.com/dimiro1/lunar/internal/events"
+	"github.com/dimiro1/lunar/internal/masking"
+	"github.com/dimiro1/lunar/internal/metrics"
+	"github.com/dimiro1/lunar/internal/runner"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+)
+
+// inboundDispatcher runs the functions inbound emails are routed to. It
+// serves both the provider webhooks and the SMTP listener.
+type inboundDispatcher struct {
+	deps   ExecuteFunctionDeps
+	routes email.RouteStore
+}
+
+// Accepts reports whether the emails of a recipient are routed to a function
+func (d *inboundDispatcher) Accepts(recipient string) bool {
+	route, err := d.routes.Match(recipient)
+	if err != nil {
+		slog.Error("Failed to match inbound email route", "recipient", recipient, "error", err)
+	}
+	return route != nil
+}
+
+// Deliver runs the functions of the recipients of an email. Failed
+// executions are recorded rather than returned, only a failure to route the
+// email is an error.
+func (d *inboundDispatcher) Deliver(ctx context.Context, inbound email.InboundEmail) error {
+	_, err := d.dispatch(ctx, inbound)
+	return err
+}
+
+// dispatch runs each function routed by the recipients once, with the first
+// recipient routed to it as the event recipient
+func (d *inboundDispatcher) dispatch(ctx context.Context, inbound email.InboundEmail) ([]InboundEmailDelivery, error) {
+	deliveries := []InboundEmailDelivery{}
+	seen := make(map[string]bool)
+	for _, recipient := range inbound.Recipients {
+		route, err := d.routes.Match(recipient)
+		if err != nil {
+			return nil, err
+		}
+		if route == nil || seen[route.FunctionID] {
+			continue
+		}
+		seen[route.FunctionID] = true
+
+		event := inbound.Event
+		event.Recipient = recipient
+		delivery := InboundEmailDelivery{Recipient: recipient, FunctionID: route.FunctionID}
+		// Attachment contents are left out of the stored event
+		executionID, status, err := executeEvent(ctx, d.deps, route.FunctionID, event, masking.MaskEmailEvent(event), map[string]string{
+			"email.recipient": recipient,
+		})
+		delivery.ExecutionID = executionID
+		delivery.Status = status
+		if err != nil && executionID == "" {
+			// Execution errors stay in the execution, like for HTTP events
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// executeEvent runs a function with an event nobody waits a response for,
+// such as an inbound email, and records the execution with storedEvent as
+// its event. It returns an error without an execution ID when the function
+// cannot run.
+func executeEvent(ctx context.Context, deps ExecuteFunctionDeps, functionID string, event events.Event, storedEvent any, attributes map[string]string) (string, store.ExecutionStatus, error) {
+	startTime := time.Now()
+	executionID := generateID()
+
+	fn, err := deps.DB.GetFunction(ctx, functionID)
+	if err != nil {
+		return "", "", errors.New("function not found")
+	}
+	if fn.Disabled {
+		return "", "", errors.New("function is disabled")
+	}
+	version, err := deps.DB.GetActiveVersion(ctx, functionID)
+	if err != nil {
+		return "", "", errors.New("no active version found")
+	}
+
+	trace := tracing.NewTrace(executionID, "")
+	spanAttributes := map[string]string{
+		"faas.trigger":       "other",
+		"faas.invocation_id": executionID,
+		"lunar.function_id":  functionID,
+		"lunar.version":      strconv.Itoa(version.Version),
+		"lunar.event_type":   string(event.Type()),
+	}
+	maps.Copy(spanAttributes, attributes)
+	rootSpan := trace.Start("execution", store.SpanKindServer, spanAttributes)
+
+	execContext := &events.ExecutionContext{
+		ExecutionID: executionID,
+		FunctionID:  functionID,
+		StartedAt:   time.Now().Unix(),
+		TraceID:     trace.TraceID(),
+		Version:     strconv.Itoa(version.Version),
+		BaseURL:     deps.BaseURL,
+	}
+
+	eventJSONBytes, err := json.Marshal(storedEvent)
+	if err != nil {
+		return "", "", errors.New("failed to serialize event")
+	}
+	eventJSONStr := string(eventJSONBytes)
+
+	execution := store.Execution{
+		ID:                executionID,
+		FunctionID:        functionID,
+		FunctionVersionID: version.ID,
+		Status:            store.ExecutionStatusPending,
+		EventJSON:         &eventJSONStr,
+	}
+	if _, err := deps.DB.CreateExecution(ctx, execution); err != nil {
+		return "", "", errors.New("failed to create execution record")
+	}
+
+	runnerDeps, runErr := newRunnerDependencies(deps, fn, trace)
+	if runErr == nil {
+		_, runErr = runner.Run(ctx, runnerDeps, runner.Request{
+			Context: execContext,
+			Event:   event,
+			Code:    version.Code,
+		})
+	}
+
+	duration := time.Since(startTime).Milliseconds()
+	var errorMsg *string
+	status := store.ExecutionStatusSuccess
+	if runErr != nil {
+		status = store.ExecutionStatusError
+		errStr := runErr.Error()
+		errorMsg = &errStr
+	}
+
+	if err := deps.DB.UpdateExecution(ctx, executionID, status, &duration, errorMsg); err != nil {
+		slog.Error("Failed to update execution status", "execution_id", executionID, "error", err)
+	}
+
+	metrics.ExecutionsTotal.Inc(functionID, string(status))
+	metrics.ExecutionDuration.Observe(time.Since(startTime).Seconds(), functionID, string(status))
+
+	if runErr != nil {
+		rootSpan.SetError(runErr.Error())
+		deps.Logger.Error(functionID, runErr.Error())
+		slog.Error("Function execution failed",
+			"execution_id", executionID,
+			"function_id", functionID,
+			"error", runErr)
+	}
+	rootSpan.End()
+	recordTrace(deps, trace)
+
+	return executionID, status, runErr
+}
+
+// InboundEmailWebhookHandler returns a handler for the inbound email
+// webhooks of Resend, Mailgun and SendGrid. Providers cannot send the API
+// key, so the webhook URL carries its own token.
+func InboundEmailWebhookHandler(deps ExecuteFunctionDeps, routes email.RouteStore, token string) http.HandlerFunc {
+	dispatcher := &inboundDispatcher{deps: deps, routes: routes}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, email.MaxInboundSize)
+		inbound, err := email.ParseInboundWebhook(r.PathValue("provider"), r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		deliveries, err := dispatcher.dispatch(r.Context(), inbound)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to route inbound email")
+			return
+		}
+		if len(deliveries) == 0 {
+			writeError(w, http.StatusNotFound, "No route for the recipients")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, InboundEmailResponse{Deliveries: deliveries})
+	}
+}
+
+// ListInboundEmailRoutesHandler returns a handler for listing the inbound
+// email routes
+func ListInboundEmailRoutesHandler(routes email.RouteStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := routes.List()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to list inbound email routes")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ListInboundEmailRoutesResponse{Routes: list})
+	}
+}
+
+// SaveInboundEmailRouteHandler returns a handler for routing an address to
+// a function
+func SaveInboundEmailRouteHandler(database store.DB, routes email.RouteStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SaveInboundEmailRouteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		address, err := email.NormalizeRouteAddress(r.PathValue("address"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := database.GetFunction(r.Context(), req.FunctionID); err != nil {
+			writeError(w, http.StatusBadRequest, "Function not found")
+			return
+		}
+
+		saved, err := routes.Save(store.InboundEmailRoute{Address: address, FunctionID: req.FunctionID})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to save inbound email route")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, saved)
+	}
+}
+
+// DeleteInboundEmailRouteHandler returns a handler for deleting an inbound
+// email route
+func DeleteInboundEmailRouteHandler(routes email.RouteStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := email.NormalizeRouteAddress(r.PathValue("address"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Inbound email route not found")
+			return
+		}
+
+		deleted, err := routes.Delete(address)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to delete inbound email route")
+			return
+		}
+		if !deleted {
+			writeError(w, http.StatusNotFound, "Inbound email route not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
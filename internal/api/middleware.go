@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/dimiro1/lunar/internal/metrics"
 )
 
 // Middleware type
@@ -17,7 +19,7 @@ func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
 	return h
 }
 
-// LoggingMiddleware logs HTTP requests
+// LoggingMiddleware logs HTTP requests and records request metrics
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -35,6 +37,14 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			rw.statusCode,
 			duration,
 		)
+
+		// Use the matched route pattern to keep metric cardinality bounded
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestsTotal.Inc(route, r.Method, metrics.StatusLabel(rw.statusCode))
+		metrics.HTTPRequestDuration.Observe(duration.Seconds(), route, r.Method)
 	})
 }
 
@@ -43,8 +53,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Function-Id, X-Function-Version-Id, X-Execution-Id, X-Execution-Duration-Ms")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, traceparent")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Function-Id, X-Function-Version-Id, X-Execution-Id, X-Execution-Duration-Ms, X-Trace-Id")
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
@@ -80,3 +90,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController
+// can reach optional interfaces such as http.Flusher
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -11,7 +11,10 @@ import (
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/metrics"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+	"github.com/dimiro1/lunar/internal/vectors"
 )
 
 // Server represents the API server
@@ -20,10 +23,19 @@ type Server struct {
 	db              store.DB
 	execDeps        *ExecuteFunctionDeps
 	logger          logger.Logger
+	httpTracker     internalhttp.Tracker
 	aiTracker       ai.Tracker
+	aiUsage         ai.UsageStore
+	aiCache         ai.Cache
 	emailTracker    email.Tracker
+	emailRoutes     email.RouteStore
+	inboundToken    string
+	resendSecret    string
+	bounceFunction  string
+	traceStore      tracing.Store
 	frontendHandler http.Handler
 	apiKey          string
+	metricsToken    string
 	httpServer      *http.Server
 }
 
@@ -32,28 +44,53 @@ type ServerConfig struct {
 	DB               store.DB
 	Logger           logger.Logger
 	KVStore          kv.Store
+	VectorStore      vectors.Store
 	EnvStore         env.Store
 	HTTPClient       internalhttp.Client
+	HTTPTracker      internalhttp.Tracker
 	AITracker        ai.Tracker
+	AIPricing        ai.Pricing    // Prices AI requests by provider and model
+	AIBudget         ai.Budget     // Budget of every function, which can only lower it
+	AIUsage          ai.UsageStore // Enables AI budgets and the usage report when set
+	AICache          ai.Cache      // Enables the cache option of ai.chat when set
+	EmailTemplates   email.TemplateStore
 	EmailTracker     email.Tracker
+	EmailRoutes      email.RouteStore // Routes inbound email addresses to functions
+	InboundToken     string           // Enables the inbound email webhooks when set
+	ResendSecret     string           // Enables the Resend delivery webhook when set
+	BounceFunctionID string           // Function run for bounced emails, optional
+	TraceStore       tracing.Store
+	TraceExporter    tracing.Exporter // Exports traces to an OTLP collector when set
 	ExecutionTimeout time.Duration
 	FrontendHandler  http.Handler
 	APIKey           string
 	BaseURL          string
+	MetricsToken     string // Enables the /metrics endpoint when set
 }
 
 // NewServer creates a new API server with full configuration
 func NewServer(config ServerConfig) *Server {
+	aiClient := ai.NewDefaultClient(config.HTTPClient, config.EnvStore).WithAccounting(ai.Accounting{
+		Pricing: config.AIPricing,
+		Usage:   config.AIUsage,
+		Budget:  config.AIBudget,
+	}).WithCache(config.AICache)
+
 	execDeps := &ExecuteFunctionDeps{
 		DB:               config.DB,
 		Logger:           config.Logger,
 		KVStore:          config.KVStore,
+		VectorStore:      config.VectorStore,
 		EnvStore:         config.EnvStore,
 		HTTPClient:       config.HTTPClient,
-		AIClient:         ai.NewDefaultClient(config.HTTPClient, config.EnvStore),
+		HTTPTracker:      config.HTTPTracker,
+		AIClient:         aiClient,
 		AITracker:        config.AITracker,
 		EmailClient:      email.NewDefaultClient(config.EnvStore),
+		EmailTemplates:   config.EmailTemplates,
 		EmailTracker:     config.EmailTracker,
+		TraceStore:       config.TraceStore,
+		TraceExporter:    config.TraceExporter,
 		ExecutionTimeout: config.ExecutionTimeout,
 		BaseURL:          config.BaseURL,
 	}
@@ -63,10 +100,19 @@ func NewServer(config ServerConfig) *Server {
 		db:              config.DB,
 		execDeps:        execDeps,
 		logger:          config.Logger,
+		httpTracker:     config.HTTPTracker,
 		aiTracker:       config.AITracker,
+		aiUsage:         config.AIUsage,
+		aiCache:         config.AICache,
 		emailTracker:    config.EmailTracker,
+		emailRoutes:     config.EmailRoutes,
+		inboundToken:    config.InboundToken,
+		resendSecret:    config.ResendSecret,
+		bounceFunction:  config.BounceFunctionID,
+		traceStore:      config.TraceStore,
 		frontendHandler: config.FrontendHandler,
 		apiKey:          config.APIKey,
+		metricsToken:    config.MetricsToken,
 	}
 
 	s.setupRoutes()
@@ -85,6 +131,11 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("GET /docs/openapi.yaml", openAPISpecHandler)
 	s.mux.HandleFunc("HEAD /docs/openapi.yaml", openAPISpecHandler)
 
+	// Prometheus metrics (optional, protected by its own token)
+	if s.metricsToken != "" {
+		s.mux.Handle("GET /metrics", AuthMiddleware(s.metricsToken)(metrics.Handler(metrics.Default)))
+	}
+
 	// Protected API routes - wrap with auth middleware
 	authMiddleware := AuthMiddleware(s.apiKey)
 
@@ -104,10 +155,40 @@ func (s *Server) setupRoutes() {
 
 	// Execution History - only need DB
 	s.mux.Handle("GET /api/functions/{id}/executions", authMiddleware(http.HandlerFunc(ListExecutionsHandler(s.db))))
+	s.mux.Handle("GET /api/functions/{id}/logs/stream", authMiddleware(http.HandlerFunc(StreamFunctionLogsHandler(s.db, s.logger))))
 	s.mux.Handle("GET /api/executions/{id}", authMiddleware(http.HandlerFunc(GetExecutionHandler(s.db))))
 	s.mux.Handle("GET /api/executions/{id}/logs", authMiddleware(http.HandlerFunc(GetExecutionLogsHandler(s.db, s.logger))))
+	s.mux.Handle("GET /api/executions/{id}/logs/stream", authMiddleware(http.HandlerFunc(StreamExecutionLogsHandler(s.db, s.logger))))
+	s.mux.Handle("GET /api/executions/{id}/http-requests", authMiddleware(http.HandlerFunc(GetExecutionHTTPRequestsHandler(s.db, s.httpTracker))))
 	s.mux.Handle("GET /api/executions/{id}/ai-requests", authMiddleware(http.HandlerFunc(GetExecutionAIRequestsHandler(s.db, s.aiTracker))))
 	s.mux.Handle("GET /api/executions/{id}/email-requests", authMiddleware(http.HandlerFunc(GetExecutionEmailRequestsHandler(s.db, s.emailTracker))))
+	s.mux.Handle("GET /api/executions/{id}/trace", authMiddleware(http.HandlerFunc(GetExecutionTraceHandler(s.db, s.traceStore))))
+
+	// AI usage report and response cache
+	s.mux.Handle("GET /api/ai/usage", authMiddleware(http.HandlerFunc(GetAIUsageHandler(s.aiUsage))))
+	s.mux.Handle("GET /api/functions/{id}/ai-cache", authMiddleware(http.HandlerFunc(ListAICacheHandler(s.db, s.aiCache))))
+	s.mux.Handle("DELETE /api/functions/{id}/ai-cache", authMiddleware(http.HandlerFunc(ClearAICacheHandler(s.db, s.aiCache))))
+
+	// Email template routes
+	s.mux.Handle("GET /api/email-templates", authMiddleware(http.HandlerFunc(ListEmailTemplatesHandler(s.execDeps.EmailTemplates))))
+	s.mux.Handle("GET /api/email-templates/{name}", authMiddleware(http.HandlerFunc(GetEmailTemplateHandler(s.execDeps.EmailTemplates))))
+	s.mux.Handle("PUT /api/email-templates/{name}", authMiddleware(http.HandlerFunc(SaveEmailTemplateHandler(s.execDeps.EmailTemplates))))
+	s.mux.Handle("DELETE /api/email-templates/{name}", authMiddleware(http.HandlerFunc(DeleteEmailTemplateHandler(s.execDeps.EmailTemplates))))
+
+	// Inbound email routes
+	s.mux.Handle("GET /api/email-routes", authMiddleware(http.HandlerFunc(ListInboundEmailRoutesHandler(s.emailRoutes))))
+	s.mux.Handle("PUT /api/email-routes/{address}", authMiddleware(http.HandlerFunc(SaveInboundEmailRouteHandler(s.db, s.emailRoutes))))
+	s.mux.Handle("DELETE /api/email-routes/{address}", authMiddleware(http.HandlerFunc(DeleteInboundEmailRouteHandler(s.emailRoutes))))
+
+	// Inbound email webhooks (optional, protected by their own token)
+	if s.inboundToken != "" {
+		s.mux.HandleFunc("POST /inbound/email/{provider}", InboundEmailWebhookHandler(*s.execDeps, s.emailRoutes, s.inboundToken))
+	}
+
+	// Email delivery webhook (optional, protected by its signature)
+	if s.resendSecret != "" {
+		s.mux.HandleFunc("POST /inbound/email-events/resend", EmailDeliveryWebhookHandler(*s.execDeps, s.resendSecret, s.bounceFunction))
+	}
 
 	// Runtime Execution - needs all dependencies (NO AUTH - public endpoint)
 	executeHandler := ExecuteFunctionHandler(*s.execDeps)
@@ -122,6 +203,12 @@ func (s *Server) setupRoutes() {
 	}
 }
 
+// InboundEmailHandler returns the handler that runs the functions of the
+// emails received by an SMTP listener
+func (s *Server) InboundEmailHandler() email.InboundHandler {
+	return &inboundDispatcher{deps: *s.execDeps, routes: s.emailRoutes}
+}
+
 // Handler returns the http.Handler with all middleware applied
 func (s *Server) Handler() http.Handler {
 	return Chain(
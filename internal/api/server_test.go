@@ -1,19 +1,30 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dimiro1/lunar/internal/ai"
+	"github.com/dimiro1/lunar/internal/email"
 	"github.com/dimiro1/lunar/internal/env"
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 )
 
 // Helper function to create a test function in the database with an initial version
@@ -66,16 +77,29 @@ func createTestExecution(t *testing.T, database store.DB, functionID, versionID
 	return created
 }
 
+// testResendSecret signs the test delivery webhooks, the base64 of "test-secret"
+const testResendSecret = "whsec_dGVzdC1zZWNyZXQ="
+
 // Helper function to create a test server with full configuration
 func createTestServer(database store.DB) *Server {
 	return NewServer(ServerConfig{
-		DB:         database,
-		Logger:     logger.NewMemoryLogger(),
-		KVStore:    kv.NewMemoryStore(),
-		EnvStore:   env.NewMemoryStore(),
-		HTTPClient: internalhttp.NewDefaultClient(),
-		APIKey:     "test-api-key",
-		BaseURL:    "http://localhost:8080",
+		DB:               database,
+		Logger:           logger.NewMemoryLogger(),
+		KVStore:          kv.NewMemoryStore(),
+		EnvStore:         env.NewMemoryStore(),
+		HTTPClient:       internalhttp.NewDefaultClient(),
+		HTTPTracker:      internalhttp.NewMemoryTracker(),
+		AIUsage:          ai.NewMemoryUsageStore(),
+		AICache:          ai.NewMemoryCache(),
+		EmailTemplates:   email.NewMemoryTemplateStore(),
+		EmailTracker:     email.NewMemoryTracker(),
+		EmailRoutes:      email.NewMemoryRouteStore(),
+		InboundToken:     "test-inbound-token",
+		ResendSecret:     testResendSecret,
+		BounceFunctionID: "func_test_123",
+		TraceStore:       tracing.NewMemoryStore(),
+		APIKey:           "test-api-key",
+		BaseURL:          "http://localhost:8080",
 	})
 }
 
@@ -477,6 +501,84 @@ func TestGetExecutionLogs(t *testing.T) {
 	}
 }
 
+func TestGetExecutionLogs_Filters(t *testing.T) {
+	database := store.NewMemoryDB()
+	memLogger := logger.NewMemoryLogger()
+	server := NewServer(ServerConfig{
+		DB:     database,
+		Logger: memLogger,
+		APIKey: "test-api-key",
+	})
+
+	fn := createTestFunction(t, database)
+	ver := createTestVersion(t, database, fn.ID, "function handler(ctx, event)\n  return {statusCode = 200}\nend")
+	exec := createTestExecution(t, database, fn.ID, ver.ID)
+
+	memLogger.Write(logger.LogEntry{ExecutionID: exec.ID, Level: logger.Info, Message: "charged", Fields: map[string]any{"user": "u_1", "amount": 10.0}, Line: 7})
+	memLogger.Write(logger.LogEntry{ExecutionID: exec.ID, Level: logger.Warn, Message: "slow charge", Fields: map[string]any{"user": "u_2"}})
+	memLogger.Debug(exec.ID, "debugging")
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"no filter", "", []string{"charged", "slow charge", "debugging"}},
+		{"minimum level", "?level=info", []string{"charged", "slow charge"}},
+		{"search", "?search=CHARGE", []string{"charged", "slow charge"}},
+		{"field", "?field.user=u_1", []string{"charged"}},
+		{"numeric field", "?field.amount=10", []string{"charged"}},
+		{"combined", "?level=warn&field.user=u_1", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+exec.ID+"/logs"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var resp PaginatedExecutionWithLogs
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			messages := make([]string, len(resp.Logs))
+			for i, log := range resp.Logs {
+				messages[i] = log.Message
+			}
+			if strings.Join(messages, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %v, got %v", tt.expected, messages)
+			}
+			if resp.Pagination.Total != int64(len(tt.expected)) {
+				t.Errorf("expected total %d, got %d", len(tt.expected), resp.Pagination.Total)
+			}
+		})
+	}
+
+	t.Run("returns fields and line", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+exec.ID+"/logs?field.user=u_1", nil))
+
+		var resp PaginatedExecutionWithLogs
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Logs[0].Line != 7 || resp.Logs[0].Fields["user"] != "u_1" {
+			t.Errorf("expected fields and line, got %+v", resp.Logs[0])
+		}
+	})
+
+	t.Run("rejects invalid level", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+exec.ID+"/logs?level=loud", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
 func TestExecuteFunction(t *testing.T) {
 	t.Run("success with simple response", func(t *testing.T) {
 		database := store.NewMemoryDB()
@@ -907,6 +1009,122 @@ func TestUpdateFunction_RetentionDays(t *testing.T) {
 	}
 }
 
+func TestExecuteFunction_NetworkPolicy(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal"))
+	}))
+	defer internal.Close()
+
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+
+	fn := createTestFunction(t, database)
+	createTestVersion(t, database, fn.ID, `
+function handler(ctx, event)
+  local resp, err = http.get("`+internal.URL+`")
+  if err then
+    return { statusCode = 502, body = err }
+  end
+  return { statusCode = 200, body = resp.body }
+end
+`)
+
+	execute := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fn/"+fn.ID, nil))
+		return w
+	}
+
+	// Loopback addresses are denied by default
+	w := execute()
+	if w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "blocked by network policy") {
+		t.Fatalf("expected request to be blocked, got %d: %s", w.Code, w.Body.String())
+	}
+
+	requests := server.httpTracker.Requests(w.Header().Get("X-Execution-Id"))
+	if len(requests) != 1 || requests[0].Status != store.HTTPRequestStatusError {
+		t.Errorf("expected blocked request to be tracked as an error, got %+v", requests)
+	}
+
+	// Invalid policies are rejected
+	body, _ := json.Marshal(store.UpdateFunctionRequest{
+		NetworkPolicy: &store.NetworkPolicy{AllowHosts: []string{"not a host"}},
+	})
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, makeAuthRequest(http.MethodPut, "/api/functions/"+fn.ID, body))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid policy, got %d", rec.Code)
+	}
+
+	// Allowing the loopback range lets the request through
+	body, _ = json.Marshal(store.UpdateFunctionRequest{
+		NetworkPolicy: &store.NetworkPolicy{AllowHosts: []string{"127.0.0.0/8"}},
+	})
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, makeAuthRequest(http.MethodPut, "/api/functions/"+fn.ID, body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	w = execute()
+	if w.Code != http.StatusOK || w.Body.String() != "internal" {
+		t.Errorf("expected request to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A stored policy that no longer compiles fails closed
+	if err := database.UpdateFunction(context.Background(), fn.ID, store.UpdateFunctionRequest{
+		NetworkPolicy: &store.NetworkPolicy{AllowHosts: []string{"not a host"}},
+	}); err != nil {
+		t.Fatalf("failed to store policy: %v", err)
+	}
+
+	w = execute()
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected the execution to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+	execution, err := database.GetExecution(context.Background(), w.Header().Get("X-Execution-Id"))
+	if err != nil {
+		t.Fatalf("failed to get execution: %v", err)
+	}
+	if execution.Status != store.ExecutionStatusError || execution.ErrorMessage == nil || !strings.Contains(*execution.ErrorMessage, "invalid network policy") {
+		t.Errorf("expected the policy error on the execution, got %+v", execution)
+	}
+}
+
+func TestExecuteFunction_StreamedResponse(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+
+	fn := createTestFunction(t, database)
+	createTestVersion(t, database, fn.ID, `
+function handler(ctx, event)
+  stream.start({ statusCode = 201, headers = { ["Content-Type"] = "text/event-stream" } })
+  stream.write("data: one\n\n")
+  stream.write("data: two\n\n")
+  return { body = "data: done\n\n" }
+end
+`)
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fn/"+fn.ID, nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+	if w.Body.String() != "data: one\n\ndata: two\n\ndata: done\n\n" {
+		t.Errorf("unexpected body: %q", w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("expected event stream content type, got %q", w.Header().Get("Content-Type"))
+	}
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if w.Header().Get("X-Execution-Id") == "" {
+		t.Error("expected X-Execution-Id header")
+	}
+}
+
 func TestExecuteFunction_DisabledFunction(t *testing.T) {
 	database := store.NewMemoryDB()
 	server := NewServer(ServerConfig{
@@ -1285,3 +1503,860 @@ end
 		t.Errorf("Expected username field to be unchanged, got %v", bodyData["username"])
 	}
 }
+
+func TestMetricsEndpoint(t *testing.T) {
+	newServer := func(token string) *Server {
+		return NewServer(ServerConfig{
+			DB:           store.NewMemoryDB(),
+			Logger:       logger.NewMemoryLogger(),
+			KVStore:      kv.NewMemoryStore(),
+			EnvStore:     env.NewMemoryStore(),
+			HTTPClient:   internalhttp.NewDefaultClient(),
+			APIKey:       "test-api-key",
+			MetricsToken: token,
+		})
+	}
+
+	t.Run("disabled without token", func(t *testing.T) {
+		server := newServer("")
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404 when no metrics token is configured, got %d", w.Code)
+		}
+	})
+
+	t.Run("rejects wrong token", func(t *testing.T) {
+		server := newServer("metrics-token")
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer test-api-key")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("serves metrics with token", func(t *testing.T) {
+		server := newServer("metrics-token")
+
+		// Make a request first so the API counters have a series
+		server.Handler().ServeHTTP(httptest.NewRecorder(), makeAuthRequest(http.MethodGet, "/api/functions", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer metrics-token")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("expected text/plain Content-Type, got %q", ct)
+		}
+		if !strings.Contains(w.Body.String(), `lunar_http_requests_total{route="GET /api/functions",method="GET",status="200"}`) {
+			t.Errorf("expected API request counter in output, got:\n%s", w.Body.String())
+		}
+	})
+}
+
+func TestGetExecutionTrace(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+
+	fn := createTestFunction(t, database)
+	createTestVersion(t, database, fn.ID, `
+function handler(ctx, event)
+  kv.set("visits", "1")
+  return { statusCode = 200, body = "ok" }
+end
+`)
+
+	req := httptest.NewRequest(http.MethodGet, "/fn/"+fn.ID, nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Trace-Id"); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected X-Trace-Id to continue the incoming trace, got %q", got)
+	}
+
+	executionID := w.Header().Get("X-Execution-Id")
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+executionID+"/trace", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp ExecutionTraceResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected trace ID, got %q", resp.TraceID)
+	}
+
+	names := make(map[string]store.Span)
+	for _, s := range resp.Spans {
+		names[s.Name] = s
+	}
+	for _, name := range []string{"execution", "lua.load", "lua.handler", "kv.set"} {
+		if _, ok := names[name]; !ok {
+			t.Errorf("expected span %q in trace, got %+v", name, resp.Spans)
+		}
+	}
+
+	root := names["execution"]
+	if root.ParentID == nil || *root.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("expected root span parent to be the caller's span, got %v", root.ParentID)
+	}
+	if root.Attributes["http.response.status_code"] != "200" {
+		t.Errorf("expected status code attribute on root span, got %v", root.Attributes)
+	}
+}
+
+func TestGetExecutionTrace_NotFound(t *testing.T) {
+	server := createTestServer(store.NewMemoryDB())
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/missing/trace", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetExecutionHTTPRequests(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+
+	fn := createTestFunction(t, database)
+	version := createTestVersion(t, database, fn.ID, "function handler() end")
+	exec := createTestExecution(t, database, fn.ID, version.ID)
+
+	server.httpTracker.Track(exec.ID, internalhttp.TrackRequest{
+		Method:       "GET",
+		URL:          "https://api.example.com/users?api_key=secret",
+		StatusCode:   200,
+		ResponseBody: `{"ok": true}`,
+		DurationMs:   12,
+	})
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+exec.ID+"/http-requests", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp PaginatedHTTPRequestsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.HTTPRequests) != 1 || resp.Pagination.Total != 1 {
+		t.Fatalf("expected 1 HTTP request, got %d (total %d)", len(resp.HTTPRequests), resp.Pagination.Total)
+	}
+	if got := resp.HTTPRequests[0].URL; got != "https://api.example.com/users?api_key=[REDACTED]" {
+		t.Errorf("expected masked URL, got %q", got)
+	}
+}
+
+func TestGetExecutionHTTPRequests_NotFound(t *testing.T) {
+	server := createTestServer(store.NewMemoryDB())
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/missing/http-requests", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetAIUsage(t *testing.T) {
+	server := createTestServer(store.NewMemoryDB())
+
+	now := time.Now()
+	_ = server.aiUsage.Record("func-1", "openai", "gpt-4o-mini", now, ai.Usage{InputTokens: 100, OutputTokens: 50}, 0.5)
+	_ = server.aiUsage.Record("func-2", "anthropic", "claude-3-5-haiku", now, ai.Usage{InputTokens: 10, OutputTokens: 5}, 0.25)
+	_ = server.aiUsage.Record("func-1", "openai", "gpt-4o-mini", now.AddDate(0, 0, -40), ai.Usage{InputTokens: 1}, 1)
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/ai/usage", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp AIUsageResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Usage) != 2 {
+		t.Fatalf("expected the last 30 days only, got %+v", resp.Usage)
+	}
+	expected := AIUsageTotal{Requests: 2, InputTokens: 110, OutputTokens: 55, Cost: 0.75}
+	if resp.Total != expected {
+		t.Errorf("expected total %+v, got %+v", expected, resp.Total)
+	}
+
+	day := now.UTC().Format(time.DateOnly)
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/ai/usage?function_id=func-2&from="+day+"&to="+day, nil))
+
+	resp = AIUsageResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Usage) != 1 || resp.Usage[0].FunctionID != "func-2" || resp.From != day {
+		t.Errorf("expected only func-2, got %+v", resp)
+	}
+}
+
+func TestAICache(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+	fn := createTestFunction(t, database)
+
+	now := time.Now().Unix()
+	_ = server.aiCache.Set(store.AICacheEntry{
+		FunctionID: fn.ID, Key: "abc", Provider: "openai", Model: "gpt-4o-mini",
+		RequestJSON: "{}", ResponseJSON: `{"content":"spam"}`, CreatedAt: now, ExpiresAt: now + 60,
+	})
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/functions/"+fn.ID+"/ai-cache", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list PaginatedAICacheResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Entries) != 1 || list.Entries[0].Key != "abc" || list.Pagination.Total != 1 {
+		t.Fatalf("unexpected entries: %+v", list)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodDelete, "/api/functions/"+fn.ID+"/ai-cache", nil))
+
+	var cleared ClearAICacheResponse
+	if err := json.NewDecoder(w.Body).Decode(&cleared); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || cleared.Deleted != 1 {
+		t.Errorf("expected 1 deleted entry, got %d (status %d)", cleared.Deleted, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/functions/missing/ai-cache", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestGetAIUsage_InvalidRange(t *testing.T) {
+	server := createTestServer(store.NewMemoryDB())
+
+	for _, query := range []string{"from=yesterday", "from=2025-03-02&to=2025-03-01"} {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/ai/usage?"+query, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// readLogEvents reads n log events from a Server-Sent Events stream
+func readLogEvents(t *testing.T, reader *bufio.Reader, n int) []LogEntry {
+	t.Helper()
+	var events []LogEntry
+	var id, event string
+	for len(events) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "log":
+			var entry LogEntry
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &entry); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			if entry.ID != id {
+				t.Errorf("expected event id %q to match entry id %q", id, entry.ID)
+			}
+			events = append(events, entry)
+		}
+	}
+	return events
+}
+
+// readStreamEvent skips to the next event named name and returns its data
+func readStreamEvent(t *testing.T, reader *bufio.Reader, name string) string {
+	t.Helper()
+	var event string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == name:
+			return strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openLogStream opens a log stream against a live test server
+func openLogStream(t *testing.T, url string, header http.Header) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Bearer test-api-key")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected Content-Type text/event-stream, got %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestStreamExecutionLogs(t *testing.T) {
+	database := store.NewMemoryDB()
+	memLogger := logger.NewMemoryLogger()
+	server := NewServer(ServerConfig{
+		DB:     database,
+		Logger: memLogger,
+		APIKey: "test-api-key",
+	})
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	fn := createTestFunction(t, database)
+	ver := createTestVersion(t, database, fn.ID, "function handler(ctx, event)\n  return {statusCode = 200}\nend")
+	exec, err := database.CreateExecution(context.Background(), store.Execution{
+		ID:                "exec_running",
+		FunctionID:        fn.ID,
+		FunctionVersionID: ver.ID,
+		Status:            store.ExecutionStatusPending,
+	})
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+
+	memLogger.Info(exec.ID, "before stream")
+
+	t.Run("sends existing and new entries", func(t *testing.T) {
+		reader := openLogStream(t, ts.URL+"/api/executions/"+exec.ID+"/logs/stream", nil)
+
+		memLogger.Warn(exec.ID, "live entry")
+		memLogger.Info("other-execution", "ignored")
+
+		events := readLogEvents(t, reader, 2)
+		if events[0].Message != "before stream" || events[1].Message != "live entry" {
+			t.Errorf("unexpected events: %+v", events)
+		}
+		if events[1].Level != LogLevelWarn || events[1].ExecutionID != exec.ID {
+			t.Errorf("unexpected live event: %+v", events[1])
+		}
+	})
+
+	t.Run("filters by level", func(t *testing.T) {
+		reader := openLogStream(t, ts.URL+"/api/executions/"+exec.ID+"/logs/stream?level=error", nil)
+
+		memLogger.Info(exec.ID, "too verbose")
+		memLogger.Error(exec.ID, "boom")
+
+		events := readLogEvents(t, reader, 1)
+		if events[0].Message != "boom" {
+			t.Errorf("expected only error entries, got %+v", events)
+		}
+	})
+
+	t.Run("resumes after Last-Event-ID", func(t *testing.T) {
+		entries := memLogger.Entries(exec.ID)
+		lastSeen := entries[len(entries)-2]
+
+		reader := openLogStream(t, ts.URL+"/api/executions/"+exec.ID+"/logs/stream", http.Header{
+			"Last-Event-Id": {lastSeen.ID},
+		})
+
+		events := readLogEvents(t, reader, 1)
+		if events[0].ID != entries[len(entries)-1].ID {
+			t.Errorf("expected to resume with %q, got %+v", entries[len(entries)-1].Message, events[0])
+		}
+	})
+
+	t.Run("ends when the execution finishes", func(t *testing.T) {
+		reader := openLogStream(t, ts.URL+"/api/executions/"+exec.ID+"/logs/stream?search=last", nil)
+
+		memLogger.Info(exec.ID, "last words")
+		duration := int64(5)
+		if err := database.UpdateExecution(context.Background(), exec.ID, store.ExecutionStatusSuccess, &duration, nil); err != nil {
+			t.Fatalf("failed to update execution: %v", err)
+		}
+
+		if events := readLogEvents(t, reader, 1); events[0].Message != "last words" {
+			t.Errorf("unexpected events: %+v", events)
+		}
+		if data := readStreamEvent(t, reader, "end"); data != `{"status":"success"}` {
+			t.Errorf("unexpected end event: %s", data)
+		}
+		if rest, err := io.ReadAll(reader); err != nil || strings.TrimSpace(string(rest)) != "" {
+			t.Errorf("expected the stream to be closed, got %q %v", rest, err)
+		}
+	})
+
+	t.Run("replays every missed entry of a finished execution", func(t *testing.T) {
+		first := memLogger.Entries(exec.ID)[0]
+		for i := range logStreamReplayLimit + 5 {
+			memLogger.Info(exec.ID, "entry "+strconv.Itoa(i))
+		}
+		total := len(memLogger.Entries(exec.ID))
+
+		reader := openLogStream(t, ts.URL+"/api/executions/"+exec.ID+"/logs/stream?last_event_id="+first.ID, nil)
+		events := readLogEvents(t, reader, total-1)
+		if events[len(events)-1].Message != "entry "+strconv.Itoa(logStreamReplayLimit+4) {
+			t.Errorf("unexpected last entry: %+v", events[len(events)-1])
+		}
+		if data := readStreamEvent(t, reader, "end"); data != `{"status":"success"}` {
+			t.Errorf("unexpected end event: %s", data)
+		}
+	})
+
+	t.Run("rejects invalid level", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+exec.ID+"/logs/stream?level=loud", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("execution not found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/missing/logs/stream", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
+func TestStreamFunctionLogs(t *testing.T) {
+	database := store.NewMemoryDB()
+	memLogger := logger.NewMemoryLogger()
+	server := NewServer(ServerConfig{
+		DB:     database,
+		Logger: memLogger,
+		APIKey: "test-api-key",
+	})
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	fn := createTestFunction(t, database)
+	ver := createTestVersion(t, database, fn.ID, "function handler(ctx, event)\n  return {statusCode = 200}\nend")
+	exec := createTestExecution(t, database, fn.ID, ver.ID)
+
+	memLogger.Info(exec.ID, "before stream")
+
+	reader := openLogStream(t, ts.URL+"/api/functions/"+fn.ID+"/logs/stream", nil)
+
+	memLogger.Info("exec_other_function", "ignored")
+	memLogger.Info(exec.ID, "live entry")
+
+	events := readLogEvents(t, reader, 1)
+	if events[0].Message != "live entry" || events[0].ExecutionID != exec.ID {
+		t.Errorf("expected only new entries of the function, got %+v", events)
+	}
+
+	// Resuming far behind replays a page and reports the gap
+	for i := range logStreamReplayLimit + 5 {
+		memLogger.Info(exec.ID, "entry "+strconv.Itoa(i))
+	}
+	reader = openLogStream(t, ts.URL+"/api/functions/"+fn.ID+"/logs/stream?last_event_id="+events[0].ID, nil)
+	replayed := readLogEvents(t, reader, logStreamReplayLimit)
+	last := replayed[len(replayed)-1]
+	if data := readStreamEvent(t, reader, "gap"); data != `{"after":"`+last.ID+`"}` {
+		t.Errorf("unexpected gap event: %s", data)
+	}
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/functions/missing/logs/stream", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+// laggingLogger floods every new subscription before returning it, as if the
+// stream client had stopped reading while a function logged in a loop
+type laggingLogger struct {
+	*logger.MemoryLogger
+	executionID string
+	entries     int
+}
+
+func (l *laggingLogger) Subscribe(executionID string) (<-chan logger.LogEntry, func()) {
+	ch, cancel := l.MemoryLogger.Subscribe(executionID)
+	for i := range l.entries {
+		l.Info(l.executionID, "entry "+strconv.Itoa(i))
+	}
+	return ch, cancel
+}
+
+func TestStreamFunctionLogs_Lagged(t *testing.T) {
+	database := store.NewMemoryDB()
+	fn := createTestFunction(t, database)
+	ver := createTestVersion(t, database, fn.ID, "function handler(ctx, event)\n  return {statusCode = 200}\nend")
+	exec := createTestExecution(t, database, fn.ID, ver.ID)
+
+	lagging := &laggingLogger{MemoryLogger: logger.NewMemoryLogger(), executionID: exec.ID, entries: 1000}
+	server := NewServer(ServerConfig{
+		DB:     database,
+		Logger: lagging,
+		APIKey: "test-api-key",
+	})
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	reader := openLogStream(t, ts.URL+"/api/functions/"+fn.ID+"/logs/stream", nil)
+
+	// The entries queued before the subscriber lagged are sent, then a gap
+	// reports where the client has to resume
+	var sent []string
+	var gap string
+	for gap == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			sent = append(sent, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: {\"after\""):
+			gap = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if len(sent) == 0 || len(sent) >= lagging.entries {
+		t.Fatalf("expected some but not all entries before the gap, got %d", len(sent))
+	}
+	last := sent[len(sent)-1]
+	if gap != `{"after":"`+last+`"}` {
+		t.Errorf("expected the gap to follow the last entry sent %s, got %s", last, gap)
+	}
+	if missed := lagging.EntriesAfter("", last, lagging.entries); len(sent)+len(missed) != lagging.entries {
+		t.Errorf("expected the missed entries to be replayable, got %d sent and %d missed", len(sent), len(missed))
+	}
+	if rest, err := io.ReadAll(reader); err != nil || strings.TrimSpace(string(rest)) != "" {
+		t.Errorf("expected the stream to close after the gap, got %q (%v)", rest, err)
+	}
+}
+
+func TestEmailTemplates(t *testing.T) {
+	server := createTestServer(store.NewMemoryDB())
+
+	body := []byte(`{"subject": "Welcome, {{.name}}", "html": "<p>Hi {{.name}}</p>"}`)
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodPut, "/api/email-templates/welcome", body))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var saved store.EmailTemplate
+	if err := json.NewDecoder(w.Body).Decode(&saved); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if saved.Name != "welcome" || saved.CreatedAt == 0 {
+		t.Errorf("unexpected template: %+v", saved)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/email-templates", nil))
+	var list ListEmailTemplatesResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Templates) != 1 || list.Templates[0].HTML != "<p>Hi {{.name}}</p>" {
+		t.Errorf("unexpected templates: %+v", list.Templates)
+	}
+
+	for _, invalid := range []struct{ path, body string }{
+		{"/api/email-templates/welcome", `{"subject": "Hi {{.name", "text": "Hi"}`},
+		{"/api/email-templates/welcome", `{"subject": "Hi"}`},
+		{"/api/email-templates/has%20space", `{"subject": "Hi", "text": "Hi"}`},
+	} {
+		w = httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodPut, invalid.path, []byte(invalid.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status 400, got %d", invalid.path, invalid.body, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodDelete, "/api/email-templates/welcome", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/email-templates/welcome", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestInboundEmail(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+	fn := createTestFunction(t, database)
+	createTestVersion(t, database, fn.ID, `
+function handler(ctx, event)
+	if event.subject == "fail" then error("cannot triage") end
+	log.info(event.recipient .. " " .. event.from .. " " .. event.subject .. " " .. event.text)
+end`)
+	if err := database.ActivateVersion(context.Background(), fn.ID, 2); err != nil {
+		t.Fatalf("failed to activate version: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodPut, "/api/email-routes/Support@Lunar.dev", []byte(`{"function_id": "`+fn.ID+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var route store.InboundEmailRoute
+	if err := json.NewDecoder(w.Body).Decode(&route); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if route.Address != "support@lunar.dev" || route.FunctionID != fn.ID {
+		t.Errorf("unexpected route: %+v", route)
+	}
+
+	for _, invalid := range []struct{ path, body string }{
+		{"/api/email-routes/billing@lunar.dev", `{"function_id": "missing"}`},
+		{"/api/email-routes/billing", `{"function_id": "` + fn.ID + `"}`},
+	} {
+		w = httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodPut, invalid.path, []byte(invalid.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", invalid.path, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/email-routes", nil))
+	var list ListInboundEmailRoutesResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Routes) != 1 {
+		t.Errorf("unexpected routes: %+v", list.Routes)
+	}
+
+	postInbound := func(token string, fields map[string]string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		for name, value := range fields {
+			form.Set(name, value)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/inbound/email/mailgun?token="+token, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		return w
+	}
+	message := map[string]string{
+		"recipient":  "support@lunar.dev",
+		"from":       "Ana <ana@example.com>",
+		"subject":    "Help",
+		"body-plain": "It broke",
+	}
+
+	if w := postInbound("wrong", message); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+
+	w = postInbound("test-inbound-token", message)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp InboundEmailResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Deliveries) != 1 || resp.Deliveries[0].Status != store.ExecutionStatusSuccess {
+		t.Fatalf("unexpected deliveries: %+v", resp.Deliveries)
+	}
+	execution, err := database.GetExecution(context.Background(), resp.Deliveries[0].ExecutionID)
+	if err != nil {
+		t.Fatalf("failed to get execution: %v", err)
+	}
+	if execution.EventJSON == nil || !strings.Contains(*execution.EventJSON, `"recipient":"support@lunar.dev"`) {
+		t.Errorf("expected the email event to be stored, got %v", execution.EventJSON)
+	}
+	entries := server.logger.Entries(resp.Deliveries[0].ExecutionID)
+	if len(entries) != 1 || entries[0].Message != "support@lunar.dev ana@example.com Help It broke" {
+		t.Errorf("unexpected logs: %+v", entries)
+	}
+
+	message["subject"] = "fail"
+	w = postInbound("test-inbound-token", message)
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || resp.Deliveries[0].Status != store.ExecutionStatusError || resp.Deliveries[0].Error != "" {
+		t.Errorf("expected a failed execution without details, got %d %+v", w.Code, resp.Deliveries)
+	}
+
+	message["recipient"] = "sales@lunar.dev"
+	if w := postInbound("test-inbound-token", message); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodDelete, "/api/email-routes/support@lunar.dev", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodDelete, "/api/email-routes/support@lunar.dev", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestEmailDeliveryWebhook(t *testing.T) {
+	database := store.NewMemoryDB()
+	server := createTestServer(database)
+	fn := createTestFunction(t, database)
+	version := createTestVersion(t, database, fn.ID, `
+function handler(ctx, event)
+	log.info(event.emailId .. " " .. event.executionId .. " " .. event.to[1] .. " " .. event.bounceType)
+end`)
+	if err := database.ActivateVersion(context.Background(), fn.ID, version.Version); err != nil {
+		t.Fatalf("failed to activate version: %v", err)
+	}
+	execution := createTestExecution(t, database, fn.ID, version.ID)
+
+	emailID := "4ef9a417"
+	server.emailTracker.Track(execution.ID, email.TrackRequest{
+		From:    "news@lunar.dev",
+		To:      []string{"gone@example.com"},
+		Subject: "Newsletter",
+		Status:  store.EmailRequestStatusSuccess,
+		EmailID: &emailID,
+	})
+
+	postEvent := func(id, body, secret string) *httptest.ResponseRecorder {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		key, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(id + "." + timestamp + "." + body))
+
+		req := httptest.NewRequest(http.MethodPost, "/inbound/email-events/resend", strings.NewReader(body))
+		req.Header.Set("svix-id", id)
+		req.Header.Set("svix-timestamp", timestamp)
+		req.Header.Set("svix-signature", "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) EmailDeliveryResponse {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp EmailDeliveryResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	delivered := `{"type": "email.delivered", "created_at": "2026-02-22T23:41:10Z", "data": {"email_id": "4ef9a417"}}`
+	if w := postEvent("msg_1", delivered, "whsec_b3RoZXI="); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	} else if strings.Contains(w.Body.String(), "mismatch") {
+		t.Errorf("expected the rejection reason to stay private, got %s", w.Body.String())
+	}
+	if resp := decode(postEvent("msg_1", delivered, testResendSecret)); !resp.Recorded || resp.Bounce != nil {
+		t.Errorf("expected the delivery to be recorded, got %+v", resp)
+	}
+
+	bounced := `{
+		"type": "email.bounced",
+		"created_at": "2026-02-22T23:41:12Z",
+		"data": {
+			"email_id": "4ef9a417",
+			"to": ["gone@example.com"],
+			"bounce": {"message": "Mailbox does not exist", "subType": "General", "type": "Permanent"}
+		}
+	}`
+	resp := decode(postEvent("msg_2", bounced, testResendSecret))
+	if !resp.Recorded || resp.Bounce == nil || resp.Bounce.FunctionID != fn.ID || resp.Bounce.Status != store.ExecutionStatusSuccess {
+		t.Fatalf("expected the bounce function to run, got %+v", resp)
+	}
+	entries := server.logger.Entries(resp.Bounce.ExecutionID)
+	if len(entries) != 1 || entries[0].Message != "4ef9a417 "+execution.ID+" gone@example.com Permanent" {
+		t.Errorf("unexpected logs: %+v", entries)
+	}
+
+	// Retries are acknowledged without running the function again
+	if resp := decode(postEvent("msg_2", bounced, testResendSecret)); resp.Recorded || resp.Bounce != nil {
+		t.Errorf("expected the retry to be ignored, got %+v", resp)
+	}
+	if resp := decode(postEvent("msg_3", `{"type": "contact.created", "data": {"id": "c1"}}`, testResendSecret)); resp.Recorded {
+		t.Errorf("expected the contact event to be ignored, got %+v", resp)
+	}
+	if w := postEvent("msg_4", `{"type": "email.opened", "data": {}}`, testResendSecret); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, makeAuthRequest(http.MethodGet, "/api/executions/"+execution.ID+"/email-requests", nil))
+	var list PaginatedEmailRequestsResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.EmailRequests) != 1 || list.EmailRequests[0].DeliveryStatus == nil || *list.EmailRequests[0].DeliveryStatus != "bounced" {
+		t.Fatalf("expected the bounced status, got %+v", list.EmailRequests)
+	}
+	if events := list.EmailRequests[0].DeliveryEvents; len(events) != 2 || events[0].Type != "delivered" {
+		t.Errorf("unexpected delivery timeline: %+v", events)
+	}
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+const (
+	// logStreamHeartbeat is how often a comment is sent on idle streams so
+	// proxies and load balancers do not close the connection
+	logStreamHeartbeat = 15 * time.Second
+
+	// logStreamReplayLimit is the page size of replayed entries. Function
+	// streams replay a single page on reconnect and report the rest as a gap.
+	logStreamReplayLimit = 1000
+
+	// logStreamStatusInterval is how often an execution stream checks whether
+	// the execution has finished
+	logStreamStatusInterval = time.Second
+
+	// logStreamOwnerCacheSize bounds the execution-to-function cache kept by
+	// function log streams
+	logStreamOwnerCacheSize = 10000
+)
+
+// logLevelSeverity orders API log levels from least to most severe
+var logLevelSeverity = map[LogLevel]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// toAPILogEntry converts a logger.LogEntry to the API LogEntry format
+func toAPILogEntry(entry logger.LogEntry) LogEntry {
+	// Map logger.LogLevel (int) to API LogLevel (string)
+	var level LogLevel
+	switch entry.Level {
+	case logger.Debug:
+		level = LogLevelDebug
+	case logger.Info:
+		level = LogLevelInfo
+	case logger.Warn:
+		level = LogLevelWarn
+	case logger.Error:
+		level = LogLevelError
+	default:
+		level = LogLevelInfo
+	}
+
+	return LogEntry{
+		ID:          entry.ID,
+		ExecutionID: entry.ExecutionID,
+		Level:       level,
+		Message:     entry.Message,
+		Fields:      entry.Fields,
+		Line:        entry.Line,
+		CreatedAt:   entry.Timestamp,
+	}
+}
+
+// logFieldParamPrefix prefixes query parameters that filter on structured
+// log fields, e.g. field.user=42
+const logFieldParamPrefix = "field."
+
+// parseLogFilter reads the log filter query parameters:
+//   - level: minimum severity (debug, info, warn or error)
+//   - search: case-insensitive substring of the message
+//   - field.<name>: value a top-level structured field must have
+func parseLogFilter(r *http.Request) (logger.Filter, error) {
+	query := r.URL.Query()
+	var filter logger.Filter
+
+	if level := LogLevel(query.Get("level")); level != "" {
+		minSeverity, ok := logLevelSeverity[level]
+		if !ok {
+			return logger.Filter{}, &ValidationError{Field: "level", Message: "must be one of debug, info, warn, error"}
+		}
+		for _, l := range []logger.LogLevel{logger.Debug, logger.Info, logger.Warn, logger.Error} {
+			if logLevelSeverity[toAPILogEntry(logger.LogEntry{Level: l}).Level] >= minSeverity {
+				filter.Levels = append(filter.Levels, l)
+			}
+		}
+	}
+
+	filter.Search = query.Get("search")
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, logFieldParamPrefix)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			return logger.Filter{}, &ValidationError{Field: key, Message: "field name is required"}
+		}
+		if filter.Fields == nil {
+			filter.Fields = make(map[string]string)
+		}
+		filter.Fields[name] = values[0]
+	}
+
+	return filter, nil
+}
+
+// lastEventID returns the ID of the last entry the client received. Browsers
+// send the Last-Event-ID header when EventSource reconnects; the query
+// parameter lets clients resume a stream they opened themselves.
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// StreamExecutionLogsHandler returns a handler that streams the logs of an
+// execution as Server-Sent Events. Existing entries are sent first, followed
+// by new entries as they are written, until the execution finishes and an
+// end event closes the stream.
+func StreamExecutionLogsHandler(database store.DB, appLogger logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if _, err := database.GetExecution(r.Context(), id); err != nil {
+			writeError(w, http.StatusNotFound, "Execution not found")
+			return
+		}
+
+		filter, err := parseLogFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Subscribe before reading the backlog so no entry is missed in between
+		entries, cancel := appLogger.Subscribe(id)
+		defer cancel()
+
+		var backlog []logger.LogEntry
+		if lastID := lastEventID(r); lastID != "" {
+			// An execution has a bounded number of entries, so replay them all
+			for {
+				page := appLogger.EntriesAfter(id, lastID, logStreamReplayLimit)
+				backlog = append(backlog, page...)
+				if len(page) < logStreamReplayLimit {
+					break
+				}
+				lastID = page[len(page)-1].ID
+			}
+		} else {
+			backlog = appLogger.Entries(id)
+		}
+
+		finished := func() (store.ExecutionStatus, bool) {
+			execution, err := database.GetExecution(r.Context(), id)
+			if err != nil {
+				// Deleted by retention while streaming
+				return "", true
+			}
+			return execution.Status, execution.Status != store.ExecutionStatusPending
+		}
+
+		streamLogs(w, r, logStream{backlog: backlog, live: entries, match: filter.Match, finished: finished})
+	}
+}
+
+// StreamFunctionLogsHandler returns a handler that streams the logs of every
+// execution of a function as Server-Sent Events. Only entries written after
+// the stream is opened are sent, unless the client resumes with Last-Event-ID.
+func StreamFunctionLogsHandler(database store.DB, appLogger logger.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		if _, err := database.GetFunction(r.Context(), id); err != nil {
+			writeError(w, http.StatusNotFound, "Function not found")
+			return
+		}
+
+		filter, err := parseLogFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Log entries only carry the execution ID, so resolve the owning
+		// function once per execution
+		owners := make(map[string]bool)
+		match := func(entry logger.LogEntry) bool {
+			if !filter.Match(entry) {
+				return false
+			}
+			owned, cached := owners[entry.ExecutionID]
+			if !cached {
+				if len(owners) >= logStreamOwnerCacheSize {
+					clear(owners)
+				}
+				execution, err := database.GetExecution(r.Context(), entry.ExecutionID)
+				owned = err == nil && execution.FunctionID == id
+				owners[entry.ExecutionID] = owned
+			}
+			return owned
+		}
+
+		// Subscribe before reading the backlog so no entry is missed in between
+		entries, cancel := appLogger.Subscribe("")
+		defer cancel()
+
+		// The logs of every execution may have been written since, so only a
+		// page is replayed and the client is told about the rest
+		var backlog []logger.LogEntry
+		if lastID := lastEventID(r); lastID != "" {
+			backlog = appLogger.EntriesAfter("", lastID, logStreamReplayLimit)
+		}
+
+		streamLogs(w, r, logStream{
+			backlog:   backlog,
+			truncated: len(backlog) == logStreamReplayLimit,
+			live:      entries,
+			match:     match,
+		})
+	}
+}
+
+// logStream is what streamLogs sends
+type logStream struct {
+	backlog   []logger.LogEntry
+	truncated bool                       // Entries after the backlog were not replayed
+	live      <-chan logger.LogEntry     // Closed by the logger when the client lags
+	match     func(logger.LogEntry) bool // Entries rejected by match are skipped
+	// finished reports the final status once the stream should end, nil for
+	// streams that never end
+	finished func() (store.ExecutionStatus, bool)
+}
+
+// streamLogs writes the backlog followed by live entries as Server-Sent
+// Events until the client disconnects or the stream is finished
+func streamLogs(w http.ResponseWriter, r *http.Request, stream logStream) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(entry logger.LogEntry) error {
+		if !stream.match(entry) {
+			return nil
+		}
+
+		data, err := json.Marshal(toAPILogEntry(entry))
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: log\ndata: %s\n\n", entry.ID, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// gap tells clients entries after the given ID were not sent, so they
+	// fetch them with the logs endpoint or resume with Last-Event-ID
+	gap := func(after string) error {
+		if _, err := fmt.Fprintf(w, "event: gap\ndata: {\"after\":%q}\n\n", after); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Entries written between subscribing and reading the backlog arrive on
+	// both, so remember what has been sent to skip the duplicates
+	sent := make(map[string]struct{}, len(stream.backlog))
+	var last string // ID of the last entry sent or skipped
+	for _, entry := range stream.backlog {
+		sent[entry.ID] = struct{}{}
+		last = entry.ID
+		if err := send(entry); err != nil {
+			return
+		}
+	}
+	if stream.truncated {
+		if err := gap(last); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	sendLive := func(entry logger.LogEntry) error {
+		last = entry.ID
+		if _, dup := sent[entry.ID]; dup {
+			delete(sent, entry.ID)
+			return nil
+		}
+		return send(entry)
+	}
+
+	// end sends the entries already received and an end event. The
+	// execution writes its logs before its status, so none is left behind.
+	end := func(status store.ExecutionStatus) {
+	drain:
+		for {
+			select {
+			case entry, ok := <-stream.live:
+				if !ok {
+					// Lagged, so the stream is incomplete and does not end
+					_ = gap(last)
+					return
+				}
+				if err := sendLive(entry); err != nil {
+					return
+				}
+			default:
+				break drain
+			}
+		}
+		if _, err := fmt.Fprintf(w, "event: end\ndata: {\"status\":%q}\n\n", status); err != nil {
+			return
+		}
+		_ = rc.Flush()
+	}
+
+	var statusCheck <-chan time.Time
+	if stream.finished != nil {
+		if status, done := stream.finished(); done {
+			end(status)
+			return
+		}
+		ticker := time.NewTicker(logStreamStatusInterval)
+		defer ticker.Stop()
+		statusCheck = ticker.C
+	}
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-stream.live:
+			if !ok {
+				// The client fell too far behind for the logger to queue
+				// entries, so it resumes after the last one it received
+				_ = gap(last)
+				return
+			}
+			if err := sendLive(entry); err != nil {
+				return
+			}
+		case <-statusCheck:
+			if status, done := stream.finished(); done {
+				end(status)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
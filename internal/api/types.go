@@ -23,9 +23,13 @@ const (
 
 // LogEntry represents a log entry from function execution
 type LogEntry struct {
-	Level     LogLevel `json:"level"`
-	Message   string   `json:"message"`
-	CreatedAt int64    `json:"created_at"`
+	ID          string         `json:"id,omitempty"`
+	ExecutionID string         `json:"execution_id,omitempty"`
+	Level       LogLevel       `json:"level"`
+	Message     string         `json:"message"`
+	Fields      map[string]any `json:"fields,omitempty"`
+	Line        int            `json:"line,omitempty"`
+	CreatedAt   int64          `json:"created_at"`
 }
 
 // DiffLine represents a line in a version diff
@@ -48,6 +52,20 @@ type UpdateEnvVarsRequest struct {
 	EnvVars map[string]string `json:"env_vars"`
 }
 
+// SaveEmailTemplateRequest is the request body for creating or replacing an
+// email template
+type SaveEmailTemplateRequest struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// SaveInboundEmailRouteRequest is the request body for routing an inbound
+// email address to a function
+type SaveInboundEmailRouteRequest struct {
+	FunctionID string `json:"function_id"`
+}
+
 // ListFunctionsResponse is the response for listing functions
 type ListFunctionsResponse struct {
 	Functions []store.FunctionWithActiveVersion `json:"functions"`
@@ -120,8 +138,90 @@ type PaginatedAIRequestsResponse struct {
 	Pagination store.PaginationInfo `json:"pagination"`
 }
 
+// PaginatedAICacheResponse is the paginated response for AI cache entries
+type PaginatedAICacheResponse struct {
+	Entries    []store.AICacheEntry `json:"entries"`
+	Pagination store.PaginationInfo `json:"pagination"`
+}
+
+// ClearAICacheResponse is the response for clearing the AI cache of a function
+type ClearAICacheResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// AIUsageResponse is the AI usage report of a range of days
+type AIUsageResponse struct {
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Usage []store.AIUsage `json:"usage"`
+	Total AIUsageTotal    `json:"total"`
+}
+
+// AIUsageTotal is the total AI usage of a report
+type AIUsageTotal struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
 // PaginatedEmailRequestsResponse is the paginated response for email requests
 type PaginatedEmailRequestsResponse struct {
 	EmailRequests []store.EmailRequest `json:"email_requests"`
 	Pagination    store.PaginationInfo `json:"pagination"`
 }
+
+// ListEmailTemplatesResponse is the response for listing email templates
+type ListEmailTemplatesResponse struct {
+	Templates []store.EmailTemplate `json:"templates"`
+}
+
+// ListInboundEmailRoutesResponse is the response for listing inbound email
+// routes
+type ListInboundEmailRoutesResponse struct {
+	Routes []store.InboundEmailRoute `json:"routes"`
+}
+
+// InboundEmailDelivery is the execution of a function for an inbound email.
+// Error is set when the function could not run.
+type InboundEmailDelivery struct {
+	Recipient   string                `json:"recipient"`
+	FunctionID  string                `json:"function_id"`
+	ExecutionID string                `json:"execution_id,omitempty"`
+	Status      store.ExecutionStatus `json:"status,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// InboundEmailResponse is the response for an inbound email webhook
+type InboundEmailResponse struct {
+	Deliveries []InboundEmailDelivery `json:"deliveries"`
+}
+
+// EmailDeliveryResponse is the response for an email delivery webhook.
+// Recorded is false for retried events and emails no function sent.
+type EmailDeliveryResponse struct {
+	Recorded bool `json:"recorded"`
+	// Bounce is the execution of the bounce function, when it ran
+	Bounce *EmailBounceExecution `json:"bounce,omitempty"`
+}
+
+// EmailBounceExecution is the execution of the bounce function. Error is
+// set when the function could not run.
+type EmailBounceExecution struct {
+	FunctionID  string                `json:"function_id"`
+	ExecutionID string                `json:"execution_id,omitempty"`
+	Status      store.ExecutionStatus `json:"status,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// PaginatedHTTPRequestsResponse is the paginated response for outbound HTTP requests
+type PaginatedHTTPRequestsResponse struct {
+	HTTPRequests []store.HTTPRequest  `json:"http_requests"`
+	Pagination   store.PaginationInfo `json:"pagination"`
+}
+
+// ExecutionTraceResponse is the response for an execution trace
+type ExecutionTraceResponse struct {
+	TraceID string       `json:"trace_id"`
+	Spans   []store.Span `json:"spans"`
+}
@@ -5,6 +5,7 @@ import (
 	"slices"
 	"strings"
 
+	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/store"
 )
 
@@ -23,6 +24,8 @@ const (
 	MaxEnvVarValueLength = 10000
 	// MaxEnvVars is the maximum number of environment variables per function
 	MaxEnvVars = 100
+	// MaxNetworkPolicyEntries is the maximum number of hosts or ports in each network policy list
+	MaxNetworkPolicyEntries = 100
 )
 
 var AllowedRetentionDays = []int{7, 15, 30, 365}
@@ -70,7 +73,8 @@ func ValidateUpdateFunctionRequest(req *store.UpdateFunctionRequest) error {
 	}
 
 	// At least one field must be provided
-	if req.Name == nil && req.Description == nil && req.Code == nil && req.Disabled == nil && req.RetentionDays == nil {
+	if req.Name == nil && req.Description == nil && req.Code == nil && req.Disabled == nil && req.RetentionDays == nil &&
+		req.NetworkPolicy == nil {
 		return &ValidationError{Field: "request", Message: "at least one field must be provided for update"}
 	}
 
@@ -102,6 +106,13 @@ func ValidateUpdateFunctionRequest(req *store.UpdateFunctionRequest) error {
 		}
 	}
 
+	// Validate network_policy if provided
+	if req.NetworkPolicy != nil {
+		if err := validateNetworkPolicy(req.NetworkPolicy); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -236,3 +247,19 @@ func validateRetentionDays(days int) error {
 		Message: fmt.Sprintf("retention_days must be one of: %v", AllowedRetentionDays),
 	}
 }
+
+// validateNetworkPolicy validates the host and port lists of a network policy
+func validateNetworkPolicy(policy *store.NetworkPolicy) error {
+	for _, n := range []int{len(policy.AllowHosts), len(policy.DenyHosts), len(policy.AllowPorts), len(policy.DenyPorts)} {
+		if n > MaxNetworkPolicyEntries {
+			return &ValidationError{
+				Field:   "network_policy",
+				Message: fmt.Sprintf("lists cannot have more than %d entries", MaxNetworkPolicyEntries),
+			}
+		}
+	}
+	if _, err := internalhttp.NewPolicy(policy); err != nil {
+		return &ValidationError{Field: "network_policy", Message: err.Error()}
+	}
+	return nil
+}
@@ -507,3 +507,55 @@ func TestValidateUpdateFunctionRequest_WithRetentionDays(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateUpdateFunctionRequest_WithNetworkPolicy(t *testing.T) {
+	tooMany := make([]string, MaxNetworkPolicyEntries+1)
+	for i := range tooMany {
+		tooMany[i] = "example.com"
+	}
+
+	tests := []struct {
+		name    string
+		policy  *store.NetworkPolicy
+		wantErr bool
+	}{
+		{
+			name:   "empty policy",
+			policy: &store.NetworkPolicy{},
+		},
+		{
+			name: "hosts, wildcards, CIDRs and ports",
+			policy: &store.NetworkPolicy{
+				AllowHosts: []string{"api.example.com", "*.example.org", "10.0.0.0/8", "192.168.1.10"},
+				DenyPorts:  []int{25},
+			},
+		},
+		{
+			name:    "invalid CIDR",
+			policy:  &store.NetworkPolicy{DenyHosts: []string{"10.0.0.0/99"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			policy:  &store.NetworkPolicy{AllowPorts: []int{0}},
+			wantErr: true,
+		},
+		{
+			name:    "too many entries",
+			policy:  &store.NetworkPolicy{AllowHosts: tooMany},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateUpdateFunctionRequest(&store.UpdateFunctionRequest{NetworkPolicy: tt.policy})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdateFunctionRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "network_policy") {
+				t.Errorf("expected error to mention network_policy, got %v", err)
+			}
+		})
+	}
+}
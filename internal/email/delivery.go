@@ -0,0 +1,113 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/webhook"
+)
+
+// DeliveryBounced is the delivery event type of a bounced email
+const DeliveryBounced = "bounced"
+
+// DeliveryReport is a delivery status change of a sent email
+type DeliveryReport struct {
+	EmailID string
+	To      []string
+	Event   store.EmailDeliveryEvent
+	Bounce  *Bounce // Set for bounced emails
+}
+
+// Bounce describes why an email bounced
+type Bounce struct {
+	Type    string // Permanent, Transient or Undetermined
+	SubType string
+	Message string
+}
+
+// VerifyResendWebhook verifies the Svix signature Resend signs webhooks
+// with, the Standard Webhooks scheme with svix- prefixed headers
+func VerifyResendWebhook(header http.Header, body []byte, secret string) error {
+	id := header.Get("svix-id")
+	timestamp := header.Get("svix-timestamp")
+	signatures := header.Get("svix-signature")
+	if id == "" || timestamp == "" || signatures == "" {
+		return errors.New("missing svix-id, svix-timestamp or svix-signature header")
+	}
+	return webhook.Verify(id, timestamp, signatures, string(body), secret, webhook.DefaultTolerance)
+}
+
+// ParseResendDelivery parses a Resend webhook into a delivery report, with
+// id as the event ID. Webhooks about anything but sent emails, such as
+// contacts, domains or received emails, have no report.
+func ParseResendDelivery(id string, body []byte) (*DeliveryReport, error) {
+	var payload struct {
+		Type      string `json:"type"`
+		CreatedAt string `json:"created_at"`
+		Data      struct {
+			EmailID string   `json:"email_id"`
+			To      []string `json:"to"`
+			Bounce  *struct {
+				Type    string `json:"type"`
+				SubType string `json:"subType"`
+				Message string `json:"message"`
+			} `json:"bounce"`
+			Failed *struct {
+				Reason string `json:"reason"`
+			} `json:"failed"`
+			Click *struct {
+				Link string `json:"link"`
+			} `json:"click"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	eventType, ok := strings.CutPrefix(payload.Type, "email.")
+	if !ok || eventType == "received" {
+		return nil, nil
+	}
+	data := payload.Data
+	if data.EmailID == "" {
+		return nil, errors.New("missing data.email_id")
+	}
+
+	occurredAt := time.Now()
+	if t, err := time.Parse(time.RFC3339Nano, payload.CreatedAt); err == nil {
+		occurredAt = t
+	}
+
+	report := &DeliveryReport{
+		EmailID: data.EmailID,
+		To:      data.To,
+		Event: store.EmailDeliveryEvent{
+			ID:         id,
+			Type:       eventType,
+			OccurredAt: occurredAt.Unix(),
+		},
+	}
+
+	var detail string
+	switch {
+	case data.Bounce != nil:
+		detail = data.Bounce.Message
+		report.Bounce = &Bounce{Type: data.Bounce.Type, SubType: data.Bounce.SubType, Message: data.Bounce.Message}
+	case data.Failed != nil:
+		detail = data.Failed.Reason
+	case data.Click != nil:
+		detail = data.Click.Link
+	}
+	if detail != "" {
+		report.Event.Detail = &detail
+	}
+	if eventType == DeliveryBounced && report.Bounce == nil {
+		report.Bounce = &Bounce{}
+	}
+	return report, nil
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// testWebhookSecret is a Svix secret, the base64 of "test-secret"
+const testWebhookSecret = "whsec_dGVzdC1zZWNyZXQ="
+
+// signedHeader signs body as Svix does at the given time
+func signedHeader(id string, at time.Time, body string) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte(id + "." + timestamp + "." + body))
+
+	header := http.Header{}
+	header.Set("svix-id", id)
+	header.Set("svix-timestamp", timestamp)
+	header.Set("svix-signature", "v1,b2xkLXNpZ25hdHVyZQ== v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerifyResendWebhook(t *testing.T) {
+	body := `{"type":"email.delivered"}`
+
+	if err := VerifyResendWebhook(signedHeader("msg_1", time.Now(), body), []byte(body), testWebhookSecret); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		header http.Header
+		body   string
+		secret string
+		err    string
+	}{
+		"tampered body":  {signedHeader("msg_1", time.Now(), body), `{"type":"email.bounced"}`, testWebhookSecret, "signature mismatch"},
+		"wrong secret":   {signedHeader("msg_1", time.Now(), body), body, "whsec_b3RoZXI=", "signature mismatch"},
+		"replayed":       {signedHeader("msg_1", time.Now().Add(-time.Hour), body), body, testWebhookSecret, "timestamp outside the tolerance window"},
+		"unsigned":       {http.Header{}, body, testWebhookSecret, "missing svix-id, svix-timestamp or svix-signature header"},
+		"invalid secret": {signedHeader("msg_1", time.Now(), body), body, "whsec_!", "invalid secret: expected base64, optionally prefixed with whsec_"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := VerifyResendWebhook(tt.header, []byte(tt.body), tt.secret)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseResendDelivery(t *testing.T) {
+	bounceMessage := "The recipient's mailbox does not exist"
+	link := "https://lunar.dev/pricing"
+
+	tests := map[string]struct {
+		body     string
+		expected *DeliveryReport
+	}{
+		"bounced": {
+			`{
+				"type": "email.bounced",
+				"created_at": "2026-02-22T23:41:12.126Z",
+				"data": {
+					"email_id": "4ef9a417",
+					"to": ["gone@example.com"],
+					"bounce": {"message": "The recipient's mailbox does not exist", "subType": "General", "type": "Permanent"}
+				}
+			}`,
+			&DeliveryReport{
+				EmailID: "4ef9a417",
+				To:      []string{"gone@example.com"},
+				Event:   store.EmailDeliveryEvent{ID: "msg_1", Type: "bounced", Detail: &bounceMessage, OccurredAt: 1771803672},
+				Bounce:  &Bounce{Type: "Permanent", SubType: "General", Message: bounceMessage},
+			},
+		},
+		"clicked": {
+			`{"type": "email.clicked", "created_at": "2026-02-22T23:41:12Z", "data": {"email_id": "4ef9a417", "click": {"link": "https://lunar.dev/pricing"}}}`,
+			&DeliveryReport{
+				EmailID: "4ef9a417",
+				Event:   store.EmailDeliveryEvent{ID: "msg_1", Type: "clicked", Detail: &link, OccurredAt: 1771803672},
+			},
+		},
+		"delivery delayed": {
+			`{"type": "email.delivery_delayed", "created_at": "2026-02-22T23:41:12Z", "data": {"email_id": "4ef9a417"}}`,
+			&DeliveryReport{
+				EmailID: "4ef9a417",
+				Event:   store.EmailDeliveryEvent{ID: "msg_1", Type: "delivery_delayed", OccurredAt: 1771803672},
+			},
+		},
+		"contact event":  {`{"type": "contact.created", "data": {"id": "c1"}}`, nil},
+		"received email": {`{"type": "email.received", "data": {"email_id": "4ef9a417"}}`, nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			report, err := ParseResendDelivery("msg_1", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(report, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, report)
+			}
+		})
+	}
+
+	if _, err := ParseResendDelivery("msg_1", []byte(`{"type": "email.sent", "data": {}}`)); err == nil || err.Error() != "missing data.email_id" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseResendDelivery("msg_1", []byte(`not json`)); err == nil {
+		t.Error("expected an error for an invalid body")
+	}
+}
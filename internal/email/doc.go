@@ -1,4 +1,8 @@
-// Package email provides email sending functionality using the Resend API.
-// It includes a Client interface for sending emails and a Tracker interface
-// for tracking email requests.
+// Package email provides email sending functionality using the Resend API
+// or an SMTP server. It includes a Client interface for sending emails and
+// a Tracker interface for tracking email requests, along with the delivery
+// events Resend reports for them through signed webhooks.
+//
+// Inbound emails are parsed into email events from provider webhooks or an
+// SMTP listener, and routed to functions by address with a RouteStore.
 package email
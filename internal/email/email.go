@@ -1,12 +1,20 @@
 package email
 
 import (
-	"net/url"
+	"cmp"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
 
 	"github.com/dimiro1/lunar/internal/env"
-	"github.com/resend/resend-go/v3"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
 )
 
+// ProviderEnv selects the transport of a function: "resend" (the default)
+// or "smtp"
+const ProviderEnv = "EMAIL_PROVIDER"
+
 // Environment variable names for Resend configuration
 const (
 	ResendAPIKeyEnv  = "RESEND_API_KEY"
@@ -19,6 +27,19 @@ type Tag struct {
 	Value string
 }
 
+// MaxAttachmentsSize limits the total size of the attachments of an email
+// (40MB)
+const MaxAttachmentsSize = 40 * 1024 * 1024
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string // Derived from the filename when empty
+	Content     []byte
+	ContentID   string // Shows the file inline, referenced as cid:<ContentID> in the HTML
+	URL         string // Where the content was fetched from, if it was
+}
+
 // SendRequest represents a request to send an email
 type SendRequest struct {
 	From        string
@@ -32,6 +53,8 @@ type SendRequest struct {
 	Headers     map[string]string
 	Tags        []Tag
 	ScheduledAt string
+	Attachments []Attachment
+	Policy      *internalhttp.Policy // Network policy the SMTP server is dialed under (optional)
 }
 
 // SendResponse represents the response from sending an email
@@ -40,12 +63,37 @@ type SendResponse struct {
 	RequestJSON string
 }
 
+// contentType returns the content type of the attachment, derived from
+// the filename when it is not set
+func (a Attachment) contentType() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	return cmp.Or(mime.TypeByExtension(filepath.Ext(a.Filename)), "application/octet-stream")
+}
+
+// ValidContentType reports whether contentType is a media type, with
+// optional parameters, that can be written in a MIME part header
+func ValidContentType(contentType string) bool {
+	if strings.ContainsAny(contentType, "\r\n") {
+		return false
+	}
+	_, _, err := mime.ParseMediaType(contentType)
+	return err == nil
+}
+
 // Client is an interface for sending emails
 type Client interface {
 	Send(functionID string, req SendRequest) (*SendResponse, error)
 }
 
-// DefaultClient is the default implementation of Client using Resend
+// transport delivers an email and returns its ID
+type transport interface {
+	send(req SendRequest) (string, error)
+}
+
+// DefaultClient is the default implementation of Client. Each function
+// picks its transport with EMAIL_PROVIDER.
 type DefaultClient struct {
 	envStore env.Store
 }
@@ -57,52 +105,11 @@ func NewDefaultClient(envStore env.Store) *DefaultClient {
 	}
 }
 
-// Send sends an email using Resend
+// Send sends an email with the function's transport
 func (c *DefaultClient) Send(functionID string, req SendRequest) (*SendResponse, error) {
-	// Get API key from environment
-	apiKey, err := c.envStore.Get(functionID, ResendAPIKeyEnv)
-	if err != nil || apiKey == "" {
-		return nil, &ConfigError{Field: ResendAPIKeyEnv}
-	}
-
-	// Create Resend client
-	client := resend.NewClient(apiKey)
-
-	// Allow custom base URL for testing (read from function env)
-	if baseURL, err := c.envStore.Get(functionID, ResendBaseURLEnv); err == nil && baseURL != "" {
-		if parsedURL, err := url.Parse(baseURL); err == nil {
-			client.BaseURL = parsedURL
-		}
-	}
-
-	// Build Resend request params
-	params := &resend.SendEmailRequest{
-		From:    req.From,
-		To:      req.To,
-		Subject: req.Subject,
-		Text:    req.Text,
-		Html:    req.HTML,
-		ReplyTo: req.ReplyTo,
-	}
-
-	if len(req.Cc) > 0 {
-		params.Cc = req.Cc
-	}
-	if len(req.Bcc) > 0 {
-		params.Bcc = req.Bcc
-	}
-	if len(req.Headers) > 0 {
-		params.Headers = req.Headers
-	}
-	if len(req.Tags) > 0 {
-		var tags []resend.Tag
-		for _, t := range req.Tags {
-			tags = append(tags, resend.Tag{Name: t.Name, Value: t.Value})
-		}
-		params.Tags = tags
-	}
-	if req.ScheduledAt != "" {
-		params.ScheduledAt = req.ScheduledAt
+	t, err := c.transport(functionID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build request JSON for tracking
@@ -113,20 +120,48 @@ func (c *DefaultClient) Send(functionID string, req SendRequest) (*SendResponse,
 			"value": tag.Value,
 		})
 	}
-	requestJSON := EmailParamsToJSON(req.From, req.To, req.Subject, req.Text, req.HTML, req.ReplyTo, req.Cc, req.Bcc, req.ScheduledAt, req.Headers, tagsForJSON)
+	// Attachments are recorded without their content
+	var attachmentsForJSON []map[string]any
+	for _, a := range req.Attachments {
+		attachment := map[string]any{
+			"filename":     a.Filename,
+			"content_type": a.contentType(),
+			"size":         len(a.Content),
+		}
+		if a.ContentID != "" {
+			attachment["content_id"] = a.ContentID
+		}
+		if a.URL != "" {
+			attachment["url"] = a.URL
+		}
+		attachmentsForJSON = append(attachmentsForJSON, attachment)
+	}
+	requestJSON := EmailParamsToJSON(req.From, req.To, req.Subject, req.Text, req.HTML, req.ReplyTo, req.Cc, req.Bcc, req.ScheduledAt, req.Headers, tagsForJSON, attachmentsForJSON)
 
-	// Send email
-	sent, err := client.Emails.Send(params)
+	id, err := t.send(req)
 	if err != nil {
 		return &SendResponse{RequestJSON: requestJSON}, err
 	}
 
 	return &SendResponse{
-		ID:          sent.Id,
+		ID:          id,
 		RequestJSON: requestJSON,
 	}, nil
 }
 
+// transport returns the transport configured in the function environment
+func (c *DefaultClient) transport(functionID string) (transport, error) {
+	provider, _ := c.envStore.Get(functionID, ProviderEnv)
+	switch provider {
+	case "", "resend":
+		return c.resendTransport(functionID)
+	case "smtp":
+		return c.smtpTransport(functionID)
+	default:
+		return nil, fmt.Errorf("unknown email provider %q (use resend or smtp)", provider)
+	}
+}
+
 // ConfigError is returned when a required configuration is missing
 type ConfigError struct {
 	Field string
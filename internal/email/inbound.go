@@ -0,0 +1,459 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+// MaxInboundSize limits the size of an inbound email (25MB)
+const MaxInboundSize = 25 * 1024 * 1024
+
+// maxMIMEDepth limits the nesting of multipart bodies
+const maxMIMEDepth = 10
+
+// Providers whose inbound webhooks are accepted
+const (
+	InboundResend   = "resend"
+	InboundMailgun  = "mailgun"
+	InboundSendGrid = "sendgrid"
+)
+
+// InboundEmail is an email received through a provider webhook or the SMTP
+// listener
+type InboundEmail struct {
+	Recipients []string // Envelope recipients, the addresses the email is routed by
+	Event      events.EmailEvent
+}
+
+// headerDecoder decodes RFC 2047 encoded words
+var headerDecoder = &mime.WordDecoder{}
+
+// ParseMessage parses a raw RFC 5322 email. The first text/plain and
+// text/html parts are the text and HTML, every other part is an attachment.
+func ParseMessage(raw []byte) (events.EmailEvent, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return events.EmailEvent{}, fmt.Errorf("invalid email: %w", err)
+	}
+
+	event := events.EmailEvent{
+		MessageID:   strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		From:        firstAddress(msg.Header.Get("From")),
+		To:          addressList(msg.Header.Get("To")),
+		Cc:          addressList(msg.Header.Get("Cc")),
+		Headers:     decodeHeaders(textproto.MIMEHeader(msg.Header)),
+		Attachments: []events.EmailAttachment{},
+	}
+	event.Subject = event.Headers["Subject"]
+
+	if err := readPart(&event, textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return events.EmailEvent{}, fmt.Errorf("invalid email: %w", err)
+	}
+	return event, nil
+}
+
+// readPart reads a MIME entity into the event, descending into multipart
+// bodies
+func readPart(event *events.EmailEvent, header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return errors.New("multipart body is nested too deeply")
+		}
+		if params["boundary"] == "" {
+			return errors.New("multipart body has no boundary")
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := readPart(event, part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	if disposition != "attachment" && filename == "" {
+		switch {
+		case mediaType == "text/plain" && event.Text == "":
+			event.Text = decodeCharset(params["charset"], content)
+			return nil
+		case mediaType == "text/html" && event.HTML == "":
+			event.HTML = decodeCharset(params["charset"], content)
+			return nil
+		}
+	}
+
+	event.Attachments = append(event.Attachments, events.EmailAttachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		ContentID:   strings.Trim(header.Get("Content-Id"), "<> "),
+		Size:        len(content),
+		Content:     content,
+	})
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// The decoder skips line breaks
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// decodeCharset converts a text body to UTF-8. Charsets other than ASCII,
+// UTF-8 and Latin-1 are kept as they are.
+func decodeCharset(charset string, content []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(content)
+	}
+}
+
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// decodeHeaders returns the first value of every header, decoded
+func decodeHeaders(header textproto.MIMEHeader) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if len(values) > 0 {
+			headers[name] = decodeHeader(values[0])
+		}
+	}
+	return headers
+}
+
+// addressList returns the bare addresses of address list headers. A value
+// that does not parse is kept as it is.
+func addressList(values ...string) []string {
+	addresses := []string{}
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		list, err := (&mail.AddressParser{WordDecoder: headerDecoder}).ParseList(value)
+		if err != nil {
+			addresses = append(addresses, strings.TrimSpace(value))
+			continue
+		}
+		for _, address := range list {
+			addresses = append(addresses, address.Address)
+		}
+	}
+	return addresses
+}
+
+func firstAddress(value string) string {
+	if addresses := addressList(value); len(addresses) > 0 {
+		return addresses[0]
+	}
+	return ""
+}
+
+// ParseInboundWebhook parses the request of a provider's inbound webhook
+func ParseInboundWebhook(provider string, r *http.Request) (InboundEmail, error) {
+	switch provider {
+	case InboundResend:
+		return parseResendInbound(r)
+	case InboundMailgun:
+		return parseMailgunInbound(r)
+	case InboundSendGrid:
+		return parseSendGridInbound(r)
+	default:
+		return InboundEmail{}, fmt.Errorf("unknown inbound email provider %q (use resend, mailgun or sendgrid)", provider)
+	}
+}
+
+// parseResendInbound parses an email.received event. Resend sends the
+// metadata of the email, the body and attachments are only included when
+// present in the payload, and can be fetched with the email ID otherwise.
+func parseResendInbound(r *http.Request) (InboundEmail, error) {
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			EmailID     string            `json:"email_id"`
+			MessageID   string            `json:"message_id"`
+			From        string            `json:"from"`
+			To          []string          `json:"to"`
+			Cc          []string          `json:"cc"`
+			Bcc         []string          `json:"bcc"`
+			Subject     string            `json:"subject"`
+			Text        string            `json:"text"`
+			HTML        string            `json:"html"`
+			Headers     map[string]string `json:"headers"`
+			Attachments []struct {
+				Filename    string `json:"filename"`
+				ContentType string `json:"content_type"`
+				ContentID   string `json:"content_id"`
+				Size        int    `json:"size"`
+				Content     string `json:"content"`
+			} `json:"attachments"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return InboundEmail{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	if payload.Type != "email.received" {
+		return InboundEmail{}, fmt.Errorf("unsupported webhook event %q (expected email.received)", payload.Type)
+	}
+
+	data := payload.Data
+	event := events.EmailEvent{
+		ID:          data.EmailID,
+		MessageID:   strings.Trim(data.MessageID, "<> "),
+		From:        firstAddress(data.From),
+		To:          addressList(data.To...),
+		Cc:          addressList(data.Cc...),
+		Subject:     data.Subject,
+		Text:        data.Text,
+		HTML:        data.HTML,
+		Headers:     make(map[string]string, len(data.Headers)),
+		Attachments: []events.EmailAttachment{},
+	}
+	for name, value := range data.Headers {
+		event.Headers[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+	for i, a := range data.Attachments {
+		content, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil {
+			return InboundEmail{}, fmt.Errorf("attachment %d: content is not base64", i+1)
+		}
+		event.Attachments = append(event.Attachments, events.EmailAttachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			ContentID:   a.ContentID,
+			Size:        max(a.Size, len(content)),
+			Content:     content,
+		})
+	}
+
+	recipients := slices.Concat(event.To, event.Cc, addressList(data.Bcc...))
+	return InboundEmail{Recipients: recipients, Event: event}, nil
+}
+
+// parseMailgunInbound parses the form posted by a Mailgun route, either
+// parsed fields or the raw message in body-mime
+func parseMailgunInbound(r *http.Request) (InboundEmail, error) {
+	if err := r.ParseMultipartForm(MaxInboundSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return InboundEmail{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	recipients := addressList(strings.Split(r.FormValue("recipient"), ",")...)
+
+	if raw := r.FormValue("body-mime"); raw != "" {
+		event, err := ParseMessage([]byte(raw))
+		if len(recipients) == 0 {
+			recipients = slices.Concat(event.To, event.Cc)
+		}
+		return InboundEmail{Recipients: recipients, Event: event}, err
+	}
+
+	event := events.EmailEvent{
+		From:        firstAddress(r.FormValue("from")),
+		Subject:     r.FormValue("subject"),
+		Text:        r.FormValue("body-plain"),
+		HTML:        r.FormValue("body-html"),
+		Headers:     map[string]string{},
+		Attachments: []events.EmailAttachment{},
+	}
+	if event.From == "" {
+		event.From = firstAddress(r.FormValue("sender"))
+	}
+
+	// message-headers is a JSON list of [name, value] pairs
+	var headers [][2]string
+	if value := r.FormValue("message-headers"); value != "" {
+		if err := json.Unmarshal([]byte(value), &headers); err != nil {
+			return InboundEmail{}, fmt.Errorf("invalid message-headers: %w", err)
+		}
+	}
+	for _, header := range headers {
+		name := textproto.CanonicalMIMEHeaderKey(header[0])
+		if _, ok := event.Headers[name]; !ok {
+			event.Headers[name] = header[1]
+		}
+	}
+	event.MessageID = strings.Trim(event.Headers["Message-Id"], "<> ")
+	event.To = addressList(cmp.Or(r.FormValue("To"), event.Headers["To"]))
+	event.Cc = addressList(cmp.Or(r.FormValue("Cc"), event.Headers["Cc"]))
+
+	// content-id-map maps <content ID> to the attachment field
+	contentIDs := map[string]string{}
+	if value := r.FormValue("content-id-map"); value != "" {
+		var idMap map[string]string
+		if err := json.Unmarshal([]byte(value), &idMap); err != nil {
+			return InboundEmail{}, fmt.Errorf("invalid content-id-map: %w", err)
+		}
+		for id, field := range idMap {
+			contentIDs[field] = strings.Trim(id, "<> ")
+		}
+	}
+	count, _ := strconv.Atoi(r.FormValue("attachment-count"))
+	for i := 1; i <= count; i++ {
+		field := "attachment-" + strconv.Itoa(i)
+		attachment, err := formAttachment(r, field)
+		if err != nil {
+			return InboundEmail{}, err
+		}
+		attachment.ContentID = contentIDs[field]
+		event.Attachments = append(event.Attachments, attachment)
+	}
+
+	if len(recipients) == 0 {
+		recipients = slices.Concat(event.To, event.Cc)
+	}
+	return InboundEmail{Recipients: recipients, Event: event}, nil
+}
+
+// parseSendGridInbound parses the form posted by the SendGrid Inbound
+// Parse webhook, either parsed fields or the raw message in email
+func parseSendGridInbound(r *http.Request) (InboundEmail, error) {
+	if err := r.ParseMultipartForm(MaxInboundSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return InboundEmail{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	// envelope holds the SMTP recipients as {"to": [...], "from": "..."}
+	var envelope struct {
+		To []string `json:"to"`
+	}
+	if value := r.FormValue("envelope"); value != "" {
+		if err := json.Unmarshal([]byte(value), &envelope); err != nil {
+			return InboundEmail{}, fmt.Errorf("invalid envelope: %w", err)
+		}
+	}
+	recipients := addressList(envelope.To...)
+
+	if raw := r.FormValue("email"); raw != "" {
+		event, err := ParseMessage([]byte(raw))
+		if len(recipients) == 0 {
+			recipients = slices.Concat(event.To, event.Cc)
+		}
+		return InboundEmail{Recipients: recipients, Event: event}, err
+	}
+
+	event := events.EmailEvent{
+		From:        firstAddress(r.FormValue("from")),
+		To:          addressList(r.FormValue("to")),
+		Cc:          addressList(r.FormValue("cc")),
+		Subject:     r.FormValue("subject"),
+		Text:        r.FormValue("text"),
+		HTML:        r.FormValue("html"),
+		Headers:     map[string]string{},
+		Attachments: []events.EmailAttachment{},
+	}
+
+	// headers is the raw header block of the message
+	if value := r.FormValue("headers"); value != "" {
+		reader := textproto.NewReader(bufio.NewReader(strings.NewReader(value + "\r\n\r\n")))
+		header, err := reader.ReadMIMEHeader()
+		if err != nil {
+			return InboundEmail{}, fmt.Errorf("invalid headers: %w", err)
+		}
+		maps.Copy(event.Headers, decodeHeaders(header))
+	}
+	event.MessageID = strings.Trim(event.Headers["Message-Id"], "<> ")
+
+	// attachment-info describes each attachment field
+	var info map[string]struct {
+		Filename  string `json:"filename"`
+		Type      string `json:"type"`
+		ContentID string `json:"content-id"`
+	}
+	if value := r.FormValue("attachment-info"); value != "" {
+		if err := json.Unmarshal([]byte(value), &info); err != nil {
+			return InboundEmail{}, fmt.Errorf("invalid attachment-info: %w", err)
+		}
+	}
+	count, _ := strconv.Atoi(r.FormValue("attachments"))
+	for i := 1; i <= count; i++ {
+		field := "attachment" + strconv.Itoa(i)
+		attachment, err := formAttachment(r, field)
+		if err != nil {
+			return InboundEmail{}, err
+		}
+		if details, ok := info[field]; ok {
+			attachment.Filename = cmp.Or(details.Filename, attachment.Filename)
+			attachment.ContentType = cmp.Or(details.Type, attachment.ContentType)
+			attachment.ContentID = strings.Trim(details.ContentID, "<> ")
+		}
+		event.Attachments = append(event.Attachments, attachment)
+	}
+
+	if len(recipients) == 0 {
+		recipients = slices.Concat(event.To, event.Cc)
+	}
+	return InboundEmail{Recipients: recipients, Event: event}, nil
+}
+
+// formAttachment reads an uploaded file of a webhook form
+func formAttachment(r *http.Request, field string) (events.EmailAttachment, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return events.EmailAttachment{}, fmt.Errorf("%s: %w", field, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return events.EmailAttachment{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return events.EmailAttachment{
+		Filename:    header.Filename,
+		ContentType: cmp.Or(header.Header.Get("Content-Type"), "application/octet-stream"),
+		Size:        len(content),
+		Content:     content,
+	}, nil
+}
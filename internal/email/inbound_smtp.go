@@ -0,0 +1,350 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the SMTP listener
+const (
+	maxInboundRecipients  = 100
+	maxInboundSessions    = 100
+	inboundCommandTimeout = 5 * time.Minute
+)
+
+// InboundHandler receives the emails accepted by the SMTP listener
+type InboundHandler interface {
+	// Accepts reports whether the emails of a recipient are routed to a
+	// function.
+	Accepts(recipient string) bool
+	// Deliver hands an email to the functions its recipients are routed to.
+	Deliver(ctx context.Context, email InboundEmail) error
+}
+
+// SMTPListener is an SMTP server that accepts the emails of routed
+// addresses. It does not relay or authenticate, so it should only be
+// exposed as the MX of the routed domains or behind a relay.
+type SMTPListener struct {
+	handler     InboundHandler
+	hostname    string
+	maxSessions int // Connections beyond it are refused
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	busy     map[net.Conn]bool // Open connections, true while delivering
+	sessions sync.WaitGroup
+}
+
+// NewSMTPListener creates an SMTP listener greeting clients as hostname
+func NewSMTPListener(handler InboundHandler, hostname string) *SMTPListener {
+	return &SMTPListener{handler: handler, hostname: hostname, maxSessions: maxInboundSessions, busy: make(map[net.Conn]bool)}
+}
+
+// ListenAndServe listens on the TCP address and serves SMTP sessions
+func (s *SMTPListener) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections until the listener is shut down
+func (s *SMTPListener) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !s.admit(conn) {
+			// Refused without starting a session, so excess connections
+			// cost no goroutine
+			_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+			_, _ = conn.Write([]byte("421 4.3.2 Too many connections, try again later\r\n"))
+			_ = conn.Close()
+			continue
+		}
+		s.sessions.Add(1)
+		go func() {
+			defer s.sessions.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Shutdown stops accepting connections, closes the idle ones and waits for
+// the deliveries in progress to end or the context to be done
+func (s *SMTPListener) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn, busy := range s.busy {
+		if !busy {
+			_ = conn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.sessions.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// admit registers a new connection, and reports whether the listener had
+// room for its session
+func (s *SMTPListener) admit(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.busy) >= s.maxSessions {
+		return false
+	}
+	s.busy[conn] = false
+	return true
+}
+
+// setBusy marks a connection as delivering or idle, and reports whether the
+// listener is still open
+func (s *SMTPListener) setBusy(conn net.Conn, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy[conn] = busy
+	return !s.closed
+}
+
+// smtpSession is the state of a client connection
+type smtpSession struct {
+	listener   *SMTPListener
+	conn       net.Conn
+	text       *textproto.Conn
+	greeted    bool
+	hasSender  bool
+	recipients []string
+}
+
+func (s *SMTPListener) serveConn(conn net.Conn) {
+	session := &smtpSession{listener: s, conn: conn, text: textproto.NewConn(conn)}
+	if !s.setBusy(conn, false) {
+		_ = conn.Close()
+		return
+	}
+	defer func() {
+		s.mu.Lock()
+		delete(s.busy, conn)
+		s.mu.Unlock()
+		_ = session.text.Close()
+	}()
+
+	session.reply(220, s.hostname+" ESMTP Lunar")
+	for {
+		_ = conn.SetDeadline(time.Now().Add(inboundCommandTimeout))
+		line, err := session.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if !session.handle(strings.ToUpper(verb), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+// handle runs a command and reports whether the session goes on
+func (c *smtpSession) handle(verb, arg string) bool {
+	switch verb {
+	case "HELO":
+		c.greeted = true
+		c.reset()
+		c.reply(250, c.listener.hostname)
+	case "EHLO":
+		c.greeted = true
+		c.reset()
+		c.reply(250, c.listener.hostname, "SIZE "+strconv.Itoa(MaxInboundSize), "8BITMIME", "PIPELINING")
+	case "MAIL":
+		c.mail(arg)
+	case "RCPT":
+		c.rcpt(arg)
+	case "DATA":
+		return c.data()
+	case "RSET":
+		c.reset()
+		c.reply(250, "2.0.0 OK")
+	case "NOOP":
+		c.reply(250, "2.0.0 OK")
+	case "VRFY":
+		c.reply(252, "2.5.0 Cannot verify the user")
+	case "QUIT":
+		c.reply(221, "2.0.0 Bye")
+		return false
+	default:
+		c.reply(502, "5.5.2 Command not recognized")
+	}
+	return true
+}
+
+func (c *smtpSession) mail(arg string) {
+	if !c.greeted {
+		c.reply(503, "5.5.1 Send HELO or EHLO first")
+		return
+	}
+	if c.hasSender {
+		c.reply(503, "5.5.1 Sender already given")
+		return
+	}
+	_, params, err := parsePath(arg, "FROM:")
+	if err != nil {
+		c.reply(501, "5.1.7 "+err.Error())
+		return
+	}
+	for _, param := range params {
+		if value, ok := strings.CutPrefix(strings.ToUpper(param), "SIZE="); ok {
+			if size, err := strconv.Atoi(value); err == nil && size > MaxInboundSize {
+				c.reply(552, "5.3.4 Message too big")
+				return
+			}
+		}
+	}
+	c.hasSender = true
+	c.reply(250, "2.1.0 OK")
+}
+
+func (c *smtpSession) rcpt(arg string) {
+	if !c.hasSender {
+		c.reply(503, "5.5.1 Send MAIL first")
+		return
+	}
+	address, _, err := parsePath(arg, "TO:")
+	if err != nil || address == "" {
+		c.reply(501, "5.1.3 Invalid recipient address")
+		return
+	}
+	if len(c.recipients) >= maxInboundRecipients {
+		c.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+	if !c.listener.handler.Accepts(address) {
+		c.reply(550, "5.1.1 No such recipient")
+		return
+	}
+	c.recipients = append(c.recipients, address)
+	c.reply(250, "2.1.5 OK")
+}
+
+// data receives and delivers the email, and reports whether the session
+// goes on
+func (c *smtpSession) data() bool {
+	if len(c.recipients) == 0 {
+		c.reply(503, "5.5.1 Send RCPT first")
+		return true
+	}
+	c.reply(354, "End data with <CR><LF>.<CR><LF>")
+
+	dot := c.text.DotReader()
+	raw, err := io.ReadAll(io.LimitReader(dot, MaxInboundSize+1))
+	if err != nil {
+		return false
+	}
+	defer c.reset()
+	if len(raw) > MaxInboundSize {
+		_, _ = io.Copy(io.Discard, dot)
+		c.reply(552, "5.3.4 Message too big")
+		return true
+	}
+
+	event, err := ParseMessage(raw)
+	if err != nil {
+		c.reply(554, "5.6.0 "+err.Error())
+		return true
+	}
+
+	// The delivery runs the functions, which have their own timeouts, and
+	// is not interrupted by a shutdown
+	_ = c.conn.SetDeadline(time.Time{})
+	if !c.listener.setBusy(c.conn, true) {
+		c.reply(421, "4.3.2 Shutting down")
+		return false
+	}
+	err = c.listener.handler.Deliver(context.Background(), InboundEmail{Recipients: c.recipients, Event: event})
+	open := c.listener.setBusy(c.conn, false)
+	if err != nil {
+		slog.Error("Failed to deliver inbound email", "message_id", event.MessageID, "error", err)
+		c.reply(451, "4.3.0 Delivery failed, try again later")
+	} else {
+		c.reply(250, "2.0.0 OK")
+	}
+	return open
+}
+
+// reset clears the current transaction
+func (c *smtpSession) reset() {
+	c.hasSender = false
+	c.recipients = nil
+}
+
+// reply writes a response, one line per message
+func (c *smtpSession) reply(code int, messages ...string) {
+	var b strings.Builder
+	for i, message := range messages {
+		separator := "-"
+		if i == len(messages)-1 {
+			separator = " "
+		}
+		b.WriteString(strconv.Itoa(code) + separator + message + "\r\n")
+	}
+	_, _ = c.conn.Write([]byte(b.String()))
+}
+
+// parsePath parses the <address> and parameters of MAIL FROM and RCPT TO.
+// The null sender <> has an empty address.
+func parsePath(arg, prefix string) (string, []string, error) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, fmt.Errorf("expected %s<address>", prefix)
+	}
+	fields := strings.Fields(strings.TrimSpace(arg[len(prefix):]))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "<") || !strings.HasSuffix(fields[0], ">") {
+		return "", nil, fmt.Errorf("expected %s<address>", prefix)
+	}
+	address := strings.Trim(fields[0], "<>")
+	if address == "" {
+		return "", fields[1:], nil
+	}
+	if _, err := mail.ParseAddress(address); err != nil {
+		return "", nil, errors.New("invalid address")
+	}
+	return address, fields[1:], nil
+}
@@ -0,0 +1,141 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHandler accepts the addresses of one domain and records the
+// delivered emails
+type recordingHandler struct {
+	mu        sync.Mutex
+	domain    string
+	delivered []InboundEmail
+	err       error
+}
+
+func (h *recordingHandler) Accepts(recipient string) bool {
+	return strings.HasSuffix(recipient, "@"+h.domain)
+}
+
+func (h *recordingHandler) Deliver(ctx context.Context, email InboundEmail) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.delivered = append(h.delivered, email)
+	return h.err
+}
+
+// startSMTPListener serves a listener on a random local port
+func startSMTPListener(t *testing.T, handler InboundHandler) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	listener := NewSMTPListener(handler, "mx.lunar.dev")
+	go func() { _ = listener.Serve(l) }()
+	t.Cleanup(func() { _ = listener.Shutdown(context.Background()) })
+	return l.Addr().String()
+}
+
+func TestSMTPListener(t *testing.T) {
+	handler := &recordingHandler{domain: "lunar.dev"}
+	addr := startSMTPListener(t, handler)
+
+	err := smtp.SendMail(addr, nil, "ana@example.com", []string{"support@lunar.dev", "billing@lunar.dev"}, []byte(supportMessage))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	if len(handler.delivered) != 1 {
+		t.Fatalf("expected one delivery, got %d", len(handler.delivered))
+	}
+	delivered := handler.delivered[0]
+	if !reflect.DeepEqual(delivered.Recipients, []string{"support@lunar.dev", "billing@lunar.dev"}) {
+		t.Errorf("unexpected recipients: %v", delivered.Recipients)
+	}
+	if delivered.Event.Subject != "Re: Invoice €12" || len(delivered.Event.Attachments) != 2 {
+		t.Errorf("unexpected event: %+v", delivered.Event)
+	}
+}
+
+func TestSMTPListener_Rejects(t *testing.T) {
+	handler := &recordingHandler{domain: "lunar.dev"}
+	addr := startSMTPListener(t, handler)
+
+	err := smtp.SendMail(addr, nil, "ana@example.com", []string{"someone@example.com"}, []byte(supportMessage))
+	if err == nil || err.Error() != `550 "5.1.1 No such recipient"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	handler.mu.Lock()
+	handler.err = errors.New("database is locked")
+	handler.mu.Unlock()
+	err = smtp.SendMail(addr, nil, "ana@example.com", []string{"support@lunar.dev"}, []byte(supportMessage))
+	if err == nil || err.Error() != `451 "4.3.0 Delivery failed, try again later"` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+	if err := client.Rcpt("support@lunar.dev"); err == nil || !strings.HasPrefix(err.Error(), "503") {
+		t.Errorf("expected RCPT before MAIL to be refused, got %v", err)
+	}
+	if ok, size := client.Extension("SIZE"); !ok || size != "26214400" {
+		t.Errorf("expected the size limit to be advertised, got %q", size)
+	}
+}
+
+func TestSMTPListener_SessionLimit(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	listener := NewSMTPListener(&recordingHandler{domain: "lunar.dev"}, "mx.lunar.dev")
+	listener.maxSessions = 1
+	go func() { _ = listener.Serve(l) }()
+	t.Cleanup(func() { _ = listener.Shutdown(context.Background()) })
+	addr := l.Addr().String()
+
+	first, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	greeting := func() string {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+		line, _ := textproto.NewReader(bufio.NewReader(conn)).ReadLine()
+		return line
+	}
+	if line := greeting(); line != "421 4.3.2 Too many connections, try again later" {
+		t.Errorf("expected the connection to be refused, got %q", line)
+	}
+
+	// The session ends with the connection, making room for another
+	_ = first.Quit()
+	deadline := time.Now().Add(5 * time.Second)
+	for line := greeting(); !strings.HasPrefix(line, "220 "); line = greeting() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a new session once the first ended, got %q", line)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,242 @@
+package email
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+// supportMessage is a reply with text, HTML, an inline image and a PDF
+const supportMessage = "From: =?utf-8?q?Ana_Mar=C3=ADa?= <ana@example.com>\r\n" +
+	"To: Support <support@lunar.dev>, billing@lunar.dev\r\n" +
+	"Cc: boss@example.com\r\n" +
+	"Subject: =?utf-8?q?Re:_Invoice_=E2=82=AC12?=\r\n" +
+	"Message-ID: <abc123@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/related; boundary=related\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=alt\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Ol=E1, the invoice is wrong.\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Hi</p><img src=\"cid:logo\">\r\n" +
+	"--alt--\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-ID: <logo>\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"\r\n" +
+	"iVBO\r\n" +
+	"Rw0K\r\n" +
+	"--related--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+	"\r\n" +
+	"JVBERi0xLjc=\r\n" +
+	"--outer--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	event, err := ParseMessage([]byte(supportMessage))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if event.From != "ana@example.com" || event.MessageID != "abc123@example.com" {
+		t.Errorf("unexpected sender or message ID: %q %q", event.From, event.MessageID)
+	}
+	if !reflect.DeepEqual(event.To, []string{"support@lunar.dev", "billing@lunar.dev"}) || !reflect.DeepEqual(event.Cc, []string{"boss@example.com"}) {
+		t.Errorf("unexpected recipients: %v %v", event.To, event.Cc)
+	}
+	if event.Subject != "Re: Invoice €12" || event.Headers["From"] != "Ana María <ana@example.com>" {
+		t.Errorf("expected decoded headers, got %q %q", event.Subject, event.Headers["From"])
+	}
+	if event.Text != "Olá, the invoice is wrong." {
+		t.Errorf("unexpected text: %q", event.Text)
+	}
+	if event.HTML != `<p>Hi</p><img src="cid:logo">` {
+		t.Errorf("unexpected HTML: %q", event.HTML)
+	}
+
+	expected := []events.EmailAttachment{
+		{ContentType: "image/png", ContentID: "logo", Size: 6, Content: []byte("\x89PNG\r\n")},
+		{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 8, Content: []byte("%PDF-1.7")},
+	}
+	if !reflect.DeepEqual(event.Attachments, expected) {
+		t.Errorf("expected attachments %+v, got %+v", expected, event.Attachments)
+	}
+}
+
+func TestParseMessage_Errors(t *testing.T) {
+	tests := map[string]string{
+		"no headers":    "not an email",
+		"no boundary":   "Content-Type: multipart/mixed\r\n\r\nbody",
+		"bad multipart": "Content-Type: multipart/mixed; boundary=x\r\n\r\n--x\r\nbroken",
+	}
+
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseMessage([]byte(raw)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// formRequest builds a multipart/form-data webhook request
+func formRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		_ = w.WriteField(name, value)
+	}
+	for name, content := range files {
+		fw, err := w.CreateFormFile(name, name+".txt")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/inbound/email", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestParseInboundWebhook_Mailgun(t *testing.T) {
+	req := formRequest(t, map[string]string{
+		"recipient":        "support@lunar.dev",
+		"from":             "Ana <ana@example.com>",
+		"To":               "support@lunar.dev",
+		"subject":          "Help",
+		"body-plain":       "It broke",
+		"body-html":        "<p>It broke</p>",
+		"message-headers":  `[["Message-Id", "<m1@example.com>"], ["X-Mailer", "test"]]`,
+		"attachment-count": "1",
+		"content-id-map":   `{"<log>": "attachment-1"}`,
+	}, map[string]string{"attachment-1": "trace"})
+
+	inbound, err := ParseInboundWebhook(InboundMailgun, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := inbound.Event
+	if !reflect.DeepEqual(inbound.Recipients, []string{"support@lunar.dev"}) {
+		t.Errorf("unexpected recipients: %v", inbound.Recipients)
+	}
+	if event.From != "ana@example.com" || event.Subject != "Help" || event.Text != "It broke" || event.HTML != "<p>It broke</p>" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if event.MessageID != "m1@example.com" || event.Headers["X-Mailer"] != "test" {
+		t.Errorf("unexpected headers: %v", event.Headers)
+	}
+	if len(event.Attachments) != 1 || event.Attachments[0].ContentID != "log" || string(event.Attachments[0].Content) != "trace" {
+		t.Errorf("unexpected attachments: %+v", event.Attachments)
+	}
+}
+
+func TestParseInboundWebhook_MailgunMIME(t *testing.T) {
+	req := formRequest(t, map[string]string{
+		"recipient": "billing@lunar.dev",
+		"body-mime": supportMessage,
+	}, nil)
+
+	inbound, err := ParseInboundWebhook(InboundMailgun, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(inbound.Recipients, []string{"billing@lunar.dev"}) || inbound.Event.Subject != "Re: Invoice €12" {
+		t.Errorf("unexpected inbound email: %+v", inbound)
+	}
+}
+
+func TestParseInboundWebhook_SendGrid(t *testing.T) {
+	req := formRequest(t, map[string]string{
+		"headers":         "Message-ID: <s1@example.com>\nX-Spam-Score: 0.1",
+		"from":            "Ana <ana@example.com>",
+		"to":              "Support <support@lunar.dev>",
+		"cc":              "",
+		"subject":         "Help",
+		"text":            "It broke",
+		"envelope":        `{"to": ["support@lunar.dev"], "from": "ana@example.com"}`,
+		"attachments":     "1",
+		"attachment-info": `{"attachment1": {"filename": "trace.log", "type": "text/plain"}}`,
+	}, map[string]string{"attachment1": "trace"})
+
+	inbound, err := ParseInboundWebhook(InboundSendGrid, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := inbound.Event
+	if !reflect.DeepEqual(inbound.Recipients, []string{"support@lunar.dev"}) || !reflect.DeepEqual(event.To, []string{"support@lunar.dev"}) {
+		t.Errorf("unexpected recipients: %v %v", inbound.Recipients, event.To)
+	}
+	if event.MessageID != "s1@example.com" || event.Headers["X-Spam-Score"] != "0.1" || event.Text != "It broke" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	expected := []events.EmailAttachment{{Filename: "trace.log", ContentType: "text/plain", Size: 5, Content: []byte("trace")}}
+	if !reflect.DeepEqual(event.Attachments, expected) {
+		t.Errorf("expected attachments %+v, got %+v", expected, event.Attachments)
+	}
+}
+
+func TestParseInboundWebhook_Resend(t *testing.T) {
+	body := `{
+		"type": "email.received",
+		"data": {
+			"email_id": "4ef9a417",
+			"message_id": "<r1@example.com>",
+			"from": "Ana <ana@example.com>",
+			"to": ["support@lunar.dev"],
+			"bcc": ["audit@lunar.dev"],
+			"subject": "Help",
+			"text": "It broke",
+			"attachments": [{"filename": "trace.log", "content_type": "text/plain", "content": "dHJhY2U="}]
+		}
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/inbound/email", strings.NewReader(body))
+
+	inbound, err := ParseInboundWebhook(InboundResend, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	event := inbound.Event
+	if !reflect.DeepEqual(inbound.Recipients, []string{"support@lunar.dev", "audit@lunar.dev"}) {
+		t.Errorf("unexpected recipients: %v", inbound.Recipients)
+	}
+	if event.ID != "4ef9a417" || event.MessageID != "r1@example.com" || event.From != "ana@example.com" || event.Text != "It broke" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if len(event.Attachments) != 1 || string(event.Attachments[0].Content) != "trace" {
+		t.Errorf("unexpected attachments: %+v", event.Attachments)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/inbound/email", strings.NewReader(`{"type": "email.sent", "data": {}}`))
+	if _, err := ParseInboundWebhook(InboundResend, req); err == nil || err.Error() != `unsupported webhook event "email.sent" (expected email.received)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := ParseInboundWebhook("postmark", req); err == nil || err.Error() != `unknown inbound email provider "postmark" (use resend, mailgun or sendgrid)` {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
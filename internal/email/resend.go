@@ -0,0 +1,80 @@
+package email
+
+import (
+	"net/url"
+
+	"github.com/resend/resend-go/v3"
+)
+
+// resendTransport sends emails with the Resend API
+type resendTransport struct {
+	client *resend.Client
+}
+
+// resendTransport reads the Resend configuration of a function
+func (c *DefaultClient) resendTransport(functionID string) (transport, error) {
+	// Get API key from environment
+	apiKey, err := c.envStore.Get(functionID, ResendAPIKeyEnv)
+	if err != nil || apiKey == "" {
+		return nil, &ConfigError{Field: ResendAPIKeyEnv}
+	}
+
+	// Create Resend client
+	client := resend.NewClient(apiKey)
+
+	// Allow custom base URL for testing (read from function env)
+	if baseURL, err := c.envStore.Get(functionID, ResendBaseURLEnv); err == nil && baseURL != "" {
+		if parsedURL, err := url.Parse(baseURL); err == nil {
+			client.BaseURL = parsedURL
+		}
+	}
+
+	return resendTransport{client: client}, nil
+}
+
+func (t resendTransport) send(req SendRequest) (string, error) {
+	// Build Resend request params
+	params := &resend.SendEmailRequest{
+		From:    req.From,
+		To:      req.To,
+		Subject: req.Subject,
+		Text:    req.Text,
+		Html:    req.HTML,
+		ReplyTo: req.ReplyTo,
+	}
+
+	if len(req.Cc) > 0 {
+		params.Cc = req.Cc
+	}
+	if len(req.Bcc) > 0 {
+		params.Bcc = req.Bcc
+	}
+	if len(req.Headers) > 0 {
+		params.Headers = req.Headers
+	}
+	if len(req.Tags) > 0 {
+		var tags []resend.Tag
+		for _, t := range req.Tags {
+			tags = append(tags, resend.Tag{Name: t.Name, Value: t.Value})
+		}
+		params.Tags = tags
+	}
+	if req.ScheduledAt != "" {
+		params.ScheduledAt = req.ScheduledAt
+	}
+	for _, a := range req.Attachments {
+		params.Attachments = append(params.Attachments, &resend.Attachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     a.Content,
+			ContentId:   a.ContentID,
+		})
+	}
+
+	// Send email
+	sent, err := t.client.Emails.Send(params)
+	if err != nil {
+		return "", err
+	}
+	return sent.Id, nil
+}
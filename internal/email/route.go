@@ -0,0 +1,186 @@
+package email
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// RouteStore stores the routes of inbound email addresses to functions
+type RouteStore interface {
+	// Match returns the route of an address, falling back to the *@domain
+	// route of its domain, or nil if neither exists.
+	Match(address string) (*store.InboundEmailRoute, error)
+	// List returns every route ordered by address.
+	List() ([]store.InboundEmailRoute, error)
+	// Save creates or replaces the route of an address.
+	Save(route store.InboundEmailRoute) (*store.InboundEmailRoute, error)
+	// Delete removes a route and reports whether it existed.
+	Delete(address string) (bool, error)
+}
+
+// NormalizeRouteAddress validates the address of a route and returns it in
+// lower case. It is either an email address or *@domain.
+func NormalizeRouteAddress(address string) (string, error) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if domain, ok := strings.CutPrefix(address, "*@"); ok {
+		if _, err := mail.ParseAddress("postmaster@" + domain); err != nil || domain == "" {
+			return "", fmt.Errorf("invalid domain %q", domain)
+		}
+		return address, nil
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address || parsed.Name != "" {
+		return "", fmt.Errorf("invalid address %q (use name@domain or *@domain)", address)
+	}
+	return address, nil
+}
+
+// routeCandidates returns the addresses that can route an email, the
+// address itself first
+func routeCandidates(address string) []string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return []string{address}
+	}
+	return []string{address, "*" + address[at:]}
+}
+
+// MemoryRouteStore is an in-memory implementation of RouteStore
+type MemoryRouteStore struct {
+	mu     sync.RWMutex
+	routes map[string]store.InboundEmailRoute
+}
+
+// NewMemoryRouteStore creates a new in-memory route store
+func NewMemoryRouteStore() *MemoryRouteStore {
+	return &MemoryRouteStore{routes: make(map[string]store.InboundEmailRoute)}
+}
+
+// Match returns the route of an address or its domain, or nil
+func (m *MemoryRouteStore) Match(address string) (*store.InboundEmailRoute, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, candidate := range routeCandidates(address) {
+		if route, ok := m.routes[candidate]; ok {
+			return &route, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns every route ordered by address
+func (m *MemoryRouteStore) List() ([]store.InboundEmailRoute, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routes := make([]store.InboundEmailRoute, 0, len(m.routes))
+	for _, route := range m.routes {
+		routes = append(routes, route)
+	}
+	slices.SortFunc(routes, func(a, b store.InboundEmailRoute) int {
+		return strings.Compare(a.Address, b.Address)
+	})
+	return routes, nil
+}
+
+// Save creates or replaces the route of an address
+func (m *MemoryRouteStore) Save(route store.InboundEmailRoute) (*store.InboundEmailRoute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	route.CreatedAt = time.Now().Unix()
+	m.routes[route.Address] = route
+	return &route, nil
+}
+
+// Delete removes a route and reports whether it existed
+func (m *MemoryRouteStore) Delete(address string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.routes[address]
+	delete(m.routes, address)
+	return ok, nil
+}
+
+// SQLiteRouteStore is a SQLite-backed implementation of RouteStore
+type SQLiteRouteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteRouteStore creates a new SQLite-backed route store
+func NewSQLiteRouteStore(db *sql.DB) *SQLiteRouteStore {
+	return &SQLiteRouteStore{db: db}
+}
+
+// Match returns the route of an address or its domain, or nil
+func (s *SQLiteRouteStore) Match(address string) (*store.InboundEmailRoute, error) {
+	for _, candidate := range routeCandidates(address) {
+		var route store.InboundEmailRoute
+		err := s.db.QueryRow(
+			"SELECT address, function_id, created_at FROM inbound_email_routes WHERE address = ?",
+			candidate,
+		).Scan(&route.Address, &route.FunctionID, &route.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &route, nil
+	}
+	return nil, nil
+}
+
+// List returns every route ordered by address
+func (s *SQLiteRouteStore) List() ([]store.InboundEmailRoute, error) {
+	rows, err := s.db.Query("SELECT address, function_id, created_at FROM inbound_email_routes ORDER BY address")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	routes := make([]store.InboundEmailRoute, 0)
+	for rows.Next() {
+		var route store.InboundEmailRoute
+		if err := rows.Scan(&route.Address, &route.FunctionID, &route.CreatedAt); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, rows.Err()
+}
+
+// Save creates or replaces the route of an address
+func (s *SQLiteRouteStore) Save(route store.InboundEmailRoute) (*store.InboundEmailRoute, error) {
+	route.CreatedAt = time.Now().Unix()
+	_, err := s.db.Exec(
+		`INSERT INTO inbound_email_routes (address, function_id, created_at) VALUES (?, ?, ?)
+		 ON CONFLICT(address) DO UPDATE SET function_id = excluded.function_id, created_at = excluded.created_at`,
+		route.Address, route.FunctionID, route.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+// Delete removes a route and reports whether it existed
+func (s *SQLiteRouteStore) Delete(address string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM inbound_email_routes WHERE address = ?", address)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
@@ -0,0 +1,94 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// routeStores returns every implementation, so each test runs against both
+func routeStores(t *testing.T) map[string]RouteStore {
+	return map[string]RouteStore{
+		"memory": NewMemoryRouteStore(),
+		"sqlite": NewSQLiteRouteStore(setupTestDB(t)),
+	}
+}
+
+func TestRouteStore(t *testing.T) {
+	for name, routes := range routeStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, route := range []store.InboundEmailRoute{
+				{Address: "support@lunar.dev", FunctionID: "fn_support"},
+				{Address: "*@lunar.dev", FunctionID: "fn_catch_all"},
+				{Address: "billing@lunar.dev", FunctionID: "fn_support"},
+				{Address: "billing@lunar.dev", FunctionID: "fn_billing"},
+			} {
+				if _, err := routes.Save(route); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			for address, expected := range map[string]string{
+				"support@lunar.dev":  "fn_support",
+				"Billing@Lunar.dev":  "fn_billing",
+				"anyone@lunar.dev":   "fn_catch_all",
+				"support@example.io": "",
+			} {
+				route, err := routes.Match(address)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got := ""
+				if route != nil {
+					got = route.FunctionID
+				}
+				if got != expected {
+					t.Errorf("%s: expected function %q, got %q", address, expected, got)
+				}
+			}
+
+			list, err := routes.List()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(list) != 3 || list[0].Address != "*@lunar.dev" || list[2].Address != "support@lunar.dev" {
+				t.Errorf("expected routes ordered by address, got %+v", list)
+			}
+
+			if deleted, _ := routes.Delete("*@lunar.dev"); !deleted {
+				t.Error("expected the catch-all route to be deleted")
+			}
+			if route, _ := routes.Match("anyone@lunar.dev"); route != nil {
+				t.Errorf("expected no route, got %+v", route)
+			}
+			if deleted, _ := routes.Delete("*@lunar.dev"); deleted {
+				t.Error("expected a second delete to find nothing")
+			}
+		})
+	}
+}
+
+func TestNormalizeRouteAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+		err      string
+	}{
+		{"Support@Lunar.dev", "support@lunar.dev", ""},
+		{" *@lunar.dev ", "*@lunar.dev", ""},
+		{"Support <support@lunar.dev>", "", `invalid address "support <support@lunar.dev>" (use name@domain or *@domain)`},
+		{"support", "", `invalid address "support" (use name@domain or *@domain)`},
+		{"*@", "", `invalid domain ""`},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeRouteAddress(tt.address)
+		gotErr := ""
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if got != tt.expected || gotErr != tt.err {
+			t.Errorf("%q: expected %q and error %q, got %q and %q", tt.address, tt.expected, tt.err, got, gotErr)
+		}
+	}
+}
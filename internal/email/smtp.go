@@ -0,0 +1,413 @@
+package email
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/rs/xid"
+)
+
+// Environment variable names for SMTP configuration
+const (
+	SMTPHostEnv               = "SMTP_HOST"
+	SMTPPortEnv               = "SMTP_PORT"
+	SMTPUsernameEnv           = "SMTP_USERNAME"
+	SMTPPasswordEnv           = "SMTP_PASSWORD"
+	SMTPTLSEnv                = "SMTP_TLS"
+	SMTPInsecureSkipVerifyEnv = "SMTP_INSECURE_SKIP_VERIFY"
+)
+
+// SMTP connection security, set with SMTP_TLS
+const (
+	smtpStartTLS = "starttls" // Upgrade a plain connection, required (default)
+	smtpTLS      = "tls"      // Implicit TLS (default on port 465)
+	smtpNone     = "none"     // No encryption, for relays on a private network
+)
+
+// smtpTimeout bounds a whole SMTP session
+const smtpTimeout = 30 * time.Second
+
+// smtpTransport sends emails through an SMTP server
+type smtpTransport struct {
+	host      string
+	port      string
+	username  string
+	password  string
+	security  string
+	tlsConfig *tls.Config
+}
+
+// smtpTransport reads the SMTP configuration of a function
+func (c *DefaultClient) smtpTransport(functionID string) (transport, error) {
+	host, err := c.envStore.Get(functionID, SMTPHostEnv)
+	if err != nil || host == "" {
+		return nil, &ConfigError{Field: SMTPHostEnv}
+	}
+	port, _ := c.envStore.Get(functionID, SMTPPortEnv)
+	security, _ := c.envStore.Get(functionID, SMTPTLSEnv)
+	username, _ := c.envStore.Get(functionID, SMTPUsernameEnv)
+	password, _ := c.envStore.Get(functionID, SMTPPasswordEnv)
+
+	if security == "" {
+		security = smtpStartTLS
+		if port == "465" {
+			security = smtpTLS
+		}
+	}
+	switch security {
+	case smtpStartTLS:
+		port = cmp.Or(port, "587")
+	case smtpTLS:
+		port = cmp.Or(port, "465")
+	case smtpNone:
+		port = cmp.Or(port, "25")
+	default:
+		return nil, fmt.Errorf("%s must be starttls, tls or none, got %q", SMTPTLSEnv, security)
+	}
+
+	tlsConfig := &tls.Config{ServerName: host}
+	if value, _ := c.envStore.Get(functionID, SMTPInsecureSkipVerifyEnv); value != "" {
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", SMTPInsecureSkipVerifyEnv, value)
+		}
+		tlsConfig.InsecureSkipVerify = skip
+	}
+
+	return smtpTransport{
+		host:      host,
+		port:      port,
+		username:  username,
+		password:  password,
+		security:  security,
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+// send delivers the email and returns its Message-ID
+func (t smtpTransport) send(req SendRequest) (string, error) {
+	if req.ScheduledAt != "" {
+		return "", errors.New("scheduled_at is not supported by the smtp provider")
+	}
+
+	from, err := mail.ParseAddress(req.From)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address %q: %w", req.From, err)
+	}
+	var recipients []string
+	for _, list := range [][]string{req.To, req.Cc, req.Bcc} {
+		addresses, err := parseAddresses(list)
+		if err != nil {
+			return "", err
+		}
+		for _, address := range addresses {
+			recipients = append(recipients, address.Address)
+		}
+	}
+
+	id := xid.New().String() + from.Address[strings.LastIndex(from.Address, "@"):]
+	message, err := buildMessage(req, id, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	client, err := t.dial(req.Policy)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = client.Close() }()
+
+	if t.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return "", errors.New("SMTP server does not support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", t.username, t.password, t.host)); err != nil {
+			return "", fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return "", err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return "", fmt.Errorf("recipient %s refused: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(message); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return id, client.Quit()
+}
+
+// dial connects to the server under the network policy, when set, and
+// secures the connection
+func (t smtpTransport) dial(policy *internalhttp.Policy) (*smtp.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), smtpTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(t.host, t.port)
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if policy != nil {
+		conn, err = policy.Dial(ctx, dialer, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	if t.security == smtpTLS {
+		tlsConn := tls.Client(conn, t.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if t.security == smtpStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(t.tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// generatedHeaders are the headers buildMessage writes, which custom headers
+// cannot set
+var generatedHeaders = []string{
+	"Bcc", "Cc", "Content-Transfer-Encoding", "Content-Type", "Date", "From",
+	"Message-ID", "MIME-Version", "Reply-To", "Subject", "To",
+}
+
+// validHeaderName reports whether name is an RFC 5322 field name, made of
+// printable ASCII characters other than the colon
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if c < '!' || c > '~' || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// mimePart is a MIME entity: its headers and encoded body
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// buildMessage renders the email as a MIME message. Bcc recipients are
+// left out of the headers.
+func buildMessage(req SendRequest, messageID string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) error {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s contains a line break", name)
+		}
+		buf.WriteString(name + ": " + value + "\r\n")
+		return nil
+	}
+
+	from, err := parseAddresses([]string{req.From})
+	if err != nil {
+		return nil, err
+	}
+	headers := [][2]string{
+		{"From", formatAddresses(from)},
+		{"Subject", mime.QEncoding.Encode("utf-8", req.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + messageID + ">"},
+		{"MIME-Version", "1.0"},
+	}
+	for _, field := range []struct {
+		name string
+		list []string
+	}{{"To", req.To}, {"Cc", req.Cc}, {"Reply-To", []string{req.ReplyTo}}} {
+		if len(field.list) == 0 || field.list[0] == "" {
+			continue
+		}
+		addresses, err := parseAddresses(field.list)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, [2]string{field.name, formatAddresses(addresses)})
+	}
+	for _, name := range slices.Sorted(maps.Keys(req.Headers)) {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		if slices.ContainsFunc(generatedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+			return nil, fmt.Errorf("header %s is set by the smtp provider", name)
+		}
+		headers = append(headers, [2]string{textproto.CanonicalMIMEHeaderKey(name), req.Headers[name]})
+	}
+	for _, header := range headers {
+		if err := writeHeader(header[0], header[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range req.Attachments {
+		if strings.ContainsAny(a.ContentID, "<> \t\r\n") {
+			return nil, fmt.Errorf("invalid content ID %q", a.ContentID)
+		}
+		if a.ContentType != "" && !ValidContentType(a.ContentType) {
+			return nil, fmt.Errorf("invalid content type %q", a.ContentType)
+		}
+	}
+	body := messageBody(req)
+	for _, name := range slices.Sorted(maps.Keys(body.header)) {
+		buf.WriteString(name + ": " + body.header.Get(name) + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.body)
+	return buf.Bytes(), nil
+}
+
+// messageBody returns the text and HTML alternatives, related to the inline
+// files, followed by the attachments
+func messageBody(req SendRequest) mimePart {
+	var alternatives []mimePart
+	if req.Text != "" || req.HTML == "" {
+		alternatives = append(alternatives, textPart("text/plain", req.Text))
+	}
+	if req.HTML != "" {
+		alternatives = append(alternatives, textPart("text/html", req.HTML))
+	}
+	body := alternatives[0]
+	if len(alternatives) > 1 {
+		body = multipartPart("alternative", alternatives)
+	}
+
+	related := []mimePart{body}
+	mixed := []mimePart{}
+	for _, a := range req.Attachments {
+		if a.ContentID != "" {
+			related = append(related, attachmentPart(a))
+		} else {
+			mixed = append(mixed, attachmentPart(a))
+		}
+	}
+	if len(related) > 1 {
+		body = multipartPart("related", related)
+	}
+	if len(mixed) == 0 {
+		return body
+	}
+	return multipartPart("mixed", append([]mimePart{body}, mixed...))
+}
+
+func textPart(contentType, text string) mimePart {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	_, _ = w.Write([]byte(text))
+	_ = w.Close()
+	return mimePart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: buf.Bytes(),
+	}
+}
+
+// attachmentPart encodes a file, inline when it has a content ID
+func attachmentPart(a Attachment) mimePart {
+	// Base64 in lines of 76 characters
+	encoded := base64.StdEncoding.EncodeToString(a.Content)
+	var body bytes.Buffer
+	for len(encoded) > 76 {
+		body.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	body.WriteString(encoded)
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{
+		"Content-Type":              {a.contentType()},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if a.ContentID != "" {
+		disposition = "inline"
+		header.Set("Content-Id", "<"+a.ContentID+">")
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	return mimePart{header: header, body: body.Bytes()}
+}
+
+func multipartPart(subtype string, parts []mimePart) mimePart {
+	// Writes to a buffer cannot fail
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, part := range parts {
+		pw, _ := w.CreatePart(part.header)
+		_, _ = pw.Write(part.body)
+	}
+	_ = w.Close()
+	return mimePart{
+		header: textproto.MIMEHeader{"Content-Type": {"multipart/" + subtype + "; boundary=" + w.Boundary()}},
+		body:   buf.Bytes(),
+	}
+}
+
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, value := range list {
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", value, err)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func formatAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,394 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+)
+
+// fakeSMTPServer is an in-process SMTP server recording the messages it
+// receives
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config // STARTTLS is offered when set
+	username  string
+	password  string
+
+	mu       sync.Mutex
+	messages []fakeSMTPMessage
+}
+
+type fakeSMTPMessage struct {
+	from   string
+	to     []string
+	data   string
+	tls    bool
+	authed bool
+}
+
+// newFakeSMTPServer starts a server. With implicitTLS the connection is
+// encrypted from the start, otherwise STARTTLS is offered if startTLS.
+func newFakeSMTPServer(t *testing.T, implicitTLS, startTLS bool) *fakeSMTPServer {
+	// Borrow the self-signed certificate of httptest
+	certServer := httptest.NewTLSServer(nil)
+	tlsConfig := &tls.Config{Certificates: certServer.TLS.Certificates}
+	certServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	if implicitTLS {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s := &fakeSMTPServer{listener: listener, username: "user", password: "secret"}
+	if startTLS {
+		s.tlsConfig = tlsConfig
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, implicitTLS)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSMTPMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn, secure bool) {
+	defer func() { _ = conn.Close() }()
+	tp := textproto.NewConn(conn)
+	msg := fakeSMTPMessage{tls: secure}
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			_ = tp.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !msg.tls {
+				_ = tp.PrintfLine("250-STARTTLS")
+			}
+			_ = tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg.tls = true
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			if string(credentials) != "\x00"+s.username+"\x00"+s.password {
+				_ = tp.PrintfLine("535 Authentication failed")
+				continue
+			}
+			msg.authed = true
+			_ = tp.PrintfLine("235 Authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			_ = tp.PrintfLine("250 Queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+// smtpClient returns a client sending through the server
+func smtpClient(s *fakeSMTPServer, extra map[string]string) *DefaultClient {
+	values := map[string]string{
+		ProviderEnv:               "smtp",
+		SMTPHostEnv:               "127.0.0.1",
+		SMTPPortEnv:               s.port(),
+		SMTPUsernameEnv:           "user",
+		SMTPPasswordEnv:           "secret",
+		SMTPInsecureSkipVerifyEnv: "true",
+	}
+	for k, v := range extra {
+		values[k] = v
+	}
+	return NewDefaultClient(&mockEnvStore{values: map[string]map[string]string{"func-1": values}})
+}
+
+func TestSMTP_Send_StartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, false, true)
+	client := smtpClient(server, nil)
+
+	resp, err := client.Send("func-1", SendRequest{
+		From:        "Lunar <noreply@example.com>",
+		To:          []string{"user@example.com"},
+		Cc:          []string{"cc@example.com"},
+		Bcc:         []string{"bcc@example.com"},
+		ReplyTo:     "support@example.com",
+		Subject:     "Relatório pronto",
+		Text:        "Your report is attached.",
+		HTML:        "<p>Your report is attached.</p>",
+		Headers:     map[string]string{"X-Entity-Ref-ID": "123"},
+		Attachments: []Attachment{{Filename: "report.pdf", Content: []byte("%PDF-1.7")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	received := messages[0]
+	if !received.tls || !received.authed {
+		t.Errorf("expected an authenticated TLS session, got tls=%v authed=%v", received.tls, received.authed)
+	}
+	if received.from != "noreply@example.com" || strings.Join(received.to, ",") != "user@example.com,cc@example.com,bcc@example.com" {
+		t.Errorf("unexpected envelope: from %s to %v", received.from, received.to)
+	}
+	if !strings.Contains(resp.RequestJSON, `"subject":"Relatório pronto"`) {
+		t.Errorf("expected the request JSON to be kept for tracking, got %s", resp.RequestJSON)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(received.data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Relatório pronto" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+	if msg.Header.Get("Message-ID") != "<"+resp.ID+">" || !strings.HasSuffix(resp.ID, "@example.com") {
+		t.Errorf("expected the Message-ID %s to be returned as the ID, got %s", msg.Header.Get("Message-ID"), resp.ID)
+	}
+	if msg.Header.Get("Bcc") != "" || msg.Header.Get("Cc") != "<cc@example.com>" || msg.Header.Get("X-Entity-Ref-Id") != "123" {
+		t.Errorf("unexpected headers: %v", msg.Header)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("expected multipart/mixed, got %s", mediaType)
+	}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	body, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read body: %v", err)
+	}
+	if !strings.HasPrefix(body.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected the text and HTML alternatives, got %s", body.Header.Get("Content-Type"))
+	}
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read attachment: %v", err)
+	}
+	content, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if attachment.FileName() != "report.pdf" || attachment.Header.Get("Content-Type") != "application/pdf" || string(content) != "%PDF-1.7" {
+		t.Errorf("unexpected attachment %s (%s): %q", attachment.FileName(), attachment.Header.Get("Content-Type"), content)
+	}
+}
+
+func TestSMTP_Send_ImplicitTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, true, false)
+	client := smtpClient(server, map[string]string{SMTPTLSEnv: "tls"})
+
+	_, err := client.Send("func-1", SendRequest{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Hi",
+		HTML:    "<p>Hi</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 || !messages[0].tls {
+		t.Fatalf("expected 1 message over TLS, got %+v", messages)
+	}
+	if !strings.Contains(messages[0].data, "Content-Type: text/html; charset=utf-8") {
+		t.Errorf("expected a single HTML body, got %s", messages[0].data)
+	}
+}
+
+func TestSMTP_Send_Errors(t *testing.T) {
+	plain := newFakeSMTPServer(t, false, false)
+	req := SendRequest{From: "noreply@example.com", To: []string{"user@example.com"}, Subject: "Hi", Text: "Hi"}
+
+	tests := []struct {
+		name     string
+		extra    map[string]string
+		req      func(SendRequest) SendRequest
+		expected string
+	}{
+		{"no STARTTLS", nil, nil, "SMTP server does not support STARTTLS"},
+		{"wrong password", map[string]string{SMTPTLSEnv: "none", SMTPPasswordEnv: "wrong"}, nil, `SMTP authentication failed: 535 "Authentication failed"`},
+		{"invalid TLS mode", map[string]string{SMTPTLSEnv: "ssl"}, nil, `SMTP_TLS must be starttls, tls or none, got "ssl"`},
+		{"unknown provider", map[string]string{ProviderEnv: "postmark"}, nil, `unknown email provider "postmark" (use resend or smtp)`},
+		{"missing host", map[string]string{SMTPHostEnv: ""}, nil, "SMTP_HOST not set in function environment"},
+		{"scheduled", map[string]string{SMTPTLSEnv: "none"}, func(r SendRequest) SendRequest {
+			r.ScheduledAt = "2030-01-01T10:00:00Z"
+			return r
+		}, "scheduled_at is not supported by the smtp provider"},
+		{"network policy", map[string]string{SMTPTLSEnv: "none"}, func(r SendRequest) SendRequest {
+			r.Policy, _ = internalhttp.NewPolicy(nil)
+			return r
+		}, "request blocked by network policy: address 127.0.0.1 is in a private or reserved range"},
+		{"header injection", map[string]string{SMTPTLSEnv: "none"}, func(r SendRequest) SendRequest {
+			r.Headers = map[string]string{"X-Note": "a\r\nBcc: victim@example.com"}
+			return r
+		}, "header X-Note contains a line break"},
+		{"header name injection", map[string]string{SMTPTLSEnv: "none"}, func(r SendRequest) SendRequest {
+			r.Headers = map[string]string{"X-A\r\nBcc: victim@example.com": "1"}
+			return r
+		}, `invalid header name "X-A\r\nBcc: victim@example.com"`},
+		{"generated header", map[string]string{SMTPTLSEnv: "none"}, func(r SendRequest) SendRequest {
+			r.Headers = map[string]string{"message-id": "<forged@example.com>"}
+			return r
+		}, "header message-id is set by the smtp provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendReq := req
+			if tt.req != nil {
+				sendReq = tt.req(req)
+			}
+			_, err := smtpClient(plain, tt.extra).Send("func-1", sendReq)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	var configErr *ConfigError
+	if _, err := smtpClient(plain, map[string]string{SMTPHostEnv: ""}).Send("func-1", req); !errors.As(err, &configErr) {
+		t.Errorf("expected a ConfigError, got %T", err)
+	}
+	if len(plain.received()) != 0 {
+		t.Errorf("expected no message to be delivered, got %d", len(plain.received()))
+	}
+
+	// Plain sessions work without credentials
+	if _, err := smtpClient(plain, map[string]string{SMTPTLSEnv: "none", SMTPUsernameEnv: ""}).Send("func-1", req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if messages := plain.received(); len(messages) != 1 || messages[0].tls || messages[0].authed {
+		t.Errorf("expected 1 unauthenticated plain message, got %+v", messages)
+	}
+}
+
+func TestBuildMessage_InlineAttachments(t *testing.T) {
+	data, err := buildMessage(SendRequest{
+		From:    "noreply@example.com",
+		To:      []string{"user@example.com"},
+		Subject: "Logo",
+		HTML:    `<img src="cid:logo">`,
+		Attachments: []Attachment{
+			{Filename: "logo.png", Content: []byte("PNG"), ContentID: "logo"},
+			{Filename: "terms.pdf", Content: []byte("%PDF-1.7")},
+		},
+	}, "id@example.com", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+	// The HTML and the inline logo are related, the PDF is attached
+	related, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read part: %v", err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(related.Header.Get("Content-Type"))
+	if mediaType != "multipart/related" {
+		t.Fatalf("expected multipart/related, got %s", mediaType)
+	}
+	inline := multipart.NewReader(related, params["boundary"])
+	if html, _ := inline.NextPart(); html == nil || !strings.HasPrefix(html.Header.Get("Content-Type"), "text/html") {
+		t.Error("expected the HTML first")
+	}
+	logo, err := inline.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read part: %v", err)
+	}
+	if logo.Header.Get("Content-Id") != "<logo>" || !strings.HasPrefix(logo.Header.Get("Content-Disposition"), "inline") {
+		t.Errorf("unexpected inline part headers: %v", logo.Header)
+	}
+	if terms, _ := mixed.NextPart(); terms == nil || terms.FileName() != "terms.pdf" {
+		t.Error("expected terms.pdf to be attached")
+	}
+
+	_, err = buildMessage(SendRequest{
+		From:        "noreply@example.com",
+		To:          []string{"user@example.com"},
+		Text:        "Hi",
+		Attachments: []Attachment{{Filename: "a.png", ContentID: "a>\r\nBcc: x@example.com"}},
+	}, "id@example.com", time.Now())
+	if err == nil {
+		t.Error("expected an invalid content ID to be refused")
+	}
+
+	for _, contentType := range []string{"text/plain\r\nX-Injected: 1", "text/plain; name=\"a\r\nX-Injected: 1\"", "not a type"} {
+		_, err = buildMessage(SendRequest{
+			From:        "noreply@example.com",
+			To:          []string{"user@example.com"},
+			Text:        "Hi",
+			Attachments: []Attachment{{Filename: "a.txt", ContentType: contentType, Content: []byte("x")}},
+		}, "id@example.com", time.Now())
+		if err == nil {
+			t.Errorf("expected content type %q to be refused", contentType)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package email
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// templateNamePattern restricts template names to URL-safe identifiers
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,99}$`)
+
+// TemplateStore stores the named email templates
+type TemplateStore interface {
+	// Get returns a template, or nil if there is none with the name.
+	Get(name string) (*store.EmailTemplate, error)
+	// List returns every template ordered by name.
+	List() ([]store.EmailTemplate, error)
+	// Save creates or replaces a template and returns it with its timestamps.
+	Save(tmpl store.EmailTemplate) (*store.EmailTemplate, error)
+	// Delete removes a template and reports whether it existed.
+	Delete(name string) (bool, error)
+}
+
+// Rendered is an email template rendered with data
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// ValidateTemplate checks the name of a template and that its parts parse
+func ValidateTemplate(tmpl store.EmailTemplate) error {
+	if !templateNamePattern.MatchString(tmpl.Name) {
+		return errors.New("name must be 1 to 100 letters, digits, '.', '_' or '-'")
+	}
+	if tmpl.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if tmpl.Text == "" && tmpl.HTML == "" {
+		return errors.New("either text or html is required")
+	}
+	_, err := RenderTemplate(tmpl, nil)
+	var execErr texttemplate.ExecError
+	if err != nil && !errors.As(err, &execErr) {
+		return err
+	}
+	return nil
+}
+
+// RenderTemplate renders the subject, text and HTML of a template. Values
+// are escaped in the HTML only.
+func RenderTemplate(tmpl store.EmailTemplate, data any) (Rendered, error) {
+	var rendered Rendered
+	for _, part := range []struct {
+		name   string
+		source string
+		output *string
+		html   bool
+	}{
+		{"subject", tmpl.Subject, &rendered.Subject, false},
+		{"text", tmpl.Text, &rendered.Text, false},
+		{"html", tmpl.HTML, &rendered.HTML, true},
+	} {
+		if part.source == "" {
+			continue
+		}
+		var buf strings.Builder
+		var err error
+		if part.html {
+			var t *htmltemplate.Template
+			if t, err = htmltemplate.New(part.name).Option("missingkey=zero").Parse(part.source); err == nil {
+				err = t.Execute(&buf, data)
+			}
+		} else {
+			var t *texttemplate.Template
+			if t, err = texttemplate.New(part.name).Option("missingkey=zero").Parse(part.source); err == nil {
+				err = t.Execute(&buf, data)
+			}
+		}
+		if err != nil {
+			return Rendered{}, fmt.Errorf("email template %q: %w", tmpl.Name, err)
+		}
+		*part.output = buf.String()
+	}
+	// A subject is a single line
+	rendered.Subject = strings.Join(strings.Fields(rendered.Subject), " ")
+	return rendered, nil
+}
+
+// MemoryTemplateStore is an in-memory implementation of TemplateStore
+type MemoryTemplateStore struct {
+	mu        sync.RWMutex
+	templates map[string]store.EmailTemplate
+}
+
+// NewMemoryTemplateStore creates a new in-memory template store
+func NewMemoryTemplateStore() *MemoryTemplateStore {
+	return &MemoryTemplateStore{templates: make(map[string]store.EmailTemplate)}
+}
+
+// Get returns a template, or nil if there is none with the name
+func (m *MemoryTemplateStore) Get(name string) (*store.EmailTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tmpl, ok := m.templates[name]
+	if !ok {
+		return nil, nil
+	}
+	return &tmpl, nil
+}
+
+// List returns every template ordered by name
+func (m *MemoryTemplateStore) List() ([]store.EmailTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	templates := make([]store.EmailTemplate, 0, len(m.templates))
+	for _, tmpl := range m.templates {
+		templates = append(templates, tmpl)
+	}
+	slices.SortFunc(templates, func(a, b store.EmailTemplate) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return templates, nil
+}
+
+// Save creates or replaces a template and returns it with its timestamps
+func (m *MemoryTemplateStore) Save(tmpl store.EmailTemplate) (*store.EmailTemplate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tmpl.UpdatedAt = time.Now().Unix()
+	tmpl.CreatedAt = tmpl.UpdatedAt
+	if existing, ok := m.templates[tmpl.Name]; ok {
+		tmpl.CreatedAt = existing.CreatedAt
+	}
+	m.templates[tmpl.Name] = tmpl
+	return &tmpl, nil
+}
+
+// Delete removes a template and reports whether it existed
+func (m *MemoryTemplateStore) Delete(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.templates[name]
+	delete(m.templates, name)
+	return ok, nil
+}
+
+// SQLiteTemplateStore is a SQLite-backed implementation of TemplateStore
+type SQLiteTemplateStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteTemplateStore creates a new SQLite-backed template store
+func NewSQLiteTemplateStore(db *sql.DB) *SQLiteTemplateStore {
+	return &SQLiteTemplateStore{db: db}
+}
+
+// Get returns a template, or nil if there is none with the name
+func (s *SQLiteTemplateStore) Get(name string) (*store.EmailTemplate, error) {
+	var tmpl store.EmailTemplate
+	err := s.db.QueryRow(
+		"SELECT name, subject, text, html, created_at, updated_at FROM email_templates WHERE name = ?",
+		name,
+	).Scan(&tmpl.Name, &tmpl.Subject, &tmpl.Text, &tmpl.HTML, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// List returns every template ordered by name
+func (s *SQLiteTemplateStore) List() ([]store.EmailTemplate, error) {
+	rows, err := s.db.Query("SELECT name, subject, text, html, created_at, updated_at FROM email_templates ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	templates := make([]store.EmailTemplate, 0)
+	for rows.Next() {
+		var tmpl store.EmailTemplate
+		if err := rows.Scan(&tmpl.Name, &tmpl.Subject, &tmpl.Text, &tmpl.HTML, &tmpl.CreatedAt, &tmpl.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, rows.Err()
+}
+
+// Save creates or replaces a template and returns it with its timestamps
+func (s *SQLiteTemplateStore) Save(tmpl store.EmailTemplate) (*store.EmailTemplate, error) {
+	now := time.Now().Unix()
+	err := s.db.QueryRow(
+		`INSERT INTO email_templates (name, subject, text, html, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET
+		   subject = excluded.subject, text = excluded.text, html = excluded.html, updated_at = excluded.updated_at
+		 RETURNING created_at, updated_at`,
+		tmpl.Name, tmpl.Subject, tmpl.Text, tmpl.HTML, now, now,
+	).Scan(&tmpl.CreatedAt, &tmpl.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// Delete removes a template and reports whether it existed
+func (s *SQLiteTemplateStore) Delete(name string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM email_templates WHERE name = ?", name)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
@@ -0,0 +1,141 @@
+package email
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/migrate"
+	"github.com/dimiro1/lunar/internal/store"
+	_ "modernc.org/sqlite"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	// Create a temporary database file
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	_ = tmpfile.Close()
+
+	db, err := sql.Open("sqlite", tmpfile.Name())
+	if err != nil {
+		_ = os.Remove(tmpfile.Name())
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// Run migrations
+	migrate.RunTest(t, db)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		_ = os.Remove(tmpfile.Name())
+	})
+
+	return db
+}
+
+// templateStores returns every implementation, so each test runs against both
+func templateStores(t *testing.T) map[string]TemplateStore {
+	return map[string]TemplateStore{
+		"memory": NewMemoryTemplateStore(),
+		"sqlite": NewSQLiteTemplateStore(setupTestDB(t)),
+	}
+}
+
+func TestTemplateStore(t *testing.T) {
+	for name, templates := range templateStores(t) {
+		t.Run(name, func(t *testing.T) {
+			first, err := templates.Save(store.EmailTemplate{Name: "welcome", Subject: "Hi", Text: "Hello"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := templates.Save(store.EmailTemplate{Name: "receipt", Subject: "Receipt", HTML: "<p>Thanks</p>"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			updated, err := templates.Save(store.EmailTemplate{Name: "welcome", Subject: "Welcome", Text: "Hello again"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated.CreatedAt != first.CreatedAt || updated.UpdatedAt < first.UpdatedAt {
+				t.Errorf("expected the creation time to be kept, got %+v after %+v", updated, first)
+			}
+
+			tmpl, err := templates.Get("welcome")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tmpl == nil || tmpl.Subject != "Welcome" || tmpl.Text != "Hello again" {
+				t.Errorf("unexpected template: %+v", tmpl)
+			}
+			if tmpl, _ := templates.Get("missing"); tmpl != nil {
+				t.Errorf("expected no template, got %+v", tmpl)
+			}
+
+			list, err := templates.List()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(list) != 2 || list[0].Name != "receipt" || list[1].Name != "welcome" {
+				t.Errorf("expected templates ordered by name, got %+v", list)
+			}
+
+			if deleted, _ := templates.Delete("welcome"); !deleted {
+				t.Error("expected welcome to be deleted")
+			}
+			if deleted, _ := templates.Delete("welcome"); deleted {
+				t.Error("expected a second delete to find nothing")
+			}
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := store.EmailTemplate{
+		Name:    "order",
+		Subject: "Order #{{.id}}\nshipped",
+		Text:    "Hi {{.name}}, {{len .items}} items are on their way.",
+		HTML:    "<p>Hi {{.name}}</p>",
+	}
+
+	rendered, err := RenderTemplate(tmpl, map[string]any{"id": 42, "name": "<Ana>", "items": []any{"a", "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := Rendered{
+		Subject: "Order #42 shipped",
+		Text:    "Hi <Ana>, 2 items are on their way.",
+		HTML:    "<p>Hi &lt;Ana&gt;</p>",
+	}
+	if rendered != expected {
+		t.Errorf("expected %+v, got %+v", expected, rendered)
+	}
+
+	tmpl.Text = "{{index .items 5}}"
+	if _, err := RenderTemplate(tmpl, map[string]any{"items": []any{}}); err == nil {
+		t.Error("expected an execution error")
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl     store.EmailTemplate
+		expected string
+	}{
+		{store.EmailTemplate{Name: "welcome", Subject: "Hi {{.name}}", Text: "{{index .items 0}}"}, ""},
+		{store.EmailTemplate{Name: "a b", Subject: "Hi", Text: "Hi"}, "name must be 1 to 100 letters, digits, '.', '_' or '-'"},
+		{store.EmailTemplate{Name: "welcome", Text: "Hi"}, "subject is required"},
+		{store.EmailTemplate{Name: "welcome", Subject: "Hi"}, "either text or html is required"},
+		{store.EmailTemplate{Name: "welcome", Subject: "Hi", HTML: "{{.name"}, `email template "welcome": template: html:1: unclosed action`},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if err := ValidateTemplate(tt.tmpl); err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("%+v: expected error %q, got %q", tt.tmpl, tt.expected, got)
+		}
+	}
+}
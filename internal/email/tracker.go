@@ -1,9 +1,11 @@
 package email
 
 import (
+	"cmp"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -34,18 +36,25 @@ type Tracker interface {
 	Track(executionID string, req TrackRequest)
 	Requests(executionID string) []store.EmailRequest
 	RequestsPaginated(executionID string, limit, offset int) ([]store.EmailRequest, int64)
+	// RecordDelivery adds a delivery event to the timeline of the email sent
+	// with emailID and returns the request that sent it, nil when no tracked
+	// request did. It reports whether the event is new, as providers retry
+	// webhooks.
+	RecordDelivery(emailID string, event store.EmailDeliveryEvent) (*store.EmailRequest, bool, error)
 }
 
 // MemoryTracker is an in-memory implementation of Tracker
 type MemoryTracker struct {
-	mu       sync.RWMutex
-	requests []store.EmailRequest
+	mu         sync.RWMutex
+	requests   []store.EmailRequest
+	deliveries map[string][]store.EmailDeliveryEvent // Delivery events by email ID
 }
 
 // NewMemoryTracker creates a new in-memory tracker
 func NewMemoryTracker() *MemoryTracker {
 	return &MemoryTracker{
-		requests: make([]store.EmailRequest, 0),
+		requests:   make([]store.EmailRequest, 0),
+		deliveries: make(map[string][]store.EmailDeliveryEvent),
 	}
 }
 
@@ -90,7 +99,7 @@ func (m *MemoryTracker) Requests(executionID string) []store.EmailRequest {
 	requests := make([]store.EmailRequest, 0)
 	for _, req := range m.requests {
 		if req.ExecutionID == executionID {
-			requests = append(requests, req)
+			requests = append(requests, m.withDelivery(req))
 		}
 	}
 	return requests
@@ -105,7 +114,7 @@ func (m *MemoryTracker) RequestsPaginated(executionID string, limit, offset int)
 	filtered := make([]store.EmailRequest, 0)
 	for _, req := range m.requests {
 		if req.ExecutionID == executionID {
-			filtered = append(filtered, req)
+			filtered = append(filtered, m.withDelivery(req))
 		}
 	}
 
@@ -121,12 +130,50 @@ func (m *MemoryTracker) RequestsPaginated(executionID string, limit, offset int)
 	return filtered[offset:end], total
 }
 
+// RecordDelivery adds a delivery event to the timeline of an email
+func (m *MemoryTracker) RecordDelivery(emailID string, event store.EmailDeliveryEvent) (*store.EmailRequest, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.IndexFunc(m.requests, func(req store.EmailRequest) bool {
+		return req.EmailID != nil && *req.EmailID == emailID
+	})
+	if index < 0 {
+		return nil, false, nil
+	}
+	req := m.requests[index]
+
+	for _, recorded := range m.deliveries[emailID] {
+		if recorded.ID == event.ID {
+			return &req, false, nil
+		}
+	}
+	m.deliveries[emailID] = append(m.deliveries[emailID], event)
+	return &req, true, nil
+}
+
+// withDelivery returns a copy of req with its delivery timeline, ordered by
+// occurrence. The status is the type of the latest event.
+func (m *MemoryTracker) withDelivery(req store.EmailRequest) store.EmailRequest {
+	if req.EmailID == nil || len(m.deliveries[*req.EmailID]) == 0 {
+		return req
+	}
+	deliveryEvents := slices.Clone(m.deliveries[*req.EmailID])
+	slices.SortStableFunc(deliveryEvents, func(a, b store.EmailDeliveryEvent) int {
+		return cmp.Compare(a.OccurredAt, b.OccurredAt)
+	})
+	req.DeliveryEvents = deliveryEvents
+	req.DeliveryStatus = &deliveryEvents[len(deliveryEvents)-1].Type
+	return req
+}
+
 // Clear removes all tracked requests
 func (m *MemoryTracker) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.requests = make([]store.EmailRequest, 0)
+	m.deliveries = make(map[string][]store.EmailDeliveryEvent)
 }
 
 // SQLiteTracker is a SQLite-backed implementation of Tracker
@@ -180,7 +227,8 @@ func (s *SQLiteTracker) Track(executionID string, req TrackRequest) {
 func (s *SQLiteTracker) Requests(executionID string) []store.EmailRequest {
 	rows, err := s.db.Query(
 		`SELECT id, execution_id, from_address, to_addresses, subject, has_text, has_html,
-		        request_json, response_json, status, error_message, email_id, duration_ms, created_at
+		        request_json, response_json, status, error_message, email_id, duration_ms, created_at,
+		        delivery_status
 		 FROM email_requests WHERE execution_id = ? ORDER BY created_at`,
 		executionID,
 	)
@@ -189,7 +237,7 @@ func (s *SQLiteTracker) Requests(executionID string) []store.EmailRequest {
 	}
 	defer func() { _ = rows.Close() }()
 
-	return s.scanRequests(rows)
+	return s.withDelivery(s.scanRequests(rows))
 }
 
 // RequestsPaginated returns paginated email requests for the specified executionID
@@ -204,7 +252,8 @@ func (s *SQLiteTracker) RequestsPaginated(executionID string, limit, offset int)
 	// Get paginated requests
 	rows, err := s.db.Query(
 		`SELECT id, execution_id, from_address, to_addresses, subject, has_text, has_html,
-		        request_json, response_json, status, error_message, email_id, duration_ms, created_at
+		        request_json, response_json, status, error_message, email_id, duration_ms, created_at,
+		        delivery_status
 		 FROM email_requests WHERE execution_id = ? ORDER BY created_at LIMIT ? OFFSET ?`,
 		executionID, limit, offset,
 	)
@@ -213,7 +262,7 @@ func (s *SQLiteTracker) RequestsPaginated(executionID string, limit, offset int)
 	}
 	defer func() { _ = rows.Close() }()
 
-	return s.scanRequests(rows), total
+	return s.withDelivery(s.scanRequests(rows)), total
 }
 
 // scanRequests is a helper to scan rows into EmailRequest slice
@@ -222,13 +271,13 @@ func (s *SQLiteTracker) scanRequests(rows *sql.Rows) []store.EmailRequest {
 	for rows.Next() {
 		var req store.EmailRequest
 		var toAddresses string
-		var responseJSON, errorMessage, emailID sql.NullString
+		var responseJSON, errorMessage, emailID, deliveryStatus sql.NullString
 		var hasText, hasHTML int
 
 		if err := rows.Scan(
 			&req.ID, &req.ExecutionID, &req.From, &toAddresses, &req.Subject,
 			&hasText, &hasHTML, &req.RequestJSON, &responseJSON, &req.Status,
-			&errorMessage, &emailID, &req.DurationMs, &req.CreatedAt,
+			&errorMessage, &emailID, &req.DurationMs, &req.CreatedAt, &deliveryStatus,
 		); err != nil {
 			continue
 		}
@@ -246,14 +295,107 @@ func (s *SQLiteTracker) scanRequests(rows *sql.Rows) []store.EmailRequest {
 		if emailID.Valid {
 			req.EmailID = &emailID.String
 		}
+		if deliveryStatus.Valid {
+			req.DeliveryStatus = &deliveryStatus.String
+		}
 
 		requests = append(requests, req)
 	}
 	return requests
 }
 
+// RecordDelivery adds a delivery event to the timeline of an email and
+// updates its delivery status
+func (s *SQLiteTracker) RecordDelivery(emailID string, event store.EmailDeliveryEvent) (*store.EmailRequest, bool, error) {
+	rows, err := s.db.Query(
+		`SELECT id, execution_id, from_address, to_addresses, subject, has_text, has_html,
+		        request_json, response_json, status, error_message, email_id, duration_ms, created_at,
+		        delivery_status
+		 FROM email_requests WHERE email_id = ? ORDER BY created_at LIMIT 1`,
+		emailID,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	requests := s.scanRequests(rows)
+	_ = rows.Close()
+	if len(requests) == 0 {
+		return nil, false, nil
+	}
+	req := requests[0]
+
+	// Events belong to the email request, so they are deleted with it
+	result, err := s.db.Exec(
+		`INSERT INTO email_delivery_events (id, email_request_id, type, detail, occurred_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+		event.ID, req.ID, event.Type, event.Detail, event.OccurredAt, time.Now().Unix(),
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+		return &req, false, err
+	}
+
+	// Events may arrive out of order, the status is the latest one
+	_, err = s.db.Exec(
+		`UPDATE email_requests SET delivery_status = (
+		     SELECT type FROM email_delivery_events WHERE email_request_id = ?
+		     ORDER BY occurred_at DESC, rowid DESC LIMIT 1
+		 ) WHERE id = ?`,
+		req.ID, req.ID,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	return &req, true, nil
+}
+
+// withDelivery loads the delivery timelines of the requests
+func (s *SQLiteTracker) withDelivery(requests []store.EmailRequest) []store.EmailRequest {
+	var requestIDs []any
+	for _, req := range requests {
+		if req.DeliveryStatus != nil {
+			requestIDs = append(requestIDs, req.ID)
+		}
+	}
+	if len(requestIDs) == 0 {
+		return requests
+	}
+
+	rows, err := s.db.Query(
+		`SELECT id, email_request_id, type, detail, occurred_at FROM email_delivery_events
+		 WHERE email_request_id IN (?`+strings.Repeat(", ?", len(requestIDs)-1)+`)
+		 ORDER BY occurred_at, rowid`,
+		requestIDs...,
+	)
+	if err != nil {
+		return requests
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := make(map[string][]store.EmailDeliveryEvent)
+	for rows.Next() {
+		var event store.EmailDeliveryEvent
+		var requestID string
+		var detail sql.NullString
+		if err := rows.Scan(&event.ID, &requestID, &event.Type, &detail, &event.OccurredAt); err != nil {
+			continue
+		}
+		if detail.Valid {
+			event.Detail = &detail.String
+		}
+		deliveries[requestID] = append(deliveries[requestID], event)
+	}
+
+	for i, req := range requests {
+		requests[i].DeliveryEvents = deliveries[req.ID]
+	}
+	return requests
+}
+
 // EmailParamsToJSON converts the email parameters to a JSON string for logging
-func EmailParamsToJSON(from string, to []string, subject, text, html, replyTo string, cc, bcc []string, scheduledAt string, headers map[string]string, tags []map[string]string) string {
+func EmailParamsToJSON(from string, to []string, subject, text, html, replyTo string, cc, bcc []string, scheduledAt string, headers map[string]string, tags []map[string]string, attachments []map[string]any) string {
 	params := map[string]any{
 		"from":    from,
 		"to":      to,
@@ -283,6 +425,9 @@ func EmailParamsToJSON(from string, to []string, subject, text, html, replyTo st
 	if len(tags) > 0 {
 		params["tags"] = tags
 	}
+	if len(attachments) > 0 {
+		params["attachments"] = attachments
+	}
 
 	jsonBytes, err := json.Marshal(params)
 	if err != nil {
@@ -1,6 +1,8 @@
 package email
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	"github.com/dimiro1/lunar/internal/store"
@@ -290,6 +292,107 @@ func TestMemoryTracker_ConcurrentAccess(t *testing.T) {
 	}
 }
 
+// trackers returns every implementation, so each test runs against both
+func trackers(t *testing.T) map[string]Tracker {
+	return map[string]Tracker{
+		"memory": NewMemoryTracker(),
+		"sqlite": NewSQLiteTracker(setupTestDB(t)),
+	}
+}
+
+func TestTracker_RecordDelivery(t *testing.T) {
+	for name, tracker := range trackers(t) {
+		t.Run(name, func(t *testing.T) {
+			emailID := "4ef9a417"
+			tracker.Track("exec-1", TrackRequest{
+				From:    "news@lunar.dev",
+				To:      []string{"ana@example.com"},
+				Subject: "Newsletter",
+				Status:  store.EmailRequestStatusSuccess,
+				EmailID: &emailID,
+			})
+
+			bounce := "Mailbox does not exist"
+			for _, tt := range []struct {
+				event store.EmailDeliveryEvent
+				isNew bool
+			}{
+				{store.EmailDeliveryEvent{ID: "msg_2", Type: "bounced", Detail: &bounce, OccurredAt: 200}, true},
+				{store.EmailDeliveryEvent{ID: "msg_1", Type: "sent", OccurredAt: 100}, true},
+				{store.EmailDeliveryEvent{ID: "msg_2", Type: "bounced", Detail: &bounce, OccurredAt: 200}, false},
+			} {
+				req, isNew, err := tracker.RecordDelivery(emailID, tt.event)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if isNew != tt.isNew {
+					t.Errorf("%s: expected new %v, got %v", tt.event.ID, tt.isNew, isNew)
+				}
+				if req == nil || req.ExecutionID != "exec-1" || req.Subject != "Newsletter" {
+					t.Errorf("%s: unexpected request %+v", tt.event.ID, req)
+				}
+			}
+
+			req, isNew, err := tracker.RecordDelivery("unknown", store.EmailDeliveryEvent{ID: "msg_3", Type: "delivered", OccurredAt: 300})
+			if req != nil || isNew || err != nil {
+				t.Errorf("expected an unknown email to be ignored, got %+v %v %v", req, isNew, err)
+			}
+
+			requests := tracker.Requests("exec-1")
+			if len(requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(requests))
+			}
+			tracked := requests[0]
+			if tracked.DeliveryStatus == nil || *tracked.DeliveryStatus != "bounced" {
+				t.Errorf("expected the latest event as status, got %v", tracked.DeliveryStatus)
+			}
+			expected := []store.EmailDeliveryEvent{
+				{ID: "msg_1", Type: "sent", OccurredAt: 100},
+				{ID: "msg_2", Type: "bounced", Detail: &bounce, OccurredAt: 200},
+			}
+			if !reflect.DeepEqual(tracked.DeliveryEvents, expected) {
+				t.Errorf("expected timeline %+v, got %+v", expected, tracked.DeliveryEvents)
+			}
+
+			paginated, _ := tracker.RequestsPaginated("exec-1", 10, 0)
+			if len(paginated) != 1 || len(paginated[0].DeliveryEvents) != 2 {
+				t.Errorf("expected the timeline in paginated requests, got %+v", paginated)
+			}
+		})
+	}
+}
+
+func TestSQLiteTracker_DeliveryEventsDeletedWithRequest(t *testing.T) {
+	db := setupTestDB(t)
+	tracker := NewSQLiteTracker(db)
+	emailID := "4ef9a417"
+	tracker.Track("exec-1", TrackRequest{From: "news@lunar.dev", To: []string{"ana@example.com"}, Subject: "Hi", Status: store.EmailRequestStatusSuccess, EmailID: &emailID})
+	if _, _, err := tracker.RecordDelivery(emailID, store.EmailDeliveryEvent{ID: "msg_1", Type: "delivered", OccurredAt: 100}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Foreign keys are enabled per connection
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get a connection: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatalf("failed to enable foreign keys: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "DELETE FROM email_requests"); err != nil {
+		t.Fatalf("failed to delete the email requests: %v", err)
+	}
+
+	var events int
+	if err := conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM email_delivery_events").Scan(&events); err != nil {
+		t.Fatalf("failed to count the delivery events: %v", err)
+	}
+	if events != 0 {
+		t.Errorf("expected the delivery events to be deleted with their request, got %d", events)
+	}
+}
+
 func TestEmailParamsToJSON(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -304,6 +407,7 @@ func TestEmailParamsToJSON(t *testing.T) {
 		scheduledAt string
 		headers     map[string]string
 		tags        []map[string]string
+		attachments []map[string]any
 		wantContain []string
 	}{
 		{
@@ -368,11 +472,20 @@ func TestEmailParamsToJSON(t *testing.T) {
 			tags:        []map[string]string{{"name": "campaign", "value": "test"}},
 			wantContain: []string{`"tags":`},
 		},
+		{
+			name:        "with attachments",
+			from:        "sender@example.com",
+			to:          []string{"recipient@example.com"},
+			subject:     "Test",
+			text:        "Hello",
+			attachments: []map[string]any{{"filename": "report.pdf", "content_type": "application/pdf", "size": 1024}},
+			wantContain: []string{`"attachments":[{"content_type":"application/pdf","filename":"report.pdf","size":1024}]`},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := EmailParamsToJSON(tt.from, tt.to, tt.subject, tt.text, tt.html, tt.replyTo, tt.cc, tt.bcc, tt.scheduledAt, tt.headers, tt.tags)
+			result := EmailParamsToJSON(tt.from, tt.to, tt.subject, tt.text, tt.html, tt.replyTo, tt.cc, tt.bcc, tt.scheduledAt, tt.headers, tt.tags, tt.attachments)
 
 			for _, want := range tt.wantContain {
 				if !contains(result, want) {
@@ -384,7 +497,7 @@ func TestEmailParamsToJSON(t *testing.T) {
 }
 
 func TestEmailParamsToJSON_EmptyOptionalFields(t *testing.T) {
-	result := EmailParamsToJSON("sender@example.com", []string{"recipient@example.com"}, "Subject", "", "", "", nil, nil, "", nil, nil)
+	result := EmailParamsToJSON("sender@example.com", []string{"recipient@example.com"}, "Subject", "", "", "", nil, nil, "", nil, nil, nil)
 
 	// Should only contain from, to, subject
 	if !contains(result, `"from"`) || !contains(result, `"to"`) || !contains(result, `"subject"`) {
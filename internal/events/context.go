@@ -14,6 +14,9 @@ type ExecutionContext struct {
 	// Request ID from the incoming event (for correlation)
 	RequestID string `json:"request_id,omitempty"`
 
+	// W3C trace ID of the execution trace
+	TraceID string `json:"trace_id,omitempty"`
+
 	// Function version
 	Version string `json:"version,omitempty"`
 
@@ -1,3 +1,3 @@
 // Package events contains types for events that can be handled by Lua functions.
-// Currently supports HTTP event types.
+// Currently supports HTTP, inbound email and email bounce event types.
 package events
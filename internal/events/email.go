@@ -0,0 +1,30 @@
+package events
+
+// EmailEvent represents an inbound email
+type EmailEvent struct {
+	ID          string            `json:"id,omitempty"` // ID given by the provider that received the email
+	MessageID   string            `json:"message_id"`
+	Recipient   string            `json:"recipient"` // Address the email was routed by
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc"`
+	Subject     string            `json:"subject"`
+	Text        string            `json:"text"`
+	HTML        string            `json:"html"`
+	Headers     map[string]string `json:"headers"`
+	Attachments []EmailAttachment `json:"attachments"`
+}
+
+// EmailAttachment represents a file attached to an inbound email
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"` // Set for inline files referenced as cid:<ContentID>
+	Size        int    `json:"size"`
+	Content     []byte `json:"-"`
+}
+
+// Type returns the event type for EmailEvent
+func (e EmailEvent) Type() EventType {
+	return EventTypeEmail
+}
@@ -0,0 +1,19 @@
+package events
+
+// EmailBounceEvent represents a bounce reported for an email sent by a
+// function
+type EmailBounceEvent struct {
+	EmailID     string   `json:"email_id"`
+	ExecutionID string   `json:"execution_id,omitempty"` // Execution that sent the email, when tracked
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	Subject     string   `json:"subject"`
+	BounceType  string   `json:"bounce_type"` // Permanent, Transient or Undetermined
+	SubType     string   `json:"bounce_sub_type"`
+	Message     string   `json:"message"`
+}
+
+// Type returns the event type for EmailBounceEvent
+func (e EmailBounceEvent) Type() EventType {
+	return EventTypeEmailBounce
+}
@@ -4,7 +4,9 @@ package events
 type EventType string
 
 const (
-	EventTypeHTTP EventType = "http"
+	EventTypeHTTP        EventType = "http"
+	EventTypeEmail       EventType = "email"
+	EventTypeEmailBounce EventType = "email_bounce"
 	// Future event types:
 	// EventTypeCron EventType = "cron"
 	// EventTypeCustom EventType = "custom"
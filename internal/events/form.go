@@ -0,0 +1,106 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+// MaxFormParts is the maximum number of parts read from a multipart body
+const MaxFormParts = 1000
+
+// FormFile represents a file uploaded in a multipart/form-data body. Its JSON
+// names match the keys of the event.files entries functions see.
+type FormFile struct {
+	Field       string `json:"field"`       // Form field name
+	Filename    string `json:"filename"`    // Client-provided file name
+	ContentType string `json:"contentType"` // Content type of the part
+	Content     []byte `json:"-"`           // File content
+}
+
+// ParseForm parses an application/x-www-form-urlencoded or multipart/form-data
+// body into form values and uploaded files. Other content types return an
+// empty result and no error.
+func (h HTTPEvent) ParseForm() (url.Values, []FormFile, error) {
+	mediaType, params, err := mime.ParseMediaType(h.header("Content-Type"))
+	if err != nil {
+		return url.Values{}, nil, nil
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(h.Body)
+		if err != nil {
+			return url.Values{}, nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		return values, nil, nil
+	case "multipart/form-data":
+		boundary := params["boundary"]
+		if boundary == "" {
+			return url.Values{}, nil, errors.New("multipart body has no boundary")
+		}
+		return parseMultipart(h.Body, boundary)
+	default:
+		return url.Values{}, nil, nil
+	}
+}
+
+// parseMultipart reads the parts of a multipart/form-data body. Parts with a
+// file name are returned as files, the others as form values.
+func parseMultipart(body, boundary string) (url.Values, []FormFile, error) {
+	values := url.Values{}
+	var files []FormFile
+
+	reader := multipart.NewReader(strings.NewReader(body), boundary)
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return url.Values{}, nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		if i >= MaxFormParts {
+			return url.Values{}, nil, fmt.Errorf("multipart body has more than %d parts", MaxFormParts)
+		}
+
+		var content bytes.Buffer
+		if _, err := io.Copy(&content, part); err != nil {
+			return url.Values{}, nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			values.Add(field, content.String())
+			continue
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		files = append(files, FormFile{
+			Field:       field,
+			Filename:    part.FileName(),
+			ContentType: contentType,
+			Content:     content.Bytes(),
+		})
+	}
+
+	return values, files, nil
+}
+
+// header returns the value of the named header, ignoring case
+func (h HTTPEvent) header(name string) string {
+	for key, value := range h.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
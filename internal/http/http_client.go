@@ -1,14 +1,31 @@
 package http
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
+// maxPolicyClients bounds the number of per-policy clients kept by DefaultClient
+const maxPolicyClients = 256
+
+// maxRedirects is the number of redirects followed before a request fails
+const maxRedirects = 10
+
+// DefaultTimeout is the timeout for requests that do not set one
+const DefaultTimeout = 30 * time.Second
+
+// MaxResponseBodySize is the maximum size of a response body in bytes
+const MaxResponseBodySize = 10 * 1024 * 1024
+
 // Headers represents HTTP headers as a map of string key-value pairs
 type Headers map[string]string
 
@@ -17,13 +34,20 @@ type Query map[string]string
 
 // Request represents an HTTP request
 type Request struct {
-	Method  string
-	URL     string
-	Headers Headers
-	Query   Query
-	Body    string
+	Method      string
+	URL         string
+	Headers     Headers
+	Query       Query
+	Body        string
+	Policy      *Policy         // Network policy enforced for the request (optional)
+	Context     context.Context // Cancels the request when done (optional)
+	Timeout     time.Duration   // Overrides DefaultTimeout when set
+	NoRedirects bool            // Return redirect responses instead of following them
 }
 
+// noRedirectsKey marks requests that must not follow redirects
+type noRedirectsKey struct{}
+
 // Error represents an HTTP error with additional context
 type Error struct {
 	StatusCode int
@@ -42,6 +66,17 @@ type Client interface {
 	Put(req Request) (Response, error)
 	Patch(req Request) (Response, error)
 	Delete(req Request) (Response, error)
+	Head(req Request) (Response, error)
+	Do(req Request) (Response, error) // Uses req.Method, GET if empty
+}
+
+// Streamer is implemented by clients that can read a response body line by
+// line as it arrives, e.g. Server-Sent Events
+type Streamer interface {
+	// Stream performs the request using req.Method (GET if empty) and calls
+	// onLine for each line of a 2xx response body, stopping at the first
+	// error. Other responses are returned whole without calling onLine.
+	Stream(req Request, onLine func(line string) error) (Response, error)
 }
 
 // Response represents an HTTP response
@@ -64,23 +99,81 @@ func (r *Response) IsError() bool {
 // DefaultClient is the default implementation of Client
 type DefaultClient struct {
 	client *http.Client
+
+	mu            sync.Mutex
+	policyClients map[string]*http.Client // Clients enforcing a network policy, keyed by policy
 }
 
 // NewDefaultClient creates a new default HTTP client with timeout and connection pooling
 func NewDefaultClient() *DefaultClient {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
 	return &DefaultClient{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				MaxConnsPerHost:     100,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Transport:     newTransport(dialer.DialContext),
+			CheckRedirect: checkRedirect(nil),
 		},
+		policyClients: make(map[string]*http.Client),
+	}
+}
+
+// newTransport creates a pooled transport that dials with dial
+func newTransport(dial func(ctx context.Context, network, address string) (net.Conn, error)) *http.Transport {
+	return &http.Transport{
+		DialContext:         dial,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		MaxConnsPerHost:     100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// checkRedirect returns a redirect check that limits the number of redirects,
+// honors Request.NoRedirects and applies policy to every hop when set
+func checkRedirect(policy *Policy) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if noRedirects, _ := req.Context().Value(noRedirectsKey{}).(bool); noRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if policy != nil {
+			return policy.CheckURL(req.URL)
+		}
+		return nil
 	}
 }
 
+// clientFor returns the client for requests under policy. Each policy gets
+// its own connection pool so a connection dialed under a permissive policy is
+// never reused by a request under a stricter one.
+func (c *DefaultClient) clientFor(policy *Policy) *http.Client {
+	if policy == nil {
+		return c.client
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.policyClients[policy.key]; ok {
+		return client
+	}
+	if len(c.policyClients) >= maxPolicyClients {
+		for _, client := range c.policyClients {
+			client.CloseIdleConnections()
+		}
+		clear(c.policyClients)
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	client := &http.Client{
+		Transport:     newTransport(policy.dialContext(dialer)),
+		CheckRedirect: checkRedirect(policy),
+	}
+	c.policyClients[policy.key] = client
+	return client
+}
+
 // Get performs an HTTP GET request
 func (c *DefaultClient) Get(req Request) (Response, error) {
 	return c.doHTTPRequest("GET", req)
@@ -106,12 +199,84 @@ func (c *DefaultClient) Delete(req Request) (Response, error) {
 	return c.doHTTPRequest("DELETE", req)
 }
 
+// Head performs an HTTP HEAD request
+func (c *DefaultClient) Head(req Request) (Response, error) {
+	return c.doHTTPRequest("HEAD", req)
+}
+
+// Do performs an HTTP request using req.Method
+func (c *DefaultClient) Do(req Request) (Response, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	return c.doHTTPRequest(method, req)
+}
+
+// Stream performs an HTTP request and reads a successful response body line
+// by line. The returned body holds the lines read, up to MaxResponseBodySize.
+func (c *DefaultClient) Stream(httpReq Request, onLine func(line string) error) (Response, error) {
+	method := strings.ToUpper(httpReq.Method)
+	if method == "" {
+		method = "GET"
+	}
+	req, cancel, err := newRequest(method, httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer cancel()
+
+	resp, err := send(c.clientFor(httpReq.Policy), req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return readResponse(resp)
+	}
+
+	result := Response{StatusCode: resp.StatusCode, Headers: convertHeaders(resp.Header)}
+	var body strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxResponseBodySize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if body.Len()+len(line) < MaxResponseBodySize {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+		if err := onLine(line); err != nil {
+			result.Body = body.String()
+			return result, err
+		}
+	}
+	result.Body = body.String()
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return result, nil
+}
+
 // doHTTPRequest builds and executes an HTTP request
 func (c *DefaultClient) doHTTPRequest(method string, httpReq Request) (Response, error) {
+	req, cancel, err := newRequest(method, httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer cancel()
+
+	return c.doRequest(c.clientFor(httpReq.Policy), req)
+}
+
+// newRequest builds an HTTP request with its query parameters, headers and
+// timeout, checking the URL against the request's network policy. The
+// returned cancel function releases the timeout.
+func newRequest(method string, httpReq Request) (*http.Request, context.CancelFunc, error) {
 	// Parse and build URL with query parameters
 	parsedURL, err := url.Parse(httpReq.URL)
 	if err != nil {
-		return Response{}, fmt.Errorf("failed to parse URL: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	// Add query parameters
@@ -123,14 +288,34 @@ func (c *DefaultClient) doHTTPRequest(method string, httpReq Request) (Response,
 		parsedURL.RawQuery = q.Encode()
 	}
 
+	if httpReq.Policy != nil {
+		if err := httpReq.Policy.CheckURL(parsedURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var bodyReader io.Reader
 	if httpReq.Body != "" {
 		bodyReader = strings.NewReader(httpReq.Body)
 	}
 
-	req, err := http.NewRequest(method, parsedURL.String(), bodyReader)
+	ctx := httpReq.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout := httpReq.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	if httpReq.NoRedirects {
+		ctx = context.WithValue(ctx, noRedirectsKey{}, true)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, parsedURL.String(), bodyReader)
 	if err != nil {
-		return Response{}, fmt.Errorf("failed to create request: %w", err)
+		cancel()
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add headers (nil-safe)
@@ -138,38 +323,62 @@ func (c *DefaultClient) doHTTPRequest(method string, httpReq Request) (Response,
 		req.Header.Set(key, value)
 	}
 
-	return c.doRequest(req)
+	return req, cancel, nil
 }
 
 // doRequest executes the HTTP request and converts the response
-func (c *DefaultClient) doRequest(req *http.Request) (Response, error) {
-	resp, err := c.client.Do(req)
+func (c *DefaultClient) doRequest(client *http.Client, req *http.Request) (Response, error) {
+	resp, err := send(client, req)
 	if err != nil {
-		return Response{}, fmt.Errorf("HTTP request failed: %w", err)
+		return Response{}, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	return readResponse(resp)
+}
+
+// send executes the HTTP request, reporting network policy violations as is
+func send(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
 	if err != nil {
-		return Response{}, fmt.Errorf("failed to read response body: %w", err)
+		var policyErr *PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, policyErr
+		}
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
+	return resp, nil
+}
 
-	// Convert headers
-	headers := make(Headers)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
+// readResponse reads the whole body of a response and converts it
+func readResponse(resp *http.Response) (Response, error) {
+	// Read response body, reading one extra byte to detect oversized bodies
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBodySize+1))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(bodyBytes) > MaxResponseBodySize {
+		return Response{}, fmt.Errorf("response body exceeds %d bytes", MaxResponseBodySize)
 	}
 
 	return Response{
 		StatusCode: resp.StatusCode,
-		Headers:    headers,
+		Headers:    convertHeaders(resp.Header),
 		Body:       string(bodyBytes),
 	}, nil
 }
 
+// convertHeaders keeps the first value of each response header
+func convertHeaders(header http.Header) Headers {
+	headers := make(Headers)
+	for key, values := range header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+	return headers
+}
+
 // FakeClient is a stub implementation of Client for testing
 type FakeClient struct {
 	// Responses maps method+URL to a Response
@@ -226,6 +435,39 @@ func (f *FakeClient) Delete(req Request) (Response, error) {
 	return f.do("DELETE", req)
 }
 
+// Head performs a fake HTTP HEAD request
+func (f *FakeClient) Head(req Request) (Response, error) {
+	return f.do("HEAD", req)
+}
+
+// Do performs a fake HTTP request using req.Method
+func (f *FakeClient) Do(req Request) (Response, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	return f.do(method, req)
+}
+
+// Stream performs a fake HTTP request, calling onLine for each line of the
+// configured body when its status code is 2xx
+func (f *FakeClient) Stream(req Request, onLine func(line string) error) (Response, error) {
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "GET"
+	}
+	resp, err := f.do(method, req)
+	if err != nil || !resp.IsSuccess() {
+		return resp, err
+	}
+	for line := range strings.Lines(resp.Body) {
+		if err := onLine(strings.TrimRight(line, "\r\n")); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
 // do is the internal method that handles fake request processing
 func (f *FakeClient) do(method string, req Request) (Response, error) {
 	// Store the request for verification
@@ -3,7 +3,9 @@ package http
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultClient_Get(t *testing.T) {
@@ -278,3 +280,157 @@ func TestDefaultClient_NetworkError(t *testing.T) {
 		t.Error("Expected network error, got nil")
 	}
 }
+
+func TestDefaultClient_Head(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" {
+			t.Errorf("Expected HEAD request, got %s", r.Method)
+		}
+		w.Header().Set("X-Total", "42")
+	}))
+	defer server.Close()
+
+	client := NewDefaultClient()
+	resp, err := client.Head(Request{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.Headers["X-Total"] != "42" {
+		t.Errorf("Expected X-Total header '42', got '%s'", resp.Headers["X-Total"])
+	}
+}
+
+func TestDefaultClient_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	}))
+	defer server.Close()
+
+	client := NewDefaultClient()
+	for method, expected := range map[string]string{"options": "OPTIONS", "": "GET"} {
+		resp, err := client.Do(Request{Method: method, URL: server.URL})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.Body != expected {
+			t.Errorf("Expected %s request, got %s", expected, resp.Body)
+		}
+	}
+}
+
+func TestDefaultClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewDefaultClient()
+	start := time.Now()
+	_, err := client.Get(Request{URL: server.URL, Timeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("Expected timeout error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected request to time out quickly, took %v", elapsed)
+	}
+}
+
+func TestDefaultClient_NoRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("new"))
+	}))
+	defer server.Close()
+
+	client := NewDefaultClient()
+
+	resp, err := client.Get(Request{URL: server.URL + "/old", NoRedirects: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+	if resp.Headers["Location"] != "/new" {
+		t.Errorf("Expected Location '/new', got '%s'", resp.Headers["Location"])
+	}
+
+	resp, err = client.Get(Request{URL: server.URL + "/old"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.Body != "new" {
+		t.Errorf("Expected redirect to be followed, got body '%s'", resp.Body)
+	}
+}
+
+func TestDefaultClient_ResponseBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("x", MaxResponseBodySize+1)))
+	}))
+	defer server.Close()
+
+	client := NewDefaultClient()
+	_, err := client.Get(Request{URL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "response body exceeds") {
+		t.Errorf("Expected body size error, got %v", err)
+	}
+}
+
+func TestDefaultClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, line := range []string{"data: one", "", "data: two", ""} {
+			_, _ = w.Write([]byte(line + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	var lines []string
+	resp, err := NewDefaultClient().Stream(Request{Method: "post", URL: server.URL}, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(lines, "|") != "data: one||data: two|" {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+	if resp.Body != "data: one\n\ndata: two\n\n" {
+		t.Errorf("Unexpected body: %q", resp.Body)
+	}
+	if resp.Headers["Content-Type"] != "text/event-stream" {
+		t.Errorf("Expected event stream content type, got %q", resp.Headers["Content-Type"])
+	}
+}
+
+func TestDefaultClient_StreamErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": "invalid key"}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewDefaultClient().Stream(Request{URL: server.URL}, func(line string) error {
+		t.Errorf("Expected no lines, got %q", line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || resp.Body != `{"error": "invalid key"}` {
+		t.Errorf("Unexpected response: %d %s", resp.StatusCode, resp.Body)
+	}
+}
@@ -0,0 +1,312 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// reservedPrefixes are the non-public ranges not covered by the netip.Addr
+// helpers used in isReserved
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may embed private IPv4 addresses
+	netip.MustParsePrefix("2001::/32"),       // Teredo, embeds IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, embeds IPv4 addresses
+}
+
+// PolicyError is returned when a request is blocked by a network policy
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "request blocked by network policy: " + e.Reason
+}
+
+// hostRule matches a hostname, a *.wildcard domain, an IP address or a CIDR
+type hostRule struct {
+	host   string       // Lowercase hostname, or domain suffix for wildcards
+	suffix bool         // Whether host was a *.wildcard
+	prefix netip.Prefix // Set for IP and CIDR rules
+}
+
+// matchHost reports whether the rule matches the hostname of a request
+func (r hostRule) matchHost(host string) bool {
+	if r.host == "" {
+		return false
+	}
+	if r.suffix {
+		return strings.HasSuffix(host, "."+r.host)
+	}
+	return host == r.host
+}
+
+// matchAddr reports whether the rule matches a resolved address
+func (r hostRule) matchAddr(addr netip.Addr) bool {
+	return r.prefix.IsValid() && r.prefix.Contains(addr)
+}
+
+// Policy restricts the addresses outbound requests may connect to.
+// It is enforced when connections are dialed, after DNS resolution, so a
+// hostname cannot be rebound to a blocked address between checks.
+type Policy struct {
+	key          string
+	allowPrivate bool
+	allow        []hostRule
+	deny         []hostRule
+	allowPorts   []int
+	denyPorts    []int
+}
+
+// NewPolicy compiles a network policy. A nil config results in the default
+// policy, which denies private, loopback and link-local addresses.
+func NewPolicy(config *store.NetworkPolicy) (*Policy, error) {
+	if config == nil {
+		config = &store.NetworkPolicy{}
+	}
+
+	p := &Policy{
+		allowPrivate: config.AllowPrivateNetworks,
+		allowPorts:   slices.Clone(config.AllowPorts),
+		denyPorts:    slices.Clone(config.DenyPorts),
+	}
+
+	var err error
+	if p.allow, err = parseHostRules(config.AllowHosts); err != nil {
+		return nil, fmt.Errorf("allow_hosts: %w", err)
+	}
+	if p.deny, err = parseHostRules(config.DenyHosts); err != nil {
+		return nil, fmt.Errorf("deny_hosts: %w", err)
+	}
+	for _, port := range slices.Concat(p.allowPorts, p.denyPorts) {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %d", port)
+		}
+	}
+
+	// The key identifies equivalent policies so they can share connections
+	slices.Sort(p.allowPorts)
+	slices.Sort(p.denyPorts)
+	allowHosts := slices.Sorted(slices.Values(config.AllowHosts))
+	denyHosts := slices.Sorted(slices.Values(config.DenyHosts))
+	p.key = fmt.Sprintf("%t|%v|%v|%v|%v", p.allowPrivate, allowHosts, denyHosts, p.allowPorts, p.denyPorts)
+
+	return p, nil
+}
+
+// parseHostRules parses hostnames, *.wildcard domains, IP addresses and CIDRs
+func parseHostRules(entries []string) ([]hostRule, error) {
+	rules := make([]hostRule, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			return nil, fmt.Errorf("empty host")
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", entry)
+			}
+			rules = append(rules, hostRule{prefix: prefix.Masked()})
+		case strings.HasPrefix(entry, "*."):
+			domain := strings.TrimPrefix(entry, "*.")
+			if !isValidHostname(domain) {
+				return nil, fmt.Errorf("invalid host %q", entry)
+			}
+			rules = append(rules, hostRule{host: domain, suffix: true})
+		default:
+			if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+				addr = addr.Unmap()
+				rules = append(rules, hostRule{prefix: netip.PrefixFrom(addr, addr.BitLen())})
+				continue
+			}
+			if !isValidHostname(entry) {
+				return nil, fmt.Errorf("invalid host %q", entry)
+			}
+			rules = append(rules, hostRule{host: entry})
+		}
+	}
+	return rules, nil
+}
+
+// isValidHostname reports whether host looks like a DNS name
+func isValidHostname(host string) bool {
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	for label := range strings.SplitSeq(host, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CheckURL checks the parts of a request URL that are known before dialing:
+// the scheme, the port and the hostname rules
+func (p *Policy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &PolicyError{Reason: fmt.Sprintf("scheme %q is not allowed", u.Scheme)}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return &PolicyError{Reason: fmt.Sprintf("invalid port %q", port)}
+	}
+	if err := p.checkPort(portNum); err != nil {
+		return err
+	}
+
+	// Addresses are checked when dialing, only hostnames are checked here
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	for _, rule := range p.deny {
+		if rule.matchHost(host) {
+			return &PolicyError{Reason: fmt.Sprintf("host %s is denied", host)}
+		}
+	}
+	if len(p.allow) > 0 && !p.allowsHostname(host) && !p.hasAddrRules() {
+		return &PolicyError{Reason: fmt.Sprintf("host %s is not in the allow list", host)}
+	}
+	return nil
+}
+
+// checkPort checks a destination port against the port lists
+func (p *Policy) checkPort(port int) error {
+	if slices.Contains(p.denyPorts, port) {
+		return &PolicyError{Reason: fmt.Sprintf("port %d is denied", port)}
+	}
+	if len(p.allowPorts) > 0 && !slices.Contains(p.allowPorts, port) {
+		return &PolicyError{Reason: fmt.Sprintf("port %d is not in the allow list", port)}
+	}
+	return nil
+}
+
+// allowsHostname reports whether a hostname rule in the allow list matches host
+func (p *Policy) allowsHostname(host string) bool {
+	return slices.ContainsFunc(p.allow, func(r hostRule) bool { return r.matchHost(host) })
+}
+
+// hasAddrRules reports whether the allow list contains IP or CIDR rules
+func (p *Policy) hasAddrRules() bool {
+	return slices.ContainsFunc(p.allow, func(r hostRule) bool { return r.prefix.IsValid() })
+}
+
+// checkAddr checks a resolved address that host is about to be dialed on
+func (p *Policy) checkAddr(host string, addr netip.Addr, port int) error {
+	addr = addr.Unmap()
+
+	if err := p.checkPort(port); err != nil {
+		return err
+	}
+	for _, rule := range p.deny {
+		if rule.matchHost(host) || rule.matchAddr(addr) {
+			return &PolicyError{Reason: fmt.Sprintf("address %s is denied", addr)}
+		}
+	}
+
+	// Only IP and CIDR rules can allow private addresses. A hostname rule does
+	// not, since its DNS records may point anywhere.
+	allowedAddr := slices.ContainsFunc(p.allow, func(r hostRule) bool { return r.matchAddr(addr) })
+	if len(p.allow) > 0 && !allowedAddr && !p.allowsHostname(host) {
+		return &PolicyError{Reason: fmt.Sprintf("%s is not in the allow list", host)}
+	}
+	if isReserved(addr) && !p.allowPrivate && !allowedAddr {
+		return &PolicyError{Reason: fmt.Sprintf("address %s is in a private or reserved range", addr)}
+	}
+	return nil
+}
+
+// isReserved reports whether addr is not a public unicast address
+func isReserved(addr netip.Addr) bool {
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dial connects to address with dialer under the policy, for protocols
+// other than HTTP
+func (p *Policy) Dial(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	return p.dialContext(dialer)(ctx, network, address)
+}
+
+// dialContext returns a dial function that resolves the destination, checks
+// every address against the policy and connects to the first allowed one.
+// Dialing the checked address directly prevents DNS rebinding.
+func (p *Policy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, portStr, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", portStr)
+		}
+
+		var addrs []netip.Addr
+		if addr, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{addr}
+		} else {
+			addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var lastErr error
+		for _, addr := range addrs {
+			if err := p.checkAddr(strings.ToLower(host), addr, port); err != nil {
+				lastErr = err
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), portStr))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+}
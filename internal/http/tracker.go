@@ -0,0 +1,276 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dimiro1/lunar/internal/masking"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/rs/xid"
+)
+
+// maxTrackedBodySize is the maximum number of bytes of a request or response
+// body stored by trackers. Larger bodies are truncated.
+const maxTrackedBodySize = 64 * 1024
+
+// TrackRequest contains the data needed to track an outbound HTTP request
+type TrackRequest struct {
+	Method          string
+	URL             string // Full URL including query parameters
+	RequestHeaders  Headers
+	RequestBody     string
+	StatusCode      int // 0 when no response was received
+	ResponseHeaders Headers
+	ResponseBody    string
+	ErrorMessage    *string
+	DurationMs      int64
+}
+
+// Tracker is an interface for tracking outbound HTTP requests
+// executionID is used to isolate requests for each function execution
+type Tracker interface {
+	Track(executionID string, req TrackRequest)
+	Requests(executionID string) []store.HTTPRequest
+	RequestsPaginated(executionID string, limit, offset int) ([]store.HTTPRequest, int64)
+}
+
+// MemoryTracker is an in-memory implementation of Tracker
+type MemoryTracker struct {
+	mu       sync.RWMutex
+	requests []store.HTTPRequest
+}
+
+// NewMemoryTracker creates a new in-memory tracker
+func NewMemoryTracker() *MemoryTracker {
+	return &MemoryTracker{
+		requests: make([]store.HTTPRequest, 0),
+	}
+}
+
+// Track records an outbound HTTP request
+func (m *MemoryTracker) Track(executionID string, req TrackRequest) {
+	httpReq := newHTTPRequest(executionID, req)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, httpReq)
+}
+
+// Requests returns all HTTP requests for the specified executionID
+func (m *MemoryTracker) Requests(executionID string) []store.HTTPRequest {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requests := make([]store.HTTPRequest, 0)
+	for _, req := range m.requests {
+		if req.ExecutionID == executionID {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// RequestsPaginated returns paginated HTTP requests for the specified executionID
+func (m *MemoryTracker) RequestsPaginated(executionID string, limit, offset int) ([]store.HTTPRequest, int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Filter requests by executionID
+	filtered := make([]store.HTTPRequest, 0)
+	for _, req := range m.requests {
+		if req.ExecutionID == executionID {
+			filtered = append(filtered, req)
+		}
+	}
+
+	total := int64(len(filtered))
+
+	// Apply pagination
+	if offset >= len(filtered) {
+		return []store.HTTPRequest{}, total
+	}
+
+	end := min(offset+limit, len(filtered))
+
+	return filtered[offset:end], total
+}
+
+// Clear removes all tracked requests
+func (m *MemoryTracker) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = make([]store.HTTPRequest, 0)
+}
+
+// SQLiteTracker is a SQLite-backed implementation of Tracker
+type SQLiteTracker struct {
+	db *sql.DB
+}
+
+// NewSQLiteTracker creates a new SQLite-backed tracker
+func NewSQLiteTracker(db *sql.DB) *SQLiteTracker {
+	return &SQLiteTracker{db: db}
+}
+
+// Track records an outbound HTTP request
+func (s *SQLiteTracker) Track(executionID string, req TrackRequest) {
+	httpReq := newHTTPRequest(executionID, req)
+
+	requestHeaders, _ := json.Marshal(httpReq.RequestHeaders)
+	var responseHeaders *string
+	if httpReq.ResponseHeaders != nil {
+		encoded, _ := json.Marshal(httpReq.ResponseHeaders)
+		headers := string(encoded)
+		responseHeaders = &headers
+	}
+
+	_, err := s.db.Exec(
+		`INSERT INTO http_requests
+		(id, execution_id, method, url, request_headers, request_body, status_code,
+		 response_headers, response_body, response_size, status, error_message, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		httpReq.ID, httpReq.ExecutionID, httpReq.Method, httpReq.URL, string(requestHeaders),
+		httpReq.RequestBody, httpReq.StatusCode, responseHeaders, httpReq.ResponseBody,
+		httpReq.ResponseSize, httpReq.Status, httpReq.ErrorMessage, httpReq.DurationMs, httpReq.CreatedAt,
+	)
+	if err != nil {
+		// Log error but don't fail the execution
+		fmt.Printf("Failed to track HTTP request: %v\n", err)
+	}
+}
+
+// Requests returns all HTTP requests for the specified executionID
+func (s *SQLiteTracker) Requests(executionID string) []store.HTTPRequest {
+	rows, err := s.db.Query(
+		`SELECT id, execution_id, method, url, request_headers, request_body, status_code,
+		        response_headers, response_body, response_size, status, error_message, duration_ms, created_at
+		 FROM http_requests WHERE execution_id = ? ORDER BY created_at, rowid`,
+		executionID,
+	)
+	if err != nil {
+		return []store.HTTPRequest{}
+	}
+	defer func() { _ = rows.Close() }()
+
+	return s.scanRequests(rows)
+}
+
+// RequestsPaginated returns paginated HTTP requests for the specified executionID
+func (s *SQLiteTracker) RequestsPaginated(executionID string, limit, offset int) ([]store.HTTPRequest, int64) {
+	// Get total count
+	var total int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM http_requests WHERE execution_id = ?", executionID).Scan(&total)
+	if err != nil {
+		return []store.HTTPRequest{}, 0
+	}
+
+	// Get paginated requests
+	rows, err := s.db.Query(
+		`SELECT id, execution_id, method, url, request_headers, request_body, status_code,
+		        response_headers, response_body, response_size, status, error_message, duration_ms, created_at
+		 FROM http_requests WHERE execution_id = ? ORDER BY created_at, rowid LIMIT ? OFFSET ?`,
+		executionID, limit, offset,
+	)
+	if err != nil {
+		return []store.HTTPRequest{}, total
+	}
+	defer func() { _ = rows.Close() }()
+
+	return s.scanRequests(rows), total
+}
+
+// scanRequests is a helper to scan rows into HTTPRequest slice
+func (s *SQLiteTracker) scanRequests(rows *sql.Rows) []store.HTTPRequest {
+	requests := make([]store.HTTPRequest, 0)
+	for rows.Next() {
+		var req store.HTTPRequest
+		var requestHeaders string
+		var requestBody, responseHeaders, responseBody, errorMessage sql.NullString
+		var statusCode sql.NullInt64
+
+		if err := rows.Scan(
+			&req.ID, &req.ExecutionID, &req.Method, &req.URL, &requestHeaders, &requestBody,
+			&statusCode, &responseHeaders, &responseBody, &req.ResponseSize, &req.Status,
+			&errorMessage, &req.DurationMs, &req.CreatedAt,
+		); err != nil {
+			continue
+		}
+
+		_ = json.Unmarshal([]byte(requestHeaders), &req.RequestHeaders)
+
+		if requestBody.Valid {
+			req.RequestBody = &requestBody.String
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			req.StatusCode = &code
+		}
+		if responseHeaders.Valid {
+			_ = json.Unmarshal([]byte(responseHeaders.String), &req.ResponseHeaders)
+		}
+		if responseBody.Valid {
+			req.ResponseBody = &responseBody.String
+		}
+		if errorMessage.Valid {
+			req.ErrorMessage = &errorMessage.String
+		}
+
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// newHTTPRequest builds the stored record for a tracked request, masking
+// sensitive headers, query parameters and body fields
+func newHTTPRequest(executionID string, req TrackRequest) store.HTTPRequest {
+	httpReq := store.HTTPRequest{
+		ID:             xid.New().String(),
+		ExecutionID:    executionID,
+		Method:         req.Method,
+		URL:            masking.MaskURL(req.URL),
+		RequestHeaders: masking.MaskHeaders(req.RequestHeaders),
+		RequestBody:    maskBody(req.RequestBody),
+		ResponseSize:   int64(len(req.ResponseBody)),
+		Status:         store.HTTPRequestStatusSuccess,
+		ErrorMessage:   req.ErrorMessage,
+		DurationMs:     req.DurationMs,
+		CreatedAt:      time.Now().Unix(),
+	}
+
+	if req.StatusCode > 0 {
+		statusCode := req.StatusCode
+		httpReq.StatusCode = &statusCode
+		httpReq.ResponseHeaders = masking.MaskHeaders(req.ResponseHeaders)
+		httpReq.ResponseBody = maskBody(req.ResponseBody)
+	}
+
+	if req.ErrorMessage != nil || req.StatusCode >= 400 {
+		httpReq.Status = store.HTTPRequestStatusError
+	}
+
+	return httpReq
+}
+
+// maskBody masks sensitive data in a body and truncates it to
+// maxTrackedBodySize, returning nil for empty bodies
+func maskBody(body string) *string {
+	if body == "" {
+		return nil
+	}
+	masked := masking.MaskLogMessage(masking.MaskJSONBody(body))
+	if len(masked) > maxTrackedBodySize {
+		// Cut at a character boundary so the stored body stays valid UTF-8
+		cut := maxTrackedBodySize
+		for cut > 0 && !utf8.RuneStart(masked[cut]) {
+			cut--
+		}
+		masked = masked[:cut] + "... (truncated)"
+	}
+	return &masked
+}
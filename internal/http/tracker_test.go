@@ -0,0 +1,286 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dimiro1/lunar/internal/migrate"
+	"github.com/dimiro1/lunar/internal/store"
+	_ "modernc.org/sqlite"
+)
+
+func TestNewMemoryTracker(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	if tracker == nil {
+		t.Fatal("expected non-nil tracker")
+	}
+	if tracker.requests == nil {
+		t.Error("expected non-nil requests slice")
+	}
+}
+
+func TestMemoryTracker_Track(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	tracker.Track("exec-1", TrackRequest{
+		Method:          "POST",
+		URL:             "https://api.example.com/items?page=1",
+		RequestHeaders:  Headers{"Content-Type": "application/json"},
+		RequestBody:     `{"name":"widget"}`,
+		StatusCode:      201,
+		ResponseHeaders: Headers{"Content-Type": "application/json"},
+		ResponseBody:    `{"id":"item_1"}`,
+		DurationMs:      120,
+	})
+
+	requests := tracker.Requests("exec-1")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+
+	tracked := requests[0]
+	if tracked.Method != "POST" {
+		t.Errorf("expected method 'POST', got '%s'", tracked.Method)
+	}
+	if tracked.URL != "https://api.example.com/items?page=1" {
+		t.Errorf("expected URL to be preserved, got '%s'", tracked.URL)
+	}
+	if tracked.StatusCode == nil || *tracked.StatusCode != 201 {
+		t.Errorf("expected status code 201, got %v", tracked.StatusCode)
+	}
+	if tracked.RequestBody == nil || *tracked.RequestBody != `{"name":"widget"}` {
+		t.Errorf("expected request body to be preserved, got %v", tracked.RequestBody)
+	}
+	if tracked.ResponseSize != int64(len(`{"id":"item_1"}`)) {
+		t.Errorf("expected response size %d, got %d", len(`{"id":"item_1"}`), tracked.ResponseSize)
+	}
+	if tracked.Status != store.HTTPRequestStatusSuccess {
+		t.Errorf("expected status 'success', got '%s'", tracked.Status)
+	}
+	if tracked.DurationMs != 120 {
+		t.Errorf("expected duration 120, got %d", tracked.DurationMs)
+	}
+	if tracked.ID == "" {
+		t.Error("expected non-empty ID")
+	}
+	if tracked.CreatedAt == 0 {
+		t.Error("expected non-zero CreatedAt")
+	}
+}
+
+func TestMemoryTracker_Track_ErrorRequest(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	errMsg := "connection refused"
+	tracker.Track("exec-1", TrackRequest{
+		Method:       "GET",
+		URL:          "https://down.example.com",
+		ErrorMessage: &errMsg,
+		DurationMs:   5,
+	})
+	tracker.Track("exec-1", TrackRequest{
+		Method:       "GET",
+		URL:          "https://api.example.com/missing",
+		StatusCode:   404,
+		ResponseBody: "not found",
+	})
+
+	requests := tracker.Requests("exec-1")
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	if requests[0].Status != store.HTTPRequestStatusError {
+		t.Errorf("expected transport error to have status 'error', got '%s'", requests[0].Status)
+	}
+	if requests[0].StatusCode != nil || requests[0].ResponseBody != nil {
+		t.Error("expected no response data for transport error")
+	}
+	if requests[0].ErrorMessage == nil || *requests[0].ErrorMessage != errMsg {
+		t.Errorf("expected error message %q, got %v", errMsg, requests[0].ErrorMessage)
+	}
+	if requests[1].Status != store.HTTPRequestStatusError {
+		t.Errorf("expected 404 to have status 'error', got '%s'", requests[1].Status)
+	}
+}
+
+func TestMemoryTracker_RequestsPaginated(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	for i := range 5 {
+		tracker.Track("exec-1", TrackRequest{Method: "GET", URL: fmt.Sprintf("https://example.com/%d", i), StatusCode: 200})
+	}
+	tracker.Track("exec-2", TrackRequest{Method: "GET", URL: "https://example.com/other", StatusCode: 200})
+
+	requests, total := tracker.RequestsPaginated("exec-1", 2, 2)
+	if total != 5 {
+		t.Errorf("expected total 5, got %d", total)
+	}
+	if len(requests) != 2 || requests[0].URL != "https://example.com/2" {
+		t.Errorf("expected second page starting at /2, got %+v", requests)
+	}
+
+	requests, total = tracker.RequestsPaginated("exec-1", 10, 10)
+	if total != 5 || len(requests) != 0 {
+		t.Errorf("expected empty page with total 5, got %d requests and total %d", len(requests), total)
+	}
+}
+
+func TestMemoryTracker_Clear(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	tracker.Track("exec-1", TrackRequest{Method: "GET", URL: "https://example.com", StatusCode: 200})
+	tracker.Clear()
+
+	if requests := tracker.Requests("exec-1"); len(requests) != 0 {
+		t.Errorf("expected 0 requests after clear, got %d", len(requests))
+	}
+}
+
+func TestMemoryTracker_MasksSensitiveData(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	tracker.Track("exec-1", TrackRequest{
+		Method:          "POST",
+		URL:             "https://api.example.com/login?api_key=sk_live_123&page=1",
+		RequestHeaders:  Headers{"Authorization": "Bearer secret-token", "Accept": "application/json"},
+		RequestBody:     `{"username":"alice","password":"hunter2"}`,
+		StatusCode:      200,
+		ResponseHeaders: Headers{"Set-Cookie": "session=abc"},
+		ResponseBody:    `{"access_token":"tok_123","user":"alice"}`,
+	})
+
+	tracked := tracker.Requests("exec-1")[0]
+
+	if strings.Contains(tracked.URL, "sk_live_123") || !strings.Contains(tracked.URL, "page=1") {
+		t.Errorf("expected api_key to be masked in URL, got %q", tracked.URL)
+	}
+	if tracked.RequestHeaders["Authorization"] != "[REDACTED]" || tracked.RequestHeaders["Accept"] != "application/json" {
+		t.Errorf("expected Authorization header to be masked, got %v", tracked.RequestHeaders)
+	}
+	if strings.Contains(*tracked.RequestBody, "hunter2") || !strings.Contains(*tracked.RequestBody, "alice") {
+		t.Errorf("expected password to be masked in request body, got %q", *tracked.RequestBody)
+	}
+	if tracked.ResponseHeaders["Set-Cookie"] != "[REDACTED]" {
+		t.Errorf("expected Set-Cookie header to be masked, got %v", tracked.ResponseHeaders)
+	}
+	if strings.Contains(*tracked.ResponseBody, "tok_123") {
+		t.Errorf("expected access_token to be masked in response body, got %q", *tracked.ResponseBody)
+	}
+}
+
+func TestMemoryTracker_TruncatesLargeBodies(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	body := strings.Repeat("a", maxTrackedBodySize+100)
+	tracker.Track("exec-1", TrackRequest{Method: "GET", URL: "https://example.com", StatusCode: 200, ResponseBody: body})
+
+	tracked := tracker.Requests("exec-1")[0]
+	if tracked.ResponseSize != int64(len(body)) {
+		t.Errorf("expected response size %d, got %d", len(body), tracked.ResponseSize)
+	}
+	if !strings.HasSuffix(*tracked.ResponseBody, "(truncated)") || len(*tracked.ResponseBody) > maxTrackedBodySize+20 {
+		t.Errorf("expected truncated response body, got %d bytes", len(*tracked.ResponseBody))
+	}
+
+	// A multi-byte character straddling the limit is dropped, not split
+	body = strings.Repeat("a", maxTrackedBodySize-1) + "€" + strings.Repeat("a", 100)
+	tracker.Track("exec-2", TrackRequest{Method: "GET", URL: "https://example.com", StatusCode: 200, ResponseBody: body})
+
+	tracked = tracker.Requests("exec-2")[0]
+	if !utf8.ValidString(*tracked.ResponseBody) || !strings.HasPrefix(*tracked.ResponseBody, strings.Repeat("a", maxTrackedBodySize-1)+"...") {
+		t.Errorf("expected the body to be cut before the split character, got %q", (*tracked.ResponseBody)[maxTrackedBodySize-5:])
+	}
+}
+
+func TestMemoryTracker_ConcurrentAccess(t *testing.T) {
+	tracker := NewMemoryTracker()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			tracker.Track("exec-1", TrackRequest{Method: "GET", URL: "https://example.com", StatusCode: 200})
+			_ = tracker.Requests("exec-1")
+		})
+	}
+	wg.Wait()
+
+	if requests := tracker.Requests("exec-1"); len(requests) != 50 {
+		t.Errorf("expected 50 requests, got %d", len(requests))
+	}
+}
+
+func TestSQLiteTracker(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrate.RunTest(t, db)
+
+	// http_requests references executions, so create the parent rows
+	if _, err := db.Exec(`INSERT INTO functions (id, name, created_at, updated_at) VALUES ('fn-1', 'fn', 0, 0)`); err != nil {
+		t.Fatalf("failed to create function: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO executions (id, function_id, function_version_id, status, created_at) VALUES ('exec-1', 'fn-1', 'v-1', 'success', 0)`); err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+
+	tracker := NewSQLiteTracker(db)
+
+	errMsg := "timeout"
+	tracker.Track("exec-1", TrackRequest{
+		Method:          "POST",
+		URL:             "https://api.example.com/items?token=abc123",
+		RequestHeaders:  Headers{"X-Api-Key": "secret"},
+		RequestBody:     `{"name":"widget"}`,
+		StatusCode:      201,
+		ResponseHeaders: Headers{"Content-Type": "application/json"},
+		ResponseBody:    `{"id":"item_1"}`,
+		DurationMs:      42,
+	})
+	tracker.Track("exec-1", TrackRequest{Method: "GET", URL: "https://down.example.com", ErrorMessage: &errMsg})
+
+	requests, total := tracker.RequestsPaginated("exec-1", 10, 0)
+	if total != 2 || len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d (total %d)", len(requests), total)
+	}
+
+	first := requests[0]
+	if first.Method != "POST" || first.URL != "https://api.example.com/items?token=[REDACTED]" {
+		t.Errorf("unexpected request: %s %s", first.Method, first.URL)
+	}
+	if first.RequestHeaders["X-Api-Key"] != "[REDACTED]" {
+		t.Errorf("expected masked request headers, got %v", first.RequestHeaders)
+	}
+	if first.StatusCode == nil || *first.StatusCode != 201 {
+		t.Errorf("expected status code 201, got %v", first.StatusCode)
+	}
+	if first.ResponseHeaders["Content-Type"] != "application/json" {
+		t.Errorf("expected response headers, got %v", first.ResponseHeaders)
+	}
+	if first.ResponseBody == nil || *first.ResponseBody != `{"id":"item_1"}` {
+		t.Errorf("expected response body, got %v", first.ResponseBody)
+	}
+	if first.ResponseSize != 15 || first.DurationMs != 42 || first.Status != store.HTTPRequestStatusSuccess {
+		t.Errorf("unexpected size, duration or status: %+v", first)
+	}
+
+	second := requests[1]
+	if second.StatusCode != nil || second.ResponseHeaders != nil || second.ResponseBody != nil {
+		t.Errorf("expected no response data, got %+v", second)
+	}
+	if second.ErrorMessage == nil || *second.ErrorMessage != errMsg || second.Status != store.HTTPRequestStatusError {
+		t.Errorf("expected error request, got %+v", second)
+	}
+
+	if all := tracker.Requests("exec-1"); len(all) != 2 {
+		t.Errorf("expected 2 requests, got %d", len(all))
+	}
+}
@@ -0,0 +1,7 @@
+// Package jose implements the parts of JSON Object Signing and Encryption used
+// by Lua functions: parsing PEM and JWK keys, JWS signatures and JSON Web Tokens.
+//
+// Keys are parsed from PEM (PKCS#1, PKCS#8, SEC1, PKIX and certificates), JWK
+// and JWKS documents, or used as HMAC secrets. Each key only accepts the
+// algorithms of its type, so a public key can never be used as an HMAC secret.
+package jose
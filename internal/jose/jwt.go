@@ -0,0 +1,223 @@
+package jose
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Token is a decoded JSON Web Token
+type Token struct {
+	Header map[string]any
+	Claims map[string]any
+}
+
+// VerifyOptions controls which tokens Verify accepts
+type VerifyOptions struct {
+	Algorithms []string      // Allowed algorithms, all algorithms of the keys if empty
+	Audience   string        // Required aud value, not checked if empty
+	Issuer     string        // Required iss value, not checked if empty
+	Leeway     time.Duration // Allowed clock skew for exp, nbf and iat
+	Now        time.Time     // Current time, time.Now if zero
+}
+
+// SignJWT signs claims as a compact JWT. Extra header fields are merged into
+// the header; alg and typ are always set.
+func SignJWT(claims map[string]any, alg string, key Key, header map[string]any) (string, error) {
+	h := map[string]any{}
+	for k, v := range header {
+		h[k] = v
+	}
+	h["alg"] = alg
+	if _, ok := h["typ"]; !ok {
+		h["typ"] = "JWT"
+	}
+	if _, ok := h["kid"]; !ok && key.ID != "" {
+		h["kid"] = key.ID
+	}
+
+	headerJSON, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	sig, err := Sign(alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(sig), nil
+}
+
+// DecodeJWT decodes a compact JWT without verifying its signature
+func DecodeJWT(token string) (Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Token{}, errors.New("malformed token: expected three segments")
+	}
+
+	var t Token
+	if err := decodeJSONSegment(parts[0], &t.Header); err != nil {
+		return Token{}, fmt.Errorf("malformed token header: %w", err)
+	}
+	if err := decodeJSONSegment(parts[1], &t.Claims); err != nil {
+		return Token{}, fmt.Errorf("malformed token claims: %w", err)
+	}
+	return t, nil
+}
+
+// VerifyJWT verifies the signature of a compact JWT against keys and checks
+// its exp, nbf, iat, aud and iss claims. When the token has a kid header,
+// only keys with that ID are tried.
+func VerifyJWT(token string, keys []Key, opts VerifyOptions) (Token, error) {
+	t, err := DecodeJWT(token)
+	if err != nil {
+		return Token{}, err
+	}
+
+	alg, _ := t.Header["alg"].(string)
+	if alg == "" || strings.EqualFold(alg, "none") {
+		return Token{}, errors.New("unsigned tokens are not accepted")
+	}
+	if len(opts.Algorithms) > 0 && !slices.Contains(opts.Algorithms, alg) {
+		return Token{}, fmt.Errorf("algorithm %s is not allowed", alg)
+	}
+
+	candidates := keys
+	if kid, _ := t.Header["kid"].(string); kid != "" && hasKeyIDs(keys) {
+		candidates = slices.DeleteFunc(slices.Clone(keys), func(k Key) bool { return k.ID != kid })
+		if len(candidates) == 0 {
+			return Token{}, fmt.Errorf("no key found for kid %q", kid)
+		}
+	}
+
+	i := strings.LastIndex(token, ".")
+	signingInput := []byte(token[:i])
+	sig, err := decodeSegment(token[i+1:])
+	if err != nil {
+		return Token{}, errors.New("malformed token signature")
+	}
+
+	verified := false
+	for _, key := range candidates {
+		if !slices.Contains(key.Algorithms(), alg) {
+			continue
+		}
+		if Verify(alg, key, signingInput, sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Token{}, ErrInvalidSignature
+	}
+
+	if err := checkClaims(t.Claims, opts); err != nil {
+		return Token{}, err
+	}
+	return t, nil
+}
+
+// checkClaims validates the registered time, audience and issuer claims
+func checkClaims(claims map[string]any, opts VerifyOptions) error {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(opts.Leeway)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(opts.Leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	if iat, ok, err := numericDate(claims, "iat"); err != nil {
+		return err
+	} else if ok && now.Add(opts.Leeway).Before(iat) {
+		return errors.New("token was issued in the future")
+	}
+
+	if opts.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != opts.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if opts.Audience != "" && !hasAudience(claims["aud"], opts.Audience) {
+		return errors.New("token audience does not match")
+	}
+	return nil
+}
+
+// numericDate reads a NumericDate claim (seconds since the epoch)
+func numericDate(claims map[string]any, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("claim %s must be a number", name)
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("claim %s must be a number", name)
+	}
+	return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), true, nil
+}
+
+// hasAudience reports whether an aud claim (a string or an array of strings)
+// contains audience
+func hasAudience(aud any, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasKeyIDs reports whether any key has an ID
+func hasKeyIDs(keys []Key) bool {
+	return slices.ContainsFunc(keys, func(k Key) bool { return k.ID != "" })
+}
+
+// encodeSegment encodes a JWT segment as unpadded base64url
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeJSONSegment decodes a base64url JSON object, keeping numbers as
+// json.Number so large integer claims are not rounded
+func decodeJSONSegment(segment string, v *map[string]any) error {
+	b, err := decodeSegment(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if *v == nil {
+		return errors.New("not a JSON object")
+	}
+	return nil
+}
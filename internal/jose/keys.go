@@ -0,0 +1,327 @@
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Key is a signing or verification key
+type Key struct {
+	ID        string           // Key ID (kid) from a JWK
+	Algorithm string           // Algorithm (alg) from a JWK, if restricted
+	Secret    []byte           // HMAC secret
+	Private   crypto.Signer    // Private key, nil for public keys and secrets
+	Public    crypto.PublicKey // Public key, nil for secrets
+}
+
+// ParseKeys parses a key from a PEM block, a JWK, a JWKS document or, if the
+// data is neither, an HMAC secret. JWKS documents return every key they
+// contain.
+func ParseKeys(data string) ([]Key, error) {
+	trimmed := strings.TrimSpace(data)
+	switch {
+	case strings.HasPrefix(trimmed, "-----BEGIN"):
+		key, err := parsePEM(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{key}, nil
+	case strings.HasPrefix(trimmed, "{"):
+		return parseJWKOrSet(trimmed)
+	case data == "":
+		return nil, errors.New("empty key")
+	default:
+		return []Key{{Secret: []byte(data)}}, nil
+	}
+}
+
+// parsePEM parses the first PEM block of data
+func parsePEM(data string) (Key, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return Key{}, errors.New("invalid PEM block")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid PKCS#8 private key: %w", err)
+		}
+		return privateKey(key)
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		return privateKey(key)
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid EC private key: %w", err)
+		}
+		return privateKey(key)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid public key: %w", err)
+		}
+		return publicKey(key)
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid RSA public key: %w", err)
+		}
+		return publicKey(key)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid certificate: %w", err)
+		}
+		return publicKey(cert.PublicKey)
+	default:
+		return Key{}, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// privateKey wraps a parsed private key, rejecting unsupported key types
+func privateKey(key any) (Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return Key{Private: k, Public: &k.PublicKey}, nil
+	case *ecdsa.PrivateKey:
+		return Key{Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return Key{Private: k, Public: k.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// publicKey wraps a parsed public key, rejecting unsupported key types
+func publicKey(key any) (Key, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return Key{Public: k}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// jwk is a JSON Web Key (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+
+	K string `json:"k"` // oct
+
+	N string `json:"n"` // RSA
+	E string `json:"e"`
+	D string `json:"d"` // RSA, EC and OKP private exponent or key
+	P string `json:"p"`
+	Q string `json:"q"`
+
+	X string `json:"x"` // EC and OKP
+	Y string `json:"y"`
+}
+
+// parseJWKOrSet parses a JWK or a JWKS document
+func parseJWKOrSet(data string) ([]Key, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(data), &set); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+
+	if set.Keys == nil {
+		key, _, err := parseJWK([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		return []Key{key}, nil
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for _, raw := range set.Keys {
+		// Skip encryption keys and key types that are not supported, e.g. X25519
+		key, use, err := parseJWK(raw)
+		if err != nil || use == "enc" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no supported signing keys")
+	}
+	return keys, nil
+}
+
+// parseJWK parses a single JWK, returning the key and its intended use
+func parseJWK(data []byte) (Key, string, error) {
+	var j jwk
+	if err := json.Unmarshal(data, &j); err != nil {
+		return Key{}, "", fmt.Errorf("invalid JWK: %w", err)
+	}
+
+	key, err := j.key()
+	if err != nil {
+		return Key{}, "", err
+	}
+	key.ID = j.Kid
+	key.Algorithm = j.Alg
+	return key, j.Use, nil
+}
+
+// key converts the JWK to a Key
+func (j jwk) key() (Key, error) {
+	switch j.Kty {
+	case "oct":
+		secret, err := decodeSegment(j.K)
+		if err != nil || len(secret) == 0 {
+			return Key{}, errors.New("invalid oct JWK: missing k")
+		}
+		return Key{Secret: secret}, nil
+	case "RSA":
+		return j.rsaKey()
+	case "EC":
+		return j.ecKey()
+	case "OKP":
+		return j.okpKey()
+	default:
+		return Key{}, fmt.Errorf("unsupported JWK key type %q", j.Kty)
+	}
+}
+
+// rsaKey converts an RSA JWK
+func (j jwk) rsaKey() (Key, error) {
+	n, err := decodeBigInt(j.N)
+	if err != nil {
+		return Key{}, errors.New("invalid RSA JWK: bad n")
+	}
+	e, err := decodeBigInt(j.E)
+	if err != nil || !e.IsInt64() {
+		return Key{}, errors.New("invalid RSA JWK: bad e")
+	}
+	public := &rsa.PublicKey{N: n, E: int(e.Int64())}
+	if j.D == "" {
+		return Key{Public: public}, nil
+	}
+
+	d, err := decodeBigInt(j.D)
+	if err != nil {
+		return Key{}, errors.New("invalid RSA JWK: bad d")
+	}
+	p, errP := decodeBigInt(j.P)
+	q, errQ := decodeBigInt(j.Q)
+	if errP != nil || errQ != nil {
+		return Key{}, errors.New("invalid RSA JWK: private keys need p and q")
+	}
+	private := &rsa.PrivateKey{PublicKey: *public, D: d, Primes: []*big.Int{p, q}}
+	if err := private.Validate(); err != nil {
+		return Key{}, fmt.Errorf("invalid RSA JWK: %w", err)
+	}
+	private.Precompute()
+	return Key{Private: private, Public: public}, nil
+}
+
+// ecKey converts an EC JWK
+func (j jwk) ecKey() (Key, error) {
+	var curve elliptic.Curve
+	switch j.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return Key{}, fmt.Errorf("unsupported EC curve %q", j.Crv)
+	}
+
+	x, errX := decodeSegment(j.X)
+	y, errY := decodeSegment(j.Y)
+	if errX != nil || errY != nil {
+		return Key{}, errors.New("invalid EC JWK: bad x or y")
+	}
+
+	// Parsing the uncompressed encoding checks the point is on the curve
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return Key{}, errors.New("invalid EC JWK: bad coordinate length")
+	}
+	point := append([]byte{4}, append(x, y...)...)
+	public, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid EC JWK: %w", err)
+	}
+	if j.D == "" {
+		return Key{Public: public}, nil
+	}
+
+	d, err := decodeSegment(j.D)
+	if err != nil {
+		return Key{}, errors.New("invalid EC JWK: bad d")
+	}
+	private, err := ecdsa.ParseRawPrivateKey(curve, d)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid EC JWK: %w", err)
+	}
+	if !private.PublicKey.Equal(public) {
+		return Key{}, errors.New("invalid EC JWK: d does not match x and y")
+	}
+	return Key{Private: private, Public: public}, nil
+}
+
+// okpKey converts an Ed25519 JWK
+func (j jwk) okpKey() (Key, error) {
+	if j.Crv != "Ed25519" {
+		return Key{}, fmt.Errorf("unsupported OKP curve %q", j.Crv)
+	}
+	x, err := decodeSegment(j.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return Key{}, errors.New("invalid OKP JWK: bad x")
+	}
+	public := ed25519.PublicKey(x)
+	if j.D == "" {
+		return Key{Public: public}, nil
+	}
+
+	d, err := decodeSegment(j.D)
+	if err != nil || len(d) != ed25519.SeedSize {
+		return Key{}, errors.New("invalid OKP JWK: bad d")
+	}
+	private := ed25519.NewKeyFromSeed(d)
+	if !public.Equal(private.Public()) {
+		return Key{}, errors.New("invalid OKP JWK: d does not match x")
+	}
+	return Key{Private: private, Public: public}, nil
+}
+
+// decodeSegment decodes unpadded base64url, tolerating padding
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := decodeSegment(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
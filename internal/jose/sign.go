@@ -0,0 +1,192 @@
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"slices"
+)
+
+// ErrInvalidSignature is returned when a signature does not match
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// algorithm describes a JWS algorithm (RFC 7518)
+type algorithm struct {
+	hash  crypto.Hash
+	curve elliptic.Curve // ECDSA curve, nil otherwise
+	pss   bool           // RSASSA-PSS instead of PKCS#1 v1.5
+}
+
+// algorithms are the supported JWS algorithms, by name
+var algorithms = map[string]algorithm{
+	"HS256": {hash: crypto.SHA256},
+	"HS384": {hash: crypto.SHA384},
+	"HS512": {hash: crypto.SHA512},
+	"RS256": {hash: crypto.SHA256},
+	"RS384": {hash: crypto.SHA384},
+	"RS512": {hash: crypto.SHA512},
+	"PS256": {hash: crypto.SHA256, pss: true},
+	"PS384": {hash: crypto.SHA384, pss: true},
+	"PS512": {hash: crypto.SHA512, pss: true},
+	"ES256": {hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, curve: elliptic.P521()},
+	"EdDSA": {},
+}
+
+// Algorithms returns the algorithms a key can sign or verify with. A key
+// with an alg restriction from its JWK only allows that algorithm.
+func (k Key) Algorithms() []string {
+	var algs []string
+	switch public := k.Public.(type) {
+	case nil:
+		if len(k.Secret) > 0 {
+			algs = []string{"HS256", "HS384", "HS512"}
+		}
+	case *rsa.PublicKey:
+		algs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		for name, alg := range algorithms {
+			if alg.curve == public.Curve {
+				algs = []string{name}
+			}
+		}
+	case ed25519.PublicKey:
+		algs = []string{"EdDSA"}
+	}
+
+	if k.Algorithm != "" {
+		if slices.Contains(algs, k.Algorithm) {
+			return []string{k.Algorithm}
+		}
+		return nil
+	}
+	return algs
+}
+
+// checkAlgorithm returns the algorithm named alg if key supports it
+func checkAlgorithm(alg string, key Key) (algorithm, error) {
+	a, ok := algorithms[alg]
+	if !ok {
+		return algorithm{}, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if !slices.Contains(key.Algorithms(), alg) {
+		return algorithm{}, fmt.Errorf("algorithm %s cannot be used with this key", alg)
+	}
+	return a, nil
+}
+
+// Sign signs data with key using the JWS algorithm alg. ECDSA signatures use
+// the JWS encoding: the big-endian r and s values, each padded to the curve
+// size.
+func Sign(alg string, key Key, data []byte) ([]byte, error) {
+	a, err := checkAlgorithm(alg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Secret != nil {
+		return hmacSum(a.hash, key.Secret, data), nil
+	}
+	if key.Private == nil {
+		return nil, errors.New("signing requires a private key")
+	}
+
+	switch private := key.Private.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(private, data), nil
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, private, digest(a.hash, data))
+		if err != nil {
+			return nil, err
+		}
+		size := curveSize(a.curve)
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	case *rsa.PrivateKey:
+		if a.pss {
+			return rsa.SignPSS(rand.Reader, private, a.hash, digest(a.hash, data), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, private, a.hash, digest(a.hash, data))
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key.Private)
+	}
+}
+
+// Verify checks that sig is a valid signature of data by key using the JWS
+// algorithm alg, returning ErrInvalidSignature if it is not
+func Verify(alg string, key Key, data, sig []byte) error {
+	a, err := checkAlgorithm(alg, key)
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	switch public := key.Public.(type) {
+	case nil:
+		valid = hmac.Equal(sig, hmacSum(a.hash, key.Secret, data))
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(public, data, sig)
+	case *ecdsa.PublicKey:
+		size := curveSize(a.curve)
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(public, digest(a.hash, data), r, s)
+		}
+	case *rsa.PublicKey:
+		if a.pss {
+			valid = rsa.VerifyPSS(public, a.hash, digest(a.hash, data), sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(public, a.hash, digest(a.hash, data), sig) == nil
+		}
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// newHash returns the constructor for a SHA-2 hash
+func newHash(h crypto.Hash) func() hash.Hash {
+	switch h {
+	case crypto.SHA384:
+		return sha512.New384
+	case crypto.SHA512:
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+// digest hashes data with h
+func digest(h crypto.Hash, data []byte) []byte {
+	hasher := newHash(h)()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+// hmacSum computes the HMAC of data with secret
+func hmacSum(h crypto.Hash, secret, data []byte) []byte {
+	mac := hmac.New(newHash(h), secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// curveSize returns the size in bytes of a coordinate on curve
+func curveSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
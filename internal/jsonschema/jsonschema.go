@@ -0,0 +1,248 @@
+// Package jsonschema validates decoded JSON values against a JSON Schema.
+//
+// It implements the subset of JSON Schema used to describe structured AI
+// replies: type, enum, const, properties, required, additionalProperties,
+// items, the string, number and array bounds, pattern and the allOf, anyOf
+// and oneOf combinators. Unknown keywords, including $ref, are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ValidationError reports where a value does not match its schema
+type ValidationError struct {
+	Path    string // JSON path of the invalid value, e.g. $.items[0].name
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks a value decoded by encoding/json (maps, slices, float64,
+// string, bool and nil) against a schema decoded the same way. It returns
+// the first mismatch as a *ValidationError.
+func Validate(schema map[string]any, value any) error {
+	return validate(schema, value, "$")
+}
+
+func validate(schema map[string]any, value any, path string) error {
+	fail := func(msg string, args ...any) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(msg, args...)}
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return fail("expected %s, got %s", typeNames(t), typeOf(value))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return equal(e, value) }) {
+		return fail("must be one of %s", compact(enum))
+	}
+	if c, ok := schema["const"]; ok && !equal(c, value) {
+		return fail("must be %s", compact(c))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := number(schema["minLength"]); ok && float64(length) < n {
+			return fail("must be at least %s characters", format(n))
+		}
+		if n, ok := number(schema["maxLength"]); ok && float64(length) > n {
+			return fail("must be at most %s characters", format(n))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fail("invalid pattern %q: %v", pattern, err)
+			}
+			if !re.MatchString(v) {
+				return fail("must match pattern %q", pattern)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			return fail("must be >= %s", format(n))
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			return fail("must be <= %s", format(n))
+		}
+		if n, ok := number(schema["exclusiveMinimum"]); ok && v <= n {
+			return fail("must be > %s", format(n))
+		}
+		if n, ok := number(schema["exclusiveMaximum"]); ok && v >= n {
+			return fail("must be < %s", format(n))
+		}
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			return fail("must have at least %s items", format(n))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fail("must have at most %s items", format(n))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validate(items, item, path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if key, ok := name.(string); ok {
+					if _, present := v[key]; !present {
+						return fail("missing required property %q", key)
+					}
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for _, key := range sortedKeys(v) {
+			propertyPath := path + "." + key
+			if propertySchema, ok := properties[key].(map[string]any); ok {
+				if err := validate(propertySchema, v[key], propertyPath); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, ok := properties[key]; ok {
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fail("unexpected property %q", key)
+				}
+			case map[string]any:
+				if err := validate(additional, v[key], propertyPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, s := range all {
+			if sub, ok := s.(map[string]any); ok {
+				if err := validate(sub, value, path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if matches(anyOf, value, path) == 0 {
+			return fail("must match at least one schema in anyOf")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := matches(oneOf, value, path); n != 1 {
+			return fail("must match exactly one schema in oneOf, matched %d", n)
+		}
+	}
+	return nil
+}
+
+// matches counts the schemas in the list that the value matches
+func matches(schemas []any, value any, path string) int {
+	n := 0
+	for _, s := range schemas {
+		if sub, ok := s.(map[string]any); ok && validate(sub, value, path) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// matchesType reports whether the value has the type, or one of the types,
+// named by the type keyword
+func matchesType(t any, value any) bool {
+	switch t := t.(type) {
+	case string:
+		return isType(t, value)
+	case []any:
+		return slices.ContainsFunc(t, func(name any) bool {
+			s, _ := name.(string)
+			return isType(s, value)
+		})
+	default:
+		return true
+	}
+}
+
+func isType(name string, value any) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+	case "number":
+		return typeOf(value) == "number"
+	default:
+		return typeOf(value) == name
+	}
+}
+
+// typeOf returns the JSON type name of a decoded value
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// typeNames formats the type keyword for error messages
+func typeNames(t any) string {
+	if names, ok := t.([]any); ok {
+		return "one of " + compact(names)
+	}
+	return fmt.Sprint(t)
+}
+
+// equal compares decoded JSON values
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// number reads a numeric keyword
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+// format prints a number without a trailing .0
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// compact encodes a value as JSON for error messages
+func compact(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// sortedKeys returns the keys of an object in order, so the first error
+// reported is deterministic
+func sortedKeys(m map[string]any) []string {
+	return slices.Sorted(maps.Keys(m))
+}
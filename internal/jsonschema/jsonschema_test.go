@@ -0,0 +1,119 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 10},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 3},
+		"nickname": {"type": ["string", "null"]},
+		"kind": {"const": "person"},
+		"address": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}},
+			"required": ["city"],
+			"additionalProperties": false
+		}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": {"type": "string"}
+}`
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	schema := decode(t, personSchema).(map[string]any)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "minimal", value: `{"name": "Ann", "age": 30}`},
+		{name: "full", value: `{"name": "Ann", "age": 30, "email": "a@b.c", "role": "admin", "tags": ["x"], "nickname": null, "kind": "person", "address": {"city": "Lisbon"}, "note": "extra"}`},
+		{name: "not an object", value: `[]`, expected: "$: expected object, got array"},
+		{name: "missing required", value: `{"name": "Ann"}`, expected: `$: missing required property "age"`},
+		{name: "wrong type", value: `{"name": 1, "age": 30}`, expected: "$.name: expected string, got number"},
+		{name: "not an integer", value: `{"name": "Ann", "age": 30.5}`, expected: "$.age: expected integer, got number"},
+		{name: "below minimum", value: `{"name": "Ann", "age": -1}`, expected: "$.age: must be >= 0"},
+		{name: "exclusive maximum", value: `{"name": "Ann", "age": 150}`, expected: "$.age: must be < 150"},
+		{name: "too short", value: `{"name": "", "age": 1}`, expected: "$.name: must be at least 1 characters"},
+		{name: "too long", value: `{"name": "Annabelle Smith", "age": 1}`, expected: "$.name: must be at most 10 characters"},
+		{name: "pattern", value: `{"name": "Ann", "age": 1, "email": "nope"}`, expected: `$.email: must match pattern "^[^@]+@[^@]+$"`},
+		{name: "enum", value: `{"name": "Ann", "age": 1, "role": "root"}`, expected: `$.role: must be one of ["admin","user"]`},
+		{name: "const", value: `{"name": "Ann", "age": 1, "kind": "robot"}`, expected: `$.kind: must be "person"`},
+		{name: "type list", value: `{"name": "Ann", "age": 1, "nickname": 5}`, expected: `$.nickname: expected one of ["string","null"], got number`},
+		{name: "array item", value: `{"name": "Ann", "age": 1, "tags": ["a", 2]}`, expected: "$.tags[1]: expected string, got number"},
+		{name: "min items", value: `{"name": "Ann", "age": 1, "tags": []}`, expected: "$.tags: must have at least 1 items"},
+		{name: "max items", value: `{"name": "Ann", "age": 1, "tags": ["a", "b", "c", "d"]}`, expected: "$.tags: must have at most 3 items"},
+		{name: "nested required", value: `{"name": "Ann", "age": 1, "address": {}}`, expected: `$.address: missing required property "city"`},
+		{name: "no additional properties", value: `{"name": "Ann", "age": 1, "address": {"city": "Porto", "zip": "4000"}}`, expected: `$.address: unexpected property "zip"`},
+		{name: "additional properties schema", value: `{"name": "Ann", "age": 1, "extra": true}`, expected: "$.extra: expected string, got boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(schema, decode(t, tt.value))
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema := decode(t, `{
+		"allOf": [{"type": "number"}, {"minimum": 0}],
+		"anyOf": [{"maximum": 10}, {"minimum": 100}],
+		"oneOf": [{"type": "integer"}, {"maximum": 5}]
+	}`).(map[string]any)
+
+	tests := map[string]string{
+		`7`:   "",
+		`4.5`: "",
+		`-1`:  "$: must be >= 0",
+		`50`:  "$: must match at least one schema in anyOf",
+		`3`:   "$: must match exactly one schema in oneOf, matched 2",
+		`9.5`: "$: must match exactly one schema in oneOf, matched 0",
+	}
+	for value, expected := range tests {
+		err := Validate(schema, decode(t, value))
+		if expected == "" && err != nil {
+			t.Errorf("%s: expected valid, got %v", value, err)
+		}
+		if expected != "" && (err == nil || err.Error() != expected) {
+			t.Errorf("%s: expected error %q, got %v", value, expected, err)
+		}
+	}
+}
+
+func TestValidate_ErrorType(t *testing.T) {
+	err := Validate(map[string]any{"type": "string"}, 1.0)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, got %T", err)
+	}
+	if verr.Path != "$" || verr.Message != "expected string, got number" {
+		t.Errorf("unexpected error %+v", verr)
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import "sync"
+
+// subscriberBuffer is the number of entries queued per subscriber.
+// Subscribers that fall further behind are closed so a slow reader never
+// blocks function execution.
+const subscriberBuffer = 256
+
+// subscriber receives entries for one execution, or all executions when
+// executionID is empty
+type subscriber struct {
+	executionID string
+	ch          chan LogEntry
+}
+
+// broker fans out newly written log entries to live subscribers.
+// The zero value is ready to use.
+type broker struct {
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+// subscribe registers a new subscriber and returns its channel along with a
+// cancel function that unregisters it and closes the channel. The channel is
+// also closed, after the entries already queued, when the subscriber lags.
+func (b *broker) subscribe(executionID string) (<-chan LogEntry, func()) {
+	sub := &subscriber{
+		executionID: executionID,
+		ch:          make(chan LogEntry, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[*subscriber]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+
+	return sub.ch, cancel
+}
+
+// publish delivers entry to every matching subscriber without blocking.
+// A subscriber whose buffer is full is closed instead of silently missing
+// the entry, so the reader knows to catch up from the stored entries.
+func (b *broker) publish(entry LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.executionID != "" && sub.executionID != entry.ExecutionID {
+			continue
+		}
+		select {
+		case sub.ch <- entry:
+		default:
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
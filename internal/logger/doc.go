@@ -1,3 +1,4 @@
 // Package logger provides logging functionality with execution isolation.
 // Each execution maintains a separate log stream identified by executionID.
+// Loggers also publish new entries to subscribers for live tailing.
 package logger
@@ -2,7 +2,9 @@ package logger
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,28 +41,96 @@ func (l LogLevel) String() string {
 
 // LogEntry represents a single log entry
 type LogEntry struct {
+	ID          string
 	ExecutionID string
 	Level       LogLevel
 	Message     string
+	Fields      map[string]any // Structured fields, nil when none were given
+	Line        int            // Source line of the log call, 0 when unknown
 	Timestamp   int64
 }
 
+// Filter narrows the entries returned by EntriesFiltered.
+// The zero value matches every entry.
+type Filter struct {
+	Levels []LogLevel        // Entry level must be one of these
+	Search string            // Case-insensitive substring of the message
+	Fields map[string]string // Top-level fields that must have these values
+}
+
+// Match reports whether entry satisfies the filter.
+// Field values match either their string form or their JSON encoding, so
+// "42" matches both the number 42 and the string "42".
+func (f Filter) Match(entry LogEntry) bool {
+	if len(f.Levels) > 0 {
+		found := false
+		for _, level := range f.Levels {
+			if entry.Level == level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Search != "" && !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(f.Search)) {
+		return false
+	}
+
+	for key, want := range f.Fields {
+		value, ok := entry.Fields[key]
+		if !ok {
+			return false
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		if got := string(encoded); got != want && got != fieldJSONString(want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fieldJSONString returns the JSON encoding of a field filter value as a string
+func fieldJSONString(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
 // Logger is an interface for logging operations
 // executionID is used to isolate logs for each function execution
 type Logger interface {
 	Log(executionID string, level LogLevel, message string)
+	// Write records an entry with structured fields. ID and Timestamp are
+	// assigned, and the message and fields are masked before storage.
+	Write(entry LogEntry)
 	Info(executionID string, message string)
 	Debug(executionID string, message string)
 	Warn(executionID string, message string)
 	Error(executionID string, message string)
 	Entries(executionID string) []LogEntry
 	EntriesPaginated(executionID string, limit, offset int) ([]LogEntry, int64)
+	EntriesFiltered(executionID string, filter Filter, limit, offset int) ([]LogEntry, int64)
+	// EntriesAfter returns up to limit entries written after the entry with
+	// afterID, in write order. An empty executionID matches all executions.
+	EntriesAfter(executionID, afterID string, limit int) []LogEntry
+	// Subscribe streams entries as they are written until cancel is called.
+	// An empty executionID subscribes to all executions. The channel is
+	// closed early when the subscriber falls too far behind; the entries it
+	// missed are those after the last one received, see EntriesAfter.
+	Subscribe(executionID string) (entries <-chan LogEntry, cancel func())
 }
 
 // MemoryLogger is an in-memory implementation of Logger
 type MemoryLogger struct {
 	mu      sync.RWMutex
 	entries []LogEntry
+	broker  broker
 }
 
 // NewMemoryLogger creates a new in-memory logger
@@ -72,20 +142,18 @@ func NewMemoryLogger() *MemoryLogger {
 
 // Log records a log entry with the specified executionID, level and message
 func (m *MemoryLogger) Log(executionID string, level LogLevel, message string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Mask sensitive data in log messages
-	maskedMessage := masking.MaskLogMessage(message)
+	m.Write(LogEntry{ExecutionID: executionID, Level: level, Message: message})
+}
 
-	entry := LogEntry{
-		ExecutionID: executionID,
-		Level:       level,
-		Message:     maskedMessage,
-		Timestamp:   time.Now().Unix(),
-	}
+// Write records an entry with structured fields
+func (m *MemoryLogger) Write(entry LogEntry) {
+	entry = prepareEntry(entry)
 
+	m.mu.Lock()
 	m.entries = append(m.entries, entry)
+	m.mu.Unlock()
+
+	m.broker.publish(entry)
 }
 
 // Info logs an informational message
@@ -124,13 +192,19 @@ func (m *MemoryLogger) Entries(executionID string) []LogEntry {
 
 // EntriesPaginated returns paginated log entries for the specified executionID
 func (m *MemoryLogger) EntriesPaginated(executionID string, limit, offset int) ([]LogEntry, int64) {
+	return m.EntriesFiltered(executionID, Filter{}, limit, offset)
+}
+
+// EntriesFiltered returns paginated log entries for the specified executionID
+// that match filter
+func (m *MemoryLogger) EntriesFiltered(executionID string, filter Filter, limit, offset int) ([]LogEntry, int64) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	// Filter entries by executionID
 	filtered := make([]LogEntry, 0)
 	for _, entry := range m.entries {
-		if entry.ExecutionID == executionID {
+		if entry.ExecutionID == executionID && filter.Match(entry) {
 			filtered = append(filtered, entry)
 		}
 	}
@@ -147,6 +221,33 @@ func (m *MemoryLogger) EntriesPaginated(executionID string, limit, offset int) (
 	return filtered[offset:end], total
 }
 
+// EntriesAfter returns up to limit entries written after the entry with afterID
+func (m *MemoryLogger) EntriesAfter(executionID, afterID string, limit int) []LogEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]LogEntry, 0)
+	found := false
+	for _, entry := range m.entries {
+		if !found {
+			found = entry.ID == afterID
+			continue
+		}
+		if len(entries) >= limit {
+			break
+		}
+		if executionID == "" || entry.ExecutionID == executionID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Subscribe streams entries as they are written until cancel is called
+func (m *MemoryLogger) Subscribe(executionID string) (<-chan LogEntry, func()) {
+	return m.broker.subscribe(executionID)
+}
+
 // EntriesByLevel returns all log entries with the specified executionID and level
 func (m *MemoryLogger) EntriesByLevel(executionID string, level LogLevel) []LogEntry {
 	m.mu.RLock()
@@ -190,9 +291,13 @@ func (m *MemoryLogger) String() string {
 	return result
 }
 
+// logColumns lists the columns scanned by scanEntries
+const logColumns = "id, execution_id, level, message, fields_json, line, timestamp"
+
 // SQLiteLogger is a SQLite-backed implementation of Logger
 type SQLiteLogger struct {
-	db *sql.DB
+	db     *sql.DB
+	broker broker
 }
 
 // NewSQLiteLogger creates a new SQLite-backed logger
@@ -202,18 +307,37 @@ func NewSQLiteLogger(db *sql.DB) *SQLiteLogger {
 
 // Log records a log entry with the specified executionID, level and message
 func (s *SQLiteLogger) Log(executionID string, level LogLevel, message string) {
-	// Mask sensitive data in log messages
-	maskedMessage := masking.MaskLogMessage(message)
+	s.Write(LogEntry{ExecutionID: executionID, Level: level, Message: message})
+}
+
+// Write records an entry with structured fields
+func (s *SQLiteLogger) Write(entry LogEntry) {
+	entry = prepareEntry(entry)
+
+	var fieldsJSON, line any
+	if entry.Fields != nil {
+		encoded, err := json.Marshal(entry.Fields)
+		if err != nil {
+			fmt.Printf("Failed to encode log fields: %v\n", err)
+		} else {
+			fieldsJSON = string(encoded)
+		}
+	}
+	if entry.Line > 0 {
+		line = entry.Line
+	}
 
-	id := xid.New().String()
 	_, err := s.db.Exec(
-		"INSERT INTO logs (id, execution_id, level, message, timestamp) VALUES (?, ?, ?, ?, ?)",
-		id, executionID, int(level), maskedMessage, time.Now().Unix(),
+		"INSERT INTO logs (id, execution_id, level, message, fields_json, line, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		entry.ID, entry.ExecutionID, int(entry.Level), entry.Message, fieldsJSON, line, entry.Timestamp,
 	)
 	if err != nil {
 		// For now, we silently ignore errors to match the Logger interface
 		fmt.Printf("Failed to write log: %v\n", err)
+		return
 	}
+
+	s.broker.publish(entry)
 }
 
 // Info logs an informational message
@@ -239,7 +363,7 @@ func (s *SQLiteLogger) Error(executionID string, message string) {
 // Entries returns all log entries for the specified executionID
 func (s *SQLiteLogger) Entries(executionID string) []LogEntry {
 	rows, err := s.db.Query(
-		"SELECT execution_id, level, message, timestamp FROM logs WHERE execution_id = ? ORDER BY timestamp",
+		"SELECT "+logColumns+" FROM logs WHERE execution_id = ? ORDER BY timestamp, rowid",
 		executionID,
 	)
 	if err != nil {
@@ -252,17 +376,46 @@ func (s *SQLiteLogger) Entries(executionID string) []LogEntry {
 
 // EntriesPaginated returns paginated log entries for the specified executionID
 func (s *SQLiteLogger) EntriesPaginated(executionID string, limit, offset int) ([]LogEntry, int64) {
+	return s.EntriesFiltered(executionID, Filter{}, limit, offset)
+}
+
+// EntriesFiltered returns paginated log entries for the specified executionID
+// that match filter
+func (s *SQLiteLogger) EntriesFiltered(executionID string, filter Filter, limit, offset int) ([]LogEntry, int64) {
+	where := "execution_id = ?"
+	args := []any{executionID}
+
+	if len(filter.Levels) > 0 {
+		placeholders := make([]string, len(filter.Levels))
+		for i, level := range filter.Levels {
+			placeholders[i] = "?"
+			args = append(args, int(level))
+		}
+		where += " AND level IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	if filter.Search != "" {
+		where += ` AND message LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+	}
+
+	for key, value := range filter.Fields {
+		// The -> operator returns the JSON encoding of the field, matching Filter.Match
+		where += " AND fields_json -> ? IN (?, ?)"
+		args = append(args, fieldPath(key), value, fieldJSONString(value))
+	}
+
 	// Get total count
 	var total int64
-	err := s.db.QueryRow("SELECT COUNT(*) FROM logs WHERE execution_id = ?", executionID).Scan(&total)
+	err := s.db.QueryRow("SELECT COUNT(*) FROM logs WHERE "+where, args...).Scan(&total)
 	if err != nil {
 		return []LogEntry{}, 0
 	}
 
 	// Get paginated entries
 	rows, err := s.db.Query(
-		"SELECT execution_id, level, message, timestamp FROM logs WHERE execution_id = ? ORDER BY timestamp LIMIT ? OFFSET ?",
-		executionID, limit, offset,
+		"SELECT "+logColumns+" FROM logs WHERE "+where+" ORDER BY timestamp, rowid LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
 	)
 	if err != nil {
 		return []LogEntry{}, total
@@ -272,10 +425,36 @@ func (s *SQLiteLogger) EntriesPaginated(executionID string, limit, offset int) (
 	return s.scanEntries(rows), total
 }
 
+// EntriesAfter returns up to limit entries written after the entry with afterID.
+// Entries are ordered by rowid, which follows insertion order.
+func (s *SQLiteLogger) EntriesAfter(executionID, afterID string, limit int) []LogEntry {
+	query := "SELECT " + logColumns + " FROM logs WHERE rowid > (SELECT rowid FROM logs WHERE id = ?)"
+	args := []any{afterID}
+	if executionID != "" {
+		query += " AND execution_id = ?"
+		args = append(args, executionID)
+	}
+	query += " ORDER BY rowid LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return []LogEntry{}
+	}
+	defer func() { _ = rows.Close() }()
+
+	return s.scanEntries(rows)
+}
+
+// Subscribe streams entries as they are written until cancel is called
+func (s *SQLiteLogger) Subscribe(executionID string) (<-chan LogEntry, func()) {
+	return s.broker.subscribe(executionID)
+}
+
 // EntriesByLevel returns all log entries with the specified executionID and level
 func (s *SQLiteLogger) EntriesByLevel(executionID string, level LogLevel) []LogEntry {
 	rows, err := s.db.Query(
-		"SELECT execution_id, level, message, timestamp FROM logs WHERE execution_id = ? AND level = ? ORDER BY timestamp",
+		"SELECT "+logColumns+" FROM logs WHERE execution_id = ? AND level = ? ORDER BY timestamp, rowid",
 		executionID, int(level),
 	)
 	if err != nil {
@@ -297,11 +476,38 @@ func (s *SQLiteLogger) scanEntries(rows *sql.Rows) []LogEntry {
 	for rows.Next() {
 		var entry LogEntry
 		var level int
-		if err := rows.Scan(&entry.ExecutionID, &level, &entry.Message, &entry.Timestamp); err != nil {
+		var fieldsJSON sql.NullString
+		var line sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.ExecutionID, &level, &entry.Message, &fieldsJSON, &line, &entry.Timestamp); err != nil {
 			continue
 		}
 		entry.Level = LogLevel(level)
+		entry.Line = int(line.Int64)
+		if fieldsJSON.Valid {
+			_ = json.Unmarshal([]byte(fieldsJSON.String), &entry.Fields)
+		}
 		entries = append(entries, entry)
 	}
 	return entries
 }
+
+// prepareEntry assigns the ID and timestamp of a new entry and masks
+// sensitive data in its message and fields
+func prepareEntry(entry LogEntry) LogEntry {
+	entry.ID = xid.New().String()
+	entry.Timestamp = time.Now().Unix()
+	entry.Message = masking.MaskLogMessage(entry.Message)
+	entry.Fields = masking.MaskLogFields(entry.Fields)
+	return entry
+}
+
+// fieldPath returns the JSON path of a top-level field, quoting the key so
+// names containing dots or brackets are not treated as path syntax
+func fieldPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
+// escapeLike escapes the LIKE wildcards in s using backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
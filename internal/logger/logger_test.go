@@ -2,6 +2,7 @@ package logger
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -347,3 +348,193 @@ func TestSQLiteLogger_SensitiveDataMasking(t *testing.T) {
 func contains(str, substr string) bool {
 	return strings.Contains(str, substr)
 }
+
+func TestLogger_EntriesAfter(t *testing.T) {
+	loggers := map[string]Logger{
+		"memory": NewMemoryLogger(),
+		"sqlite": NewSQLiteLogger(setupTestDB(t)),
+	}
+
+	for name, logger := range loggers {
+		t.Run(name, func(t *testing.T) {
+			logger.Info("exec-1", "first")
+			logger.Info("exec-2", "other")
+			logger.Info("exec-1", "second")
+			logger.Info("exec-1", "third")
+
+			entries := logger.Entries("exec-1")
+			if len(entries) != 3 {
+				t.Fatalf("Expected 3 entries, got %d", len(entries))
+			}
+			if entries[0].ID == "" {
+				t.Fatal("Expected entries to have an ID")
+			}
+
+			after := logger.EntriesAfter("exec-1", entries[0].ID, 10)
+			if len(after) != 2 || after[0].Message != "second" || after[1].Message != "third" {
+				t.Errorf("Expected [second third], got %+v", after)
+			}
+
+			all := logger.EntriesAfter("", entries[0].ID, 10)
+			if len(all) != 3 || all[0].Message != "other" {
+				t.Errorf("Expected entries from all executions, got %+v", all)
+			}
+
+			limited := logger.EntriesAfter("exec-1", entries[0].ID, 1)
+			if len(limited) != 1 || limited[0].Message != "second" {
+				t.Errorf("Expected [second], got %+v", limited)
+			}
+
+			if unknown := logger.EntriesAfter("exec-1", "unknown", 10); len(unknown) != 0 {
+				t.Errorf("Expected no entries for unknown ID, got %d", len(unknown))
+			}
+		})
+	}
+}
+
+func TestLogger_Subscribe(t *testing.T) {
+	loggers := map[string]Logger{
+		"memory": NewMemoryLogger(),
+		"sqlite": NewSQLiteLogger(setupTestDB(t)),
+	}
+
+	for name, logger := range loggers {
+		t.Run(name, func(t *testing.T) {
+			execEntries, cancelExec := logger.Subscribe("exec-1")
+			allEntries, cancelAll := logger.Subscribe("")
+			defer cancelAll()
+
+			logger.Info("exec-2", "other")
+			logger.Warn("exec-1", "watched")
+
+			entry := <-execEntries
+			if entry.Message != "watched" || entry.Level != Warn || entry.ID == "" {
+				t.Errorf("Unexpected entry: %+v", entry)
+			}
+
+			if first, second := <-allEntries, <-allEntries; first.Message != "other" || second.Message != "watched" {
+				t.Errorf("Expected [other watched], got [%s %s]", first.Message, second.Message)
+			}
+
+			cancelExec()
+			cancelExec() // cancel is idempotent
+			if _, ok := <-execEntries; ok {
+				t.Error("Expected channel to be closed after cancel")
+			}
+
+			// Publishing after cancel must not panic
+			logger.Info("exec-1", "after cancel")
+		})
+	}
+}
+
+func TestLogger_SubscribeLagged(t *testing.T) {
+	logger := NewMemoryLogger()
+	entries, cancel := logger.Subscribe("exec-1")
+	defer cancel()
+
+	for i := range subscriberBuffer + 10 {
+		logger.Info("exec-1", fmt.Sprintf("line %d", i))
+	}
+
+	var last LogEntry
+	received := 0
+	for entry := range entries {
+		last = entry
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Fatalf("Expected %d queued entries before the channel closed, got %d", subscriberBuffer, received)
+	}
+
+	missed := logger.EntriesAfter("exec-1", last.ID, 100)
+	if len(missed) != 10 || missed[0].Message != fmt.Sprintf("line %d", subscriberBuffer) {
+		t.Errorf("Expected the 10 missed entries after the last received one, got %d", len(missed))
+	}
+}
+
+func TestLogger_EntriesFiltered(t *testing.T) {
+	loggers := map[string]Logger{
+		"memory": NewMemoryLogger(),
+		"sqlite": NewSQLiteLogger(setupTestDB(t)),
+	}
+
+	for name, logger := range loggers {
+		t.Run(name, func(t *testing.T) {
+			logger.Write(LogEntry{ExecutionID: "exec-1", Level: Info, Message: "Charged card", Fields: map[string]any{"amount": 10.0, "user": "u_1"}, Line: 3})
+			logger.Write(LogEntry{ExecutionID: "exec-1", Level: Warn, Message: "Retrying 100% of batch", Fields: map[string]any{"amount": 25.5, "user": "u_2"}})
+			logger.Write(LogEntry{ExecutionID: "exec-1", Level: Error, Message: "Charge failed", Fields: map[string]any{"user": "42", "retry": true}})
+			logger.Info("exec-1", "plain message")
+
+			all, total := logger.EntriesFiltered("exec-1", Filter{}, 10, 0)
+			if total != 4 || len(all) != 4 {
+				t.Fatalf("Expected 4 entries, got %d (total %d)", len(all), total)
+			}
+			if all[0].Line != 3 || all[0].Fields["user"] != "u_1" {
+				t.Errorf("Expected fields and line to round-trip, got %+v", all[0])
+			}
+			if all[3].Fields != nil {
+				t.Errorf("Expected no fields, got %v", all[3].Fields)
+			}
+
+			tests := []struct {
+				name     string
+				filter   Filter
+				expected []string
+			}{
+				{"levels", Filter{Levels: []LogLevel{Warn, Error}}, []string{"Retrying 100% of batch", "Charge failed"}},
+				{"search is case-insensitive", Filter{Search: "CHARGE"}, []string{"Charged card", "Charge failed"}},
+				{"search escapes wildcards", Filter{Search: "100%"}, []string{"Retrying 100% of batch"}},
+				{"string field", Filter{Fields: map[string]string{"user": "u_2"}}, []string{"Retrying 100% of batch"}},
+				{"number field", Filter{Fields: map[string]string{"amount": "10"}}, []string{"Charged card"}},
+				{"decimal field", Filter{Fields: map[string]string{"amount": "25.5"}}, []string{"Retrying 100% of batch"}},
+				{"numeric string field", Filter{Fields: map[string]string{"user": "42"}}, []string{"Charge failed"}},
+				{"boolean field", Filter{Fields: map[string]string{"retry": "true"}}, []string{"Charge failed"}},
+				{"missing field", Filter{Fields: map[string]string{"missing": "x"}}, []string{}},
+				{"combined", Filter{Levels: []LogLevel{Info}, Fields: map[string]string{"user": "u_1"}}, []string{"Charged card"}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					entries, total := logger.EntriesFiltered("exec-1", tt.filter, 10, 0)
+					if int(total) != len(tt.expected) {
+						t.Errorf("Expected total %d, got %d", len(tt.expected), total)
+					}
+					messages := make([]string, len(entries))
+					for i, entry := range entries {
+						messages[i] = entry.Message
+					}
+					if strings.Join(messages, "|") != strings.Join(tt.expected, "|") {
+						t.Errorf("Expected %v, got %v", tt.expected, messages)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestLogger_WriteMasksFields(t *testing.T) {
+	logger := NewMemoryLogger()
+
+	logger.Write(LogEntry{
+		ExecutionID: "exec-1",
+		Level:       Info,
+		Message:     "login",
+		Fields: map[string]any{
+			"password": "hunter2",
+			"note":     "Bearer abc123.def456",
+			"nested":   map[string]any{"api_key": "sk_live_123"},
+		},
+	})
+
+	fields := logger.Entries("exec-1")[0].Fields
+	if fields["password"] != "[REDACTED]" {
+		t.Errorf("Expected password to be redacted, got %v", fields["password"])
+	}
+	if contains(fields["note"].(string), "abc123") {
+		t.Errorf("Expected bearer token to be masked, got %v", fields["note"])
+	}
+	if nested := fields["nested"].(map[string]any); nested["api_key"] != "[REDACTED]" {
+		t.Errorf("Expected nested api_key to be redacted, got %v", nested["api_key"])
+	}
+}
@@ -2,7 +2,9 @@ package masking
 
 import (
 	"encoding/json"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/dimiro1/lunar/internal/events"
@@ -135,6 +137,28 @@ func MaskQueryParams(query map[string]string) map[string]string {
 	return masked
 }
 
+// MaskURL masks sensitive query parameters and the userinfo password of a URL.
+// The order and encoding of the remaining query parameters are preserved.
+func MaskURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return MaskLogMessage(rawURL)
+	}
+
+	if parsed.RawQuery != "" {
+		params := strings.Split(parsed.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && IsSensitiveQueryParam(name) {
+				params[i] = key + "=" + redactedValue
+			}
+		}
+		parsed.RawQuery = strings.Join(params, "&")
+	}
+
+	return parsed.Redacted()
+}
+
 // MaskJSONBody attempts to parse the body as JSON and mask sensitive fields
 // If parsing fails, returns the original body unchanged
 func MaskJSONBody(body string) string {
@@ -186,6 +210,67 @@ func maskJSONValue(value any) any {
 	}
 }
 
+// maxInlineBinary is the length above which base64 data in JSON bodies is
+// replaced by a placeholder
+const maxInlineBinary = 256
+
+// base64Pattern matches standard and URL-safe base64 data
+var base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+
+// TruncateBinaryJSON replaces base64 data and base64 data URLs longer than
+// maxInlineBinary in a JSON body with a placeholder giving their length, so
+// images and documents do not bloat stored bodies. If parsing fails, returns
+// the original body unchanged.
+func TruncateBinaryJSON(body string) string {
+	if len(body) <= maxInlineBinary {
+		return body
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return body
+	}
+
+	truncated, err := json.Marshal(truncateBinaryValue(data))
+	if err != nil {
+		return body
+	}
+	return string(truncated)
+}
+
+// truncateBinaryValue recursively truncates base64 strings in JSON structures
+func truncateBinaryValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			v[key] = truncateBinaryValue(val)
+		}
+		return v
+	case []any:
+		for i, val := range v {
+			v[i] = truncateBinaryValue(val)
+		}
+		return v
+	case string:
+		if len(v) <= maxInlineBinary {
+			return v
+		}
+		// Data URLs keep their media type
+		prefix, data := "", v
+		if strings.HasPrefix(v, "data:") {
+			if i := strings.Index(v, ";base64,"); i >= 0 {
+				prefix, data = v[:i+len(";base64,")], v[i+len(";base64,"):]
+			}
+		}
+		if !base64Pattern.MatchString(data) {
+			return v
+		}
+		return prefix + "[" + strconv.Itoa(len(data)) + " base64 characters omitted]"
+	default:
+		return v
+	}
+}
+
 // MaskHTTPEvent creates a copy of the HTTPEvent with sensitive data masked
 func MaskHTTPEvent(event events.HTTPEvent) events.HTTPEvent {
 	return events.HTTPEvent{
@@ -197,6 +282,13 @@ func MaskHTTPEvent(event events.HTTPEvent) events.HTTPEvent {
 	}
 }
 
+// MaskEmailEvent creates a copy of the EmailEvent with sensitive headers
+// masked
+func MaskEmailEvent(event events.EmailEvent) events.EmailEvent {
+	event.Headers = MaskHeaders(event.Headers)
+	return event
+}
+
 // MaskLogMessage masks sensitive patterns in log messages
 func MaskLogMessage(message string) string {
 	masked := message
@@ -205,3 +297,39 @@ func MaskLogMessage(message string) string {
 	}
 	return masked
 }
+
+// MaskLogFields creates a copy of structured log fields with sensitive keys
+// redacted and sensitive patterns masked in string values
+func MaskLogFields(fields map[string]any) map[string]any {
+	if fields == nil {
+		return nil
+	}
+	masked, _ := maskLogValue(fields).(map[string]any)
+	return masked
+}
+
+// maskLogValue recursively masks a structured log field value
+func maskLogValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for key, val := range v {
+			if IsSensitiveBodyField(key) {
+				masked[key] = redactedValue
+			} else {
+				masked[key] = maskLogValue(val)
+			}
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(v))
+		for i, val := range v {
+			masked[i] = maskLogValue(val)
+		}
+		return masked
+	case string:
+		return MaskLogMessage(v)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,7 @@
+// Package metrics provides a minimal Prometheus-compatible metrics registry.
+//
+// It implements counters, gauges and histograms with labels and renders them
+// in the Prometheus text exposition format, without depending on the official
+// client library. The package-level metrics are recorded by the API server and
+// the Lua runtime and exposed on the optional /metrics endpoint.
+package metrics
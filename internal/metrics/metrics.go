@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Default is the registry used by the Lunar server
+var Default = NewRegistry()
+
+// Function execution metrics
+var (
+	ExecutionsTotal = Default.NewCounterVec(
+		"lunar_executions_total",
+		"Total number of function executions by function and status.",
+		"function_id", "status",
+	)
+	ExecutionDuration = Default.NewHistogramVec(
+		"lunar_execution_duration_seconds",
+		"Function execution duration in seconds by function and status.",
+		nil,
+		"function_id", "status",
+	)
+)
+
+// API server metrics recorded by LoggingMiddleware
+var (
+	HTTPRequestsTotal = Default.NewCounterVec(
+		"lunar_http_requests_total",
+		"Total number of HTTP requests served by route, method and status code.",
+		"route", "method", "status",
+	)
+	HTTPRequestDuration = Default.NewHistogramVec(
+		"lunar_http_request_duration_seconds",
+		"HTTP request duration in seconds by route and method.",
+		nil,
+		"route", "method",
+	)
+)
+
+// Outbound call metrics recorded by the Lua runtime
+var (
+	HTTPClientRequestsTotal = Default.NewCounterVec(
+		"lunar_http_client_requests_total",
+		"Total number of outbound HTTP requests made by functions by method and status code.",
+		"method", "status",
+	)
+	HTTPClientRequestDuration = Default.NewHistogramVec(
+		"lunar_http_client_request_duration_seconds",
+		"Outbound HTTP request duration in seconds by method.",
+		nil,
+		"method",
+	)
+	AIRequestsTotal = Default.NewCounterVec(
+		"lunar_ai_requests_total",
+		"Total number of AI provider requests by provider, model and status.",
+		"provider", "model", "status",
+	)
+	AIRequestDuration = Default.NewHistogramVec(
+		"lunar_ai_request_duration_seconds",
+		"AI provider request duration in seconds by provider and model.",
+		nil,
+		"provider", "model",
+	)
+	AITokensTotal = Default.NewCounterVec(
+		"lunar_ai_tokens_total",
+		"Total number of AI tokens used by provider, model and direction (input or output).",
+		"provider", "model", "direction",
+	)
+	AICostTotal = Default.NewCounterVec(
+		"lunar_ai_cost_usd_total",
+		"Total cost in USD of AI requests to priced models by provider and model.",
+		"provider", "model",
+	)
+	EmailRequestsTotal = Default.NewCounterVec(
+		"lunar_email_requests_total",
+		"Total number of email send requests by status.",
+		"status",
+	)
+	EmailRequestDuration = Default.NewHistogramVec(
+		"lunar_email_request_duration_seconds",
+		"Email send request duration in seconds.",
+		nil,
+	)
+	KVOperationsTotal = Default.NewCounterVec(
+		"lunar_kv_operations_total",
+		"Total number of KV store operations by operation and result.",
+		"operation", "result",
+	)
+)
+
+// startTime is the process start time reported by process_start_time_seconds
+var startTime = time.Now()
+
+func init() {
+	registerRuntimeMetrics(Default)
+}
+
+// registerRuntimeMetrics registers Go runtime and process gauges
+func registerRuntimeMetrics(r *Registry) {
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", func() float64 {
+		return float64(readMemStats().Alloc)
+	})
+	r.NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from the system.", func() float64 {
+		return float64(readMemStats().Sys)
+	})
+	r.NewGaugeFunc("go_memstats_heap_objects", "Number of allocated heap objects.", func() float64 {
+		return float64(readMemStats().HeapObjects)
+	})
+	r.NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", func() float64 {
+		return float64(readMemStats().HeapInuse)
+	})
+	r.NewCounterFunc("go_gc_cycles_total", "Number of completed GC cycles.", func() float64 {
+		return float64(readMemStats().NumGC)
+	})
+	r.NewCounterFunc("go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses in seconds.", func() float64 {
+		return float64(readMemStats().PauseTotalNs) / float64(time.Second)
+	})
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return float64(startTime.Unix())
+	})
+}
+
+// readMemStats reads the current runtime memory statistics
+func readMemStats() runtime.MemStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms
+}
+
+// StatusLabel converts an HTTP status code to a metric label value
+func StatusLabel(code int) string {
+	return strconv.Itoa(code)
+}
+
+// Handler returns an http.Handler that serves the registry in the Prometheus text format
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_ = r.WriteText(w)
+	})
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec_WriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_requests_total", "Total test requests.", "method", "status")
+
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(3, "POST", "500")
+	c.Add(-1, "POST", "500") // negative values are ignored
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := `# HELP test_requests_total Total test requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",status="200"} 2
+test_requests_total{method="POST",status="500"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	if got := c.Value("GET", "200"); got != 2 {
+		t.Errorf("expected value 2, got %v", got)
+	}
+	if got := c.Value("DELETE", "204"); got != 0 {
+		t.Errorf("expected value 0 for unknown series, got %v", got)
+	}
+}
+
+func TestHistogramVec_WriteText(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_duration_seconds", "Test durations.", []float64{1, 0.1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(2, "/a")
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := `# HELP test_duration_seconds Test durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/a",le="0.1"} 1
+test_duration_seconds_bucket{route="/a",le="1"} 2
+test_duration_seconds_bucket{route="/a",le="+Inf"} 3
+test_duration_seconds_sum{route="/a"} 2.55
+test_duration_seconds_count{route="/a"} 3
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+
+	if got := h.Count("/a"); got != 3 {
+		t.Errorf("expected count 3, got %d", got)
+	}
+}
+
+func TestHistogramVec_NoLabels(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{1})
+	h.Observe(0.5)
+
+	var buf bytes.Buffer
+	_ = r.WriteText(&buf)
+
+	if !strings.Contains(buf.String(), `test_latency_seconds_bucket{le="1"} 1`) {
+		t.Errorf("expected bucket without other labels, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "test_latency_seconds_count 1") {
+		t.Errorf("expected count without labels, got:\n%s", buf.String())
+	}
+}
+
+func TestLabelValueEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_escape_total", "Help with \\ backslash\nand newline.", "value")
+	c.Inc("quote\" backslash\\ newline\n")
+
+	var buf bytes.Buffer
+	_ = r.WriteText(&buf)
+
+	if !strings.Contains(buf.String(), `# HELP test_escape_total Help with \\ backslash\nand newline.`) {
+		t.Errorf("expected escaped help text, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `test_escape_total{value="quote\" backslash\\ newline\n"} 1`) {
+		t.Errorf("expected escaped label value, got:\n%s", buf.String())
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	value := 1.0
+	r.NewGaugeFunc("test_gauge", "A gauge.", func() float64 { return value })
+
+	value = 42
+	var buf bytes.Buffer
+	_ = r.WriteText(&buf)
+
+	if !strings.Contains(buf.String(), "# TYPE test_gauge gauge\ntest_gauge 42\n") {
+		t.Errorf("expected gauge value read on scrape, got:\n%s", buf.String())
+	}
+}
+
+func TestRegistry_DuplicateNamePanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_dup_total", "First.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate metric name")
+		}
+	}()
+	r.NewCounterVec("test_dup_total", "Second.")
+}
+
+func TestCounterVec_WrongLabelCountPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_labels_total", "Labels.", "a", "b")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on wrong number of label values")
+		}
+	}()
+	c.Inc("only-one")
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_handler_total", "Handler test.").Inc()
+
+	w := httptest.NewRecorder()
+	Handler(r).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	if !strings.Contains(w.Body.String(), "test_handler_total 1") {
+		t.Errorf("expected counter in body, got:\n%s", w.Body.String())
+	}
+}
+
+func TestDefaultRegistry_RuntimeMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Default.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	for _, name := range []string{"go_goroutines", "go_memstats_alloc_bytes", "go_gc_cycles_total", "process_start_time_seconds"} {
+		if !strings.Contains(buf.String(), "# TYPE "+name+" ") {
+			t.Errorf("expected runtime metric %s in output", name)
+		}
+	}
+}
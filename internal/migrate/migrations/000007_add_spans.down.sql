@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_spans_trace_id;
+DROP INDEX IF EXISTS idx_spans_execution_id;
+DROP TABLE IF EXISTS spans;
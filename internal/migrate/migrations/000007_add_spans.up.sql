@@ -0,0 +1,18 @@
+-- Execution trace spans
+CREATE TABLE IF NOT EXISTS spans (
+    id TEXT PRIMARY KEY,
+    trace_id TEXT NOT NULL,
+    parent_id TEXT,
+    execution_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error_message TEXT,
+    attributes_json TEXT NOT NULL DEFAULT '{}',
+    start_time INTEGER NOT NULL,
+    end_time INTEGER NOT NULL,
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_spans_execution_id ON spans(execution_id);
+CREATE INDEX IF NOT EXISTS idx_spans_trace_id ON spans(trace_id);
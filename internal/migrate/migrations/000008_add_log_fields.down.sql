@@ -0,0 +1,3 @@
+-- Remove structured fields and source line columns from logs table
+ALTER TABLE logs DROP COLUMN line;
+ALTER TABLE logs DROP COLUMN fields_json;
@@ -0,0 +1,3 @@
+-- Add structured fields and source line columns to logs table
+ALTER TABLE logs ADD COLUMN fields_json TEXT;
+ALTER TABLE logs ADD COLUMN line INTEGER;
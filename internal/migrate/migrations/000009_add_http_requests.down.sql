@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_http_requests_created_at;
+DROP INDEX IF EXISTS idx_http_requests_execution_id;
+DROP TABLE IF EXISTS http_requests;
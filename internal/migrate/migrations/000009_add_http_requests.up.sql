@@ -0,0 +1,21 @@
+-- HTTP Requests tracking table
+CREATE TABLE IF NOT EXISTS http_requests (
+    id TEXT PRIMARY KEY,
+    execution_id TEXT NOT NULL,
+    method TEXT NOT NULL,
+    url TEXT NOT NULL,
+    request_headers TEXT NOT NULL,
+    request_body TEXT,
+    status_code INTEGER,
+    response_headers TEXT,
+    response_body TEXT,
+    response_size INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    error_message TEXT,
+    duration_ms INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_http_requests_execution_id ON http_requests(execution_id);
+CREATE INDEX IF NOT EXISTS idx_http_requests_created_at ON http_requests(created_at);
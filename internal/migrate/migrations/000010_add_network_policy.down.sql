@@ -0,0 +1,2 @@
+-- Remove network_policy column from functions table
+ALTER TABLE functions DROP COLUMN network_policy;
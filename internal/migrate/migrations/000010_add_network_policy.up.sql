@@ -0,0 +1,2 @@
+-- Add network_policy column to functions table (JSON-encoded egress policy)
+ALTER TABLE functions ADD COLUMN network_policy TEXT;
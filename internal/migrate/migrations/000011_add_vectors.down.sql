@@ -0,0 +1,2 @@
+-- Remove vector collections
+DROP TABLE IF EXISTS vectors;
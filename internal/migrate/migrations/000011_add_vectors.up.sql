@@ -0,0 +1,11 @@
+-- Vector collections (embeddings with JSON metadata, per function)
+CREATE TABLE IF NOT EXISTS vectors (
+    function_id TEXT NOT NULL,
+    collection TEXT NOT NULL,
+    id TEXT NOT NULL,
+    dimensions INTEGER NOT NULL,
+    vector BLOB NOT NULL,
+    metadata TEXT NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (function_id, collection, id)
+);
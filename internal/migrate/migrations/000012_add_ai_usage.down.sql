@@ -0,0 +1,3 @@
+-- Remove AI usage accounting
+DROP TABLE IF EXISTS ai_usage;
+ALTER TABLE ai_requests DROP COLUMN cost;
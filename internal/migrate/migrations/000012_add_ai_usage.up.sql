@@ -0,0 +1,18 @@
+-- Add cost column to ai_requests table (USD, NULL when the model has no price)
+ALTER TABLE ai_requests ADD COLUMN cost REAL;
+
+-- Daily AI usage per function and model. Kept apart from ai_requests so
+-- budgets and reports outlive execution retention.
+CREATE TABLE IF NOT EXISTS ai_usage (
+    function_id TEXT NOT NULL,
+    day TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    requests INTEGER NOT NULL,
+    input_tokens INTEGER NOT NULL,
+    output_tokens INTEGER NOT NULL,
+    cost REAL NOT NULL,
+    PRIMARY KEY (function_id, day, provider, model)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_usage_day ON ai_usage(day);
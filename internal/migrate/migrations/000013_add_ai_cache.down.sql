@@ -0,0 +1,3 @@
+-- Remove the AI response cache
+DROP TABLE IF EXISTS ai_cache;
+ALTER TABLE ai_requests DROP COLUMN cached;
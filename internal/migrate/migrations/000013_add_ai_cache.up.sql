@@ -0,0 +1,18 @@
+-- Mark AI requests answered from the response cache
+ALTER TABLE ai_requests ADD COLUMN cached INTEGER NOT NULL DEFAULT 0;
+
+-- Cached AI chat responses per function, keyed by a hash of the request
+CREATE TABLE IF NOT EXISTS ai_cache (
+    function_id TEXT NOT NULL,
+    key TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    request_json TEXT NOT NULL,
+    response_json TEXT NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (function_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_cache_expires_at ON ai_cache(expires_at);
@@ -0,0 +1,2 @@
+-- Remove the email templates
+DROP TABLE IF EXISTS email_templates;
@@ -0,0 +1,9 @@
+-- Named email templates rendered by email.send
+CREATE TABLE IF NOT EXISTS email_templates (
+    name TEXT PRIMARY KEY,
+    subject TEXT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    html TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
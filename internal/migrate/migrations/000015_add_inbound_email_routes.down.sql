@@ -0,0 +1,3 @@
+-- Remove the inbound email routes
+DROP INDEX IF EXISTS idx_inbound_email_routes_function_id;
+DROP TABLE IF EXISTS inbound_email_routes;
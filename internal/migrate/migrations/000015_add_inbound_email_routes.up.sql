@@ -0,0 +1,9 @@
+-- Routes inbound email addresses to the functions that handle them
+CREATE TABLE IF NOT EXISTS inbound_email_routes (
+    address TEXT PRIMARY KEY,
+    function_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (function_id) REFERENCES functions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_email_routes_function_id ON inbound_email_routes(function_id);
@@ -0,0 +1,5 @@
+-- Remove the email delivery status
+DROP INDEX IF EXISTS idx_email_delivery_events_email_request_id;
+DROP TABLE IF EXISTS email_delivery_events;
+DROP INDEX IF EXISTS idx_email_requests_email_id;
+ALTER TABLE email_requests DROP COLUMN delivery_status;
@@ -0,0 +1,16 @@
+-- Delivery status of sent emails, reported by provider webhooks
+ALTER TABLE email_requests ADD COLUMN delivery_status TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_email_requests_email_id ON email_requests(email_id);
+
+CREATE TABLE IF NOT EXISTS email_delivery_events (
+    id TEXT PRIMARY KEY,
+    email_request_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    detail TEXT,
+    occurred_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    FOREIGN KEY (email_request_id) REFERENCES email_requests(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_delivery_events_email_request_id ON email_delivery_events(email_request_id);
@@ -42,9 +42,10 @@ func EnhanceError(err error, sourceCode string) error {
 	return formatEnhancedError(errMsg, lineNum, codeContext, suggestion)
 }
 
-// extractLineNumber parses the line number from Lua error messages
+// extractLineNumber parses the line number from Lua and template error messages
 // Example: "<string>:7:" -> 7
 // Example: "<string> line:6(column:33)" -> 6
+// Example: "template: main:3: ..." -> 3
 func extractLineNumber(errMsg string) int {
 	// Try format: <string>:7:
 	re := regexp.MustCompile(`<string>:(\d+):`)
@@ -66,11 +67,22 @@ func extractLineNumber(errMsg string) int {
 		}
 	}
 
+	// Try format: template: main:3: or html/template:main:3:12:
+	re = regexp.MustCompile(`template:\s?[^:\s]+:(\d+)`)
+	matches = re.FindStringSubmatch(errMsg)
+	if len(matches) > 1 {
+		var lineNum int
+		if _, err := fmt.Sscanf(matches[1], "%d", &lineNum); err == nil {
+			return lineNum
+		}
+	}
+
 	return 0
 }
 
-// extractColumnNumber parses the column number from Lua error messages
+// extractColumnNumber parses the column number from Lua and template error messages
 // Example: "<string> line:6(column:33)" -> 33
+// Example: "template: main:2:28: ..." -> 29 (templates report a 0-based offset)
 func extractColumnNumber(errMsg string) int {
 	re := regexp.MustCompile(`column:(\d+)`)
 	matches := re.FindStringSubmatch(errMsg)
@@ -80,6 +92,21 @@ func extractColumnNumber(errMsg string) int {
 			return colNum
 		}
 	}
+
+	// A template error re-raised from Lua is reported at the Lua line, where
+	// the template column does not apply
+	if regexp.MustCompile(`<string>(:| line:)\d+`).MatchString(errMsg) {
+		return 0
+	}
+
+	re = regexp.MustCompile(`template:\s?[^:\s]+:\d+:(\d+):`)
+	matches = re.FindStringSubmatch(errMsg)
+	if len(matches) > 1 {
+		var offset int
+		if _, err := fmt.Sscanf(matches[1], "%d", &offset); err == nil {
+			return offset + 1
+		}
+	}
 	return 0
 }
 
@@ -137,6 +164,7 @@ func detectErrorPattern(errMsg string) string {
 		{`attempt to compare`, "compare_error"},
 		{`handler function not found`, "no_handler"},
 		{`handler did not return a table`, "bad_return"},
+		{`template:`, "template_error"},
 	}
 
 	for _, p := range patterns {
@@ -213,6 +241,12 @@ func generateSuggestion(pattern string) string {
   • statusCode is required (number)
   • body is optional (string)
   • headers is optional (table)`,
+
+		"template_error": `[TIP] The template could not be rendered.
+  • Fields are accessed with a dot: {{.name}}, {{.user.email}}
+  • Blocks need an {{end}}: {{if .x}}...{{end}}, {{range .items}}...{{end}}
+  • Partials must be passed in options.partials to use {{template "name" .}}
+  • Line numbers refer to the template named in the error`,
 	}
 
 	if suggestion, ok := suggestions[pattern]; ok {
@@ -408,6 +408,8 @@ func TestExtractLineNumber(t *testing.T) {
 		{"<string>:1: first line error", 1},
 		{"<string> line:6(column:33) near '=': syntax error", 6},
 		{"<string> line:42(column:10) near 'end': syntax error", 42},
+		{`template: main:3: function "nope" not defined`, 3},
+		{`html/template:header:2:11: no such template "footer"`, 2},
 		{"error without line number", 0},
 		{"", 0},
 	}
@@ -420,6 +422,27 @@ func TestExtractLineNumber(t *testing.T) {
 	}
 }
 
+func TestExtractColumnNumber(t *testing.T) {
+	tests := []struct {
+		errMsg   string
+		expected int
+	}{
+		{"<string> line:6(column:33) near '=': syntax error", 33},
+		{`template: main:2:28: executing "main" at <.name>: error`, 29},
+		{`html/template:header:1:11: no such template "footer"`, 12},
+		{`<string>:4: template: main:2:28: executing "main" at <.name>: error`, 0},
+		{"<string>:7: some error", 0},
+		{"error without column number", 0},
+	}
+
+	for _, tt := range tests {
+		result := extractColumnNumber(tt.errMsg)
+		if result != tt.expected {
+			t.Errorf("extractColumnNumber(%q) = %d, expected %d", tt.errMsg, result, tt.expected)
+		}
+	}
+}
+
 func TestExtractCodeContext(t *testing.T) {
 	sourceCode := `line 1
 line 2
@@ -478,6 +501,7 @@ func TestDetectErrorPattern(t *testing.T) {
 		{"attempt to compare string with number", "compare_error"},
 		{"handler function not found in Lua code", "no_handler"},
 		{"handler did not return a table", "bad_return"},
+		{`template: main:1: unexpected EOF`, "template_error"},
 		{"some unknown error", "unknown"},
 	}
 
@@ -501,6 +525,7 @@ func TestGenerateSuggestion(t *testing.T) {
 		"compare_error",
 		"no_handler",
 		"bad_return",
+		"template_error",
 	}
 
 	for _, pattern := range patterns {
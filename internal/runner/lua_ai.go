@@ -1,87 +1,294 @@
 package runner
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/dimiro1/lunar/internal/ai"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/jsonschema"
+	"github.com/dimiro1/lunar/internal/metrics"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// defaultSchemaRetries is the number of times ai.chat asks again for a reply
+// that does not match the schema, unless retries is set
+const defaultSchemaRetries = 1
+
+// schemaNamePattern matches the reply format names accepted by providers
+var schemaNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// defaultMaxToolRounds is the number of model round trips ai.run allows
+// before giving up, unless max_rounds is set
+const defaultMaxToolRounds = 10
+
 // registerAI creates the global 'ai' table with AI provider functions
-func registerAI(L *lua.LState, client ai.Client, functionID string, tracker ai.Tracker, executionID string) {
+func registerAI(L *lua.LState, client ai.Client, functionID string, policy *internalhttp.Policy, tracker ai.Tracker, executionID string, trace *tracing.Trace, response *streamedResponse) {
 	aiTable := L.NewTable()
 
+	// chat sends one request, recording a span and tracking the round trip
+	chat := func(req ai.ChatRequest) (*ai.ChatResponse, string) {
+		span := trace.Start("ai.chat", store.SpanKindClient, map[string]string{
+			"gen_ai.system":        req.Provider,
+			"gen_ai.request.model": req.Model,
+		})
+		defer span.End()
+
+		// Execute the request with tracking
+		req.Policy = policy
+		response, trackReq := executeWithTracking(client, functionID, req)
+
+		if trackReq.InputTokens != nil && trackReq.OutputTokens != nil {
+			span.SetAttribute("gen_ai.usage.input_tokens", strconv.Itoa(*trackReq.InputTokens))
+			span.SetAttribute("gen_ai.usage.output_tokens", strconv.Itoa(*trackReq.OutputTokens))
+		}
+		if trackReq.Cached {
+			span.SetAttribute("ai.cache.hit", "true")
+		}
+
+		// Track the request (success or error)
+		if tracker != nil {
+			tracker.Track(executionID, trackReq)
+		}
+
+		if trackReq.Status == store.AIRequestStatusError {
+			span.SetError(*trackReq.ErrorMessage)
+			return nil, *trackReq.ErrorMessage
+		}
+		return response, ""
+	}
+
 	// ai.chat(options)
 	L.SetField(aiTable, "chat", L.NewFunction(func(L *lua.LState) int {
 		options := L.CheckTable(1)
 
-		// Extract required parameters
-		provider := lua.LVAsString(options.RawGetString("provider"))
-		model := lua.LVAsString(options.RawGetString("model"))
-		messagesLV := options.RawGetString("messages")
-
-		// Validate required parameters
-		if provider == "" {
+		req, _, errMsg := luaChatRequest(L, options, response)
+		if errMsg != "" {
 			L.Push(lua.LNil)
-			L.Push(lua.LString("provider is required (openai or anthropic)"))
+			L.Push(lua.LString(errMsg))
 			return 2
 		}
-		if model == "" {
+
+		if req.Schema == nil {
+			response, errMsg := chat(req)
+			if errMsg != "" {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(errMsg))
+				return 2
+			}
+
+			// Convert response to Lua table
+			L.Push(aiResponseToLuaTable(L, response))
 			L.Push(lua.LNil)
-			L.Push(lua.LString("model is required"))
 			return 2
 		}
-		if messagesLV.Type() != lua.LTTable {
-			L.Push(lua.LNil)
-			L.Push(lua.LString("messages is required and must be a table"))
-			return 2
+
+		retries := defaultSchemaRetries
+		if v, ok := options.RawGetString("retries").(lua.LNumber); ok {
+			retries = max(int(v), 0)
+		}
+
+		// Replies that do not match the schema are sent back with the
+		// validation error, asking the model to correct them
+		var usage ai.Usage
+		for attempt := 1; ; attempt++ {
+			response, errMsg := chat(req)
+			if errMsg != "" {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(errMsg))
+				return 2
+			}
+			usage.InputTokens += response.Usage.InputTokens
+			usage.OutputTokens += response.Usage.OutputTokens
+
+			data, err := decodeStructuredReply(req.Schema, response.Content)
+			if err == nil {
+				response.Usage = usage
+				result := aiResponseToLuaTable(L, response)
+				L.SetField(result, "data", goValueToLua(L, data))
+				L.SetField(result, "attempts", lua.LNumber(attempt))
+				L.Push(result)
+				L.Push(lua.LNil)
+				return 2
+			}
+			if attempt > retries {
+				L.Push(lua.LNil)
+				L.Push(lua.LString("response does not match schema: " + err.Error()))
+				return 2
+			}
+
+			req.Messages = append(req.Messages,
+				ai.Message{Role: "assistant", Content: response.Content},
+				ai.Message{Role: "user", Content: fmt.Sprintf(
+					"Your reply does not match the schema: %v. Reply again with only a JSON object that matches the schema.", err)},
+			)
 		}
+	}))
+
+	// ai.run(options) calls the model and runs the handler of every tool it
+	// requests, sending the results back until the model answers without
+	// calling tools
+	L.SetField(aiTable, "run", L.NewFunction(func(L *lua.LState) int {
+		options := L.CheckTable(1)
 
-		// Convert messages from Lua to Go
-		messages := luaMessagesToGo(messagesLV.(*lua.LTable))
-		if len(messages) == 0 {
+		req, handlers, errMsg := luaChatRequest(L, options, response)
+		if errMsg == "" && len(req.Tools) == 0 {
+			errMsg = "tools is required"
+		}
+		if errMsg == "" && req.Schema != nil {
+			errMsg = "schema is not supported by ai.run, use ai.chat with the final messages"
+		}
+		for _, tool := range req.Tools {
+			if errMsg == "" && handlers[tool.Name] == nil {
+				errMsg = fmt.Sprintf("tool %q has no handler", tool.Name)
+			}
+		}
+		if errMsg != "" {
 			L.Push(lua.LNil)
-			L.Push(lua.LString("messages cannot be empty"))
+			L.Push(lua.LString(errMsg))
 			return 2
 		}
 
-		// Extract optional parameters
-		maxTokens := int(lua.LVAsNumber(options.RawGetString("max_tokens")))
-		temperature := lua.LVAsNumber(options.RawGetString("temperature"))
-		endpoint := lua.LVAsString(options.RawGetString("endpoint"))
+		maxRounds := int(lua.LVAsNumber(options.RawGetString("max_rounds")))
+		if maxRounds <= 0 {
+			maxRounds = defaultMaxToolRounds
+		}
+
+		span := trace.Start("ai.run", store.SpanKindInternal, map[string]string{
+			"gen_ai.system":        req.Provider,
+			"gen_ai.request.model": req.Model,
+		})
+		defer span.End()
 
-		// Set defaults for optional parameters
-		if maxTokens == 0 {
-			maxTokens = 1024
+		var usage ai.Usage
+		for round := 1; round <= maxRounds; round++ {
+			response, errMsg := chat(req)
+			if errMsg != "" {
+				span.SetError(errMsg)
+				L.Push(lua.LNil)
+				L.Push(lua.LString(errMsg))
+				return 2
+			}
+			usage.InputTokens += response.Usage.InputTokens
+			usage.OutputTokens += response.Usage.OutputTokens
+
+			req.Messages = append(req.Messages, ai.Message{
+				Role:      "assistant",
+				Content:   response.Content,
+				ToolCalls: response.ToolCalls,
+			})
+
+			if len(response.ToolCalls) == 0 {
+				span.SetAttribute("ai.rounds", strconv.Itoa(round))
+				response.Usage = usage
+				result := aiResponseToLuaTable(L, response)
+				L.SetField(result, "messages", goMessagesToLua(L, req.Messages))
+				L.SetField(result, "rounds", lua.LNumber(round))
+				L.Push(result)
+				L.Push(lua.LNil)
+				return 2
+			}
+
+			for _, call := range response.ToolCalls {
+				req.Messages = append(req.Messages, ai.Message{
+					Role:       "tool",
+					Content:    runToolHandler(L, trace, handlers[call.Name], call),
+					ToolCallID: call.ID,
+				})
+			}
 		}
 
-		// Build chat request
-		req := ai.ChatRequest{
-			Provider:    provider,
-			Model:       model,
-			Messages:    messages,
-			MaxTokens:   maxTokens,
-			Temperature: float64(temperature),
-			Endpoint:    endpoint,
+		errMsg = fmt.Sprintf("model still requested tools after %d rounds", maxRounds)
+		span.SetError(errMsg)
+		L.Push(lua.LNil)
+		L.Push(lua.LString(errMsg))
+		return 2
+	}))
+
+	// ai.embed(texts, options)
+	L.SetField(aiTable, "embed", L.NewFunction(func(L *lua.LState) int {
+		var input []string
+		switch v := L.CheckAny(1).(type) {
+		case lua.LString:
+			input = []string{string(v)}
+		case *lua.LTable:
+			for i := 1; i <= v.Len(); i++ {
+				text, ok := v.RawGetInt(i).(lua.LString)
+				if !ok {
+					L.ArgError(1, "texts must be a string or a list of strings")
+					return 0
+				}
+				input = append(input, string(text))
+			}
+		default:
+			L.ArgError(1, "texts must be a string or a list of strings")
+			return 0
 		}
+		options := L.CheckTable(2)
 
-		// Execute the request with tracking
-		response, trackReq := executeWithTracking(client, functionID, req)
+		req := ai.EmbedRequest{
+			Provider: lua.LVAsString(options.RawGetString("provider")),
+			Model:    lua.LVAsString(options.RawGetString("model")),
+			Input:    input,
+			Endpoint: lua.LVAsString(options.RawGetString("endpoint")),
+			Policy:   policy,
+		}
+		errMsg := ""
+		switch {
+		case req.Provider == "":
+			errMsg = "provider is required (openai, ollama or a profile name)"
+		case req.Model == "":
+			errMsg = "model is required"
+		case len(input) == 0:
+			errMsg = "texts cannot be empty"
+		}
+		if errMsg != "" {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(errMsg))
+			return 2
+		}
 
-		// Track the request (success or error)
+		span := trace.Start("ai.embed", store.SpanKindClient, map[string]string{
+			"gen_ai.system":        req.Provider,
+			"gen_ai.request.model": req.Model,
+		})
+		defer span.End()
+
+		response, trackReq := executeEmbedWithTracking(client, functionID, req)
+		if trackReq.InputTokens != nil {
+			span.SetAttribute("gen_ai.usage.input_tokens", strconv.Itoa(*trackReq.InputTokens))
+		}
 		if tracker != nil {
 			tracker.Track(executionID, trackReq)
 		}
-
 		if trackReq.Status == store.AIRequestStatusError {
+			span.SetError(*trackReq.ErrorMessage)
 			L.Push(lua.LNil)
 			L.Push(lua.LString(*trackReq.ErrorMessage))
 			return 2
 		}
 
-		// Convert response to Lua table
-		L.Push(aiResponseToLuaTable(L, response))
+		embeddings := L.NewTable()
+		for _, embedding := range response.Embeddings {
+			vector := L.CreateTable(len(embedding), 0)
+			for _, v := range embedding {
+				vector.Append(lua.LNumber(v))
+			}
+			embeddings.Append(vector)
+		}
+		result := L.NewTable()
+		L.SetField(result, "embeddings", embeddings)
+		L.SetField(result, "model", lua.LString(response.Model))
+		usageTbl := L.NewTable()
+		L.SetField(usageTbl, "input_tokens", lua.LNumber(response.Usage.InputTokens))
+		L.SetField(result, "usage", usageTbl)
+
+		L.Push(result)
 		L.Push(lua.LNil)
 		return 2
 	}))
@@ -89,23 +296,289 @@ func registerAI(L *lua.LState, client ai.Client, functionID string, tracker ai.T
 	L.SetGlobal("ai", aiTable)
 }
 
+// luaChatRequest builds a chat request from the options table shared by
+// ai.chat and ai.run. It also returns the tool handlers by tool name and a
+// message describing the first invalid option.
+func luaChatRequest(L *lua.LState, options *lua.LTable, response *streamedResponse) (ai.ChatRequest, map[string]*lua.LFunction, string) {
+	// Extract required parameters
+	provider := lua.LVAsString(options.RawGetString("provider"))
+	model := lua.LVAsString(options.RawGetString("model"))
+	messagesLV := options.RawGetString("messages")
+
+	// Validate required parameters
+	if provider == "" {
+		return ai.ChatRequest{}, nil, "provider is required (openai, anthropic, gemini, ollama or a profile name)"
+	}
+	if model == "" {
+		return ai.ChatRequest{}, nil, "model is required"
+	}
+	if messagesLV.Type() != lua.LTTable {
+		return ai.ChatRequest{}, nil, "messages is required and must be a table"
+	}
+
+	// Convert messages from Lua to Go
+	messages, errMsg := luaMessagesToGo(L, messagesLV.(*lua.LTable))
+	if errMsg != "" {
+		return ai.ChatRequest{}, nil, errMsg
+	}
+	if len(messages) == 0 {
+		return ai.ChatRequest{}, nil, "messages cannot be empty"
+	}
+
+	var tools []ai.Tool
+	var handlers map[string]*lua.LFunction
+	if toolsLV := options.RawGetString("tools"); toolsLV != lua.LNil {
+		toolsTbl, ok := toolsLV.(*lua.LTable)
+		if !ok {
+			return ai.ChatRequest{}, nil, "tools must be a table"
+		}
+		var errMsg string
+		tools, handlers, errMsg = luaToolsToGo(L, toolsTbl)
+		if errMsg != "" {
+			return ai.ChatRequest{}, nil, errMsg
+		}
+	}
+
+	var schema *ai.ResponseSchema
+	if schemaLV := options.RawGetString("schema"); schemaLV != lua.LNil {
+		schemaMap, ok := luaValueToGo(L, schemaLV).(map[string]any)
+		if !ok {
+			return ai.ChatRequest{}, nil, "schema must be a JSON schema object"
+		}
+		name := "response"
+		if v := lua.LVAsString(options.RawGetString("schema_name")); v != "" {
+			name = v
+		}
+		if !schemaNamePattern.MatchString(name) {
+			return ai.ChatRequest{}, nil, "schema_name must be 1 to 64 letters, digits, underscores or dashes"
+		}
+		schema = &ai.ResponseSchema{Name: name, Schema: schemaMap}
+	}
+
+	// Streamed text goes to on_token, or straight to the streamed HTTP
+	// response without one
+	var onToken func(string) error
+	switch fn := options.RawGetString("on_token").(type) {
+	case *lua.LFunction:
+		onToken = func(token string) error {
+			err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, lua.LString(token))
+			if apiErr, ok := err.(*lua.ApiError); ok {
+				return fmt.Errorf("on_token failed: %s", apiErr.Object.String())
+			}
+			return err
+		}
+	case *lua.LNilType:
+		if lua.LVAsBool(options.RawGetString("stream")) {
+			if !response.available() {
+				return ai.ChatRequest{}, nil, "on_token is required when the response cannot be streamed"
+			}
+			onToken = response.write
+		}
+	default:
+		return ai.ChatRequest{}, nil, "on_token must be a function"
+	}
+
+	// cache = true uses the default TTL, cache = {ttl = seconds} sets it
+	var cacheTTL time.Duration
+	switch cache := options.RawGetString("cache").(type) {
+	case *lua.LNilType:
+	case lua.LBool:
+		if cache {
+			cacheTTL = ai.DefaultCacheTTL
+		}
+	case *lua.LTable:
+		cacheTTL = ai.DefaultCacheTTL
+		if ttl := cache.RawGetString("ttl"); ttl != lua.LNil {
+			seconds, ok := ttl.(lua.LNumber)
+			if !ok || seconds <= 0 {
+				return ai.ChatRequest{}, nil, "cache.ttl must be a positive number of seconds"
+			}
+			cacheTTL = time.Duration(float64(seconds) * float64(time.Second))
+		}
+	default:
+		return ai.ChatRequest{}, nil, "cache must be a boolean or a table"
+	}
+
+	// Extract optional parameters
+	maxTokens := int(lua.LVAsNumber(options.RawGetString("max_tokens")))
+	temperature := lua.LVAsNumber(options.RawGetString("temperature"))
+	endpoint := lua.LVAsString(options.RawGetString("endpoint"))
+
+	// Set defaults for optional parameters
+	if maxTokens == 0 {
+		maxTokens = 1024
+	}
+
+	return ai.ChatRequest{
+		Provider:    provider,
+		Model:       model,
+		Messages:    messages,
+		Tools:       tools,
+		Schema:      schema,
+		MaxTokens:   maxTokens,
+		Temperature: float64(temperature),
+		Endpoint:    endpoint,
+		OnToken:     onToken,
+		CacheTTL:    cacheTTL,
+	}, handlers, ""
+}
+
+// decodeStructuredReply decodes a JSON reply and validates it against the
+// response schema
+func decodeStructuredReply(schema *ai.ResponseSchema, content string) (any, error) {
+	var data any
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON: %v", err)
+	}
+	if err := jsonschema.Validate(schema.Schema, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// luaToolsToGo converts a list of tool definitions, each a table with name,
+// description, parameters (a JSON schema) and an optional handler function
+func luaToolsToGo(L *lua.LState, tbl *lua.LTable) ([]ai.Tool, map[string]*lua.LFunction, string) {
+	var tools []ai.Tool
+	handlers := make(map[string]*lua.LFunction)
+	for i := 1; i <= tbl.Len(); i++ {
+		toolTbl, ok := tbl.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return nil, nil, fmt.Sprintf("tool %d must be a table", i)
+		}
+
+		tool := ai.Tool{
+			Name:        lua.LVAsString(toolTbl.RawGetString("name")),
+			Description: lua.LVAsString(toolTbl.RawGetString("description")),
+		}
+		if tool.Name == "" {
+			return nil, nil, fmt.Sprintf("tool %d requires a name", i)
+		}
+		if _, ok := handlers[tool.Name]; ok {
+			return nil, nil, fmt.Sprintf("tool %q is defined more than once", tool.Name)
+		}
+
+		if params := toolTbl.RawGetString("parameters"); params != lua.LNil {
+			schema, ok := luaValueToGo(L, params).(map[string]any)
+			if !ok {
+				return nil, nil, fmt.Sprintf("parameters of tool %q must be a JSON schema object", tool.Name)
+			}
+			tool.Parameters = schema
+		}
+
+		handler, _ := toolTbl.RawGetString("handler").(*lua.LFunction)
+		handlers[tool.Name] = handler
+		tools = append(tools, tool)
+	}
+	return tools, handlers, ""
+}
+
+// runToolHandler calls the handler of a tool call with the decoded arguments
+// and the call table, and returns the content of the tool result message.
+// Strings are sent as they are and other values as JSON; a handler returning
+// nil, err reports the error to the model so it can recover.
+func runToolHandler(L *lua.LState, trace *tracing.Trace, handler *lua.LFunction, call ai.ToolCall) string {
+	span := trace.Start("ai.tool", store.SpanKindInternal, map[string]string{
+		"gen_ai.tool.name":    call.Name,
+		"gen_ai.tool.call.id": call.ID,
+	})
+	defer span.End()
+
+	if handler == nil {
+		msg := fmt.Sprintf("unknown tool %q", call.Name)
+		span.SetError(msg)
+		return "error: " + msg
+	}
+
+	callTbl := toolCallToLuaTable(L, call)
+	L.Push(handler)
+	L.Push(callTbl.RawGetString("arguments"))
+	L.Push(callTbl)
+	L.Call(2, 2)
+	result, errValue := L.Get(-2), L.Get(-1)
+	L.Pop(2)
+
+	if errValue != lua.LNil {
+		span.SetError(errValue.String())
+		return "error: " + errValue.String()
+	}
+	switch v := result.(type) {
+	case *lua.LNilType:
+		return ""
+	case lua.LString:
+		return string(v)
+	default:
+		encoded, err := json.Marshal(luaValueToGo(L, v))
+		if err != nil {
+			span.SetError(err.Error())
+			return "error: " + err.Error()
+		}
+		return string(encoded)
+	}
+}
+
+// aiExchange is the tracking info and usage of an AI response
+type aiExchange struct {
+	endpoint     string
+	requestJSON  string
+	responseJSON string
+	usage        ai.Usage
+	cost         *float64
+	cached       bool
+}
+
 // executeWithTracking executes an AI chat request and returns tracking info
 func executeWithTracking(client ai.Client, functionID string, req ai.ChatRequest) (*ai.ChatResponse, ai.TrackRequest) {
+	response, err := (*ai.ChatResponse)(nil), error(nil)
+	trackReq := trackAICall(req.Provider, req.Model, func() (*aiExchange, error) {
+		response, err = client.Chat(functionID, req)
+		if response == nil {
+			return nil, err
+		}
+		return &aiExchange{response.Endpoint, response.RequestJSON, response.ResponseJSON, response.Usage, response.Cost, response.Cached}, err
+	})
+	if err != nil {
+		return nil, trackReq
+	}
+	return response, trackReq
+}
+
+// executeEmbedWithTracking executes an AI embeddings request and returns tracking info
+func executeEmbedWithTracking(client ai.Client, functionID string, req ai.EmbedRequest) (*ai.EmbedResponse, ai.TrackRequest) {
+	response, err := (*ai.EmbedResponse)(nil), error(nil)
+	trackReq := trackAICall(req.Provider, req.Model, func() (*aiExchange, error) {
+		response, err = client.Embed(functionID, req)
+		if response == nil {
+			return nil, err
+		}
+		return &aiExchange{response.Endpoint, response.RequestJSON, response.ResponseJSON, response.Usage, response.Cost, false}, err
+	})
+	if err != nil {
+		return nil, trackReq
+	}
+	return response, trackReq
+}
+
+// trackAICall times an AI request and builds its tracking record, including
+// the tracking info of the response even when the request failed
+func trackAICall(provider, model string, call func() (*aiExchange, error)) ai.TrackRequest {
 	trackReq := ai.TrackRequest{
-		Provider: req.Provider,
-		Model:    req.Model,
+		Provider: provider,
+		Model:    model,
 	}
 
 	startTime := time.Now()
-	response, err := client.Chat(functionID, req)
-	trackReq.DurationMs = time.Since(startTime).Milliseconds()
+	exchange, err := call()
+	duration := time.Since(startTime)
+	trackReq.DurationMs = duration.Milliseconds()
+	metrics.AIRequestDuration.Observe(duration.Seconds(), provider, model)
 
 	// Capture tracking info from response even on error (if available)
-	if response != nil {
-		trackReq.Endpoint = response.Endpoint
-		trackReq.RequestJSON = response.RequestJSON
-		if response.ResponseJSON != "" {
-			trackReq.ResponseJSON = &response.ResponseJSON
+	if exchange != nil {
+		trackReq.Endpoint = exchange.endpoint
+		trackReq.RequestJSON = exchange.requestJSON
+		if exchange.responseJSON != "" {
+			trackReq.ResponseJSON = &exchange.responseJSON
 		}
 	}
 
@@ -113,38 +586,203 @@ func executeWithTracking(client ai.Client, functionID string, req ai.ChatRequest
 		errMsg := err.Error()
 		trackReq.Status = store.AIRequestStatusError
 		trackReq.ErrorMessage = &errMsg
-		return nil, trackReq
+		metrics.AIRequestsTotal.Inc(provider, model, string(trackReq.Status))
+		return trackReq
 	}
 
 	trackReq.Status = store.AIRequestStatusSuccess
-	trackReq.InputTokens = &response.Usage.InputTokens
-	trackReq.OutputTokens = &response.Usage.OutputTokens
+	trackReq.Cached = exchange.cached
+	trackReq.InputTokens = &exchange.usage.InputTokens
+	trackReq.OutputTokens = &exchange.usage.OutputTokens
+	metrics.AIRequestsTotal.Inc(provider, model, string(trackReq.Status))
+	metrics.AITokensTotal.Add(float64(exchange.usage.InputTokens), provider, model, "input")
+	metrics.AITokensTotal.Add(float64(exchange.usage.OutputTokens), provider, model, "output")
+	if exchange.cost != nil {
+		trackReq.Cost = exchange.cost
+		metrics.AICostTotal.Add(*exchange.cost, provider, model)
+	}
 
-	return response, trackReq
+	return trackReq
 }
 
-// luaMessagesToGo converts a Lua table of messages to Go
-func luaMessagesToGo(tbl *lua.LTable) []ai.Message {
+// luaMessagesToGo converts a Lua table of messages to Go. Assistant
+// messages may list tool_calls and tool messages carry a tool_call_id.
+// Content is a string or a list of content parts.
+func luaMessagesToGo(L *lua.LState, tbl *lua.LTable) ([]ai.Message, string) {
 	var messages []ai.Message
-	tbl.ForEach(func(_, v lua.LValue) {
-		if msgTbl, ok := v.(*lua.LTable); ok {
+	var errMsg string
+	tbl.ForEach(func(k, v lua.LValue) {
+		if msgTbl, ok := v.(*lua.LTable); ok && errMsg == "" {
 			msg := ai.Message{
-				Role:    lua.LVAsString(msgTbl.RawGetString("role")),
-				Content: lua.LVAsString(msgTbl.RawGetString("content")),
+				Role:       lua.LVAsString(msgTbl.RawGetString("role")),
+				ToolCallID: lua.LVAsString(msgTbl.RawGetString("tool_call_id")),
+			}
+			if partsTbl, ok := msgTbl.RawGetString("content").(*lua.LTable); ok {
+				if msg.Parts, errMsg = luaPartsToGo(partsTbl); errMsg != "" {
+					errMsg = fmt.Sprintf("message %s: %s", k.String(), errMsg)
+					return
+				}
+			} else {
+				msg.Content = lua.LVAsString(msgTbl.RawGetString("content"))
 			}
-			if msg.Role != "" && msg.Content != "" {
+			if calls, ok := msgTbl.RawGetString("tool_calls").(*lua.LTable); ok {
+				for i := 1; i <= calls.Len(); i++ {
+					if callTbl, ok := calls.RawGetInt(i).(*lua.LTable); ok {
+						msg.ToolCalls = append(msg.ToolCalls, luaToolCallToGo(L, callTbl))
+					}
+				}
+			}
+			if msg.Role != "" && (msg.Content != "" || len(msg.Parts) > 0 || len(msg.ToolCalls) > 0 || msg.ToolCallID != "") {
 				messages = append(messages, msg)
 			}
 		}
 	})
-	return messages
+	if errMsg != "" {
+		return nil, errMsg
+	}
+	return messages, ""
+}
+
+// luaPartsToGo converts a list of content parts. A string is a text part,
+// tables have a type (text, image or document) and the fields it needs.
+func luaPartsToGo(tbl *lua.LTable) ([]ai.Part, string) {
+	var parts []ai.Part
+	for i := 1; i <= tbl.Len(); i++ {
+		var part ai.Part
+		switch v := tbl.RawGetInt(i).(type) {
+		case lua.LString:
+			part = ai.Part{Type: ai.PartText, Text: string(v)}
+		case *lua.LTable:
+			part = ai.Part{
+				Type:      lua.LVAsString(v.RawGetString("type")),
+				Text:      lua.LVAsString(v.RawGetString("text")),
+				Data:      lua.LVAsString(v.RawGetString("data")),
+				URL:       lua.LVAsString(v.RawGetString("url")),
+				MediaType: lua.LVAsString(v.RawGetString("media_type")),
+				Name:      lua.LVAsString(v.RawGetString("name")),
+			}
+		default:
+			return nil, fmt.Sprintf("content part %d must be a string or a table", i)
+		}
+		if err := part.Validate(); err != nil {
+			return nil, fmt.Sprintf("content part %d: %v", i, err)
+		}
+		parts = append(parts, part)
+	}
+	return parts, ""
+}
+
+// luaToolCallToGo converts a tool call table; arguments may be a table or
+// a JSON string
+func luaToolCallToGo(L *lua.LState, tbl *lua.LTable) ai.ToolCall {
+	call := ai.ToolCall{
+		ID:   lua.LVAsString(tbl.RawGetString("id")),
+		Name: lua.LVAsString(tbl.RawGetString("name")),
+	}
+	switch args := tbl.RawGetString("arguments").(type) {
+	case lua.LString:
+		call.Arguments = string(args)
+	case *lua.LTable:
+		encoded, _ := json.Marshal(luaValueToGo(L, args))
+		call.Arguments = string(encoded)
+	default:
+		call.Arguments = "{}"
+	}
+	return call
+}
+
+// toolCallToLuaTable converts a tool call to a table with id, name and the
+// decoded arguments. Arguments that are not valid JSON are kept as a string.
+func toolCallToLuaTable(L *lua.LState, call ai.ToolCall) *lua.LTable {
+	tbl := L.NewTable()
+	L.SetField(tbl, "id", lua.LString(call.ID))
+	L.SetField(tbl, "name", lua.LString(call.Name))
+
+	var args any
+	if call.Arguments == "" {
+		L.SetField(tbl, "arguments", L.NewTable())
+	} else if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		L.SetField(tbl, "arguments", lua.LString(call.Arguments))
+	} else {
+		L.SetField(tbl, "arguments", goValueToLua(L, args))
+	}
+	return tbl
 }
 
-// aiResponseToLuaTable converts an AI response to a Lua table
+// goMessagesToLua converts chat messages back to the table format accepted
+// by ai.chat
+func goMessagesToLua(L *lua.LState, messages []ai.Message) *lua.LTable {
+	tbl := L.NewTable()
+	for _, msg := range messages {
+		tbl.Append(messageToLuaTable(L, msg))
+	}
+	return tbl
+}
+
+// messageToLuaTable converts a chat message to a table
+func messageToLuaTable(L *lua.LState, msg ai.Message) *lua.LTable {
+	tbl := L.NewTable()
+	L.SetField(tbl, "role", lua.LString(msg.Role))
+	if len(msg.Parts) > 0 {
+		L.SetField(tbl, "content", partsToLuaTable(L, msg.Parts))
+	} else {
+		L.SetField(tbl, "content", lua.LString(msg.Content))
+	}
+	if len(msg.ToolCalls) > 0 {
+		calls := L.NewTable()
+		for _, call := range msg.ToolCalls {
+			calls.Append(toolCallToLuaTable(L, call))
+		}
+		L.SetField(tbl, "tool_calls", calls)
+	}
+	if msg.ToolCallID != "" {
+		L.SetField(tbl, "tool_call_id", lua.LString(msg.ToolCallID))
+	}
+	return tbl
+}
+
+// partsToLuaTable converts content parts to a list of tables, leaving out
+// empty fields
+func partsToLuaTable(L *lua.LState, parts []ai.Part) *lua.LTable {
+	tbl := L.NewTable()
+	for _, part := range parts {
+		partTbl := L.NewTable()
+		for name, value := range map[string]string{
+			"type":       part.Type,
+			"text":       part.Text,
+			"data":       part.Data,
+			"url":        part.URL,
+			"media_type": part.MediaType,
+			"name":       part.Name,
+		} {
+			if value != "" {
+				L.SetField(partTbl, name, lua.LString(value))
+			}
+		}
+		tbl.Append(partTbl)
+	}
+	return tbl
+}
+
+// aiResponseToLuaTable converts an AI response to a Lua table. The message
+// field holds the assistant message, ready to append to the conversation.
 func aiResponseToLuaTable(L *lua.LState, resp *ai.ChatResponse) *lua.LTable {
 	tbl := L.NewTable()
 	L.SetField(tbl, "content", lua.LString(resp.Content))
 	L.SetField(tbl, "model", lua.LString(resp.Model))
+	L.SetField(tbl, "stop_reason", lua.LString(resp.StopReason))
+	L.SetField(tbl, "cached", lua.LBool(resp.Cached))
+
+	toolCalls := L.NewTable()
+	for _, call := range resp.ToolCalls {
+		toolCalls.Append(toolCallToLuaTable(L, call))
+	}
+	L.SetField(tbl, "tool_calls", toolCalls)
+	L.SetField(tbl, "message", messageToLuaTable(L, ai.Message{
+		Role:      "assistant",
+		Content:   resp.Content,
+		ToolCalls: resp.ToolCalls,
+	}))
 
 	usageTbl := L.NewTable()
 	L.SetField(usageTbl, "input_tokens", lua.LNumber(resp.Usage.InputTokens))
@@ -3,9 +3,11 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -1017,3 +1019,731 @@ end
 		t.Errorf("expected error about empty messages, got: %s", resp.HTTP.Body)
 	}
 }
+
+// runAIHandler runs Lua code with an AI client whose OpenAI and Anthropic
+// endpoints point at the server, tracking requests in the tracker
+func runAIHandler(t *testing.T, server *httptest.Server, tracker ai.Tracker, luaCode string) string {
+	t.Helper()
+	return runAIHandlerWithStream(t, server, tracker, nil, luaCode)
+}
+
+// runAIHandlerWithStream runs the handler like runAIHandler, letting it
+// stream its response to stream
+func runAIHandlerWithStream(t *testing.T, server *httptest.Server, tracker ai.Tracker, stream ResponseStream, luaCode string) string {
+	t.Helper()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("test-function", "OPENAI_API_KEY", "test-api-key")
+	_ = envStore.Set("test-function", "OPENAI_ENDPOINT", server.URL)
+	_ = envStore.Set("test-function", "ANTHROPIC_API_KEY", "test-api-key")
+	_ = envStore.Set("test-function", "ANTHROPIC_ENDPOINT", server.URL)
+
+	deps := Dependencies{
+		Logger:    logger.NewMemoryLogger(),
+		KV:        kv.NewMemoryStore(),
+		Env:       envStore,
+		HTTP:      internalhttp.NewDefaultClient(),
+		AI:        ai.NewDefaultClient(internalhttp.NewDefaultClient(), envStore),
+		AITracker: tracker,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "POST", Path: "/"}, Code: luaCode, Stream: stream})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp.HTTP.Body
+}
+
+func TestRun_AI_Chat_ToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			Tools []struct {
+				Function struct {
+					Name       string         `json:"name"`
+					Parameters map[string]any `json:"parameters"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&reqBody)
+		if len(reqBody.Tools) != 1 || reqBody.Tools[0].Function.Name != "get_weather" {
+			t.Errorf("expected get_weather tool, got %+v", reqBody.Tools)
+		} else if reqBody.Tools[0].Function.Parameters["required"] == nil {
+			t.Errorf("expected parameters schema, got %v", reqBody.Tools[0].Function.Parameters)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"model": "gpt-4o-mini",
+			"choices": [{
+				"message": {"content": null, "tool_calls": [
+					{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Lisbon\"}"}}
+				]},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 4}
+		}`)
+	}))
+	defer server.Close()
+
+	body := runAIHandler(t, server, nil, `
+function handler(ctx, event)
+	local response, err = ai.chat({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Weather in Lisbon?"}},
+		tools = {{
+			name = "get_weather",
+			description = "Current weather for a city",
+			parameters = {
+				type = "object",
+				properties = { city = { type = "string" } },
+				required = { "city" },
+			},
+		}},
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	local call = response.tool_calls[1]
+	return {
+		statusCode = 200,
+		body = table.concat({
+			response.stop_reason, call.id, call.name, call.arguments.city,
+			response.message.role, response.message.tool_calls[1].name,
+		}, ",")
+	}
+end
+`)
+
+	if body != "tool_calls,call_1,get_weather,Lisbon,assistant,get_weather" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestRun_AI_Run_Anthropic(t *testing.T) {
+	round := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		round++
+		raw, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+
+		if round == 1 {
+			_, _ = io.WriteString(w, `{
+				"model": "claude-3-haiku",
+				"content": [
+					{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Lisbon"}},
+					{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {"city": "Nowhere"}}
+				],
+				"stop_reason": "tool_use",
+				"usage": {"input_tokens": 20, "output_tokens": 10}
+			}`)
+			return
+		}
+
+		var reqBody struct {
+			Messages []struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.Unmarshal(raw, &reqBody)
+		if len(reqBody.Messages) != 3 {
+			t.Fatalf("expected 3 messages in round 2, got %d: %s", len(reqBody.Messages), raw)
+		}
+		results := string(reqBody.Messages[2].Content)
+		if !strings.Contains(results, `"tool_use_id":"toolu_1","content":"{\"temp\":21}"`) {
+			t.Errorf("expected JSON result for toolu_1, got %s", results)
+		}
+		if !strings.Contains(results, `"tool_use_id":"toolu_2","content":"error: unknown city Nowhere"`) {
+			t.Errorf("expected error result for toolu_2, got %s", results)
+		}
+
+		_, _ = io.WriteString(w, `{
+			"model": "claude-3-haiku",
+			"content": [{"type": "text", "text": "It is 21C in Lisbon."}],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 40, "output_tokens": 8}
+		}`)
+	}))
+	defer server.Close()
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, `
+function handler(ctx, event)
+	local response, err = ai.run({
+		provider = "anthropic",
+		model = "claude-3-haiku",
+		messages = {{role = "user", content = "Weather in Lisbon?"}},
+		tools = {{
+			name = "get_weather",
+			parameters = { type = "object", properties = { city = { type = "string" } } },
+			handler = function(args, call)
+				if args.city ~= "Lisbon" then
+					return nil, "unknown city " .. args.city
+				end
+				return { temp = 21 }
+			end,
+		}},
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return {
+		statusCode = 200,
+		body = table.concat({
+			response.content, response.stop_reason, response.rounds, #response.messages,
+			response.usage.input_tokens, response.usage.output_tokens,
+		}, "|")
+	}
+end
+`)
+
+	if body != "It is 21C in Lisbon.|stop|2|5|60|18" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if requests := tracker.Requests("exec-123"); len(requests) != 2 {
+		t.Errorf("expected 2 tracked requests, got %d", len(requests))
+	}
+}
+
+func TestRun_AI_Run_MaxRounds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"model": "gpt-4o-mini",
+			"choices": [{
+				"message": {"content": "", "tool_calls": [
+					{"id": "call_1", "type": "function", "function": {"name": "ping", "arguments": "{}"}}
+				]},
+				"finish_reason": "tool_calls"
+			}]
+		}`)
+	}))
+	defer server.Close()
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, `
+function handler(ctx, event)
+	local calls = 0
+	local response, err = ai.run({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Ping forever"}},
+		tools = {{ name = "ping", handler = function() calls = calls + 1 return "pong" end }},
+		max_rounds = 3,
+	})
+	return { statusCode = 200, body = tostring(response) .. "|" .. tostring(err) .. "|" .. calls }
+end
+`)
+
+	if body != "nil|model still requested tools after 3 rounds|3" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if requests := tracker.Requests("exec-123"); len(requests) != 3 {
+		t.Errorf("expected 3 tracked requests, got %d", len(requests))
+	}
+}
+
+func TestRun_AI_Run_InvalidTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to the provider")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		tools    string
+		expected string
+	}{
+		{name: "no tools", tools: `nil`, expected: "tools is required"},
+		{name: "missing handler", tools: `{{ name = "ping" }}`, expected: `tool "ping" has no handler`},
+		{name: "missing name", tools: `{{ handler = function() end }}`, expected: "tool 1 requires a name"},
+		{name: "duplicate", tools: `{{ name = "a", handler = print }, { name = "a", handler = print }}`, expected: `tool "a" is defined more than once`},
+		{name: "invalid schema", tools: `{{ name = "a", parameters = "object", handler = print }}`, expected: `parameters of tool "a" must be a JSON schema object`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := runAIHandler(t, server, nil, `
+function handler(ctx, event)
+	local response, err = ai.run({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Hi"}},
+		tools = `+tt.tools+`,
+	})
+	return { statusCode = 200, body = tostring(err) }
+end
+`)
+			if body != tt.expected {
+				t.Errorf("expected error %q, got %q", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestRun_AI_Chat_Schema(t *testing.T) {
+	var requests []string
+	replies := []string{`{\"name\": \"Ann\", \"age\": \"thirty\"}`, `{\"name\": \"Ann\", \"age\": 30}`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(raw))
+		reply := replies[min(len(requests), len(replies))-1]
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{
+			"model": "gpt-4o-mini",
+			"choices": [{"message": {"content": "`+reply+`"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5}
+		}`)
+	}))
+	defer server.Close()
+
+	code := func(retries int) string {
+		return `
+function handler(ctx, event)
+	local response, err = ai.chat({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Ann is thirty"}},
+		schema_name = "person",
+		schema = {
+			type = "object",
+			properties = { name = { type = "string" }, age = { type = "integer" } },
+			required = { "name", "age" },
+		},
+		retries = ` + strconv.Itoa(retries) + `,
+	})
+	if err then
+		return { statusCode = 200, body = "error: " .. err }
+	end
+	return {
+		statusCode = 200,
+		body = response.data.name .. "," .. response.data.age .. "," .. response.attempts .. "," .. response.usage.input_tokens
+	}
+end
+`
+	}
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, code(2))
+	if body != "Ann,30,2,20" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if len(tracker.Requests("exec-123")) != 2 {
+		t.Errorf("expected 2 tracked requests, got %d", len(tracker.Requests("exec-123")))
+	}
+	if !strings.Contains(requests[0], `"response_format":{"type":"json_schema","json_schema":{"name":"person"`) {
+		t.Errorf("expected response_format in request, got %s", requests[0])
+	}
+	if !strings.Contains(requests[1], `Your reply does not match the schema: $.age: expected integer, got string`) {
+		t.Errorf("expected validation error in retry, got %s", requests[1])
+	}
+
+	requests = nil
+	body = runAIHandler(t, server, nil, code(0))
+	if body != "error: response does not match schema: $.age: expected integer, got string" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if len(requests) != 1 {
+		t.Errorf("expected no retries, got %d requests", len(requests))
+	}
+}
+
+func TestRun_AI_Chat_InvalidSchema(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to the provider")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		call     string
+		expected string
+	}{
+		{call: `ai.chat({provider = "openai", model = "m", messages = msgs, schema = "object"})`, expected: "schema must be a JSON schema object"},
+		{call: `ai.chat({provider = "openai", model = "m", messages = msgs, schema = {}, schema_name = "a b"})`, expected: "schema_name must be 1 to 64 letters, digits, underscores or dashes"},
+		{call: `ai.run({provider = "openai", model = "m", messages = msgs, schema = {}, tools = {{name = "a", handler = print}}})`, expected: "schema is not supported by ai.run, use ai.chat with the final messages"},
+	}
+
+	for _, tt := range tests {
+		body := runAIHandler(t, server, nil, `
+function handler(ctx, event)
+	local msgs = {{role = "user", content = "Hi"}}
+	local response, err = `+tt.call+`
+	return { statusCode = 200, body = tostring(err) }
+end
+`)
+		if body != tt.expected {
+			t.Errorf("expected error %q, got %q", tt.expected, body)
+		}
+	}
+}
+
+func TestRun_AI_Ollama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected /api/chat, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"model": "llama3.2", "message": {"role": "assistant", "content": "spam"}, "done_reason": "stop", "prompt_eval_count": 7, "eval_count": 1}`)
+	}))
+	defer server.Close()
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, `
+function handler(ctx, event)
+	local response, err = ai.chat({
+		provider = "ollama",
+		model = "llama3.2",
+		messages = {{role = "user", content = "Classify: WIN A PRIZE"}},
+		endpoint = "`+server.URL+`",
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return { statusCode = 200, body = response.content .. "," .. response.usage.input_tokens }
+end
+`)
+
+	if body != "spam,7" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if requests := tracker.Requests("exec-123"); len(requests) != 1 || requests[0].Provider != "ollama" {
+		t.Errorf("expected one tracked ollama request, got %+v", requests)
+	}
+}
+
+func TestRun_AI_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("expected /embeddings, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"model": "text-embedding-3-small", "data": [{"index": 0, "embedding": [0.6, 0.8]}, {"index": 1, "embedding": [1, 0]}], "usage": {"prompt_tokens": 4}}`)
+	}))
+	defer server.Close()
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, `
+function handler(ctx, event)
+	local response, err = ai.embed({"hello", "world"}, {provider = "openai", model = "text-embedding-3-small"})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	local first = response.embeddings[1]
+	return { statusCode = 200, body = #response.embeddings .. "," .. first[2] .. "," .. response.usage.input_tokens }
+end
+`)
+
+	if body != "2,0.8,4" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	if requests := tracker.Requests("exec-123"); len(requests) != 1 || *requests[0].InputTokens != 4 {
+		t.Errorf("expected one tracked request with 4 input tokens, got %+v", requests)
+	}
+}
+
+func TestRun_AI_Embed_UnsupportedProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request")
+	}))
+	defer server.Close()
+
+	body := runAIHandler(t, server, ai.NewMemoryTracker(), `
+function handler(ctx, event)
+	local response, err = ai.embed("hello", {provider = "anthropic", model = "claude"})
+	return { statusCode = 200, body = tostring(response) .. "," .. err }
+end
+`)
+
+	if body != "nil,provider anthropic does not support embeddings" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+// openAIStreamServer streams a chat completion of the given tokens
+func openAIStreamServer(t *testing.T, tokens ...string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range tokens {
+			_, _ = fmt.Fprintf(w, "data: {\"model\": \"gpt-4o-mini\", \"choices\": [{\"delta\": {\"content\": %q}}]}\n\n", token)
+		}
+		_, _ = io.WriteString(w, "data: {\"choices\": [{\"delta\": {}, \"finish_reason\": \"stop\"}]}\n\n")
+		_, _ = io.WriteString(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 5, \"completion_tokens\": 2}}\n\ndata: [DONE]\n\n")
+	}))
+}
+
+func TestRun_AI_Chat_Stream_OnToken(t *testing.T) {
+	server := openAIStreamServer(t, "Hel", "lo")
+	defer server.Close()
+
+	tracker := ai.NewMemoryTracker()
+	body := runAIHandler(t, server, tracker, `
+function handler(ctx, event)
+	local tokens = {}
+	local response, err = ai.chat({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Hi"}},
+		stream = true,
+		on_token = function(token) table.insert(tokens, token) end,
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return { statusCode = 200, body = table.concat(tokens, "|") .. "," .. response.content .. "," .. response.usage.output_tokens }
+end
+`)
+
+	if body != "Hel|lo,Hello,2" {
+		t.Errorf("unexpected body: %s", body)
+	}
+	requests := tracker.Requests("exec-123")
+	if len(requests) != 1 || *requests[0].OutputTokens != 2 || !strings.Contains(*requests[0].ResponseJSON, "[DONE]") {
+		t.Errorf("expected the streamed request to be tracked with its usage, got %+v", requests)
+	}
+}
+
+func TestRun_AI_Chat_Stream_ToResponse(t *testing.T) {
+	server := openAIStreamServer(t, "Hel", "lo")
+	defer server.Close()
+
+	stream := &fakeResponseStream{}
+	runAIHandlerWithStream(t, server, ai.NewMemoryTracker(), stream, `
+function handler(ctx, event)
+	stream.start({headers = {["Content-Type"] = "text/plain"}})
+	local response, err = ai.chat({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Hi"}},
+		stream = true,
+	})
+	return { body = "\n" .. tostring(err) }
+end
+`)
+
+	if strings.Join(stream.chunks, "|") != "Hel|lo|\nnil" {
+		t.Errorf("expected tokens to flow to the response, got %q", stream.chunks)
+	}
+}
+
+func TestRun_AI_Chat_Stream_Errors(t *testing.T) {
+	server := openAIStreamServer(t, "Hel", "lo")
+	defer server.Close()
+
+	body := runAIHandler(t, server, ai.NewMemoryTracker(), `
+function handler(ctx, event)
+	local options = {provider = "openai", model = "gpt-4o-mini", messages = {{role = "user", content = "Hi"}}, stream = true}
+	local _, unavailable = ai.chat(options)
+	options.on_token = function(token) error("stop here") end
+	local _, failed = ai.chat(options)
+	return { statusCode = 200, body = unavailable .. "," .. failed }
+end
+`)
+
+	if !strings.HasPrefix(body, "on_token is required when the response cannot be streamed,on_token failed: ") || !strings.Contains(body, "stop here") {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestRun_AI_Chat_Cache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"model": "gpt-4o-mini", "choices": [{"message": {"content": "spam"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 12, "completion_tokens": 1}}`)
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("test-function", "OPENAI_API_KEY", "test-api-key")
+	_ = envStore.Set("test-function", "OPENAI_ENDPOINT", server.URL)
+	tracker := ai.NewMemoryTracker()
+
+	deps := Dependencies{
+		Logger:    logger.NewMemoryLogger(),
+		KV:        kv.NewMemoryStore(),
+		Env:       envStore,
+		HTTP:      internalhttp.NewDefaultClient(),
+		AI:        ai.NewDefaultClient(internalhttp.NewDefaultClient(), envStore).WithCache(ai.NewMemoryCache()),
+		AITracker: tracker,
+	}
+	execCtx := &events.ExecutionContext{ExecutionID: "exec-123", FunctionID: "test-function", StartedAt: time.Now().Unix()}
+	code := `
+function handler(ctx, event)
+	local results = {}
+	for _, cache in ipairs({{ttl = 60}, true, false}) do
+		local response, err = ai.chat({
+			provider = "openai",
+			model = "gpt-4o-mini",
+			messages = {{role = "user", content = "Is this spam?"}},
+			cache = cache,
+		})
+		if err then
+			return { statusCode = 500, body = err }
+		end
+		table.insert(results, response.content .. ":" .. tostring(response.cached) .. ":" .. response.usage.input_tokens)
+	end
+	local _, err = ai.chat({provider = "openai", model = "gpt-4o-mini", messages = {{role = "user", content = "Hi"}}, cache = "yes"})
+	table.insert(results, err)
+	return { statusCode = 200, body = table.concat(results, ",") }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "POST", Path: "/"}, Code: code})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if resp.HTTP.Body != "spam:false:12,spam:true:0,spam:false:12,cache must be a boolean or a table" {
+		t.Errorf("unexpected body: %s", resp.HTTP.Body)
+	}
+	if calls != 2 {
+		t.Errorf("expected the cached call to skip the provider, got %d calls", calls)
+	}
+
+	requests := tracker.Requests("exec-123")
+	if len(requests) != 3 || requests[0].Cached || !requests[1].Cached || requests[2].Cached {
+		t.Fatalf("expected only the second request to be cached, got %+v", requests)
+	}
+	if requests[1].Cost == nil || *requests[1].Cost != 0 {
+		t.Errorf("expected a cached request to cost nothing, got %v", requests[1].Cost)
+	}
+}
+
+func TestRun_AI_Chat_Parts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content []map[string]any `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if len(body.Messages) != 1 || len(body.Messages[0].Content) != 2 {
+			t.Errorf("expected one message with two parts, got %+v", body.Messages)
+			return
+		}
+		image, _ := body.Messages[0].Content[1]["image_url"].(map[string]any)
+		if url, _ := image["url"].(string); !strings.HasPrefix(url, "data:image/png;base64,eHh4") {
+			t.Errorf("unexpected image url: %.40s", url)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"model": "gpt-4o-mini", "choices": [{"message": {"content": "A cat"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 300, "completion_tokens": 2}}`)
+	}))
+	defer server.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("test-function", "OPENAI_API_KEY", "test-api-key")
+	_ = envStore.Set("test-function", "OPENAI_ENDPOINT", server.URL)
+	tracker := ai.NewMemoryTracker()
+
+	deps := Dependencies{
+		Logger:    logger.NewMemoryLogger(),
+		KV:        kv.NewMemoryStore(),
+		Env:       envStore,
+		HTTP:      internalhttp.NewDefaultClient(),
+		AI:        ai.NewDefaultClient(internalhttp.NewDefaultClient(), envStore),
+		AITracker: tracker,
+	}
+	execCtx := &events.ExecutionContext{ExecutionID: "exec-123", FunctionID: "test-function", StartedAt: time.Now().Unix()}
+	code := `
+function handler(ctx, event)
+	local messages = {{role = "user", content = {
+		"What is this?",
+		{type = "image", data = base64.encode(string.rep("x", 600)), media_type = "image/png"},
+	}}}
+	local response, err = ai.chat({provider = "openai", model = "gpt-4o-mini", messages = messages})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	local _, invalid = ai.chat({provider = "openai", model = "gpt-4o-mini", messages = {
+		{role = "user", content = {"Hi", {type = "image"}}},
+	}})
+	return { statusCode = 200, body = response.content .. "," .. invalid }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "POST", Path: "/"}, Code: code})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if resp.HTTP.Body != "A cat,message 1: content part 2: image needs data or url" {
+		t.Errorf("unexpected body: %s", resp.HTTP.Body)
+	}
+
+	requests := tracker.Requests("exec-123")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 tracked request, got %d", len(requests))
+	}
+	if !strings.Contains(requests[0].RequestJSON, "data:image/png;base64,[800 base64 characters omitted]") {
+		t.Errorf("expected the image data to be truncated, got %s", requests[0].RequestJSON)
+	}
+}
+
+func TestRun_AI_NetworkPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected the request to be blocked")
+	}))
+	defer server.Close()
+
+	policy, err := internalhttp.NewPolicy(nil)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("test-function", "OPENAI_API_KEY", "test-api-key")
+
+	deps := Dependencies{
+		Logger:     logger.NewMemoryLogger(),
+		KV:         kv.NewMemoryStore(),
+		Env:        envStore,
+		HTTP:       internalhttp.NewDefaultClient(),
+		HTTPPolicy: policy,
+		AI:         ai.NewDefaultClient(internalhttp.NewDefaultClient(), envStore),
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local response, err = ai.chat({
+		provider = "openai",
+		model = "gpt-4o-mini",
+		messages = {{role = "user", content = "Hello"}},
+		endpoint = "` + server.URL + `"
+	})
+	local embedding, embedErr = ai.embed("Hello", {
+		provider = "openai",
+		model = "text-embedding-3-small",
+		endpoint = "` + server.URL + `"
+	})
+	return { statusCode = 200, body = tostring(err) .. "\n" .. tostring(embedErr) }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "POST", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, line := range strings.Split(resp.HTTP.Body, "\n") {
+		if !strings.Contains(line, "blocked by network policy") {
+			t.Errorf("expected a network policy error, got %q", line)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package runner
 
 import (
+	"encoding/base64"
+
 	"github.com/dimiro1/lunar/internal/events"
 	lua "github.com/yuin/gopher-lua"
 )
@@ -27,6 +29,94 @@ func httpEventToLuaTable(L *lua.LState, event events.HTTPEvent) *lua.LTable {
 	}
 	L.SetField(tbl, "query", queryTbl)
 
+	// Parse form bodies. A malformed body leaves form and files empty, the raw
+	// body is still available to the handler.
+	form, files, err := event.ParseForm()
+	if err != nil {
+		L.SetField(tbl, "formError", lua.LString(err.Error()))
+	}
+	L.SetField(tbl, "form", urlValuesToLuaTable(L, form))
+
+	filesTbl := L.NewTable()
+	for _, file := range files {
+		fileTbl := L.NewTable()
+		L.SetField(fileTbl, "field", lua.LString(file.Field))
+		L.SetField(fileTbl, "filename", lua.LString(file.Filename))
+		L.SetField(fileTbl, "contentType", lua.LString(file.ContentType))
+		L.SetField(fileTbl, "size", lua.LNumber(len(file.Content)))
+		L.SetField(fileTbl, "content", lua.LString(base64.StdEncoding.EncodeToString(file.Content)))
+		filesTbl.Append(fileTbl)
+	}
+	L.SetField(tbl, "files", filesTbl)
+
+	return tbl
+}
+
+// emailEventToLuaTable converts an EmailEvent to a Lua table
+func emailEventToLuaTable(L *lua.LState, event events.EmailEvent) *lua.LTable {
+	tbl := L.NewTable()
+
+	if event.ID != "" {
+		L.SetField(tbl, "id", lua.LString(event.ID))
+	}
+	L.SetField(tbl, "messageId", lua.LString(event.MessageID))
+	L.SetField(tbl, "recipient", lua.LString(event.Recipient))
+	L.SetField(tbl, "from", lua.LString(event.From))
+	L.SetField(tbl, "subject", lua.LString(event.Subject))
+	L.SetField(tbl, "text", lua.LString(event.Text))
+	L.SetField(tbl, "html", lua.LString(event.HTML))
+
+	for name, addresses := range map[string][]string{"to": event.To, "cc": event.Cc} {
+		addressesTbl := L.NewTable()
+		for _, address := range addresses {
+			addressesTbl.Append(lua.LString(address))
+		}
+		L.SetField(tbl, name, addressesTbl)
+	}
+
+	headersTbl := L.NewTable()
+	for k, v := range event.Headers {
+		L.SetField(headersTbl, k, lua.LString(v))
+	}
+	L.SetField(tbl, "headers", headersTbl)
+
+	attachmentsTbl := L.NewTable()
+	for _, attachment := range event.Attachments {
+		attachmentTbl := L.NewTable()
+		L.SetField(attachmentTbl, "filename", lua.LString(attachment.Filename))
+		L.SetField(attachmentTbl, "contentType", lua.LString(attachment.ContentType))
+		if attachment.ContentID != "" {
+			L.SetField(attachmentTbl, "contentId", lua.LString(attachment.ContentID))
+		}
+		L.SetField(attachmentTbl, "size", lua.LNumber(attachment.Size))
+		L.SetField(attachmentTbl, "content", lua.LString(base64.StdEncoding.EncodeToString(attachment.Content)))
+		attachmentsTbl.Append(attachmentTbl)
+	}
+	L.SetField(tbl, "attachments", attachmentsTbl)
+
+	return tbl
+}
+
+// emailBounceEventToLuaTable converts an EmailBounceEvent to a Lua table
+func emailBounceEventToLuaTable(L *lua.LState, event events.EmailBounceEvent) *lua.LTable {
+	tbl := L.NewTable()
+
+	L.SetField(tbl, "emailId", lua.LString(event.EmailID))
+	if event.ExecutionID != "" {
+		L.SetField(tbl, "executionId", lua.LString(event.ExecutionID))
+	}
+	L.SetField(tbl, "from", lua.LString(event.From))
+	L.SetField(tbl, "subject", lua.LString(event.Subject))
+	L.SetField(tbl, "bounceType", lua.LString(event.BounceType))
+	L.SetField(tbl, "bounceSubType", lua.LString(event.SubType))
+	L.SetField(tbl, "message", lua.LString(event.Message))
+
+	toTbl := L.NewTable()
+	for _, address := range event.To {
+		toTbl.Append(lua.LString(address))
+	}
+	L.SetField(tbl, "to", toTbl)
+
 	return tbl
 }
 
@@ -42,6 +132,10 @@ func contextToLuaTable(L *lua.LState, ctx *events.ExecutionContext) *lua.LTable
 		L.SetField(tbl, "requestId", lua.LString(ctx.RequestID))
 	}
 
+	if ctx.TraceID != "" {
+		L.SetField(tbl, "traceId", lua.LString(ctx.TraceID))
+	}
+
 	if ctx.FunctionName != "" {
 		L.SetField(tbl, "functionName", lua.LString(ctx.FunctionName))
 	}
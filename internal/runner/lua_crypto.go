@@ -1,19 +1,45 @@
 package runner
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"hash"
+	"slices"
+	"strings"
 
+	"github.com/dimiro1/lunar/internal/jose"
 	"github.com/google/uuid"
 	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// registerCrypto registers the crypto module with hashing and UUID functions
+// Argon2id parameters for new password hashes (OWASP recommendation)
+const (
+	argon2Memory  = 19 * 1024 // KiB
+	argon2Time    = 2
+	argon2Threads = 1
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// maxArgon2Memory limits the memory a stored hash can make password_verify
+// allocate, so a crafted hash cannot exhaust the server
+const maxArgon2Memory = 256 * 1024 // KiB
+
+// registerCrypto registers the crypto module with hashing, encryption,
+// signature, password hashing and UUID functions
 func registerCrypto(L *lua.LState) {
 	cryptoModule := L.NewTable()
 
@@ -28,6 +54,18 @@ func registerCrypto(L *lua.LState) {
 	L.SetField(cryptoModule, "hmac_sha256", L.NewFunction(cryptoHMACSHA256))
 	L.SetField(cryptoModule, "hmac_sha512", L.NewFunction(cryptoHMACSHA512))
 
+	// Authenticated encryption
+	L.SetField(cryptoModule, "aes_gcm_encrypt", L.NewFunction(cryptoAESGCMEncrypt))
+	L.SetField(cryptoModule, "aes_gcm_decrypt", L.NewFunction(cryptoAESGCMDecrypt))
+
+	// Signatures with PEM or JWK keys
+	L.SetField(cryptoModule, "sign", L.NewFunction(cryptoSign))
+	L.SetField(cryptoModule, "verify", L.NewFunction(cryptoVerify))
+
+	// Password hashing
+	L.SetField(cryptoModule, "password_hash", L.NewFunction(cryptoPasswordHash))
+	L.SetField(cryptoModule, "password_verify", L.NewFunction(cryptoPasswordVerify))
+
 	// UUID function
 	L.SetField(cryptoModule, "uuid", L.NewFunction(cryptoUUID))
 
@@ -95,6 +133,270 @@ func cryptoUUID(L *lua.LState) int {
 	return 1
 }
 
+// cryptoAESGCMEncrypt encrypts plaintext with AES-GCM. The key must be 16,
+// 24 or 32 raw bytes; the result is the base64 encoded nonce followed by the
+// ciphertext.
+// Usage: local ciphertext, err = crypto.aes_gcm_encrypt(plaintext, key, aad?)
+func cryptoAESGCMEncrypt(L *lua.LState) int {
+	plaintext := L.CheckString(1)
+	key := L.CheckString(2)
+	aad := L.OptString(3, "")
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	L.Push(lua.LString(base64.StdEncoding.EncodeToString(sealed)))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cryptoAESGCMDecrypt decrypts and authenticates the output of aes_gcm_encrypt
+// Usage: local plaintext, err = crypto.aes_gcm_decrypt(ciphertext, key, aad?)
+func cryptoAESGCMDecrypt(L *lua.LState) int {
+	encoded := L.CheckString(1)
+	key := L.CheckString(2)
+	aad := L.OptString(3, "")
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid ciphertext"))
+		return 2
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("decryption failed"))
+		return 2
+	}
+
+	L.Push(lua.LString(plaintext))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cryptoSign signs data with a PEM or JWK private key (or an HMAC secret for
+// HS256, HS384 and HS512) and returns the base64 encoded signature. ECDSA
+// signatures use the raw r||s encoding of JWS.
+// Usage: local signature, err = crypto.sign(alg, key, data)
+func cryptoSign(L *lua.LState) int {
+	alg := L.CheckString(1)
+	keyData := L.CheckString(2)
+	data := L.CheckString(3)
+
+	keys, err := jose.ParseKeys(keyData)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	// A JWKS may hold several keys; sign with the first one that fits alg
+	key := keys[0]
+	for _, k := range keys {
+		if slices.Contains(k.Algorithms(), alg) {
+			key = k
+			break
+		}
+	}
+
+	sig, err := jose.Sign(alg, key, []byte(data))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LString(base64.StdEncoding.EncodeToString(sig)))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cryptoVerify checks a base64 encoded signature made by crypto.sign. With a
+// JWKS document every key that supports alg is tried.
+// Usage: local valid, err = crypto.verify(alg, key, data, signature)
+func cryptoVerify(L *lua.LState) int {
+	alg := L.CheckString(1)
+	keyData := L.CheckString(2)
+	data := L.CheckString(3)
+	encoded := L.CheckString(4)
+
+	keys, err := jose.ParseKeys(keyData)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("invalid signature encoding"))
+		return 2
+	}
+
+	err = jose.ErrInvalidSignature
+	for _, key := range keys {
+		if err = jose.Verify(alg, key, []byte(data), sig); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cryptoPasswordHash hashes a password for storage. Hashes use argon2id in
+// the PHC string format unless bcrypt is requested.
+// Usage: local hash, err = crypto.password_hash(password, {algorithm = "bcrypt", cost = 12}?)
+func cryptoPasswordHash(L *lua.LState) int {
+	password := L.CheckString(1)
+	options := L.OptTable(2, L.NewTable())
+
+	algorithm := "argon2id"
+	if v, ok := L.GetField(options, "algorithm").(lua.LString); ok {
+		algorithm = string(v)
+	}
+
+	var encoded string
+	switch algorithm {
+	case "argon2id":
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+		encoded = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	case "bcrypt":
+		cost := bcrypt.DefaultCost
+		if v, ok := L.GetField(options, "cost").(lua.LNumber); ok {
+			cost = int(v)
+		}
+		if cost < bcrypt.MinCost || cost > 14 {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("bcrypt cost must be between %d and 14", bcrypt.MinCost)))
+			return 2
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		encoded = string(hashed)
+	default:
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("unsupported password hash algorithm %q", algorithm)))
+		return 2
+	}
+
+	L.Push(lua.LString(encoded))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// cryptoPasswordVerify checks a password against an argon2id or bcrypt hash
+// Usage: local valid, err = crypto.password_verify(password, hash)
+func cryptoPasswordVerify(L *lua.LState) int {
+	password := L.CheckString(1)
+	encoded := L.CheckString(2)
+
+	var valid bool
+	var err error
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		valid, err = verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		valid = err == nil
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			err = nil
+		}
+	default:
+		err = errors.New("unsupported password hash format")
+	}
+
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LBool(valid))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// verifyArgon2id checks a password against an argon2id PHC string
+func verifyArgon2id(password, encoded string) (bool, error) {
+	var version int
+	var memory, iterations uint32
+	var threads uint8
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, errors.New("invalid argon2id hash")
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errors.New("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, errors.New("invalid argon2id parameters")
+	}
+	if memory > maxArgon2Memory || iterations == 0 || iterations > 10 || threads == 0 {
+		return false, errors.New("argon2id parameters are out of range")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, errors.New("invalid argon2id hash")
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// newGCM creates an AES-GCM cipher from a raw 16, 24 or 32 byte key
+func newGCM(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, errors.New("key must be 16, 24 or 32 bytes")
+	}
+	return cipher.NewGCM(block)
+}
+
 // hashString is a helper function to compute hash of a string
 func hashString(L *lua.LState, h hash.Hash, str string) int {
 	h.Write([]byte(str))
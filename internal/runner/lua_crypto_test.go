@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_Crypto_AESGCM(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local key = "0123456789abcdef0123456789abcdef"
+	local ciphertext, err = crypto.aes_gcm_encrypt("hello", key, "user-1")
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local plaintext = crypto.aes_gcm_decrypt(ciphertext, key, "user-1")
+	local _, aadErr = crypto.aes_gcm_decrypt(ciphertext, key, "user-2")
+	local _, keyErr = crypto.aes_gcm_encrypt("hello", "short")
+	local other = crypto.aes_gcm_encrypt("hello", key, "user-1")
+
+	return {
+		statusCode = 200,
+		body = table.concat({plaintext, aadErr, keyErr, tostring(other ~= ciphertext)}, "|")
+	}
+end
+`)
+
+	expected := "hello|decryption failed|key must be 16, 24 or 32 bytes|true"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Crypto_SignVerify(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+	publicDER, _ := x509.MarshalPKIXPublicKey(public)
+	privatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local signature, err = crypto.sign("EdDSA", `+luaString(privatePEM)+`, "message")
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local valid = crypto.verify("EdDSA", `+luaString(publicPEM)+`, "message", signature)
+	local tampered, tamperedErr = crypto.verify("EdDSA", `+luaString(publicPEM)+`, "other", signature)
+	local _, algErr = crypto.sign("RS256", `+luaString(privatePEM)+`, "message")
+
+	return {
+		statusCode = 200,
+		body = table.concat({tostring(valid), tostring(tampered), tamperedErr, algErr}, "|")
+	}
+end
+`)
+
+	expected := "true|false|invalid signature|algorithm RS256 cannot be used with this key"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Crypto_PasswordHash(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local results = {}
+	for _, algorithm in ipairs({"argon2id", "bcrypt"}) do
+		local hash, err = crypto.password_hash("hunter2", {algorithm = algorithm, cost = 4})
+		if err then
+			return { statusCode = 500, body = err }
+		end
+		table.insert(results, hash:sub(1, 4))
+		table.insert(results, tostring(crypto.password_verify("hunter2", hash)))
+		table.insert(results, tostring(crypto.password_verify("hunter3", hash)))
+	end
+
+	local _, formatErr = crypto.password_verify("hunter2", "plain")
+	local _, algErr = crypto.password_hash("hunter2", {algorithm = "md5"})
+	local _, costErr = crypto.password_hash("hunter2", {algorithm = "bcrypt", cost = 31})
+	table.insert(results, formatErr)
+	table.insert(results, algErr)
+	table.insert(results, costErr)
+	return { statusCode = 200, body = table.concat(results, "|") }
+end
+`)
+
+	expected := strings.Join([]string{
+		"$arg", "true", "false",
+		"$2a$", "true", "false",
+		"unsupported password hash format",
+		`unsupported password hash algorithm "md5"`,
+		"bcrypt cost must be between 4 and 14",
+	}, "|")
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Crypto_PasswordVerify_Argon2Limits(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local ok, err = crypto.password_verify("pw", "$argon2id$v=19$m=4194304,t=1,p=1$c2FsdHNhbHQ$aGFzaA")
+	return { statusCode = 200, body = tostring(ok) .. "|" .. err }
+end
+`)
+
+	if body != "false|argon2id parameters are out of range" {
+		t.Errorf("expected out of range error, got %q", body)
+	}
+}
@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerCSV registers the csv module with encode/decode functions
+func registerCSV(L *lua.LState) {
+	csvModule := L.NewTable()
+
+	L.SetField(csvModule, "decode", L.NewFunction(csvDecode))
+	L.SetField(csvModule, "encode", L.NewFunction(csvEncode))
+
+	// Set the csv module as a global
+	L.SetGlobal("csv", csvModule)
+}
+
+// csvDecode parses CSV text. With headers (the default) the first record
+// names the columns and each row is a table keyed by column name; otherwise
+// each row is a list of fields.
+// Usage: local rows, err = csv.decode(str, {headers = true, delimiter = ","})
+func csvDecode(L *lua.LState) int {
+	str := L.CheckString(1)
+	options := L.OptTable(2, L.NewTable())
+
+	delimiter, err := csvDelimiter(options)
+	if err != nil {
+		L.ArgError(2, err.Error())
+		return 0
+	}
+
+	reader := csv.NewReader(strings.NewReader(str))
+	reader.Comma = delimiter
+	records, err := reader.ReadAll()
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	rows := L.NewTable()
+	if options.RawGetString("headers") == lua.LFalse {
+		for _, record := range records {
+			row := L.NewTable()
+			for _, field := range record {
+				row.Append(lua.LString(field))
+			}
+			rows.Append(row)
+		}
+		L.Push(rows)
+		L.Push(lua.LNil)
+		return 2
+	}
+
+	if len(records) == 0 {
+		L.Push(rows)
+		L.Push(lua.LNil)
+		return 2
+	}
+	headers := records[0]
+	for _, record := range records[1:] {
+		row := L.NewTable()
+		for i, field := range record {
+			L.SetField(row, headers[i], lua.LString(field))
+		}
+		rows.Append(row)
+	}
+
+	L.Push(rows)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// csvEncode writes rows as CSV. Rows are lists of fields or tables keyed by
+// column name; keyed rows are written under a header record whose columns
+// come from the headers option, or the sorted keys of all rows.
+// Usage: local str, err = csv.encode(rows, {headers = {"id", "name"}, delimiter = ","})
+func csvEncode(L *lua.LState) int {
+	rows := L.CheckTable(1)
+	options := L.OptTable(2, L.NewTable())
+
+	delimiter, err := csvDelimiter(options)
+	if err != nil {
+		L.ArgError(2, err.Error())
+		return 0
+	}
+
+	var headers []string
+	if v, ok := options.RawGetString("headers").(*lua.LTable); ok {
+		for i := 1; i <= v.Len(); i++ {
+			headers = append(headers, lua.LVAsString(v.RawGetInt(i)))
+		}
+	}
+
+	var list []*lua.LTable
+	for i := 1; i <= rows.Len(); i++ {
+		row, ok := rows.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("row %d is not a table", i)))
+			return 2
+		}
+		list = append(list, row)
+	}
+
+	// Without a headers option, keyed rows use the sorted keys of all rows
+	if headers == nil {
+		for _, row := range list {
+			if row.Len() > 0 {
+				continue
+			}
+			row.ForEach(func(k, _ lua.LValue) {
+				if key, ok := k.(lua.LString); ok && !slices.Contains(headers, string(key)) {
+					headers = append(headers, string(key))
+				}
+			})
+		}
+		slices.Sort(headers)
+	}
+
+	records := make([][]string, 0, len(list))
+	for _, row := range list {
+		var record []string
+		if row.Len() > 0 {
+			for j := 1; j <= row.Len(); j++ {
+				record = append(record, csvField(row.RawGetInt(j)))
+			}
+		} else {
+			for _, header := range headers {
+				record = append(record, csvField(row.RawGetString(header)))
+			}
+		}
+		records = append(records, record)
+	}
+
+	var out strings.Builder
+	writer := csv.NewWriter(&out)
+	writer.Comma = delimiter
+	if len(headers) > 0 {
+		_ = writer.Write(headers)
+	}
+	_ = writer.WriteAll(records)
+	if err := writer.Error(); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LString(out.String()))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// csvDelimiter reads the delimiter option, which must be a single character
+func csvDelimiter(options *lua.LTable) (rune, error) {
+	v, ok := options.RawGetString("delimiter").(lua.LString)
+	if !ok {
+		return ',', nil
+	}
+	r, size := utf8.DecodeRuneInString(string(v))
+	if size == 0 || size != len(v) || r == '"' || r == '\r' || r == '\n' {
+		return 0, errors.New("delimiter must be a single character")
+	}
+	return r, nil
+}
+
+// csvField converts a Lua value to a CSV field; nil becomes an empty field
+func csvField(v lua.LValue) string {
+	if v == lua.LNil {
+		return ""
+	}
+	return v.String()
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_CSV_Decode(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local rows = csv.decode("id,name\n1,\"Smith, Ann\"\n2,Bob\n")
+	local raw = csv.decode("a;b\nc;d", {headers = false, delimiter = ";"})
+	local _, err = csv.decode("a,b\n1,2,3")
+	return {
+		statusCode = 200,
+		body = table.concat({#rows, rows[1].name, rows[2].id, #raw, raw[2][2], err}, "|")
+	}
+end
+`)
+
+	expected := "2|Smith, Ann|2|2|d|record on line 2: wrong number of fields"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_CSV_Encode(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local keyed = csv.encode({
+		{ name = "Smith, Ann", id = 1 },
+		{ name = "Bob", id = 2, active = true },
+	})
+	local ordered = csv.encode({ { id = 1, name = "Ann" } }, { headers = {"name", "id"}, delimiter = ";" })
+	local lists = csv.encode({ {"a", "b"}, {"c", "d \"q\""} })
+	return { statusCode = 200, body = keyed .. "--\n" .. ordered .. "--\n" .. lists }
+end
+`)
+
+	expected := "active,id,name\n,1,\"Smith, Ann\"\ntrue,2,Bob\n--\nname;id\nAnn;1\n--\na,b\nc,\"d \"\"q\"\"\"\n"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
@@ -1,16 +1,37 @@
 package runner
 
 import (
+	"cmp"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dimiro1/lunar/internal/email"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/metrics"
 	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 	lua "github.com/yuin/gopher-lua"
 )
 
-// registerEmail creates the global 'email' table with email sending functions
-func registerEmail(L *lua.LState, emailClient email.Client, functionID string, emailTracker email.Tracker, executionID string) {
+// registerEmail creates the global 'email' table with email sending functions.
+// Attachments given by URL are fetched with httpClient under policy.
+func registerEmail(
+	L *lua.LState,
+	emailClient email.Client,
+	templates email.TemplateStore,
+	httpClient internalhttp.Client,
+	policy *internalhttp.Policy,
+	functionID string,
+	emailTracker email.Tracker,
+	httpTracker internalhttp.Tracker,
+	executionID string,
+	trace *tracing.Trace,
+) {
 	emailTable := L.NewTable()
 
 	// email.send(options)
@@ -22,6 +43,11 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 		toLV := options.RawGetString("to")
 		subject := lua.LVAsString(options.RawGetString("subject"))
 
+		// Extract optional parameters
+		text := lua.LVAsString(options.RawGetString("text"))
+		html := lua.LVAsString(options.RawGetString("html"))
+		replyTo := lua.LVAsString(options.RawGetString("reply_to"))
+
 		// Validate required parameters
 		if from == "" {
 			L.Push(lua.LNil)
@@ -33,6 +59,20 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 			L.Push(lua.LString("to is required"))
 			return 2
 		}
+
+		// A template fills in the subject and bodies not given
+		if name := lua.LVAsString(options.RawGetString("template")); name != "" {
+			rendered, errMsg := renderEmailTemplate(L, templates, name, options.RawGetString("data"))
+			if errMsg != "" {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(errMsg))
+				return 2
+			}
+			subject = cmp.Or(subject, rendered.Subject)
+			text = cmp.Or(text, rendered.Text)
+			html = cmp.Or(html, rendered.HTML)
+		}
+
 		if subject == "" {
 			L.Push(lua.LNil)
 			L.Push(lua.LString("subject is required"))
@@ -57,11 +97,6 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 			return 2
 		}
 
-		// Extract optional parameters
-		text := lua.LVAsString(options.RawGetString("text"))
-		html := lua.LVAsString(options.RawGetString("html"))
-		replyTo := lua.LVAsString(options.RawGetString("reply_to"))
-
 		// Handle scheduled_at - accepts Unix timestamp (number) or ISO 8601 string
 		var scheduledAt string
 		scheduledAtLV := options.RawGetString("scheduled_at")
@@ -123,6 +158,22 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 			})
 		}
 
+		// Convert optional attachments, fetching those given by URL
+		fetch := func(url string) (internalhttp.Response, error) {
+			ctx := L.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			req := internalhttp.Request{Method: "GET", URL: url, Policy: policy, Context: ctx}
+			return doInstrumentedHTTP(trace, httpTracker, executionID, req.Method, httpClient.Do, req)
+		}
+		attachments, errMsg := luaAttachmentsToGo(options.RawGetString("attachments"), fetch)
+		if errMsg != "" {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(errMsg))
+			return 2
+		}
+
 		// Build the send request
 		req := email.SendRequest{
 			From:        from,
@@ -136,12 +187,22 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 			Headers:     headers,
 			Tags:        tags,
 			ScheduledAt: scheduledAt,
+			Attachments: attachments,
+			Policy:      policy,
 		}
 
+		span := trace.Start("email.send", store.SpanKindClient, map[string]string{
+			"email.recipients":  strconv.Itoa(len(to) + len(cc) + len(bcc)),
+			"email.attachments": strconv.Itoa(len(attachments)),
+		})
+		defer span.End()
+
 		// Send the email
 		startTime := time.Now()
 		resp, err := emailClient.Send(functionID, req)
-		durationMs := time.Since(startTime).Milliseconds()
+		duration := time.Since(startTime)
+		durationMs := duration.Milliseconds()
+		metrics.EmailRequestDuration.Observe(duration.Seconds())
 
 		// Get request JSON for tracking (available even on error)
 		var requestJSON string
@@ -150,6 +211,9 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 		}
 
 		if err != nil {
+			span.SetError(err.Error())
+			metrics.EmailRequestsTotal.Inc(string(store.EmailRequestStatusError))
+
 			// Track failed request
 			errMsg := err.Error()
 			if emailTracker != nil {
@@ -170,6 +234,9 @@ func registerEmail(L *lua.LState, emailClient email.Client, functionID string, e
 			return 2
 		}
 
+		metrics.EmailRequestsTotal.Inc(string(store.EmailRequestStatusSuccess))
+		span.SetAttribute("email.id", resp.ID)
+
 		// Build response JSON
 		responseJSON, _ := json.Marshal(map[string]string{"id": resp.ID})
 		responseJSONStr := string(responseJSON)
@@ -212,3 +279,92 @@ func luaTableToStringSlice(tbl *lua.LTable) []string {
 	})
 	return result
 }
+
+// renderEmailTemplate renders a stored template with the data option
+func renderEmailTemplate(L *lua.LState, templates email.TemplateStore, name string, data lua.LValue) (email.Rendered, string) {
+	if templates == nil {
+		return email.Rendered{}, "email templates are not available"
+	}
+	tmpl, err := templates.Get(name)
+	if err != nil {
+		return email.Rendered{}, err.Error()
+	}
+	if tmpl == nil {
+		return email.Rendered{}, fmt.Sprintf("email template %q not found", name)
+	}
+	rendered, err := email.RenderTemplate(*tmpl, luaValueToGo(L, data))
+	if err != nil {
+		return email.Rendered{}, err.Error()
+	}
+	return rendered, ""
+}
+
+// luaAttachmentsToGo converts the attachments option. Each attachment has a
+// filename and either base64 content or a url the file is fetched from; a
+// content_id makes it an inline file referenced as cid:<content_id>.
+func luaAttachmentsToGo(lv lua.LValue, fetch func(url string) (internalhttp.Response, error)) ([]email.Attachment, string) {
+	if lv.Type() == lua.LTNil {
+		return nil, ""
+	}
+	tbl, ok := lv.(*lua.LTable)
+	if !ok {
+		return nil, "attachments must be a table"
+	}
+
+	var attachments []email.Attachment
+	size := 0
+	for i := 1; i <= tbl.Len(); i++ {
+		attachmentTbl, ok := tbl.RawGetInt(i).(*lua.LTable)
+		if !ok {
+			return nil, fmt.Sprintf("attachment %d must be a table", i)
+		}
+		a := email.Attachment{
+			Filename:    lua.LVAsString(attachmentTbl.RawGetString("filename")),
+			ContentType: lua.LVAsString(attachmentTbl.RawGetString("content_type")),
+			ContentID:   lua.LVAsString(attachmentTbl.RawGetString("content_id")),
+			URL:         lua.LVAsString(attachmentTbl.RawGetString("url")),
+		}
+		content := lua.LVAsString(attachmentTbl.RawGetString("content"))
+
+		switch {
+		case a.Filename == "":
+			return nil, fmt.Sprintf("attachment %d: filename is required", i)
+		case strings.ContainsAny(a.ContentID, "<> \t\r\n"):
+			return nil, fmt.Sprintf("attachment %d: content_id cannot contain spaces or angle brackets", i)
+		case a.ContentType != "" && !email.ValidContentType(a.ContentType):
+			return nil, fmt.Sprintf("attachment %d: invalid content_type %q", i, a.ContentType)
+		case content == "" && a.URL == "":
+			return nil, fmt.Sprintf("attachment %d: content or url is required", i)
+		case content != "" && a.URL != "":
+			return nil, fmt.Sprintf("attachment %d: takes content or url, not both", i)
+		}
+
+		if a.URL != "" {
+			resp, err := fetch(a.URL)
+			if err != nil {
+				return nil, fmt.Sprintf("attachment %d: %v", i, err)
+			}
+			if !resp.IsSuccess() {
+				return nil, fmt.Sprintf("attachment %d: fetching %s failed with HTTP %d", i, a.URL, resp.StatusCode)
+			}
+			a.Content = []byte(resp.Body)
+			if fetched := resp.Headers["Content-Type"]; a.ContentType == "" && email.ValidContentType(fetched) {
+				// Otherwise derived from the filename
+				a.ContentType = fetched
+			}
+		} else {
+			decoded, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				return nil, fmt.Sprintf("attachment %d: content must be base64 encoded", i)
+			}
+			a.Content = decoded
+		}
+
+		size += len(a.Content)
+		if size > email.MaxAttachmentsSize {
+			return nil, fmt.Sprintf("attachments exceed %dMB", email.MaxAttachmentsSize/1024/1024)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, ""
+}
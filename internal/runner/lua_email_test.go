@@ -15,6 +15,7 @@ import (
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/store"
 )
 
 func TestRun_Email_MissingFrom(t *testing.T) {
@@ -595,3 +596,129 @@ end
 		t.Errorf("expected scheduled_at '%s', got '%s'", expectedScheduledAt, receivedScheduledAt)
 	}
 }
+
+func TestRun_Email_TemplateAndAttachments(t *testing.T) {
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logo.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("PNG"))
+	}))
+	defer files.Close()
+
+	var sent struct {
+		Subject     string `json:"subject"`
+		HTML        string `json:"html"`
+		Text        string `json:"text"`
+		Attachments []struct {
+			Filename    string `json:"filename"`
+			Content     []int  `json:"content"`
+			ContentType string `json:"content_type"`
+			ContentID   string `json:"content_id"`
+		} `json:"attachments"`
+	}
+	resend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "email_123456"}`))
+	}))
+	defer resend.Close()
+
+	envStore := env.NewMemoryStore()
+	_ = envStore.Set("test-function", "RESEND_API_KEY", "test-resend-key")
+	_ = envStore.Set("test-function", "RESEND_BASE_URL", resend.URL)
+	templates := email.NewMemoryTemplateStore()
+	_, _ = templates.Save(store.EmailTemplate{
+		Name:    "receipt",
+		Subject: "Receipt #{{.order}}",
+		Text:    "Thanks {{.name}}",
+		HTML:    `<img src="cid:logo"><p>Thanks {{.name}}</p>`,
+	})
+	emailTracker := email.NewMemoryTracker()
+
+	deps := Dependencies{
+		Logger:         logger.NewMemoryLogger(),
+		KV:             kv.NewMemoryStore(),
+		Env:            envStore,
+		HTTP:           internalhttp.NewDefaultClient(),
+		Email:          email.NewDefaultClient(envStore),
+		EmailTemplates: templates,
+		EmailTracker:   emailTracker,
+	}
+	execCtx := &events.ExecutionContext{ExecutionID: "exec-123", FunctionID: "test-function", StartedAt: time.Now().Unix()}
+	luaCode := `
+function handler(ctx, event)
+	local result, err = email.send({
+		from = "shop@example.com",
+		to = "ana@example.com",
+		template = "receipt",
+		data = {order = 42, name = "<Ana>"},
+		text = "Thanks!",
+		attachments = {
+			{filename = "receipt.txt", content = base64.encode("total: 10")},
+			{filename = "logo.png", url = "` + files.URL + `/logo.png", content_id = "logo"},
+		},
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local errors = {}
+	for _, options in ipairs({
+		{template = "missing"},
+		{text = "Hi", attachments = {{filename = "a.txt"}}},
+		{text = "Hi", attachments = {{filename = "a.txt", content = "not base64!"}}},
+		{text = "Hi", attachments = {{filename = "a.txt", content = base64.encode("x"), content_type = "text/plain\r\nX-Injected: 1"}}},
+		{text = "Hi", attachments = {{filename = "a.png", url = "` + files.URL + `/missing.png"}}},
+	}) do
+		options.from = "shop@example.com"
+		options.to = "ana@example.com"
+		options.subject = options.subject or "Hi"
+		local _, err = email.send(options)
+		table.insert(errors, err)
+	end
+	return { statusCode = 200, body = table.concat(errors, "\n") }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "POST", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.HTTP.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d: %s", resp.HTTP.StatusCode, resp.HTTP.Body)
+	}
+
+	expectedErrors := strings.Join([]string{
+		`email template "missing" not found`,
+		"attachment 1: content or url is required",
+		"attachment 1: content must be base64 encoded",
+		`attachment 1: invalid content_type "text/plain\r\nX-Injected: 1"`,
+		"attachment 1: fetching " + files.URL + "/missing.png failed with HTTP 404",
+	}, "\n")
+	if resp.HTTP.Body != expectedErrors {
+		t.Errorf("expected errors:\n%s\ngot:\n%s", expectedErrors, resp.HTTP.Body)
+	}
+
+	if sent.Subject != "Receipt #42" || sent.Text != "Thanks!" || sent.HTML != `<img src="cid:logo"><p>Thanks &lt;Ana&gt;</p>` {
+		t.Errorf("unexpected rendered email: %+v", sent)
+	}
+	if len(sent.Attachments) != 2 || sent.Attachments[1].ContentID != "logo" || sent.Attachments[1].ContentType != "image/png" || len(sent.Attachments[1].Content) != 3 {
+		t.Errorf("unexpected attachments: %+v", sent.Attachments)
+	}
+
+	requests := emailTracker.Requests("exec-123")
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 tracked email, got %d", len(requests))
+	}
+	if !strings.Contains(requests[0].RequestJSON, `{"content_id":"logo","content_type":"image/png","filename":"logo.png","size":3,"url":"`+files.URL+`/logo.png"}`) {
+		t.Errorf("expected attachment metadata in the request JSON, got %s", requests[0].RequestJSON)
+	}
+	if strings.Contains(requests[0].RequestJSON, "dG90YWw6IDEw") {
+		t.Errorf("expected attachment content not to be recorded, got %s", requests[0].RequestJSON)
+	}
+}
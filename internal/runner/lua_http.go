@@ -1,26 +1,74 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/metrics"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 	lua "github.com/yuin/gopher-lua"
 )
 
-// registerHTTP creates the global 'http' table with HTTP client functions
-func registerHTTP(L *lua.LState, httpClient internalhttp.Client) {
-	httpTable := L.NewTable()
+// retryStatusCodes are the response status codes that are retried
+var retryStatusCodes = []int{408, 429, 500, 502, 503, 504}
 
-	// http.get(url, options)
-	L.SetField(httpTable, "get", L.NewFunction(func(L *lua.LState) int {
-		url := L.CheckString(1)
-		options := L.OptTable(2, L.NewTable())
+// idempotentMethods are the methods that may be retried
+var idempotentMethods = []string{"GET", "HEAD", "PUT", "DELETE", "OPTIONS"}
+
+// retryPolicy controls how failed requests are retried
+type retryPolicy struct {
+	attempts   int           // Total attempts, including the first one
+	backoff    time.Duration // Delay before the first retry, doubled on each retry
+	maxBackoff time.Duration // Upper bound for the delay between attempts
+}
 
-		req := internalhttp.Request{
-			URL:     url,
-			Headers: luaTableToHeaders(options.RawGetString("headers")),
-			Query:   luaTableToQuery(options.RawGetString("query")),
+// registerHTTP creates the global 'http' table with HTTP client functions.
+// Requests are restricted by policy when it is not nil.
+func registerHTTP(
+	L *lua.LState,
+	httpClient internalhttp.Client,
+	policy *internalhttp.Policy,
+	trace *tracing.Trace,
+	httpTracker internalhttp.Tracker,
+	executionID string,
+) {
+	httpTable := L.NewTable()
+
+	// do builds the request from options, performs it with retries and pushes
+	// the response table (or nil and an error message)
+	do := func(L *lua.LState, method, rawURL string, options *lua.LTable) int {
+		req, retry, err := luaOptionsToRequest(L, method, rawURL, options)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		ctx := L.Context()
+		if ctx == nil {
+			ctx = context.Background()
 		}
+		req.Policy = policy
+		req.Context = ctx
 
-		resp, err := httpClient.Get(req)
+		resp, err := doWithRetry(ctx, retry, func() (internalhttp.Response, error) {
+			return doInstrumentedHTTP(trace, httpTracker, executionID, req.Method, httpClient.Do, req)
+		})
 		if err != nil {
 			L.Push(lua.LNil)
 			L.Push(lua.LString(err.Error()))
@@ -30,80 +78,327 @@ func registerHTTP(L *lua.LState, httpClient internalhttp.Client) {
 		L.Push(httpResponseToLuaTable(L, resp))
 		L.Push(lua.LNil)
 		return 2
-	}))
-
-	// http.post(url, options)
-	L.SetField(httpTable, "post", L.NewFunction(func(L *lua.LState) int {
-		url := L.CheckString(1)
-		options := L.OptTable(2, L.NewTable())
+	}
 
-		req := internalhttp.Request{
-			URL:     url,
-			Headers: luaTableToHeaders(options.RawGetString("headers")),
-			Query:   luaTableToQuery(options.RawGetString("query")),
-			Body:    lua.LVAsString(options.RawGetString("body")),
-		}
+	// http.get(url, options), http.post(url, options), ...
+	for _, method := range []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"} {
+		L.SetField(httpTable, strings.ToLower(method), L.NewFunction(func(L *lua.LState) int {
+			rawURL := L.CheckString(1)
+			options := L.OptTable(2, L.NewTable())
+			return do(L, method, rawURL, options)
+		}))
+	}
 
-		resp, err := httpClient.Post(req)
+	// http.multipart({fields = {...}, files = {...}}) returns body, contentType
+	L.SetField(httpTable, "multipart", L.NewFunction(func(L *lua.LState) int {
+		body, contentType, err := buildMultipart(L.CheckTable(1))
 		if err != nil {
 			L.Push(lua.LNil)
 			L.Push(lua.LString(err.Error()))
 			return 2
 		}
-
-		L.Push(httpResponseToLuaTable(L, resp))
-		L.Push(lua.LNil)
+		L.Push(lua.LString(body))
+		L.Push(lua.LString(contentType))
 		return 2
 	}))
 
-	// http.put(url, options)
-	L.SetField(httpTable, "put", L.NewFunction(func(L *lua.LState) int {
-		url := L.CheckString(1)
-		options := L.OptTable(2, L.NewTable())
+	// http.request({method = "GET", url = "...", ...})
+	L.SetField(httpTable, "request", L.NewFunction(func(L *lua.LState) int {
+		options := L.CheckTable(1)
+		rawURL, ok := options.RawGetString("url").(lua.LString)
+		if !ok || rawURL == "" {
+			L.ArgError(1, "url is required")
+			return 0
+		}
+		method := "GET"
+		if m, ok := options.RawGetString("method").(lua.LString); ok && m != "" {
+			method = strings.ToUpper(string(m))
+		}
+		return do(L, method, string(rawURL), options)
+	}))
+
+	L.SetGlobal("http", httpTable)
+}
+
+// luaOptionsToRequest builds a request and its retry policy from the options
+// table shared by all http functions
+func luaOptionsToRequest(L *lua.LState, method, rawURL string, options *lua.LTable) (internalhttp.Request, retryPolicy, error) {
+	req := internalhttp.Request{
+		Method:  method,
+		URL:     rawURL,
+		Headers: luaTableToHeaders(options.RawGetString("headers")),
+		Query:   luaTableToQuery(options.RawGetString("query")),
+		Body:    lua.LVAsString(options.RawGetString("body")),
+	}
+	retry := retryPolicy{attempts: 1}
 
-		req := internalhttp.Request{
-			URL:     url,
-			Headers: luaTableToHeaders(options.RawGetString("headers")),
-			Query:   luaTableToQuery(options.RawGetString("query")),
-			Body:    lua.LVAsString(options.RawGetString("body")),
+	// json, form and multipart build the body and set its content type
+	bodyOptions := 0
+	for _, name := range []string{"body", "json", "form", "multipart"} {
+		if options.RawGetString(name) != lua.LNil {
+			bodyOptions++
 		}
+	}
+	if bodyOptions > 1 {
+		return req, retry, errors.New("only one of body, json, form or multipart can be set")
+	}
 
-		resp, err := httpClient.Put(req)
+	contentType := ""
+	if value := options.RawGetString("json"); value != lua.LNil {
+		body, err := json.Marshal(luaValueToGo(L, value))
 		if err != nil {
-			L.Push(lua.LNil)
-			L.Push(lua.LString(err.Error()))
-			return 2
+			return req, retry, fmt.Errorf("failed to encode json: %w", err)
+		}
+		req.Body, contentType = string(body), "application/json"
+	}
+	if value, ok := options.RawGetString("form").(*lua.LTable); ok {
+		req.Body, contentType = luaTableToURLValues(value).Encode(), "application/x-www-form-urlencoded"
+	}
+	if value, ok := options.RawGetString("multipart").(*lua.LTable); ok {
+		var err error
+		if req.Body, contentType, err = buildMultipart(value); err != nil {
+			return req, retry, err
 		}
+	}
+	if contentType != "" && !hasHeader(req.Headers, "Content-Type") {
+		req.Headers["Content-Type"] = contentType
+	}
 
-		L.Push(httpResponseToLuaTable(L, resp))
-		L.Push(lua.LNil)
-		return 2
-	}))
+	if auth, ok := options.RawGetString("auth").(*lua.LTable); ok {
+		username := lua.LVAsString(auth.RawGetString("username"))
+		password := lua.LVAsString(auth.RawGetString("password"))
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		req.Headers["Authorization"] = "Basic " + credentials
+	}
+
+	if value := options.RawGetString("timeout"); value != lua.LNil {
+		ms, ok := value.(lua.LNumber)
+		if !ok || ms <= 0 {
+			return req, retry, errors.New("timeout must be a positive number of milliseconds")
+		}
+		req.Timeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if value, ok := options.RawGetString("follow_redirects").(lua.LBool); ok {
+		req.NoRedirects = !bool(value)
+	}
+
+	if value, ok := options.RawGetString("retry").(*lua.LTable); ok {
+		if !slices.Contains(idempotentMethods, req.Method) {
+			return req, retry, fmt.Errorf("retry is not supported for %s requests", req.Method)
+		}
+		retry = retryPolicy{
+			attempts:   int(lua.LVAsNumber(value.RawGetString("attempts"))),
+			backoff:    time.Duration(lua.LVAsNumber(value.RawGetString("backoff"))) * time.Millisecond,
+			maxBackoff: time.Duration(lua.LVAsNumber(value.RawGetString("max_backoff"))) * time.Millisecond,
+		}
+		if retry.attempts <= 0 {
+			retry.attempts = 3
+		}
+		if retry.backoff <= 0 {
+			retry.backoff = 200 * time.Millisecond
+		}
+		if retry.maxBackoff <= 0 {
+			retry.maxBackoff = 5 * time.Second
+		}
+	}
+
+	return req, retry, nil
+}
+
+// buildMultipart encodes a multipart/form-data body from a table with
+// optional fields (name -> value or array of values) and files entries
+// ({field, filename, content, contentType}), returning the body and its
+// content type including the boundary
+func buildMultipart(spec *lua.LTable) (string, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if fields, ok := spec.RawGetString("fields").(*lua.LTable); ok {
+		values := luaTableToURLValues(fields)
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			for _, value := range values[key] {
+				if err := writer.WriteField(key, value); err != nil {
+					return "", "", fmt.Errorf("failed to write field %q: %w", key, err)
+				}
+			}
+		}
+	}
+
+	if files, ok := spec.RawGetString("files").(*lua.LTable); ok {
+		for i := 1; i <= files.Len(); i++ {
+			file, ok := files.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				return "", "", fmt.Errorf("files[%d] must be a table", i)
+			}
+			field := lua.LVAsString(file.RawGetString("field"))
+			if field == "" {
+				return "", "", fmt.Errorf("files[%d].field is required", i)
+			}
+			filename := lua.LVAsString(file.RawGetString("filename"))
+			if filename == "" {
+				filename = field
+			}
+			contentType := lua.LVAsString(file.RawGetString("contentType"))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+				"name":     field,
+				"filename": filename,
+			}))
+			header.Set("Content-Type", contentType)
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to write file %q: %w", filename, err)
+			}
+			if _, err := io.WriteString(part, lua.LVAsString(file.RawGetString("content"))); err != nil {
+				return "", "", fmt.Errorf("failed to write file %q: %w", filename, err)
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to build multipart body: %w", err)
+	}
+	return body.String(), writer.FormDataContentType(), nil
+}
+
+// doWithRetry calls do until it succeeds, fails with a non-retryable error
+// or the attempts run out, waiting with exponential backoff between attempts.
+// A Retry-After header in seconds overrides the backoff, up to maxBackoff.
+func doWithRetry(ctx context.Context, retry retryPolicy, do func() (internalhttp.Response, error)) (internalhttp.Response, error) {
+	delay := retry.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := do()
+		if attempt >= retry.attempts || !shouldRetry(resp, err) {
+			return resp, err
+		}
 
-	// http.delete(url, options)
-	L.SetField(httpTable, "delete", L.NewFunction(func(L *lua.LState) int {
-		url := L.CheckString(1)
-		options := L.OptTable(2, L.NewTable())
+		wait := delay
+		if seconds, parseErr := strconv.Atoi(resp.Headers["Retry-After"]); parseErr == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		wait = min(wait, retry.maxBackoff)
+
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, retry.maxBackoff)
+	}
+}
+
+// shouldRetry reports whether a request that ended with resp or err is worth
+// retrying. Requests blocked by the network policy are never retried.
+func shouldRetry(resp internalhttp.Response, err error) bool {
+	if err != nil {
+		var policyErr *internalhttp.PolicyError
+		return !errors.As(err, &policyErr)
+	}
+	return slices.Contains(retryStatusCodes, resp.StatusCode)
+}
+
+// doInstrumentedHTTP executes an outbound request inside a client span,
+// propagating the trace context, recording its count and latency, and
+// tracking it for the execution when a tracker is set
+func doInstrumentedHTTP(
+	trace *tracing.Trace,
+	httpTracker internalhttp.Tracker,
+	executionID string,
+	method string,
+	do func(internalhttp.Request) (internalhttp.Response, error),
+	req internalhttp.Request,
+) (internalhttp.Response, error) {
+	span := trace.Start("http."+strings.ToLower(method), store.SpanKindClient, map[string]string{
+		"http.request.method": method,
+		"url.full":            stripQuery(req.URL),
+	})
+	defer span.End()
 
-		req := internalhttp.Request{
-			URL:     url,
-			Headers: luaTableToHeaders(options.RawGetString("headers")),
-			Query:   luaTableToQuery(options.RawGetString("query")),
+	if span != nil && !hasHeader(req.Headers, tracing.TraceparentHeader) {
+		if req.Headers == nil {
+			req.Headers = make(internalhttp.Headers)
 		}
+		req.Headers[tracing.TraceparentHeader] = span.Traceparent()
+	}
+
+	startTime := time.Now()
+	resp, err := do(req)
+	duration := time.Since(startTime)
 
-		resp, err := httpClient.Delete(req)
+	if httpTracker != nil {
+		trackReq := internalhttp.TrackRequest{
+			Method:         method,
+			URL:            requestURL(req),
+			RequestHeaders: req.Headers,
+			RequestBody:    req.Body,
+			DurationMs:     duration.Milliseconds(),
+		}
 		if err != nil {
-			L.Push(lua.LNil)
-			L.Push(lua.LString(err.Error()))
-			return 2
+			errMsg := err.Error()
+			trackReq.ErrorMessage = &errMsg
+		} else {
+			trackReq.StatusCode = resp.StatusCode
+			trackReq.ResponseHeaders = resp.Headers
+			trackReq.ResponseBody = resp.Body
 		}
+		httpTracker.Track(executionID, trackReq)
+	}
 
-		L.Push(httpResponseToLuaTable(L, resp))
-		L.Push(lua.LNil)
-		return 2
-	}))
+	status := "error"
+	if err == nil {
+		status = metrics.StatusLabel(resp.StatusCode)
+		span.SetAttribute("http.response.status_code", status)
+		if resp.IsError() {
+			span.SetError("HTTP " + status)
+		}
+	} else {
+		span.SetError(err.Error())
+	}
+	metrics.HTTPClientRequestsTotal.Inc(method, status)
+	metrics.HTTPClientRequestDuration.Observe(duration.Seconds(), method)
 
-	L.SetGlobal("http", httpTable)
+	return resp, err
+}
+
+// requestURL returns the URL of req with its query parameters applied
+func requestURL(req internalhttp.Request) string {
+	if len(req.Query) == 0 {
+		return req.URL
+	}
+	parsed, err := url.Parse(req.URL)
+	if err != nil {
+		return req.URL
+	}
+	q := parsed.Query()
+	for key, value := range req.Query {
+		q.Set(key, value)
+	}
+	parsed.RawQuery = q.Encode()
+	return parsed.String()
+}
+
+// hasHeader reports whether headers contains name, ignoring case
+func hasHeader(headers internalhttp.Headers, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripQuery removes the query string and fragment from a URL so secrets passed
+// as query parameters are never recorded in span attributes
+func stripQuery(rawURL string) string {
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
 }
 
 // luaTableToHeaders converts a Lua table to HTTP headers map
@@ -141,5 +436,26 @@ func httpResponseToLuaTable(L *lua.LState, resp internalhttp.Response) *lua.LTab
 	}
 	L.SetField(tbl, "headers", headersTbl)
 
+	// Decode JSON responses so handlers do not need to call json.decode
+	if isJSONContentType(resp.Headers) {
+		var value any
+		if err := json.Unmarshal([]byte(resp.Body), &value); err == nil {
+			L.SetField(tbl, "json", goValueToLua(L, value))
+		}
+	}
+
 	return tbl
 }
+
+// isJSONContentType reports whether headers declare a JSON body, including
+// suffixed types such as application/problem+json
+func isJSONContentType(headers internalhttp.Headers) bool {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			mediaType, _, _ := strings.Cut(strings.ToLower(v), ";")
+			mediaType = strings.TrimSpace(mediaType)
+			return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+		}
+	}
+	return false
+}
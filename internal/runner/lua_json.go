@@ -116,6 +116,9 @@ func goValueToLua(L *lua.LState, v any) lua.LValue {
 		return lua.LBool(val)
 	case float64:
 		return lua.LNumber(val)
+	case json.Number:
+		f, _ := val.Float64()
+		return lua.LNumber(f)
 	case string:
 		return lua.LString(val)
 	case []any:
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/jose"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// defaultJWTAlgorithm is used by jwt.sign when no alg option is given
+const defaultJWTAlgorithm = "HS256"
+
+// registerJWT registers the jwt module for signing and verifying JSON Web Tokens
+func registerJWT(L *lua.LState) {
+	jwtModule := L.NewTable()
+
+	L.SetField(jwtModule, "sign", L.NewFunction(jwtSign))
+	L.SetField(jwtModule, "verify", L.NewFunction(jwtVerify))
+	L.SetField(jwtModule, "decode", L.NewFunction(jwtDecode))
+
+	// Set the jwt module as a global
+	L.SetGlobal("jwt", jwtModule)
+}
+
+// jwtSign signs a claims table as a JWT. The key is an HMAC secret, a PEM
+// private key or a private JWK.
+// Usage: local token, err = jwt.sign(claims, key, {alg = "RS256", kid = "key-1", expires_in = 3600, header = {}})
+func jwtSign(L *lua.LState) int {
+	claimsTable := L.CheckTable(1)
+	keyData := L.CheckString(2)
+	options := L.OptTable(3, L.NewTable())
+
+	claims, ok := luaValueToGo(L, claimsTable).(map[string]any)
+	if !ok {
+		L.ArgError(1, "claims must be a table with string keys")
+		return 0
+	}
+
+	alg := defaultJWTAlgorithm
+	if v, ok := options.RawGetString("alg").(lua.LString); ok {
+		alg = string(v)
+	}
+
+	header := map[string]any{}
+	if v, ok := options.RawGetString("header").(*lua.LTable); ok {
+		if h, ok := luaValueToGo(L, v).(map[string]any); ok {
+			header = h
+		}
+	}
+	if v, ok := options.RawGetString("kid").(lua.LString); ok {
+		header["kid"] = string(v)
+	}
+
+	if v, ok := options.RawGetString("expires_in").(lua.LNumber); ok {
+		now := time.Now().Unix()
+		if _, ok := claims["iat"]; !ok {
+			claims["iat"] = now
+		}
+		claims["exp"] = now + int64(v)
+	}
+
+	keys, err := jose.ParseKeys(keyData)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	token, err := jose.SignJWT(claims, alg, keys[0], header)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LString(token))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// jwtVerify verifies a JWT signature and its exp, nbf, iat, aud and iss
+// claims, returning the claims. The key is an HMAC secret, a PEM public key
+// or certificate, a JWK or a JWKS document.
+// Usage: local claims, err = jwt.verify(token, key, {algorithms = {"RS256"}, audience = "api", issuer = "https://issuer", leeway = 30})
+func jwtVerify(L *lua.LState) int {
+	token := L.CheckString(1)
+	keyData := L.CheckString(2)
+	options := L.OptTable(3, L.NewTable())
+
+	opts, err := jwtVerifyOptions(options)
+	if err != nil {
+		L.ArgError(3, err.Error())
+		return 0
+	}
+
+	keys, err := jose.ParseKeys(keyData)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	verified, err := jose.VerifyJWT(token, keys, opts)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(goValueToLua(L, verified.Claims))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// jwtDecode decodes a JWT without verifying it, e.g. to read the kid or iss
+// before choosing a key. Never trust claims returned by decode.
+// Usage: local token, err = jwt.decode(token) -- token.header, token.claims
+func jwtDecode(L *lua.LState) int {
+	token := L.CheckString(1)
+
+	decoded, err := jose.DecodeJWT(token)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "header", goValueToLua(L, decoded.Header))
+	L.SetField(result, "claims", goValueToLua(L, decoded.Claims))
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// jwtVerifyOptions converts the jwt.verify options table
+func jwtVerifyOptions(options *lua.LTable) (jose.VerifyOptions, error) {
+	var opts jose.VerifyOptions
+
+	switch v := options.RawGetString("algorithms").(type) {
+	case *lua.LTable:
+		for i := 1; i <= v.Len(); i++ {
+			opts.Algorithms = append(opts.Algorithms, lua.LVAsString(v.RawGetInt(i)))
+		}
+	case *lua.LNilType:
+	default:
+		return opts, errors.New("algorithms must be a list of algorithm names")
+	}
+	if v, ok := options.RawGetString("alg").(lua.LString); ok {
+		opts.Algorithms = append(opts.Algorithms, string(v))
+	}
+
+	if v, ok := options.RawGetString("audience").(lua.LString); ok {
+		opts.Audience = string(v)
+	}
+	if v, ok := options.RawGetString("issuer").(lua.LString); ok {
+		opts.Issuer = string(v)
+	}
+	if v, ok := options.RawGetString("leeway").(lua.LNumber); ok {
+		opts.Leeway = time.Duration(v) * time.Second
+	}
+	return opts, nil
+}
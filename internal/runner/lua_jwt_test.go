@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+// luaString quotes s as a Lua long string
+func luaString(s string) string {
+	return "[==[" + s + "]==]"
+}
+
+func TestRun_JWT_SignVerify(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local token, err = jwt.sign({sub = "user-1", aud = "api", roles = {"admin"}}, "secret", {expires_in = 60, kid = "k1"})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local claims, verifyErr = jwt.verify(token, "secret", {audience = "api", algorithms = {"HS256"}})
+	if verifyErr then
+		return { statusCode = 500, body = verifyErr }
+	end
+
+	local decoded = jwt.decode(token)
+	local _, wrongKey = jwt.verify(token, "other")
+	local _, wrongAudience = jwt.verify(token, "secret", {audience = "web"})
+	local _, wrongAlg = jwt.verify(token, "secret", {alg = "HS512"})
+
+	return {
+		statusCode = 200,
+		body = table.concat({
+			claims.sub, claims.roles[1], tostring(claims.exp - claims.iat),
+			decoded.header.alg, decoded.header.kid,
+			wrongKey, wrongAudience, wrongAlg,
+		}, "|")
+	}
+end
+`)
+
+	expected := "user-1|admin|60|HS256|k1|invalid signature|token audience does not match|algorithm HS256 is not allowed"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_JWT_Expired(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local token = jwt.sign({sub = "user-1"}, "secret", {expires_in = -120})
+	local _, err = jwt.verify(token, "secret")
+	local claims = jwt.verify(token, "secret", {leeway = 300})
+	return { statusCode = 200, body = err .. "|" .. claims.sub }
+end
+`)
+
+	if body != "token is expired|user-1" {
+		t.Errorf("expected expired token, got %q", body)
+	}
+}
+
+func TestRun_JWT_ECDSAWithJWKS(t *testing.T) {
+	private, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	privatePEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	point, _ := private.PublicKey.Bytes()
+	jwks := `{"keys":[{"kty":"EC","crv":"P-256","kid":"ec-1","x":"` +
+		base64.RawURLEncoding.EncodeToString(point[1:33]) + `","y":"` +
+		base64.RawURLEncoding.EncodeToString(point[33:]) + `"}]}`
+
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local token, err = jwt.sign({iss = "https://issuer.example"}, `+luaString(privatePEM)+`, {alg = "ES256", kid = "ec-1"})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local claims, verifyErr = jwt.verify(token, `+luaString(jwks)+`, {issuer = "https://issuer.example"})
+	if verifyErr then
+		return { statusCode = 500, body = verifyErr }
+	end
+
+	-- The public key cannot be used as an HMAC secret
+	local forged = jwt.sign({iss = "https://issuer.example"}, `+luaString(jwks)+`)
+	local _, forgedErr = jwt.verify(forged or "", `+luaString(jwks)+`)
+
+	return { statusCode = 200, body = claims.iss .. "|" .. tostring(forgedErr ~= nil) }
+end
+`)
+
+	if body != "https://issuer.example|true" {
+		t.Errorf("expected verified ES256 token, got %q", body)
+	}
+}
+
+func TestRun_JWT_Decode_Invalid(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local token, err = jwt.decode("not-a-token")
+	return { statusCode = 200, body = tostring(token) .. "|" .. err }
+end
+`)
+
+	if !strings.HasPrefix(body, "nil|malformed token") {
+		t.Errorf("expected malformed token error, got %q", body)
+	}
+}
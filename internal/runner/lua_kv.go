@@ -2,21 +2,31 @@ package runner
 
 import (
 	"github.com/dimiro1/lunar/internal/kv"
+	"github.com/dimiro1/lunar/internal/metrics"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 	lua "github.com/yuin/gopher-lua"
 )
 
 // registerKV creates the global 'kv' table with key-value storage functions
-func registerKV(L *lua.LState, kvStore kv.Store, functionID string) {
+func registerKV(L *lua.LState, kvStore kv.Store, functionID string, trace *tracing.Trace) {
 	kvTable := L.NewTable()
 
 	// kv.get(key)
 	L.SetField(kvTable, "get", L.NewFunction(func(L *lua.LState) int {
 		key := L.CheckString(1)
+		span := trace.Start("kv.get", store.SpanKindInternal, map[string]string{"kv.key": key})
+		defer span.End()
+
 		value, err := kvStore.Get(functionID, key)
 		if err != nil {
+			span.SetAttribute("kv.hit", "false")
+			metrics.KVOperationsTotal.Inc("get", "miss")
 			L.Push(lua.LNil)
 			return 1
 		}
+		span.SetAttribute("kv.hit", "true")
+		metrics.KVOperationsTotal.Inc("get", "hit")
 		L.Push(lua.LString(value))
 		return 1
 	}))
@@ -25,11 +35,17 @@ func registerKV(L *lua.LState, kvStore kv.Store, functionID string) {
 	L.SetField(kvTable, "set", L.NewFunction(func(L *lua.LState) int {
 		key := L.CheckString(1)
 		value := L.CheckString(2)
+		span := trace.Start("kv.set", store.SpanKindInternal, map[string]string{"kv.key": key})
+		defer span.End()
+
 		err := kvStore.Set(functionID, key, value)
 		if err != nil {
+			span.SetError(err.Error())
+			metrics.KVOperationsTotal.Inc("set", "error")
 			L.Push(lua.LFalse)
 			return 1
 		}
+		metrics.KVOperationsTotal.Inc("set", "ok")
 		L.Push(lua.LTrue)
 		return 1
 	}))
@@ -37,11 +53,17 @@ func registerKV(L *lua.LState, kvStore kv.Store, functionID string) {
 	// kv.delete(key)
 	L.SetField(kvTable, "delete", L.NewFunction(func(L *lua.LState) int {
 		key := L.CheckString(1)
+		span := trace.Start("kv.delete", store.SpanKindInternal, map[string]string{"kv.key": key})
+		defer span.End()
+
 		err := kvStore.Delete(functionID, key)
 		if err != nil {
+			span.SetError(err.Error())
+			metrics.KVOperationsTotal.Inc("delete", "error")
 			L.Push(lua.LFalse)
 			return 1
 		}
+		metrics.KVOperationsTotal.Inc("delete", "ok")
 		L.Push(lua.LTrue)
 		return 1
 	}))
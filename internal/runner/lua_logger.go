@@ -9,33 +9,59 @@ import (
 func registerLogger(L *lua.LState, log logger.Logger, executionID string) {
 	logTable := L.NewTable()
 
-	// log.info(message)
-	L.SetField(logTable, "info", L.NewFunction(func(L *lua.LState) int {
-		message := L.CheckString(1)
-		log.Info(executionID, message)
-		return 0
-	}))
+	// log.info(message, fields?)
+	L.SetField(logTable, "info", L.NewFunction(logFunction(log, executionID, logger.Info)))
 
-	// log.debug(message)
-	L.SetField(logTable, "debug", L.NewFunction(func(L *lua.LState) int {
-		message := L.CheckString(1)
-		log.Debug(executionID, message)
-		return 0
-	}))
+	// log.debug(message, fields?)
+	L.SetField(logTable, "debug", L.NewFunction(logFunction(log, executionID, logger.Debug)))
 
-	// log.warn(message)
-	L.SetField(logTable, "warn", L.NewFunction(func(L *lua.LState) int {
-		message := L.CheckString(1)
-		log.Warn(executionID, message)
-		return 0
-	}))
+	// log.warn(message, fields?)
+	L.SetField(logTable, "warn", L.NewFunction(logFunction(log, executionID, logger.Warn)))
+
+	// log.error(message, fields?)
+	L.SetField(logTable, "error", L.NewFunction(logFunction(log, executionID, logger.Error)))
+
+	L.SetGlobal("log", logTable)
+}
 
-	// log.error(message)
-	L.SetField(logTable, "error", L.NewFunction(func(L *lua.LState) int {
+// logFunction returns a Lua function that writes a log entry at level.
+// The optional second argument is a table of structured fields.
+func logFunction(log logger.Logger, executionID string, level logger.LogLevel) lua.LGFunction {
+	return func(L *lua.LState) int {
 		message := L.CheckString(1)
-		log.Error(executionID, message)
+
+		var fields map[string]any
+		if tbl := L.OptTable(2, nil); tbl != nil {
+			converted, ok := luaValueToGo(L, tbl).(map[string]any)
+			if !ok {
+				L.ArgError(2, "fields must be a table with string keys")
+				return 0
+			}
+			if len(converted) > 0 {
+				fields = converted
+			}
+		}
+
+		log.Write(logger.LogEntry{
+			ExecutionID: executionID,
+			Level:       level,
+			Message:     message,
+			Fields:      fields,
+			Line:        callerLine(L),
+		})
 		return 0
-	}))
+	}
+}
 
-	L.SetGlobal("log", logTable)
+// callerLine returns the current line of the Lua function calling into Go,
+// or 0 when it is not available
+func callerLine(L *lua.LState) int {
+	dbg, ok := L.GetStack(1)
+	if !ok {
+		return 0
+	}
+	if _, err := L.GetInfo("l", dbg, lua.LNil); err != nil {
+		return 0
+	}
+	return max(dbg.CurrentLine, 0)
 }
@@ -0,0 +1,191 @@
+package runner
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxCachedRegexps is the number of compiled patterns kept in the cache
+const maxCachedRegexps = 512
+
+// regexCache holds compiled patterns shared by all executions, so patterns
+// used on every request are compiled once
+var regexCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// compileRegex returns the compiled pattern, compiling and caching it on
+// first use. The cache is cleared when full so dynamic patterns cannot grow
+// it without bound.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+
+	if re, ok := regexCache.patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(regexCache.patterns) >= maxCachedRegexps {
+		clear(regexCache.patterns)
+	}
+	regexCache.patterns[pattern] = re
+	return re, nil
+}
+
+// registerRegex registers the regex module with Go regular expressions (RE2 syntax)
+func registerRegex(L *lua.LState) {
+	regexModule := L.NewTable()
+
+	L.SetField(regexModule, "match", L.NewFunction(regexMatch))
+	L.SetField(regexModule, "find", L.NewFunction(regexFind))
+	L.SetField(regexModule, "findAll", L.NewFunction(regexFindAll))
+	L.SetField(regexModule, "replace", L.NewFunction(regexReplace))
+	L.SetField(regexModule, "split", L.NewFunction(regexSplit))
+	L.SetField(regexModule, "escape", L.NewFunction(regexEscape))
+
+	// Set the regex module as a global
+	L.SetGlobal("regex", regexModule)
+}
+
+// checkRegex compiles the pattern in argument n, raising an argument error
+// if it is invalid
+func checkRegex(L *lua.LState, n int) *regexp.Regexp {
+	re, err := compileRegex(L.CheckString(n))
+	if err != nil {
+		L.ArgError(n, err.Error())
+		return nil
+	}
+	return re
+}
+
+// regexMatch reports whether the string contains a match of the pattern
+// Usage: local ok = regex.match(pattern, str)
+func regexMatch(L *lua.LState) int {
+	re := checkRegex(L, 1)
+	str := L.CheckString(2)
+	L.Push(lua.LBool(re.MatchString(str)))
+	return 1
+}
+
+// regexFind returns the first match with its capture groups, or nil
+// Usage: local m = regex.find(pattern, str) -- m.match, m.groups[1], m.named.year
+func regexFind(L *lua.LState) int {
+	re := checkRegex(L, 1)
+	str := L.CheckString(2)
+
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(regexMatchTable(L, re, str, loc))
+	return 1
+}
+
+// regexFindAll returns all matches, or at most n matches if n is given
+// Usage: local matches = regex.findAll(pattern, str, n?)
+func regexFindAll(L *lua.LState) int {
+	re := checkRegex(L, 1)
+	str := L.CheckString(2)
+	n := L.OptInt(3, -1)
+
+	result := L.NewTable()
+	for _, loc := range re.FindAllStringSubmatchIndex(str, n) {
+		result.Append(regexMatchTable(L, re, str, loc))
+	}
+	L.Push(result)
+	return 1
+}
+
+// regexReplace replaces all matches. The replacement is either a string,
+// where $1 and ${name} expand to capture groups, or a function that receives
+// the match table and returns the replacement string.
+// Usage: local result = regex.replace(pattern, str, "$2-$1")
+// Usage: local result = regex.replace(pattern, str, function(m) return m.groups[1]:upper() end)
+func regexReplace(L *lua.LState) int {
+	re := checkRegex(L, 1)
+	str := L.CheckString(2)
+
+	switch replacement := L.Get(3).(type) {
+	case lua.LString:
+		L.Push(lua.LString(re.ReplaceAllString(str, string(replacement))))
+	case *lua.LFunction:
+		var out strings.Builder
+		last := 0
+		for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+			out.WriteString(str[last:loc[0]])
+			L.Push(replacement)
+			L.Push(regexMatchTable(L, re, str, loc))
+			L.Call(1, 1)
+			out.WriteString(lua.LVAsString(L.Get(-1)))
+			L.Pop(1)
+			last = loc[1]
+		}
+		out.WriteString(str[last:])
+		L.Push(lua.LString(out.String()))
+	default:
+		L.ArgError(3, "replacement must be a string or a function")
+		return 0
+	}
+	return 1
+}
+
+// regexSplit splits a string around matches of the pattern
+// Usage: local parts = regex.split(pattern, str, n?)
+func regexSplit(L *lua.LState) int {
+	re := checkRegex(L, 1)
+	str := L.CheckString(2)
+	n := L.OptInt(3, -1)
+
+	result := L.NewTable()
+	for _, part := range re.Split(str, n) {
+		result.Append(lua.LString(part))
+	}
+	L.Push(result)
+	return 1
+}
+
+// regexEscape escapes all regular expression metacharacters in a string
+// Usage: local pattern = regex.escape(str)
+func regexEscape(L *lua.LState) int {
+	str := L.CheckString(1)
+	L.Push(lua.LString(regexp.QuoteMeta(str)))
+	return 1
+}
+
+// regexMatchTable converts a submatch index slice into a table with the
+// full match, the positional groups and the named groups. Groups that did
+// not participate in the match are empty strings.
+func regexMatchTable(L *lua.LState, re *regexp.Regexp, str string, loc []int) *lua.LTable {
+	groups := L.NewTable()
+	named := L.NewTable()
+	for i, name := range re.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		value := ""
+		if loc[2*i] >= 0 {
+			value = str[loc[2*i]:loc[2*i+1]]
+		}
+		groups.Append(lua.LString(value))
+		if name != "" {
+			L.SetField(named, name, lua.LString(value))
+		}
+	}
+
+	match := L.NewTable()
+	L.SetField(match, "match", lua.LString(str[loc[0]:loc[1]]))
+	L.SetField(match, "start", lua.LNumber(loc[0]+1))
+	L.SetField(match, "stop", lua.LNumber(loc[1]))
+	L.SetField(match, "groups", groups)
+	L.SetField(match, "named", named)
+	return match
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_Regex(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local pattern = "(?P<year>\\d{4})-(?P<month>\\d{2})"
+	local text = "from 2024-01 to 2025-12"
+
+	local m = regex.find(pattern, text)
+	local all = regex.findAll(pattern, text)
+	local first = regex.findAll(pattern, text, 1)
+
+	return {
+		statusCode = 200,
+		body = table.concat({
+			tostring(regex.match("^\\d+$", "12345")),
+			tostring(regex.match("^\\d+$", "12a45")),
+			m.match, m.groups[1], m.named.month, m.start .. ":" .. m.stop,
+			tostring(#all), all[2].named.year, tostring(#first),
+			tostring(regex.find("x", "abc")),
+			regex.replace(pattern, text, "${month}/$year"),
+			regex.replace("\\d+", "a1b22", function(m) return "<" .. #m.match .. ">" end),
+			table.concat(regex.split("\\s*,\\s*", "a , b,c"), "|"),
+			regex.escape("1.5*2"),
+		}, " ")
+	}
+end
+`)
+
+	expected := "true false 2024-01 2024 01 6:12 2 2025 1 nil from 01/2024 to 12/2025 a<1>b<2> a|b|c 1\\.5\\*2"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Regex_InvalidPattern(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local ok, err = pcall(regex.match, "(", "abc")
+	return { statusCode = 200, body = tostring(ok) .. " " .. err }
+end
+`)
+
+	if !strings.HasPrefix(body, "false ") || !strings.Contains(body, "missing closing )") {
+		t.Errorf("expected invalid pattern error, got %q", body)
+	}
+}
+
+func TestCompileRegex_Cache(t *testing.T) {
+	first, err := compileRegex(`\w+`)
+	if err != nil {
+		t.Fatalf("compileRegex failed: %v", err)
+	}
+	second, _ := compileRegex(`\w+`)
+	if first != second {
+		t.Error("expected the cached pattern to be reused")
+	}
+}
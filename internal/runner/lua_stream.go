@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"errors"
+	"net/http"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ResponseStream writes the HTTP response of an execution as the handler
+// produces it
+type ResponseStream interface {
+	// Start sends the status code and headers. It is called once, before the
+	// first Write.
+	Start(statusCode int, headers map[string]string) error
+	// Write sends a chunk of the body to the client right away.
+	Write(chunk string) error
+}
+
+// streamedResponse tracks whether the handler has started streaming its
+// response
+type streamedResponse struct {
+	stream     ResponseStream
+	started    bool
+	statusCode int
+}
+
+// available reports whether the response can be streamed
+func (r *streamedResponse) available() bool {
+	return r.stream != nil
+}
+
+// start sends the status code and headers
+func (r *streamedResponse) start(statusCode int, headers map[string]string) error {
+	if r.stream == nil {
+		return errors.New("response streaming is not available")
+	}
+	if r.started {
+		return errors.New("response already started")
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	r.started = true
+	r.statusCode = statusCode
+	return r.stream.Start(statusCode, headers)
+}
+
+// write sends a chunk of the body, starting a 200 response if needed
+func (r *streamedResponse) write(chunk string) error {
+	if !r.started {
+		if err := r.start(http.StatusOK, nil); err != nil {
+			return err
+		}
+	}
+	return r.stream.Write(chunk)
+}
+
+// registerStream creates the global 'stream' table for streaming the HTTP
+// response
+func registerStream(L *lua.LState, response *streamedResponse) {
+	streamTable := L.NewTable()
+
+	// stream.start(options)
+	// Usage: local ok, err = stream.start({statusCode = 200, headers = {["Content-Type"] = "text/plain"}})
+	L.SetField(streamTable, "start", L.NewFunction(func(L *lua.LState) int {
+		options := L.OptTable(1, L.NewTable())
+
+		statusCode := int(lua.LVAsNumber(options.RawGetString("statusCode")))
+		headers := make(map[string]string)
+		if headersTbl, ok := options.RawGetString("headers").(*lua.LTable); ok {
+			headersTbl.ForEach(func(k, v lua.LValue) {
+				headers[k.String()] = v.String()
+			})
+		}
+
+		if err := response.start(statusCode, headers); err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}))
+
+	// stream.write(chunk)
+	// Usage: local ok, err = stream.write("data: hello\n\n")
+	L.SetField(streamTable, "write", L.NewFunction(func(L *lua.LState) int {
+		chunk := L.CheckString(1)
+
+		if err := response.write(chunk); err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}))
+
+	L.SetGlobal("stream", streamTable)
+}
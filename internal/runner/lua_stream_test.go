@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/env"
+	"github.com/dimiro1/lunar/internal/events"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/kv"
+	"github.com/dimiro1/lunar/internal/logger"
+)
+
+// fakeResponseStream records a streamed response
+type fakeResponseStream struct {
+	statusCode int
+	headers    map[string]string
+	chunks     []string
+	err        error // Returned by Write when set
+}
+
+func (s *fakeResponseStream) Start(statusCode int, headers map[string]string) error {
+	s.statusCode = statusCode
+	s.headers = headers
+	return nil
+}
+
+func (s *fakeResponseStream) Write(chunk string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func runStreamHandler(t *testing.T, stream ResponseStream, luaCode string) Response {
+	t.Helper()
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   internalhttp.NewFakeClient(),
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode, Stream: stream})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp
+}
+
+func TestRun_Stream(t *testing.T) {
+	stream := &fakeResponseStream{}
+	resp := runStreamHandler(t, stream, `
+function handler(ctx, event)
+	stream.start({statusCode = 202, headers = {["Content-Type"] = "text/event-stream"}})
+	stream.write("data: 1\n\n")
+	local ok, err = stream.start()
+	stream.write("data: " .. tostring(ok) .. " " .. err .. "\n\n")
+	return { statusCode = 500, body = "data: end\n\n" }
+end
+`)
+
+	if !resp.Streamed || resp.HTTP.StatusCode != 202 {
+		t.Errorf("expected a streamed 202 response, got %+v %+v", resp, resp.HTTP)
+	}
+	if stream.statusCode != 202 || stream.headers["Content-Type"] != "text/event-stream" {
+		t.Errorf("unexpected start: %d %v", stream.statusCode, stream.headers)
+	}
+	expected := "data: 1\n\n|data: false response already started\n\n|data: end\n\n"
+	if strings.Join(stream.chunks, "|") != expected {
+		t.Errorf("unexpected chunks: %q", stream.chunks)
+	}
+}
+
+func TestRun_Stream_WriteStartsResponse(t *testing.T) {
+	stream := &fakeResponseStream{}
+	resp := runStreamHandler(t, stream, `
+function handler(ctx, event)
+	stream.write("hello")
+end
+`)
+
+	if !resp.Streamed || stream.statusCode != 200 || strings.Join(stream.chunks, "") != "hello" {
+		t.Errorf("expected an implicit 200 response, got %d %q", stream.statusCode, stream.chunks)
+	}
+}
+
+func TestRun_Stream_WriteError(t *testing.T) {
+	stream := &fakeResponseStream{err: errors.New("client disconnected")}
+	resp := runStreamHandler(t, stream, `
+function handler(ctx, event)
+	local ok, err = stream.write("hello")
+	log.info(tostring(ok) .. " " .. err)
+end
+`)
+
+	if !resp.Streamed {
+		t.Error("expected a streamed response")
+	}
+}
+
+func TestRun_Stream_NotAvailable(t *testing.T) {
+	resp := runStreamHandler(t, nil, `
+function handler(ctx, event)
+	local ok, err = stream.write("hello")
+	return { statusCode = 200, body = tostring(ok) .. "," .. err }
+end
+`)
+
+	if resp.Streamed || resp.HTTP.Body != "false,response streaming is not available" {
+		t.Errorf("unexpected response: %+v", resp.HTTP)
+	}
+}
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// mainTemplateName is the name of the template passed to render; partials
+// are available under their own names
+const mainTemplateName = "main"
+
+// MaxTemplateOutputSize limits the size of a rendered template (10MB)
+const MaxTemplateOutputSize = 10 * 1024 * 1024
+
+// templateErrorSource matches the template name in Go template errors, e.g.
+// "template: main:3: ..." or "html/template:header:1:11: ..."
+var templateErrorSource = regexp.MustCompile(`template:\s?([^:\s]+):\d+`)
+
+// registerTemplate registers the template module for rendering HTML and text
+func registerTemplate(L *lua.LState) {
+	templateModule := L.NewTable()
+
+	L.SetField(templateModule, "render", L.NewFunction(templateRender(true)))
+	L.SetField(templateModule, "renderText", L.NewFunction(templateRender(false)))
+
+	// Set the template module as a global
+	L.SetGlobal("template", templateModule)
+}
+
+// templateRender renders a Go template with a Lua table as data. HTML
+// templates escape values for the context they appear in; text templates
+// do not escape. Errors include the template line and code context.
+// Usage: local html, err = template.render(source, data, {partials = {header = "..."}})
+// Usage: local text, err = template.renderText(source, data, {partials = {...}})
+func templateRender(html bool) lua.LGFunction {
+	return func(L *lua.LState) int {
+		source := L.CheckString(1)
+		data := luaValueToGo(L, L.Get(2))
+		options := L.OptTable(3, L.NewTable())
+
+		sources := map[string]string{mainTemplateName: source}
+		if partials, ok := options.RawGetString("partials").(*lua.LTable); ok {
+			if partials.RawGetString(mainTemplateName) != lua.LNil {
+				// It would replace the template being rendered
+				L.Push(lua.LNil)
+				L.Push(lua.LString(`partial name "` + mainTemplateName + `" is reserved for the rendered template`))
+				return 2
+			}
+			partials.ForEach(func(k, v lua.LValue) {
+				if name, ok := k.(lua.LString); ok {
+					sources[string(name)] = lua.LVAsString(v)
+				}
+			})
+		}
+
+		output, err := renderTemplate(sources, data, html)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(enhanceTemplateError(err, sources).Error()))
+			return 2
+		}
+
+		L.Push(lua.LString(output))
+		L.Push(lua.LNil)
+		return 2
+	}
+}
+
+// renderTemplate parses the main template and its partials and executes the
+// main template with data
+func renderTemplate(sources map[string]string, data any, html bool) (string, error) {
+	// Partials are parsed in name order so errors are deterministic
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		if name != mainTemplateName {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var out strings.Builder
+	w := &limitedWriter{w: &out, limit: MaxTemplateOutputSize}
+
+	if html {
+		tmpl, err := htmltemplate.New(mainTemplateName).Parse(sources[mainTemplateName])
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			if _, err := tmpl.New(name).Parse(sources[name]); err != nil {
+				return "", err
+			}
+		}
+		if err := tmpl.Execute(w, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+
+	tmpl, err := texttemplate.New(mainTemplateName).Parse(sources[mainTemplateName])
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if _, err := tmpl.New(name).Parse(sources[name]); err != nil {
+			return "", err
+		}
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// enhanceTemplateError adds the line and code context of the template that
+// failed, which may be a partial, to a template error
+func enhanceTemplateError(err error, sources map[string]string) error {
+	var source string
+	if matches := templateErrorSource.FindStringSubmatch(err.Error()); matches != nil {
+		source = sources[matches[1]]
+	}
+	return EnhanceError(err, source)
+}
+
+// errTemplateOutputTooLarge is returned when a template renders more than
+// MaxTemplateOutputSize bytes
+var errTemplateOutputTooLarge = errors.New("template output exceeds 10MB")
+
+// limitedWriter fails writes once limit bytes have been written
+type limitedWriter struct {
+	w     io.Writer
+	limit int
+	n     int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n+len(p) > l.limit {
+		return 0, errTemplateOutputTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n += n
+	return n, err
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_Template_Render(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local html, err = template.render([[
+{{template "header" .}}<ul>{{range .items}}<li>{{.}}</li>{{end}}</ul><a href="{{.link}}">{{.count}}</a>]], {
+		title = "<script>alert(1)</script>",
+		items = {"a & b", "c"},
+		link = "javascript:alert(1)",
+		count = 3,
+	}, {
+		partials = { header = "<h1>{{.title}}</h1>" },
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return { statusCode = 200, body = html }
+end
+`)
+
+	expected := `<h1>&lt;script&gt;alert(1)&lt;/script&gt;</h1><ul><li>a &amp; b</li><li>c</li></ul><a href="#ZgotmplZ">3</a>`
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Template_RenderText(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local text, err = template.renderText("Hi {{.name}},\n{{template \"signature\"}}", { name = "<Ann>" }, {
+		partials = { signature = "-- The Team" },
+	})
+	return { statusCode = 200, body = text or err }
+end
+`)
+
+	if body != "Hi <Ann>,\n-- The Team" {
+		t.Errorf("expected unescaped text, got %q", body)
+	}
+}
+
+func TestRun_Template_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     string
+		contains []string
+	}{
+		{
+			name:     "parse error",
+			call:     `template.render("<p>\n{{.name | nope}}</p>", {})`,
+			contains: []string{"Error at line 2", `function "nope" not defined`, ">   2 | {{.name | nope}}</p>", "[TIP]"},
+		},
+		{
+			name:     "error in partial",
+			call:     `template.render("{{template \"row\" .}}", {}, {partials = {row = "<tr>\n<td>{{index .items 3}}</td>"}})`,
+			contains: []string{"Error at line 2", "error calling index", ">   2 | <td>{{index .items 3}}</td>"},
+		},
+		{
+			name:     "partial named main",
+			call:     `template.render("<p>{{.name}}</p>", {name = "Ann"}, {partials = {main = "replaced"}})`,
+			contains: []string{`partial name "main" is reserved`},
+		},
+		{
+			name:     "missing partial",
+			call:     `template.renderText("{{template \"footer\"}}", {})`,
+			contains: []string{`template "footer" not defined`, "Error at line 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local output, err = `+tt.call+`
+	return { statusCode = 200, body = tostring(output) .. "\n" .. tostring(err) }
+end
+`)
+
+			if !strings.HasPrefix(body, "nil\n") {
+				t.Errorf("expected no output, got %q", body)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected error to contain %q, got:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,32 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	_ "time/tzdata" // Embedded zone database for containers without /usr/share/zoneinfo
 
 	lua "github.com/yuin/gopher-lua"
 )
 
+// locations caches loaded time zones by IANA name
+var locations sync.Map
+
+// isoDuration matches ISO 8601 durations such as "P1Y2M3DT4H5M6.5S" or "P2W"
+var isoDuration = regexp.MustCompile(`^([-+])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// calendarDuration is a duration with calendar parts (years, months, days),
+// which depend on the time zone and date they are added to, and exact parts
+type calendarDuration struct {
+	years, months, days     int
+	hours, minutes, seconds float64
+}
+
 // registerTime registers the time module with time-related functions
 func registerTime(L *lua.LState) {
 	timeModule := L.NewTable()
@@ -16,6 +37,17 @@ func registerTime(L *lua.LState) {
 	L.SetField(timeModule, "parse", L.NewFunction(timeParse))
 	L.SetField(timeModule, "sleep", L.NewFunction(timeSleep))
 
+	// Time zones and calendar arithmetic
+	L.SetField(timeModule, "date", L.NewFunction(timeDate))
+	L.SetField(timeModule, "fromDate", L.NewFunction(timeFromDate))
+	L.SetField(timeModule, "add", L.NewFunction(timeAdd))
+	L.SetField(timeModule, "sub", L.NewFunction(timeSub))
+	L.SetField(timeModule, "diff", L.NewFunction(timeDiff))
+	L.SetField(timeModule, "truncate", L.NewFunction(timeTruncate))
+	L.SetField(timeModule, "parseDuration", L.NewFunction(timeParseDuration))
+	L.SetField(timeModule, "isBusinessDay", L.NewFunction(timeIsBusinessDay))
+	L.SetField(timeModule, "nextBusinessDay", L.NewFunction(timeNextBusinessDay))
+
 	// Set the time module as a global
 	L.SetGlobal("time", timeModule)
 }
@@ -28,28 +60,32 @@ func timeNow(L *lua.LState) int {
 	return 1
 }
 
-// timeFormat formats a Unix timestamp to a string
+// timeFormat formats a Unix timestamp to a string, in the server's local
+// time zone unless a zone is given
 // Uses Go's time format layout (e.g., "2006-01-02 15:04:05")
-// Usage: local formatted = time.format(timestamp, layout)
+// Usage: local formatted = time.format(timestamp, layout, zone?)
 func timeFormat(L *lua.LState) int {
 	timestamp := L.CheckNumber(1)
 	layout := L.CheckString(2)
+	loc := optLocation(L, 3, time.Local)
 
-	t := time.Unix(int64(timestamp), 0)
+	t := time.Unix(int64(timestamp), 0).In(loc)
 	formatted := t.Format(layout)
 
 	L.Push(lua.LString(formatted))
 	return 1
 }
 
-// timeParse parses a time string according to a layout
+// timeParse parses a time string according to a layout. Times without a
+// zone offset are read in the given zone (UTC by default).
 // Returns Unix timestamp or nil + error
-// Usage: local timestamp, err = time.parse(timeStr, layout)
+// Usage: local timestamp, err = time.parse(timeStr, layout, zone?)
 func timeParse(L *lua.LState) int {
 	timeStr := L.CheckString(1)
 	layout := L.CheckString(2)
+	loc := optLocation(L, 3, time.UTC)
 
-	t, err := time.Parse(layout, timeStr)
+	t, err := time.ParseInLocation(layout, timeStr, loc)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
@@ -76,3 +112,388 @@ func timeSleep(L *lua.LState) int {
 		return 0
 	}
 }
+
+// timeDate returns the calendar fields of a timestamp in a time zone, using
+// the field names of os.date("*t") plus the zone abbreviation and offset
+// Usage: local d = time.date(timestamp, "America/New_York") -- d.year, d.month, d.day, d.hour, d.wday, d.offset
+func timeDate(L *lua.LState) int {
+	timestamp := L.CheckNumber(1)
+	loc := optLocation(L, 2, time.UTC)
+
+	t := time.Unix(int64(timestamp), 0).In(loc)
+	zone, offset := t.Zone()
+
+	date := L.NewTable()
+	L.SetField(date, "year", lua.LNumber(t.Year()))
+	L.SetField(date, "month", lua.LNumber(t.Month()))
+	L.SetField(date, "day", lua.LNumber(t.Day()))
+	L.SetField(date, "hour", lua.LNumber(t.Hour()))
+	L.SetField(date, "min", lua.LNumber(t.Minute()))
+	L.SetField(date, "sec", lua.LNumber(t.Second()))
+	L.SetField(date, "wday", lua.LNumber(t.Weekday()+1))
+	L.SetField(date, "yday", lua.LNumber(t.YearDay()))
+	L.SetField(date, "isdst", lua.LBool(t.IsDST()))
+	L.SetField(date, "zone", lua.LString(zone))
+	L.SetField(date, "offset", lua.LNumber(offset))
+	L.Push(date)
+	return 1
+}
+
+// timeFromDate converts calendar fields in a time zone to a timestamp.
+// Missing fields default to the start of the period; out of range values
+// are normalized, e.g. day 32 of January is February 1.
+// Usage: local timestamp = time.fromDate({year = 2024, month = 3, day = 10, hour = 9}, "Europe/Berlin")
+func timeFromDate(L *lua.LState) int {
+	date := L.CheckTable(1)
+	loc := optLocation(L, 2, time.UTC)
+
+	field := func(name string, def int) int {
+		if v, ok := date.RawGetString(name).(lua.LNumber); ok {
+			return int(v)
+		}
+		return def
+	}
+	year, ok := date.RawGetString("year").(lua.LNumber)
+	if !ok {
+		L.ArgError(1, "date requires a year")
+		return 0
+	}
+
+	t := time.Date(int(year), time.Month(field("month", 1)), field("day", 1),
+		field("hour", 0), field("min", 0), field("sec", 0), 0, loc)
+	L.Push(lua.LNumber(t.Unix()))
+	return 1
+}
+
+// timeAdd adds a duration to a timestamp. The duration is a number of
+// seconds, an ISO 8601 duration ("P1DT2H"), a Go duration ("90m") or a
+// table with years, months, weeks, days, hours, minutes and seconds.
+// Calendar parts are added to the wall clock in the zone, so adding a day
+// across a DST change keeps the local time.
+// Usage: local later = time.add(timestamp, "P1M", "Europe/Lisbon")
+func timeAdd(L *lua.LState) int {
+	return addDuration(L, 1)
+}
+
+// timeSub subtracts a duration from a timestamp, see time.add
+// Usage: local earlier = time.sub(timestamp, {days = 7}, "America/Sao_Paulo")
+func timeSub(L *lua.LState) int {
+	return addDuration(L, -1)
+}
+
+// addDuration implements time.add and time.sub
+func addDuration(L *lua.LState, sign int) int {
+	timestamp := L.CheckNumber(1)
+	d, err := checkDuration(L, 2)
+	if err != nil {
+		L.ArgError(2, err.Error())
+		return 0
+	}
+	loc := optLocation(L, 3, time.UTC)
+
+	t := time.Unix(int64(timestamp), 0).In(loc)
+	L.Push(lua.LNumber(d.scale(sign).addTo(t).Unix()))
+	return 1
+}
+
+// timeDiff returns a - b in the given unit. Seconds, minutes and hours are
+// exact; days and weeks count calendar days between the dates in the zone,
+// so a DST change does not produce fractional days.
+// Usage: local days = time.diff(dueAt, time.now(), "days", "America/Chicago")
+func timeDiff(L *lua.LState) int {
+	a := time.Unix(int64(L.CheckNumber(1)), 0)
+	b := time.Unix(int64(L.CheckNumber(2)), 0)
+	unit := L.OptString(3, "seconds")
+	loc := optLocation(L, 4, time.UTC)
+
+	var result float64
+	switch unit {
+	case "seconds":
+		result = a.Sub(b).Seconds()
+	case "minutes":
+		result = a.Sub(b).Minutes()
+	case "hours":
+		result = a.Sub(b).Hours()
+	case "days", "weeks":
+		result = float64(calendarDays(a.In(loc)) - calendarDays(b.In(loc)))
+		if unit == "weeks" {
+			result = math.Trunc(result / 7)
+		}
+	default:
+		L.ArgError(3, fmt.Sprintf("unknown unit %q", unit))
+		return 0
+	}
+
+	L.Push(lua.LNumber(result))
+	return 1
+}
+
+// timeTruncate returns the start of the minute, hour, day, week (Monday),
+// month or year containing a timestamp in the zone
+// Usage: local startOfDay = time.truncate(timestamp, "day", "Asia/Tokyo")
+func timeTruncate(L *lua.LState) int {
+	timestamp := L.CheckNumber(1)
+	unit := L.CheckString(2)
+	loc := optLocation(L, 3, time.UTC)
+
+	t := time.Unix(int64(timestamp), 0).In(loc)
+	year, month, day := t.Date()
+
+	var result time.Time
+	switch unit {
+	case "minute":
+		result = time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, loc)
+	case "hour":
+		result = time.Date(year, month, day, t.Hour(), 0, 0, 0, loc)
+	case "day":
+		result = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	case "week":
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		result = time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, loc)
+	case "month":
+		result = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	case "year":
+		result = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	default:
+		L.ArgError(2, fmt.Sprintf("unknown unit %q", unit))
+		return 0
+	}
+
+	L.Push(lua.LNumber(result.Unix()))
+	return 1
+}
+
+// timeParseDuration parses an ISO 8601 or Go duration into a table with
+// years, months, days, hours, minutes and seconds, plus the exact total in
+// seconds for durations without calendar parts
+// Usage: local d, err = time.parseDuration("PT1H30M") -- d.hours, d.minutes, d.total
+func timeParseDuration(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	d, err := parseDuration(str)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	result := L.NewTable()
+	L.SetField(result, "years", lua.LNumber(d.years))
+	L.SetField(result, "months", lua.LNumber(d.months))
+	L.SetField(result, "days", lua.LNumber(d.days))
+	L.SetField(result, "hours", lua.LNumber(d.hours))
+	L.SetField(result, "minutes", lua.LNumber(d.minutes))
+	L.SetField(result, "seconds", lua.LNumber(d.seconds))
+	if d.years == 0 && d.months == 0 && d.days == 0 {
+		L.SetField(result, "total", lua.LNumber(d.exact().Seconds()))
+	}
+	L.Push(result)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// timeIsBusinessDay reports whether a timestamp falls on a business day in
+// the zone
+// Usage: local ok = time.isBusinessDay(timestamp, "Europe/London", {holidays = {"2024-12-25"}})
+func timeIsBusinessDay(L *lua.LState) int {
+	timestamp := L.CheckNumber(1)
+	loc := optLocation(L, 2, time.UTC)
+	calendar := checkBusinessCalendar(L, 3)
+
+	t := time.Unix(int64(timestamp), 0).In(loc)
+	L.Push(lua.LBool(calendar.isBusinessDay(t)))
+	return 1
+}
+
+// timeNextBusinessDay returns the same local time on the next business day
+// after a timestamp. Weekends default to Saturday and Sunday (wday 7 and 1);
+// holidays are "YYYY-MM-DD" dates.
+// Usage: local next = time.nextBusinessDay(timestamp, "America/New_York", {holidays = {"2024-07-04"}, weekend = {1, 7}})
+func timeNextBusinessDay(L *lua.LState) int {
+	timestamp := L.CheckNumber(1)
+	loc := optLocation(L, 2, time.UTC)
+	calendar := checkBusinessCalendar(L, 3)
+
+	t := time.Unix(int64(timestamp), 0).In(loc)
+	year, month, day := t.Date()
+	// A year of consecutive non-business days means the calendar is unusable
+	for i := 1; i <= 366; i++ {
+		next := time.Date(year, month, day+i, t.Hour(), t.Minute(), t.Second(), 0, loc)
+		if calendar.isBusinessDay(next) {
+			L.Push(lua.LNumber(next.Unix()))
+			return 1
+		}
+	}
+
+	L.ArgError(3, "no business day within a year")
+	return 0
+}
+
+// businessCalendar holds the weekend days and holidays of time.nextBusinessDay
+type businessCalendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+// isBusinessDay reports whether t falls outside weekends and holidays
+func (c businessCalendar) isBusinessDay(t time.Time) bool {
+	return !c.weekend[t.Weekday()] && !c.holidays[t.Format(time.DateOnly)]
+}
+
+// checkBusinessCalendar reads the weekend and holidays options in argument n
+func checkBusinessCalendar(L *lua.LState, n int) businessCalendar {
+	options := L.OptTable(n, L.NewTable())
+	calendar := businessCalendar{
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: map[string]bool{},
+	}
+
+	if weekend, ok := options.RawGetString("weekend").(*lua.LTable); ok {
+		calendar.weekend = map[time.Weekday]bool{}
+		for i := 1; i <= weekend.Len(); i++ {
+			wday, ok := weekend.RawGetInt(i).(lua.LNumber)
+			if !ok || wday < 1 || wday > 7 {
+				L.ArgError(n, "weekend days must be numbers from 1 (Sunday) to 7 (Saturday)")
+			}
+			calendar.weekend[time.Weekday(wday-1)] = true
+		}
+	}
+	if holidays, ok := options.RawGetString("holidays").(*lua.LTable); ok {
+		for i := 1; i <= holidays.Len(); i++ {
+			calendar.holidays[lua.LVAsString(holidays.RawGetInt(i))] = true
+		}
+	}
+	return calendar
+}
+
+// optLocation loads the IANA time zone in argument n, or returns def if the
+// argument is absent
+func optLocation(L *lua.LState, n int, def *time.Location) *time.Location {
+	name := L.OptString(n, "")
+	if name == "" {
+		return def
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		L.ArgError(n, fmt.Sprintf("unknown time zone %q", name))
+		return nil
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// checkDuration reads a duration given as seconds, a string or a table
+func checkDuration(L *lua.LState, n int) (calendarDuration, error) {
+	switch v := L.Get(n).(type) {
+	case lua.LNumber:
+		return calendarDuration{seconds: float64(v)}, nil
+	case lua.LString:
+		return parseDuration(string(v))
+	case *lua.LTable:
+		number := func(name string) float64 {
+			f, _ := v.RawGetString(name).(lua.LNumber)
+			return float64(f)
+		}
+		return calendarDuration{
+			years:   int(number("years")),
+			months:  int(number("months")),
+			days:    int(number("weeks"))*7 + int(number("days")),
+			hours:   number("hours"),
+			minutes: number("minutes"),
+			seconds: number("seconds"),
+		}, nil
+	default:
+		return calendarDuration{}, errors.New("duration must be seconds, a duration string or a table")
+	}
+}
+
+// parseDuration parses an ISO 8601 duration or a Go duration string
+func parseDuration(str string) (calendarDuration, error) {
+	if !strings.HasPrefix(strings.TrimLeft(str, "+-"), "P") {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return calendarDuration{}, fmt.Errorf("invalid duration %q", str)
+		}
+		return calendarDuration{seconds: d.Seconds()}, nil
+	}
+
+	m := isoDuration.FindStringSubmatch(str)
+	if m == nil || strings.HasSuffix(str, "T") || strings.TrimLeft(str, "+-") == "P" {
+		return calendarDuration{}, fmt.Errorf("invalid ISO 8601 duration %q", str)
+	}
+
+	integer := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	decimal := func(s string) float64 {
+		f, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		return f
+	}
+	d := calendarDuration{
+		years:   integer(m[2]),
+		months:  integer(m[3]),
+		days:    integer(m[4])*7 + integer(m[5]),
+		hours:   decimal(m[6]),
+		minutes: decimal(m[7]),
+		seconds: decimal(m[8]),
+	}
+	if m[1] == "-" {
+		d = d.scale(-1)
+	}
+	return d, nil
+}
+
+// scale multiplies every part of the duration by sign
+func (d calendarDuration) scale(sign int) calendarDuration {
+	return calendarDuration{
+		years:   d.years * sign,
+		months:  d.months * sign,
+		days:    d.days * sign,
+		hours:   d.hours * float64(sign),
+		minutes: d.minutes * float64(sign),
+		seconds: d.seconds * float64(sign),
+	}
+}
+
+// exact returns the hours, minutes and seconds of the duration
+func (d calendarDuration) exact() time.Duration {
+	return time.Duration((d.hours*3600 + d.minutes*60 + d.seconds) * float64(time.Second))
+}
+
+// addTo adds the duration to t: years and months first, clamping the day to
+// the end of a shorter month (January 31 plus one month is February 28 or
+// 29), then days on the wall clock, then the exact parts
+func (d calendarDuration) addTo(t time.Time) time.Time {
+	year, month, day := t.Date()
+	if d.years != 0 || d.months != 0 {
+		months := int(month) - 1 + d.years*12 + d.months
+		year += months / 12
+		months %= 12
+		if months < 0 {
+			months += 12
+			year--
+		}
+		month = time.Month(months + 1)
+		day = min(day, daysIn(year, month))
+	}
+
+	t = time.Date(year, month, day+d.days, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	return t.Add(d.exact())
+}
+
+// daysIn returns the number of days in a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// calendarDays returns the number of days from the epoch to the date of t,
+// ignoring the time of day
+func calendarDays(t time.Time) int {
+	year, month, day := t.Date()
+	return int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+// timeHandler returns Lua code that joins the results of exprs with "|"
+func timeHandler(exprs ...string) string {
+	return `
+function handler(ctx, event)
+	return { statusCode = 200, body = table.concat({` + strings.Join(exprs, ", ") + `}, "|") }
+end
+`
+}
+
+func TestRun_Time_Zones(t *testing.T) {
+	// 2024-03-09 09:00 in New York, the day before DST starts
+	ny, _ := time.LoadLocation("America/New_York")
+	ts := time.Date(2024, 3, 9, 9, 0, 0, 0, ny).Unix()
+	nextDay := time.Date(2024, 3, 10, 9, 0, 0, 0, ny).Unix()
+
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, timeHandler(
+		fmt.Sprintf(`time.format(%d, "2006-01-02 15:04 MST", "America/New_York")`, ts),
+		fmt.Sprintf(`time.format(%d, "15:04", "Asia/Kolkata")`, ts),
+		fmt.Sprintf(`time.date(%d, "America/New_York").hour`, ts),
+		fmt.Sprintf(`time.date(%d, "America/New_York").wday`, ts),
+		fmt.Sprintf(`time.date(%d, "America/New_York").offset`, nextDay),
+		fmt.Sprintf(`tostring(time.date(%d, "America/New_York").isdst)`, nextDay),
+		`time.fromDate({year = 2024, month = 3, day = 10, hour = 9}, "America/New_York")`,
+		`time.parse("2024-03-10 09:00", "2006-01-02 15:04", "America/New_York")`,
+		fmt.Sprintf(`time.add(%d, {days = 1}, "America/New_York")`, ts),
+		fmt.Sprintf(`time.add(%d, "PT24H", "America/New_York")`, ts),
+	))
+
+	expected := strings.Join([]string{
+		"2024-03-09 09:00 EST", "19:30", "9", "7", "-14400", "true",
+		fmt.Sprint(nextDay), fmt.Sprint(nextDay),
+		// Adding a calendar day keeps 09:00; adding 24 hours crosses the DST change
+		fmt.Sprint(nextDay), fmt.Sprint(nextDay + 3600),
+	}, "|")
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Time_Arithmetic(t *testing.T) {
+	jan31 := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC).Unix()
+	wed := time.Date(2024, 5, 15, 18, 45, 30, 0, time.UTC).Unix()
+
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, timeHandler(
+		fmt.Sprintf(`time.format(time.add(%d, "P1M"), "2006-01-02", "UTC")`, jan31),
+		fmt.Sprintf(`time.format(time.add(%d, {years = 1, months = 1}), "2006-01-02", "UTC")`, jan31),
+		fmt.Sprintf(`time.format(time.sub(%d, "P2W"), "2006-01-02", "UTC")`, jan31),
+		fmt.Sprintf(`time.add(%d, 90) - %d`, jan31, jan31),
+		fmt.Sprintf(`time.add(%d, "1h30m") - %d`, jan31, jan31),
+		fmt.Sprintf(`time.diff(%d, %d)`, wed, jan31),
+		fmt.Sprintf(`time.diff(%d, %d, "days")`, wed, jan31),
+		fmt.Sprintf(`time.diff(%d, %d, "weeks")`, wed, jan31),
+		fmt.Sprintf(`time.format(time.truncate(%d, "week"), "Mon 2006-01-02 15:04:05", "UTC")`, wed),
+		fmt.Sprintf(`time.format(time.truncate(%d, "day", "Asia/Tokyo"), "2006-01-02 15:04 MST", "Asia/Tokyo")`, wed),
+		fmt.Sprintf(`time.format(time.truncate(%d, "month"), "2006-01-02", "UTC")`, wed),
+	))
+
+	expected := strings.Join([]string{
+		"2024-02-29", "2025-02-28", "2024-01-17", "90", "5400",
+		fmt.Sprint(wed - jan31), "105", "15",
+		"Mon 2024-05-13 00:00:00", "2024-05-16 00:00 JST", "2024-05-01",
+	}, "|")
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Time_ParseDuration(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local d = time.parseDuration("P1Y2M10DT2H30M1.5S")
+	local exact = time.parseDuration("PT1H30M")
+	local negative = time.parseDuration("-P1W")
+	local goDuration = time.parseDuration("2h45m")
+	local _, err = time.parseDuration("P1H")
+	local _, emptyErr = time.parseDuration("PT")
+	return {
+		statusCode = 200,
+		body = table.concat({
+			d.years, d.months, d.days, d.hours, d.minutes, d.seconds, tostring(d.total),
+			exact.total, negative.days, goDuration.total, err, emptyErr,
+		}, "|")
+	}
+end
+`)
+
+	expected := `1|2|10|2|30|1.5|nil|5400|-7|9900|invalid ISO 8601 duration "P1H"|invalid ISO 8601 duration "PT"`
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Time_BusinessDays(t *testing.T) {
+	// Friday 2024-12-20 17:00 in London
+	london, _ := time.LoadLocation("Europe/London")
+	friday := time.Date(2024, 12, 20, 17, 0, 0, 0, london).Unix()
+	tuesday := time.Date(2024, 12, 24, 17, 0, 0, 0, london).Unix()
+
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, timeHandler(
+		fmt.Sprintf(`time.format(time.nextBusinessDay(%d, "Europe/London"), "Mon 2006-01-02 15:04", "Europe/London")`, friday),
+		fmt.Sprintf(`time.format(time.nextBusinessDay(%d, "Europe/London", {holidays = {"2024-12-25", "2024-12-26"}}), "Mon 2006-01-02", "Europe/London")`, tuesday),
+		fmt.Sprintf(`time.format(time.nextBusinessDay(%d, "Europe/London", {weekend = {6, 7}}), "Mon 2006-01-02", "Europe/London")`, friday),
+		fmt.Sprintf(`tostring(time.isBusinessDay(%d, "Europe/London"))`, friday),
+		fmt.Sprintf(`tostring(time.isBusinessDay(%d, "Europe/London", {weekend = {6}}))`, friday),
+	))
+
+	expected := "Mon 2024-12-23 17:00|Fri 2024-12-27|Sun 2024-12-22|true|false"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_Time_UnknownZone(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local ok, err = pcall(time.date, 0, "Mars/Olympus_Mons")
+	return { statusCode = 200, body = tostring(ok) .. " " .. err }
+end
+`)
+
+	if !strings.Contains(body, `unknown time zone "Mars/Olympus_Mons"`) {
+		t.Errorf("expected unknown zone error, got %q", body)
+	}
+}
@@ -14,6 +14,8 @@ func registerURL(L *lua.LState) {
 	L.SetField(urlModule, "parse", L.NewFunction(urlParse))
 	L.SetField(urlModule, "encode", L.NewFunction(urlEncode))
 	L.SetField(urlModule, "decode", L.NewFunction(urlDecode))
+	L.SetField(urlModule, "encodeForm", L.NewFunction(urlEncodeForm))
+	L.SetField(urlModule, "decodeForm", L.NewFunction(urlDecodeForm))
 
 	// Set the url module as a global
 	L.SetGlobal("url", urlModule)
@@ -40,25 +42,7 @@ func urlParse(L *lua.LState) int {
 	L.SetField(result, "fragment", lua.LString(parsedURL.Fragment))
 
 	// Parse query parameters into a table
-	if parsedURL.RawQuery != "" {
-		queryTable := L.NewTable()
-		queryValues := parsedURL.Query()
-		for key, values := range queryValues {
-			if len(values) == 1 {
-				L.SetField(queryTable, key, lua.LString(values[0]))
-			} else {
-				// Multiple values - create an array
-				arrayTable := L.NewTable()
-				for i, v := range values {
-					arrayTable.RawSetInt(i+1, lua.LString(v))
-				}
-				L.SetField(queryTable, key, arrayTable)
-			}
-		}
-		L.SetField(result, "query", queryTable)
-	} else {
-		L.SetField(result, "query", L.NewTable())
-	}
+	L.SetField(result, "query", urlValuesToLuaTable(L, parsedURL.Query()))
 
 	// Add username and password if present
 	if parsedURL.User != nil {
@@ -100,3 +84,65 @@ func urlDecode(L *lua.LState) int {
 	L.Push(lua.LNil)
 	return 2
 }
+
+// urlEncodeForm encodes a table as an application/x-www-form-urlencoded string.
+// Array values are encoded as repeated keys.
+// Usage: local body = url.encodeForm({name = "Ada", tags = {"a", "b"}})
+func urlEncodeForm(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	L.Push(lua.LString(luaTableToURLValues(tbl).Encode()))
+	return 1
+}
+
+// urlDecodeForm decodes an application/x-www-form-urlencoded string into a table
+// Usage: local form, err = url.decodeForm(body)
+func urlDecodeForm(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	values, err := url.ParseQuery(str)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(urlValuesToLuaTable(L, values))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// urlValuesToLuaTable converts URL values to a Lua table. Keys with a single
+// value map to a string, keys with multiple values map to an array.
+func urlValuesToLuaTable(L *lua.LState, values url.Values) *lua.LTable {
+	tbl := L.NewTable()
+	for key, vals := range values {
+		if len(vals) == 1 {
+			L.SetField(tbl, key, lua.LString(vals[0]))
+		} else {
+			// Multiple values - create an array
+			arrayTable := L.NewTable()
+			for i, v := range vals {
+				arrayTable.RawSetInt(i+1, lua.LString(v))
+			}
+			L.SetField(tbl, key, arrayTable)
+		}
+	}
+	return tbl
+}
+
+// luaTableToURLValues converts a Lua table to URL values. Array values become
+// repeated keys, in array order.
+func luaTableToURLValues(tbl *lua.LTable) url.Values {
+	values := url.Values{}
+	tbl.ForEach(func(k, v lua.LValue) {
+		key := lua.LVAsString(k)
+		if arr, ok := v.(*lua.LTable); ok {
+			for i := 1; i <= arr.Len(); i++ {
+				values.Add(key, lua.LVAsString(arr.RawGetInt(i)))
+			}
+			return
+		}
+		values.Add(key, lua.LVAsString(v))
+	})
+	return values
+}
@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"strconv"
+
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+	"github.com/dimiro1/lunar/internal/vectors"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerVectors creates the global 'vectors' table with vector collection functions
+func registerVectors(L *lua.LState, vectorStore vectors.Store, functionID string, trace *tracing.Trace) {
+	vectorsTable := L.NewTable()
+
+	// vectors.upsert(collection, records)
+	// Usage: local ok, err = vectors.upsert("docs", {{id = "a", vector = {...}, metadata = {lang = "en"}}})
+	L.SetField(vectorsTable, "upsert", L.NewFunction(func(L *lua.LState) int {
+		collection := L.CheckString(1)
+		recordsTbl := L.CheckTable(2)
+
+		var records []vectors.Record
+		for i := 1; i <= recordsTbl.Len(); i++ {
+			recordTbl, ok := recordsTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				L.ArgError(2, "records must be a list of tables")
+				return 0
+			}
+			record := vectors.Record{
+				ID:     lua.LVAsString(recordTbl.RawGetString("id")),
+				Vector: checkVector(L, 2, recordTbl.RawGetString("vector")),
+			}
+			if metadata, ok := luaValueToGo(L, recordTbl.RawGetString("metadata")).(map[string]any); ok {
+				record.Metadata = metadata
+			}
+			records = append(records, record)
+		}
+
+		span := trace.Start("vectors.upsert", store.SpanKindInternal, map[string]string{
+			"vectors.collection": collection,
+			"vectors.records":    strconv.Itoa(len(records)),
+		})
+		defer span.End()
+
+		if vectorStore == nil {
+			return vectorsNotConfigured(L, span, lua.LFalse)
+		}
+		if err := vectorStore.Upsert(functionID, collection, records); err != nil {
+			span.SetError(err.Error())
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}))
+
+	// vectors.query(collection, vector, options)
+	// Usage: local matches, err = vectors.query("docs", embedding, {top_k = 5, filter = {lang = "en"}, min_score = 0.5})
+	L.SetField(vectorsTable, "query", L.NewFunction(func(L *lua.LState) int {
+		collection := L.CheckString(1)
+		vector := checkVector(L, 2, L.CheckTable(2))
+		options := L.OptTable(3, L.NewTable())
+
+		opts := vectors.QueryOptions{
+			TopK: int(lua.LVAsNumber(options.RawGetString("top_k"))),
+		}
+		if filterLV := options.RawGetString("filter"); filterLV != lua.LNil {
+			filter, ok := luaValueToGo(L, filterLV).(map[string]any)
+			if !ok {
+				L.ArgError(3, "filter must be a table of metadata values")
+				return 0
+			}
+			opts.Filter = filter
+		}
+		if minScore, ok := options.RawGetString("min_score").(lua.LNumber); ok {
+			score := float64(minScore)
+			opts.MinScore = &score
+		}
+
+		span := trace.Start("vectors.query", store.SpanKindInternal, map[string]string{
+			"vectors.collection": collection,
+		})
+		defer span.End()
+
+		if vectorStore == nil {
+			return vectorsNotConfigured(L, span, lua.LNil)
+		}
+		matches, err := vectorStore.Query(functionID, collection, vector, opts)
+		if err != nil {
+			span.SetError(err.Error())
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		span.SetAttribute("vectors.matches", strconv.Itoa(len(matches)))
+
+		result := L.NewTable()
+		for _, match := range matches {
+			matchTbl := L.NewTable()
+			L.SetField(matchTbl, "id", lua.LString(match.ID))
+			L.SetField(matchTbl, "score", lua.LNumber(match.Score))
+			L.SetField(matchTbl, "metadata", goValueToLua(L, match.Metadata))
+			result.Append(matchTbl)
+		}
+		L.Push(result)
+		L.Push(lua.LNil)
+		return 2
+	}))
+
+	// vectors.delete(collection, ids)
+	// Usage: local ok, err = vectors.delete("docs", {"a", "b"})
+	L.SetField(vectorsTable, "delete", L.NewFunction(func(L *lua.LState) int {
+		collection := L.CheckString(1)
+
+		var ids []string
+		switch v := L.CheckAny(2).(type) {
+		case lua.LString:
+			ids = []string{string(v)}
+		case *lua.LTable:
+			for i := 1; i <= v.Len(); i++ {
+				ids = append(ids, lua.LVAsString(v.RawGetInt(i)))
+			}
+		default:
+			L.ArgError(2, "ids must be a string or a list of strings")
+			return 0
+		}
+
+		span := trace.Start("vectors.delete", store.SpanKindInternal, map[string]string{
+			"vectors.collection": collection,
+			"vectors.records":    strconv.Itoa(len(ids)),
+		})
+		defer span.End()
+
+		if vectorStore == nil {
+			return vectorsNotConfigured(L, span, lua.LFalse)
+		}
+		if err := vectorStore.Delete(functionID, collection, ids); err != nil {
+			span.SetError(err.Error())
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		return 1
+	}))
+
+	L.SetGlobal("vectors", vectorsTable)
+}
+
+// checkVector converts a list of numbers, raising an argument error for
+// argument n if it is not one
+func checkVector(L *lua.LState, n int, lv lua.LValue) []float64 {
+	tbl, ok := lv.(*lua.LTable)
+	if !ok {
+		L.ArgError(n, "vector must be a list of numbers")
+		return nil
+	}
+	vector := make([]float64, 0, tbl.Len())
+	for i := 1; i <= tbl.Len(); i++ {
+		num, ok := tbl.RawGetInt(i).(lua.LNumber)
+		if !ok {
+			L.ArgError(n, "vector must be a list of numbers")
+			return nil
+		}
+		vector = append(vector, float64(num))
+	}
+	return vector
+}
+
+// vectorsNotConfigured returns the error for executions without a vector store
+func vectorsNotConfigured(L *lua.LState, span *tracing.Span, result lua.LValue) int {
+	span.SetError("vector store is not configured")
+	L.Push(result)
+	L.Push(lua.LString("vector store is not configured"))
+	return 2
+}
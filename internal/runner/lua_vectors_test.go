@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/env"
+	"github.com/dimiro1/lunar/internal/events"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/kv"
+	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/vectors"
+)
+
+func runVectorsHandler(t *testing.T, vectorStore vectors.Store, luaCode string) string {
+	t.Helper()
+
+	deps := Dependencies{
+		Logger:  logger.NewMemoryLogger(),
+		KV:      kv.NewMemoryStore(),
+		Env:     env.NewMemoryStore(),
+		HTTP:    internalhttp.NewFakeClient(),
+		Vectors: vectorStore,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp.HTTP.Body
+}
+
+func TestRun_Vectors_UpsertQueryDelete(t *testing.T) {
+	body := runVectorsHandler(t, vectors.NewMemoryStore(), `
+function handler(ctx, event)
+	local ok, err = vectors.upsert("docs", {
+		{id = "a", vector = {1, 0}, metadata = {lang = "en"}},
+		{id = "b", vector = {0.8, 0.6}, metadata = {lang = "pt"}},
+		{id = "c", vector = {0, 1}, metadata = {lang = "en"}},
+	})
+	if not ok then
+		return { statusCode = 500, body = err }
+	end
+
+	local all = vectors.query("docs", {1, 0}, {top_k = 2})
+	local english = vectors.query("docs", {1, 0}, {filter = {lang = "en"}, min_score = 0.5})
+	vectors.delete("docs", "a")
+	local remaining = vectors.query("docs", {1, 0})
+
+	return {
+		statusCode = 200,
+		body = all[1].id .. all[2].id .. "," .. #english .. english[1].metadata.lang .. "," .. #remaining .. remaining[1].id,
+	}
+end
+`)
+
+	if body != "ab,1en,2b" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestRun_Vectors_DimensionMismatch(t *testing.T) {
+	body := runVectorsHandler(t, vectors.NewMemoryStore(), `
+function handler(ctx, event)
+	vectors.upsert("docs", {{id = "a", vector = {1, 0}}})
+	local matches, err = vectors.query("docs", {1, 0, 0})
+	return { statusCode = 200, body = tostring(matches) .. "," .. tostring(err ~= nil) }
+end
+`)
+
+	if body != "nil,true" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestRun_Vectors_NotConfigured(t *testing.T) {
+	body := runVectorsHandler(t, nil, `
+function handler(ctx, event)
+	local ok, err = vectors.upsert("docs", {{id = "a", vector = {1, 0}}})
+	return { statusCode = 200, body = tostring(ok) .. "," .. err }
+end
+`)
+
+	if body != "false,vector store is not configured" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/webhook"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerWebhook registers the webhook module with signature helpers
+func registerWebhook(L *lua.LState) {
+	webhookModule := L.NewTable()
+
+	L.SetField(webhookModule, "compare", L.NewFunction(webhookCompare))
+
+	// Verifiers for incoming webhooks
+	L.SetField(webhookModule, "verifyStripe", L.NewFunction(webhookVerifier(verifyStripe)))
+	L.SetField(webhookModule, "verifyGithub", L.NewFunction(webhookVerifier(verifyGithub)))
+	L.SetField(webhookModule, "verifySlack", L.NewFunction(webhookVerifier(verifySlack)))
+	L.SetField(webhookModule, "verifyStandard", L.NewFunction(webhookVerifier(verifyStandard)))
+
+	// Signer for outgoing webhooks
+	L.SetField(webhookModule, "sign", L.NewFunction(webhookSign))
+
+	// Set the webhook module as a global
+	L.SetGlobal("webhook", webhookModule)
+}
+
+// webhookCompare compares two strings in constant time
+// Usage: local equal = webhook.compare(a, b)
+func webhookCompare(L *lua.LState) int {
+	a := L.CheckString(1)
+	b := L.CheckString(2)
+	L.Push(lua.LBool(subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1))
+	return 1
+}
+
+// webhookRequest holds the parts of an incoming request that are signed
+type webhookRequest struct {
+	body      string
+	headers   map[string]string // Lowercase header names
+	tolerance time.Duration     // Zero disables the timestamp check
+}
+
+// header returns the value of a header by its lowercase name
+func (r webhookRequest) header(name string) string {
+	return r.headers[name]
+}
+
+// webhookVerifier wraps a verification scheme as a Lua function
+// Usage: local ok, err = webhook.verifyStripe(event, secret, {tolerance = 300})
+func webhookVerifier(verify func(req webhookRequest, secret string) error) lua.LGFunction {
+	return func(L *lua.LState) int {
+		event := L.CheckTable(1)
+		secret := L.CheckString(2)
+		options := L.OptTable(3, L.NewTable())
+
+		req := webhookRequest{
+			body:      lua.LVAsString(event.RawGetString("body")),
+			headers:   make(map[string]string),
+			tolerance: webhook.DefaultTolerance,
+		}
+		if headers, ok := event.RawGetString("headers").(*lua.LTable); ok {
+			headers.ForEach(func(k, v lua.LValue) {
+				req.headers[strings.ToLower(lua.LVAsString(k))] = lua.LVAsString(v)
+			})
+		}
+		if tolerance, ok := options.RawGetString("tolerance").(lua.LNumber); ok {
+			req.tolerance = time.Duration(tolerance) * time.Second
+		}
+
+		if err := verify(req, secret); err != nil {
+			L.Push(lua.LFalse)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		L.Push(lua.LTrue)
+		L.Push(lua.LNil)
+		return 2
+	}
+}
+
+// verifyStripe verifies a Stripe-Signature header ("t=<timestamp>,v1=<hex>")
+// signed over "<timestamp>.<body>"
+func verifyStripe(req webhookRequest, secret string) error {
+	header := req.header("stripe-signature")
+	if header == "" {
+		return errors.New("missing Stripe-Signature header")
+	}
+
+	var timestamp string
+	var signatures []string
+	for item := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("invalid Stripe-Signature header")
+	}
+	if err := webhook.CheckTimestamp(timestamp, req.tolerance); err != nil {
+		return err
+	}
+
+	expected := hex.EncodeToString(hmacSHA256([]byte(secret), timestamp+"."+req.body))
+	return webhook.MatchSignature(expected, signatures)
+}
+
+// verifyGithub verifies an X-Hub-Signature-256 header ("sha256=<hex>")
+// signed over the body
+func verifyGithub(req webhookRequest, secret string) error {
+	signature, ok := strings.CutPrefix(req.header("x-hub-signature-256"), "sha256=")
+	if !ok {
+		return errors.New("missing or invalid X-Hub-Signature-256 header")
+	}
+
+	expected := hex.EncodeToString(hmacSHA256([]byte(secret), req.body))
+	return webhook.MatchSignature(expected, []string{signature})
+}
+
+// verifySlack verifies an X-Slack-Signature header ("v0=<hex>") signed over
+// "v0:<X-Slack-Request-Timestamp>:<body>"
+func verifySlack(req webhookRequest, secret string) error {
+	timestamp := req.header("x-slack-request-timestamp")
+	if timestamp == "" {
+		return errors.New("missing X-Slack-Request-Timestamp header")
+	}
+	signature, ok := strings.CutPrefix(req.header("x-slack-signature"), "v0=")
+	if !ok {
+		return errors.New("missing or invalid X-Slack-Signature header")
+	}
+	if err := webhook.CheckTimestamp(timestamp, req.tolerance); err != nil {
+		return err
+	}
+
+	expected := hex.EncodeToString(hmacSHA256([]byte(secret), "v0:"+timestamp+":"+req.body))
+	return webhook.MatchSignature(expected, []string{signature})
+}
+
+// verifyStandard verifies a Standard Webhooks signature: webhook-signature
+// holds space separated "v1,<base64>" entries signed over
+// "<webhook-id>.<webhook-timestamp>.<body>"
+func verifyStandard(req webhookRequest, secret string) error {
+	id := req.header("webhook-id")
+	timestamp := req.header("webhook-timestamp")
+	header := req.header("webhook-signature")
+	if id == "" || timestamp == "" || header == "" {
+		return errors.New("missing webhook-id, webhook-timestamp or webhook-signature header")
+	}
+	return webhook.Verify(id, timestamp, header, req.body, secret, req.tolerance)
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of message with key
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// webhookSign signs an outgoing payload and returns the headers to send with it.
+// The scheme is one of "standard" (default), "stripe", "github" or "slack".
+// Usage: local headers, err = webhook.sign(body, secret, {scheme = "standard", id = "msg_1"})
+func webhookSign(L *lua.LState) int {
+	payload := L.CheckString(1)
+	secret := L.CheckString(2)
+	options := L.OptTable(3, L.NewTable())
+
+	scheme := lua.LVAsString(options.RawGetString("scheme"))
+	timestamp := time.Now().Unix()
+	if ts, ok := options.RawGetString("timestamp").(lua.LNumber); ok {
+		timestamp = int64(ts)
+	}
+	ts := strconv.FormatInt(timestamp, 10)
+
+	headers := L.NewTable()
+	switch scheme {
+	case "", "standard":
+		key, err := webhook.Key(secret)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		id := lua.LVAsString(options.RawGetString("id"))
+		if id == "" {
+			id = newWebhookID()
+		}
+		signature := webhook.Sign(key, id, ts, payload)
+		L.SetField(headers, "webhook-id", lua.LString(id))
+		L.SetField(headers, "webhook-timestamp", lua.LString(ts))
+		L.SetField(headers, "webhook-signature", lua.LString("v1,"+signature))
+	case "stripe":
+		signature := hex.EncodeToString(hmacSHA256([]byte(secret), ts+"."+payload))
+		L.SetField(headers, "Stripe-Signature", lua.LString("t="+ts+",v1="+signature))
+	case "github":
+		signature := hex.EncodeToString(hmacSHA256([]byte(secret), payload))
+		L.SetField(headers, "X-Hub-Signature-256", lua.LString("sha256="+signature))
+	case "slack":
+		signature := hex.EncodeToString(hmacSHA256([]byte(secret), "v0:"+ts+":"+payload))
+		L.SetField(headers, "X-Slack-Request-Timestamp", lua.LString(ts))
+		L.SetField(headers, "X-Slack-Signature", lua.LString("v0="+signature))
+	default:
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("unknown scheme %q", scheme)))
+		return 2
+	}
+
+	L.Push(headers)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// newWebhookID returns a random message ID for Standard Webhooks
+func newWebhookID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "msg_" + hex.EncodeToString(b)
+}
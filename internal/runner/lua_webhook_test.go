@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/env"
+	"github.com/dimiro1/lunar/internal/events"
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/kv"
+	"github.com/dimiro1/lunar/internal/logger"
+)
+
+// runHandler runs luaCode against event and returns the response body
+func runHandler(t *testing.T, event events.HTTPEvent, luaCode string) string {
+	t.Helper()
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   internalhttp.NewFakeClient(),
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return resp.HTTP.Body
+}
+
+// verifyHandler returns Lua code that calls verifier with the event and secret
+func verifyHandler(verifier, secret, options string) string {
+	return `
+function handler(ctx, event)
+	local ok, err = webhook.` + verifier + `(event, "` + secret + `", ` + options + `)
+	return { statusCode = 200, body = tostring(ok) .. "," .. tostring(err) }
+end
+`
+}
+
+func hexHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRun_Webhook_Compare(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	return {
+		statusCode = 200,
+		body = tostring(webhook.compare("abc", "abc")) .. "," .. tostring(webhook.compare("abc", "abd"))
+	}
+end
+`)
+
+	if body != "true,false" {
+		t.Errorf("expected body %q, got %q", "true,false", body)
+	}
+}
+
+func TestRun_Webhook_VerifyGithub(t *testing.T) {
+	// Example from the GitHub webhook documentation
+	event := events.HTTPEvent{
+		Method: "POST",
+		Path:   "/",
+		Headers: map[string]string{
+			"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+		Body: "Hello, World!",
+	}
+
+	if body := runHandler(t, event, verifyHandler("verifyGithub", "It's a Secret to Everybody", "nil")); body != "true,nil" {
+		t.Errorf("expected valid signature, got %q", body)
+	}
+	if body := runHandler(t, event, verifyHandler("verifyGithub", "wrong", "nil")); body != "false,signature mismatch" {
+		t.Errorf("expected signature mismatch, got %q", body)
+	}
+
+	event.Headers = nil
+	if body := runHandler(t, event, verifyHandler("verifyGithub", "secret", "nil")); body != "false,missing or invalid X-Hub-Signature-256 header" {
+		t.Errorf("expected missing header error, got %q", body)
+	}
+}
+
+func TestRun_Webhook_VerifyStripe(t *testing.T) {
+	payload := `{"id":"evt_1"}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := hexHMAC("whsec_test", timestamp+"."+payload)
+
+	event := events.HTTPEvent{
+		Method: "POST",
+		Path:   "/",
+		Headers: map[string]string{
+			"Stripe-Signature": "t=" + timestamp + ",v1=0000,v1=" + signature,
+		},
+		Body: payload,
+	}
+	if body := runHandler(t, event, verifyHandler("verifyStripe", "whsec_test", "nil")); body != "true,nil" {
+		t.Errorf("expected valid signature, got %q", body)
+	}
+
+	// Signatures older than the tolerance are rejected
+	old := "1000"
+	event.Headers["Stripe-Signature"] = "t=" + old + ",v1=" + hexHMAC("whsec_test", old+"."+payload)
+	if body := runHandler(t, event, verifyHandler("verifyStripe", "whsec_test", "nil")); body != "false,timestamp outside the tolerance window" {
+		t.Errorf("expected tolerance error, got %q", body)
+	}
+	if body := runHandler(t, event, verifyHandler("verifyStripe", "whsec_test", "{tolerance = 0}")); body != "true,nil" {
+		t.Errorf("expected disabled tolerance to accept old signature, got %q", body)
+	}
+}
+
+func TestRun_Webhook_VerifySlack(t *testing.T) {
+	payload := "token=abc&team_id=T1"
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	event := events.HTTPEvent{
+		Method: "POST",
+		Path:   "/",
+		Headers: map[string]string{
+			"X-Slack-Request-Timestamp": timestamp,
+			"X-Slack-Signature":         "v0=" + hexHMAC("slack-secret", "v0:"+timestamp+":"+payload),
+		},
+		Body: payload,
+	}
+	if body := runHandler(t, event, verifyHandler("verifySlack", "slack-secret", "nil")); body != "true,nil" {
+		t.Errorf("expected valid signature, got %q", body)
+	}
+
+	event.Body = "token=abc&team_id=T2"
+	if body := runHandler(t, event, verifyHandler("verifySlack", "slack-secret", "nil")); body != "false,signature mismatch" {
+		t.Errorf("expected signature mismatch, got %q", body)
+	}
+}
+
+func TestRun_Webhook_VerifyStandard(t *testing.T) {
+	// Example from the Standard Webhooks specification
+	event := events.HTTPEvent{
+		Method: "POST",
+		Path:   "/",
+		Headers: map[string]string{
+			"Webhook-Id":        "msg_p5jXN8AQM9LWM0D4loKWxJek",
+			"Webhook-Timestamp": "1614265330",
+			"Webhook-Signature": "v1,invalid v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+		},
+		Body: `{"test": 2432232314}`,
+	}
+
+	secret := "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	if body := runHandler(t, event, verifyHandler("verifyStandard", secret, "{tolerance = 0}")); body != "true,nil" {
+		t.Errorf("expected valid signature, got %q", body)
+	}
+	if body := runHandler(t, event, verifyHandler("verifyStandard", secret, "nil")); body != "false,timestamp outside the tolerance window" {
+		t.Errorf("expected tolerance error, got %q", body)
+	}
+}
+
+func TestRun_Webhook_Sign(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local headers, err = webhook.sign('{"test": 2432232314}', "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", {
+		id = "msg_p5jXN8AQM9LWM0D4loKWxJek",
+		timestamp = 1614265330,
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	-- Each scheme verifies with its own verifier
+	local results = {}
+	for _, scheme in ipairs({"standard", "stripe", "github", "slack"}) do
+		local secret = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+		local signed = webhook.sign("payload", secret, {scheme = scheme})
+		local verifier = ({standard = "verifyStandard", stripe = "verifyStripe", github = "verifyGithub", slack = "verifySlack"})[scheme]
+		local ok, verifyErr = webhook[verifier]({body = "payload", headers = signed}, secret)
+		table.insert(results, scheme .. "=" .. tostring(ok))
+	end
+
+	local _, schemeErr = webhook.sign("payload", "secret", {scheme = "unknown"})
+	return {
+		statusCode = 200,
+		body = headers["webhook-signature"] .. " " .. table.concat(results, ",") .. " " .. schemeErr
+	}
+end
+`)
+
+	expected := `v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE= standard=true,stripe=true,github=true,slack=true unknown scheme "unknown"`
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
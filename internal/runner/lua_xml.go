@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxXMLEncodeDepth limits element nesting in xml.encode, which also stops
+// cyclic tables from recursing forever
+const maxXMLEncodeDepth = 100
+
+// registerXML registers the xml module with encode/decode functions
+func registerXML(L *lua.LState) {
+	xmlModule := L.NewTable()
+
+	L.SetField(xmlModule, "decode", L.NewFunction(xmlDecode))
+	L.SetField(xmlModule, "encode", L.NewFunction(xmlEncode))
+
+	// Set the xml module as a global
+	L.SetGlobal("xml", xmlModule)
+}
+
+// xmlDecode parses an XML document into its root element. Each element is a
+// table with name, namespace (if any), attributes, children (a list of
+// elements) and text (the trimmed character data directly inside it).
+// Usage: local root, err = xml.decode(str)
+func xmlDecode(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	decoder := xml.NewDecoder(strings.NewReader(str))
+	var stack []*lua.LTable
+	var texts []*strings.Builder
+	var root *lua.LTable
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root != nil && len(stack) == 0 {
+				L.Push(lua.LNil)
+				L.Push(lua.LString("XML document has more than one root element"))
+				return 2
+			}
+			element := xmlElementTable(L, t)
+			if len(stack) > 0 {
+				stack[len(stack)-1].RawGetString("children").(*lua.LTable).Append(element)
+			} else {
+				root = element
+			}
+			stack = append(stack, element)
+			texts = append(texts, &strings.Builder{})
+		case xml.CharData:
+			if len(texts) > 0 {
+				texts[len(texts)-1].Write(t)
+			}
+		case xml.EndElement:
+			element := stack[len(stack)-1]
+			L.SetField(element, "text", lua.LString(strings.TrimSpace(texts[len(texts)-1].String())))
+			stack = stack[:len(stack)-1]
+			texts = texts[:len(texts)-1]
+		}
+	}
+
+	if root == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("XML document has no root element"))
+		return 2
+	}
+
+	L.Push(root)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// xmlElementTable creates the table for a start element
+func xmlElementTable(L *lua.LState, start xml.StartElement) *lua.LTable {
+	element := L.NewTable()
+	L.SetField(element, "name", lua.LString(start.Name.Local))
+	if start.Name.Space != "" {
+		L.SetField(element, "namespace", lua.LString(start.Name.Space))
+	}
+
+	// The default namespace declaration is reported as the namespace field
+	attributes := L.NewTable()
+	for _, attr := range start.Attr {
+		name := attr.Name.Local
+		if attr.Name.Space == "" && name == "xmlns" {
+			continue
+		}
+		if attr.Name.Space == "xmlns" {
+			name = "xmlns:" + name
+		}
+		L.SetField(attributes, name, lua.LString(attr.Value))
+	}
+	L.SetField(element, "attributes", attributes)
+	L.SetField(element, "children", L.NewTable())
+	return element
+}
+
+// xmlEncode writes an element table, in the shape returned by xml.decode, as
+// XML. Children may also be strings, which are written as text.
+// Usage: local str, err = xml.encode(root, {indent = "  ", declaration = true})
+func xmlEncode(L *lua.LState) int {
+	root := L.CheckTable(1)
+	options := L.OptTable(2, L.NewTable())
+
+	var out strings.Builder
+	if lua.LVAsBool(options.RawGetString("declaration")) {
+		out.WriteString(xml.Header)
+	}
+
+	encoder := xml.NewEncoder(&out)
+	if indent, ok := options.RawGetString("indent").(lua.LString); ok {
+		encoder.Indent("", string(indent))
+	}
+
+	if err := encodeXMLElement(encoder, root, 0); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if err := encoder.Flush(); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(lua.LString(out.String()))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// encodeXMLElement writes an element table and its children
+func encodeXMLElement(encoder *xml.Encoder, element *lua.LTable, depth int) error {
+	if depth >= maxXMLEncodeDepth {
+		return fmt.Errorf("XML nesting exceeds %d levels", maxXMLEncodeDepth)
+	}
+
+	name, ok := element.RawGetString("name").(lua.LString)
+	if !ok || name == "" {
+		return errors.New("XML element requires a name")
+	}
+	start := xml.StartElement{Name: xml.Name{Local: string(name)}}
+	if namespace, ok := element.RawGetString("namespace").(lua.LString); ok {
+		start.Name.Space = string(namespace)
+	}
+
+	// Attributes are sorted so the output is deterministic
+	if attributes, ok := element.RawGetString("attributes").(*lua.LTable); ok {
+		attributes.ForEach(func(k, v lua.LValue) {
+			if k.String() == "xmlns" && start.Name.Space != "" {
+				return
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: k.String()}, Value: v.String()})
+		})
+		slices.SortFunc(start.Attr, func(a, b xml.Attr) int { return strings.Compare(a.Name.Local, b.Name.Local) })
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if text, ok := element.RawGetString("text").(lua.LString); ok && text != "" {
+		if err := encoder.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	if children, ok := element.RawGetString("children").(*lua.LTable); ok {
+		for i := 1; i <= children.Len(); i++ {
+			var err error
+			switch child := children.RawGetInt(i).(type) {
+			case *lua.LTable:
+				err = encodeXMLElement(encoder, child, depth+1)
+			case lua.LString, lua.LNumber:
+				err = encoder.EncodeToken(xml.CharData(child.String()))
+			default:
+				err = fmt.Errorf("XML child %d of %s must be an element or a string", i, name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
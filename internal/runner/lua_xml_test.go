@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_XML_Decode(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local root, err = xml.decode([==[<?xml version="1.0"?>
+<order id="42" xmlns="urn:orders">
+  <item sku="A1">Book &amp; pen</item>
+  <item sku="B2"><![CDATA[<gift>]]></item>
+</order>]==])
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return {
+		statusCode = 200,
+		body = table.concat({
+			root.name, root.namespace, root.attributes.id, #root.children,
+			root.children[1].text, root.children[2].attributes.sku, root.children[2].text,
+		}, "|")
+	}
+end
+`)
+
+	expected := "order|urn:orders|42|2|Book & pen|B2|<gift>"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_XML_DecodeErrors(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local _, unclosed = xml.decode("<a><b></a>")
+	local _, empty = xml.decode("   ")
+	local _, twoRoots = xml.decode("<a/><b/>")
+	return { statusCode = 200, body = unclosed .. "|" .. empty .. "|" .. twoRoots }
+end
+`)
+
+	parts := strings.Split(body, "|")
+	if len(parts) != 3 || !strings.Contains(parts[0], "element <b> closed by </a>") ||
+		parts[1] != "XML document has no root element" || parts[2] != "XML document has more than one root element" {
+		t.Errorf("unexpected errors: %q", body)
+	}
+}
+
+func TestRun_XML_Encode(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local out, err = xml.encode({
+		name = "order",
+		attributes = { id = 42, status = "new" },
+		children = {
+			{ name = "note", text = "Fish & chips" },
+			"tail",
+		},
+	})
+	if err then
+		return { statusCode = 500, body = err }
+	end
+
+	local cyclic = { name = "a", children = {} }
+	table.insert(cyclic.children, cyclic)
+	local _, cycleErr = xml.encode(cyclic)
+	local _, nameErr = xml.encode({ text = "x" })
+
+	return { statusCode = 200, body = out .. "|" .. cycleErr .. "|" .. nameErr }
+end
+`)
+
+	expected := `<order id="42" status="new"><note>Fish &amp; chips</note>tail</order>|XML nesting exceeds 100 levels|XML element requires a name`
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v3"
+)
+
+// registerYAML registers the yaml module with a decode function
+func registerYAML(L *lua.LState) {
+	yamlModule := L.NewTable()
+
+	L.SetField(yamlModule, "decode", L.NewFunction(yamlDecode))
+
+	// Set the yaml module as a global
+	L.SetGlobal("yaml", yamlModule)
+}
+
+// yamlDecode converts a YAML document to a Lua value
+// Usage: local data, err = yaml.decode(str)
+func yamlDecode(L *lua.LState) int {
+	str := L.CheckString(1)
+
+	var goValue any
+	if err := yaml.Unmarshal([]byte(str), &goValue); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	L.Push(goValueToLua(L, normalizeYAML(goValue)))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// normalizeYAML converts decoded YAML values to the types goValueToLua
+// understands: integers become float64, timestamps RFC 3339 strings and
+// mapping keys strings
+func normalizeYAML(v any) any {
+	switch val := v.(type) {
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case uint64:
+		return float64(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeYAML(item)
+		}
+		return val
+	case map[any]any:
+		m := make(map[string]any, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	default:
+		return v
+	}
+}
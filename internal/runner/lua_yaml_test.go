@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/events"
+)
+
+func TestRun_YAML_Decode(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local data, err = yaml.decode([[
+name: lunar
+replicas: 3
+ratio: 0.5
+enabled: true
+created: 2024-01-02T03:04:05Z
+tags: [a, b]
+limits:
+  1: one
+]])
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	return {
+		statusCode = 200,
+		body = table.concat({
+			data.name, data.replicas + 1, data.ratio, tostring(data.enabled),
+			data.created, data.tags[2], data.limits["1"],
+		}, "|")
+	}
+end
+`)
+
+	expected := "lunar|4|0.5|true|2024-01-02T03:04:05Z|b|one"
+	if body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRun_YAML_DecodeInvalid(t *testing.T) {
+	body := runHandler(t, events.HTTPEvent{Method: "GET", Path: "/"}, `
+function handler(ctx, event)
+	local data, err = yaml.decode("a: [1, 2")
+	return { statusCode = 200, body = tostring(data) .. "|" .. err }
+end
+`)
+
+	if !strings.HasPrefix(body, "nil|yaml:") {
+		t.Errorf("expected YAML error, got %q", body)
+	}
+}
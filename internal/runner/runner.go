@@ -12,27 +12,36 @@ import (
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
+	"github.com/dimiro1/lunar/internal/vectors"
 	lua "github.com/yuin/gopher-lua"
 )
 
 // Response represents the response from executing a function
 // The actual response data depends on the event type
 type Response struct {
-	Type events.EventType
-	HTTP *events.HTTPResponse
+	Type     events.EventType
+	HTTP     *events.HTTPResponse
+	Streamed bool // The handler wrote the response to Request.Stream, HTTP only holds its status code
 }
 
 // Dependencies holds all the dependencies needed to run a Lua function
 type Dependencies struct {
-	Logger       logger.Logger
-	KV           kv.Store
-	Env          env.Store
-	HTTP         internalhttp.Client
-	AI           ai.Client
-	AITracker    ai.Tracker
-	Email        email.Client
-	EmailTracker email.Tracker
-	Timeout      time.Duration // Execution timeout (defaults to 5 minutes if not set)
+	Logger         logger.Logger
+	KV             kv.Store
+	Vectors        vectors.Store
+	Env            env.Store
+	HTTP           internalhttp.Client
+	HTTPTracker    internalhttp.Tracker
+	HTTPPolicy     *internalhttp.Policy // Network policy for outbound HTTP requests (unrestricted if nil)
+	AI             ai.Client
+	AITracker      ai.Tracker
+	Email          email.Client
+	EmailTemplates email.TemplateStore // Named templates for email.send (optional)
+	EmailTracker   email.Tracker
+	Trace          *tracing.Trace // Execution trace (optional, spans are not recorded if nil)
+	Timeout        time.Duration  // Execution timeout (defaults to 5 minutes if not set)
 }
 
 // Request represents a function execution request
@@ -40,6 +49,7 @@ type Request struct {
 	Context *events.ExecutionContext
 	Event   events.Event
 	Code    string
+	Stream  ResponseStream // Lets the handler stream its HTTP response (optional)
 }
 
 // Run executes a Lua function with the given event
@@ -60,32 +70,47 @@ func Run(ctx context.Context, deps Dependencies, req Request) (Response, error)
 	// Set the context to enable timeout
 	L.SetContext(ctx)
 
+	response := &streamedResponse{stream: req.Stream}
+
 	// Register global modules
 	registerLogger(L, deps.Logger, req.Context.ExecutionID)
-	registerKV(L, deps.KV, req.Context.FunctionID)
+	registerKV(L, deps.KV, req.Context.FunctionID, deps.Trace)
+	registerVectors(L, deps.Vectors, req.Context.FunctionID, deps.Trace)
 	registerEnv(L, deps.Env, req.Context.FunctionID)
-	registerHTTP(L, deps.HTTP)
+	registerHTTP(L, deps.HTTP, deps.HTTPPolicy, deps.Trace, deps.HTTPTracker, req.Context.ExecutionID)
+	registerStream(L, response)
 
 	// Register utility modules
 	registerJSON(L)
 	registerBase64(L)
+	registerCSV(L)
+	registerXML(L)
+	registerYAML(L)
 	registerCrypto(L)
+	registerWebhook(L)
+	registerJWT(L)
+	registerTemplate(L)
 	registerTime(L)
 	registerURL(L)
 	registerStrings(L)
+	registerRegex(L)
 	registerRandom(L)
 
 	// Register AI module
-	registerAI(L, deps.AI, req.Context.FunctionID, deps.AITracker, req.Context.ExecutionID)
+	registerAI(L, deps.AI, req.Context.FunctionID, deps.HTTPPolicy, deps.AITracker, req.Context.ExecutionID, deps.Trace, response)
 
 	// Register Email module
-	registerEmail(L, deps.Email, req.Context.FunctionID, deps.EmailTracker, req.Context.ExecutionID)
+	registerEmail(L, deps.Email, deps.EmailTemplates, deps.HTTP, deps.HTTPPolicy, req.Context.FunctionID, deps.EmailTracker, deps.HTTPTracker, req.Context.ExecutionID, deps.Trace)
 
 	// Load and execute the Lua code
+	loadSpan := deps.Trace.Start("lua.load", store.SpanKindInternal, nil)
 	if err := L.DoString(req.Code); err != nil {
 		enhancedErr := EnhanceError(fmt.Errorf("failed to load Lua code: %w", err), req.Code)
+		loadSpan.SetError(enhancedErr.Error())
+		loadSpan.End()
 		return Response{}, enhancedErr
 	}
+	loadSpan.End()
 
 	// Get the handler function
 	handlerFn := L.GetGlobal("handler")
@@ -94,17 +119,56 @@ func Run(ctx context.Context, deps Dependencies, req Request) (Response, error)
 		return Response{}, enhancedErr
 	}
 
+	handlerSpan := deps.Trace.Start("lua.handler", store.SpanKindInternal, nil)
+	defer handlerSpan.End()
+
 	// Handle different event types
 	switch req.Event.Type() {
 	case events.EventTypeHTTP:
-		return runHTTPEvent(L, req.Context, req.Event.(events.HTTPEvent), req.Code)
+		resp, err := runHTTPEvent(L, req.Context, req.Event.(events.HTTPEvent), req.Code, response)
+		if err != nil {
+			handlerSpan.SetError(err.Error())
+		}
+		return resp, err
+	case events.EventTypeEmail:
+		resp, err := runNotificationEvent(L, req.Context, emailEventToLuaTable(L, req.Event.(events.EmailEvent)), events.EventTypeEmail, req.Code)
+		if err != nil {
+			handlerSpan.SetError(err.Error())
+		}
+		return resp, err
+	case events.EventTypeEmailBounce:
+		resp, err := runNotificationEvent(L, req.Context, emailBounceEventToLuaTable(L, req.Event.(events.EmailBounceEvent)), events.EventTypeEmailBounce, req.Code)
+		if err != nil {
+			handlerSpan.SetError(err.Error())
+		}
+		return resp, err
 	default:
 		return Response{}, fmt.Errorf("unsupported event type: %s", req.Event.Type())
 	}
 }
 
+// runNotificationEvent executes the handler for an event nobody waits a
+// response for, such as an inbound email. Its return value is ignored, the
+// event is handled unless it raises an error.
+func runNotificationEvent(L *lua.LState, execCtx *events.ExecutionContext, eventTable *lua.LTable, eventType events.EventType, sourceCode string) (Response, error) {
+	ctxTable := contextToLuaTable(L, execCtx)
+
+	handlerFn := L.GetGlobal("handler")
+	if err := L.CallByParam(lua.P{
+		Fn:      handlerFn,
+		NRet:    1,
+		Protect: true,
+	}, ctxTable, eventTable); err != nil {
+		enhancedErr := EnhanceError(fmt.Errorf("failed to execute handler: %w", err), sourceCode)
+		return Response{}, enhancedErr
+	}
+	L.Pop(1)
+
+	return Response{Type: eventType}, nil
+}
+
 // runHTTPEvent executes the handler for an HTTP event
-func runHTTPEvent(L *lua.LState, execCtx *events.ExecutionContext, event events.HTTPEvent, sourceCode string) (Response, error) {
+func runHTTPEvent(L *lua.LState, execCtx *events.ExecutionContext, event events.HTTPEvent, sourceCode string, response *streamedResponse) (Response, error) {
 	// Create context and event Lua tables
 	ctxTable := contextToLuaTable(L, execCtx)
 	eventTable := httpEventToLuaTable(L, event)
@@ -124,6 +188,23 @@ func runHTTPEvent(L *lua.LState, execCtx *events.ExecutionContext, event events.
 	ret := L.Get(-1)
 	L.Pop(1)
 
+	// A streamed response is already on its way, a returned body is its
+	// last chunk
+	if response.started {
+		if tbl, ok := ret.(*lua.LTable); ok {
+			if body := lua.LVAsString(tbl.RawGetString("body")); body != "" {
+				if err := response.write(body); err != nil {
+					return Response{}, fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+		return Response{
+			Type:     events.EventTypeHTTP,
+			HTTP:     &events.HTTPResponse{StatusCode: response.statusCode},
+			Streamed: true,
+		}, nil
+	}
+
 	// Convert response table to HTTPResponse
 	if tbl, ok := ret.(*lua.LTable); ok {
 		httpResp := luaTableToHTTPResponse(L, tbl)
@@ -1,8 +1,11 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +15,8 @@ import (
 	internalhttp "github.com/dimiro1/lunar/internal/http"
 	"github.com/dimiro1/lunar/internal/kv"
 	"github.com/dimiro1/lunar/internal/logger"
+	"github.com/dimiro1/lunar/internal/store"
+	"github.com/dimiro1/lunar/internal/tracing"
 )
 
 func TestRun_HTTPEvent_Success(t *testing.T) {
@@ -173,6 +178,247 @@ end
 	}
 }
 
+func TestRun_HTTPEvent_Form(t *testing.T) {
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	event := events.HTTPEvent{
+		Method: "POST",
+		Path:   "/",
+		Headers: map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded; charset=utf-8",
+		},
+		Body: "name=Ada+Lovelace&tags=a&tags=b",
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	return {
+		statusCode = 200,
+		body = event.form.name .. "," .. event.form.tags[1] .. event.form.tags[2] .. "," .. #event.files
+	}
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expectedBody := "Ada Lovelace,ab,0"
+	if resp.HTTP.Body != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
+	}
+}
+
+func TestRun_HTTPEvent_MultipartForm(t *testing.T) {
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("title", "Report")
+	part, _ := writer.CreateFormFile("upload", "report.txt")
+	_, _ = part.Write([]byte("hello"))
+	_ = writer.Close()
+
+	event := events.HTTPEvent{
+		Method:  "POST",
+		Path:    "/",
+		Headers: map[string]string{"Content-Type": writer.FormDataContentType()},
+		Body:    body.String(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local file = event.files[1]
+	return {
+		statusCode = 200,
+		body = event.form.title .. "," .. file.field .. "," .. file.filename .. "," ..
+		       file.contentType .. "," .. file.size .. "," .. base64.decode(file.content)
+	}
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expectedBody := "Report,upload,report.txt,application/octet-stream,5,hello"
+	if resp.HTTP.Body != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
+	}
+}
+
+func TestRun_HTTPEvent_InvalidMultipartForm(t *testing.T) {
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	event := events.HTTPEvent{
+		Method:  "POST",
+		Path:    "/",
+		Headers: map[string]string{"Content-Type": "multipart/form-data"},
+		Body:    "not multipart",
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	return { statusCode = 200, body = event.formError .. "," .. #event.files }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expectedBody := "multipart body has no boundary,0"
+	if resp.HTTP.Body != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
+	}
+}
+
+func TestRun_EmailEvent(t *testing.T) {
+	memLogger := logger.NewMemoryLogger()
+	deps := Dependencies{
+		Logger: memLogger,
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	event := events.EmailEvent{
+		MessageID: "abc@example.com",
+		Recipient: "support@lunar.dev",
+		From:      "ana@example.com",
+		To:        []string{"support@lunar.dev", "billing@lunar.dev"},
+		Cc:        []string{},
+		Subject:   "Invoice",
+		Text:      "Hi",
+		Headers:   map[string]string{"X-Priority": "1"},
+		Attachments: []events.EmailAttachment{
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 8, Content: []byte("%PDF-1.7")},
+		},
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local attachment = event.attachments[1]
+	log.info(table.concat({
+		event.recipient, event.from, event.to[2], #event.cc, event.subject, event.text,
+		event.headers["X-Priority"], event.messageId,
+		attachment.filename, attachment.contentType, attachment.size, attachment.content
+	}, ","))
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.Type != events.EventTypeEmail || resp.HTTP != nil {
+		t.Errorf("expected an email response, got %+v", resp)
+	}
+
+	entries := memLogger.Entries("exec-123")
+	expected := "support@lunar.dev,ana@example.com,billing@lunar.dev,0,Invoice,Hi,1,abc@example.com,invoice.pdf,application/pdf,8,JVBERi0xLjc="
+	if len(entries) != 1 || entries[0].Message != expected {
+		t.Errorf("expected log %q, got %+v", expected, entries)
+	}
+
+	_, err = Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: `function handler(ctx, event) error("spam") end`})
+	if err == nil || !strings.Contains(err.Error(), "spam") {
+		t.Errorf("expected the handler error, got %v", err)
+	}
+}
+
+func TestRun_EmailBounceEvent(t *testing.T) {
+	memLogger := logger.NewMemoryLogger()
+	deps := Dependencies{
+		Logger: memLogger,
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-456",
+		FunctionID:  "bounces",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	event := events.EmailBounceEvent{
+		EmailID:     "4ef9a417",
+		ExecutionID: "exec-123",
+		From:        "news@lunar.dev",
+		To:          []string{"gone@example.com"},
+		Subject:     "Newsletter",
+		BounceType:  "Permanent",
+		SubType:     "Suppressed",
+		Message:     "The recipient is on the suppression list",
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	log.info(table.concat({
+		event.emailId, event.executionId, event.from, event.to[1], event.subject,
+		event.bounceType, event.bounceSubType, event.message
+	}, ","))
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: event, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.Type != events.EventTypeEmailBounce {
+		t.Errorf("expected an email bounce response, got %+v", resp)
+	}
+
+	entries := memLogger.Entries("exec-456")
+	expected := "4ef9a417,exec-123,news@lunar.dev,gone@example.com,Newsletter,Permanent,Suppressed,The recipient is on the suppression list"
+	if len(entries) != 1 || entries[0].Message != expected {
+		t.Errorf("expected log %q, got %+v", expected, entries)
+	}
+}
+
 func TestRun_Logger(t *testing.T) {
 	memLogger := logger.NewMemoryLogger()
 	deps := Dependencies{
@@ -237,6 +483,84 @@ end
 	}
 }
 
+func TestRun_LoggerFields(t *testing.T) {
+	memLogger := logger.NewMemoryLogger()
+	deps := Dependencies{
+		Logger: memLogger,
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	log.info("charged", {amount = 10, user = "u_1", password = "hunter2", meta = {retry = true}})
+	log.warn("plain")
+	return { statusCode = 200 }
+end
+`
+
+	_, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	entries := memLogger.Entries("exec-123")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+
+	fields := entries[0].Fields
+	if fields["amount"] != float64(10) || fields["user"] != "u_1" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+	if fields["password"] != "[REDACTED]" {
+		t.Errorf("expected password to be redacted, got %v", fields["password"])
+	}
+	if meta, ok := fields["meta"].(map[string]any); !ok || meta["retry"] != true {
+		t.Errorf("expected nested fields, got %v", fields["meta"])
+	}
+	if entries[0].Line != 3 || entries[1].Line != 4 {
+		t.Errorf("expected lines 3 and 4, got %d and %d", entries[0].Line, entries[1].Line)
+	}
+	if entries[1].Fields != nil {
+		t.Errorf("expected no fields, got %v", entries[1].Fields)
+	}
+}
+
+func TestRun_LoggerInvalidFields(t *testing.T) {
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	log.info("list", {1, 2, 3})
+	return { statusCode = 200 }
+end
+`
+
+	_, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err == nil || !strings.Contains(err.Error(), "fields must be a table with string keys") {
+		t.Errorf("expected fields error, got %v", err)
+	}
+}
+
 func TestRun_KV(t *testing.T) {
 	deps := Dependencies{
 		Logger: logger.NewMemoryLogger(),
@@ -1448,3 +1772,510 @@ end
 		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
 	}
 }
+
+func TestRun_HTTPTracksRequests(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	fakeClient.SetResponse("POST", "https://api.example.com/items", internalhttp.Response{
+		StatusCode: 201,
+		Body:       `{"id": "item_1"}`,
+	})
+	fakeClient.SetError("GET", "https://down.example.com", errors.New("connection refused"))
+
+	tracker := internalhttp.NewMemoryTracker()
+	deps := Dependencies{
+		Logger:      logger.NewMemoryLogger(),
+		KV:          kv.NewMemoryStore(),
+		Env:         env.NewMemoryStore(),
+		HTTP:        fakeClient,
+		HTTPTracker: tracker,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	http.post("https://api.example.com/items", {
+		headers = { Authorization = "Bearer secret" },
+		query = { page = "2" },
+		body = '{"name": "widget"}'
+	})
+	http.get("https://down.example.com")
+	return { statusCode = 200 }
+end
+`
+
+	_, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	requests := tracker.Requests("exec-123")
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 tracked requests, got %d", len(requests))
+	}
+
+	post := requests[0]
+	if post.Method != "POST" || post.URL != "https://api.example.com/items?page=2" {
+		t.Errorf("unexpected request: %s %s", post.Method, post.URL)
+	}
+	if post.RequestHeaders["Authorization"] != "[REDACTED]" {
+		t.Errorf("expected Authorization to be masked, got %q", post.RequestHeaders["Authorization"])
+	}
+	if post.StatusCode == nil || *post.StatusCode != 201 || post.Status != store.HTTPRequestStatusSuccess {
+		t.Errorf("expected successful 201 response, got %+v", post)
+	}
+
+	failed := requests[1]
+	if failed.Status != store.HTTPRequestStatusError || failed.ErrorMessage == nil {
+		t.Errorf("expected failed request to be tracked as error, got %+v", failed)
+	}
+}
+
+func TestRun_HTTPPolicy(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	policy, err := internalhttp.NewPolicy(&store.NetworkPolicy{AllowHosts: []string{"api.example.com"}})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	deps := Dependencies{
+		Logger:     logger.NewMemoryLogger(),
+		KV:         kv.NewMemoryStore(),
+		Env:        env.NewMemoryStore(),
+		HTTP:       fakeClient,
+		HTTPPolicy: policy,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	http.get("https://api.example.com")
+	http.post("https://api.example.com")
+	http.put("https://api.example.com")
+	http.delete("https://api.example.com")
+	return { statusCode = 200 }
+end
+`
+
+	if _, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(fakeClient.Requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(fakeClient.Requests))
+	}
+	for _, req := range fakeClient.Requests {
+		if req.Policy != policy {
+			t.Errorf("expected request to %s to carry the network policy", req.URL)
+		}
+	}
+}
+
+func TestRun_HTTPRequestOptions(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	http.patch("https://api.example.com/items/1", {json = {name = "widget"}})
+	http.head("https://api.example.com/items/1", {timeout = 1500, follow_redirects = false})
+	http.request({
+		method = "options",
+		url = "https://api.example.com/items",
+		auth = {username = "user", password = "secret"},
+	})
+	local _, err = http.post("https://api.example.com/items", {body = "a", json = {}})
+	return { statusCode = 200, body = err }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if resp.HTTP.Body != "only one of body, json, form or multipart can be set" {
+		t.Errorf("expected body options error, got %q", resp.HTTP.Body)
+	}
+	if len(fakeClient.Requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(fakeClient.Requests))
+	}
+
+	patch := fakeClient.Requests[0]
+	if patch.Method != "PATCH" || patch.Body != `{"name":"widget"}` {
+		t.Errorf("expected PATCH with JSON body, got %s %q", patch.Method, patch.Body)
+	}
+	if patch.Headers["Content-Type"] != "application/json" {
+		t.Errorf("expected JSON content type, got %q", patch.Headers["Content-Type"])
+	}
+
+	head := fakeClient.Requests[1]
+	if head.Method != "HEAD" || head.Timeout != 1500*time.Millisecond || !head.NoRedirects {
+		t.Errorf("expected HEAD with timeout and no redirects, got %+v", head)
+	}
+
+	options := fakeClient.Requests[2]
+	if options.Method != "OPTIONS" {
+		t.Errorf("expected OPTIONS request, got %s", options.Method)
+	}
+	if options.Headers["Authorization"] != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("expected basic auth header, got %q", options.Headers["Authorization"])
+	}
+}
+
+func TestRun_HTTPRetry(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	fakeClient.SetResponse("GET", "https://api.example.com/flaky", internalhttp.Response{StatusCode: 503})
+	fakeClient.SetResponse("GET", "https://api.example.com/missing", internalhttp.Response{StatusCode: 404})
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local flaky = http.get("https://api.example.com/flaky", {retry = {attempts = 3, backoff = 1}})
+	local missing = http.get("https://api.example.com/missing", {retry = {attempts = 3, backoff = 1}})
+	local _, err = http.post("https://api.example.com/items", {retry = {attempts = 3}})
+	return { statusCode = 200, body = flaky.statusCode .. "," .. missing.statusCode .. "," .. err }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expectedBody := "503,404,retry is not supported for POST requests"
+	if resp.HTTP.Body != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
+	}
+
+	// The 503 is retried until attempts run out, the 404 is not retried
+	if len(fakeClient.Requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(fakeClient.Requests))
+	}
+}
+
+func TestRun_HTTPResponseJSON(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	fakeClient.SetResponse("GET", "https://api.example.com/item", internalhttp.Response{
+		StatusCode: 200,
+		Headers:    internalhttp.Headers{"Content-Type": "application/json; charset=utf-8"},
+		Body:       `{"name": "widget", "tags": ["a", "b"]}`,
+	})
+	fakeClient.SetResponse("GET", "https://api.example.com/text", internalhttp.Response{
+		StatusCode: 200,
+		Headers:    internalhttp.Headers{"Content-Type": "text/plain"},
+		Body:       `{"name": "widget"}`,
+	})
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local item = http.get("https://api.example.com/item")
+	local text = http.get("https://api.example.com/text")
+	return { statusCode = 200, body = item.json.name .. "," .. item.json.tags[2] .. "," .. tostring(text.json) }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if resp.HTTP.Body != "widget,b,nil" {
+		t.Errorf("expected body %q, got %q", "widget,b,nil", resp.HTTP.Body)
+	}
+}
+
+func TestRun_HTTPFormBodies(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	http.post("https://api.example.com/login", {form = {user = "ada", scope = {"read", "write"}}})
+	http.post("https://api.example.com/upload", {
+		multipart = {
+			fields = {title = "Report"},
+			files = {{field = "upload", filename = "report.csv", contentType = "text/csv", content = "a,b"}},
+		},
+	})
+	local body, contentType = http.multipart({fields = {title = "Manual"}})
+	return { statusCode = 200, body = contentType }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !strings.HasPrefix(resp.HTTP.Body, "multipart/form-data; boundary=") {
+		t.Errorf("expected multipart content type, got %q", resp.HTTP.Body)
+	}
+	if len(fakeClient.Requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(fakeClient.Requests))
+	}
+
+	login := fakeClient.Requests[0]
+	if login.Body != "scope=read&scope=write&user=ada" {
+		t.Errorf("expected urlencoded body, got %q", login.Body)
+	}
+	if login.Headers["Content-Type"] != "application/x-www-form-urlencoded" {
+		t.Errorf("expected form content type, got %q", login.Headers["Content-Type"])
+	}
+
+	// The multipart body round-trips through the form parser used for events
+	upload := fakeClient.Requests[1]
+	form, files, err := events.HTTPEvent{Headers: upload.Headers, Body: upload.Body}.ParseForm()
+	if err != nil {
+		t.Fatalf("ParseForm failed: %v", err)
+	}
+	if form.Get("title") != "Report" {
+		t.Errorf("expected title field 'Report', got %q", form.Get("title"))
+	}
+	if len(files) != 1 || files[0].Filename != "report.csv" || files[0].ContentType != "text/csv" || string(files[0].Content) != "a,b" {
+		t.Errorf("unexpected files: %+v", files)
+	}
+}
+
+func TestRun_URL_EncodeForm_DecodeForm(t *testing.T) {
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	local encoded = url.encodeForm({q = "a b", ids = {"1", "2"}})
+	local decoded, err = url.decodeForm(encoded)
+	if err then
+		return { statusCode = 500, body = err }
+	end
+	local _, invalidErr = url.decodeForm("a=%zz")
+	return {
+		statusCode = 200,
+		body = encoded .. "," .. decoded.q .. "," .. decoded.ids[2] .. "," .. tostring(invalidErr ~= nil)
+	}
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{Context: execCtx, Event: events.HTTPEvent{Method: "GET", Path: "/"}, Code: luaCode})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expectedBody := "ids=1&ids=2&q=a+b,a b,2,true"
+	if resp.HTTP.Body != expectedBody {
+		t.Errorf("expected body %q, got %q", expectedBody, resp.HTTP.Body)
+	}
+}
+
+func TestRun_RecordsTrace(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	trace := tracing.NewTrace("exec-123", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+		Trace:  trace,
+	}
+
+	execCtx := &events.ExecutionContext{
+		ExecutionID: "exec-123",
+		FunctionID:  "test-function",
+		StartedAt:   time.Now().Unix(),
+		TraceID:     trace.TraceID(),
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	kv.set("greeting", "hello")
+	kv.get("greeting")
+	http.get("https://api.example.com/data?api_key=secret")
+	return { statusCode = 200, body = ctx.traceId }
+end
+`
+
+	resp, err := Run(context.Background(), deps, Request{
+		Context: execCtx,
+		Event:   events.HTTPEvent{Method: "GET", Path: "/"},
+		Code:    luaCode,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if resp.HTTP.Body != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected ctx.traceId to be the trace ID, got %q", resp.HTTP.Body)
+	}
+
+	spans := trace.Spans()
+	names := make([]string, 0, len(spans))
+	byName := make(map[string]store.Span)
+	for _, s := range spans {
+		names = append(names, s.Name)
+		byName[s.Name] = s
+	}
+
+	expected := []string{"lua.load", "lua.handler", "kv.set", "kv.get", "http.get"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected spans %v, got %v", expected, names)
+	}
+
+	handlerID := byName["lua.handler"].ID
+	for _, name := range []string{"kv.set", "kv.get", "http.get"} {
+		if p := byName[name].ParentID; p == nil || *p != handlerID {
+			t.Errorf("expected %s to be a child of lua.handler", name)
+		}
+	}
+
+	httpSpan := byName["http.get"]
+	if httpSpan.Attributes["url.full"] != "https://api.example.com/data" {
+		t.Errorf("expected url.full without query string, got %q", httpSpan.Attributes["url.full"])
+	}
+	if httpSpan.Attributes["http.response.status_code"] != "200" {
+		t.Errorf("expected status code attribute, got %q", httpSpan.Attributes["http.response.status_code"])
+	}
+	if byName["kv.get"].Attributes["kv.hit"] != "true" {
+		t.Errorf("expected kv.hit=true, got %q", byName["kv.get"].Attributes["kv.hit"])
+	}
+
+	// The outbound request carries a traceparent identifying the http.get span
+	if len(fakeClient.Requests) != 1 {
+		t.Fatalf("expected 1 outbound request, got %d", len(fakeClient.Requests))
+	}
+	traceparent := fakeClient.Requests[0].Headers[tracing.TraceparentHeader]
+	if traceparent != tracing.FormatTraceparent(trace.TraceID(), httpSpan.ID) {
+		t.Errorf("expected propagated traceparent for http.get span, got %q", traceparent)
+	}
+}
+
+func TestRun_TracePreservesUserTraceparent(t *testing.T) {
+	fakeClient := internalhttp.NewFakeClient()
+	trace := tracing.NewTrace("exec-123", "")
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   fakeClient,
+		Trace:  trace,
+	}
+
+	luaCode := `
+function handler(ctx, event)
+	http.post("https://api.example.com", { headers = { Traceparent = "custom" } })
+	return { statusCode = 200 }
+end
+`
+
+	_, err := Run(context.Background(), deps, Request{
+		Context: &events.ExecutionContext{ExecutionID: "exec-123", FunctionID: "fn"},
+		Event:   events.HTTPEvent{Method: "GET", Path: "/"},
+		Code:    luaCode,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	headers := fakeClient.Requests[0].Headers
+	if headers["Traceparent"] != "custom" {
+		t.Errorf("expected user traceparent to be kept, got %q", headers["Traceparent"])
+	}
+	if _, ok := headers[tracing.TraceparentHeader]; ok {
+		t.Error("expected no additional traceparent header")
+	}
+}
+
+func TestRun_TraceRecordsLoadError(t *testing.T) {
+	trace := tracing.NewTrace("exec-123", "")
+
+	deps := Dependencies{
+		Logger: logger.NewMemoryLogger(),
+		KV:     kv.NewMemoryStore(),
+		Env:    env.NewMemoryStore(),
+		HTTP:   &internalhttp.FakeClient{},
+		Trace:  trace,
+	}
+
+	_, err := Run(context.Background(), deps, Request{
+		Context: &events.ExecutionContext{ExecutionID: "exec-123", FunctionID: "fn"},
+		Event:   events.HTTPEvent{Method: "GET", Path: "/"},
+		Code:    "function handler(ctx, event",
+	})
+	if err == nil {
+		t.Fatal("expected load error")
+	}
+
+	spans := trace.Spans()
+	if len(spans) != 1 || spans[0].Name != "lua.load" || spans[0].Status != store.SpanStatusError {
+		t.Errorf("expected a single failed lua.load span, got %+v", spans)
+	}
+}
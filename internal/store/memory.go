@@ -117,6 +117,9 @@ func (db *MemoryDB) UpdateFunction(_ context.Context, id string, updates UpdateF
 	if updates.RetentionDays != nil {
 		fn.RetentionDays = updates.RetentionDays
 	}
+	if updates.NetworkPolicy != nil {
+		fn.NetworkPolicy = updates.NetworkPolicy
+	}
 
 	fn.UpdatedAt = time.Now().Unix()
 	db.functions[id] = fn
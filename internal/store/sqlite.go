@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -42,15 +43,15 @@ func (db *SQLiteDB) CreateFunction(ctx context.Context, fn Function) (Function,
 }
 
 func (db *SQLiteDB) GetFunction(ctx context.Context, id string) (Function, error) {
-	query := `SELECT id, name, description, disabled, retention_days, created_at, updated_at
+	query := `SELECT id, name, description, disabled, retention_days, network_policy, created_at, updated_at
 	          FROM functions WHERE id = ?`
 
 	var fn Function
-	var description sql.NullString
+	var description, networkPolicy sql.NullString
 	var retentionDays sql.NullInt64
 
 	err := db.db.QueryRowContext(ctx, query, id).Scan(
-		&fn.ID, &fn.Name, &description, &fn.Disabled, &retentionDays, &fn.CreatedAt, &fn.UpdatedAt,
+		&fn.ID, &fn.Name, &description, &fn.Disabled, &retentionDays, &networkPolicy, &fn.CreatedAt, &fn.UpdatedAt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return Function{}, ErrFunctionNotFound
@@ -66,6 +67,9 @@ func (db *SQLiteDB) GetFunction(ctx context.Context, id string) (Function, error
 		days := int(retentionDays.Int64)
 		fn.RetentionDays = &days
 	}
+	if fn.NetworkPolicy, err = decodeNetworkPolicy(networkPolicy); err != nil {
+		return Function{}, err
+	}
 
 	fn.EnvVars = make(map[string]string)
 
@@ -84,7 +88,7 @@ func (db *SQLiteDB) ListFunctions(ctx context.Context, params PaginationParams)
 	params = params.Normalize()
 
 	query := `SELECT
-		f.id, f.name, f.description, f.disabled, f.retention_days, f.created_at, f.updated_at,
+		f.id, f.name, f.description, f.disabled, f.retention_days, f.network_policy, f.created_at, f.updated_at,
 		fv.id, fv.version, fv.code, fv.created_at, fv.created_by
 	FROM functions f
 	LEFT JOIN function_versions fv ON f.id = fv.function_id AND fv.is_active = 1
@@ -100,7 +104,7 @@ func (db *SQLiteDB) ListFunctions(ctx context.Context, params PaginationParams)
 	var functions []FunctionWithActiveVersion
 	for rows.Next() {
 		var fn FunctionWithActiveVersion
-		var description sql.NullString
+		var description, networkPolicy sql.NullString
 		var retentionDays sql.NullInt64
 		var versionID, versionCode sql.NullString
 		var versionNum sql.NullInt64
@@ -108,7 +112,7 @@ func (db *SQLiteDB) ListFunctions(ctx context.Context, params PaginationParams)
 		var versionCreatedBy sql.NullString
 
 		if err := rows.Scan(
-			&fn.ID, &fn.Name, &description, &fn.Disabled, &retentionDays, &fn.CreatedAt, &fn.UpdatedAt,
+			&fn.ID, &fn.Name, &description, &fn.Disabled, &retentionDays, &networkPolicy, &fn.CreatedAt, &fn.UpdatedAt,
 			&versionID, &versionNum, &versionCode, &versionCreatedAt, &versionCreatedBy,
 		); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan function: %w", err)
@@ -121,6 +125,9 @@ func (db *SQLiteDB) ListFunctions(ctx context.Context, params PaginationParams)
 			days := int(retentionDays.Int64)
 			fn.RetentionDays = &days
 		}
+		if fn.NetworkPolicy, err = decodeNetworkPolicy(networkPolicy); err != nil {
+			return nil, 0, err
+		}
 
 		fn.EnvVars = make(map[string]string)
 
@@ -194,9 +201,33 @@ func (db *SQLiteDB) UpdateFunction(ctx context.Context, id string, updates Updat
 		}
 	}
 
+	if updates.NetworkPolicy != nil {
+		networkPolicy, err := json.Marshal(updates.NetworkPolicy)
+		if err != nil {
+			return fmt.Errorf("failed to encode network policy: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE functions SET network_policy = ?, updated_at = ? WHERE id = ?",
+			string(networkPolicy), time.Now().Unix(), id)
+		if err != nil {
+			return fmt.Errorf("failed to update network policy: %w", err)
+		}
+	}
+
 	return tx.Commit()
 }
 
+// decodeNetworkPolicy decodes a JSON-encoded network policy column
+func decodeNetworkPolicy(value sql.NullString) (*NetworkPolicy, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	var policy NetworkPolicy
+	if err := json.Unmarshal([]byte(value.String), &policy); err != nil {
+		return nil, fmt.Errorf("failed to decode network policy: %w", err)
+	}
+	return &policy, nil
+}
+
 func (db *SQLiteDB) DeleteFunction(ctx context.Context, id string) error {
 	result, err := db.db.ExecContext(ctx, "DELETE FROM functions WHERE id = ?", id)
 	if err != nil {
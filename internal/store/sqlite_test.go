@@ -1257,6 +1257,54 @@ func TestSQLiteDB_UpdateFunction_RetentionDays(t *testing.T) {
 	}
 }
 
+func TestSQLiteDB_UpdateFunction_NetworkPolicy(t *testing.T) {
+	db, sqliteDB := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+
+	fn := Function{
+		ID:      "func_network",
+		Name:    "network-test",
+		EnvVars: make(map[string]string),
+	}
+	if _, err := sqliteDB.CreateFunction(ctx, fn); err != nil {
+		t.Fatalf("CreateFunction failed: %v", err)
+	}
+
+	updates := UpdateFunctionRequest{
+		NetworkPolicy: &NetworkPolicy{
+			AllowHosts: []string{"api.example.com", "10.0.0.0/8"},
+			DenyPorts:  []int{25},
+		},
+	}
+	if err := sqliteDB.UpdateFunction(ctx, fn.ID, updates); err != nil {
+		t.Fatalf("UpdateFunction failed: %v", err)
+	}
+
+	updated, err := sqliteDB.GetFunction(ctx, fn.ID)
+	if err != nil {
+		t.Fatalf("GetFunction failed: %v", err)
+	}
+	if updated.NetworkPolicy == nil {
+		t.Fatal("Expected NetworkPolicy to be set")
+	}
+	if len(updated.NetworkPolicy.AllowHosts) != 2 || updated.NetworkPolicy.AllowHosts[1] != "10.0.0.0/8" {
+		t.Errorf("Expected AllowHosts to round-trip, got %v", updated.NetworkPolicy.AllowHosts)
+	}
+	if len(updated.NetworkPolicy.DenyPorts) != 1 || updated.NetworkPolicy.DenyPorts[0] != 25 {
+		t.Errorf("Expected DenyPorts [25], got %v", updated.NetworkPolicy.DenyPorts)
+	}
+
+	functions, _, err := sqliteDB.ListFunctions(ctx, PaginationParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListFunctions failed: %v", err)
+	}
+	if len(functions) != 1 || functions[0].NetworkPolicy == nil {
+		t.Error("Expected ListFunctions to include the network policy")
+	}
+}
+
 func TestSQLiteDB_DeleteOldExecutions(t *testing.T) {
 	db, sqliteDB := setupTestDB(t)
 	defer func() { _ = db.Close() }()
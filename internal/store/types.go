@@ -40,10 +40,56 @@ type AIRequest struct {
 	ErrorMessage *string         `json:"error_message,omitempty"`
 	InputTokens  *int            `json:"input_tokens,omitempty"`
 	OutputTokens *int            `json:"output_tokens,omitempty"`
+	Cost         *float64        `json:"cost,omitempty"` // USD, nil when the model has no price
+	Cached       bool            `json:"cached"`         // Answered from the response cache
 	DurationMs   int64           `json:"duration_ms"`
 	CreatedAt    int64           `json:"created_at"`
 }
 
+// AIUsage is the AI usage of a function for one model on one day (UTC)
+type AIUsage struct {
+	FunctionID   string  `json:"function_id"`
+	Day          string  `json:"day"` // YYYY-MM-DD
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"` // USD
+}
+
+// AICacheEntry is a cached AI chat response of a function
+type AICacheEntry struct {
+	FunctionID   string `json:"function_id"`
+	Key          string `json:"key"` // SHA-256 of the request
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+	RequestJSON  string `json:"request_json"`
+	ResponseJSON string `json:"response_json"`
+	Hits         int    `json:"hits"`
+	CreatedAt    int64  `json:"created_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+// EmailTemplate is a named email body rendered by email.send. The subject
+// and text are Go text templates, the HTML is a Go HTML template.
+type EmailTemplate struct {
+	Name      string `json:"name"`
+	Subject   string `json:"subject"`
+	Text      string `json:"text"`
+	HTML      string `json:"html"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// InboundEmailRoute routes the emails received for an address to a
+// function. An address of the form *@domain matches the whole domain.
+type InboundEmailRoute struct {
+	Address    string `json:"address"`
+	FunctionID string `json:"function_id"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
 // EmailRequestStatus represents the status of an email request
 type EmailRequestStatus string
 
@@ -68,6 +114,78 @@ type EmailRequest struct {
 	EmailID      *string            `json:"email_id,omitempty"`
 	DurationMs   int64              `json:"duration_ms"`
 	CreatedAt    int64              `json:"created_at"`
+
+	// Delivery status reported by the provider, the type of the latest
+	// delivery event
+	DeliveryStatus *string              `json:"delivery_status,omitempty"`
+	DeliveryEvents []EmailDeliveryEvent `json:"delivery_events,omitempty"`
+}
+
+// EmailDeliveryEvent is a delivery status change reported by the email
+// provider, such as delivered, bounced or complained
+type EmailDeliveryEvent struct {
+	ID         string  `json:"id"` // ID of the webhook message, to ignore retries
+	Type       string  `json:"type"`
+	Detail     *string `json:"detail,omitempty"` // Bounce message, failure reason or clicked link
+	OccurredAt int64   `json:"occurred_at"`
+}
+
+// HTTPRequestStatus represents the status of an outbound HTTP request
+type HTTPRequestStatus string
+
+const (
+	HTTPRequestStatusSuccess HTTPRequestStatus = "success"
+	HTTPRequestStatusError   HTTPRequestStatus = "error"
+)
+
+// HTTPRequest represents a tracked outbound HTTP request made by a function
+type HTTPRequest struct {
+	ID              string            `json:"id"`
+	ExecutionID     string            `json:"execution_id"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     *string           `json:"request_body,omitempty"`
+	StatusCode      *int              `json:"status_code,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    *string           `json:"response_body,omitempty"`
+	ResponseSize    int64             `json:"response_size"`
+	Status          HTTPRequestStatus `json:"status"`
+	ErrorMessage    *string           `json:"error_message,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	CreatedAt       int64             `json:"created_at"`
+}
+
+// SpanKind describes the role of a span in a trace
+type SpanKind string
+
+const (
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+	SpanKindInternal SpanKind = "internal"
+)
+
+// SpanStatus represents the outcome of a span
+type SpanStatus string
+
+const (
+	SpanStatusOK    SpanStatus = "ok"
+	SpanStatusError SpanStatus = "error"
+)
+
+// Span represents a timed operation within an execution trace
+type Span struct {
+	ID           string            `json:"id"`
+	TraceID      string            `json:"trace_id"`
+	ParentID     *string           `json:"parent_id,omitempty"`
+	ExecutionID  string            `json:"execution_id"`
+	Name         string            `json:"name"`
+	Kind         SpanKind          `json:"kind"`
+	Status       SpanStatus        `json:"status"`
+	ErrorMessage *string           `json:"error_message,omitempty"`
+	Attributes   map[string]string `json:"attributes"`
+	StartTime    int64             `json:"start_time"` // Unix nanoseconds
+	EndTime      int64             `json:"end_time"`   // Unix nanoseconds
 }
 
 // Function represents a serverless function
@@ -78,10 +196,22 @@ type Function struct {
 	EnvVars       map[string]string `json:"env_vars"`
 	Disabled      bool              `json:"disabled"`
 	RetentionDays *int              `json:"retention_days,omitempty"`
+	NetworkPolicy *NetworkPolicy    `json:"network_policy,omitempty"`
 	CreatedAt     int64             `json:"created_at"`
 	UpdatedAt     int64             `json:"updated_at"`
 }
 
+// NetworkPolicy controls which addresses the outbound HTTP requests of a
+// function may connect to. Private, loopback and link-local addresses are
+// denied unless AllowPrivateNetworks is set or an allowed CIDR contains them.
+type NetworkPolicy struct {
+	AllowPrivateNetworks bool     `json:"allow_private_networks"`
+	AllowHosts           []string `json:"allow_hosts,omitempty"` // Hostnames, *.wildcards, IPs or CIDRs
+	DenyHosts            []string `json:"deny_hosts,omitempty"`  // Hostnames, *.wildcards, IPs or CIDRs
+	AllowPorts           []int    `json:"allow_ports,omitempty"`
+	DenyPorts            []int    `json:"deny_ports,omitempty"`
+}
+
 // FunctionVersion represents a specific version of a function
 type FunctionVersion struct {
 	ID         string  `json:"id"`
@@ -140,9 +270,10 @@ type PaginationInfo struct {
 
 // UpdateFunctionRequest is the request body for updating a function
 type UpdateFunctionRequest struct {
-	Name          *string `json:"name,omitempty"`
-	Description   *string `json:"description,omitempty"`
-	Code          *string `json:"code,omitempty"`
-	Disabled      *bool   `json:"disabled,omitempty"`
-	RetentionDays *int    `json:"retention_days,omitempty"`
+	Name          *string        `json:"name,omitempty"`
+	Description   *string        `json:"description,omitempty"`
+	Code          *string        `json:"code,omitempty"`
+	Disabled      *bool          `json:"disabled,omitempty"`
+	RetentionDays *int           `json:"retention_days,omitempty"`
+	NetworkPolicy *NetworkPolicy `json:"network_policy,omitempty"`
 }
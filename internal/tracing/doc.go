@@ -0,0 +1,5 @@
+// Package tracing records a span tree for each function execution.
+// Spans follow the OpenTelemetry data model, accept and propagate W3C
+// traceparent headers, are stored locally for the dashboard waterfall, and
+// can optionally be exported to an OTLP/HTTP collector.
+package tracing
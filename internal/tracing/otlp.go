@@ -0,0 +1,206 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// Exporter sends finished spans to an external tracing backend
+type Exporter interface {
+	Export(spans []store.Span) error
+}
+
+// OTLPExporter exports spans to an OpenTelemetry collector using OTLP/HTTP with JSON encoding
+type OTLPExporter struct {
+	client      internalhttp.Client
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+}
+
+// NewOTLPExporter creates an exporter that posts spans to endpoint, which
+// must be the full traces URL (e.g. http://localhost:4318/v1/traces)
+func NewOTLPExporter(client internalhttp.Client, endpoint string, headers map[string]string, serviceName string) *OTLPExporter {
+	if serviceName == "" {
+		serviceName = "lunar"
+	}
+	return &OTLPExporter{
+		client:      client,
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+	}
+}
+
+// Export sends the spans to the collector
+func (e *OTLPExporter) Export(spans []store.Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(e.buildPayload(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	headers := internalhttp.Headers{"Content-Type": "application/json"}
+	for k, v := range e.headers {
+		headers[k] = v
+	}
+
+	resp, err := e.client.Post(internalhttp.Request{
+		URL:     e.endpoint,
+		Headers: headers,
+		Body:    string(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return &internalhttp.Error{StatusCode: resp.StatusCode, Message: "OTLP export failed", Body: resp.Body}
+	}
+	return nil
+}
+
+// OTLP/JSON payload types (see opentelemetry-proto trace/v1)
+
+type otlpPayload struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value otlpStringAttr `json:"value"`
+}
+
+type otlpStringAttr struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// OTLP span kind and status code values
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+	otlpKindClient   = 3
+
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+func (e *OTLPExporter) buildPayload(spans []store.Span) otlpPayload {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.ID,
+			Name:              s.Name,
+			Kind:              otlpKind(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime, 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime, 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.ParentID != nil {
+			span.ParentSpanID = *s.ParentID
+		}
+		if s.Status == store.SpanStatusError {
+			span.Status.Code = otlpStatusError
+			if s.ErrorMessage != nil {
+				span.Status.Message = *s.ErrorMessage
+			}
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+
+	return otlpPayload{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]string{"service.name": e.serviceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "lunar"},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+func otlpKind(kind store.SpanKind) int {
+	switch kind {
+	case store.SpanKindServer:
+		return otlpKindServer
+	case store.SpanKindClient:
+		return otlpKindClient
+	default:
+		return otlpKindInternal
+	}
+}
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpStringAttr{StringValue: attrs[k]}})
+	}
+	return kvs
+}
+
+// ParseHeaders parses an OTEL_EXPORTER_OTLP_HEADERS value ("key1=value1,key2=value2")
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for pair := range strings.SplitSeq(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		headers[key] = strings.TrimSpace(val)
+	}
+	return headers
+}
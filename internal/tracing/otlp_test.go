@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	internalhttp "github.com/dimiro1/lunar/internal/http"
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+func TestOTLPExporter_Export(t *testing.T) {
+	client := internalhttp.NewFakeClient()
+	exporter := NewOTLPExporter(client, "http://collector:4318/v1/traces", map[string]string{"x-api-key": "secret"}, "")
+
+	parentID := "00f067aa0ba902b7"
+	errMsg := "boom"
+	err := exporter.Export([]store.Span{
+		{
+			ID:        "1111111111111111",
+			TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			ParentID:  &parentID,
+			Name:      "execution",
+			Kind:      store.SpanKindServer,
+			Status:    store.SpanStatusOK,
+			StartTime: 1000,
+			EndTime:   2000,
+			Attributes: map[string]string{
+				"lunar.function_id": "fn-1",
+			},
+		},
+		{
+			ID:           "2222222222222222",
+			TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+			Name:         "http.get",
+			Kind:         store.SpanKindClient,
+			Status:       store.SpanStatusError,
+			ErrorMessage: &errMsg,
+			StartTime:    1100,
+			EndTime:      1900,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if len(client.Requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(client.Requests))
+	}
+	req := client.Requests[0]
+	if req.URL != "http://collector:4318/v1/traces" {
+		t.Errorf("unexpected URL: %s", req.URL)
+	}
+	if req.Headers["x-api-key"] != "secret" || req.Headers["Content-Type"] != "application/json" {
+		t.Errorf("unexpected headers: %v", req.Headers)
+	}
+
+	var payload otlpPayload
+	if err := json.Unmarshal([]byte(req.Body), &payload); err != nil {
+		t.Fatalf("invalid JSON payload: %v", err)
+	}
+
+	rs := payload.ResourceSpans[0]
+	if rs.Resource.Attributes[0].Key != "service.name" || rs.Resource.Attributes[0].Value.StringValue != "lunar" {
+		t.Errorf("expected default service.name lunar, got %+v", rs.Resource.Attributes)
+	}
+
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	root := spans[0]
+	if root.ParentSpanID != parentID || root.Kind != otlpKindServer || root.Status.Code != otlpStatusOK {
+		t.Errorf("unexpected root span: %+v", root)
+	}
+	if root.StartTimeUnixNano != "1000" || root.EndTimeUnixNano != "2000" {
+		t.Errorf("unexpected timestamps: %s - %s", root.StartTimeUnixNano, root.EndTimeUnixNano)
+	}
+
+	child := spans[1]
+	if child.Kind != otlpKindClient || child.Status.Code != otlpStatusError || child.Status.Message != "boom" {
+		t.Errorf("unexpected child span: %+v", child)
+	}
+}
+
+func TestOTLPExporter_ErrorStatus(t *testing.T) {
+	client := internalhttp.NewFakeClient()
+	client.SetResponse("POST", "http://collector/v1/traces", internalhttp.Response{StatusCode: 503, Body: "unavailable"})
+	exporter := NewOTLPExporter(client, "http://collector/v1/traces", nil, "svc")
+
+	err := exporter.Export([]store.Span{{ID: "1", TraceID: "t", Name: "execution"}})
+	if err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+}
+
+func TestOTLPExporter_NoSpans(t *testing.T) {
+	client := internalhttp.NewFakeClient()
+	exporter := NewOTLPExporter(client, "http://collector/v1/traces", nil, "svc")
+
+	if err := exporter.Export(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.Requests) != 0 {
+		t.Error("expected no request when there are no spans")
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := ParseHeaders(" api-key = abc ,x-tenant=t1,,invalid")
+	want := map[string]string{"api-key": "abc", "x-tenant": "t1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
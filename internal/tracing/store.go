@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// Store is an interface for persisting execution spans
+type Store interface {
+	Save(spans []store.Span)
+	Spans(executionID string) []store.Span
+}
+
+// MemoryStore is an in-memory implementation of Store
+type MemoryStore struct {
+	mu    sync.RWMutex
+	spans []store.Span
+}
+
+// NewMemoryStore creates a new in-memory span store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		spans: make([]store.Span, 0),
+	}
+}
+
+// Save records the given spans
+func (m *MemoryStore) Save(spans []store.Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = append(m.spans, spans...)
+}
+
+// Spans returns all spans for the specified executionID ordered by start time
+func (m *MemoryStore) Spans(executionID string) []store.Span {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	spans := make([]store.Span, 0)
+	for _, s := range m.spans {
+		if s.ExecutionID == executionID {
+			spans = append(spans, s)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTime < spans[j].StartTime
+	})
+	return spans
+}
+
+// Clear removes all stored spans
+func (m *MemoryStore) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.spans = make([]store.Span, 0)
+}
+
+// SQLiteStore is a SQLite-backed implementation of Store
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed span store
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// Save records the given spans
+func (s *SQLiteStore) Save(spans []store.Span) {
+	for _, span := range spans {
+		attrsJSON, err := json.Marshal(span.Attributes)
+		if err != nil {
+			attrsJSON = []byte("{}")
+		}
+
+		_, err = s.db.Exec(
+			`INSERT INTO spans
+			(id, trace_id, parent_id, execution_id, name, kind, status, error_message,
+			 attributes_json, start_time, end_time)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			span.ID, span.TraceID, span.ParentID, span.ExecutionID, span.Name, span.Kind,
+			span.Status, span.ErrorMessage, string(attrsJSON), span.StartTime, span.EndTime,
+		)
+		if err != nil {
+			// Log error but don't fail the execution
+			fmt.Printf("Failed to save span: %v\n", err)
+		}
+	}
+}
+
+// Spans returns all spans for the specified executionID ordered by start time
+func (s *SQLiteStore) Spans(executionID string) []store.Span {
+	rows, err := s.db.Query(
+		`SELECT id, trace_id, parent_id, execution_id, name, kind, status, error_message,
+		        attributes_json, start_time, end_time
+		 FROM spans WHERE execution_id = ? ORDER BY start_time`,
+		executionID,
+	)
+	if err != nil {
+		return []store.Span{}
+	}
+	defer func() { _ = rows.Close() }()
+
+	spans := make([]store.Span, 0)
+	for rows.Next() {
+		var span store.Span
+		var parentID, errorMessage sql.NullString
+		var attrsJSON string
+
+		if err := rows.Scan(
+			&span.ID, &span.TraceID, &parentID, &span.ExecutionID, &span.Name, &span.Kind,
+			&span.Status, &errorMessage, &attrsJSON, &span.StartTime, &span.EndTime,
+		); err != nil {
+			continue
+		}
+
+		if parentID.Valid {
+			span.ParentID = &parentID.String
+		}
+		if errorMessage.Valid {
+			span.ErrorMessage = &errorMessage.String
+		}
+		if err := json.Unmarshal([]byte(attrsJSON), &span.Attributes); err != nil || span.Attributes == nil {
+			span.Attributes = map[string]string{}
+		}
+
+		spans = append(spans, span)
+	}
+	return spans
+}
@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+// TraceparentHeader is the W3C Trace Context header name
+const TraceparentHeader = "traceparent"
+
+// Trace records the spans of a single function execution.
+// A nil *Trace is valid and records nothing, so callers don't need to check for it.
+type Trace struct {
+	mu          sync.Mutex
+	traceID     string
+	executionID string
+	remoteID    *string // parent span ID received via traceparent
+	spans       []*Span
+	stack       []*Span
+}
+
+// NewTrace creates a trace for an execution, continuing the trace from the
+// given traceparent header when it is valid, or starting a new one otherwise
+func NewTrace(executionID, traceparent string) *Trace {
+	t := &Trace{executionID: executionID}
+
+	if traceID, parentID, ok := ParseTraceparent(traceparent); ok {
+		t.traceID = traceID
+		t.remoteID = &parentID
+	} else {
+		t.traceID = newID(16)
+	}
+
+	return t
+}
+
+// TraceID returns the 32 hex character trace ID
+func (t *Trace) TraceID() string {
+	if t == nil {
+		return ""
+	}
+	return t.traceID
+}
+
+// Start begins a new span as a child of the current span and makes it current
+func (t *Trace) Start(name string, kind store.SpanKind, attrs map[string]string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parentID := t.remoteID
+	if len(t.stack) > 0 {
+		id := t.stack[len(t.stack)-1].id
+		parentID = &id
+	}
+
+	s := &Span{
+		trace:      t,
+		id:         newID(8),
+		parentID:   parentID,
+		name:       name,
+		kind:       kind,
+		status:     store.SpanStatusOK,
+		attributes: make(map[string]string, len(attrs)),
+		start:      time.Now(),
+	}
+	for k, v := range attrs {
+		s.attributes[k] = v
+	}
+
+	t.spans = append(t.spans, s)
+	t.stack = append(t.stack, s)
+	return s
+}
+
+// Spans returns a snapshot of all ended spans ordered by start time
+func (t *Trace) Spans() []store.Span {
+	if t == nil {
+		return []store.Span{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]store.Span, 0, len(t.spans))
+	for _, s := range t.spans {
+		if s.end.IsZero() {
+			continue
+		}
+		spans = append(spans, s.toStore())
+	}
+	return spans
+}
+
+// end removes a span from the stack of current spans
+func (t *Trace) end(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		if t.stack[i] == s {
+			t.stack = append(t.stack[:i], t.stack[i+1:]...)
+			break
+		}
+	}
+}
+
+// Span is a single timed operation within a trace.
+// All methods are safe to call on a nil *Span.
+type Span struct {
+	trace        *Trace
+	id           string
+	parentID     *string
+	name         string
+	kind         store.SpanKind
+	status       store.SpanStatus
+	errorMessage *string
+	attributes   map[string]string
+	start        time.Time
+	end          time.Time
+}
+
+// ID returns the 16 hex character span ID
+func (s *Span) ID() string {
+	if s == nil {
+		return ""
+	}
+	return s.id
+}
+
+// SetAttribute sets a string attribute on the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed with the given message
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	s.status = store.SpanStatusError
+	s.errorMessage = &message
+}
+
+// End records the span end time; calling End more than once has no effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.trace.end(s)
+}
+
+// Traceparent returns the W3C traceparent header value identifying this span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return FormatTraceparent(s.trace.traceID, s.id)
+}
+
+func (s *Span) toStore() store.Span {
+	attrs := make(map[string]string, len(s.attributes))
+	for k, v := range s.attributes {
+		attrs[k] = v
+	}
+	return store.Span{
+		ID:           s.id,
+		TraceID:      s.trace.traceID,
+		ParentID:     s.parentID,
+		ExecutionID:  s.trace.executionID,
+		Name:         s.name,
+		Kind:         s.kind,
+		Status:       s.status,
+		ErrorMessage: s.errorMessage,
+		Attributes:   attrs,
+		StartTime:    s.start.UnixNano(),
+		EndTime:      s.end.UnixNano(),
+	}
+}
+
+// ParseTraceparent extracts the trace ID and parent span ID from a W3C traceparent header
+func ParseTraceparent(header string) (traceID, parentID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return "", "", false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", false
+	}
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return "", "", false
+	}
+	if !isHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	if !isHex(flags, 2) {
+		return "", "", false
+	}
+
+	return traceID, parentID, true
+}
+
+// FormatTraceparent builds a sampled W3C traceparent header value
+func FormatTraceparent(traceID, spanID string) string {
+	return fmt.Sprintf("00-%s-%s-01", traceID, spanID)
+}
+
+// isHex reports whether s is exactly n lowercase hex characters
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newID generates a random hex ID of n bytes
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
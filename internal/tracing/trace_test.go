@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/store"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantOK   bool
+		traceID  string
+		parentID string
+	}{
+		{
+			name:     "valid",
+			header:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantOK:   true,
+			traceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			parentID: "00f067aa0ba902b7",
+		},
+		{
+			name:     "future version with extra fields",
+			header:   "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			wantOK:   true,
+			traceID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+			parentID: "00f067aa0ba902b7",
+		},
+		{name: "empty", header: ""},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "version 00 with extra fields", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{name: "zero trace id", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero parent id", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "uppercase hex", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "short trace id", header: "00-4bf92f35-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, parentID, ok := ParseTraceparent(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if traceID != tt.traceID || parentID != tt.parentID {
+				t.Errorf("expected (%s, %s), got (%s, %s)", tt.traceID, tt.parentID, traceID, parentID)
+			}
+		})
+	}
+}
+
+func TestNewTrace_ContinuesIncomingTrace(t *testing.T) {
+	trace := NewTrace("exec-1", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	if trace.TraceID() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected incoming trace ID, got %s", trace.TraceID())
+	}
+
+	root := trace.Start("execution", store.SpanKindServer, nil)
+	root.End()
+
+	spans := trace.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].ParentID == nil || *spans[0].ParentID != "00f067aa0ba902b7" {
+		t.Errorf("expected root span parent to be the remote span, got %v", spans[0].ParentID)
+	}
+}
+
+func TestNewTrace_StartsNewTrace(t *testing.T) {
+	trace := NewTrace("exec-1", "garbage")
+
+	if !isHex(trace.TraceID(), 32) {
+		t.Errorf("expected generated 32 char hex trace ID, got %q", trace.TraceID())
+	}
+
+	root := trace.Start("execution", store.SpanKindServer, nil)
+	root.End()
+
+	if spans := trace.Spans(); spans[0].ParentID != nil {
+		t.Errorf("expected root span without parent, got %v", *spans[0].ParentID)
+	}
+}
+
+func TestTrace_SpanTree(t *testing.T) {
+	trace := NewTrace("exec-1", "")
+
+	root := trace.Start("execution", store.SpanKindServer, map[string]string{"faas.trigger": "http"})
+	handler := trace.Start("lua.handler", store.SpanKindInternal, nil)
+	kvSpan := trace.Start("kv.get", store.SpanKindInternal, map[string]string{"kv.key": "counter"})
+	kvSpan.SetAttribute("kv.hit", "true")
+	kvSpan.End()
+	httpSpan := trace.Start("http.get", store.SpanKindClient, nil)
+	httpSpan.SetError("connection refused")
+	httpSpan.End()
+	handler.End()
+	root.End()
+
+	spans := trace.Spans()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+
+	byName := make(map[string]store.Span)
+	for _, s := range spans {
+		byName[s.Name] = s
+		if s.TraceID != trace.TraceID() {
+			t.Errorf("span %s has trace ID %s, expected %s", s.Name, s.TraceID, trace.TraceID())
+		}
+		if s.ExecutionID != "exec-1" {
+			t.Errorf("span %s has execution ID %s", s.Name, s.ExecutionID)
+		}
+		if s.EndTime < s.StartTime {
+			t.Errorf("span %s ends before it starts", s.Name)
+		}
+	}
+
+	if p := byName["lua.handler"].ParentID; p == nil || *p != root.ID() {
+		t.Errorf("expected lua.handler to be a child of execution")
+	}
+	if p := byName["kv.get"].ParentID; p == nil || *p != handler.ID() {
+		t.Errorf("expected kv.get to be a child of lua.handler")
+	}
+	if p := byName["http.get"].ParentID; p == nil || *p != handler.ID() {
+		t.Errorf("expected http.get to be a sibling of kv.get")
+	}
+
+	if byName["kv.get"].Attributes["kv.hit"] != "true" {
+		t.Errorf("expected kv.hit attribute, got %v", byName["kv.get"].Attributes)
+	}
+	if byName["execution"].Attributes["faas.trigger"] != "http" {
+		t.Errorf("expected faas.trigger attribute, got %v", byName["execution"].Attributes)
+	}
+
+	httpSpanData := byName["http.get"]
+	if httpSpanData.Status != store.SpanStatusError {
+		t.Errorf("expected error status, got %s", httpSpanData.Status)
+	}
+	if httpSpanData.ErrorMessage == nil || *httpSpanData.ErrorMessage != "connection refused" {
+		t.Errorf("expected error message, got %v", httpSpanData.ErrorMessage)
+	}
+}
+
+func TestTrace_UnendedSpansAreExcluded(t *testing.T) {
+	trace := NewTrace("exec-1", "")
+	trace.Start("execution", store.SpanKindServer, nil)
+
+	if spans := trace.Spans(); len(spans) != 0 {
+		t.Errorf("expected no spans, got %d", len(spans))
+	}
+}
+
+func TestSpan_Traceparent(t *testing.T) {
+	trace := NewTrace("exec-1", "")
+	span := trace.Start("http.get", store.SpanKindClient, nil)
+
+	traceID, parentID, ok := ParseTraceparent(span.Traceparent())
+	if !ok {
+		t.Fatalf("expected valid traceparent, got %q", span.Traceparent())
+	}
+	if traceID != trace.TraceID() || parentID != span.ID() {
+		t.Errorf("traceparent does not identify the span: %s", span.Traceparent())
+	}
+}
+
+func TestNilTrace(t *testing.T) {
+	var trace *Trace
+
+	span := trace.Start("kv.get", store.SpanKindInternal, nil)
+	span.SetAttribute("key", "value")
+	span.SetError("boom")
+	span.End()
+
+	if span.Traceparent() != "" {
+		t.Error("expected empty traceparent for nil span")
+	}
+	if len(trace.Spans()) != 0 {
+		t.Error("expected no spans for nil trace")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+
+	s.Save([]store.Span{
+		{ID: "b", ExecutionID: "exec-1", Name: "second", StartTime: 20},
+		{ID: "a", ExecutionID: "exec-1", Name: "first", StartTime: 10},
+		{ID: "c", ExecutionID: "exec-2", Name: "other", StartTime: 5},
+	})
+
+	spans := s.Spans("exec-1")
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "first" || spans[1].Name != "second" {
+		t.Errorf("expected spans ordered by start time, got %s, %s", spans[0].Name, spans[1].Name)
+	}
+
+	s.Clear()
+	if len(s.Spans("exec-1")) != 0 {
+		t.Error("expected no spans after Clear")
+	}
+}
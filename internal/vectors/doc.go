@@ -0,0 +1,7 @@
+// Package vectors provides vector collections with function isolation.
+// Each function has its own named collections of embeddings with JSON
+// metadata, searched by cosine similarity. Queries compare the query vector
+// with every record of the collection, which is fast enough for the small
+// collections functions keep (see MaxRecordsPerCollection).
+// Supports both in-memory and SQLite-backed implementations.
+package vectors
@@ -0,0 +1,403 @@
+package vectors
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Limits keeping brute force search fast
+const (
+	MaxRecordsPerCollection = 10000
+	MaxDimensions           = 8192
+	MaxTopK                 = 100
+	DefaultTopK             = 10
+	maxCollectionNameLength = 128
+)
+
+// Record is a vector with its ID and metadata
+type Record struct {
+	ID       string
+	Vector   []float64
+	Metadata map[string]any
+}
+
+// Match is a record found by a query with its cosine similarity to the
+// query vector, from -1 to 1
+type Match struct {
+	Record
+	Score float64
+}
+
+// QueryOptions controls which records a query returns
+type QueryOptions struct {
+	TopK int // Maximum number of matches (default DefaultTopK, at most MaxTopK)
+	// Filter keeps records whose metadata has the given values. A list
+	// matches any of its values.
+	Filter   map[string]any
+	MinScore *float64 // Optional minimum similarity
+}
+
+// Store is an interface for vector collections
+// functionID is used to isolate collections between functions
+type Store interface {
+	Upsert(functionID, collection string, records []Record) error
+	Query(functionID, collection string, vector []float64, opts QueryOptions) ([]Match, error)
+	Delete(functionID, collection string, ids []string) error
+}
+
+// validateRecords checks the collection name and records of an upsert and
+// returns their dimensions
+func validateRecords(collection string, records []Record) (int, error) {
+	if err := validateCollection(collection); err != nil {
+		return 0, err
+	}
+	dimensions := 0
+	for i, r := range records {
+		if r.ID == "" {
+			return 0, fmt.Errorf("record %d requires an id", i+1)
+		}
+		if err := validateVector(r.Vector); err != nil {
+			return 0, fmt.Errorf("record %q: %w", r.ID, err)
+		}
+		if dimensions != 0 && len(r.Vector) != dimensions {
+			return 0, fmt.Errorf("record %q has %d dimensions, expected %d", r.ID, len(r.Vector), dimensions)
+		}
+		dimensions = len(r.Vector)
+	}
+	return dimensions, nil
+}
+
+// validateCollection checks a collection name
+func validateCollection(collection string) error {
+	if collection == "" || len(collection) > maxCollectionNameLength {
+		return fmt.Errorf("collection name must be 1 to %d characters", maxCollectionNameLength)
+	}
+	return nil
+}
+
+// validateVector checks that a vector can be compared by cosine similarity
+func validateVector(vector []float64) error {
+	if len(vector) == 0 || len(vector) > MaxDimensions {
+		return fmt.Errorf("vector must have 1 to %d dimensions", MaxDimensions)
+	}
+	if norm(vector) == 0 {
+		return errors.New("vector must not be all zeros")
+	}
+	return nil
+}
+
+// rank returns the records matching the filter, most similar first
+func rank(records []Record, vector []float64, opts QueryOptions) ([]Match, error) {
+	if err := validateVector(vector); err != nil {
+		return nil, err
+	}
+	topK := opts.TopK
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
+	topK = min(topK, MaxTopK)
+
+	queryNorm := norm(vector)
+	var matches []Match
+	for _, r := range records {
+		if len(r.Vector) != len(vector) {
+			return nil, fmt.Errorf("query vector has %d dimensions, collection has %d", len(vector), len(r.Vector))
+		}
+		if !matchesFilter(r.Metadata, opts.Filter) {
+			continue
+		}
+		score := dot(r.Vector, vector) / (norm(r.Vector) * queryNorm)
+		if opts.MinScore != nil && score < *opts.MinScore {
+			continue
+		}
+		matches = append(matches, Match{Record: r, Score: score})
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// matchesFilter reports whether metadata has every value in the filter
+func matchesFilter(metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		if options, isList := want.([]any); isList {
+			if !slices.ContainsFunc(options, func(o any) bool { return reflect.DeepEqual(o, got) }) {
+				return false
+			}
+		} else if !reflect.DeepEqual(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func norm(v []float64) float64 {
+	return math.Sqrt(dot(v, v))
+}
+
+// normalizeMetadata round-trips metadata through JSON, so values compare the
+// same way whether they were just stored or loaded back
+func normalizeMetadata(metadata map[string]any) ([]byte, map[string]any, error) {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return encoded, decoded, nil
+}
+
+// encodeVector stores a vector as little-endian float32 values
+func encodeVector(vector []float64) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+	}
+	return buf
+}
+
+// decodeVector reads a vector written by encodeVector
+func decodeVector(buf []byte) []float64 {
+	vector := make([]float64, len(buf)/4)
+	for i := range vector {
+		vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:])))
+	}
+	return vector
+}
+
+// MemoryStore is an in-memory implementation of Store
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string]map[string][]Record // functionID -> collection -> records
+}
+
+// NewMemoryStore creates a new in-memory vector store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string]map[string][]Record),
+	}
+}
+
+// Upsert inserts records or replaces the records with the same IDs
+func (m *MemoryStore) Upsert(functionID, collection string, records []Record) error {
+	dimensions, err := validateRecords(collection, records)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.data[functionID]; !exists {
+		m.data[functionID] = make(map[string][]Record)
+	}
+	existing := slices.Clone(m.data[functionID][collection])
+	if len(existing) > 0 && len(existing[0].Vector) != dimensions {
+		return fmt.Errorf("collection has %d dimensions, got %d", len(existing[0].Vector), dimensions)
+	}
+
+	for _, r := range records {
+		_, metadata, err := normalizeMetadata(r.Metadata)
+		if err != nil {
+			return fmt.Errorf("record %q: %w", r.ID, err)
+		}
+		// Vectors are stored as float32, like the SQLite store
+		stored := Record{ID: r.ID, Vector: decodeVector(encodeVector(r.Vector)), Metadata: metadata}
+		if i := slices.IndexFunc(existing, func(e Record) bool { return e.ID == r.ID }); i >= 0 {
+			existing[i] = stored
+		} else {
+			existing = append(existing, stored)
+		}
+	}
+	if len(existing) > MaxRecordsPerCollection {
+		return fmt.Errorf("collection would exceed %d records", MaxRecordsPerCollection)
+	}
+	m.data[functionID][collection] = existing
+	return nil
+}
+
+// Query returns the records most similar to the vector
+func (m *MemoryStore) Query(functionID, collection string, vector []float64, opts QueryOptions) ([]Match, error) {
+	if err := validateCollection(collection); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return rank(m.data[functionID][collection], vector, opts)
+}
+
+// Delete removes the records with the given IDs
+func (m *MemoryStore) Delete(functionID, collection string, ids []string) error {
+	if err := validateCollection(collection); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if collections, exists := m.data[functionID]; exists {
+		collections[collection] = slices.DeleteFunc(collections[collection], func(r Record) bool {
+			return slices.Contains(ids, r.ID)
+		})
+	}
+	return nil
+}
+
+// SQLiteStore is a SQLite-backed implementation of Store
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a new SQLite-backed vector store
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// Upsert inserts records or replaces the records with the same IDs
+func (s *SQLiteStore) Upsert(functionID, collection string, records []Record) (err error) {
+	dimensions, err := validateRecords(collection, records)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var existing int
+	err = tx.QueryRow(
+		"SELECT dimensions FROM vectors WHERE function_id = ? AND collection = ? LIMIT 1",
+		functionID, collection,
+	).Scan(&existing)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	if existing != 0 && existing != dimensions {
+		return fmt.Errorf("collection has %d dimensions, got %d", existing, dimensions)
+	}
+
+	now := time.Now().Unix()
+	for _, r := range records {
+		metadata, _, err := normalizeMetadata(r.Metadata)
+		if err != nil {
+			return fmt.Errorf("record %q: %w", r.ID, err)
+		}
+		_, err = tx.Exec(
+			`INSERT OR REPLACE INTO vectors (function_id, collection, id, dimensions, vector, metadata, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			functionID, collection, r.ID, dimensions, encodeVector(r.Vector), string(metadata), now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert vectors: %w", err)
+		}
+	}
+
+	var count int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM vectors WHERE function_id = ? AND collection = ?",
+		functionID, collection,
+	).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	if count > MaxRecordsPerCollection {
+		return fmt.Errorf("collection would exceed %d records", MaxRecordsPerCollection)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to upsert vectors: %w", err)
+	}
+	return nil
+}
+
+// Query returns the records most similar to the vector
+func (s *SQLiteStore) Query(functionID, collection string, vector []float64, opts QueryOptions) ([]Match, error) {
+	if err := validateCollection(collection); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT id, vector, metadata FROM vectors WHERE function_id = ? AND collection = ?",
+		functionID, collection,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		var vector []byte
+		var metadata string
+		if err := rows.Scan(&r.ID, &vector, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to query vectors: %w", err)
+		}
+		r.Vector = decodeVector(vector)
+		if err := json.Unmarshal([]byte(metadata), &r.Metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata for record %q: %w", r.ID, err)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query vectors: %w", err)
+	}
+
+	return rank(records, vector, opts)
+}
+
+// Delete removes the records with the given IDs
+func (s *SQLiteStore) Delete(functionID, collection string, ids []string) error {
+	if err := validateCollection(collection); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		_, err := s.db.Exec(
+			"DELETE FROM vectors WHERE function_id = ? AND collection = ? AND id = ?",
+			functionID, collection, id,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete vector: %w", err)
+		}
+	}
+	return nil
+}
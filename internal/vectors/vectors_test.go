@@ -0,0 +1,195 @@
+package vectors
+
+import (
+	"database/sql"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dimiro1/lunar/internal/migrate"
+	_ "modernc.org/sqlite"
+)
+
+func setupTestDB(t *testing.T) *sql.DB {
+	// Create a temporary database file
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	_ = tmpfile.Close()
+
+	db, err := sql.Open("sqlite", tmpfile.Name())
+	if err != nil {
+		_ = os.Remove(tmpfile.Name())
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	// Run migrations
+	migrate.RunTest(t, db)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		_ = os.Remove(tmpfile.Name())
+	})
+
+	return db
+}
+
+// stores returns every implementation, so each test runs against both
+func stores(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"sqlite": NewSQLiteStore(setupTestDB(t)),
+	}
+}
+
+var docs = []Record{
+	{ID: "cats", Vector: []float64{1, 0, 0}, Metadata: map[string]any{"lang": "en", "topic": "pets"}},
+	{ID: "dogs", Vector: []float64{0.9, 0.1, 0}, Metadata: map[string]any{"lang": "pt", "topic": "pets"}},
+	{ID: "cars", Vector: []float64{0, 1, 0}, Metadata: map[string]any{"lang": "en", "topic": "vehicles", "year": 2024}},
+	{ID: "opposite", Vector: []float64{-1, 0, 0}},
+}
+
+func ids(matches []Match) string {
+	var out []string
+	for _, m := range matches {
+		out = append(out, m.ID)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestStore_Query(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Upsert("func-1", "docs", docs); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+
+			minScore := 0.0
+			tests := []struct {
+				name     string
+				opts     QueryOptions
+				expected string
+			}{
+				{name: "all", expected: "cats,dogs,cars,opposite"},
+				{name: "top k", opts: QueryOptions{TopK: 2}, expected: "cats,dogs"},
+				{name: "filter", opts: QueryOptions{Filter: map[string]any{"lang": "en"}}, expected: "cats,cars"},
+				{name: "filter list", opts: QueryOptions{Filter: map[string]any{"lang": []any{"pt", "de"}}}, expected: "dogs"},
+				{name: "filter number", opts: QueryOptions{Filter: map[string]any{"year": 2024.0}}, expected: "cars"},
+				{name: "filter several keys", opts: QueryOptions{Filter: map[string]any{"lang": "en", "topic": "pets"}}, expected: "cats"},
+				{name: "filter missing key", opts: QueryOptions{Filter: map[string]any{"author": "ann"}}, expected: ""},
+				{name: "min score", opts: QueryOptions{MinScore: &minScore}, expected: "cats,dogs,cars"},
+			}
+			for _, tt := range tests {
+				matches, err := store.Query("func-1", "docs", []float64{2, 0, 0}, tt.opts)
+				if err != nil {
+					t.Fatalf("%s: Query failed: %v", tt.name, err)
+				}
+				if got := ids(matches); got != tt.expected {
+					t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+				}
+			}
+
+			matches, _ := store.Query("func-1", "docs", []float64{1, 0, 0}, QueryOptions{TopK: 1})
+			if len(matches) != 1 || math.Abs(matches[0].Score-1) > 1e-6 || matches[0].Metadata["topic"] != "pets" {
+				t.Errorf("unexpected best match %+v", matches)
+			}
+		})
+	}
+}
+
+func TestStore_UpsertReplacesAndDeletes(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = store.Upsert("func-1", "docs", docs)
+			err := store.Upsert("func-1", "docs", []Record{{ID: "cars", Vector: []float64{1, 0, 0}, Metadata: map[string]any{"new": true}}})
+			if err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+
+			matches, _ := store.Query("func-1", "docs", []float64{1, 0, 0}, QueryOptions{Filter: map[string]any{"new": true}})
+			if ids(matches) != "cars" {
+				t.Errorf("expected replaced record, got %q", ids(matches))
+			}
+
+			if err := store.Delete("func-1", "docs", []string{"cars", "cats", "missing"}); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			matches, _ = store.Query("func-1", "docs", []float64{1, 0, 0}, QueryOptions{})
+			if ids(matches) != "dogs,opposite" {
+				t.Errorf("expected remaining records, got %q", ids(matches))
+			}
+		})
+	}
+}
+
+func TestStore_Isolation(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = store.Upsert("func-1", "docs", docs)
+			_ = store.Upsert("func-1", "other", []Record{{ID: "x", Vector: []float64{1, 1}}})
+
+			for _, q := range []struct{ functionID, collection string }{{"func-2", "docs"}, {"func-1", "empty"}} {
+				matches, err := store.Query(q.functionID, q.collection, []float64{1, 0, 0}, QueryOptions{})
+				if err != nil || len(matches) != 0 {
+					t.Errorf("expected no matches in %s/%s, got %v, %v", q.functionID, q.collection, matches, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = store.Upsert("func-1", "docs", docs)
+
+			upserts := []struct {
+				collection string
+				records    []Record
+				expected   string
+			}{
+				{"", docs, "collection name must be 1 to 128 characters"},
+				{"docs", []Record{{Vector: []float64{1}}}, "record 1 requires an id"},
+				{"docs", []Record{{ID: "a"}}, `record "a": vector must have 1 to 8192 dimensions`},
+				{"docs", []Record{{ID: "a", Vector: []float64{0, 0, 0}}}, `record "a": vector must not be all zeros`},
+				{"new", []Record{{ID: "a", Vector: []float64{1, 0}}, {ID: "b", Vector: []float64{1}}}, `record "b" has 1 dimensions, expected 2`},
+				{"docs", []Record{{ID: "a", Vector: []float64{1, 0}}}, "collection has 3 dimensions, got 2"},
+				{"docs", []Record{{ID: "a", Vector: []float64{1, 0, 0}, Metadata: map[string]any{"bad": math.NaN()}}}, `record "a": invalid metadata`},
+			}
+			for _, tt := range upserts {
+				err := store.Upsert("func-1", tt.collection, tt.records)
+				if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+					t.Errorf("expected error %q, got %v", tt.expected, err)
+				}
+			}
+
+			if _, err := store.Query("func-1", "docs", []float64{1, 0}, QueryOptions{}); err == nil || err.Error() != "query vector has 2 dimensions, collection has 3" {
+				t.Errorf("unexpected query error: %v", err)
+			}
+
+			// Failed upserts leave the collection unchanged
+			matches, _ := store.Query("func-1", "docs", []float64{1, 0, 0}, QueryOptions{})
+			if ids(matches) != "cats,dogs,cars,opposite" {
+				t.Errorf("expected unchanged collection, got %q", ids(matches))
+			}
+		})
+	}
+}
+
+func TestStore_MaxRecords(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			records := make([]Record, MaxRecordsPerCollection+1)
+			for i := range records {
+				records[i] = Record{ID: strconv.Itoa(i), Vector: []float64{1}}
+			}
+			err := store.Upsert("func-1", "big", records)
+			if err == nil || err.Error() != "collection would exceed 10000 records" {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// Package webhook verifies and signs webhooks with the Standard Webhooks
+// scheme, which Svix and the providers built on it, such as Resend, use.
+// It also provides the timestamp and signature checks shared by other HMAC
+// webhook schemes.
+package webhook
@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how old a signed timestamp may be before a webhook is
+// rejected as a possible replay
+const DefaultTolerance = 5 * time.Minute
+
+// secretPrefix prefixes base64 encoded Standard Webhooks secrets
+const secretPrefix = "whsec_"
+
+// Verify verifies a Standard Webhooks signature. signatures is the value of
+// the signature header, space separated "v1,<base64>" entries signed over
+// "<id>.<timestamp>.<body>". A zero tolerance disables the timestamp check.
+func Verify(id, timestamp, signatures, body, secret string, tolerance time.Duration) error {
+	if err := CheckTimestamp(timestamp, tolerance); err != nil {
+		return err
+	}
+	key, err := Key(secret)
+	if err != nil {
+		return err
+	}
+
+	var candidates []string
+	for item := range strings.FieldsSeq(signatures) {
+		if signature, ok := strings.CutPrefix(item, "v1,"); ok {
+			candidates = append(candidates, signature)
+		}
+	}
+	return MatchSignature(Sign(key, id, timestamp, body), candidates)
+}
+
+// Sign returns the base64 Standard Webhooks signature of a message
+func Sign(key []byte, id, timestamp, body string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "." + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Key decodes a Standard Webhooks secret. Secrets are base64 encoded,
+// usually with a "whsec_" prefix.
+func Key(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, secretPrefix))
+	if err != nil {
+		return nil, errors.New("invalid secret: expected base64, optionally prefixed with whsec_")
+	}
+	return key, nil
+}
+
+// CheckTimestamp rejects Unix timestamps further than tolerance from now.
+// A zero tolerance only checks that the timestamp is valid.
+func CheckTimestamp(timestamp string, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if tolerance <= 0 {
+		return nil
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.New("timestamp outside the tolerance window")
+	}
+	return nil
+}
+
+// MatchSignature compares expected with each candidate in constant time
+func MatchSignature(expected string, candidates []string) error {
+	for _, candidate := range candidates {
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(candidate)) == 1 {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSecret is the base64 of "test-secret"
+const testSecret = "whsec_dGVzdC1zZWNyZXQ="
+
+func TestVerify(t *testing.T) {
+	key, err := Key(testSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	body := `{"type":"email.delivered"}`
+	signatures := "v1,b2xkLXNpZ25hdHVyZQ== v1," + Sign(key, "msg_1", now, body)
+
+	if err := Verify("msg_1", now, signatures, body, testSecret, DefaultTolerance); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		id, timestamp, signatures, body, secret string
+		tolerance                               time.Duration
+		err                                     string
+	}{
+		"tampered body":     {"msg_1", now, signatures, `{}`, testSecret, DefaultTolerance, "signature mismatch"},
+		"other message":     {"msg_2", now, signatures, body, testSecret, DefaultTolerance, "signature mismatch"},
+		"wrong secret":      {"msg_1", now, signatures, body, "whsec_b3RoZXI=", DefaultTolerance, "signature mismatch"},
+		"unknown version":   {"msg_1", now, "v2," + Sign(key, "msg_1", now, body), body, testSecret, DefaultTolerance, "signature mismatch"},
+		"replayed":          {"msg_1", old, "v1," + Sign(key, "msg_1", old, body), body, testSecret, DefaultTolerance, "timestamp outside the tolerance window"},
+		"invalid timestamp": {"msg_1", "soon", signatures, body, testSecret, 0, `invalid timestamp "soon"`},
+		"invalid secret":    {"msg_1", now, signatures, body, "whsec_!", DefaultTolerance, "invalid secret: expected base64, optionally prefixed with whsec_"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Verify(tt.id, tt.timestamp, tt.signatures, tt.body, tt.secret, tt.tolerance)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// Without a tolerance old timestamps are accepted
+	if err := Verify("msg_1", old, "v1,"+Sign(key, "msg_1", old, body), body, testSecret, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}